@@ -0,0 +1,42 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+// notifyEvacuation moves rooms off this node when SIGUSR1 is received
+func notifyEvacuation(server *service.LivekitServer) {
+	evacuateChan := make(chan os.Signal, 1)
+	signal.Notify(evacuateChan, syscall.SIGUSR1)
+
+	go func() {
+		for range evacuateChan {
+			logger.Infow("evacuation requested")
+			if err := server.Evacuate(); err != nil {
+				logger.Warnw("could not evacuate node", err)
+			}
+		}
+	}()
+}
@@ -316,6 +316,8 @@ func startServer(ctx context.Context, c *cli.Command) error {
 		}
 	}()
 
+	notifyEvacuation(server)
+
 	return server.Start()
 }
 
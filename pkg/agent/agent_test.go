@@ -12,6 +12,9 @@ import (
 
 	"github.com/livekit/livekit-server/pkg/agent"
 	"github.com/livekit/livekit-server/pkg/agent/testutils"
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/rpc"
@@ -57,6 +60,56 @@ func TestAgent(t *testing.T) {
 	})
 }
 
+func TestJobLifecycleEvents(t *testing.T) {
+	testAgentName := "test_agent"
+
+	bus := psrpc.NewLocalMessageBus()
+	client := must.Get(rpc.NewAgentInternalClient(bus))
+	t.Cleanup(client.Close)
+
+	events := &telemetryfakes.FakeTelemetryService{}
+	server := testutils.NewTestServerWithTelemetry(bus, &config.Config{Region: "test"}, events)
+	t.Cleanup(server.Close)
+
+	worker := server.SimulateAgentWorker()
+	responses := worker.RegisterWorkerResponses.Observe()
+	worker.Register(testAgentName, livekit.JobType_JT_ROOM)
+	select {
+	case <-responses.Events():
+	case <-time.After(time.Second):
+		require.Fail(t, "registration timeout")
+	}
+	responses.Stop()
+
+	job := &livekit.Job{
+		Id:         guid.New(guid.AgentJobPrefix),
+		DispatchId: guid.New(guid.AgentDispatchPrefix),
+		Type:       livekit.JobType_JT_ROOM,
+		Room:       &livekit.Room{Name: "test"},
+		AgentName:  testAgentName,
+	}
+	_, err := client.JobRequest(context.Background(), testAgentName, agent.RoomAgentTopic, job)
+	require.NoError(t, err)
+
+	// repeated running updates report the job started once
+	for _, status := range []livekit.JobStatus{livekit.JobStatus_JS_RUNNING, livekit.JobStatus_JS_RUNNING, livekit.JobStatus_JS_SUCCESS} {
+		worker.SendUpdateJob(&livekit.UpdateJobStatus{JobId: job.Id, Status: status})
+	}
+
+	expected := []string{telemetry.EventAgentJobAssigned, telemetry.EventAgentJobStarted, telemetry.EventAgentJobEnded}
+	require.Eventually(t, func() bool {
+		return events.AgentJobEventCallCount() == len(expected)
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, len(expected), events.AgentJobEventCallCount())
+
+	for i, event := range expected {
+		_, name, j := events.AgentJobEventArgsForCall(i)
+		require.Equal(t, event, name)
+		require.Equal(t, job.Id, j.Id)
+	}
+}
+
 func testBatchJobRequest(t require.TestingT, batchSize int, totalJobs int, client rpc.AgentInternalClient, workers []*testutils.AgentWorker) <-chan struct{} {
 	var assigned atomic.Uint32
 	done := make(chan struct{})
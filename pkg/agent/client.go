@@ -37,6 +37,9 @@ const (
 	PublisherAgentTopic     = "publisher"
 	ParticipantAgentTopic   = "participant"
 	DefaultHandlerNamespace = ""
+	// topic of WorkerRegistered updates published when a worker has capacity again,
+	// queued jobs are retried without invalidating the agent cache
+	WorkerAvailableTopic = "worker_available"
 
 	CheckEnabledTimeout = 5 * time.Second
 )
@@ -49,7 +52,9 @@ var jobTypeTopics = map[livekit.JobType]string{
 
 type Client interface {
 	// LaunchJob starts a room or participant job on an agent.
-	// it will launch a job once for each worker in each namespace
+	// it will launch a job once for each worker in each namespace.
+	// Jobs waiting for a worker are dispatched as pending and again with their final state,
+	// they are cancelled with ctx.
 	LaunchJob(ctx context.Context, desc *JobRequest) *serverutils.IncrementalDispatcher[*livekit.Job]
 	TerminateJob(ctx context.Context, jobID string, reason rpc.JobTerminateReason) (*livekit.JobState, error)
 	Stop() error
@@ -66,8 +71,9 @@ type JobRequest struct {
 }
 
 type agentClient struct {
-	client rpc.AgentInternalClient
-	config Config
+	client   rpc.AgentInternalClient
+	config   Config
+	jobQueue *jobQueue
 
 	mu sync.RWMutex
 
@@ -85,6 +91,7 @@ type agentClient struct {
 	workers *workerpool.WorkerPool
 
 	invalidateSub psrpc.Subscription[*emptypb.Empty]
+	availableSub  psrpc.Subscription[*emptypb.Empty]
 	subDone       chan struct{}
 }
 
@@ -95,10 +102,11 @@ func NewAgentClient(bus psrpc.MessageBus, config Config) (Client, error) {
 	}
 
 	c := &agentClient{
-		client:  client,
-		config:  config,
-		workers: workerpool.New(50),
-		subDone: make(chan struct{}),
+		client:   client,
+		config:   config,
+		jobQueue: newJobQueue(client, config.JobQueue),
+		workers:  workerpool.New(50),
+		subDone:  make(chan struct{}),
 	}
 
 	sub, err := c.client.SubscribeWorkerRegistered(context.Background(), DefaultHandlerNamespace)
@@ -108,6 +116,22 @@ func NewAgentClient(bus psrpc.MessageBus, config Config) (Client, error) {
 
 	c.invalidateSub = sub
 
+	availableSub, err := c.client.SubscribeWorkerRegistered(context.Background(), WorkerAvailableTopic)
+	if err != nil {
+		_ = sub.Close()
+		return nil, err
+	}
+
+	c.availableSub = availableSub
+
+	go func() {
+		for range availableSub.Channel() {
+			c.jobQueue.retryAll()
+		}
+
+		c.subDone <- struct{}{}
+	}()
+
 	go func() {
 		// invalidate cache
 		for range sub.Channel() {
@@ -119,6 +143,8 @@ func NewAgentClient(bus psrpc.MessageBus, config Config) (Client, error) {
 			c.publisherAgentNames = nil
 			c.participantAgentNames = nil
 			c.mu.Unlock()
+
+			c.jobQueue.retryAll()
 		}
 
 		c.subDone <- struct{}{}
@@ -131,10 +157,11 @@ func (c *agentClient) LaunchJob(ctx context.Context, desc *JobRequest) *serverut
 	var wg sync.WaitGroup
 	ret := serverutils.NewIncrementalDispatcher[*livekit.Job]()
 	defer func() {
-		c.workers.Submit(func() {
+		// queued jobs can hold this for up to max wait, not taking up the worker pool
+		go func() {
 			wg.Wait()
 			ret.Done()
-		})
+		}()
 	}()
 
 	jobTypeTopic, ok := jobTypeTopics[desc.JobType]
@@ -145,6 +172,14 @@ func (c *agentClient) LaunchJob(ctx context.Context, desc *JobRequest) *serverut
 	dispatcher := c.getDispatcher(desc.AgentName, desc.JobType)
 
 	if dispatcher == nil {
+		if desc.AgentName != "" && c.jobQueue.enabled() {
+			// scale from zero, the job waits for a worker to register for the agent name,
+			// queues are retried when a worker registers
+			job := c.newJob(desc, DefaultHandlerNamespace)
+			c.queueJob(ctx, desc, job, GetAgentTopic(desc.AgentName, DefaultHandlerNamespace), jobTypeTopic, ret, &wg)
+			return ret
+		}
+
 		logger.Infow("not dispatching agent job since no worker is available",
 			"agentName", desc.AgentName,
 			"jobType", desc.JobType,
@@ -160,19 +195,14 @@ func (c *agentClient) LaunchJob(ctx context.Context, desc *JobRequest) *serverut
 		c.workers.Submit(func() {
 			defer wg.Done()
 			// The cached agent parameters do not provide the exact combination of available job type/agent name/namespace, so some of the JobRequest RPC may not trigger any worker
-			job := &livekit.Job{
-				Id:              utils.NewGuid(utils.AgentJobPrefix),
-				DispatchId:      desc.DispatchId,
-				Type:            desc.JobType,
-				Room:            desc.Room,
-				Participant:     desc.Participant,
-				Namespace:       curNs,
-				AgentName:       desc.AgentName,
-				Metadata:        desc.Metadata,
-				EnableRecording: c.config.EnableUserDataRecording,
-			}
+			job := c.newJob(desc, curNs)
+			// ctx only cancels jobs once they are queued
 			resp, err := c.client.JobRequest(context.Background(), topic, jobTypeTopic, job)
 			if err != nil {
+				if IsNoWorkerAvailable(err) && c.jobQueue.enabled() {
+					c.queueJob(ctx, desc, job, topic, jobTypeTopic, ret, &wg)
+					return
+				}
 				logger.Infow("failed to send job request", "error", err, "namespace", curNs, "jobType", desc.JobType, "agentName", desc.AgentName)
 				return
 			}
@@ -184,6 +214,53 @@ func (c *agentClient) LaunchJob(ctx context.Context, desc *JobRequest) *serverut
 	return ret
 }
 
+func (c *agentClient) newJob(desc *JobRequest, namespace string) *livekit.Job {
+	return &livekit.Job{
+		Id:              utils.NewGuid(utils.AgentJobPrefix),
+		DispatchId:      desc.DispatchId,
+		Type:            desc.JobType,
+		Room:            desc.Room,
+		Participant:     desc.Participant,
+		Namespace:       namespace,
+		AgentName:       desc.AgentName,
+		Metadata:        desc.Metadata,
+		EnableRecording: c.config.EnableUserDataRecording,
+	}
+}
+
+// queueJob adds the job to the dispatcher as pending, and again with its final state once a worker
+// takes it or it gives up waiting
+func (c *agentClient) queueJob(
+	ctx context.Context,
+	desc *JobRequest,
+	job *livekit.Job,
+	topic string,
+	jobTypeTopic string,
+	ret *serverutils.IncrementalDispatcher[*livekit.Job],
+	wg *sync.WaitGroup,
+) {
+	pending := utils.CloneProto(job)
+	pending.State = &livekit.JobState{
+		Status:    livekit.JobStatus_JS_PENDING,
+		UpdatedAt: time.Now().UnixNano(),
+	}
+	ret.Add(pending)
+
+	wg.Add(1)
+	onDone := func(state *livekit.JobState) {
+		defer wg.Done()
+
+		done := utils.CloneProto(job)
+		done.State = state
+		ret.Add(done)
+	}
+	priority := c.config.JobQueue.JobPriority(desc.Room, desc.Participant)
+	if err := c.jobQueue.enqueue(ctx, job, priority, topic, jobTypeTopic, onDone); err != nil {
+		logger.Infow("failed to queue job request", "error", err, "namespace", job.Namespace, "jobType", desc.JobType, "agentName", desc.AgentName)
+		onDone(failedJobState(err))
+	}
+}
+
 func (c *agentClient) TerminateJob(ctx context.Context, jobID string, reason rpc.JobTerminateReason) (*livekit.JobState, error) {
 	resp, err := c.client.JobTerminate(context.Background(), jobID, &rpc.JobTerminateRequest{
 		JobId:  jobID,
@@ -322,7 +399,10 @@ func (c *agentClient) checkEnabled(roomNamespaces, publisherNamespaces, particip
 
 func (c *agentClient) Stop() error {
 	_ = c.invalidateSub.Close()
+	_ = c.availableSub.Close()
+	<-c.subDone
 	<-c.subDone
+	c.jobQueue.stop()
 	return nil
 }
 
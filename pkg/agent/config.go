@@ -1,5 +1,126 @@
 package agent
 
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+)
+
 type Config struct {
-	EnableUserDataRecording bool `yaml:"enable_user_data_recording"`
+	EnableUserDataRecording bool                  `yaml:"enable_user_data_recording"`
+	JobQueue                JobQueueConfig        `yaml:"job_queue,omitempty"`
+	WorkerSelection         WorkerSelectionConfig `yaml:"worker_selection,omitempty"`
+	JobHistory              JobHistoryConfig      `yaml:"job_history,omitempty"`
+	Autoscaling             AutoscalingConfig     `yaml:"autoscaling,omitempty"`
+	// how long the agent of the replacement of a job handed off by a draining worker has to join the room
+	// before the handoff is abandoned and the job stays on the draining worker
+	JobHandoffTimeout time.Duration `yaml:"job_handoff_timeout,omitempty"`
+}
+
+const DefaultJobHandoffTimeout = 30 * time.Second
+
+// JobQueueConfig controls queueing of jobs that could not be assigned because no worker was available,
+// queued jobs are retried with exponential backoff and whenever a worker registers or becomes available
+type JobQueueConfig struct {
+	// how long a job waits for a worker before failing, 0 disables queueing
+	MaxWait time.Duration `yaml:"max_wait,omitempty"`
+	// maximum number of jobs waiting per agent name, namespace and job type
+	MaxPending     int           `yaml:"max_pending,omitempty"`
+	InitialBackoff time.Duration `yaml:"initial_backoff,omitempty"`
+	MaxBackoff     time.Duration `yaml:"max_backoff,omitempty"`
+	// waiting jobs matching a rule are dispatched before jobs of lower priority,
+	// jobs matching no rule have priority 0
+	Priorities []JobPriorityRule `yaml:"priorities,omitempty"`
+}
+
+// JobPriorityRule matches jobs by room and target participant, empty fields match any job
+type JobPriorityRule struct {
+	RoomPrefix string `yaml:"room_prefix,omitempty"`
+	// kind of the participant targeted by publisher and participant jobs, e.g. sip
+	ParticipantKind string `yaml:"participant_kind,omitempty"`
+	Priority        int32  `yaml:"priority,omitempty"`
+}
+
+// JobPriority returns the highest priority of the rules matching a job
+func (c JobQueueConfig) JobPriority(room *livekit.Room, participant *livekit.ParticipantInfo) int32 {
+	var priority int32
+	matched := false
+	for _, rule := range c.Priorities {
+		if rule.RoomPrefix != "" && !strings.HasPrefix(room.GetName(), rule.RoomPrefix) {
+			continue
+		}
+		if rule.ParticipantKind != "" && (participant == nil || !strings.EqualFold(participant.Kind.String(), rule.ParticipantKind)) {
+			continue
+		}
+		if !matched || rule.Priority > priority {
+			priority = rule.Priority
+			matched = true
+		}
+	}
+	return priority
+}
+
+var DefaultJobQueueConfig = JobQueueConfig{
+	MaxPending:     100,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+}
+
+// JobHistoryConfig controls retention of ended jobs, listed by the agent jobs admin API
+type JobHistoryConfig struct {
+	// number of most recently ended jobs kept, 0 disables job history
+	MaxJobs int `yaml:"max_jobs,omitempty"`
+	// how long ended jobs are kept, 0 keeps them until dropped by MaxJobs
+	TTL time.Duration `yaml:"ttl,omitempty"`
+}
+
+var DefaultJobHistoryConfig = JobHistoryConfig{
+	MaxJobs: 1000,
+	TTL:     24 * time.Hour,
+}
+
+// AutoscalingConfig controls the worker count recommended by the agent scaling admin API
+type AutoscalingConfig struct {
+	// fraction of worker capacity the recommendation aims to use
+	TargetUtilization float64 `yaml:"target_utilization,omitempty"`
+	// bounds of the whole fleet, applied once the recommendations of all nodes are summed
+	MinWorkers int `yaml:"min_workers,omitempty"`
+	// 0 for no maximum
+	MaxWorkers int `yaml:"max_workers,omitempty"`
+	// job requests which found no worker within this window are counted as unmet demand
+	DemandWindow time.Duration `yaml:"demand_window,omitempty"`
+}
+
+var DefaultAutoscalingConfig = AutoscalingConfig{
+	TargetUtilization: 0.7,
+	DemandWindow:      time.Minute,
+}
+
+// RecommendWorkers returns the number of workers needed to run the current load along with the jobs
+// that found no worker, at the target utilization. The load of a job that found no worker is
+// estimated from the running jobs, a job per worker when nothing runs. The result is the demand seen by
+// a single node, it is not bounded by MinWorkers and MaxWorkers.
+func (c AutoscalingConfig) RecommendWorkers(load float64, runningJobs int, unmetJobs int) int {
+	loadPerJob := 1.0
+	if runningJobs > 0 && load > 0 {
+		loadPerJob = load / float64(runningJobs)
+	}
+
+	target := c.TargetUtilization
+	if target <= 0 || target > 1 {
+		target = 1
+	}
+
+	return int(math.Ceil((load + float64(unmetJobs)*loadPerJob) / target))
+}
+
+// BoundWorkers applies MinWorkers and MaxWorkers to the worker count recommended for the whole fleet.
+func (c AutoscalingConfig) BoundWorkers(workers int) int {
+	workers = max(workers, c.MinWorkers)
+	if c.MaxWorkers > 0 {
+		workers = min(workers, c.MaxWorkers)
+	}
+	return workers
 }
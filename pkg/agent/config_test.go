@@ -0,0 +1,75 @@
+package agent_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/agent"
+	"github.com/livekit/protocol/livekit"
+)
+
+func TestRecommendWorkers(t *testing.T) {
+	conf := agent.AutoscalingConfig{TargetUtilization: 0.5}
+
+	cases := []struct {
+		name        string
+		conf        agent.AutoscalingConfig
+		load        float64
+		runningJobs int
+		unmetJobs   int
+		expected    int
+	}{
+		{name: "idle", conf: conf, expected: 0},
+		{name: "min workers not applied per node", conf: agent.AutoscalingConfig{TargetUtilization: 0.5, MinWorkers: 2}, expected: 0},
+		{name: "load at target utilization", conf: conf, load: 1.5, runningJobs: 6, expected: 3},
+		{name: "unmet demand at observed load per job", conf: conf, load: 1.5, runningJobs: 6, unmetJobs: 4, expected: 5},
+		{name: "unmet demand with nothing running", conf: conf, unmetJobs: 2, expected: 4},
+		{name: "max workers not applied per node", conf: agent.AutoscalingConfig{TargetUtilization: 0.5, MaxWorkers: 3}, load: 4, runningJobs: 4, expected: 8},
+		{name: "invalid target utilization", conf: agent.AutoscalingConfig{}, load: 1.2, runningJobs: 2, expected: 2},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.expected, c.conf.RecommendWorkers(c.load, c.runningJobs, c.unmetJobs))
+		})
+	}
+}
+
+func TestBoundWorkers(t *testing.T) {
+	conf := agent.AutoscalingConfig{MinWorkers: 2, MaxWorkers: 5}
+
+	// two nodes each recommending workers, the bounds apply to the sum
+	require.Equal(t, 2, conf.BoundWorkers(0+0))
+	require.Equal(t, 4, conf.BoundWorkers(1+3))
+	require.Equal(t, 5, conf.BoundWorkers(4+4))
+	require.Equal(t, 8, agent.AutoscalingConfig{MinWorkers: 2}.BoundWorkers(4+4))
+}
+
+func TestJobPriority(t *testing.T) {
+	conf := agent.JobQueueConfig{
+		Priorities: []agent.JobPriorityRule{
+			{RoomPrefix: "support-", Priority: 5},
+			{ParticipantKind: "sip", Priority: 10},
+			{RoomPrefix: "test-", ParticipantKind: "standard", Priority: -1},
+		},
+	}
+
+	cases := []struct {
+		name        string
+		room        string
+		participant *livekit.ParticipantInfo
+		expected    int32
+	}{
+		{name: "no match", room: "room", expected: 0},
+		{name: "room prefix", room: "support-1", expected: 5},
+		{name: "participant kind", room: "room", participant: &livekit.ParticipantInfo{Kind: livekit.ParticipantInfo_SIP}, expected: 10},
+		{name: "highest matching rule", room: "support-1", participant: &livekit.ParticipantInfo{Kind: livekit.ParticipantInfo_SIP}, expected: 10},
+		{name: "kind rule needs a participant", room: "test-1", expected: 0},
+		{name: "all fields of a rule match", room: "test-1", participant: &livekit.ParticipantInfo{}, expected: -1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.expected, conf.JobPriority(&livekit.Room{Name: c.room}, c.participant))
+		})
+	}
+}
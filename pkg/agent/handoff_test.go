@@ -0,0 +1,188 @@
+package agent_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/livekit/livekit-server/pkg/agent"
+	"github.com/livekit/livekit-server/pkg/agent/testutils"
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/protocol/utils/guid"
+	"github.com/livekit/protocol/utils/must"
+	"github.com/livekit/psrpc"
+)
+
+func TestJobHandoff(t *testing.T) {
+	testAgentName := "test_agent"
+
+	// the room registers the replacement once its agent joined, i.e. the channel is closed
+	newRoom := func(joined <-chan struct{}) *servicefakes.FakeModerationClient {
+		room := &servicefakes.FakeModerationClient{}
+		room.HandOffAgentJobStub = func(ctx context.Context, _ rpc.RoomTopic, _ *structpb.Struct, _ ...psrpc.RequestOption) (*structpb.Struct, error) {
+			select {
+			case <-joined:
+				return &structpb.Struct{}, nil
+			case <-ctx.Done():
+				return nil, psrpc.NewError(psrpc.DeadlineExceeded, ctx.Err())
+			}
+		}
+		return room
+	}
+
+	setup := func(t *testing.T, room service.ModerationClient) (rpc.AgentInternalClient, *testutils.TestServer) {
+		bus := psrpc.NewLocalMessageBus()
+
+		client := must.Get(rpc.NewAgentInternalClient(bus))
+		t.Cleanup(client.Close)
+		server := testutils.NewTestServerWithRoomClient(bus, &config.Config{
+			Region: "test",
+			Agents: agent.Config{JobHandoffTimeout: time.Second},
+		}, room)
+		t.Cleanup(server.Close)
+		return client, server
+	}
+
+	register := func(t *testing.T, server *testutils.TestServer) *testutils.AgentWorker {
+		worker := server.SimulateAgentWorker()
+		responses := worker.RegisterWorkerResponses.Observe()
+		defer responses.Stop()
+		worker.Register(testAgentName, livekit.JobType_JT_ROOM)
+		select {
+		case <-responses.Events():
+		case <-time.After(time.Second):
+			require.Fail(t, "registration timeout")
+		}
+		return worker
+	}
+
+	requestJob := func(t *testing.T, client rpc.AgentInternalClient) *livekit.Job {
+		job := &livekit.Job{
+			Id:         guid.New(guid.AgentJobPrefix),
+			DispatchId: guid.New(guid.AgentDispatchPrefix),
+			Type:       livekit.JobType_JT_ROOM,
+			Room:       &livekit.Room{Name: "test"},
+			AgentName:  testAgentName,
+		}
+		_, err := client.JobRequest(context.Background(), testAgentName, agent.RoomAgentTopic, job)
+		require.NoError(t, err)
+		return job
+	}
+
+	next := func(t *testing.T, ch <-chan *livekit.JobAssignment) *livekit.Job {
+		select {
+		case a := <-ch:
+			return a.Job
+		case <-time.After(2 * time.Second):
+			require.Fail(t, "job assignment timeout")
+			return nil
+		}
+	}
+
+	terminated := func(t *testing.T, ch <-chan *livekit.JobTermination, jobID string) {
+		timeout := time.After(3 * time.Second)
+		for {
+			select {
+			case m := <-ch:
+				if m.JobId == jobID {
+					return
+				}
+			case <-timeout:
+				require.Fail(t, "job termination timeout", jobID)
+				return
+			}
+		}
+	}
+
+	t.Run("job is handed off once the agent of the replacement joined", func(t *testing.T) {
+		joined := make(chan struct{})
+		room := newRoom(joined)
+		client, server := setup(t, room)
+		draining := register(t, server)
+		job := requestJob(t, client)
+
+		replacing := register(t, server)
+		assignments := replacing.JobAssignments.Observe()
+		defer assignments.Stop()
+		drainingTerminations := draining.JobTerminations.Observe()
+		defer drainingTerminations.Stop()
+		replacingTerminations := replacing.JobTerminations.Observe()
+		defer replacingTerminations.Stop()
+
+		draining.SendMigrateJob(&livekit.MigrateJobRequest{})
+
+		replacement := next(t, assignments.Events())
+		require.NotEqual(t, job.Id, replacement.Id)
+		require.Equal(t, job.DispatchId, replacement.DispatchId)
+		require.Empty(t, drainingTerminations.Events())
+
+		// the replacement running is not enough, its agent has to join the room
+		replacing.SendUpdateJob(&livekit.UpdateJobStatus{JobId: replacement.Id, Status: livekit.JobStatus_JS_RUNNING})
+		require.Eventually(t, func() bool { return room.HandOffAgentJobCallCount() == 1 }, time.Second, 10*time.Millisecond)
+		require.Empty(t, drainingTerminations.Events())
+
+		_, topic, req, _ := room.HandOffAgentJobArgsForCall(0)
+		require.Equal(t, rpc.FormatRoomTopic("test"), topic)
+		require.Equal(t, job.Id, req.AsMap()["job_id"])
+		require.Equal(t, replacement.Id, req.AsMap()["replacement"].(map[string]any)["id"])
+
+		close(joined)
+		terminated(t, drainingTerminations.Events(), job.Id)
+
+		// the draining worker takes no new jobs
+		requestJob(t, client)
+		next(t, assignments.Events())
+
+		// the agent of the handed off job leaving does not end the replacement
+		_, err := client.JobTerminate(context.Background(), job.Id, &rpc.JobTerminateRequest{
+			JobId:  job.Id,
+			Reason: rpc.JobTerminateReason_AGENT_LEFT_ROOM,
+		})
+		require.Error(t, err)
+		require.Empty(t, replacingTerminations.Events())
+
+		// the room tracks the replacement and terminates it by its id
+		res, err := client.JobTerminate(context.Background(), replacement.Id, &rpc.JobTerminateRequest{
+			JobId:  replacement.Id,
+			Reason: rpc.JobTerminateReason_TERMINATION_REQUESTED,
+		})
+		require.NoError(t, err)
+		require.Equal(t, livekit.JobStatus_JS_SUCCESS, res.State.Status)
+		terminated(t, replacingTerminations.Events(), replacement.Id)
+	})
+
+	t.Run("job stays on draining worker when the agent of the replacement does not join", func(t *testing.T) {
+		client, server := setup(t, newRoom(nil))
+		draining := register(t, server)
+		job := requestJob(t, client)
+
+		replacing := register(t, server)
+		assignments := replacing.JobAssignments.Observe()
+		defer assignments.Stop()
+		drainingTerminations := draining.JobTerminations.Observe()
+		defer drainingTerminations.Stop()
+		replacingTerminations := replacing.JobTerminations.Observe()
+		defer replacingTerminations.Stop()
+
+		draining.SendMigrateJob(&livekit.MigrateJobRequest{JobIds: []string{job.Id}})
+
+		replacement := next(t, assignments.Events())
+		terminated(t, replacingTerminations.Events(), replacement.Id)
+		require.Len(t, draining.Jobs(), 1)
+
+		res, err := client.JobTerminate(context.Background(), job.Id, &rpc.JobTerminateRequest{
+			JobId:  job.Id,
+			Reason: rpc.JobTerminateReason_TERMINATION_REQUESTED,
+		})
+		require.NoError(t, err)
+		require.Equal(t, livekit.JobStatus_JS_SUCCESS, res.State.Status)
+		terminated(t, drainingTerminations.Events(), job.Id)
+	})
+}
@@ -0,0 +1,326 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+)
+
+var (
+	ErrJobQueueFull      = errors.New("job queue full")
+	ErrJobQueueCancelled = errors.New("job request cancelled")
+	ErrJobQueueTimeout   = errors.New("no worker available within max wait")
+	ErrJobQueueStopped   = errors.New("job queue stopped")
+)
+
+// IsNoWorkerAvailable returns true when a job request failed because no worker could take the job,
+// either all workers are busy or the last worker for the agent went away
+func IsNoWorkerAvailable(err error) bool {
+	var psrpcErr psrpc.Error
+	if !errors.As(err, &psrpcErr) {
+		return false
+	}
+
+	switch psrpcErr.Code() {
+	case psrpc.ResourceExhausted, psrpc.Unavailable:
+		return true
+	default:
+		return false
+	}
+}
+
+type jobQueueKey struct {
+	agentName string
+	namespace string
+	jobType   livekit.JobType
+}
+
+type queuedJob struct {
+	ctx          context.Context
+	job          *livekit.Job
+	topic        string
+	jobTypeTopic string
+	priority     int32
+	expiresAt    time.Time
+	onDone       func(state *livekit.JobState)
+	stopWatch    func() bool
+}
+
+type pendingJobs struct {
+	// ordered by priority, jobs of same priority in arrival order
+	jobs []*queuedJob
+	// signalled when a worker becomes available
+	retry chan struct{}
+	// signalled when a job is cancelled
+	update chan struct{}
+}
+
+func signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
+
+// jobQueue holds jobs which could not be assigned because no worker was available.
+// There is a queue per agent name, namespace and job type, each served by its own goroutine
+// while it has jobs. Jobs are retried in priority order, backing off exponentially after
+// each attempt that finds no worker, and retried right away when a worker registers or
+// becomes available. Jobs still queued when the queue stops fail.
+type jobQueue struct {
+	client rpc.AgentInternalClient
+	config JobQueueConfig
+
+	// cancelled on stop, also cancels requests in flight
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	lock    sync.Mutex
+	queues  map[jobQueueKey]*pendingJobs
+	workers sync.WaitGroup
+}
+
+func newJobQueue(client rpc.AgentInternalClient, config JobQueueConfig) *jobQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &jobQueue{
+		client: client,
+		config: config,
+		ctx:    ctx,
+		cancel: cancel,
+		queues: make(map[jobQueueKey]*pendingJobs),
+	}
+}
+
+// stop fails queued jobs and waits for the queue goroutines to exit
+func (q *jobQueue) stop() {
+	q.lock.Lock()
+	q.cancel()
+	q.lock.Unlock()
+
+	q.workers.Wait()
+}
+
+func (q *jobQueue) enabled() bool {
+	return q.config.MaxWait > 0
+}
+
+// enqueue adds a job to wait for a worker, onDone is called once with the final job state
+// when the job is assigned, fails, times out or ctx is cancelled.
+func (q *jobQueue) enqueue(
+	ctx context.Context,
+	job *livekit.Job,
+	priority int32,
+	topic string,
+	jobTypeTopic string,
+	onDone func(state *livekit.JobState),
+) error {
+	key := jobQueueKey{job.AgentName, job.Namespace, job.Type}
+
+	q.lock.Lock()
+	if q.ctx.Err() != nil {
+		q.lock.Unlock()
+		return ErrJobQueueStopped
+	}
+	pj := q.queues[key]
+	if pj != nil && q.config.MaxPending > 0 && len(pj.jobs) >= q.config.MaxPending {
+		q.lock.Unlock()
+		prometheus.RecordAgentJobRequestFailed(job.AgentName, job.Namespace, "queue_full")
+		return ErrJobQueueFull
+	}
+	if pj == nil {
+		pj = &pendingJobs{
+			retry:  make(chan struct{}, 1),
+			update: make(chan struct{}, 1),
+		}
+		q.queues[key] = pj
+		q.workers.Add(1)
+		go q.worker(key, pj)
+	}
+
+	qj := &queuedJob{
+		ctx:          ctx,
+		job:          job,
+		topic:        topic,
+		jobTypeTopic: jobTypeTopic,
+		priority:     priority,
+		expiresAt:    time.Now().Add(q.config.MaxWait),
+		onDone:       onDone,
+	}
+	idx := sort.Search(len(pj.jobs), func(i int) bool {
+		return pj.jobs[i].priority < priority
+	})
+	pj.jobs = slices.Insert(pj.jobs, idx, qj)
+	qj.stopWatch = context.AfterFunc(ctx, func() { signal(pj.update) })
+	q.lock.Unlock()
+
+	prometheus.AddAgentQueuedJobs(job.AgentName, job.Namespace, 1)
+
+	logger.Infow("queued agent job, no worker available",
+		"jobID", job.Id,
+		"agentName", job.AgentName,
+		"namespace", job.Namespace,
+		"jobType", job.Type,
+		"priority", priority,
+	)
+	return nil
+}
+
+// retryAll makes all queues retry right away
+func (q *jobQueue) retryAll() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for _, pj := range q.queues {
+		signal(pj.retry)
+	}
+}
+
+func (q *jobQueue) worker(key jobQueueKey, pj *pendingJobs) {
+	defer q.workers.Done()
+
+	// jobs are queued after an attempt found no worker, back off before the first retry
+	backoff := q.config.InitialBackoff
+	nextAttemptAt := time.Now().Add(backoff)
+
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		qj, expiresAt, ok := q.next(key, pj)
+		if !ok {
+			return
+		}
+
+		if wait := time.Until(nextAttemptAt); wait > 0 {
+			timer.Reset(min(wait, time.Until(expiresAt)))
+			select {
+			case <-timer.C:
+			case <-pj.retry:
+				nextAttemptAt = time.Time{}
+			case <-pj.update:
+			case <-q.ctx.Done():
+			}
+			timer.Stop()
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(qj.ctx)
+		stopCancel := context.AfterFunc(q.ctx, cancel)
+		resp, err := q.client.JobRequest(ctx, qj.topic, qj.jobTypeTopic, qj.job)
+		stopCancel()
+		cancel()
+		switch {
+		case err == nil:
+			backoff = 0
+			q.done(pj, qj, resp.State)
+
+		case qj.ctx.Err() != nil || q.ctx.Err() != nil:
+			// cancelled or stopped while the request was in flight, dropped on next pass
+
+		case IsNoWorkerAvailable(err):
+			backoff = max(min(2*backoff, q.config.MaxBackoff), q.config.InitialBackoff)
+			nextAttemptAt = time.Now().Add(backoff)
+
+		default:
+			logger.Infow("failed to send queued job request", "error", err, "jobID", qj.job.Id, "agentName", key.agentName, "namespace", key.namespace, "jobType", key.jobType)
+			q.done(pj, qj, failedJobState(err))
+		}
+	}
+}
+
+// next drops cancelled and expired jobs, or all of them once stopped, and returns the highest priority job along with the earliest expiry,
+// the queue is removed when it runs out of jobs.
+func (q *jobQueue) next(key jobQueueKey, pj *pendingJobs) (*queuedJob, time.Time, bool) {
+	var dropped []*queuedJob
+	defer func() {
+		for _, qj := range dropped {
+			err, reason := ErrJobQueueTimeout, "queue_timeout"
+			switch {
+			case qj.ctx.Err() != nil:
+				err, reason = ErrJobQueueCancelled, "cancelled"
+			case q.ctx.Err() != nil:
+				err, reason = ErrJobQueueStopped, "stopped"
+			}
+			logger.Infow("dropping queued agent job", "error", err, "jobID", qj.job.Id, "agentName", key.agentName, "namespace", key.namespace, "jobType", key.jobType)
+			prometheus.AddAgentQueuedJobs(key.agentName, key.namespace, -1)
+			prometheus.RecordAgentJobRequestFailed(key.agentName, key.namespace, reason)
+			qj.onDone(failedJobState(err))
+		}
+	}()
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	now := time.Now()
+	pj.jobs = slices.DeleteFunc(pj.jobs, func(qj *queuedJob) bool {
+		if qj.ctx.Err() != nil || q.ctx.Err() != nil || !now.Before(qj.expiresAt) {
+			qj.stopWatch()
+			dropped = append(dropped, qj)
+			return true
+		}
+		return false
+	})
+
+	if len(pj.jobs) == 0 {
+		delete(q.queues, key)
+		return nil, time.Time{}, false
+	}
+
+	expiresAt := pj.jobs[0].expiresAt
+	for _, qj := range pj.jobs[1:] {
+		if qj.expiresAt.Before(expiresAt) {
+			expiresAt = qj.expiresAt
+		}
+	}
+	return pj.jobs[0], expiresAt, true
+}
+
+func (q *jobQueue) done(pj *pendingJobs, qj *queuedJob, state *livekit.JobState) {
+	q.lock.Lock()
+	if idx := slices.Index(pj.jobs, qj); idx != -1 {
+		pj.jobs = slices.Delete(pj.jobs, idx, idx+1)
+	}
+	q.lock.Unlock()
+
+	prometheus.AddAgentQueuedJobs(qj.job.AgentName, qj.job.Namespace, -1)
+	if state.GetStatus() == livekit.JobStatus_JS_FAILED {
+		prometheus.RecordAgentJobRequestFailed(qj.job.AgentName, qj.job.Namespace, "error")
+	}
+
+	qj.stopWatch()
+	qj.onDone(state)
+}
+
+func failedJobState(err error) *livekit.JobState {
+	now := time.Now().UnixNano()
+	return &livekit.JobState{
+		Status:    livekit.JobStatus_JS_FAILED,
+		Error:     err.Error(),
+		EndedAt:   now,
+		UpdatedAt: now,
+	}
+}
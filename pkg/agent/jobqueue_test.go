@@ -0,0 +1,243 @@
+package agent_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/livekit-server/pkg/agent"
+	"github.com/livekit/livekit-server/pkg/agent/testutils"
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/utils/guid"
+	"github.com/livekit/protocol/utils/must"
+	"github.com/livekit/psrpc"
+)
+
+func TestJobQueue(t *testing.T) {
+	testAgentName := "test_agent"
+	queueConfig := agent.JobQueueConfig{
+		MaxWait:        2 * time.Second,
+		MaxPending:     10,
+		InitialBackoff: 50 * time.Millisecond,
+		MaxBackoff:     200 * time.Millisecond,
+	}
+
+	register := func(t *testing.T, server *testutils.TestServer, handleAvailability func(testutils.AgentJobRequest)) {
+		worker := server.SimulateAgentWorker(testutils.WithJobAvailabilityHandler(handleAvailability))
+		responses := worker.RegisterWorkerResponses.Observe()
+		worker.Register(testAgentName, livekit.JobType_JT_ROOM)
+		select {
+		case <-responses.Events():
+		case <-time.After(time.Second):
+			require.Fail(t, "registration timeout")
+		}
+		responses.Stop()
+	}
+
+	newServer := func(t *testing.T) (psrpc.MessageBus, *testutils.TestServer) {
+		bus := psrpc.NewLocalMessageBus()
+		server := testutils.NewTestServerWithConfig(bus, &config.Config{
+			Region: "test",
+			Agents: agent.Config{Autoscaling: agent.DefaultAutoscalingConfig},
+		})
+		t.Cleanup(server.Close)
+		return bus, server
+	}
+
+	newClient := func(t *testing.T, bus psrpc.MessageBus, config agent.JobQueueConfig) agent.Client {
+		client := must.Get(agent.NewAgentClient(bus, agent.Config{JobQueue: config}))
+		t.Cleanup(func() { client.Stop() })
+		return client
+	}
+
+	setup := func(t *testing.T, config agent.JobQueueConfig, handleAvailability func(testutils.AgentJobRequest)) agent.Client {
+		bus, server := newServer(t)
+		register(t, server, handleAvailability)
+		return newClient(t, bus, config)
+	}
+
+	launchInRoom := func(ctx context.Context, client agent.Client, roomName string) <-chan *livekit.Job {
+		jobs := make(chan *livekit.Job, 10)
+		go func() {
+			// returns once agent names are known
+			inc := client.LaunchJob(ctx, &agent.JobRequest{
+				DispatchId: guid.New(guid.AgentDispatchPrefix),
+				JobType:    livekit.JobType_JT_ROOM,
+				Room:       &livekit.Room{Name: roomName, Sid: guid.New(guid.RoomPrefix)},
+				AgentName:  testAgentName,
+			})
+			inc.ForEach(func(job *livekit.Job) { jobs <- job })
+			close(jobs)
+		}()
+		return jobs
+	}
+
+	launch := func(ctx context.Context, client agent.Client) <-chan *livekit.Job {
+		return launchInRoom(ctx, client, "test")
+	}
+
+	waitPending := func(t *testing.T, jobs <-chan *livekit.Job) {
+		select {
+		case job := <-jobs:
+			require.Equal(t, livekit.JobStatus_JS_PENDING, job.State.Status)
+		case <-time.After(10 * time.Second):
+			require.Fail(t, "job launch timeout")
+		}
+	}
+
+	collect := func(t *testing.T, jobs <-chan *livekit.Job) []*livekit.Job {
+		var ret []*livekit.Job
+		timeout := time.After(10 * time.Second)
+		for {
+			select {
+			case job, ok := <-jobs:
+				if !ok {
+					return ret
+				}
+				ret = append(ret, job)
+			case <-timeout:
+				require.Fail(t, "job launch timeout")
+				return ret
+			}
+		}
+	}
+
+	t.Run("queued job is assigned once a worker accepts it", func(t *testing.T) {
+		var requests atomic.Int32
+		client := setup(t, queueConfig, func(r testutils.AgentJobRequest) {
+			if requests.Inc() <= 3 {
+				r.Reject()
+			} else {
+				r.Accept()
+			}
+		})
+
+		jobs := collect(t, launch(context.Background(), client))
+		require.Len(t, jobs, 2)
+		require.Equal(t, jobs[0].Id, jobs[1].Id)
+		require.Equal(t, livekit.JobStatus_JS_PENDING, jobs[0].State.Status)
+		require.Equal(t, livekit.JobStatus_JS_RUNNING, jobs[1].State.Status)
+		require.EqualValues(t, 4, requests.Load())
+	})
+
+	t.Run("queued job fails after max wait", func(t *testing.T) {
+		bus, server := newServer(t)
+		var requests atomic.Int32
+		register(t, server, func(r testutils.AgentJobRequest) {
+			requests.Inc()
+			r.Reject()
+		})
+		client := newClient(t, bus, queueConfig)
+
+		start := time.Now()
+		jobs := collect(t, launch(context.Background(), client))
+		require.Len(t, jobs, 2)
+		require.Equal(t, livekit.JobStatus_JS_PENDING, jobs[0].State.Status)
+		require.Equal(t, livekit.JobStatus_JS_FAILED, jobs[1].State.Status)
+		require.Equal(t, agent.ErrJobQueueTimeout.Error(), jobs[1].State.Error)
+		require.GreaterOrEqual(t, time.Since(start), queueConfig.MaxWait)
+
+		// retries of the queued job are one unmet job
+		require.Greater(t, requests.Load(), int32(1))
+		stats := server.AgentService.(*service.AgentService).FleetStats()
+		require.Len(t, stats, 1)
+		require.Equal(t, 1, stats[0].UnmetJobRequests)
+	})
+
+	t.Run("queued job is cancelled with launch context", func(t *testing.T) {
+		// jobs are dispatched once agent names are known, wait longer than that
+		config := queueConfig
+		config.MaxWait = time.Minute
+		client := setup(t, config, func(r testutils.AgentJobRequest) { r.Reject() })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		jobs := launch(ctx, client)
+		select {
+		case job := <-jobs:
+			require.Equal(t, livekit.JobStatus_JS_PENDING, job.State.Status)
+		case <-time.After(10 * time.Second):
+			require.Fail(t, "job launch timeout")
+		}
+		cancel()
+
+		rest := collect(t, jobs)
+		require.Len(t, rest, 1)
+		require.Equal(t, livekit.JobStatus_JS_FAILED, rest[0].State.Status)
+		require.Equal(t, agent.ErrJobQueueCancelled.Error(), rest[0].State.Error)
+	})
+
+	t.Run("queued job is assigned to a worker registering later", func(t *testing.T) {
+		bus, server := newServer(t)
+		client := newClient(t, bus, queueConfig)
+
+		jobs := launch(context.Background(), client)
+		select {
+		case job := <-jobs:
+			require.Equal(t, livekit.JobStatus_JS_PENDING, job.State.Status)
+		case <-time.After(10 * time.Second):
+			require.Fail(t, "job launch timeout")
+		}
+
+		register(t, server, func(r testutils.AgentJobRequest) { r.Accept() })
+
+		rest := collect(t, jobs)
+		require.Len(t, rest, 1)
+		require.Equal(t, livekit.JobStatus_JS_RUNNING, rest[0].State.Status)
+	})
+	t.Run("higher priority queued job is assigned first", func(t *testing.T) {
+		config := queueConfig
+		config.MaxWait = time.Minute
+		// no retries until a worker registers, both jobs are queued by then
+		config.InitialBackoff = time.Minute
+		config.MaxBackoff = time.Minute
+		config.Priorities = []agent.JobPriorityRule{{RoomPrefix: "vip-", Priority: 10}}
+
+		bus, server := newServer(t)
+		client := newClient(t, bus, config)
+
+		low := launchInRoom(context.Background(), client, "test")
+		waitPending(t, low)
+		high := launchInRoom(context.Background(), client, "vip-test")
+		waitPending(t, high)
+
+		assigned := make(chan string, 2)
+		register(t, server, func(r testutils.AgentJobRequest) {
+			assigned <- r.Job.Room.Name
+			r.Accept()
+		})
+		for _, expected := range []string{"vip-test", "test"} {
+			select {
+			case name := <-assigned:
+				require.Equal(t, expected, name)
+			case <-time.After(10 * time.Second):
+				require.Fail(t, "job assignment timeout")
+			}
+		}
+		for _, jobs := range []<-chan *livekit.Job{high, low} {
+			rest := collect(t, jobs)
+			require.Len(t, rest, 1)
+			require.Equal(t, livekit.JobStatus_JS_RUNNING, rest[0].State.Status)
+		}
+	})
+
+	t.Run("queued job fails when the client stops", func(t *testing.T) {
+		config := queueConfig
+		config.MaxWait = time.Minute
+		bus, _ := newServer(t)
+		client := must.Get(agent.NewAgentClient(bus, agent.Config{JobQueue: config}))
+
+		jobs := launch(context.Background(), client)
+		waitPending(t, jobs)
+		require.NoError(t, client.Stop())
+
+		rest := collect(t, jobs)
+		require.Len(t, rest, 1)
+		require.Equal(t, livekit.JobStatus_JS_FAILED, rest[0].State.Status)
+		require.Equal(t, agent.ErrJobQueueStopped.Error(), rest[0].State.Error)
+	})
+}
@@ -0,0 +1,202 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"fmt"
+	"math/rand"
+
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+type WorkerSelectionStrategy string
+
+const (
+	// random, weighted by spare load, default
+	WorkerSelectionRandom WorkerSelectionStrategy = "random"
+	// worker with the lowest load
+	WorkerSelectionLeastLoaded WorkerSelectionStrategy = "least_loaded"
+	// workers in turn
+	WorkerSelectionRoundRobin WorkerSelectionStrategy = "round_robin"
+	// worker already running a job for the room, least loaded otherwise
+	WorkerSelectionStickyRoom WorkerSelectionStrategy = "sticky_room"
+	// random among workers in the region of the server, all workers when there are none in the region
+	WorkerSelectionRegion WorkerSelectionStrategy = "region"
+	// worker with the least spare capacity, fills up workers before using others
+	WorkerSelectionBinPacking WorkerSelectionStrategy = "bin_packing"
+)
+
+type WorkerSelectionConfig struct {
+	Default WorkerSelectionStrategy `yaml:"default,omitempty"`
+	// by agent name, overrides the default
+	Agents map[string]WorkerSelectionStrategy `yaml:"agents,omitempty"`
+}
+
+func (c WorkerSelectionConfig) Validate() error {
+	if err := c.Default.validate(); err != nil {
+		return err
+	}
+	for _, strategy := range c.Agents {
+		if err := strategy.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c WorkerSelectionConfig) StrategyFor(agentName string) WorkerSelectionStrategy {
+	if strategy, ok := c.Agents[agentName]; ok {
+		return strategy
+	}
+	return c.Default
+}
+
+func (s WorkerSelectionStrategy) validate() error {
+	switch s {
+	case "", WorkerSelectionRandom, WorkerSelectionLeastLoaded, WorkerSelectionRoundRobin,
+		WorkerSelectionStickyRoom, WorkerSelectionRegion, WorkerSelectionBinPacking:
+		return nil
+	default:
+		return fmt.Errorf("unknown worker selection strategy: %s", s)
+	}
+}
+
+// ------------------------------------------------
+
+// WorkerSelector picks the worker to offer a job to. Candidates are available, have capacity
+// for another job and have not been offered the job yet, there is at least one.
+type WorkerSelector interface {
+	SelectWorker(job *livekit.Job, candidates []*Worker) *Worker
+}
+
+// NewWorkerSelector creates a selector for the strategy, region is the region of the server
+// used by region aware selection
+func NewWorkerSelector(strategy WorkerSelectionStrategy, region string) WorkerSelector {
+	switch strategy {
+	case WorkerSelectionLeastLoaded:
+		return leastLoadedSelector{}
+	case WorkerSelectionRoundRobin:
+		return &roundRobinSelector{}
+	case WorkerSelectionStickyRoom:
+		return stickyRoomSelector{}
+	case WorkerSelectionRegion:
+		return regionSelector{region: region}
+	case WorkerSelectionBinPacking:
+		return binPackingSelector{}
+	default:
+		return randomSelector{}
+	}
+}
+
+// ------------------------------------------------
+
+type randomSelector struct{}
+
+func (randomSelector) SelectWorker(job *livekit.Job, candidates []*Worker) *Worker {
+	spare := make([]float32, len(candidates))
+	var spareSum float32
+	for idx, w := range candidates {
+		spare[idx] = max(0, 1-w.Load())
+		spareSum += spare[idx]
+	}
+
+	currentSum := rand.Float32() * spareSum
+	for idx, w := range candidates {
+		if currentSum -= spare[idx]; currentSum <= 0 {
+			return w
+		}
+	}
+	return candidates[0]
+}
+
+// ------------------------------------------------
+
+type leastLoadedSelector struct{}
+
+func (leastLoadedSelector) SelectWorker(job *livekit.Job, candidates []*Worker) *Worker {
+	selected := candidates[0]
+	selectedLoad := selected.Load()
+	for _, w := range candidates[1:] {
+		if load := w.Load(); load < selectedLoad {
+			selected, selectedLoad = w, load
+		}
+	}
+	return selected
+}
+
+// ------------------------------------------------
+
+type roundRobinSelector struct {
+	next atomic.Uint64
+}
+
+func (r *roundRobinSelector) SelectWorker(job *livekit.Job, candidates []*Worker) *Worker {
+	return candidates[(r.next.Inc()-1)%uint64(len(candidates))]
+}
+
+// ------------------------------------------------
+
+type stickyRoomSelector struct{}
+
+func (stickyRoomSelector) SelectWorker(job *livekit.Job, candidates []*Worker) *Worker {
+	if roomName := job.GetRoom().GetName(); roomName != "" {
+		for _, w := range candidates {
+			for _, running := range w.RunningJobs() {
+				if running.GetRoom().GetName() == roomName {
+					return w
+				}
+			}
+		}
+	}
+
+	return leastLoadedSelector{}.SelectWorker(job, candidates)
+}
+
+// ------------------------------------------------
+
+type regionSelector struct {
+	region string
+}
+
+func (r regionSelector) SelectWorker(job *livekit.Job, candidates []*Worker) *Worker {
+	var inRegion []*Worker
+	for _, w := range candidates {
+		if w.Region == r.region {
+			inRegion = append(inRegion, w)
+		}
+	}
+	if len(inRegion) == 0 {
+		inRegion = candidates
+	}
+
+	return randomSelector{}.SelectWorker(job, inRegion)
+}
+
+// ------------------------------------------------
+
+type binPackingSelector struct{}
+
+func (binPackingSelector) SelectWorker(job *livekit.Job, candidates []*Worker) *Worker {
+	selected := candidates[0]
+	selectedSpare := selected.SpareCapacity()
+	for _, w := range candidates[1:] {
+		if spare := w.SpareCapacity(); spare < selectedSpare {
+			selected, selectedSpare = w, spare
+		}
+	}
+	return selected
+}
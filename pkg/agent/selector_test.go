@@ -0,0 +1,221 @@
+package agent_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/livekit-server/pkg/agent"
+	"github.com/livekit/livekit-server/pkg/agent/testutils"
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/protocol/utils/guid"
+	"github.com/livekit/protocol/utils/must"
+	"github.com/livekit/psrpc"
+)
+
+func TestWorkerSelection(t *testing.T) {
+	testAgentName := "test_agent"
+
+	setup := func(t *testing.T, strategy agent.WorkerSelectionStrategy, opts ...[]testutils.SimulatedWorkerOption) (rpc.AgentInternalClient, []*testutils.AgentWorker) {
+		bus := psrpc.NewLocalMessageBus()
+
+		client := must.Get(rpc.NewAgentInternalClient(bus))
+		t.Cleanup(client.Close)
+		server := testutils.NewTestServerWithConfig(bus, &config.Config{
+			Region: "test",
+			Agents: agent.Config{
+				WorkerSelection: agent.WorkerSelectionConfig{
+					Agents: map[string]agent.WorkerSelectionStrategy{testAgentName: strategy},
+				},
+			},
+		})
+		t.Cleanup(server.Close)
+
+		workers := make([]*testutils.AgentWorker, len(opts))
+		for i, o := range opts {
+			workers[i] = server.SimulateAgentWorker(append(o, testutils.WithLabel(fmt.Sprintf("agent-%d", i)))...)
+			responses := workers[i].RegisterWorkerResponses.Observe()
+			workers[i].Register(testAgentName, livekit.JobType_JT_ROOM)
+			select {
+			case <-responses.Events():
+			case <-time.After(time.Second):
+				require.Fail(t, "registration timeout")
+			}
+			responses.Stop()
+		}
+		return client, workers
+	}
+
+	requestJobs := func(t *testing.T, client rpc.AgentInternalClient, roomNames ...string) {
+		for _, roomName := range roomNames {
+			_, err := client.JobRequest(context.Background(), testAgentName, agent.RoomAgentTopic, &livekit.Job{
+				Id:         guid.New(guid.AgentJobPrefix),
+				DispatchId: guid.New(guid.AgentDispatchPrefix),
+				Type:       livekit.JobType_JT_ROOM,
+				Room:       &livekit.Room{Name: roomName},
+				AgentName:  testAgentName,
+			})
+			require.NoError(t, err)
+		}
+	}
+
+	jobCounts := func(workers []*testutils.AgentWorker) []int {
+		counts := make([]int, len(workers))
+		for i, w := range workers {
+			counts[i] = len(w.Jobs())
+		}
+		return counts
+	}
+
+	t.Run("round robin", func(t *testing.T) {
+		client, workers := setup(t, agent.WorkerSelectionRoundRobin, nil, nil, nil)
+		requestJobs(t, client, "a", "b", "c", "d", "e", "f")
+		require.Eventually(t, func() bool {
+			return slices.Equal([]int{2, 2, 2}, jobCounts(workers))
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("sticky room", func(t *testing.T) {
+		client, workers := setup(t, agent.WorkerSelectionStickyRoom, nil, nil, nil)
+		requestJobs(t, client, "a", "a", "a")
+		require.Eventually(t, func() bool {
+			counts := jobCounts(workers)
+			slices.Sort(counts)
+			return slices.Equal([]int{0, 0, 3}, counts)
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("region", func(t *testing.T) {
+		client, workers := setup(t, agent.WorkerSelectionRegion,
+			[]testutils.SimulatedWorkerOption{testutils.WithRegion("other")},
+			[]testutils.SimulatedWorkerOption{testutils.WithRegion("test")},
+		)
+		requestJobs(t, client, "a", "b", "c", "d")
+		require.Eventually(t, func() bool {
+			return slices.Equal([]int{0, 4}, jobCounts(workers))
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("bin packing", func(t *testing.T) {
+		maxJobs := []testutils.SimulatedWorkerOption{testutils.WithMaxJobs(2)}
+		client, workers := setup(t, agent.WorkerSelectionBinPacking, maxJobs, maxJobs, maxJobs)
+		requestJobs(t, client, "a", "b", "c", "d")
+		require.Eventually(t, func() bool {
+			counts := jobCounts(workers)
+			slices.Sort(counts)
+			return slices.Equal([]int{0, 2, 2}, counts)
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestWorkerSelectionConnectionParams(t *testing.T) {
+	testAgentName := "test_agent"
+
+	bus := psrpc.NewLocalMessageBus()
+	client := must.Get(rpc.NewAgentInternalClient(bus))
+	t.Cleanup(client.Close)
+	server := testutils.NewTestServerWithConfig(bus, &config.Config{
+		Region: "test",
+		Agents: agent.Config{
+			WorkerSelection: agent.WorkerSelectionConfig{Default: agent.WorkerSelectionRegion},
+		},
+	})
+	t.Cleanup(server.Close)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		grants := &auth.ClaimGrants{Video: &auth.VideoGrant{Agent: true}}
+		server.AgentService.(*service.AgentService).ServeHTTP(w, r.WithContext(service.WithAPIKey(r.Context(), grants, server.TestAPIKey)))
+	}))
+	t.Cleanup(ts.Close)
+
+	// connects a worker which accepts all jobs, the room names of jobs offered to it are sent to the returned channel
+	connect := func(t *testing.T, query string) <-chan string {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"?"+query, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+
+		write := func(msg *livekit.WorkerMessage) {
+			require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, must.Get(proto.Marshal(msg))))
+		}
+		read := func() (*livekit.ServerMessage, error) {
+			_, payload, err := conn.ReadMessage()
+			if err != nil {
+				return nil, err
+			}
+			msg := &livekit.ServerMessage{}
+			return msg, proto.Unmarshal(payload, msg)
+		}
+
+		write(&livekit.WorkerMessage{Message: &livekit.WorkerMessage_Register{
+			Register: &livekit.RegisterWorkerRequest{Type: livekit.JobType_JT_ROOM, AgentName: testAgentName},
+		}})
+		msg, err := read()
+		require.NoError(t, err)
+		require.NotNil(t, msg.GetRegister())
+
+		offered := make(chan string, 10)
+		go func() {
+			for {
+				msg, err := read()
+				if err != nil {
+					return
+				}
+				if req := msg.GetAvailability(); req != nil {
+					offered <- req.Job.Room.Name
+					identity := guid.New("PI_")
+					_ = conn.WriteMessage(websocket.BinaryMessage, must.Get(proto.Marshal(&livekit.WorkerMessage{
+						Message: &livekit.WorkerMessage_Availability{Availability: &livekit.AvailabilityResponse{
+							JobId:               req.Job.Id,
+							Available:           true,
+							ParticipantName:     identity,
+							ParticipantIdentity: identity,
+						}},
+					})))
+				}
+			}
+		}()
+		return offered
+	}
+
+	other := connect(t, "region=other")
+	local := connect(t, "region=test&max_jobs=1")
+
+	requestJob := func(roomName string) {
+		_, err := client.JobRequest(context.Background(), testAgentName, agent.RoomAgentTopic, &livekit.Job{
+			Id:         guid.New(guid.AgentJobPrefix),
+			DispatchId: guid.New(guid.AgentDispatchPrefix),
+			Type:       livekit.JobType_JT_ROOM,
+			Room:       &livekit.Room{Name: roomName},
+			AgentName:  testAgentName,
+		})
+		require.NoError(t, err)
+	}
+
+	expectOffer := func(offered <-chan string, roomName string) {
+		select {
+		case name := <-offered:
+			require.Equal(t, roomName, name)
+		case <-time.After(time.Second):
+			require.Fail(t, "job not offered", roomName)
+		}
+	}
+
+	// the worker in the region of the server is selected until it runs max jobs
+	requestJob("a")
+	expectOffer(local, "a")
+	requestJob("b")
+	expectOffer(other, "b")
+}
@@ -17,8 +17,11 @@ import (
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
 	"github.com/livekit/protocol/utils/events"
 	"github.com/livekit/protocol/utils/guid"
 	"github.com/livekit/protocol/utils/must"
@@ -38,12 +41,35 @@ type TestServer struct {
 }
 
 func NewTestServer(bus psrpc.MessageBus) *TestServer {
+	return NewTestServerWithConfig(bus, &config.Config{Region: "test"})
+}
+
+func NewTestServerWithConfig(bus psrpc.MessageBus, conf *config.Config) *TestServer {
+	return NewTestServerWithRoomClient(bus, conf, must.Get(service.NewModerationClient(rpc.ClientParams{Bus: bus})))
+}
+
+// NewTestServerWithRoomClient creates a server reaching rooms with the client, e.g. to simulate agents joining them
+func NewTestServerWithRoomClient(bus psrpc.MessageBus, conf *config.Config, roomClient service.ModerationClient) *TestServer {
+	return newTestServer(bus, conf, roomClient, telemetry.NullTelemetryService{})
+}
+
+// NewTestServerWithTelemetry creates a server reporting agent job events to ts
+func NewTestServerWithTelemetry(bus psrpc.MessageBus, conf *config.Config, ts telemetry.TelemetryService) *TestServer {
+	return newTestServer(bus, conf, must.Get(service.NewModerationClient(rpc.ClientParams{Bus: bus})), ts)
+}
+
+func newTestServer(bus psrpc.MessageBus, conf *config.Config, roomClient service.ModerationClient, ts telemetry.TelemetryService) *TestServer {
+	prometheus.Init("test", livekit.NodeType_SERVER)
 	localNode, _ := routing.NewLocalNode(nil)
 	return NewTestServerWithService(must.Get(service.NewAgentService(
-		&config.Config{Region: "test"},
+		conf,
 		localNode,
 		bus,
 		auth.NewSimpleKeyProvider("test", "verysecretsecret"),
+		ts,
+		nil,
+		roomClient,
+		rpc.NewTopicFormatter(),
 	)))
 }
 
@@ -58,6 +84,8 @@ type SimulatedWorkerOptions struct {
 	DefaultJobLoad     float32
 	JobLoadThreshold   float32
 	DefaultWorkerLoad  float32
+	Region             string
+	MaxJobs            int
 	HandleAvailability func(AgentJobRequest)
 	HandleAssignment   func(*livekit.Job) JobLoad
 }
@@ -98,6 +126,18 @@ func WithDefaultWorkerLoad(load float32) SimulatedWorkerOption {
 	}
 }
 
+func WithRegion(region string) SimulatedWorkerOption {
+	return func(o *SimulatedWorkerOptions) {
+		o.Region = region
+	}
+}
+
+func WithMaxJobs(maxJobs int) SimulatedWorkerOption {
+	return func(o *SimulatedWorkerOptions) {
+		o.MaxJobs = maxJobs
+	}
+}
+
 func (h *TestServer) SimulateAgentWorker(opts ...SimulatedWorkerOption) *AgentWorker {
 	o := &SimulatedWorkerOptions{
 		Context:            context.Background(),
@@ -128,7 +168,10 @@ func (h *TestServer) SimulateAgentWorker(opts ...SimulatedWorkerOption) *AgentWo
 	}
 
 	ctx := service.WithAPIKey(o.Context, &auth.ClaimGrants{}, "test")
-	go h.HandleConnection(ctx, w, agent.MakeWorkerRegistration())
+	registration := agent.MakeWorkerRegistration()
+	registration.Region = o.Region
+	registration.MaxJobs = o.MaxJobs
+	go h.HandleConnection(ctx, w, registration)
 
 	return w
 }
@@ -149,6 +149,9 @@ type WorkerRegistration struct {
 	JobType     livekit.JobType
 	Permissions *livekit.ParticipantPermission
 	ClientIP    string
+	// optional, reported by the worker when connecting
+	Region  string
+	MaxJobs int
 }
 
 func MakeWorkerRegistration() WorkerRegistration {
@@ -237,11 +240,14 @@ type Worker struct {
 	cancel context.CancelFunc
 	closed chan struct{}
 
-	mu     sync.Mutex
-	load   float32
-	status livekit.WorkerStatus
+	mu       sync.Mutex
+	load     float32
+	status   livekit.WorkerStatus
+	draining bool
 
-	runningJobs  map[livekit.JobID]*livekit.Job
+	runningJobs map[livekit.JobID]*livekit.Job
+	// running jobs the worker reported as running
+	startedJobs  map[livekit.JobID]struct{}
 	availability map[livekit.JobID]chan *livekit.AvailabilityResponse
 }
 
@@ -270,6 +276,7 @@ func NewWorker(
 		closed: make(chan struct{}),
 
 		runningJobs:  make(map[livekit.JobID]*livekit.Job),
+		startedJobs:  make(map[livekit.JobID]struct{}),
 		availability: make(map[livekit.JobID]chan *livekit.AvailabilityResponse),
 	}
 }
@@ -292,6 +299,32 @@ func (w *Worker) Load() float32 {
 	return w.load
 }
 
+// HasCapacity returns true when the worker can be offered another job
+func (w *Worker) HasCapacity() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.draining && w.status == livekit.WorkerStatus_WS_AVAILABLE && w.load < 1 && (w.MaxJobs == 0 || len(w.runningJobs) < w.MaxJobs)
+}
+
+// IsDraining returns true once the worker asked for its jobs to be moved to other workers,
+// a draining worker is not offered new jobs
+func (w *Worker) IsDraining() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.draining
+}
+
+// SpareCapacity returns the fraction of capacity left, by job count when the worker reports
+// the maximum number of jobs it runs, by load otherwise
+func (w *Worker) SpareCapacity() float32 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.MaxJobs > 0 {
+		return 1 - float32(len(w.runningJobs))/float32(w.MaxJobs)
+	}
+	return max(0, 1-w.load)
+}
+
 func (w *Worker) Logger() logger.Logger {
 	return w.logger
 }
@@ -312,6 +345,16 @@ func (w *Worker) RunningJobCount() int {
 	return len(w.runningJobs)
 }
 
+func (w *Worker) GetJob(jobID livekit.JobID) (*livekit.Job, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	j, ok := w.runningJobs[jobID]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return utils.CloneProto(j), nil
+}
+
 func (w *Worker) GetJobState(jobID livekit.JobID) (*livekit.JobState, error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
@@ -504,13 +547,27 @@ func (w *Worker) HandleUpdateJob(update *livekit.UpdateJobStatus) error {
 }
 
 func (w *Worker) UpdateJobStatus(update *livekit.UpdateJobStatus) (*livekit.JobState, error) {
+	state, _, err := w.ReportJobStatus(update)
+	return state, err
+}
+
+// ReportJobStatus updates the job state with a status reported by the worker,
+// started is true for the first report of the job running
+func (w *Worker) ReportJobStatus(update *livekit.UpdateJobStatus) (state *livekit.JobState, started bool, err error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
 	jobID := livekit.JobID(update.JobId)
 	job, ok := w.runningJobs[jobID]
 	if !ok {
-		return nil, psrpc.NewErrorf(psrpc.NotFound, "received job update for unknown job")
+		return nil, false, psrpc.NewErrorf(psrpc.NotFound, "received job update for unknown job")
+	}
+
+	if update.Status == livekit.JobStatus_JS_RUNNING {
+		if _, ok := w.startedJobs[jobID]; !ok {
+			w.startedJobs[jobID] = struct{}{}
+			started = true
+		}
 	}
 
 	now := time.Now()
@@ -526,11 +583,12 @@ func (w *Worker) UpdateJobStatus(update *livekit.UpdateJobStatus) (*livekit.JobS
 	if JobStatusIsEnded(update.Status) {
 		job.State.EndedAt = now.UnixNano()
 		delete(w.runningJobs, jobID)
+		delete(w.startedJobs, jobID)
 
 		w.logger.Infow("job ended", "jobID", update.JobId, "status", update.Status, "error", update.Error)
 	}
 
-	return proto.Clone(job.State).(*livekit.JobState), nil
+	return proto.Clone(job.State).(*livekit.JobState), started, nil
 }
 
 func (w *Worker) HandleSimulateJob(simulate *livekit.SimulateJobRequest) error {
@@ -571,8 +629,14 @@ func (w *Worker) HandleUpdateWorker(update *livekit.UpdateWorkerStatus) error {
 	return nil
 }
 
+// HandleMigrateJob starts draining the worker, the jobs are handed off by the agent service
 func (w *Worker) HandleMigrateJob(req *livekit.MigrateJobRequest) error {
-	// TODO(theomonnom): On OSS this is not implemented
-	// We could maybe just move a specific job to another worker
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.draining {
+		w.draining = true
+		w.logger.Infow("worker draining", "jobIDs", req.JobIds)
+	}
 	return nil
 }
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -38,6 +39,7 @@ import (
 	"github.com/livekit/livekit-server/pkg/agent"
 	"github.com/livekit/livekit-server/pkg/metric"
 	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/bwe/delaybasedbwe"
 	"github.com/livekit/livekit-server/pkg/sfu/bwe/remotebwe"
 	"github.com/livekit/livekit-server/pkg/sfu/bwe/sendsidebwe"
 	"github.com/livekit/livekit-server/pkg/sfu/pacer"
@@ -51,6 +53,7 @@ const (
 var (
 	ErrKeyFileIncorrectPermission = errors.New("key file others permissions must be set to 0")
 	ErrKeysNotSet                 = errors.New("one of key-file or keys must be provided")
+	ErrConflictingBWE             = errors.New("use_send_side_bwe and use_delay_based_bwe cannot both be enabled")
 )
 
 type Config struct {
@@ -87,6 +90,14 @@ type Config struct {
 
 	NodeStats NodeStatsConfig `yaml:"node_stats,omitempty"`
 
+	Evacuation EvacuationConfig `yaml:"evacuation,omitempty"`
+
+	Relay RelayConfig `yaml:"relay,omitempty"`
+
+	RoomSnapshot RoomSnapshotConfig `yaml:"room_snapshot,omitempty"`
+
+	Admission AdmissionConfig `yaml:"admission,omitempty"`
+
 	EnableDataTracks bool `yaml:"enable_data_tracks,omitempty"`
 }
 
@@ -108,6 +119,12 @@ type RTCConfig struct {
 	// Throttle periods for pli/fir rtcp packets
 	PLIThrottle sfu.PLIThrottleConfig `yaml:"pli_throttle,omitempty"`
 
+	// cache of latest key frames to start subscribers without requesting a key frame from the publisher
+	KeyFrameCache sfu.KeyFrameCacheConfig `yaml:"key_frame_cache,omitempty"`
+
+	// FlexFEC generation for video sent to subscribers that negotiate it
+	FEC sfu.FECConfig `yaml:"fec,omitempty"`
+
 	CongestionControl CongestionControlConfig `yaml:"congestion_control,omitempty"`
 
 	// allow TCP and TURN/TLS fallback
@@ -165,6 +182,42 @@ type CongestionControlConfig struct {
 	UseSendSideBWE   bool                          `yaml:"use_send_side_bwe,omitempty"`
 	SendSideBWEPacer string                        `yaml:"send_side_bwe_pacer,omitempty"`
 	SendSideBWE      sendsidebwe.SendSideBWEConfig `yaml:"send_side_bwe,omitempty"`
+
+	// used when send_side_bwe_pacer is priority
+	PriorityPacer pacer.PriorityConfig `yaml:"priority_pacer,omitempty"`
+
+	// GCC style delay based estimator driven by TWCC feedback, uses the pacer configured by send_side_bwe_pacer
+	UseDelayBasedBWE bool                              `yaml:"use_delay_based_bwe,omitempty"`
+	DelayBasedBWE    delaybasedbwe.DelayBasedBWEConfig `yaml:"delay_based_bwe,omitempty"`
+
+	NodeBandwidth NodeBandwidthConfig `yaml:"node_bandwidth,omitempty"`
+}
+
+// Validate rejects enabling more than one send side bandwidth estimator.
+func (c CongestionControlConfig) Validate() error {
+	if c.UseSendSideBWE && c.UseDelayBasedBWE {
+		return ErrConflictingBWE
+	}
+	return nil
+}
+
+// NodeBandwidthConfig shares egress capacity of the node among subscribers when their total demand exceeds it.
+// Higher priority subscribers are served first, video of the lowest priority ones is shed first.
+type NodeBandwidthConfig struct {
+	// egress capacity of the node for video in bps, no arbitration when 0
+	Capacity int64 `yaml:"capacity,omitempty"`
+	// how often demand of subscribers is evaluated
+	UpdateInterval time.Duration `yaml:"update_interval,omitempty"`
+	// subscribers are not limited below this bitrate, even when the node is short of capacity
+	MinSubscriberBitrate int64 `yaml:"min_subscriber_bitrate,omitempty"`
+	// the first rule matching a subscriber gives its priority, it is 0 when none match
+	Priorities []NodeBandwidthPriority `yaml:"priorities,omitempty"`
+}
+
+type NodeBandwidthPriority struct {
+	// rule over the subscriber with the syntax of subscription rules, e.g. "kind == EGRESS" or "tier in [vip, staff]"
+	Subscribers string `yaml:"subscribers,omitempty"`
+	Priority    int    `yaml:"priority,omitempty"`
 }
 
 type PlayoutDelayConfig struct {
@@ -202,6 +255,48 @@ type RoomConfig struct {
 	// deprecated, moved to limits
 	MaxParticipantIdentityLength int                                   `yaml:"max_participant_identity_length,omitempty"`
 	RoomConfigurations           map[string]*livekit.RoomConfiguration `yaml:"room_configurations,omitempty"`
+	Lobby                        LobbyConfig                           `yaml:"lobby,omitempty"`
+	PublishRequest               PublishRequestConfig                  `yaml:"publish_request,omitempty"`
+	DataPolicy                   DataPolicyConfig                      `yaml:"data_policy,omitempty"`
+	DataHistory                  DataHistoryConfig                     `yaml:"data_history,omitempty"`
+}
+
+type LobbyConfig struct {
+	// hold participants of all rooms in the lobby
+	Enabled bool `yaml:"enabled,omitempty"`
+	// hold participants of rooms created with one of these presets in the lobby,
+	// room admins can override it per room
+	RoomPresets []string `yaml:"room_presets,omitempty"`
+	// pending participants not admitted within this time are removed, 0 for no timeout
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+func (c LobbyConfig) IsEnabledForPreset(roomPreset string) bool {
+	if c.Enabled {
+		return true
+	}
+	return roomPreset != "" && slices.Contains(c.RoomPresets, roomPreset)
+}
+
+type DataPolicyConfig struct {
+	// data policy scripts running longer than this on a message fail
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+type DataHistoryConfig struct {
+	// upper bound of messages kept per topic, 0 for no bound
+	MaxMessages int `yaml:"max_messages,omitempty"`
+	// upper bound of the time messages are kept, 0 for no bound
+	MaxAge time.Duration `yaml:"max_age,omitempty"`
+	// when set, kept messages of a room are written to this directory as JSON lines when the room closes
+	ExportPath string `yaml:"export_path,omitempty"`
+}
+
+type PublishRequestConfig struct {
+	// requests to publish not approved or denied within this time expire, 0 for no expiry
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// approvals are refused while this many participants are allowed to publish, 0 for no limit
+	MaxPublishers int `yaml:"max_publishers,omitempty"`
 }
 
 type CodecSpec struct {
@@ -262,6 +357,10 @@ type LimitConfig struct {
 	MaxRoomNameLength            int    `yaml:"max_room_name_length,omitempty"`
 	MaxParticipantIdentityLength int    `yaml:"max_participant_identity_length,omitempty"`
 	MaxParticipantNameLength     int    `yaml:"max_participant_name_length,omitempty"`
+	// shared state of a room, total size of all keys and values
+	MaxRoomStateSize      uint32 `yaml:"max_room_state_size,omitempty"`
+	MaxRoomStateKeys      int    `yaml:"max_room_state_keys,omitempty"`
+	MaxRoomStateKeyLength int    `yaml:"max_room_state_key_length,omitempty"`
 }
 
 func (l LimitConfig) CheckRoomNameLength(name string) bool {
@@ -280,6 +379,15 @@ func (l LimitConfig) CheckMetadataSize(metadata string) bool {
 	return l.MaxMetadataSize == 0 || uint32(len(metadata)) <= l.MaxMetadataSize
 }
 
+func (l LimitConfig) CheckRoomStateKeyLength(key string) bool {
+	return l.MaxRoomStateKeyLength == 0 || len(key) <= l.MaxRoomStateKeyLength
+}
+
+func (l LimitConfig) CheckRoomStateSize(numKeys int, size int) bool {
+	return (l.MaxRoomStateKeys == 0 || numKeys <= l.MaxRoomStateKeys) &&
+		(l.MaxRoomStateSize == 0 || uint32(size) <= l.MaxRoomStateSize)
+}
+
 func (l LimitConfig) CheckAttributesSize(attributes map[string]string) bool {
 	if l.MaxAttributesSize == 0 {
 		return true
@@ -349,6 +457,74 @@ var DefaultNodeStatsConfig = NodeStatsConfig{
 	StatsMaxDelay:                 30 * time.Second,
 }
 
+type EvacuationConfig struct {
+	// migrate rooms to other nodes on shutdown instead of waiting for participants to leave
+	OnShutdown bool `yaml:"on_shutdown,omitempty"`
+	// amount of time to wait for participants of a room to resume on the target node
+	// before closing their sessions on this node
+	MigrationTimeout time.Duration `yaml:"migration_timeout,omitempty"`
+	// pause between rooms, spreads out the resume load on target nodes
+	RoomInterval time.Duration `yaml:"room_interval,omitempty"`
+}
+
+var DefaultEvacuationConfig = EvacuationConfig{
+	MigrationTimeout: 30 * time.Second,
+	RoomInterval:     200 * time.Millisecond,
+}
+
+type RelayConfig struct {
+	// allow rooms to span nodes, participants connect to a node in their own region
+	// and media is relayed between the nodes hosting the room
+	Enabled bool `yaml:"enabled,omitempty"`
+	// port used for relay control (TCP) and media (UDP) between nodes
+	Port uint32 `yaml:"port,omitempty"`
+	// shared by all nodes, required. Nodes prove knowing it to each other and derive keys
+	// sealing control messages and media relayed between them from it
+	Secret string `yaml:"secret,omitempty"`
+}
+
+var DefaultRelayConfig = RelayConfig{
+	Port: 7890,
+}
+
+type RoomSnapshotConfig struct {
+	// snapshot rooms on shutdown and restore them on startup, participants resume their sessions after a restart
+	Enabled bool `yaml:"enabled,omitempty"`
+	// snapshots not resumed within this time are discarded
+	TTL time.Duration `yaml:"ttl,omitempty"`
+	// directory to keep snapshots in, required to restore rooms when running without redis
+	Path string `yaml:"path,omitempty"`
+	// identifies snapshots of this node in redis, it has to stay the same across restarts. node IDs do not,
+	// defaults to node_ip:port
+	NodeKey string `yaml:"node_key,omitempty"`
+}
+
+var DefaultRoomSnapshotConfig = RoomSnapshotConfig{
+	TTL: 2 * time.Minute,
+}
+
+type AdmissionConfig struct {
+	// tengo script deciding on joins, see service.AdmissionHook
+	Script string `yaml:"script,omitempty"`
+	// URL the join is POSTed to for a decision, instead of a script
+	URL string `yaml:"url,omitempty"`
+	// time given to the script or URL to decide
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// admit joins when the script or URL fails, they are denied otherwise
+	FailOpen bool `yaml:"fail_open,omitempty"`
+	// header with the country of the client set by a trusted proxy in front of the server, e.g. CF-IPCountry,
+	// the country of the request is left empty when not set
+	CountryHeader string `yaml:"country_header,omitempty"`
+}
+
+func (c AdmissionConfig) Enabled() bool {
+	return c.Script != "" || c.URL != ""
+}
+
+var DefaultAdmissionConfig = AdmissionConfig{
+	Timeout: 2 * time.Second,
+}
+
 var DefaultConfig = Config{
 	Port: 7880,
 	RTC: RTCConfig{
@@ -363,6 +539,8 @@ var DefaultConfig = Config{
 		PacketBufferSizeVideo: 500,
 		PacketBufferSizeAudio: 200,
 		PLIThrottle:           sfu.DefaultPLIThrottleConfig,
+		KeyFrameCache:         sfu.DefaultKeyFrameCacheConfig,
+		FEC:                   sfu.DefaultFECConfig,
 		CongestionControl: CongestionControlConfig{
 			Enabled:                   true,
 			AllowPause:                false,
@@ -372,6 +550,12 @@ var DefaultConfig = Config{
 			UseSendSideBWE:            false,
 			SendSideBWEPacer:          string(pacer.PacerBehaviorNoQueue),
 			SendSideBWE:               sendsidebwe.DefaultSendSideBWEConfig,
+			PriorityPacer:             pacer.DefaultPriorityConfig,
+			UseDelayBasedBWE:          false,
+			DelayBasedBWE:             delaybasedbwe.DefaultDelayBasedBWEConfig,
+			NodeBandwidth: NodeBandwidthConfig{
+				UpdateInterval: time.Second,
+			},
 		},
 	},
 	Audio: sfu.DefaultAudioConfig,
@@ -401,10 +585,26 @@ var DefaultConfig = Config{
 		CreateRoomTimeout:     10 * time.Second,
 		CreateRoomAttempts:    3,
 		UpdateBatchTargetSize: 128 * 1024,
+		Lobby: LobbyConfig{
+			Timeout: 10 * time.Minute,
+		},
+		PublishRequest: PublishRequestConfig{
+			Timeout: 2 * time.Minute,
+		},
+		DataPolicy: DataPolicyConfig{
+			Timeout: 10 * time.Millisecond,
+		},
+		DataHistory: DataHistoryConfig{
+			MaxMessages: 1000,
+			MaxAge:      24 * time.Hour,
+		},
 	},
 	Limit: LimitConfig{
 		MaxMetadataSize:              64000,
 		MaxAttributesSize:            64000,
+		MaxRoomStateSize:             64000,
+		MaxRoomStateKeys:             1000,
+		MaxRoomStateKeyLength:        256,
 		MaxRoomNameLength:            256,
 		MaxParticipantIdentityLength: 256,
 		MaxParticipantNameLength:     256,
@@ -429,11 +629,21 @@ var DefaultConfig = Config{
 		StreamBufferSize: 1000,
 		ConnectAttempts:  3,
 	},
-	PSRPC:     rpc.DefaultPSRPCConfig,
-	Keys:      map[string]string{},
-	Metric:    metric.DefaultMetricConfig,
-	WebHook:   webhook.DefaultWebHookConfig,
-	NodeStats: DefaultNodeStatsConfig,
+	PSRPC:        rpc.DefaultPSRPCConfig,
+	Keys:         map[string]string{},
+	Metric:       metric.DefaultMetricConfig,
+	WebHook:      webhook.DefaultWebHookConfig,
+	NodeStats:    DefaultNodeStatsConfig,
+	Evacuation:   DefaultEvacuationConfig,
+	Relay:        DefaultRelayConfig,
+	RoomSnapshot: DefaultRoomSnapshotConfig,
+	Admission:    DefaultAdmissionConfig,
+	Agents: agent.Config{
+		JobQueue:          agent.DefaultJobQueueConfig,
+		JobHistory:        agent.DefaultJobHistoryConfig,
+		Autoscaling:       agent.DefaultAutoscalingConfig,
+		JobHandoffTimeout: agent.DefaultJobHandoffTimeout,
+	},
 }
 
 func NewConfig(confString string, strictMode bool, c *cli.Command, baseFlags []cli.Flag) (*Config, error) {
@@ -466,6 +676,9 @@ func NewConfig(confString string, strictMode bool, c *cli.Command, baseFlags []c
 	if err := conf.RTC.Validate(conf.Development); err != nil {
 		return nil, fmt.Errorf("could not validate RTC config: %v", err)
 	}
+	if err := conf.RTC.CongestionControl.Validate(); err != nil {
+		return nil, fmt.Errorf("could not validate congestion control config: %w", err)
+	}
 
 	// expand env vars in filenames
 	file, err := homedir.Expand(os.ExpandEnv(conf.KeyFile))
@@ -16,6 +16,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/urfave/cli/v3"
@@ -48,6 +49,40 @@ room:
 	require.Error(t, err)
 }
 
+func TestConfig_Lobby(t *testing.T) {
+	const content = `room:
+  lobby:
+    room_presets: [waiting-room]`
+	conf, err := NewConfig(content, true, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, 10*time.Minute, conf.Room.Lobby.Timeout)
+	require.True(t, conf.Room.Lobby.IsEnabledForPreset("waiting-room"))
+	require.False(t, conf.Room.Lobby.IsEnabledForPreset("other"))
+	require.False(t, conf.Room.Lobby.IsEnabledForPreset(""))
+
+	conf.Room.Lobby.Enabled = true
+	require.True(t, conf.Room.Lobby.IsEnabledForPreset(""))
+}
+
+func TestConfig_LobbyWithoutTimeout(t *testing.T) {
+	const content = `room:
+  lobby:
+    enabled: true
+    timeout: 0s`
+	conf, err := NewConfig(content, true, nil, nil)
+	require.NoError(t, err)
+	require.Zero(t, conf.Room.Lobby.Timeout)
+}
+
+func TestConfig_ConflictingBWE(t *testing.T) {
+	const content = `rtc:
+  congestion_control:
+    use_send_side_bwe: true
+    use_delay_based_bwe: true`
+	_, err := NewConfig(content, true, nil, nil)
+	require.ErrorIs(t, err, ErrConflictingBWE)
+}
+
 func TestGeneratedFlags(t *testing.T) {
 	generatedFlags, err := GenerateCLIFlags(nil, false)
 	require.NoError(t, err)
@@ -0,0 +1,227 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relay
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/pion/webrtc/v4"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const (
+	maxMessageSize = 4 * 1024 * 1024
+
+	// kind(1) + stream id(4) + layer(1)
+	packetHeaderSize = 6
+)
+
+var (
+	ErrMessageTooLarge = errors.New("relay message too large")
+	ErrInvalidPacket   = errors.New("invalid relay packet")
+	ErrReplayedPacket  = errors.New("replayed relay packet")
+)
+
+// Control messages are exchanged over a TCP connection between nodes,
+// each message is a JSON object prefixed by its length (uint32, big endian).
+// Messages following the handshake are sealed with the session key.
+type MessageType string
+
+const (
+	// first message on a connection, identifies the connecting node
+	MessageTypeHello MessageType = "hello"
+	// reply of the listening node to hello, proves it knows the relay secret
+	MessageTypeChallenge MessageType = "challenge"
+	// reply of the connecting node to challenge, proves it knows the relay secret
+	MessageTypeAuth MessageType = "auth"
+	// node started hosting a room, receiving node replies with its participants
+	MessageTypeJoinRoom MessageType = "join_room"
+	// node stopped hosting a room
+	MessageTypeLeaveRoom MessageType = "leave_room"
+	// participant hosted by the sending node joined or changed
+	MessageTypeParticipantUpdate MessageType = "participant_update"
+	// participant hosted by the sending node left
+	MessageTypeParticipantLeft MessageType = "participant_left"
+	// sending node wants media of a published track
+	MessageTypeSubscribe MessageType = "subscribe"
+	// sending node no longer needs media of a published track
+	MessageTypeUnsubscribe MessageType = "unsubscribe"
+	// max quality subscribed to on the sending node, drives dynacast on the publisher's node
+	MessageTypeSubscribedQuality MessageType = "subscribed_quality"
+	// data packet to be delivered to participants on the receiving node
+	MessageTypeData MessageType = "data"
+)
+
+type Message struct {
+	Type   MessageType      `json:"type"`
+	NodeID livekit.NodeID   `json:"node_id,omitempty"`
+	Room   livekit.RoomName `json:"room,omitempty"`
+
+	// handshake
+	Nonce []byte `json:"nonce,omitempty"`
+	MAC   []byte `json:"mac,omitempty"`
+
+	Participant   *ParticipantState     `json:"participant,omitempty"`
+	ParticipantID livekit.ParticipantID `json:"participant_id,omitempty"`
+	Stream        *StreamRequest        `json:"stream,omitempty"`
+	Qualities     []*SubscribedQuality  `json:"qualities,omitempty"`
+
+	// marshalled livekit.DataPacket
+	Data []byte `json:"data,omitempty"`
+	// DataPacket_Kind
+	DataKind int32 `json:"data_kind,omitempty"`
+}
+
+// ParticipantState is the replicated state of a participant hosted by another node
+type ParticipantState struct {
+	// marshalled livekit.ParticipantInfo
+	Info []byte `json:"info"`
+	// marshalled livekit.SubscriptionPermission, empty when all participants are allowed
+	Permission []byte        `json:"permission,omitempty"`
+	Tracks     []*TrackState `json:"tracks,omitempty"`
+}
+
+type TrackState struct {
+	TrackID livekit.TrackID `json:"track_id"`
+	Codecs  []*CodecState   `json:"codecs,omitempty"`
+}
+
+type CodecState struct {
+	Codec            webrtc.RTPCodecParameters            `json:"codec"`
+	HeaderExtensions []webrtc.RTPHeaderExtensionParameter `json:"header_extensions,omitempty"`
+}
+
+// StreamRequest identifies media of a published track (codec) relayed to a node,
+// stream ids are allocated by the receiving node
+type StreamRequest struct {
+	StreamID      uint32                `json:"stream_id"`
+	ParticipantID livekit.ParticipantID `json:"participant_id"`
+	TrackID       livekit.TrackID       `json:"track_id"`
+	MimeType      string                `json:"mime_type"`
+}
+
+type SubscribedQuality struct {
+	TrackID  livekit.TrackID      `json:"track_id"`
+	MimeType string               `json:"mime_type"`
+	Quality  livekit.VideoQuality `json:"quality"`
+}
+
+func WriteMessage(w io.Writer, msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return writeFrame(w, data)
+}
+
+func ReadMessage(r io.Reader) (*Message, error) {
+	data, err := readFrame(r)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &Message{}
+	if err := json.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func writeFrame(w io.Writer, data []byte) error {
+	if len(data) > maxMessageSize {
+		return ErrMessageTooLarge
+	}
+
+	buf := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	copy(buf[4:], data)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readFrame(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxMessageSize {
+		return nil, ErrMessageTooLarge
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ---------------------------------------
+
+// Media and feedback are sent over UDP, each datagram is
+// kind (1 byte) + stream id (4 bytes, big endian) + layer (1 byte) + payload.
+// Between nodes, the payload is sealed with the session key, prefixed by its counter (8 bytes, big endian).
+type PacketKind uint8
+
+const (
+	PacketKindRTP PacketKind = iota + 1
+	// picture loss indication, sent by the receiving node, no payload
+	PacketKindPLI
+	// marshalled livekit.RTCPSenderReportState of the publisher's stream
+	PacketKindSenderReport
+)
+
+type Packet struct {
+	Kind     PacketKind
+	StreamID uint32
+	Layer    int32
+	Payload  []byte
+}
+
+func (p *Packet) Marshal() []byte {
+	buf := p.marshalHeader(make([]byte, packetHeaderSize, packetHeaderSize+len(p.Payload)))
+	return append(buf, p.Payload...)
+}
+
+// marshalHeader writes the header to the start of buf
+func (p *Packet) marshalHeader(buf []byte) []byte {
+	buf[0] = byte(p.Kind)
+	binary.BigEndian.PutUint32(buf[1:], p.StreamID)
+	buf[5] = byte(p.Layer)
+	return buf
+}
+
+// Unmarshal parses a packet, payload references buf
+func (p *Packet) Unmarshal(buf []byte) error {
+	if len(buf) < packetHeaderSize {
+		return ErrInvalidPacket
+	}
+
+	p.Kind = PacketKind(buf[0])
+	switch p.Kind {
+	case PacketKindRTP, PacketKindPLI, PacketKindSenderReport:
+	default:
+		return ErrInvalidPacket
+	}
+	p.StreamID = binary.BigEndian.Uint32(buf[1:])
+	p.Layer = int32(buf[5])
+	p.Payload = buf[packetHeaderSize:]
+	return nil
+}
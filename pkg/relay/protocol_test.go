@@ -0,0 +1,78 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relay
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	msg := &Message{
+		Type: MessageTypeParticipantUpdate,
+		Room: "room",
+		Participant: &ParticipantState{
+			Info: []byte{1, 2, 3},
+			Tracks: []*TrackState{
+				{
+					TrackID: "TR_video",
+					Codecs: []*CodecState{
+						{
+							Codec: webrtc.RTPCodecParameters{
+								RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000},
+								PayloadType:        96,
+							},
+							HeaderExtensions: []webrtc.RTPHeaderExtensionParameter{{URI: "urn:ietf:params:rtp-hdrext:sdes:mid", ID: 1}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteMessage(&buf, msg))
+	require.NoError(t, WriteMessage(&buf, &Message{Type: MessageTypeLeaveRoom, Room: "room"}))
+
+	decoded, err := ReadMessage(&buf)
+	require.NoError(t, err)
+	require.Equal(t, msg, decoded)
+
+	decoded, err = ReadMessage(&buf)
+	require.NoError(t, err)
+	require.Equal(t, MessageTypeLeaveRoom, decoded.Type)
+
+	_, err = ReadMessage(&buf)
+	require.Error(t, err)
+}
+
+func TestPacketRoundTrip(t *testing.T) {
+	pkt := &Packet{
+		Kind:     PacketKindRTP,
+		StreamID: 0x01020304,
+		Layer:    2,
+		Payload:  []byte{0x80, 0x60, 0x00, 0x01},
+	}
+
+	var decoded Packet
+	require.NoError(t, decoded.Unmarshal(pkt.Marshal()))
+	require.Equal(t, *pkt, decoded)
+
+	require.ErrorIs(t, decoded.Unmarshal([]byte{1, 2, 3}), ErrInvalidPacket)
+	require.ErrorIs(t, decoded.Unmarshal([]byte{0, 0, 0, 0, 1, 0}), ErrInvalidPacket)
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relay
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const (
+	// set of node_id hosting a room, keyed by room name
+	RoomNodesKeyPrefix = "room_relay_nodes:"
+)
+
+// Registry keeps track of the nodes hosting (part of) a room
+type Registry interface {
+	AddNode(ctx context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) error
+	RemoveNode(ctx context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) error
+	GetNodes(ctx context.Context, roomName livekit.RoomName) ([]livekit.NodeID, error)
+}
+
+func NewRegistry(rc redis.UniversalClient) Registry {
+	if rc != nil {
+		return NewRedisRegistry(rc)
+	}
+	return NewLocalRegistry()
+}
+
+// ---------------------------------------
+
+type LocalRegistry struct {
+	lock  sync.RWMutex
+	rooms map[livekit.RoomName][]livekit.NodeID
+}
+
+func NewLocalRegistry() *LocalRegistry {
+	return &LocalRegistry{
+		rooms: make(map[livekit.RoomName][]livekit.NodeID),
+	}
+}
+
+func (l *LocalRegistry) AddNode(_ context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if !slices.Contains(l.rooms[roomName], nodeID) {
+		l.rooms[roomName] = append(l.rooms[roomName], nodeID)
+	}
+	return nil
+}
+
+func (l *LocalRegistry) RemoveNode(_ context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	nodes := slices.DeleteFunc(l.rooms[roomName], func(n livekit.NodeID) bool { return n == nodeID })
+	if len(nodes) == 0 {
+		delete(l.rooms, roomName)
+	} else {
+		l.rooms[roomName] = nodes
+	}
+	return nil
+}
+
+func (l *LocalRegistry) GetNodes(_ context.Context, roomName livekit.RoomName) ([]livekit.NodeID, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	return slices.Clone(l.rooms[roomName]), nil
+}
+
+// ---------------------------------------
+
+type RedisRegistry struct {
+	rc redis.UniversalClient
+}
+
+func NewRedisRegistry(rc redis.UniversalClient) *RedisRegistry {
+	return &RedisRegistry{
+		rc: rc,
+	}
+}
+
+func (r *RedisRegistry) AddNode(ctx context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) error {
+	return r.rc.SAdd(ctx, RoomNodesKeyPrefix+string(roomName), string(nodeID)).Err()
+}
+
+func (r *RedisRegistry) RemoveNode(ctx context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) error {
+	return r.rc.SRem(ctx, RoomNodesKeyPrefix+string(roomName), string(nodeID)).Err()
+}
+
+func (r *RedisRegistry) GetNodes(ctx context.Context, roomName livekit.RoomName) ([]livekit.NodeID, error) {
+	ids, err := r.rc.SMembers(ctx, RoomNodesKeyPrefix+string(roomName)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]livekit.NodeID, 0, len(ids))
+	for _, id := range ids {
+		nodes = append(nodes, livekit.NodeID(id))
+	}
+	return nodes, nil
+}
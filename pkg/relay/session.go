@@ -0,0 +1,235 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relay
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// Nodes authenticate each other with the relay secret they share. The dialing node sends a hello with a nonce,
+// the listening node answers with a challenge carrying its nonce and a MAC proving it knows the secret,
+// the dialing node proves it as well with an auth message. Both derive session keys from the secret and nonces,
+// control messages and media sent by the dialing node on the session are sealed with them (AES-GCM).
+const (
+	handshakeNonceSize = 32
+	// explicit counter of sealed media packets, datagrams can be lost or reordered
+	packetCounterSize = 8
+	// tag of AES-GCM
+	aeadOverhead = 16
+	// sealed media packets are accepted once, and only when at most this many packets behind the latest one
+	replayWindowSize = 1024
+
+	labelListenerProof = "livekit relay listener"
+	labelDialerProof   = "livekit relay dialer"
+	labelControlKey    = "livekit relay control"
+	labelMediaKey      = "livekit relay media"
+)
+
+type handshake struct {
+	secret        []byte
+	dialer        livekit.NodeID
+	listener      livekit.NodeID
+	dialerNonce   []byte
+	listenerNonce []byte
+}
+
+func newHandshakeNonce() ([]byte, error) {
+	nonce := make([]byte, handshakeNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return nonce, nil
+}
+
+func (h *handshake) mac(label string) []byte {
+	mac := hmac.New(sha256.New, h.secret)
+	for _, field := range [][]byte{[]byte(label), []byte(h.dialer), []byte(h.listener), h.dialerNonce, h.listenerNonce} {
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(field)))
+		mac.Write(size[:])
+		mac.Write(field)
+	}
+	return mac.Sum(nil)
+}
+
+func (h *handshake) verify(label string, proof []byte) bool {
+	return hmac.Equal(h.mac(label), proof)
+}
+
+func (h *handshake) sessionKeys() (*sessionKeys, error) {
+	control, err := newAEAD(h.mac(labelControlKey))
+	if err != nil {
+		return nil, err
+	}
+	media, err := newAEAD(h.mac(labelMediaKey))
+	if err != nil {
+		return nil, err
+	}
+	return &sessionKeys{control: control, media: media}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+type sessionKeys struct {
+	control cipher.AEAD
+	media   cipher.AEAD
+}
+
+func counterNonce(aead cipher.AEAD, counter uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-packetCounterSize:], counter)
+	return nonce
+}
+
+// controlCipher seals or opens control messages of a session, in order as they are carried over TCP
+type controlCipher struct {
+	aead    cipher.AEAD
+	counter uint64
+}
+
+func (c *controlCipher) writeMessage(w io.Writer, msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	sealed := c.aead.Seal(nil, counterNonce(c.aead, c.counter), data, nil)
+	c.counter++
+	return writeFrame(w, sealed)
+}
+
+func (c *controlCipher) readMessage(r io.Reader) (*Message, error) {
+	sealed, err := readFrame(r)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.aead.Open(sealed[:0], counterNonce(c.aead, c.counter), sealed, nil)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+	c.counter++
+
+	msg := &Message{}
+	if err := json.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// mediaCipher seals media and feedback sent on a session, the packet header is authenticated but not encrypted
+type mediaCipher struct {
+	aead    cipher.AEAD
+	counter atomic.Uint64
+}
+
+func (c *mediaCipher) sealPacket(pkt *Packet) []byte {
+	counter := c.counter.Inc()
+	header := pkt.marshalHeader(make([]byte, packetHeaderSize+packetCounterSize, packetHeaderSize+packetCounterSize+len(pkt.Payload)+c.aead.Overhead()))
+	binary.BigEndian.PutUint64(header[packetHeaderSize:], counter)
+	return c.aead.Seal(header, counterNonce(c.aead, counter), pkt.Payload, header[:packetHeaderSize])
+}
+
+// mediaOpener opens media and feedback received on a session, rejecting packets which were opened already
+// or are too old to tell. Not safe for concurrent use.
+type mediaOpener struct {
+	aead   cipher.AEAD
+	window replayWindow
+}
+
+func newMediaOpener(aead cipher.AEAD) *mediaOpener {
+	return &mediaOpener{aead: aead}
+}
+
+// openPacket parses a sealed packet, payload is decrypted in place and references buf
+func (o *mediaOpener) openPacket(buf []byte, pkt *Packet) error {
+	if len(buf) < packetHeaderSize+packetCounterSize+o.aead.Overhead() {
+		return ErrInvalidPacket
+	}
+	if err := pkt.Unmarshal(buf[:packetHeaderSize]); err != nil {
+		return err
+	}
+
+	counter := binary.BigEndian.Uint64(buf[packetHeaderSize:])
+	if !o.window.isFresh(counter) {
+		return ErrReplayedPacket
+	}
+	sealed := buf[packetHeaderSize+packetCounterSize:]
+	payload, err := o.aead.Open(sealed[:0], counterNonce(o.aead, counter), sealed, buf[:packetHeaderSize])
+	if err != nil {
+		return ErrUnauthorized
+	}
+	// only authenticated counters move the window
+	o.window.accept(counter)
+	pkt.Payload = payload
+	return nil
+}
+
+// replayWindow is a sliding window over packet counters, one bit per counter, indexed by counter modulo window size
+type replayWindow struct {
+	latest uint64
+	seen   [replayWindowSize / 64]uint64
+}
+
+func (w *replayWindow) bit(counter uint64) (int, uint64) {
+	idx := counter % replayWindowSize
+	return int(idx / 64), 1 << (idx % 64)
+}
+
+func (w *replayWindow) isFresh(counter uint64) bool {
+	switch {
+	case counter == 0:
+		// counters start at 1
+		return false
+	case counter > w.latest:
+		return true
+	case w.latest-counter >= replayWindowSize:
+		return false
+	default:
+		word, mask := w.bit(counter)
+		return w.seen[word]&mask == 0
+	}
+}
+
+func (w *replayWindow) accept(counter uint64) {
+	if counter > w.latest {
+		if counter-w.latest >= replayWindowSize {
+			w.seen = [replayWindowSize / 64]uint64{}
+		} else {
+			for c := w.latest + 1; c < counter; c++ {
+				word, mask := w.bit(c)
+				w.seen[word] &^= mask
+			}
+		}
+		w.latest = counter
+	}
+	word, mask := w.bit(counter)
+	w.seen[word] |= mask
+}
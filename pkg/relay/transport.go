@@ -0,0 +1,510 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relay
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/livekit/mediatransportutil/pkg/bucket"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+const (
+	dialTimeout      = 5 * time.Second
+	handshakeTimeout = 10 * time.Second
+)
+
+var (
+	ErrTransportClosed = errors.New("relay transport closed")
+	ErrUnauthorized    = errors.New("relay peer not authorized")
+)
+
+type TransportParams struct {
+	NodeID livekit.NodeID
+	// address to listen on for both TCP and UDP, e. g. ":7890"
+	ListenAddress string
+	// shared by all nodes, authenticates them to each other and keys their sessions
+	Secret string
+	// returns relay address (host:port) of a node
+	ResolveNode func(nodeID livekit.NodeID) (string, error)
+	Logger      logger.Logger
+}
+
+type peer struct {
+	nodeID livekit.NodeID
+	conn   net.Conn
+	media  *mediaCipher
+
+	lock    sync.Mutex
+	control *controlCipher
+}
+
+func (p *peer) write(msg *Message) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.control.writeMessage(p.conn, msg)
+}
+
+type inboundSession struct {
+	nodeID livekit.NodeID
+	media  *mediaOpener
+}
+
+// Transport carries relay traffic between nodes. Control messages go over a TCP connection
+// dialed by the sending node, media and feedback go over UDP. Both are sealed with keys of the session
+// the sending node established when connecting, packets which cannot be opened or are replayed are dropped.
+type Transport struct {
+	params TransportParams
+
+	tcpListener net.Listener
+	udpConn     *net.UDPConn
+
+	// serializes connecting to peers, a peer sees a closed duplicate connection as the node going away
+	dialLock sync.Mutex
+
+	lock     sync.RWMutex
+	outbound map[livekit.NodeID]*peer
+	inbound  map[net.Conn]*inboundSession
+	// latest session of each connected node opens media it sends, used by the UDP worker only
+	mediaKeys map[livekit.NodeID]*mediaOpener
+	peerAddrs map[livekit.NodeID]*net.UDPAddr
+	udpPeers  map[string]livekit.NodeID
+	closed    bool
+
+	onMessage          func(from livekit.NodeID, msg *Message)
+	onPacket           func(from livekit.NodeID, pkt *Packet)
+	onPeerDisconnected func(nodeID livekit.NodeID)
+}
+
+func NewTransport(params TransportParams) *Transport {
+	return &Transport{
+		params:    params,
+		outbound:  make(map[livekit.NodeID]*peer),
+		inbound:   make(map[net.Conn]*inboundSession),
+		mediaKeys: make(map[livekit.NodeID]*mediaOpener),
+		peerAddrs: make(map[livekit.NodeID]*net.UDPAddr),
+		udpPeers:  make(map[string]livekit.NodeID),
+	}
+}
+
+func (t *Transport) OnMessage(f func(from livekit.NodeID, msg *Message)) {
+	t.lock.Lock()
+	t.onMessage = f
+	t.lock.Unlock()
+}
+
+func (t *Transport) OnPacket(f func(from livekit.NodeID, pkt *Packet)) {
+	t.lock.Lock()
+	t.onPacket = f
+	t.lock.Unlock()
+}
+
+// OnPeerDisconnected is called when the control connection from a node goes away
+func (t *Transport) OnPeerDisconnected(f func(nodeID livekit.NodeID)) {
+	t.lock.Lock()
+	t.onPeerDisconnected = f
+	t.lock.Unlock()
+}
+
+func (t *Transport) Start() error {
+	tcpListener, err := net.Listen("tcp", t.params.ListenAddress)
+	if err != nil {
+		return err
+	}
+
+	// use the same port for media, relevant when an ephemeral port was requested
+	udpAddr, err := net.ResolveUDPAddr("udp", tcpListener.Addr().String())
+	if err != nil {
+		_ = tcpListener.Close()
+		return err
+	}
+	udpConn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		_ = tcpListener.Close()
+		return err
+	}
+
+	t.tcpListener = tcpListener
+	t.udpConn = udpConn
+
+	t.params.Logger.Infow("relay transport started", "address", tcpListener.Addr().String())
+
+	go t.acceptWorker()
+	go t.udpWorker()
+	return nil
+}
+
+func (t *Transport) Addr() net.Addr {
+	if t.tcpListener == nil {
+		return nil
+	}
+	return t.tcpListener.Addr()
+}
+
+func (t *Transport) Stop() {
+	t.lock.Lock()
+	if t.closed {
+		t.lock.Unlock()
+		return
+	}
+	t.closed = true
+
+	conns := make([]net.Conn, 0, len(t.outbound)+len(t.inbound))
+	for _, p := range t.outbound {
+		conns = append(conns, p.conn)
+	}
+	for conn := range t.inbound {
+		conns = append(conns, conn)
+	}
+	t.lock.Unlock()
+
+	if t.tcpListener != nil {
+		_ = t.tcpListener.Close()
+	}
+	if t.udpConn != nil {
+		_ = t.udpConn.Close()
+	}
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+}
+
+// SendMessage delivers a control message to a node, connecting to it if needed
+func (t *Transport) SendMessage(nodeID livekit.NodeID, msg *Message) error {
+	p, err := t.getOrDialPeer(nodeID)
+	if err != nil {
+		return err
+	}
+
+	if err := p.write(msg); err != nil {
+		t.removeOutbound(p)
+		return err
+	}
+	return nil
+}
+
+// SendPacket sends media or feedback to a node over UDP, connecting to it if needed
+func (t *Transport) SendPacket(nodeID livekit.NodeID, pkt *Packet) error {
+	p, err := t.getOrDialPeer(nodeID)
+	if err != nil {
+		return err
+	}
+
+	t.lock.RLock()
+	addr := t.peerAddrs[nodeID]
+	t.lock.RUnlock()
+	if addr == nil {
+		if addr, err = t.resolvePeer(nodeID); err != nil {
+			return err
+		}
+	}
+
+	_, err = t.udpConn.WriteToUDP(p.media.sealPacket(pkt), addr)
+	return err
+}
+
+func (t *Transport) getOrDialPeer(nodeID livekit.NodeID) (*peer, error) {
+	t.lock.RLock()
+	p := t.outbound[nodeID]
+	closed := t.closed
+	t.lock.RUnlock()
+
+	if closed {
+		return nil, ErrTransportClosed
+	}
+	if p != nil {
+		return p, nil
+	}
+
+	t.dialLock.Lock()
+	defer t.dialLock.Unlock()
+
+	t.lock.RLock()
+	p = t.outbound[nodeID]
+	t.lock.RUnlock()
+	if p != nil {
+		return p, nil
+	}
+
+	addr, err := t.params.ResolveNode(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	keys, err := t.dialHandshake(conn, nodeID)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	p = &peer{
+		nodeID:  nodeID,
+		conn:    conn,
+		media:   &mediaCipher{aead: keys.media},
+		control: &controlCipher{aead: keys.control},
+	}
+	if _, err := t.resolvePeer(nodeID); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	t.lock.Lock()
+	if t.closed {
+		t.lock.Unlock()
+		_ = conn.Close()
+		return nil, ErrTransportClosed
+	}
+	t.outbound[nodeID] = p
+	t.lock.Unlock()
+
+	t.params.Logger.Debugw("connected to relay peer", "peerNodeID", nodeID, "address", addr)
+
+	// nothing is expected from the peer on this connection, read to detect it going away
+	go func() {
+		var buf [1]byte
+		_, _ = conn.Read(buf[:])
+		t.removeOutbound(p)
+	}()
+	return p, nil
+}
+
+func (t *Transport) removeOutbound(p *peer) {
+	t.lock.Lock()
+	if t.outbound[p.nodeID] == p {
+		delete(t.outbound, p.nodeID)
+	}
+	t.lock.Unlock()
+
+	_ = p.conn.Close()
+}
+
+func (t *Transport) resolvePeer(nodeID livekit.NodeID) (*net.UDPAddr, error) {
+	address, err := t.params.ResolveNode(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	t.lock.Lock()
+	t.peerAddrs[nodeID] = addr
+	t.udpPeers[addr.String()] = nodeID
+	t.lock.Unlock()
+	return addr, nil
+}
+
+func (t *Transport) acceptWorker() {
+	for {
+		conn, err := t.tcpListener.Accept()
+		if err != nil {
+			return
+		}
+
+		go t.handleInbound(conn)
+	}
+}
+
+func (t *Transport) handleInbound(conn net.Conn) {
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	nodeID, keys, err := t.acceptHandshake(conn)
+	if err != nil {
+		t.params.Logger.Warnw("rejecting relay peer", err, "remote", conn.RemoteAddr().String(), "peerNodeID", nodeID)
+		return
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	addr, err := t.resolvePeer(nodeID)
+	if err != nil {
+		t.params.Logger.Warnw("could not resolve relay peer", err, "peerNodeID", nodeID)
+		return
+	}
+	// accept media from the address the control connection came from as well
+	if tcpAddr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		srcAddr := &net.UDPAddr{IP: tcpAddr.IP, Port: addr.Port}
+		t.lock.Lock()
+		t.udpPeers[srcAddr.String()] = nodeID
+		t.lock.Unlock()
+	}
+
+	t.lock.Lock()
+	if t.closed {
+		t.lock.Unlock()
+		return
+	}
+	session := &inboundSession{nodeID: nodeID, media: newMediaOpener(keys.media)}
+	t.inbound[conn] = session
+	t.mediaKeys[nodeID] = session.media
+	t.lock.Unlock()
+
+	t.params.Logger.Debugw("relay peer connected", "peerNodeID", nodeID)
+
+	control := &controlCipher{aead: keys.control}
+	for {
+		msg, err := control.readMessage(conn)
+		if err != nil {
+			if errors.Is(err, ErrUnauthorized) {
+				t.params.Logger.Warnw("dropping relay peer, message could not be opened", err, "peerNodeID", nodeID)
+			}
+			break
+		}
+
+		t.lock.RLock()
+		onMessage := t.onMessage
+		t.lock.RUnlock()
+		if onMessage != nil {
+			onMessage(nodeID, msg)
+		}
+	}
+
+	t.lock.Lock()
+	delete(t.inbound, conn)
+	var latest *inboundSession
+	for _, s := range t.inbound {
+		if s.nodeID == nodeID {
+			latest = s
+		}
+	}
+	connected := latest != nil
+	if connected {
+		t.mediaKeys[nodeID] = latest.media
+	} else {
+		delete(t.mediaKeys, nodeID)
+	}
+	onPeerDisconnected := t.onPeerDisconnected
+	closed := t.closed
+	t.lock.Unlock()
+
+	t.params.Logger.Debugw("relay peer disconnected", "peerNodeID", nodeID)
+	if !connected && !closed && onPeerDisconnected != nil {
+		onPeerDisconnected(nodeID)
+	}
+}
+
+// dialHandshake authenticates a connection to a node and this node to it, returns keys of the session
+func (t *Transport) dialHandshake(conn net.Conn, nodeID livekit.NodeID) (*sessionKeys, error) {
+	nonce, err := newHandshakeNonce()
+	if err != nil {
+		return nil, err
+	}
+	if err := WriteMessage(conn, &Message{Type: MessageTypeHello, NodeID: t.params.NodeID, Nonce: nonce}); err != nil {
+		return nil, err
+	}
+
+	challenge, err := ReadMessage(conn)
+	if err != nil {
+		return nil, err
+	}
+	h := &handshake{
+		secret:        []byte(t.params.Secret),
+		dialer:        t.params.NodeID,
+		listener:      nodeID,
+		dialerNonce:   nonce,
+		listenerNonce: challenge.Nonce,
+	}
+	if challenge.Type != MessageTypeChallenge ||
+		challenge.NodeID != nodeID ||
+		len(challenge.Nonce) != handshakeNonceSize ||
+		!h.verify(labelListenerProof, challenge.MAC) {
+		return nil, ErrUnauthorized
+	}
+
+	if err := WriteMessage(conn, &Message{Type: MessageTypeAuth, MAC: h.mac(labelDialerProof)}); err != nil {
+		return nil, err
+	}
+	return h.sessionKeys()
+}
+
+// acceptHandshake authenticates a node connecting to this node and this node to it, returns keys of the session
+func (t *Transport) acceptHandshake(conn net.Conn) (livekit.NodeID, *sessionKeys, error) {
+	hello, err := ReadMessage(conn)
+	if err != nil {
+		return "", nil, err
+	}
+	if hello.Type != MessageTypeHello || hello.NodeID == "" || len(hello.Nonce) != handshakeNonceSize {
+		return hello.NodeID, nil, ErrUnauthorized
+	}
+
+	nonce, err := newHandshakeNonce()
+	if err != nil {
+		return hello.NodeID, nil, err
+	}
+	h := &handshake{
+		secret:        []byte(t.params.Secret),
+		dialer:        hello.NodeID,
+		listener:      t.params.NodeID,
+		dialerNonce:   hello.Nonce,
+		listenerNonce: nonce,
+	}
+	if err := WriteMessage(conn, &Message{
+		Type:   MessageTypeChallenge,
+		NodeID: t.params.NodeID,
+		Nonce:  nonce,
+		MAC:    h.mac(labelListenerProof),
+	}); err != nil {
+		return hello.NodeID, nil, err
+	}
+
+	auth, err := ReadMessage(conn)
+	if err != nil {
+		return hello.NodeID, nil, err
+	}
+	if auth.Type != MessageTypeAuth || !h.verify(labelDialerProof, auth.MAC) {
+		return hello.NodeID, nil, ErrUnauthorized
+	}
+
+	keys, err := h.sessionKeys()
+	return hello.NodeID, keys, err
+}
+
+func (t *Transport) udpWorker() {
+	buf := make([]byte, bucket.RTPMaxPktSize+packetHeaderSize+packetCounterSize+aeadOverhead)
+	for {
+		n, addr, err := t.udpConn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		t.lock.RLock()
+		nodeID, ok := t.udpPeers[addr.String()]
+		media := t.mediaKeys[nodeID]
+		onPacket := t.onPacket
+		t.lock.RUnlock()
+		if !ok || media == nil || onPacket == nil {
+			continue
+		}
+
+		var pkt Packet
+		if err := media.openPacket(buf[:n], &pkt); err != nil {
+			continue
+		}
+		// payload is only valid for the duration of the callback
+		onPacket(nodeID, &pkt)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relay
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+const testSecret = "secretsecretsecretsecretsecretsecret"
+
+type testNetwork struct {
+	lock       sync.Mutex
+	transports map[livekit.NodeID]*Transport
+}
+
+func (n *testNetwork) resolve(nodeID livekit.NodeID) (string, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	tr := n.transports[nodeID]
+	if tr == nil {
+		return "", errors.New("unknown node")
+	}
+	return tr.Addr().String(), nil
+}
+
+func (n *testNetwork) newTransport(t *testing.T, nodeID livekit.NodeID, secret string) *Transport {
+	tr := NewTransport(TransportParams{
+		NodeID:        nodeID,
+		ListenAddress: "127.0.0.1:0",
+		Secret:        secret,
+		ResolveNode:   n.resolve,
+		Logger:        logger.GetLogger(),
+	})
+	require.NoError(t, tr.Start())
+	t.Cleanup(tr.Stop)
+
+	n.lock.Lock()
+	n.transports[nodeID] = tr
+	n.lock.Unlock()
+	return tr
+}
+
+func TestTransport(t *testing.T) {
+	network := &testNetwork{transports: make(map[livekit.NodeID]*Transport)}
+	trA := network.newTransport(t, "node_a", testSecret)
+	trB := network.newTransport(t, "node_b", testSecret)
+
+	messages := make(chan *Message, 10)
+	trB.OnMessage(func(from livekit.NodeID, msg *Message) {
+		if from == "node_a" {
+			messages <- msg
+		}
+	})
+	packets := make(chan Packet, 10)
+	trB.OnPacket(func(from livekit.NodeID, pkt *Packet) {
+		if from != "node_a" {
+			return
+		}
+		p := *pkt
+		p.Payload = append([]byte(nil), pkt.Payload...)
+		packets <- p
+	})
+	disconnected := make(chan livekit.NodeID, 1)
+	trB.OnPeerDisconnected(func(nodeID livekit.NodeID) {
+		disconnected <- nodeID
+	})
+
+	require.NoError(t, trA.SendMessage("node_b", &Message{Type: MessageTypeJoinRoom, Room: "room"}))
+	select {
+	case msg := <-messages:
+		require.Equal(t, MessageTypeJoinRoom, msg.Type)
+		require.Equal(t, livekit.RoomName("room"), msg.Room)
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+	}
+
+	pkt := &Packet{Kind: PacketKindRTP, StreamID: 7, Layer: 1, Payload: []byte{1, 2, 3, 4}}
+	require.Eventually(t, func() bool {
+		require.NoError(t, trA.SendPacket("node_b", pkt))
+		select {
+		case received := <-packets:
+			require.Equal(t, *pkt, received)
+			return true
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// packets not sealed with the session key are dropped, even from the address of the peer
+	_, err := trA.udpConn.WriteToUDP((&Packet{Kind: PacketKindRTP, StreamID: 8, Payload: []byte{5}}).Marshal(), trB.udpConn.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+	select {
+	case <-packets:
+		t.Fatal("unsealed packet delivered")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	trA.Stop()
+	select {
+	case nodeID := <-disconnected:
+		require.Equal(t, livekit.NodeID("node_a"), nodeID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("disconnect not detected")
+	}
+}
+
+func TestTransportRejectsUnauthorizedPeer(t *testing.T) {
+	network := &testNetwork{transports: make(map[livekit.NodeID]*Transport)}
+	trA := network.newTransport(t, "node_a", "wrongsecretwrongsecretwrongsecret")
+	trB := network.newTransport(t, "node_b", testSecret)
+
+	messages := make(chan *Message, 10)
+	trB.OnMessage(func(_ livekit.NodeID, msg *Message) {
+		messages <- msg
+	})
+	trB.OnPacket(func(_ livekit.NodeID, _ *Packet) {
+		messages <- &Message{}
+	})
+
+	require.ErrorIs(t, trA.SendMessage("node_b", &Message{Type: MessageTypeJoinRoom, Room: "room"}), ErrUnauthorized)
+	require.ErrorIs(t, trA.SendPacket("node_b", &Packet{Kind: PacketKindPLI, StreamID: 1}), ErrUnauthorized)
+	select {
+	case <-messages:
+		t.Fatal("message from unauthorized peer delivered")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestSealedPacket(t *testing.T) {
+	h := &handshake{secret: []byte(testSecret), dialer: "node_a", listener: "node_b", dialerNonce: []byte{1}, listenerNonce: []byte{2}}
+	keys, err := h.sessionKeys()
+	require.NoError(t, err)
+	sender := &mediaCipher{aead: keys.media}
+
+	pkt := &Packet{Kind: PacketKindRTP, StreamID: 7, Layer: 2, Payload: []byte{1, 2, 3, 4}}
+	sealed := sender.sealPacket(pkt)
+	require.NotContains(t, string(sealed), string(pkt.Payload))
+
+	// header is authenticated
+	var opened Packet
+	tampered := append([]byte(nil), sealed...)
+	tampered[1] ^= 0xff
+	require.ErrorIs(t, newMediaOpener(keys.media).openPacket(tampered, &opened), ErrUnauthorized)
+
+	receiver := newMediaOpener(keys.media)
+	require.NoError(t, receiver.openPacket(append([]byte(nil), sealed...), &opened))
+	require.Equal(t, *pkt, opened)
+
+	// keys of another session do not open it
+	other := &handshake{secret: []byte(testSecret), dialer: "node_a", listener: "node_b", dialerNonce: []byte{1}, listenerNonce: []byte{3}}
+	otherKeys, err := other.sessionKeys()
+	require.NoError(t, err)
+	require.ErrorIs(t, newMediaOpener(otherKeys.media).openPacket(append([]byte(nil), sealed...), &opened), ErrUnauthorized)
+}
+
+func TestReplayedPacket(t *testing.T) {
+	h := &handshake{secret: []byte(testSecret), dialer: "node_a", listener: "node_b", dialerNonce: []byte{1}, listenerNonce: []byte{2}}
+	keys, err := h.sessionKeys()
+	require.NoError(t, err)
+	sender := &mediaCipher{aead: keys.media}
+	receiver := newMediaOpener(keys.media)
+
+	open := func(sealed []byte) error {
+		var opened Packet
+		return receiver.openPacket(append([]byte(nil), sealed...), &opened)
+	}
+
+	var sealed [][]byte
+	for i := range replayWindowSize + 10 {
+		sealed = append(sealed, sender.sealPacket(&Packet{Kind: PacketKindRTP, StreamID: 1, Payload: []byte{byte(i)}}))
+	}
+
+	// reordered packets are accepted once
+	require.NoError(t, open(sealed[1]))
+	require.NoError(t, open(sealed[0]))
+	require.ErrorIs(t, open(sealed[0]), ErrReplayedPacket)
+	require.ErrorIs(t, open(sealed[1]), ErrReplayedPacket)
+
+	// a tampered packet does not take the counter of the genuine one
+	tampered := append([]byte(nil), sealed[2]...)
+	tampered[len(tampered)-1] ^= 0xff
+	require.ErrorIs(t, open(tampered), ErrUnauthorized)
+	require.NoError(t, open(sealed[2]))
+
+	// packets too far behind the latest one are rejected
+	require.NoError(t, open(sealed[len(sealed)-1]))
+	require.ErrorIs(t, open(sealed[5]), ErrReplayedPacket)
+	require.NoError(t, open(sealed[15]))
+	require.ErrorIs(t, open(sealed[len(sealed)-1]), ErrReplayedPacket)
+}
+
+func TestLocalRegistry(t *testing.T) {
+	ctx := context.Background()
+	registry := NewLocalRegistry()
+
+	require.NoError(t, registry.AddNode(ctx, "room", "node_a"))
+	require.NoError(t, registry.AddNode(ctx, "room", "node_b"))
+	require.NoError(t, registry.AddNode(ctx, "room", "node_a"))
+
+	nodes, err := registry.GetNodes(ctx, "room")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []livekit.NodeID{"node_a", "node_b"}, nodes)
+
+	require.NoError(t, registry.RemoveNode(ctx, "room", "node_a"))
+	require.NoError(t, registry.RemoveNode(ctx, "room", "node_b"))
+	nodes, err = registry.GetNodes(ctx, "room")
+	require.NoError(t, err)
+	require.Empty(t, nodes)
+}
@@ -160,6 +160,14 @@ type MessageRouter interface {
 		roomName livekit.RoomName,
 		pi ParticipantInit,
 	) (res StartParticipantSignalResults, err error)
+
+	// StartParticipantSignalWithNodeID starts participant signal connection on the given node
+	StartParticipantSignalWithNodeID(
+		ctx context.Context,
+		roomName livekit.RoomName,
+		pi ParticipantInit,
+		nodeID livekit.NodeID,
+	) (res StartParticipantSignalResults, err error)
 }
 
 func CreateRouter(
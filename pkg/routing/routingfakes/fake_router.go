@@ -134,6 +134,22 @@ type FakeRouter struct {
 		result1 routing.StartParticipantSignalResults
 		result2 error
 	}
+	StartParticipantSignalWithNodeIDStub        func(context.Context, livekit.RoomName, routing.ParticipantInit, livekit.NodeID) (routing.StartParticipantSignalResults, error)
+	startParticipantSignalWithNodeIDMutex       sync.RWMutex
+	startParticipantSignalWithNodeIDArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 routing.ParticipantInit
+		arg4 livekit.NodeID
+	}
+	startParticipantSignalWithNodeIDReturns struct {
+		result1 routing.StartParticipantSignalResults
+		result2 error
+	}
+	startParticipantSignalWithNodeIDReturnsOnCall map[int]struct {
+		result1 routing.StartParticipantSignalResults
+		result2 error
+	}
 	StopStub        func()
 	stopMutex       sync.RWMutex
 	stopArgsForCall []struct {
@@ -765,6 +781,73 @@ func (fake *FakeRouter) StartParticipantSignalReturnsOnCall(i int, result1 routi
 	}{result1, result2}
 }
 
+func (fake *FakeRouter) StartParticipantSignalWithNodeID(arg1 context.Context, arg2 livekit.RoomName, arg3 routing.ParticipantInit, arg4 livekit.NodeID) (routing.StartParticipantSignalResults, error) {
+	fake.startParticipantSignalWithNodeIDMutex.Lock()
+	ret, specificReturn := fake.startParticipantSignalWithNodeIDReturnsOnCall[len(fake.startParticipantSignalWithNodeIDArgsForCall)]
+	fake.startParticipantSignalWithNodeIDArgsForCall = append(fake.startParticipantSignalWithNodeIDArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 routing.ParticipantInit
+		arg4 livekit.NodeID
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.StartParticipantSignalWithNodeIDStub
+	fakeReturns := fake.startParticipantSignalWithNodeIDReturns
+	fake.recordInvocation("StartParticipantSignalWithNodeID", []interface{}{arg1, arg2, arg3, arg4})
+	fake.startParticipantSignalWithNodeIDMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRouter) StartParticipantSignalWithNodeIDCallCount() int {
+	fake.startParticipantSignalWithNodeIDMutex.RLock()
+	defer fake.startParticipantSignalWithNodeIDMutex.RUnlock()
+	return len(fake.startParticipantSignalWithNodeIDArgsForCall)
+}
+
+func (fake *FakeRouter) StartParticipantSignalWithNodeIDCalls(stub func(context.Context, livekit.RoomName, routing.ParticipantInit, livekit.NodeID) (routing.StartParticipantSignalResults, error)) {
+	fake.startParticipantSignalWithNodeIDMutex.Lock()
+	defer fake.startParticipantSignalWithNodeIDMutex.Unlock()
+	fake.StartParticipantSignalWithNodeIDStub = stub
+}
+
+func (fake *FakeRouter) StartParticipantSignalWithNodeIDArgsForCall(i int) (context.Context, livekit.RoomName, routing.ParticipantInit, livekit.NodeID) {
+	fake.startParticipantSignalWithNodeIDMutex.RLock()
+	defer fake.startParticipantSignalWithNodeIDMutex.RUnlock()
+	argsForCall := fake.startParticipantSignalWithNodeIDArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeRouter) StartParticipantSignalWithNodeIDReturns(result1 routing.StartParticipantSignalResults, result2 error) {
+	fake.startParticipantSignalWithNodeIDMutex.Lock()
+	defer fake.startParticipantSignalWithNodeIDMutex.Unlock()
+	fake.StartParticipantSignalWithNodeIDStub = nil
+	fake.startParticipantSignalWithNodeIDReturns = struct {
+		result1 routing.StartParticipantSignalResults
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRouter) StartParticipantSignalWithNodeIDReturnsOnCall(i int, result1 routing.StartParticipantSignalResults, result2 error) {
+	fake.startParticipantSignalWithNodeIDMutex.Lock()
+	defer fake.startParticipantSignalWithNodeIDMutex.Unlock()
+	fake.StartParticipantSignalWithNodeIDStub = nil
+	if fake.startParticipantSignalWithNodeIDReturnsOnCall == nil {
+		fake.startParticipantSignalWithNodeIDReturnsOnCall = make(map[int]struct {
+			result1 routing.StartParticipantSignalResults
+			result2 error
+		})
+	}
+	fake.startParticipantSignalWithNodeIDReturnsOnCall[i] = struct {
+		result1 routing.StartParticipantSignalResults
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeRouter) Stop() {
 	fake.stopMutex.Lock()
 	fake.stopArgsForCall = append(fake.stopArgsForCall, struct {
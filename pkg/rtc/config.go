@@ -19,14 +19,15 @@ import (
 	"github.com/pion/webrtc/v4"
 
 	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
 	act "github.com/livekit/livekit-server/pkg/sfu/rtpextension/abscapturetime"
 	dd "github.com/livekit/livekit-server/pkg/sfu/rtpextension/dependencydescriptor"
+	fm "github.com/livekit/livekit-server/pkg/sfu/rtpextension/framemarking"
 	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
 )
 
 const (
-	frameMarkingURI        = "urn:ietf:params:rtp-hdrext:framemarking"
 	repairedRTPStreamIDURI = "urn:ietf:params:rtp-hdrext:sdes:repaired-rtp-stream-id"
 )
 
@@ -57,6 +58,8 @@ type RTCPFeedbackConfig struct {
 type DirectionConfig struct {
 	RTPHeaderExtension RTPHeaderExtensionConfig
 	RTCPFeedback       RTCPFeedbackConfig
+	// FEC generation for video, only for sending direction
+	FEC sfu.FECConfig
 }
 
 func NewWebRTCConfig(conf *config.Config) (*WebRTCConfig, error) {
@@ -80,6 +83,9 @@ func NewWebRTCConfig(conf *config.Config) (*WebRTCConfig, error) {
 		rtcConf.PacketBufferSizeAudio = rtcConf.PacketBufferSize
 	}
 
+	subscriberConfig := getSubscriberConfig(rtcConf.CongestionControl.UseSendSideBWEInterceptor || rtcConf.CongestionControl.UseSendSideBWE || rtcConf.CongestionControl.UseDelayBasedBWE)
+	subscriberConfig.FEC = rtcConf.FEC
+
 	return &WebRTCConfig{
 		WebRTCConfig: *webRTCConfig,
 		Receiver: ReceiverConfig{
@@ -87,7 +93,7 @@ func NewWebRTCConfig(conf *config.Config) (*WebRTCConfig, error) {
 			PacketBufferSizeAudio: rtcConf.PacketBufferSizeAudio,
 		},
 		Publisher:  getPublisherConfig(false),
-		Subscriber: getSubscriberConfig(rtcConf.CongestionControl.UseSendSideBWEInterceptor || rtcConf.CongestionControl.UseSendSideBWE),
+		Subscriber: subscriberConfig,
 	}, nil
 }
 
@@ -96,7 +102,9 @@ func (c *WebRTCConfig) UpdatePublisherConfig(consolidated bool) {
 }
 
 func (c *WebRTCConfig) UpdateSubscriberConfig(ccConf config.CongestionControlConfig) {
-	c.Subscriber = getSubscriberConfig(ccConf.UseSendSideBWEInterceptor || ccConf.UseSendSideBWE)
+	fecConfig := c.Subscriber.FEC
+	c.Subscriber = getSubscriberConfig(ccConf.UseSendSideBWEInterceptor || ccConf.UseSendSideBWE || ccConf.UseDelayBasedBWE)
+	c.Subscriber.FEC = fecConfig
 }
 
 func (c *WebRTCConfig) SetBufferFactory(factory *buffer.Factory) {
@@ -119,7 +127,7 @@ func getPublisherConfig(consolidated bool) DirectionConfig {
 					sdp.SDESRTPStreamIDURI,
 					sdp.TransportCCURI,
 					sdp.ABSSendTimeURI,
-					frameMarkingURI,
+					fm.FrameMarkingURI,
 					dd.ExtensionURI,
 					repairedRTPStreamIDURI,
 					act.AbsCaptureTimeURI,
@@ -152,7 +160,7 @@ func getPublisherConfig(consolidated bool) DirectionConfig {
 				sdp.SDESMidURI,
 				sdp.SDESRTPStreamIDURI,
 				sdp.TransportCCURI,
-				frameMarkingURI,
+				fm.FrameMarkingURI,
 				dd.ExtensionURI,
 				repairedRTPStreamIDURI,
 				act.AbsCaptureTimeURI,
@@ -32,6 +32,10 @@ var (
 	ErrEmptyParticipantID       = errors.New("participant ID cannot be empty")
 	ErrMissingGrants            = errors.New("VideoGrant is missing")
 	ErrInternalError            = errors.New("internal error")
+	ErrParticipantNotPending    = errors.New("participant is not waiting in the lobby")
+	ErrNoPublishRequest         = errors.New("participant has not requested to publish")
+	ErrMaxPublishersExceeded    = errors.New("room has reached its maximum number of publishers")
+	ErrParticipantNotFound      = errors.New("participant not found")
 
 	// Track subscription related
 	ErrNoTrackPermission         = errors.New("participant is not allowed to subscribe to this track")
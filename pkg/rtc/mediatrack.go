@@ -84,6 +84,7 @@ type MediaTrackParams struct {
 	ReceiverConfig                   ReceiverConfig
 	SubscriberConfig                 DirectionConfig
 	PLIThrottleConfig                sfu.PLIThrottleConfig
+	KeyFrameCacheConfig              sfu.KeyFrameCacheConfig
 	AudioConfig                      sfu.AudioConfig
 	VideoConfig                      config.VideoConfig
 	Telemetry                        telemetry.TelemetryService
@@ -333,8 +334,16 @@ func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track sfu.TrackRe
 							nowNTP := util.ToNtpTime(time.Now())
 							nowNTP32 := uint32(nowNTP >> 16)
 							ntpDiff := nowNTP32 - dlrrReport.LastRR - dlrrReport.DLRR
+							if int32(ntpDiff) < 0 {
+								// delay since last RRTR larger than elapsed time, bad report
+								continue
+							}
 							rtt := uint32(math.Ceil(float64(ntpDiff) * 1000.0 / 65536.0))
-							buff.SetRTT(rtt)
+							if wr, ok := t.MediaTrackReceiver.Receiver(mime.NormalizeMimeType(track.Codec().MimeType)).(*sfu.WebRTCReceiver); ok {
+								wr.SetUpstreamRTT(rtt)
+							} else {
+								buff.SetRTT(rtt)
+							}
 							t.rttFromXR.Store(true)
 							lastRR = dlrrReport.LastRR
 							break rttFromXR
@@ -415,6 +424,7 @@ func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track sfu.TrackRe
 			t.params.OnRTCP,
 			t.params.VideoConfig.StreamTrackerManager,
 			sfu.WithPliThrottleConfig(t.params.PLIThrottleConfig),
+			sfu.WithKeyFrameCacheConfig(t.params.KeyFrameCacheConfig),
 			sfu.WithAudioConfig(t.params.AudioConfig),
 			sfu.WithLoadBalanceThreshold(20),
 			sfu.WithForwardStats(t.params.ForwardStats),
@@ -623,7 +623,7 @@ func (t *MediaTrackReceiver) removeAllSubscribersForMime(mime mime.MimeType, isE
 	}
 }
 
-func (t *MediaTrackReceiver) RevokeDisallowedSubscribers(allowedSubscriberIdentities []livekit.ParticipantIdentity) []livekit.ParticipantIdentity {
+func (t *MediaTrackReceiver) RevokeDisallowedSubscribers(isAllowed func(sub types.LocalParticipant) bool) []livekit.ParticipantIdentity {
 	var revokedSubscriberIdentities []livekit.ParticipantIdentity
 
 	// LK-TODO: large number of subscribers needs to be solved for this loop
@@ -632,8 +632,7 @@ func (t *MediaTrackReceiver) RevokeDisallowedSubscribers(allowedSubscriberIdenti
 			continue
 		}
 
-		found := slices.Contains(allowedSubscriberIdentities, subTrack.SubscriberIdentity())
-		if !found {
+		if !isAllowed(subTrack.Subscriber()) {
 			t.params.Logger.Infow("revoking subscription",
 				"subscriber", subTrack.SubscriberIdentity(),
 				"subscriberID", subTrack.SubscriberID(),
@@ -0,0 +1,228 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/frostbyte73/core"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	// node channel capacity of a subscriber is updated only when it changes by more than this fraction,
+	// to avoid re-allocating tracks on every small fluctuation of demand
+	nodeBandwidthUpdateThreshold = 0.05
+)
+
+type NodeBandwidthArbiterParams struct {
+	Config config.NodeBandwidthConfig
+	// local participants of all rooms on the node
+	Participants func() []types.LocalParticipant
+	Logger       logger.Logger
+}
+
+// NodeBandwidthArbiter keeps aggregate egress of the node within its configured capacity. Stream allocators of
+// subscribers work independently, so when the node is short of capacity, it gives each subscriber a share of
+// the capacity as a ceiling on its channel capacity. Shares follow subscriber priority, higher priorities get their
+// full demand first, the rest is shared equally within the first priority that cannot be fully served and
+// lower priorities are limited to the configured minimum.
+type NodeBandwidthArbiter struct {
+	params     NodeBandwidthArbiterParams
+	priorities []nodeBandwidthPriority
+
+	// updated only by the worker
+	capacities    map[livekit.ParticipantID]int64
+	isConstrained bool
+
+	stopped core.Fuse
+}
+
+type nodeBandwidthPriority struct {
+	rule     *SubscriptionRule
+	priority int
+}
+
+type nodeBandwidthSubscriber struct {
+	participant types.LocalParticipant
+	priority    int
+	demand      int64
+	capacity    int64
+}
+
+func NewNodeBandwidthArbiter(params NodeBandwidthArbiterParams) (*NodeBandwidthArbiter, error) {
+	a := &NodeBandwidthArbiter{
+		params:     params,
+		capacities: make(map[livekit.ParticipantID]int64),
+	}
+	for i, p := range params.Config.Priorities {
+		rule, err := ParseSubscriptionRule(p.Subscribers)
+		if err != nil {
+			return nil, fmt.Errorf("node bandwidth priority %d: %w", i, err)
+		}
+		a.priorities = append(a.priorities, nodeBandwidthPriority{rule: rule, priority: p.Priority})
+	}
+	return a, nil
+}
+
+func (a *NodeBandwidthArbiter) Start() {
+	if a.params.Config.Capacity <= 0 {
+		return
+	}
+
+	a.params.Logger.Infow("starting node bandwidth arbitration", "capacity", a.params.Config.Capacity)
+	go a.worker()
+}
+
+func (a *NodeBandwidthArbiter) Stop() {
+	a.stopped.Break()
+}
+
+func (a *NodeBandwidthArbiter) worker() {
+	interval := a.params.Config.UpdateInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.stopped.Watch():
+			return
+		case <-ticker.C:
+			a.update()
+		}
+	}
+}
+
+func (a *NodeBandwidthArbiter) update() {
+	var subscribers []*nodeBandwidthSubscriber
+	totalDemand := int64(0)
+	for _, p := range a.params.Participants() {
+		if p.IsClosed() {
+			continue
+		}
+		s := &nodeBandwidthSubscriber{
+			participant: p,
+			priority:    a.getPriority(p),
+			demand:      p.GetSubscriberBandwidthDemand(),
+		}
+		totalDemand += s.demand
+		subscribers = append(subscribers, s)
+	}
+
+	isConstrained := totalDemand > a.params.Config.Capacity
+	if isConstrained != a.isConstrained {
+		a.isConstrained = isConstrained
+		a.params.Logger.Infow(
+			"node bandwidth arbitration changed",
+			"constrained", isConstrained,
+			"capacity", a.params.Config.Capacity,
+			"demand", totalDemand,
+			"numSubscribers", len(subscribers),
+		)
+	}
+	if isConstrained {
+		arbitrateNodeBandwidth(a.params.Config.Capacity, a.params.Config.MinSubscriberBitrate, subscribers)
+	}
+
+	capacities := make(map[livekit.ParticipantID]int64, len(subscribers))
+	for _, s := range subscribers {
+		pID := s.participant.ID()
+		capacities[pID] = s.capacity
+		if last, ok := a.capacities[pID]; ok && !isNodeBandwidthChanged(last, s.capacity) {
+			capacities[pID] = last
+			continue
+		}
+		s.participant.SetSubscriberNodeChannelCapacity(s.capacity)
+	}
+	a.capacities = capacities
+}
+
+func (a *NodeBandwidthArbiter) getPriority(p types.LocalParticipant) int {
+	for _, np := range a.priorities {
+		if np.rule.Matches(p) {
+			return np.priority
+		}
+	}
+	return 0
+}
+
+func isNodeBandwidthChanged(last int64, capacity int64) bool {
+	if last == 0 || capacity == 0 {
+		return last != capacity
+	}
+	diff := last - capacity
+	if diff < 0 {
+		diff = -diff
+	}
+	return float64(diff) > float64(last)*nodeBandwidthUpdateThreshold
+}
+
+// arbitrateNodeBandwidth sets capacity of subscribers to their share of the node capacity, serving higher
+// priorities first. Within the priority that cannot be fully served, subscribers needing less than an equal
+// share keep their demand and leave the remainder to others.
+func arbitrateNodeBandwidth(capacity int64, minBitrate int64, subscribers []*nodeBandwidthSubscriber) {
+	slices.SortStableFunc(subscribers, func(a, b *nodeBandwidthSubscriber) int {
+		if a.priority != b.priority {
+			return b.priority - a.priority
+		}
+		switch {
+		case a.demand < b.demand:
+			return -1
+		case a.demand > b.demand:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	remaining := capacity
+	for start := 0; start < len(subscribers); {
+		end := start
+		tierDemand := int64(0)
+		for end < len(subscribers) && subscribers[end].priority == subscribers[start].priority {
+			tierDemand += subscribers[end].demand
+			end++
+		}
+
+		tier := subscribers[start:end]
+		if tierDemand <= remaining {
+			for _, s := range tier {
+				s.capacity = s.demand
+			}
+			remaining -= tierDemand
+		} else {
+			for i, s := range tier {
+				s.capacity = min(s.demand, remaining/int64(len(tier)-i))
+				remaining -= s.capacity
+			}
+		}
+		start = end
+	}
+
+	for _, s := range subscribers {
+		// at least a bit, as no capacity means no ceiling
+		s.capacity = max(s.capacity, minBitrate, 1)
+	}
+}
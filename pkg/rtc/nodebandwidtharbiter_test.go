@@ -0,0 +1,95 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+)
+
+func TestNodeBandwidthArbiter(t *testing.T) {
+	newSubscriber := func(identity livekit.ParticipantIdentity, kind livekit.ParticipantInfo_Kind, demand int64) *typesfakes.FakeLocalParticipant {
+		p := NewMockParticipant(identity, types.CurrentProtocol, false, false, nil)
+		p.KindReturns(kind)
+		p.GetSubscriberBandwidthDemandReturns(demand)
+		return p
+	}
+
+	lastCapacity := func(p *typesfakes.FakeLocalParticipant) int64 {
+		require.NotZero(t, p.SetSubscriberNodeChannelCapacityCallCount())
+		return p.SetSubscriberNodeChannelCapacityArgsForCall(p.SetSubscriberNodeChannelCapacityCallCount() - 1)
+	}
+
+	recorder := newSubscriber("recorder", livekit.ParticipantInfo_EGRESS, 4_000_000)
+	viewer1 := newSubscriber("viewer1", livekit.ParticipantInfo_STANDARD, 3_000_000)
+	viewer2 := newSubscriber("viewer2", livekit.ParticipantInfo_STANDARD, 1_000_000)
+	viewer3 := newSubscriber("viewer3", livekit.ParticipantInfo_STANDARD, 2_000_000)
+	participants := []types.LocalParticipant{recorder, viewer1, viewer2, viewer3}
+
+	a, err := NewNodeBandwidthArbiter(NodeBandwidthArbiterParams{
+		Config: config.NodeBandwidthConfig{
+			Capacity:             10_000_000,
+			MinSubscriberBitrate: 100_000,
+			Priorities:           []config.NodeBandwidthPriority{{Subscribers: "kind == EGRESS", Priority: 10}},
+		},
+		Participants: func() []types.LocalParticipant { return participants },
+		Logger:       logger.GetLogger(),
+	})
+	require.NoError(t, err)
+
+	// within capacity, no ceiling
+	a.update()
+	for _, p := range participants {
+		require.Zero(t, lastCapacity(p.(*typesfakes.FakeLocalParticipant)))
+	}
+
+	// over capacity, recorder is served first, viewer2 needs less than an equal share of the rest
+	viewer3.GetSubscriberBandwidthDemandReturns(5_000_000)
+	a.update()
+	require.EqualValues(t, 4_000_000, lastCapacity(recorder))
+	require.EqualValues(t, 1_000_000, lastCapacity(viewer2))
+	require.EqualValues(t, 2_500_000, lastCapacity(viewer1))
+	require.EqualValues(t, 2_500_000, lastCapacity(viewer3))
+
+	// small change in demand does not update ceilings
+	numCalls := viewer1.SetSubscriberNodeChannelCapacityCallCount()
+	viewer2.GetSubscriberBandwidthDemandReturns(1_020_000)
+	a.update()
+	require.Equal(t, numCalls, viewer1.SetSubscriberNodeChannelCapacityCallCount())
+
+	// lower priority is limited to the minimum when higher priority takes all capacity
+	recorder.GetSubscriberBandwidthDemandReturns(12_000_000)
+	a.update()
+	require.EqualValues(t, 10_000_000, lastCapacity(recorder))
+	require.EqualValues(t, 100_000, lastCapacity(viewer1))
+	require.EqualValues(t, 100_000, lastCapacity(viewer2))
+
+	t.Run("invalid priority rule", func(t *testing.T) {
+		_, err := NewNodeBandwidthArbiter(NodeBandwidthArbiterParams{
+			Config: config.NodeBandwidthConfig{
+				Priorities: []config.NodeBandwidthPriority{{Subscribers: "kind == ROBOT", Priority: 1}},
+			},
+		})
+		require.ErrorIs(t, err, ErrInvalidSubscriptionRule)
+	})
+}
@@ -172,51 +172,54 @@ type ParticipantParams struct {
 	Telemetry               telemetry.TelemetryService
 	Trailer                 []byte
 	PLIThrottleConfig       sfu.PLIThrottleConfig
+	KeyFrameCacheConfig     sfu.KeyFrameCacheConfig
 	CongestionControlConfig config.CongestionControlConfig
 	// codecs that are enabled for this room
-	PublishEnabledCodecs            []*livekit.Codec
-	SubscribeEnabledCodecs          []*livekit.Codec
-	Logger                          logger.Logger
-	LoggerResolver                  logger.DeferredFieldResolver
-	Reporter                        roomobs.ParticipantSessionReporter
-	ReporterResolver                roomobs.ParticipantReporterResolver
-	SimTracks                       map[uint32]interceptor.SimulcastTrackInfo
-	Grants                          *auth.ClaimGrants
-	InitialVersion                  uint32
-	ClientConf                      *livekit.ClientConfiguration
-	ClientInfo                      ClientInfo
-	Region                          string
-	Migration                       bool
-	Reconnect                       bool
-	AdaptiveStream                  bool
-	AllowTCPFallback                bool
-	TCPFallbackRTTThreshold         int
-	AllowUDPUnstableFallback        bool
-	TURNSEnabled                    bool
-	ParticipantListener             types.LocalParticipantListener
-	ParticipantHelper               types.LocalParticipantHelper
-	DisableSupervisor               bool
-	ReconnectOnPublicationError     bool
-	ReconnectOnSubscriptionError    bool
-	ReconnectOnDataChannelError     bool
-	VersionGenerator                utils.TimedVersionGenerator
-	DisableDynacast                 bool
-	SubscriberAllowPause            bool
-	SubscriptionLimitAudio          int32
-	SubscriptionLimitVideo          int32
-	PlayoutDelay                    *livekit.PlayoutDelay
-	SyncStreams                     bool
-	ForwardStats                    *sfu.ForwardStats
-	DisableSenderReportPassThrough  bool
-	MetricConfig                    metric.MetricConfig
-	UseOneShotSignallingMode        bool
-	EnableMetrics                   bool
-	DataChannelMaxBufferedAmount    uint64
-	DatachannelSlowThreshold        int
-	DatachannelLossyTargetLatency   time.Duration
-	FireOnTrackBySdp                bool
-	DisableCodecRegression          bool
-	LastPubReliableSeq              uint32
+	PublishEnabledCodecs           []*livekit.Codec
+	SubscribeEnabledCodecs         []*livekit.Codec
+	Logger                         logger.Logger
+	LoggerResolver                 logger.DeferredFieldResolver
+	Reporter                       roomobs.ParticipantSessionReporter
+	ReporterResolver               roomobs.ParticipantReporterResolver
+	SimTracks                      map[uint32]interceptor.SimulcastTrackInfo
+	Grants                         *auth.ClaimGrants
+	InitialVersion                 uint32
+	ClientConf                     *livekit.ClientConfiguration
+	ClientInfo                     ClientInfo
+	Region                         string
+	Migration                      bool
+	Reconnect                      bool
+	AdaptiveStream                 bool
+	AllowTCPFallback               bool
+	TCPFallbackRTTThreshold        int
+	AllowUDPUnstableFallback       bool
+	TURNSEnabled                   bool
+	ParticipantListener            types.LocalParticipantListener
+	ParticipantHelper              types.LocalParticipantHelper
+	DisableSupervisor              bool
+	ReconnectOnPublicationError    bool
+	ReconnectOnSubscriptionError   bool
+	ReconnectOnDataChannelError    bool
+	VersionGenerator               utils.TimedVersionGenerator
+	DisableDynacast                bool
+	SubscriberAllowPause           bool
+	SubscriptionLimitAudio         int32
+	SubscriptionLimitVideo         int32
+	PlayoutDelay                   *livekit.PlayoutDelay
+	SyncStreams                    bool
+	ForwardStats                   *sfu.ForwardStats
+	DisableSenderReportPassThrough bool
+	MetricConfig                   metric.MetricConfig
+	UseOneShotSignallingMode       bool
+	EnableMetrics                  bool
+	DataChannelMaxBufferedAmount   uint64
+	DatachannelSlowThreshold       int
+	DatachannelLossyTargetLatency  time.Duration
+	FireOnTrackBySdp               bool
+	DisableCodecRegression         bool
+	LastPubReliableSeq             uint32
+	// versions of metadata and attributes the session continues from, after a resume or from an earlier session
+	MetadataVersions                *types.MetadataVersions
 	Country                         string
 	PreferVideoSizeFromMedia        bool
 	UseSinglePeerConnection         bool
@@ -241,6 +244,9 @@ type ParticipantImpl struct {
 
 	state        atomic.Value // livekit.ParticipantInfo_State
 	disconnected chan struct{}
+	// primary transport connected with its data channels open, and listener told once the participant is active as well
+	primaryEstablished       atomic.Bool
+	fullyEstablishedNotified atomic.Bool
 
 	grants      atomic.Pointer[auth.ClaimGrants]
 	isPublisher atomic.Bool
@@ -279,6 +285,8 @@ type ParticipantImpl struct {
 	icQueue [2]atomic.Pointer[webrtc.ICECandidate]
 
 	requireBroadcast bool
+	// versions of metadata and attributes, guarded by lock
+	versions participantVersions
 	// queued participant updates before join response is sent
 	// guarded by updateLock
 	queuedUpdates []*livekit.ParticipantInfo
@@ -305,6 +313,9 @@ type ParticipantImpl struct {
 	migrateState                atomic.Value // types.MigrateState
 	migratedTracksPublishedFuse core.Fuse
 
+	// ceiling on quality of subscribed video, set by room admins
+	subscriberMaxVideoQuality atomic.Value // livekit.VideoQuality
+
 	onClose            map[string]func(types.LocalParticipant)
 	onClaimsChanged    func(participant types.LocalParticipant)
 	onICEConfigChanged func(participant types.LocalParticipant, iceConfig *livekit.ICEConfig)
@@ -369,6 +380,7 @@ func NewParticipant(params ParticipantParams) (*ParticipantImpl, error) {
 		telemetryGuard:                &telemetry.ReferenceGuard{},
 		nextSubscribedDataTrackHandle: uint16(rand.Intn(256)),
 		requireBroadcast:              params.Grants.Metadata != "" || len(params.Grants.Attributes) != 0,
+		versions:                      newParticipantVersions(params.MetadataVersions),
 	}
 	p.setupSignalling()
 
@@ -491,6 +503,26 @@ func (p *ParticipantImpl) GetPacer() pacer.Pacer {
 	return p.TransportManager.GetSubscriberPacer()
 }
 
+// SetSubscriberMaxVideoQuality limits the quality of all subscribed video tracks irrespective of subscriber settings,
+// VideoQuality_HIGH removes the limit
+func (p *ParticipantImpl) SetSubscriberMaxVideoQuality(quality livekit.VideoQuality) {
+	if prev := p.subscriberMaxVideoQuality.Swap(quality); prev == quality {
+		return
+	}
+
+	p.subLogger.Debugw("setting subscriber max video quality", "quality", quality)
+	for _, st := range p.SubscriptionManager.GetSubscribedTracks() {
+		st.UpdateVideoLayer()
+	}
+}
+
+func (p *ParticipantImpl) GetSubscriberMaxVideoQuality() livekit.VideoQuality {
+	if quality, ok := p.subscriberMaxVideoQuality.Load().(livekit.VideoQuality); ok {
+		return quality
+	}
+	return livekit.VideoQuality_HIGH
+}
+
 func (p *ParticipantImpl) GetDisableSenderReportPassThrough() bool {
 	return p.params.DisableSenderReportPassThrough
 }
@@ -616,10 +648,13 @@ func (p *ParticipantImpl) checkMetadataLimits(
 	return nil
 }
 
-func (p *ParticipantImpl) UpdateMetadata(update *livekit.UpdateParticipantMetadata, fromAdmin bool) error {
+// UpdateMetadata applies an update of the participant itself or of a room admin, when expected versions are given
+// it is applied only if they match. Updates of the participant carry them in ExpectedVersionsAttribute.
+func (p *ParticipantImpl) UpdateMetadata(update *livekit.UpdateParticipantMetadata, fromAdmin bool, expected *types.ExpectedVersions) error {
 	lgr := p.params.Logger.WithUnlikelyValues(
 		"update", logger.Proto(update),
 		"fromAdmin", fromAdmin,
+		"expected", expected,
 	)
 	lgr.Debugw("updating participant metadata")
 
@@ -647,7 +682,24 @@ func (p *ParticipantImpl) UpdateMetadata(update *livekit.UpdateParticipantMetada
 		return sendRequestResponse()
 	}
 
-	if err = p.checkMetadataLimits(update.Name, update.Metadata, update.Attributes); err != nil {
+	attributes := update.Attributes
+	if value, ok := attributes[ExpectedVersionsAttribute]; ok {
+		if fromAdmin {
+			requestResponse.Reason = livekit.RequestResponse_NOT_ALLOWED
+			requestResponse.Message = "expected versions attribute is reserved"
+			err = ErrVersionsAttributeNotAllowed
+			return sendRequestResponse()
+		}
+		if expected, err = ParseExpectedVersions(value); err != nil {
+			requestResponse.Reason = livekit.RequestResponse_UNCLASSIFIED_ERROR
+			requestResponse.Message = err.Error()
+			return sendRequestResponse()
+		}
+		attributes = maps.Clone(attributes)
+		delete(attributes, ExpectedVersionsAttribute)
+	}
+
+	if err = p.checkMetadataLimits(update.Name, update.Metadata, attributes); err != nil {
 		switch err {
 		case signalling.ErrNameExceedsLimits:
 			requestResponse.Reason = livekit.RequestResponse_LIMIT_EXCEEDED
@@ -664,15 +716,28 @@ func (p *ParticipantImpl) UpdateMetadata(update *livekit.UpdateParticipantMetada
 		return sendRequestResponse()
 	}
 
-	if update.Name != "" {
-		p.SetName(update.Name)
-	}
+	var metadata *string
 	if update.Metadata != "" {
-		p.SetMetadata(update.Metadata)
+		metadata = &update.Metadata
 	}
-	if update.Attributes != nil {
-		p.SetAttributes(update.Attributes)
+	versions, err := p.updateMetadataAndAttributes(metadata, attributes, expected)
+	if err != nil {
+		requestResponse.Reason = livekit.RequestResponse_UNCLASSIFIED_ERROR
+		requestResponse.Message = err.Error()
+		var conflictErr *MetadataConflictError
+		if errors.As(err, &conflictErr) {
+			requestResponse.Message = (&MetadataVersionsMessage{
+				Error:     ErrMetadataConflict.Error(),
+				Conflicts: conflictErr.Conflicts,
+				Versions:  conflictErr.Versions,
+			}).String()
+		}
+		return sendRequestResponse()
+	}
+	if update.Name != "" {
+		p.SetName(update.Name)
 	}
+	requestResponse.Message = (&MetadataVersionsMessage{Versions: versions}).String()
 	return sendRequestResponse()
 }
 
@@ -702,52 +767,64 @@ func (p *ParticipantImpl) SetName(name string) {
 
 // SetMetadata attaches metadata to the participant
 func (p *ParticipantImpl) SetMetadata(metadata string) {
-	p.lock.Lock()
-	grants := p.grants.Load()
-	if grants.Metadata == metadata {
-		p.lock.Unlock()
-		return
-	}
-
-	grants = grants.Clone()
-	grants.Metadata = metadata
-	p.grants.Store(grants)
-	p.requireBroadcast = p.requireBroadcast || metadata != ""
-	p.dirty.Store(true)
-
-	onClaimsChanged := p.onClaimsChanged
-	p.lock.Unlock()
-
-	p.listener().OnParticipantUpdate(p)
-
-	if onClaimsChanged != nil {
-		onClaimsChanged(p)
-	}
+	_, _ = p.updateMetadataAndAttributes(&metadata, nil, nil)
 }
 
 func (p *ParticipantImpl) SetAttributes(attrs map[string]string) {
 	if len(attrs) == 0 {
 		return
 	}
+	_, _ = p.updateMetadataAndAttributes(nil, attrs, nil)
+}
+
+// updateMetadataAndAttributes sets metadata, when not nil, and attributes together, attributes with an empty value are deleted.
+// When expected versions are given, nothing is updated unless all of them match. Returns the versions after the update.
+func (p *ParticipantImpl) updateMetadataAndAttributes(metadata *string, attrs map[string]string, expected *types.ExpectedVersions) (types.MetadataVersions, error) {
 	p.lock.Lock()
-	grants := p.grants.Load().Clone()
-	if grants.Attributes == nil {
-		grants.Attributes = make(map[string]string)
+	if err := p.versions.check(expected); err != nil {
+		p.lock.Unlock()
+		return types.MetadataVersions{}, err
 	}
-	var keysToDelete []string
+
+	grants := p.grants.Load()
+	metadataChanged := metadata != nil && *metadata != grants.Metadata
+	var setKeys, deletedKeys []string
 	for k, v := range attrs {
-		if v == "" {
-			keysToDelete = append(keysToDelete, k)
-		} else {
-			grants.Attributes[k] = v
+		current, ok := grants.Attributes[k]
+		switch {
+		case v == "" && ok:
+			deletedKeys = append(deletedKeys, k)
+		case v != "" && v != current:
+			setKeys = append(setKeys, k)
 		}
 	}
-	for _, k := range keysToDelete {
-		delete(grants.Attributes, k)
+	if !metadataChanged && len(setKeys) == 0 && len(deletedKeys) == 0 {
+		versions := p.versions.clone()
+		p.lock.Unlock()
+		return versions, nil
+	}
+
+	grants = grants.Clone()
+	if metadataChanged {
+		grants.Metadata = *metadata
+		p.requireBroadcast = p.requireBroadcast || *metadata != ""
+	}
+	if len(setKeys) != 0 || len(deletedKeys) != 0 {
+		if grants.Attributes == nil {
+			grants.Attributes = make(map[string]string)
+		}
+		for _, k := range setKeys {
+			grants.Attributes[k] = attrs[k]
+		}
+		for _, k := range deletedKeys {
+			delete(grants.Attributes, k)
+		}
+		p.requireBroadcast = true
 	}
+	p.versions.update(metadataChanged, append(setKeys, deletedKeys...))
+	versions := p.versions.clone()
 
 	p.grants.Store(grants)
-	p.requireBroadcast = true // already checked above
 	p.dirty.Store(true)
 
 	onClaimsChanged := p.onClaimsChanged
@@ -758,6 +835,15 @@ func (p *ParticipantImpl) SetAttributes(attrs map[string]string) {
 	if onClaimsChanged != nil {
 		onClaimsChanged(p)
 	}
+	return versions, nil
+}
+
+// MetadataVersions returns the versions of metadata and attributes of the participant
+func (p *ParticipantImpl) MetadataVersions() types.MetadataVersions {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.versions.clone()
 }
 
 func (p *ParticipantImpl) ClaimGrants() *auth.ClaimGrants {
@@ -1367,6 +1453,10 @@ func (p *ParticipantImpl) IsReconnect() bool {
 	return p.params.Reconnect
 }
 
+func (p *ParticipantImpl) IsMigration() bool {
+	return p.params.Migration
+}
+
 func (p *ParticipantImpl) Close(sendLeave bool, reason types.ParticipantCloseReason, isExpectedToResume bool) error {
 	if p.isClosed.Swap(true) {
 		// already closed
@@ -2289,7 +2379,20 @@ func (p *ParticipantImpl) handlePendingRemoteTracks() {
 }
 
 func (p *ParticipantImpl) onReceivedDataMessage(kind livekit.DataPacket_Kind, data []byte) {
-	if p.IsDisconnected() || !p.CanPublishData() {
+	if p.IsDisconnected() {
+		return
+	}
+	if !p.CanPublishData() {
+		// asking for permission to publish and updating room state do not require data permission
+		dp := &livekit.DataPacket{}
+		if err := proto.Unmarshal(data, dp); err == nil {
+			switch dp.GetUser().GetTopic() {
+			case PublishRequestTopic:
+				p.handlePublishPermissionRequest(dp.GetUser())
+			case RoomStateTopic:
+				p.handleRoomStateRequest(dp.GetUser())
+			}
+		}
 		return
 	}
 
@@ -2372,6 +2475,15 @@ func (p *ParticipantImpl) handleReceivedDataMessage(kind livekit.DataPacket_Kind
 			return
 		}
 		u := payload.User
+		switch u.GetTopic() {
+		case PublishRequestTopic:
+			// handled by the server, not forwarded
+			p.handlePublishPermissionRequest(u)
+			return
+		case RoomStateTopic:
+			p.handleRoomStateRequest(u)
+			return
+		}
 		if p.Hidden() {
 			u.ParticipantSid = ""
 			u.ParticipantIdentity = ""
@@ -2501,6 +2613,26 @@ func (p *ParticipantImpl) handleReceivedDataMessage(kind livekit.DataPacket_Kind
 	}
 }
 
+func (p *ParticipantImpl) handlePublishPermissionRequest(u *livekit.UserPacket) {
+	req, err := ParsePublishRequestMessage(u.Payload)
+	if err != nil {
+		p.pubLogger.Infow("invalid publish request", "error", err)
+		return
+	}
+
+	p.listener().OnPublishPermissionRequest(p, req)
+}
+
+func (p *ParticipantImpl) handleRoomStateRequest(u *livekit.UserPacket) {
+	req, err := ParseRoomStateRequest(u.Payload)
+	if err != nil {
+		p.pubLogger.Infow("invalid room state request", "error", err)
+		return
+	}
+
+	p.listener().OnRoomStateRequest(p, req)
+}
+
 func (p *ParticipantImpl) onReceivedDataMessageUnlabeled(data []byte) {
 	if p.IsDisconnected() || !p.CanPublishData() {
 		return
@@ -2556,10 +2688,25 @@ func (p *ParticipantImpl) onPrimaryTransportInitialConnected() {
 		prometheus.RecordSessionStartTime(int(p.ProtocolVersion()), time.Since(p.params.SessionStartTime))
 	}
 	p.updateState(livekit.ParticipantInfo_ACTIVE)
+	p.maybeNotifyFullyEstablished()
 }
 
 func (p *ParticipantImpl) onPrimaryTransportFullyEstablished() {
 	p.replayJoiningReliableMessages()
+	p.primaryEstablished.Store(true)
+	p.maybeNotifyFullyEstablished()
+}
+
+// IsFullyEstablished returns true once the participant is active and its data channels are open
+func (p *ParticipantImpl) IsFullyEstablished() bool {
+	return p.primaryEstablished.Load() && p.State() == livekit.ParticipantInfo_ACTIVE
+}
+
+// data channels may open before the state changes to active, the listener is told once both happened
+func (p *ParticipantImpl) maybeNotifyFullyEstablished() {
+	if p.IsFullyEstablished() && !p.fullyEstablishedNotified.Swap(true) {
+		go p.listener().OnFullyEstablished(p)
+	}
 }
 
 func (p *ParticipantImpl) clearDisconnectTimer() {
@@ -3296,6 +3443,7 @@ func (p *ParticipantImpl) addMediaTrack(signalCid string, ti *livekit.TrackInfo)
 		Reporter:              p.params.Reporter.WithTrack(ti.Sid),
 		SubscriberConfig:      p.params.Config.Subscriber,
 		PLIThrottleConfig:     p.params.PLIThrottleConfig,
+		KeyFrameCacheConfig:   p.params.KeyFrameCacheConfig,
 		SimTracks:             p.params.SimTracks,
 		OnRTCP:                p.postRtcp,
 		ForwardStats:          p.params.ForwardStats,
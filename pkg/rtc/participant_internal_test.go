@@ -15,6 +15,7 @@
 package rtc
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
@@ -78,6 +79,26 @@ func TestIsReady(t *testing.T) {
 	}
 }
 
+func TestFullyEstablished(t *testing.T) {
+	p := newParticipantForTest("test")
+	listener := &typesfakes.FakeLocalParticipantListener{}
+	p.setListener(listener)
+
+	// data channels opened before the state changed to active
+	p.state.Store(livekit.ParticipantInfo_JOINED)
+	p.onPrimaryTransportFullyEstablished()
+	require.False(t, p.IsFullyEstablished())
+
+	p.onPrimaryTransportInitialConnected()
+	require.True(t, p.IsFullyEstablished())
+	require.Eventually(t, func() bool { return listener.OnFullyEstablishedCallCount() == 1 }, time.Second, 10*time.Millisecond)
+
+	// told once, e.g. not again after an ICE restart
+	p.onPrimaryTransportFullyEstablished()
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, 1, listener.OnFullyEstablishedCallCount())
+}
+
 func TestTrackPublishing(t *testing.T) {
 	t.Run("should send the correct events", func(t *testing.T) {
 		p := newParticipantForTest("test")
@@ -252,6 +273,122 @@ func TestOutOfOrderUpdates(t *testing.T) {
 	require.Equal(t, "second update", sent.GetUpdate().Participants[0].Metadata)
 }
 
+func TestMetadataVersions(t *testing.T) {
+	expected := func(metadata *uint64, attributes map[string]uint64) string {
+		data, err := json.Marshal(&types.ExpectedVersions{Metadata: metadata, Attributes: attributes})
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("updates increment versions of what they change", func(t *testing.T) {
+		p := newParticipantForTest("test")
+
+		require.NoError(t, p.UpdateMetadata(&livekit.UpdateParticipantMetadata{
+			Metadata:   "m1",
+			Attributes: map[string]string{"a": "1", "b": "1"},
+		}, true, nil))
+		require.Equal(t, types.MetadataVersions{Metadata: 1, Attributes: map[string]uint64{"a": 1, "b": 1}}, p.MetadataVersions())
+
+		p.SetAttributes(map[string]string{"a": "1", "b": "2"})
+		require.Equal(t, types.MetadataVersions{Metadata: 1, Attributes: map[string]uint64{"a": 1, "b": 2}}, p.MetadataVersions())
+
+		// deleted attributes keep their version
+		p.SetAttributes(map[string]string{"a": ""})
+		p.SetMetadata("m1")
+		require.Equal(t, types.MetadataVersions{Metadata: 1, Attributes: map[string]uint64{"a": 2, "b": 2}}, p.MetadataVersions())
+		require.Equal(t, map[string]string{"b": "2"}, p.ClaimGrants().Attributes)
+		require.Equal(t, map[string]string{"b": "2"}, p.ToProto().Attributes)
+	})
+
+	t.Run("update is applied only when expected versions match", func(t *testing.T) {
+		p := newParticipantForTest("test")
+		p.SetAttributes(map[string]string{"slide": "1"})
+
+		update := func(expectedSlide uint64) error {
+			zero := uint64(0)
+			return p.UpdateMetadata(&livekit.UpdateParticipantMetadata{
+				Metadata: "presenting",
+				Attributes: map[string]string{
+					"slide": "2",
+					"hand":  "up",
+				},
+			}, true, &types.ExpectedVersions{Metadata: &zero, Attributes: map[string]uint64{"slide": expectedSlide, "hand": 0}})
+		}
+
+		err := update(0)
+		var conflictErr *MetadataConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		require.ErrorIs(t, err, ErrMetadataConflict)
+		require.Equal(t, []*MetadataConflict{{Key: "slide", Version: 1}}, conflictErr.Conflicts)
+		require.Equal(t, types.MetadataVersions{Attributes: map[string]uint64{"slide": 1}}, conflictErr.Versions)
+		require.Equal(t, `metadata version conflict: attribute "slide" is at version 1`, err.Error())
+		require.Empty(t, p.ClaimGrants().Metadata)
+		require.Equal(t, map[string]string{"slide": "1"}, p.ClaimGrants().Attributes)
+
+		require.NoError(t, update(1))
+		require.Equal(t, "presenting", p.ClaimGrants().Metadata)
+		require.Equal(t, map[string]string{"slide": "2", "hand": "up"}, p.ClaimGrants().Attributes)
+		require.Equal(t, types.MetadataVersions{Metadata: 1, Attributes: map[string]uint64{"slide": 2, "hand": 1}}, p.MetadataVersions())
+	})
+
+	t.Run("participant requests", func(t *testing.T) {
+		// responses to successful requests need a newer protocol
+		p := newParticipantForTestWithOpts("test", &participantOpts{protocolVersion: 15})
+		grants := p.ClaimGrants().Clone()
+		grants.Video.SetCanUpdateOwnMetadata(true)
+		p.grants.Store(grants)
+		sink := p.params.Sink.(*routingfakes.FakeMessageSink)
+
+		lastMessage := func() (*livekit.RequestResponse, *MetadataVersionsMessage) {
+			res := sink.WriteMessageArgsForCall(sink.WriteMessageCallCount() - 1).(*livekit.SignalResponse).GetRequestResponse()
+			msg := &MetadataVersionsMessage{}
+			require.NoError(t, json.Unmarshal([]byte(res.Message), msg))
+			return res, msg
+		}
+
+		// versions after the update are returned on success
+		require.NoError(t, p.UpdateMetadata(&livekit.UpdateParticipantMetadata{
+			RequestId:  1,
+			Attributes: map[string]string{"slide": "1", ExpectedVersionsAttribute: expected(nil, map[string]uint64{"slide": 0})},
+		}, false, nil))
+		require.Equal(t, map[string]string{"slide": "1"}, p.ClaimGrants().Attributes)
+		res, msg := lastMessage()
+		require.Equal(t, livekit.RequestResponse_OK, res.Reason)
+		require.Equal(t, &MetadataVersionsMessage{Versions: types.MetadataVersions{Attributes: map[string]uint64{"slide": 1}}}, msg)
+
+		err := p.UpdateMetadata(&livekit.UpdateParticipantMetadata{
+			RequestId:  2,
+			Attributes: map[string]string{"slide": "2", ExpectedVersionsAttribute: expected(nil, map[string]uint64{"slide": 0})},
+		}, false, nil)
+		require.ErrorIs(t, err, ErrMetadataConflict)
+		res, msg = lastMessage()
+		require.Equal(t, livekit.RequestResponse_UNCLASSIFIED_ERROR, res.Reason)
+		require.Equal(t, ErrMetadataConflict.Error(), msg.Error)
+		require.Equal(t, []*MetadataConflict{{Key: "slide", Version: 1}}, msg.Conflicts)
+		require.Equal(t, p.MetadataVersions(), msg.Versions)
+
+		err = p.UpdateMetadata(&livekit.UpdateParticipantMetadata{
+			Attributes: map[string]string{"slide": "2", ExpectedVersionsAttribute: "latest"},
+		}, false, nil)
+		require.ErrorIs(t, err, ErrInvalidExpectedVersions)
+
+		// reserved for participants, admins give expected versions directly
+		err = p.UpdateMetadata(&livekit.UpdateParticipantMetadata{
+			Attributes: map[string]string{"slide": "2", ExpectedVersionsAttribute: "{}"},
+		}, true, nil)
+		require.ErrorIs(t, err, ErrVersionsAttributeNotAllowed)
+		require.Equal(t, map[string]string{"slide": "1"}, p.ClaimGrants().Attributes)
+	})
+
+	t.Run("versions continue from an earlier session", func(t *testing.T) {
+		p := newParticipantForTest("test")
+		p.SetAttributes(map[string]string{"slide": "1"})
+
+		next := nextSessionVersions(p.MetadataVersions(), map[string]string{"role": "speaker"})
+		require.Equal(t, &types.MetadataVersions{Metadata: 1, Attributes: map[string]uint64{"slide": 2, "role": 1}}, next)
+	})
+}
+
 // after disconnection, things should continue to function and not panic
 func TestDisconnectTiming(t *testing.T) {
 	t.Run("Negotiate doesn't panic after channel closed", func(t *testing.T) {
@@ -0,0 +1,172 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// ExpectedVersionsAttribute is a reserved attribute making an update of metadata and attributes sent by the participant
+// itself conditional, as UpdateParticipantMetadata has no field for it. Its value is a JSON encoded types.ExpectedVersions,
+// e.g. {"metadata": 3, "attributes": {"slide": 7, "hand": 0}}. It is not stored as an attribute.
+const ExpectedVersionsAttribute = "lk.expected_versions"
+
+var (
+	ErrInvalidExpectedVersions     = errors.New("invalid expected versions")
+	ErrMetadataConflict            = errors.New("metadata version conflict")
+	ErrVersionsAttributeNotAllowed = errors.New("expected versions attribute is only accepted from the participant")
+)
+
+type MetadataConflict struct {
+	// attribute which did not match, empty for metadata
+	Key string `json:"key,omitempty"`
+	// current version, 0 when it was not updated
+	Version uint64 `json:"version"`
+}
+
+// MetadataConflictError is returned when expected versions did not match
+type MetadataConflictError struct {
+	Conflicts []*MetadataConflict
+	// current versions, only the metadata one for room metadata
+	Versions types.MetadataVersions
+}
+
+func (e *MetadataConflictError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(ErrMetadataConflict.Error())
+	for i, c := range e.Conflicts {
+		if i == 0 {
+			sb.WriteString(": ")
+		} else {
+			sb.WriteString(", ")
+		}
+		if c.Key == "" {
+			fmt.Fprintf(&sb, "metadata is at version %d", c.Version)
+		} else {
+			fmt.Fprintf(&sb, "attribute %q is at version %d", c.Key, c.Version)
+		}
+	}
+	return sb.String()
+}
+
+func (e *MetadataConflictError) Unwrap() error {
+	return ErrMetadataConflict
+}
+
+// MetadataVersionsMessage is the JSON encoded message of responses to metadata updates of the participant, it holds
+// the versions after the update. Updates which were not applied as expected versions did not match are answered with
+// UNCLASSIFIED_ERROR, as RequestResponse_Reason has no value for conflicts yet, and have the error and conflicts set.
+type MetadataVersionsMessage struct {
+	Error     string                 `json:"error,omitempty"`
+	Conflicts []*MetadataConflict    `json:"conflicts,omitempty"`
+	Versions  types.MetadataVersions `json:"versions"`
+}
+
+func (m *MetadataVersionsMessage) String() string {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func ParseExpectedVersions(value string) (*types.ExpectedVersions, error) {
+	expected := &types.ExpectedVersions{}
+	if err := json.Unmarshal([]byte(value), expected); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExpectedVersions, err)
+	}
+	return expected, nil
+}
+
+// participantVersions tracks types.MetadataVersions of a participant, protected by the participant lock
+type participantVersions struct {
+	types.MetadataVersions
+}
+
+func newParticipantVersions(versions *types.MetadataVersions) participantVersions {
+	if versions == nil {
+		return participantVersions{}
+	}
+	return participantVersions{types.MetadataVersions{
+		Metadata:   versions.Metadata,
+		Attributes: maps.Clone(versions.Attributes),
+	}}
+}
+
+func (v *participantVersions) check(expected *types.ExpectedVersions) error {
+	if expected == nil {
+		return nil
+	}
+
+	var conflicts []*MetadataConflict
+	if expected.Metadata != nil && *expected.Metadata != v.Metadata {
+		conflicts = append(conflicts, &MetadataConflict{Version: v.Metadata})
+	}
+	keys := maps.Keys(expected.Attributes)
+	// stable order for the error
+	slices.Sort(keys)
+	for _, key := range keys {
+		if current := v.Attributes[key]; current != expected.Attributes[key] {
+			conflicts = append(conflicts, &MetadataConflict{Key: key, Version: current})
+		}
+	}
+	if len(conflicts) != 0 {
+		return &MetadataConflictError{Conflicts: conflicts, Versions: v.clone()}
+	}
+	return nil
+}
+
+// update increments the version of metadata, when it changed, and of changed attributes, set or deleted
+func (v *participantVersions) update(metadataChanged bool, changedKeys []string) {
+	if metadataChanged {
+		v.Metadata++
+	}
+	if len(changedKeys) != 0 && v.Attributes == nil {
+		v.Attributes = make(map[string]uint64, len(changedKeys))
+	}
+	for _, key := range changedKeys {
+		v.Attributes[key]++
+	}
+}
+
+func (v *participantVersions) clone() types.MetadataVersions {
+	return types.MetadataVersions{
+		Metadata:   v.Metadata,
+		Attributes: maps.Clone(v.Attributes),
+	}
+}
+
+// nextSessionVersions returns the versions a new session of a participant starts with, metadata and attributes of
+// the earlier session and of the token of the new one count as changed, so that updates expecting versions
+// seen before do not apply.
+func nextSessionVersions(previous types.MetadataVersions, attributes map[string]string) *types.MetadataVersions {
+	v := newParticipantVersions(&previous)
+	keys := maps.Keys(v.Attributes)
+	for key := range attributes {
+		if _, ok := v.Attributes[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	v.update(true, keys)
+	return &v.MetadataVersions
+}
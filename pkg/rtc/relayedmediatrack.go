@@ -0,0 +1,296 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"sync"
+
+	"github.com/livekit/protocol/codecs/mime"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/relay"
+	"github.com/livekit/livekit-server/pkg/rtc/dynacast"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+// relayStreams carries stream requests and feedback of relayed tracks to the node hosting the publisher
+type relayStreams interface {
+	registerStream(receiver *sfu.RelayReceiver) uint32
+	unregisterStream(streamID uint32)
+	requestStream(nodeID livekit.NodeID, req *relay.StreamRequest)
+	releaseStream(nodeID livekit.NodeID, req *relay.StreamRequest)
+	requestKeyFrame(nodeID livekit.NodeID, streamID uint32, layer int32)
+	sendSubscribedQuality(nodeID livekit.NodeID, qualities []*relay.SubscribedQuality)
+}
+
+type RelayedMediaTrackParams struct {
+	// node hosting the publisher
+	NodeID              livekit.NodeID
+	ParticipantID       livekit.ParticipantID
+	ParticipantIdentity livekit.ParticipantIdentity
+	ParticipantVersion  uint32
+	ReceiverConfig      ReceiverConfig
+	SubscriberConfig    DirectionConfig
+	PLIThrottleConfig   sfu.PLIThrottleConfig
+	AudioConfig         sfu.AudioConfig
+	VideoConfig         config.VideoConfig
+	Telemetry           telemetry.TelemetryService
+	Logger              logger.Logger
+	Streams             relayStreams
+}
+
+type relayedCodec struct {
+	receiver  *sfu.RelayReceiver
+	streamID  uint32
+	requested bool
+}
+
+var _ types.MediaTrack = (*RelayedMediaTrack)(nil)
+
+// RelayedMediaTrack is a track published on another node. Media of each codec is requested from
+// that node when the track gets its first subscriber and released once it has none left.
+type RelayedMediaTrack struct {
+	params RelayedMediaTrackParams
+
+	*MediaTrackReceiver
+
+	dynacastManager dynacast.DynacastManager
+
+	lock   sync.Mutex
+	codecs map[mime.MimeType]*relayedCodec
+}
+
+func NewRelayedMediaTrack(params RelayedMediaTrackParams, ti *livekit.TrackInfo) *RelayedMediaTrack {
+	t := &RelayedMediaTrack{
+		params: params,
+		codecs: make(map[mime.MimeType]*relayedCodec),
+	}
+
+	t.MediaTrackReceiver = NewMediaTrackReceiver(MediaTrackReceiverParams{
+		MediaTrack:          t,
+		IsRelayed:           true,
+		ParticipantID:       func() livekit.ParticipantID { return params.ParticipantID },
+		ParticipantIdentity: params.ParticipantIdentity,
+		ParticipantVersion:  params.ParticipantVersion,
+		ReceiverConfig:      params.ReceiverConfig,
+		SubscriberConfig:    params.SubscriberConfig,
+		AudioConfig:         params.AudioConfig,
+		Telemetry:           params.Telemetry,
+		Logger:              params.Logger,
+	}, ti)
+
+	// video quality subscribed on this node is reported to the publisher's node which runs dynacast for the publisher
+	if ti.Type == livekit.TrackType_VIDEO {
+		t.dynacastManager = dynacast.NewDynacastManagerVideo(dynacast.DynacastManagerVideoParams{
+			DynacastPauseDelay: params.VideoConfig.DynacastPauseDelay,
+			Listener:           t,
+			Logger:             params.Logger,
+		})
+		t.MediaTrackReceiver.OnSetupReceiver(t.dynacastManager.AddCodec)
+		t.MediaTrackReceiver.OnSubscriberMaxQualityChange(
+			func(subscriberID livekit.ParticipantID, mimeType mime.MimeType, layer int32) {
+				t.dynacastManager.NotifySubscriberMaxQuality(
+					subscriberID,
+					mimeType,
+					buffer.GetVideoQualityForSpatialLayer(mimeType, layer, t.MediaTrackReceiver.TrackInfo()),
+				)
+			},
+		)
+	}
+
+	t.MediaTrackReceiver.SetMuted(ti.Muted)
+	return t
+}
+
+func (t *RelayedMediaTrack) NodeID() livekit.NodeID {
+	return t.params.NodeID
+}
+
+// AddCodec sets up a receiver for a codec of the track, no-op if the codec is already set up
+func (t *RelayedMediaTrack) AddCodec(state *relay.CodecState, priority int) {
+	mimeType := mime.NormalizeMimeType(state.Codec.MimeType)
+
+	t.lock.Lock()
+	if _, ok := t.codecs[mimeType]; ok {
+		t.lock.Unlock()
+		return
+	}
+
+	rc := &relayedCodec{}
+	rc.receiver = sfu.NewRelayReceiver(sfu.RelayReceiverParams{
+		TrackInfo:                  t.MediaTrackReceiver.TrackInfoClone(),
+		Codec:                      state.Codec,
+		HeaderExtensions:           state.HeaderExtensions,
+		MaxVideoPkts:               t.params.ReceiverConfig.PacketBufferSizeVideo,
+		MaxAudioPkts:               t.params.ReceiverConfig.PacketBufferSizeAudio,
+		PLIThrottleConfig:          t.params.PLIThrottleConfig,
+		AudioConfig:                t.params.AudioConfig,
+		StreamTrackerManagerConfig: t.params.VideoConfig.StreamTrackerManager,
+		Logger:                     LoggerWithCodecMime(t.params.Logger, mimeType),
+		OnPLI: func(layer int32) {
+			t.params.Streams.requestKeyFrame(t.params.NodeID, rc.streamID, layer)
+		},
+	})
+	rc.streamID = t.params.Streams.registerStream(rc.receiver)
+	t.codecs[mimeType] = rc
+	hasSubscribers := t.GetNumSubscribers() != 0
+	t.lock.Unlock()
+
+	t.MediaTrackReceiver.SetupReceiver(rc.receiver, priority, "")
+
+	if hasSubscribers {
+		t.requestStreams()
+	}
+}
+
+func (t *RelayedMediaTrack) AddSubscriber(sub types.LocalParticipant) (types.SubscribedTrack, error) {
+	subTrack, err := t.MediaTrackReceiver.AddSubscriber(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	t.requestStreams()
+	return subTrack, nil
+}
+
+func (t *RelayedMediaTrack) requestStreams() {
+	t.lock.Lock()
+	var reqs []*relay.StreamRequest
+	for mimeType, rc := range t.codecs {
+		if rc.requested {
+			continue
+		}
+		rc.requested = true
+		reqs = append(reqs, t.streamRequest(mimeType, rc))
+	}
+	t.lock.Unlock()
+
+	for _, req := range reqs {
+		t.params.Logger.Debugw("requesting relayed stream", "mime", req.MimeType, "streamID", req.StreamID)
+		t.params.Streams.requestStream(t.params.NodeID, req)
+	}
+}
+
+// ReleaseIdleStreams stops media from the publisher's node when there are no subscribers on this node
+func (t *RelayedMediaTrack) ReleaseIdleStreams() {
+	if t.GetNumSubscribers() != 0 {
+		return
+	}
+
+	t.releaseStreams(false)
+}
+
+func (t *RelayedMediaTrack) releaseStreams(unregister bool) {
+	t.lock.Lock()
+	var reqs []*relay.StreamRequest
+	var streamIDs []uint32
+	for mimeType, rc := range t.codecs {
+		if rc.requested {
+			rc.requested = false
+			reqs = append(reqs, t.streamRequest(mimeType, rc))
+		}
+		if unregister {
+			streamIDs = append(streamIDs, rc.streamID)
+		}
+	}
+	t.lock.Unlock()
+
+	for _, req := range reqs {
+		t.params.Logger.Debugw("releasing relayed stream", "mime", req.MimeType, "streamID", req.StreamID)
+		t.params.Streams.releaseStream(t.params.NodeID, req)
+	}
+	for _, streamID := range streamIDs {
+		t.params.Streams.unregisterStream(streamID)
+	}
+}
+
+func (t *RelayedMediaTrack) streamRequest(mimeType mime.MimeType, rc *relayedCodec) *relay.StreamRequest {
+	return &relay.StreamRequest{
+		StreamID:      rc.streamID,
+		ParticipantID: t.params.ParticipantID,
+		TrackID:       t.ID(),
+		MimeType:      mimeType.String(),
+	}
+}
+
+func (t *RelayedMediaTrack) SetMuted(muted bool) {
+	if !muted && t.dynacastManager != nil {
+		t.dynacastManager.ForceUpdate()
+	}
+
+	t.MediaTrackReceiver.SetMuted(muted)
+}
+
+func (t *RelayedMediaTrack) Close(isExpectedToResume bool) {
+	t.MediaTrackReceiver.SetClosing(isExpectedToResume)
+	if t.dynacastManager != nil {
+		t.dynacastManager.Close()
+	}
+	t.MediaTrackReceiver.Close(isExpectedToResume)
+
+	t.releaseStreams(true)
+
+	t.lock.Lock()
+	codecs := t.codecs
+	t.codecs = make(map[mime.MimeType]*relayedCodec)
+	t.lock.Unlock()
+
+	for _, rc := range codecs {
+		rc.receiver.Close("relayed track closed")
+	}
+}
+
+// tracks of remote participants do not have subscription driven callbacks
+func (t *RelayedMediaTrack) OnTrackSubscribed() {}
+
+func (t *RelayedMediaTrack) Logger() logger.Logger {
+	return t.params.Logger
+}
+
+func (t *RelayedMediaTrack) ToProto() *livekit.TrackInfo {
+	return t.MediaTrackReceiver.TrackInfoClone()
+}
+
+// dynacast.DynacastManagerListener implementation
+var _ dynacast.DynacastManagerListener = (*RelayedMediaTrack)(nil)
+
+func (t *RelayedMediaTrack) OnDynacastSubscribedMaxQualityChange(
+	_subscribedQualities []*livekit.SubscribedCodec,
+	maxSubscribedQualities []types.SubscribedCodecQuality,
+) {
+	qualities := make([]*relay.SubscribedQuality, 0, len(maxSubscribedQualities))
+	for _, q := range maxSubscribedQualities {
+		qualities = append(qualities, &relay.SubscribedQuality{
+			TrackID:  t.ID(),
+			MimeType: q.CodecMime.String(),
+			Quality:  q.Quality,
+		})
+
+		if receiver := t.Receiver(q.CodecMime); receiver != nil {
+			receiver.SetMaxExpectedSpatialLayer(
+				buffer.GetSpatialLayerForVideoQuality(q.CodecMime, q.Quality, t.MediaTrackReceiver.TrackInfo()),
+			)
+		}
+	}
+	t.params.Streams.sendSubscribedQuality(t.params.NodeID, qualities)
+}
+
+func (t *RelayedMediaTrack) OnDynacastSubscribedAudioCodecChange(_codecs []*livekit.SubscribedAudioCodec) {
+}
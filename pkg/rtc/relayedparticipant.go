@@ -0,0 +1,254 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/rtc/datatrack"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+var _ types.Participant = (*RelayedParticipant)(nil)
+
+type RelayedParticipantParams struct {
+	// node hosting the participant's session
+	NodeID           livekit.NodeID
+	Info             *livekit.ParticipantInfo
+	VersionGenerator utils.TimedVersionGenerator
+	Logger           logger.Logger
+}
+
+// RelayedParticipant is the replica of a participant connected to another node hosting the same room.
+// Its tracks are relayed from that node, state changes are applied as they are replicated.
+type RelayedParticipant struct {
+	*UpTrackManager
+
+	params RelayedParticipantParams
+
+	lock         sync.RWMutex
+	info         *livekit.ParticipantInfo
+	timedVersion utils.TimedVersion
+
+	closed atomic.Bool
+}
+
+func NewRelayedParticipant(params RelayedParticipantParams) *RelayedParticipant {
+	p := &RelayedParticipant{
+		params: params,
+		info:   utils.CloneProto(params.Info),
+	}
+	p.timedVersion.Update(params.VersionGenerator.Next())
+	p.UpTrackManager = NewUpTrackManager(UpTrackManagerParams{
+		Logger:           params.Logger,
+		VersionGenerator: params.VersionGenerator,
+	})
+	return p
+}
+
+func (p *RelayedParticipant) NodeID() livekit.NodeID {
+	return p.params.NodeID
+}
+
+// UpdateInfo applies replicated participant info, returns false when it is older than the current one
+func (p *RelayedParticipant) UpdateInfo(info *livekit.ParticipantInfo) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if info.Version < p.info.Version {
+		return false
+	}
+
+	p.info = utils.CloneProto(info)
+	p.timedVersion.Update(p.params.VersionGenerator.Next())
+	return true
+}
+
+func (p *RelayedParticipant) ID() livekit.ParticipantID {
+	return livekit.ParticipantID(p.params.Info.Sid)
+}
+
+func (p *RelayedParticipant) Identity() livekit.ParticipantIdentity {
+	return livekit.ParticipantIdentity(p.params.Info.Identity)
+}
+
+func (p *RelayedParticipant) State() livekit.ParticipantInfo_State {
+	if p.closed.Load() {
+		return livekit.ParticipantInfo_DISCONNECTED
+	}
+
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.info.State
+}
+
+func (p *RelayedParticipant) ConnectedAt() time.Time {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return time.UnixMilli(p.info.JoinedAtMs)
+}
+
+func (p *RelayedParticipant) CloseReason() types.ParticipantCloseReason {
+	return types.ParticipantCloseReasonNone
+}
+
+func (p *RelayedParticipant) Kind() livekit.ParticipantInfo_Kind {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.info.Kind
+}
+
+// ClaimGrants returns grants of the participant as replicated by its node, without token specific fields
+func (p *RelayedParticipant) ClaimGrants() *auth.ClaimGrants {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	video := &auth.VideoGrant{}
+	if p.info.Permission != nil {
+		video.UpdateFromPermission(p.info.Permission)
+	}
+	grants := &auth.ClaimGrants{
+		Identity:   p.info.Identity,
+		Name:       p.info.Name,
+		Metadata:   p.info.Metadata,
+		Attributes: p.info.Attributes,
+		Video:      video,
+	}
+	grants.SetParticipantKind(p.info.Kind)
+	return grants
+}
+
+func (p *RelayedParticipant) IsRecorder() bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.info.Kind == livekit.ParticipantInfo_EGRESS || p.info.Permission.GetRecorder()
+}
+
+func (p *RelayedParticipant) IsDependent() bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	switch p.info.Kind {
+	case livekit.ParticipantInfo_AGENT, livekit.ParticipantInfo_EGRESS:
+		return true
+	default:
+		return p.info.Permission.GetAgent() || p.info.Permission.GetRecorder()
+	}
+}
+
+func (p *RelayedParticipant) IsAgent() bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.info.Kind == livekit.ParticipantInfo_AGENT || p.info.Permission.GetAgent()
+}
+
+func (p *RelayedParticipant) GetLogger() logger.Logger {
+	return p.params.Logger
+}
+
+// broadcast decisions are made by the node hosting the participant
+func (p *RelayedParticipant) CanSkipBroadcast() bool {
+	return false
+}
+
+func (p *RelayedParticipant) Version() utils.TimedVersion {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.timedVersion
+}
+
+func (p *RelayedParticipant) ToProto() *livekit.ParticipantInfo {
+	pi, _ := p.ToProtoWithVersion()
+	return pi
+}
+
+func (p *RelayedParticipant) ToProtoWithVersion() (*livekit.ParticipantInfo, utils.TimedVersion) {
+	p.lock.RLock()
+	pi := utils.CloneProto(p.info)
+	piv := p.timedVersion
+	p.lock.RUnlock()
+
+	if p.closed.Load() {
+		pi.State = livekit.ParticipantInfo_DISCONNECTED
+	}
+	return pi, piv
+}
+
+func (p *RelayedParticipant) IsPublisher() bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.info.IsPublisher
+}
+
+// data tracks are not relayed
+func (p *RelayedParticipant) GetPublishedDataTracks() []types.DataTrack {
+	return nil
+}
+
+func (p *RelayedParticipant) GetPublishedDataTrack(_handle uint16) types.DataTrack {
+	return nil
+}
+
+func (p *RelayedParticipant) RemovePublishedDataTrack(_track types.DataTrack) {}
+
+func (p *RelayedParticipant) Hidden() bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.info.Permission.GetHidden()
+}
+
+func (p *RelayedParticipant) MigrateState() types.MigrateState {
+	return types.MigrateStateComplete
+}
+
+func (p *RelayedParticipant) Close(_sendLeave bool, _reason types.ParticipantCloseReason, isExpectedToResume bool) error {
+	if p.closed.Swap(true) {
+		return nil
+	}
+
+	p.UpTrackManager.Close(isExpectedToResume)
+	return nil
+}
+
+func (p *RelayedParticipant) IsClosed() bool {
+	return p.closed.Load()
+}
+
+func (p *RelayedParticipant) IsDisconnected() bool {
+	return p.State() == livekit.ParticipantInfo_DISCONNECTED
+}
+
+func (p *RelayedParticipant) DebugInfo() map[string]any {
+	return map[string]any{
+		"ID":             p.ID(),
+		"State":          p.State().String(),
+		"NodeID":         p.params.NodeID,
+		"UpTrackManager": p.UpTrackManager.DebugInfo(),
+	}
+}
+
+func (p *RelayedParticipant) HandleReceivedDataTrackMessage(_data []byte, _packet *datatrack.Packet, _arrivalTime int64) {
+}
+
+func (p *RelayedParticipant) GetParticipantListener() types.ParticipantListener {
+	return &types.NullParticipantListener{}
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+func newTestRelayedParticipant(info *livekit.ParticipantInfo) *RelayedParticipant {
+	return NewRelayedParticipant(RelayedParticipantParams{
+		NodeID:           "ND_remote",
+		Info:             info,
+		VersionGenerator: utils.NewDefaultTimedVersionGenerator(),
+		Logger:           logger.GetLogger(),
+	})
+}
+
+func TestRelayedParticipant(t *testing.T) {
+	t.Run("applies newer info only", func(t *testing.T) {
+		p := newTestRelayedParticipant(&livekit.ParticipantInfo{
+			Sid:      "PA_1",
+			Identity: "remote",
+			State:    livekit.ParticipantInfo_ACTIVE,
+			Metadata: "first",
+			Version:  2,
+		})
+		v := p.Version()
+
+		require.False(t, p.UpdateInfo(&livekit.ParticipantInfo{Sid: "PA_1", Identity: "remote", Metadata: "stale", Version: 1}))
+		require.Equal(t, "first", p.ToProto().Metadata)
+		require.Equal(t, v, p.Version())
+
+		require.True(t, p.UpdateInfo(&livekit.ParticipantInfo{Sid: "PA_1", Identity: "remote", Metadata: "second", Version: 3}))
+		require.Equal(t, "second", p.ToProto().Metadata)
+		require.True(t, v.Compare(p.Version()) < 0)
+	})
+
+	t.Run("disconnected once closed", func(t *testing.T) {
+		p := newTestRelayedParticipant(&livekit.ParticipantInfo{
+			Sid:      "PA_1",
+			Identity: "remote",
+			State:    livekit.ParticipantInfo_ACTIVE,
+		})
+		require.False(t, p.IsDisconnected())
+
+		require.NoError(t, p.Close(false, types.ParticipantCloseReasonNone, false))
+		require.True(t, p.IsClosed())
+		require.True(t, p.IsDisconnected())
+		require.Equal(t, livekit.ParticipantInfo_DISCONNECTED, p.ToProto().State)
+	})
+}
@@ -0,0 +1,861 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/codecs/mime"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	protoutils "github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/relay"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+const (
+	// relayed streams without subscribers on the receiving node are released after this interval
+	relayIdleStreamCheckInterval = 5 * time.Second
+
+	relaySubscriberPrefix = "RELAY_"
+)
+
+type RelayManagerParams struct {
+	NodeID           livekit.NodeID
+	Transport        *relay.Transport
+	Registry         relay.Registry
+	ReceiverConfig   ReceiverConfig
+	SubscriberConfig DirectionConfig
+	PLIThrottle      sfu.PLIThrottleConfig
+	AudioConfig      sfu.AudioConfig
+	VideoConfig      config.VideoConfig
+	Telemetry        telemetry.TelemetryService
+	VersionGenerator protoutils.TimedVersionGenerator
+	Logger           logger.Logger
+}
+
+type outboundStreamKey struct {
+	nodeID   livekit.NodeID
+	streamID uint32
+}
+
+type outboundStream struct {
+	sender   *sfu.RelaySender
+	receiver sfu.TrackReceiver
+	track    types.MediaTrack
+}
+
+// RelayManager lets rooms span nodes. It replicates the state of local participants to the other nodes
+// hosting a room, and forwards media of published tracks to the nodes that have subscribers for them.
+type RelayManager struct {
+	params RelayManagerParams
+
+	lock  sync.RWMutex
+	rooms map[livekit.RoomName]*RoomRelay
+	// streams received by this node, keyed by stream id allocated by this node
+	inbound      map[uint32]*sfu.RelayReceiver
+	nextStreamID uint32
+	// streams sent by this node, keyed by receiving node and its stream id
+	outbound map[outboundStreamKey]*outboundStream
+	// control messages are sent in order per node, without blocking the caller
+	sendQueues map[livekit.NodeID]*utils.OpsQueue
+
+	stop chan struct{}
+}
+
+func NewRelayManager(params RelayManagerParams) *RelayManager {
+	m := &RelayManager{
+		params:     params,
+		rooms:      make(map[livekit.RoomName]*RoomRelay),
+		inbound:    make(map[uint32]*sfu.RelayReceiver),
+		outbound:   make(map[outboundStreamKey]*outboundStream),
+		sendQueues: make(map[livekit.NodeID]*utils.OpsQueue),
+		stop:       make(chan struct{}),
+	}
+
+	params.Transport.OnMessage(m.handleMessage)
+	params.Transport.OnPacket(m.handlePacket)
+	params.Transport.OnPeerDisconnected(m.handlePeerDisconnected)
+
+	go m.idleStreamWorker()
+	return m
+}
+
+func (m *RelayManager) Stop() {
+	m.lock.Lock()
+	select {
+	case <-m.stop:
+		m.lock.Unlock()
+		return
+	default:
+		close(m.stop)
+	}
+	queues := m.sendQueues
+	m.sendQueues = make(map[livekit.NodeID]*utils.OpsQueue)
+	m.lock.Unlock()
+
+	for _, q := range queues {
+		<-q.Stop()
+	}
+	m.params.Transport.Stop()
+}
+
+// RoomNodes returns the other nodes hosting a room
+func (m *RelayManager) RoomNodes(ctx context.Context, roomName livekit.RoomName) ([]livekit.NodeID, error) {
+	nodes, err := m.params.Registry.GetNodes(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(nodes, func(nodeID livekit.NodeID) bool { return nodeID == m.params.NodeID }), nil
+}
+
+// AddRoom starts relaying a room hosted on this node, other nodes hosting the room are asked for their participants
+func (m *RelayManager) AddRoom(ctx context.Context, room *Room) {
+	rr := newRoomRelay(m, room)
+
+	m.lock.Lock()
+	m.rooms[room.Name()] = rr
+	m.lock.Unlock()
+	room.SetRelay(rr)
+
+	if err := m.params.Registry.AddNode(ctx, room.Name(), m.params.NodeID); err != nil {
+		room.Logger().Errorw("could not register room node", err)
+	}
+
+	nodes, err := m.RoomNodes(ctx, room.Name())
+	if err != nil {
+		room.Logger().Errorw("could not get room nodes", err)
+		return
+	}
+	for _, nodeID := range nodes {
+		rr.addPeer(nodeID)
+		m.sendJoinRoom(room.Name(), nodeID)
+	}
+}
+
+// RemoveRoom stops relaying a room, returns the nodes still hosting it
+func (m *RelayManager) RemoveRoom(ctx context.Context, room *Room) []livekit.NodeID {
+	m.lock.Lock()
+	rr := m.rooms[room.Name()]
+	if rr != nil && rr.room == room {
+		delete(m.rooms, room.Name())
+	} else {
+		rr = nil
+	}
+	m.lock.Unlock()
+
+	if rr != nil {
+		for _, nodeID := range rr.close() {
+			m.send(nodeID, &relay.Message{Type: relay.MessageTypeLeaveRoom, Room: room.Name()})
+		}
+	}
+
+	if err := m.params.Registry.RemoveNode(ctx, room.Name(), m.params.NodeID); err != nil {
+		room.Logger().Errorw("could not unregister room node", err)
+	}
+
+	nodes, err := m.RoomNodes(ctx, room.Name())
+	if err != nil {
+		room.Logger().Errorw("could not get room nodes", err)
+		return nil
+	}
+	return nodes
+}
+
+func (m *RelayManager) getRoom(roomName livekit.RoomName) *RoomRelay {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.rooms[roomName]
+}
+
+func (m *RelayManager) getRooms() []*RoomRelay {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	rooms := make([]*RoomRelay, 0, len(m.rooms))
+	for _, rr := range m.rooms {
+		rooms = append(rooms, rr)
+	}
+	return rooms
+}
+
+func (m *RelayManager) sendJoinRoom(roomName livekit.RoomName, nodeID livekit.NodeID) {
+	m.getSendQueue(nodeID).Enqueue(func() {
+		err := m.params.Transport.SendMessage(nodeID, &relay.Message{Type: relay.MessageTypeJoinRoom, Room: roomName})
+		if err != nil {
+			// node is gone without unregistering, do not try it again
+			m.params.Logger.Warnw("could not join room on node", err, "room", roomName, "peerNodeID", nodeID)
+			if rr := m.getRoom(roomName); rr != nil {
+				rr.removePeer(nodeID)
+			}
+			_ = m.params.Registry.RemoveNode(context.Background(), roomName, nodeID)
+		}
+	})
+}
+
+func (m *RelayManager) send(nodeID livekit.NodeID, msg *relay.Message) {
+	m.getSendQueue(nodeID).Enqueue(func() {
+		if err := m.params.Transport.SendMessage(nodeID, msg); err != nil {
+			m.params.Logger.Debugw("could not send relay message", "error", err, "peerNodeID", nodeID, "type", msg.Type, "room", msg.Room)
+		}
+	})
+}
+
+func (m *RelayManager) getSendQueue(nodeID livekit.NodeID) *utils.OpsQueue {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	q := m.sendQueues[nodeID]
+	if q == nil {
+		q = utils.NewOpsQueue(utils.OpsQueueParams{
+			Name:        "relay-send",
+			MinSize:     64,
+			FlushOnStop: true,
+			Logger:      m.params.Logger.WithValues("peerNodeID", nodeID),
+		})
+		q.Start()
+		m.sendQueues[nodeID] = q
+	}
+	return q
+}
+
+func (m *RelayManager) handleMessage(from livekit.NodeID, msg *relay.Message) {
+	rr := m.getRoom(msg.Room)
+	if rr == nil {
+		return
+	}
+
+	switch msg.Type {
+	case relay.MessageTypeJoinRoom:
+		rr.handleJoinRoom(from)
+	case relay.MessageTypeLeaveRoom:
+		rr.removePeer(from)
+		m.closeOutboundStreams(from)
+	case relay.MessageTypeParticipantUpdate:
+		rr.handleParticipantUpdate(from, msg.Participant)
+	case relay.MessageTypeParticipantLeft:
+		rr.handleParticipantLeft(from, msg.ParticipantID)
+	case relay.MessageTypeSubscribe:
+		rr.handleSubscribe(from, msg.Stream)
+	case relay.MessageTypeUnsubscribe:
+		if msg.Stream != nil {
+			m.closeOutboundStream(outboundStreamKey{nodeID: from, streamID: msg.Stream.StreamID})
+		}
+	case relay.MessageTypeSubscribedQuality:
+		rr.handleSubscribedQuality(from, msg.Qualities)
+	case relay.MessageTypeData:
+		rr.handleData(msg.Data, livekit.DataPacket_Kind(msg.DataKind))
+	}
+}
+
+func (m *RelayManager) handlePacket(from livekit.NodeID, pkt *relay.Packet) {
+	switch pkt.Kind {
+	case relay.PacketKindRTP:
+		m.lock.RLock()
+		receiver := m.inbound[pkt.StreamID]
+		m.lock.RUnlock()
+		if receiver != nil {
+			_ = receiver.WriteRTP(pkt.Layer, pkt.Payload)
+		}
+
+	case relay.PacketKindSenderReport:
+		m.lock.RLock()
+		receiver := m.inbound[pkt.StreamID]
+		m.lock.RUnlock()
+		if receiver == nil {
+			return
+		}
+		srData := &livekit.RTCPSenderReportState{}
+		if err := proto.Unmarshal(pkt.Payload, srData); err != nil {
+			return
+		}
+		receiver.SetSenderReportData(pkt.Layer, srData)
+
+	case relay.PacketKindPLI:
+		m.lock.RLock()
+		stream := m.outbound[outboundStreamKey{nodeID: from, streamID: pkt.StreamID}]
+		m.lock.RUnlock()
+		if stream != nil {
+			stream.receiver.SendPLI(pkt.Layer, false)
+		}
+	}
+}
+
+func (m *RelayManager) handlePeerDisconnected(nodeID livekit.NodeID) {
+	m.params.Logger.Infow("relay peer disconnected", "peerNodeID", nodeID)
+	for _, rr := range m.getRooms() {
+		rr.removePeer(nodeID)
+	}
+	m.closeOutboundStreams(nodeID)
+}
+
+func (m *RelayManager) idleStreamWorker() {
+	ticker := time.NewTicker(relayIdleStreamCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			for _, rr := range m.getRooms() {
+				rr.releaseIdleStreams()
+			}
+		}
+	}
+}
+
+// ---------------------------------------
+// streams received by this node
+
+func (m *RelayManager) registerStream(receiver *sfu.RelayReceiver) uint32 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for {
+		m.nextStreamID++
+		if _, ok := m.inbound[m.nextStreamID]; !ok && m.nextStreamID != 0 {
+			break
+		}
+	}
+	m.inbound[m.nextStreamID] = receiver
+	return m.nextStreamID
+}
+
+func (m *RelayManager) unregisterStream(streamID uint32) {
+	m.lock.Lock()
+	delete(m.inbound, streamID)
+	m.lock.Unlock()
+}
+
+func (m *RelayManager) requestKeyFrame(nodeID livekit.NodeID, streamID uint32, layer int32) {
+	_ = m.params.Transport.SendPacket(nodeID, &relay.Packet{
+		Kind:     relay.PacketKindPLI,
+		StreamID: streamID,
+		Layer:    layer,
+	})
+}
+
+// ---------------------------------------
+// streams sent by this node
+
+func (m *RelayManager) startOutboundStream(nodeID livekit.NodeID, streamID uint32, track types.MediaTrack, receiver sfu.TrackReceiver) {
+	key := outboundStreamKey{nodeID: nodeID, streamID: streamID}
+	stream := &outboundStream{
+		receiver: receiver,
+		track:    track,
+	}
+	stream.sender = sfu.NewRelaySender(sfu.RelaySenderParams{
+		SubscriberID: livekit.ParticipantID(fmt.Sprintf("%s%s_%d", relaySubscriberPrefix, nodeID, streamID)),
+		Logger:       track.Logger().WithValues("peerNodeID", nodeID, "streamID", streamID),
+		OnPacket: func(layer int32, pkt []byte) {
+			_ = m.params.Transport.SendPacket(nodeID, &relay.Packet{
+				Kind:     relay.PacketKindRTP,
+				StreamID: streamID,
+				Layer:    layer,
+				Payload:  pkt,
+			})
+		},
+		OnSenderReport: func(layer int32, srData *livekit.RTCPSenderReportState) {
+			payload, err := proto.Marshal(srData)
+			if err != nil {
+				return
+			}
+			_ = m.params.Transport.SendPacket(nodeID, &relay.Packet{
+				Kind:     relay.PacketKindSenderReport,
+				StreamID: streamID,
+				Layer:    layer,
+				Payload:  payload,
+			})
+		},
+		OnClose: func() {
+			m.lock.Lock()
+			if m.outbound[key] == stream {
+				delete(m.outbound, key)
+			}
+			m.lock.Unlock()
+		},
+	}, receiver)
+
+	m.lock.Lock()
+	existing := m.outbound[key]
+	m.outbound[key] = stream
+	m.lock.Unlock()
+
+	if existing != nil {
+		existing.sender.Close()
+	}
+
+	if err := receiver.AddDownTrack(stream.sender); err != nil {
+		track.Logger().Warnw("could not relay track", err, "peerNodeID", nodeID, "mime", receiver.Mime())
+		stream.sender.Close()
+		return
+	}
+	track.Logger().Debugw("relaying track", "peerNodeID", nodeID, "streamID", streamID, "mime", receiver.Mime())
+}
+
+func (m *RelayManager) closeOutboundStream(key outboundStreamKey) {
+	m.lock.RLock()
+	stream := m.outbound[key]
+	m.lock.RUnlock()
+
+	if stream == nil {
+		return
+	}
+
+	stream.sender.Close()
+	// the node no longer takes part in dynacast of the codec
+	if lt, ok := stream.track.(types.LocalMediaTrack); ok {
+		lt.NotifySubscriberNodeMaxQuality(key.nodeID, []types.SubscribedCodecQuality{
+			{CodecMime: stream.receiver.Mime(), Quality: livekit.VideoQuality_OFF},
+		})
+	}
+}
+
+func (m *RelayManager) closeOutboundStreams(nodeID livekit.NodeID) {
+	m.lock.RLock()
+	var keys []outboundStreamKey
+	for key := range m.outbound {
+		if key.nodeID == nodeID {
+			keys = append(keys, key)
+		}
+	}
+	m.lock.RUnlock()
+
+	for _, key := range keys {
+		m.closeOutboundStream(key)
+	}
+}
+
+// ---------------------------------------
+
+// RoomRelay is the relay state of a room hosted on this node
+type RoomRelay struct {
+	manager *RelayManager
+	room    *Room
+	logger  logger.Logger
+
+	lock   sync.RWMutex
+	peers  map[livekit.NodeID]struct{}
+	closed bool
+}
+
+var _ relayStreams = (*RoomRelay)(nil)
+
+func newRoomRelay(manager *RelayManager, room *Room) *RoomRelay {
+	return &RoomRelay{
+		manager: manager,
+		room:    room,
+		logger:  room.Logger(),
+		peers:   make(map[livekit.NodeID]struct{}),
+	}
+}
+
+func (rr *RoomRelay) addPeer(nodeID livekit.NodeID) bool {
+	rr.lock.Lock()
+	defer rr.lock.Unlock()
+
+	if rr.closed {
+		return false
+	}
+	if _, ok := rr.peers[nodeID]; ok {
+		return false
+	}
+	rr.peers[nodeID] = struct{}{}
+	rr.logger.Infow("room node joined", "peerNodeID", nodeID)
+	return true
+}
+
+func (rr *RoomRelay) removePeer(nodeID livekit.NodeID) {
+	rr.lock.Lock()
+	_, ok := rr.peers[nodeID]
+	delete(rr.peers, nodeID)
+	rr.lock.Unlock()
+
+	if ok {
+		rr.logger.Infow("room node left", "peerNodeID", nodeID)
+	}
+	rr.room.removeRelayedParticipantsOfNode(nodeID)
+}
+
+func (rr *RoomRelay) getPeers() []livekit.NodeID {
+	rr.lock.RLock()
+	defer rr.lock.RUnlock()
+
+	peers := make([]livekit.NodeID, 0, len(rr.peers))
+	for nodeID := range rr.peers {
+		peers = append(peers, nodeID)
+	}
+	return peers
+}
+
+func (rr *RoomRelay) close() []livekit.NodeID {
+	rr.lock.Lock()
+	rr.closed = true
+	peers := make([]livekit.NodeID, 0, len(rr.peers))
+	for nodeID := range rr.peers {
+		peers = append(peers, nodeID)
+	}
+	rr.peers = make(map[livekit.NodeID]struct{})
+	rr.lock.Unlock()
+
+	for _, nodeID := range peers {
+		rr.room.removeRelayedParticipantsOfNode(nodeID)
+	}
+	return peers
+}
+
+func (rr *RoomRelay) broadcast(msg *relay.Message) {
+	for _, nodeID := range rr.getPeers() {
+		rr.manager.send(nodeID, msg)
+	}
+}
+
+// ---------------------------------------
+// replication of local state
+
+// ParticipantChanged replicates state of a local participant to the other nodes
+func (rr *RoomRelay) ParticipantChanged(p types.LocalParticipant) {
+	state, err := participantStateForRelay(p)
+	if err != nil {
+		p.GetLogger().Errorw("could not encode participant for relay", err)
+		return
+	}
+
+	rr.broadcast(&relay.Message{
+		Type:        relay.MessageTypeParticipantUpdate,
+		Room:        rr.room.Name(),
+		Participant: state,
+	})
+}
+
+func (rr *RoomRelay) ParticipantLeft(p types.LocalParticipant) {
+	rr.broadcast(&relay.Message{
+		Type:          relay.MessageTypeParticipantLeft,
+		Room:          rr.room.Name(),
+		ParticipantID: p.ID(),
+	})
+}
+
+func (rr *RoomRelay) DataPacket(kind livekit.DataPacket_Kind, dp *livekit.DataPacket) {
+	data, err := proto.Marshal(dp)
+	if err != nil {
+		rr.logger.Errorw("could not encode data packet for relay", err)
+		return
+	}
+
+	rr.broadcast(&relay.Message{
+		Type:     relay.MessageTypeData,
+		Room:     rr.room.Name(),
+		Data:     data,
+		DataKind: int32(kind),
+	})
+}
+
+func participantStateForRelay(p types.LocalParticipant) (*relay.ParticipantState, error) {
+	info, err := proto.Marshal(p.ToProto())
+	if err != nil {
+		return nil, err
+	}
+
+	state := &relay.ParticipantState{Info: info}
+	if perm, _ := p.SubscriptionPermission(); perm != nil {
+		if state.Permission, err = proto.Marshal(perm); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, track := range p.GetPublishedTracks() {
+		ts := &relay.TrackState{TrackID: track.ID()}
+		for _, receiver := range track.Receivers() {
+			codec := receiver.Codec()
+			if codec.MimeType == "" {
+				continue
+			}
+			ts.Codecs = append(ts.Codecs, &relay.CodecState{
+				Codec:            codec,
+				HeaderExtensions: receiver.HeaderExtensions(),
+			})
+		}
+		state.Tracks = append(state.Tracks, ts)
+	}
+	return state, nil
+}
+
+// ---------------------------------------
+// state received from other nodes
+
+func (rr *RoomRelay) handleJoinRoom(from livekit.NodeID) {
+	rr.addPeer(from)
+
+	for _, p := range rr.room.GetParticipants() {
+		if p.IsDisconnected() {
+			continue
+		}
+		state, err := participantStateForRelay(p)
+		if err != nil {
+			continue
+		}
+		rr.manager.send(from, &relay.Message{
+			Type:        relay.MessageTypeParticipantUpdate,
+			Room:        rr.room.Name(),
+			Participant: state,
+		})
+	}
+}
+
+func (rr *RoomRelay) handleParticipantUpdate(from livekit.NodeID, state *relay.ParticipantState) {
+	if state == nil {
+		return
+	}
+	// a participant update implies the node is hosting the room
+	rr.addPeer(from)
+
+	info := &livekit.ParticipantInfo{}
+	if err := proto.Unmarshal(state.Info, info); err != nil {
+		rr.logger.Warnw("could not decode relayed participant", err, "peerNodeID", from)
+		return
+	}
+	var permission *livekit.SubscriptionPermission
+	if len(state.Permission) != 0 {
+		permission = &livekit.SubscriptionPermission{}
+		if err := proto.Unmarshal(state.Permission, permission); err != nil {
+			rr.logger.Warnw("could not decode relayed permission", err, "peerNodeID", from)
+			return
+		}
+	}
+
+	if info.State == livekit.ParticipantInfo_DISCONNECTED {
+		rr.handleParticipantLeft(from, livekit.ParticipantID(info.Sid))
+		return
+	}
+
+	p := rr.room.getRelayedParticipant(livekit.ParticipantIdentity(info.Identity))
+	if p != nil && (p.ID() != livekit.ParticipantID(info.Sid) || p.NodeID() != from) {
+		// participant reconnected with a new session
+		rr.room.removeRelayedParticipant(p)
+		p = nil
+	}
+
+	if p == nil {
+		p = NewRelayedParticipant(RelayedParticipantParams{
+			NodeID:           from,
+			Info:             info,
+			VersionGenerator: rr.manager.params.VersionGenerator,
+			Logger: LoggerWithParticipant(
+				rr.logger,
+				livekit.ParticipantIdentity(info.Identity),
+				livekit.ParticipantID(info.Sid),
+				true,
+			).WithValues("peerNodeID", from),
+		})
+		rr.room.addRelayedParticipant(p)
+	} else if !p.UpdateInfo(info) {
+		return
+	}
+
+	existingPermission, _ := p.SubscriptionPermission()
+	permissionChanged := !proto.Equal(existingPermission, permission)
+	if permissionChanged {
+		if err := p.UpdateSubscriptionPermission(permission, protoutils.TimedVersion(0), rr.room.GetParticipantByID); err != nil {
+			p.GetLogger().Warnw("could not apply relayed permission", err)
+		}
+	}
+
+	// tracks are relayed once the publisher's node has receivers for them
+	trackStates := make(map[livekit.TrackID]*relay.TrackState, len(state.Tracks))
+	for _, ts := range state.Tracks {
+		trackStates[ts.TrackID] = ts
+	}
+
+	published := make(map[livekit.TrackID]struct{}, len(info.Tracks))
+	var newTracks []types.MediaTrack
+	for _, ti := range info.Tracks {
+		trackID := livekit.TrackID(ti.Sid)
+		published[trackID] = struct{}{}
+		ts := trackStates[trackID]
+
+		if existing, ok := p.GetPublishedTrack(trackID).(*RelayedMediaTrack); ok {
+			existing.UpdateTrackInfo(ti)
+			if existing.IsMuted() != ti.Muted {
+				existing.SetMuted(ti.Muted)
+			}
+			if ts != nil {
+				for priority, cs := range ts.Codecs {
+					existing.AddCodec(cs, priority)
+				}
+			}
+			continue
+		}
+
+		if ts == nil || len(ts.Codecs) == 0 {
+			continue
+		}
+
+		track := NewRelayedMediaTrack(RelayedMediaTrackParams{
+			NodeID:              from,
+			ParticipantID:       p.ID(),
+			ParticipantIdentity: p.Identity(),
+			ParticipantVersion:  info.Version,
+			ReceiverConfig:      rr.manager.params.ReceiverConfig,
+			SubscriberConfig:    rr.manager.params.SubscriberConfig,
+			PLIThrottleConfig:   rr.manager.params.PLIThrottle,
+			AudioConfig:         rr.manager.params.AudioConfig,
+			VideoConfig:         rr.manager.params.VideoConfig,
+			Telemetry:           rr.manager.params.Telemetry,
+			Logger:              LoggerWithTrack(p.GetLogger(), trackID, true),
+			Streams:             rr,
+		}, ti)
+		for priority, cs := range ts.Codecs {
+			track.AddCodec(cs, priority)
+		}
+		p.AddPublishedTrack(track)
+		newTracks = append(newTracks, track)
+	}
+
+	var removedTracks []types.MediaTrack
+	for _, track := range p.GetPublishedTracks() {
+		if _, ok := published[track.ID()]; !ok {
+			p.RemovePublishedTrack(track, false)
+			removedTracks = append(removedTracks, track)
+		}
+	}
+
+	rr.room.onRelayedParticipantChanged(p, newTracks, removedTracks, permissionChanged)
+}
+
+func (rr *RoomRelay) handleParticipantLeft(from livekit.NodeID, participantID livekit.ParticipantID) {
+	p := rr.room.getRelayedParticipantByID(participantID)
+	if p == nil || p.NodeID() != from {
+		return
+	}
+
+	rr.room.removeRelayedParticipant(p)
+}
+
+func (rr *RoomRelay) handleData(data []byte, kind livekit.DataPacket_Kind) {
+	dp := &livekit.DataPacket{}
+	if err := proto.Unmarshal(data, dp); err != nil {
+		rr.logger.Warnw("could not decode relayed data packet", err)
+		return
+	}
+
+	BroadcastDataPacketForRoom(rr.room, nil, kind, dp, rr.logger)
+}
+
+func (rr *RoomRelay) handleSubscribe(from livekit.NodeID, req *relay.StreamRequest) {
+	if req == nil {
+		return
+	}
+
+	p := rr.room.GetParticipantByID(req.ParticipantID)
+	if p == nil {
+		return
+	}
+	track := p.GetPublishedTrack(req.TrackID)
+	if track == nil {
+		return
+	}
+
+	mimeType := mime.NormalizeMimeType(req.MimeType)
+	for _, receiver := range track.Receivers() {
+		if receiver.Mime() == mimeType {
+			rr.manager.startOutboundStream(from, req.StreamID, track, receiver)
+			return
+		}
+	}
+}
+
+func (rr *RoomRelay) handleSubscribedQuality(from livekit.NodeID, qualities []*relay.SubscribedQuality) {
+	byTrack := make(map[livekit.TrackID][]types.SubscribedCodecQuality)
+	for _, q := range qualities {
+		byTrack[q.TrackID] = append(byTrack[q.TrackID], types.SubscribedCodecQuality{
+			CodecMime: mime.NormalizeMimeType(q.MimeType),
+			Quality:   q.Quality,
+		})
+	}
+
+	for trackID, maxQualities := range byTrack {
+		info := rr.room.trackManager.GetTrackInfo(trackID)
+		if info == nil {
+			continue
+		}
+		if p := rr.room.GetParticipantByID(info.PublisherID); p != nil {
+			_ = p.UpdateSubscribedQuality(from, trackID, maxQualities)
+		}
+	}
+}
+
+func (rr *RoomRelay) releaseIdleStreams() {
+	for _, p := range rr.room.GetRelayedParticipants() {
+		for _, track := range p.GetPublishedTracks() {
+			if rt, ok := track.(*RelayedMediaTrack); ok {
+				rt.ReleaseIdleStreams()
+			}
+		}
+	}
+}
+
+// ---------------------------------------
+// relayStreams implementation
+
+func (rr *RoomRelay) registerStream(receiver *sfu.RelayReceiver) uint32 {
+	return rr.manager.registerStream(receiver)
+}
+
+func (rr *RoomRelay) unregisterStream(streamID uint32) {
+	rr.manager.unregisterStream(streamID)
+}
+
+func (rr *RoomRelay) requestStream(nodeID livekit.NodeID, req *relay.StreamRequest) {
+	rr.manager.send(nodeID, &relay.Message{
+		Type:   relay.MessageTypeSubscribe,
+		Room:   rr.room.Name(),
+		Stream: req,
+	})
+}
+
+func (rr *RoomRelay) releaseStream(nodeID livekit.NodeID, req *relay.StreamRequest) {
+	rr.manager.send(nodeID, &relay.Message{
+		Type:   relay.MessageTypeUnsubscribe,
+		Room:   rr.room.Name(),
+		Stream: req,
+	})
+}
+
+func (rr *RoomRelay) requestKeyFrame(nodeID livekit.NodeID, streamID uint32, layer int32) {
+	rr.manager.requestKeyFrame(nodeID, streamID, layer)
+}
+
+func (rr *RoomRelay) sendSubscribedQuality(nodeID livekit.NodeID, qualities []*relay.SubscribedQuality) {
+	rr.manager.send(nodeID, &relay.Message{
+		Type:      relay.MessageTypeSubscribedQuality,
+		Room:      rr.room.Name(),
+		Qualities: qualities,
+	})
+}
@@ -16,6 +16,7 @@ package rtc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
 	"slices"
@@ -24,6 +25,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/pion/webrtc/v4"
 	"go.uber.org/atomic"
 	"golang.org/x/exp/maps"
 	"google.golang.org/protobuf/proto"
@@ -32,6 +34,7 @@ import (
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/observability/roomobs"
 	"github.com/livekit/protocol/rpc"
+	protosignalling "github.com/livekit/protocol/signalling"
 	"github.com/livekit/protocol/utils"
 	"github.com/livekit/protocol/utils/guid"
 	"github.com/livekit/psrpc"
@@ -67,6 +70,9 @@ var (
 	roomUpdateInterval = 5 * time.Second // frequency to update room participant counts
 
 	ErrJobShutdownTimeout = psrpc.NewErrorf(psrpc.DeadlineExceeded, "timed out waiting for agent job to shutdown")
+	ErrJobHandoffTimeout  = psrpc.NewErrorf(psrpc.DeadlineExceeded, "timed out waiting for agent of replacement job to join")
+	ErrAgentJobNotFound   = psrpc.NewErrorf(psrpc.NotFound, "agent job not found")
+	ErrNoJobParticipant   = psrpc.NewErrorf(psrpc.InvalidArgument, "agent job has no participant identity")
 )
 
 // Duplicate the service.AgentStore interface to avoid a rtc -> service -> rtc import cycle
@@ -101,10 +107,12 @@ type Room struct {
 
 	lock sync.RWMutex
 
-	protoRoom  *livekit.Room
-	internal   *livekit.RoomInternal
-	protoProxy *utils.ProtoProxy[*livekit.Room]
-	logger     logger.Logger
+	protoRoom *livekit.Room
+	// version of the metadata, incremented when it changes
+	metadataVersion uint64
+	internal        *livekit.RoomInternal
+	protoProxy      *utils.ProtoProxy[*livekit.Room]
+	logger          logger.Logger
 
 	config          WebRTCConfig
 	roomConfig      config.RoomConfig
@@ -118,6 +126,12 @@ type Room struct {
 	// agents
 	agentClient agent.Client
 	agentStore  AgentStore
+	// cancels launches of agent jobs still waiting for a worker when the room closes
+	agentCtx    context.Context
+	agentCancel context.CancelFunc
+	// per participant, cancels launches of agent jobs targeting the participant when it leaves
+	agentLaunchLock     sync.Mutex
+	participantAgentCtx map[livekit.ParticipantID]participantAgentContext
 
 	// map of identity -> Participant
 	participants              map[livekit.ParticipantIdentity]types.LocalParticipant
@@ -127,6 +141,46 @@ type Room struct {
 	agentParticpants          map[livekit.ParticipantIdentity]*agentJob
 	bufferFactory             *buffer.FactoryOfBufferFactory
 
+	// versions of metadata and attributes of participants which left, a later session of the identity continues from them
+	leftMetadataVersions map[livekit.ParticipantIdentity]types.MetadataVersions
+
+	// set when the room can span nodes, participants of other nodes are replicated as relayed participants
+	relay               *RoomRelay
+	relayedParticipants map[livekit.ParticipantIdentity]*RelayedParticipant
+
+	// participants held in the lobby till admitted, they are connected but hidden from others.
+	// pendingParticipants is modified holding both lock and lobbyLock, so that it can be read holding either
+	lobbyEnabled        bool
+	lobbyLock           sync.RWMutex
+	pendingParticipants map[livekit.ParticipantIdentity]*pendingParticipant
+
+	// requests of participants to publish, waiting for a room admin to approve or deny them
+	publishRequests map[livekit.ParticipantIdentity]*publishRequest
+
+	// rule inputs of subscribers when subscription rules were last evaluated for them
+	subscriptionRuleInputs map[livekit.ParticipantID]subscriptionRuleInputs
+
+	// set by room admins to decide which tracks participants subscribe to
+	subscriptionPolicy *subscriptionPolicy
+
+	// set by room admins to bound what participants receive, the room bitrate is shared by all subscribers
+	subscriptionLimits            *SubscriptionLimits
+	participantSubscriptionLimits map[livekit.ParticipantIdentity]*SubscriptionLimits
+
+	// set by room admins to moderate data messages sent by participants
+	dataPolicy *dataPolicy
+
+	// set by room admins to keep messages of selected topics, replayed to participants joining
+	dataRetention    *DataRetention
+	dataHistoryStore DataHistoryStore
+	dataHistoryQueue *sutils.OpsQueue
+
+	// shared key/value state of the room, updated by participants and the server API
+	roomStateLock    sync.Mutex
+	roomStateVersion uint64
+	roomState        map[string]*types.RoomStateEntry
+	limits           config.LimitConfig
+
 	// batch update participant info for non-publishers
 	batchedUpdates   map[livekit.ParticipantIdentity]*ParticipantUpdate
 	batchedUpdatesMu sync.Mutex
@@ -154,26 +208,40 @@ type Room struct {
 type ParticipantOptions struct {
 	AutoSubscribe          bool
 	AutoSubscribeDataTrack bool
+	// when set, participant joins in the lobby and is granted this permission once admitted
+	LobbyPermission *livekit.ParticipantPermission
 }
 
 type agentDispatch struct {
 	*livekit.AgentDispatch
+	ctx     context.Context
+	cancel  context.CancelFunc
 	lock    sync.Mutex
 	pending map[chan struct{}]struct{}
 }
 
+type participantAgentContext struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
 type agentJob struct {
 	*livekit.Job
-	lock sync.Mutex
-	done chan struct{}
+	lock   sync.Mutex
+	joined chan struct{}
+	done   chan struct{}
 }
 
 // This provides utilities attached the agent dispatch to ensure that all pending jobs are created
 // before terminating jobs attached to an agent dispatch. This avoids a race that could cause some pending jobs
-// to not be terminated when a dispatch is deleted.
-func newAgentDispatch(ad *livekit.AgentDispatch) *agentDispatch {
+// to not be terminated when a dispatch is deleted. Launches still waiting for a worker are cancelled
+// with the dispatch context.
+func newAgentDispatch(ctx context.Context, ad *livekit.AgentDispatch) *agentDispatch {
+	ctx, cancel := context.WithCancel(ctx)
 	return &agentDispatch{
 		AgentDispatch: ad,
+		ctx:           ctx,
+		cancel:        cancel,
 		pending:       make(map[chan struct{}]struct{}),
 	}
 }
@@ -202,12 +270,38 @@ func (ad *agentDispatch) waitForPendingJobs() {
 	}
 }
 
-// This provides utilities to ensure that an agent left the room when killing a job
+// This provides utilities to ensure that an agent joined the room when handing off a job,
+// and that it left the room when killing a job
 func newAgentJob(j *livekit.Job) *agentJob {
 	return &agentJob{
-		Job:  j,
-		done: make(chan struct{}),
+		Job:    j,
+		joined: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+func (j *agentJob) participantJoined() {
+	j.lock.Lock()
+	if j.joined != nil {
+		close(j.joined)
+		j.joined = nil
+	}
+	j.lock.Unlock()
+}
+
+func (j *agentJob) waitForParticipantJoining(ctx context.Context) error {
+	j.lock.Lock()
+	joined := j.joined
+	j.lock.Unlock()
+
+	if joined != nil {
+		select {
+		case <-joined:
+		case <-ctx.Done():
+			return ErrJobHandoffTimeout
+		}
 	}
+	return nil
 }
 
 func (j *agentJob) participantLeft() {
@@ -249,6 +343,7 @@ func NewRoom(
 	agentClient agent.Client,
 	agentStore AgentStore,
 	egressLauncher EgressLauncher,
+	restoredAgentDispatches []*livekit.AgentDispatch,
 ) *Room {
 	r := &Room{
 		protoRoom: utils.CloneProto(room),
@@ -266,12 +361,19 @@ func NewRoom(
 		agentClient:                          agentClient,
 		agentStore:                           agentStore,
 		agentDispatches:                      make(map[string]*agentDispatch),
+		participantAgentCtx:                  make(map[livekit.ParticipantID]participantAgentContext),
+		subscriptionRuleInputs:               make(map[livekit.ParticipantID]subscriptionRuleInputs),
 		serverInfo:                           serverInfo,
 		participants:                         make(map[livekit.ParticipantIdentity]types.LocalParticipant),
 		participantOpts:                      make(map[livekit.ParticipantIdentity]*ParticipantOptions),
 		participantRequestSources:            make(map[livekit.ParticipantIdentity]routing.MessageSource),
 		hasPublished:                         make(map[livekit.ParticipantIdentity]bool),
 		agentParticpants:                     make(map[livekit.ParticipantIdentity]*agentJob),
+		leftMetadataVersions:                 make(map[livekit.ParticipantIdentity]types.MetadataVersions),
+		relayedParticipants:                  make(map[livekit.ParticipantIdentity]*RelayedParticipant),
+		pendingParticipants:                  make(map[livekit.ParticipantIdentity]*pendingParticipant),
+		publishRequests:                      make(map[livekit.ParticipantIdentity]*publishRequest),
+		participantSubscriptionLimits:        make(map[livekit.ParticipantIdentity]*SubscriptionLimits),
 		bufferFactory:                        buffer.NewFactoryOfBufferFactory(config.Receiver.PacketBufferSizeVideo, config.Receiver.PacketBufferSizeAudio),
 		batchedUpdates:                       make(map[livekit.ParticipantIdentity]*ParticipantUpdate),
 		closed:                               make(chan struct{}),
@@ -284,6 +386,7 @@ func NewRoom(
 			MaxSize: dataMessageCacheSize,
 		}),
 	}
+	r.agentCtx, r.agentCancel = context.WithCancel(context.Background())
 	r.trackManager = NewRoomTrackManager(r.logger)
 	r.localParticipantListener = &localParticipantListener{room: r}
 
@@ -300,9 +403,14 @@ func NewRoom(
 	}
 	r.protoProxy = utils.NewProtoProxy(roomUpdateInterval, r.updateProto)
 
-	r.createAgentDispatchesFromRoomAgent()
+	if len(restoredAgentDispatches) != 0 {
+		// room is continuing from another node/session, agents have already been dispatched
+		r.restoreAgentDispatches(restoredAgentDispatches)
+	} else {
+		r.createAgentDispatchesFromRoomAgent()
 
-	r.launchRoomAgents(maps.Values(r.agentDispatches))
+		r.launchRoomAgents(maps.Values(r.agentDispatches))
+	}
 
 	go r.audioUpdateWorker()
 	go r.connectionQualityWorker()
@@ -364,8 +472,12 @@ func (r *Room) GetParticipants() []types.LocalParticipant {
 	return maps.Values(r.participants)
 }
 
+// GetLocalParticipants returns participants taking part in the room, i. e. excluding those waiting in the lobby
 func (r *Room) GetLocalParticipants() []types.LocalParticipant {
-	return r.GetParticipants()
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.getAdmittedParticipantsLocked()
 }
 
 func (r *Room) GetParticipantCount() int {
@@ -466,7 +578,10 @@ func (r *Room) Join(
 		r.joinedAt.Store(time.Now().Unix())
 	}
 
-	r.launchTargetAgents(maps.Values(r.agentDispatches), participant, livekit.JobType_JT_PARTICIPANT)
+	if !participant.IsMigration() {
+		// agents targeting a migrating participant were launched on the node it is migrating from
+		r.launchTargetAgents(maps.Values(r.agentDispatches), participant, livekit.JobType_JT_PARTICIPANT)
+	}
 
 	r.logger.Debugw(
 		"new participant joined",
@@ -486,7 +601,21 @@ func (r *Room) Join(
 
 	r.participants[participant.Identity()] = participant
 	r.participantOpts[participant.Identity()] = opts
+	if agentJob := r.agentParticpants[participant.Identity()]; agentJob != nil {
+		agentJob.participantJoined()
+	}
 	r.participantRequestSources[participant.Identity()] = requestSource
+	if opts != nil && opts.LobbyPermission != nil {
+		r.lobbyLock.Lock()
+		r.pendingParticipants[participant.Identity()] = &pendingParticipant{
+			permission: opts.LobbyPermission,
+			heldAt:     time.Now(),
+		}
+		r.lobbyLock.Unlock()
+		go r.onParticipantPending(participant)
+	}
+	// re-distribute the room budget and apply limits kept from a previous session
+	go r.maybeApplySubscriptionLimits()
 
 	if r.onParticipantChanged != nil {
 		r.onParticipantChanged(participant)
@@ -498,13 +627,22 @@ func (r *Room) Join(
 		}
 	})
 
-	joinResponse := r.createJoinResponseLocked(participant, iceServers)
-	if err := participant.SendJoinResponse(joinResponse); err != nil {
-		prometheus.RecordServiceOperationError("participant_join", "send_response")
-		return err
-	}
+	if participant.IsMigration() {
+		// a migrating participant resumes its session, it gets a reconnect response instead of a join response
+		if err := r.sendMigrationResponseLocked(participant, iceServers); err != nil {
+			prometheus.RecordServiceOperationError("participant_join", "send_response")
+			return err
+		}
+		// migration moves to sync state once client sends its sync state, see onSyncState
+	} else {
+		joinResponse := r.createJoinResponseLocked(participant, iceServers)
+		if err := participant.SendJoinResponse(joinResponse); err != nil {
+			prometheus.RecordServiceOperationError("participant_join", "send_response")
+			return err
+		}
 
-	participant.SetMigrateState(types.MigrateStateComplete)
+		participant.SetMigrateState(types.MigrateStateComplete)
+	}
 
 	if participant.SubscriberAsPrimary() {
 		// initiates sub connection as primary
@@ -527,6 +665,26 @@ func (r *Room) Join(
 	return nil
 }
 
+func (r *Room) sendMigrationResponseLocked(participant types.LocalParticipant, iceServers []*livekit.ICEServer) error {
+	if err := participant.HandleReconnectAndSendResponse(livekit.ReconnectReason_RR_UNKNOWN, &livekit.ReconnectResponse{
+		IceServers:          iceServers,
+		ClientConfiguration: participant.GetClientConfiguration(),
+		ServerInfo:          r.serverInfo,
+		LastMessageSeq:      participant.GetLastReliableSequence(false),
+	}); err != nil {
+		return err
+	}
+
+	// participants on this node could be different from the ones on the node migrating from,
+	// send full state so that client can reconcile
+	updates := GetOtherParticipantInfo(nil, false, toParticipants(maps.Values(r.participants)), false)
+	if err := participant.SendParticipantUpdate(updates); err != nil {
+		return err
+	}
+
+	return participant.SendRoomUpdate(r.protoProxy.Get())
+}
+
 func (r *Room) ReplaceParticipantRequestSource(identity livekit.ParticipantIdentity, reqSource routing.MessageSource) {
 	r.lock.Lock()
 	if rs, ok := r.participantRequestSources[identity]; ok {
@@ -586,7 +744,12 @@ func (r *Room) ResumeParticipant(
 	}
 
 	// include the local participant's info as well, since metadata could have been changed
-	updates := GetOtherParticipantInfo(nil, false, toParticipants(r.GetParticipants()), false)
+	var updates []*livekit.ParticipantInfo
+	if r.IsPending(p.Identity()) {
+		updates = []*livekit.ParticipantInfo{p.ToProto()}
+	} else {
+		updates = GetOtherParticipantInfo(nil, false, toParticipants(r.GetLocalParticipants()), false)
+	}
 	if err := p.SendParticipantUpdate(updates); err != nil {
 		return err
 	}
@@ -619,6 +782,10 @@ func (r *Room) onSyncState(participant types.LocalParticipant, state *livekit.Sy
 	pLogger := participant.GetLogger()
 	pLogger.Infow("setting sync state", "state", logger.Proto(state))
 
+	if participant.IsMigration() && participant.MigrateState() == types.MigrateStateInit {
+		r.applyMigrateSyncState(participant, state)
+	}
+
 	shouldReconnect := false
 	pubTracks := state.GetPublishTracks()
 	existingPubTracks := participant.GetPublishedTracks()
@@ -695,6 +862,35 @@ func (r *Room) onSyncState(participant types.LocalParticipant, state *livekit.Sy
 	return nil
 }
 
+// applyMigrateSyncState seeds a participant migrating in with the session state it had on the node
+// it is migrating from and lets pending negotiation proceed
+func (r *Room) applyMigrateSyncState(participant types.LocalParticipant, state *livekit.SyncState) {
+	var previousOffer, previousAnswer *webrtc.SessionDescription
+	if state.Offer != nil {
+		offer, _, _ := protosignalling.FromProtoSessionDescription(state.Offer)
+		previousOffer = &offer
+	}
+	if state.Answer != nil {
+		answer, _, _ := protosignalling.FromProtoSessionDescription(state.Answer)
+		previousAnswer = &answer
+	}
+	participant.SetMigrateInfo(
+		previousOffer,
+		previousAnswer,
+		state.PublishTracks,
+		state.DataChannels,
+		state.DatachannelReceiveStates,
+		state.PublishDataTracks,
+	)
+
+	// allow pending publisher offer to be processed, migration completes once transports are connected
+	participant.SetMigrateState(types.MigrateStateSync)
+
+	if participant.SubscriberAsPrimary() {
+		participant.Negotiate(true)
+	}
+}
+
 func (r *Room) onUpdateSubscriptionPermission(participant types.LocalParticipant, subscriptionPermission *livekit.SubscriptionPermission) error {
 	if err := participant.UpdateSubscriptionPermission(subscriptionPermission, utils.TimedVersion(0), r.GetParticipantByID); err != nil {
 		return err
@@ -702,9 +898,56 @@ func (r *Room) onUpdateSubscriptionPermission(participant types.LocalParticipant
 	for _, track := range participant.GetPublishedTracks() {
 		r.trackManager.NotifyTrackChanged(track.ID())
 	}
+	if relay := r.getRelay(); relay != nil {
+		relay.ParticipantChanged(participant)
+	}
 	return nil
 }
 
+// reevaluateSubscriptionRules applies subscription permissions granted by rules of publishers
+// after attributes or claims of a subscriber have changed, other updates of the subscriber are skipped
+func (r *Room) reevaluateSubscriptionRules(sub types.LocalParticipant) {
+	if IsParticipantExemptFromTrackPermissionsRestrictions(sub) {
+		return
+	}
+
+	inputs := newSubscriptionRuleInputs(sub)
+	r.lock.Lock()
+	prev, ok := r.subscriptionRuleInputs[sub.ID()]
+	changed := !ok || !prev.equal(inputs)
+	if changed && r.participants[sub.Identity()] == sub {
+		r.subscriptionRuleInputs[sub.ID()] = inputs
+	}
+	r.lock.Unlock()
+	if !changed {
+		return
+	}
+
+	var publishers []types.Participant
+	for _, p := range r.GetParticipants() {
+		publishers = append(publishers, p)
+	}
+	publishers = append(publishers, r.GetRelayedParticipants()...)
+	for _, pub := range publishers {
+		if pub.Identity() == sub.Identity() || !pub.HasSubscriptionRules() {
+			continue
+		}
+
+		for _, track := range pub.GetPublishedTracks() {
+			hasPermission := pub.HasPermission(track.ID(), sub)
+			isSubscriber := track.IsSubscriber(sub.ID())
+			switch {
+			case isSubscriber && !hasPermission:
+				sub.GetLogger().Infow("revoking subscription, no longer matching subscription rules", "trackID", track.ID())
+				track.RemoveSubscriber(sub.ID(), false)
+			case !isSubscriber && hasPermission:
+				// let pending subscriptions waiting for permission resolve the track again
+				r.trackManager.NotifyTrackChanged(track.ID())
+			}
+		}
+	}
+}
+
 func (r *Room) ResolveMediaTrackForSubscriber(sub types.LocalParticipant, trackID livekit.TrackID) types.MediaResolverResult {
 	res := types.MediaResolverResult{}
 
@@ -720,10 +963,15 @@ func (r *Room) ResolveMediaTrackForSubscriber(sub types.LocalParticipant, trackI
 	res.PublisherIdentity = info.PublisherIdentity
 	res.PublisherID = info.PublisherID
 
-	pub := r.GetParticipantByID(info.PublisherID)
+	var pub types.Participant
+	if lp := r.GetParticipantByID(info.PublisherID); lp != nil {
+		pub = lp
+	} else if rp := r.getRelayedParticipantByID(info.PublisherID); rp != nil {
+		pub = rp
+	}
 	// when publisher is not found, we will assume it doesn't have permission to access
 	if pub != nil {
-		res.HasPermission = IsParticipantExemptFromTrackPermissionsRestrictions(sub) || pub.HasPermission(trackID, sub.Identity())
+		res.HasPermission = IsParticipantExemptFromTrackPermissionsRestrictions(sub) || pub.HasPermission(trackID, sub)
 	}
 
 	return res
@@ -804,12 +1052,15 @@ func (r *Room) Close(reason types.ParticipantCloseReason) {
 	close(r.closed)
 	r.lock.Unlock()
 
+	r.agentCancel()
+
 	r.logger.Infow("closing room")
 	for _, p := range r.GetParticipants() {
 		_ = p.Close(true, reason, false)
 	}
 
 	r.protoProxy.Stop()
+	r.stopDataHistory()
 
 	if r.onClose != nil {
 		r.onClose()
@@ -828,11 +1079,84 @@ func (r *Room) SendDataPacket(dp *livekit.DataPacket, kind livekit.DataPacket_Ki
 	r.onDataMessage(nil, kind, dp)
 }
 
+// sendServerData sends a JSON encoded message from the server to the given participants on a reserved topic
+func (r *Room) sendServerData(topic string, msg any, identities []string) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		r.logger.Warnw("could not marshal server data", err, "topic", topic)
+		return
+	}
+
+	r.SendDataPacket(&livekit.DataPacket{
+		Kind:                  livekit.DataPacket_RELIABLE,
+		DestinationIdentities: identities,
+		Value: &livekit.DataPacket_User{
+			User: &livekit.UserPacket{
+				Payload:               payload,
+				DestinationIdentities: identities,
+				Topic:                 &topic,
+			},
+		},
+	}, livekit.DataPacket_RELIABLE)
+}
+
+// getRoomAdminIdentities returns participants with room admin grant, excluding those waiting in the lobby
+func (r *Room) getRoomAdminIdentities() []string {
+	var admins []string
+	for _, p := range r.GetLocalParticipants() {
+		if grants := p.ClaimGrants(); grants != nil && grants.Video != nil && grants.Video.RoomAdmin {
+			admins = append(admins, string(p.Identity()))
+		}
+	}
+	return admins
+}
+
 func (r *Room) SetMetadata(metadata string) <-chan struct{} {
+	_, done, _ := r.UpdateMetadata(metadata, nil)
+	return done
+}
+
+// UpdateMetadata sets the metadata of the room when expectedVersion is nil or matches the version of the metadata,
+// 0 expecting it not to have been updated since the room was created. Returns the version of the metadata and
+// a channel closed once the update is applied.
+func (r *Room) UpdateMetadata(metadata string, expectedVersion *uint64) (uint64, <-chan struct{}, error) {
 	r.lock.Lock()
-	r.protoRoom.Metadata = metadata
+	if expectedVersion != nil && *expectedVersion != r.metadataVersion {
+		version := r.metadataVersion
+		r.lock.Unlock()
+		return version, nil, &MetadataConflictError{
+			Conflicts: []*MetadataConflict{{Version: version}},
+			Versions:  types.MetadataVersions{Metadata: version},
+		}
+	}
+	if r.protoRoom.Metadata != metadata {
+		r.protoRoom.Metadata = metadata
+		r.metadataVersion++
+	}
+	version := r.metadataVersion
 	r.lock.Unlock()
-	return r.protoProxy.MarkDirty(true)
+	return version, r.protoProxy.MarkDirty(true), nil
+}
+
+// MetadataVersion returns the version of the room metadata, 0 when it was not updated since the room was created
+func (r *Room) MetadataVersion() uint64 {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.metadataVersion
+}
+
+// ParticipantMetadataVersions returns the versions of metadata and attributes a new session of a participant
+// starts with, nil unless an earlier session of the identity left the room
+func (r *Room) ParticipantMetadataVersions(identity livekit.ParticipantIdentity, attributes map[string]string) *types.MetadataVersions {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	previous, ok := r.leftMetadataVersions[identity]
+	if !ok {
+		return nil
+	}
+	return nextSessionVersions(previous, attributes)
 }
 
 func (r *Room) sendRoomUpdate() {
@@ -897,6 +1221,9 @@ func (r *Room) DeleteAgentDispatch(dispatchID string) (*livekit.AgentDispatch, e
 	delete(r.agentDispatches, dispatchID)
 	r.lock.Unlock()
 
+	// stop waiting for workers, jobs not launched yet end as failed
+	ad.cancel()
+
 	// Should Delete be synchronous instead?
 	go func() {
 		ad.waitForPendingJobs()
@@ -1032,15 +1359,10 @@ func (r *Room) createJoinResponseLocked(
 	iceConfig := participant.GetICEConfig()
 	hasICEFallback := iceConfig.GetPreferencePublisher() != livekit.ICECandidateType_ICT_NONE || iceConfig.GetPreferenceSubscriber() != livekit.ICECandidateType_ICT_NONE
 	return &livekit.JoinResponse{
-		Room:        r.ToProto(),
-		Participant: participant.ToProto(),
-		OtherParticipants: GetOtherParticipantInfo(
-			participant,
-			false, // isMigratingIn
-			toParticipants(maps.Values(r.participants)),
-			false, // skipSubscriberBroadcast
-		),
-		IceServers: iceServers,
+		Room:              r.ToProto(),
+		Participant:       participant.ToProto(),
+		OtherParticipants: r.getOtherParticipantInfoLocked(participant),
+		IceServers:        iceServers,
 		// indicates both server and client support subscriber as primary
 		SubscriberPrimary:   participant.SubscriberAsPrimary(),
 		ClientConfiguration: participant.GetClientConfiguration(),
@@ -1056,36 +1378,44 @@ func (r *Room) createJoinResponseLocked(
 	}
 }
 
-// a ParticipantImpl in the room added a new track, subscribe other participants to it
-func (r *Room) onTrackPublished(participant types.Participant, track types.MediaTrack) {
-	r.trackManager.AddTrack(track, participant.Identity(), participant.ID())
-
-	// publish participant update, since track state is changed
-	r.broadcastParticipantState(participant, broadcastOptions{skipSource: true})
-
-	r.lock.RLock()
-	// subscribe all existing participants to this MediaTrack
+// subscribe all existing participants to a new MediaTrack
+func (r *Room) subscribeToNewTrackLocked(publisher types.Participant, track types.MediaTrack) {
 	for _, existingParticipant := range r.participants {
-		if existingParticipant == participant {
+		if existingParticipant.ID() == publisher.ID() {
 			// skip publishing participant
 			continue
 		}
+		if r.pendingParticipants[existingParticipant.Identity()] != nil {
+			// subscribes once admitted
+			continue
+		}
 		if existingParticipant.State() != livekit.ParticipantInfo_ACTIVE {
 			// not fully joined. don't subscribe yet
 			continue
 		}
-		if !r.autoSubscribe(existingParticipant) {
+		if !r.shouldSubscribeLocked(existingParticipant, publisher, track) {
 			continue
 		}
 
 		existingParticipant.GetLogger().Debugw(
 			"subscribing to new track",
-			"publisher", participant.Identity(),
-			"publisherID", participant.ID(),
+			"publisher", publisher.Identity(),
+			"publisherID", publisher.ID(),
 			"trackID", track.ID(),
 		)
 		existingParticipant.SubscribeToTrack(track.ID(), false)
 	}
+}
+
+// a ParticipantImpl in the room added a new track, subscribe other participants to it
+func (r *Room) onTrackPublished(participant types.Participant, track types.MediaTrack) {
+	r.trackManager.AddTrack(track, participant.Identity(), participant.ID())
+
+	// publish participant update, since track state is changed
+	r.broadcastParticipantState(participant, broadcastOptions{skipSource: true})
+
+	r.lock.RLock()
+	r.subscribeToNewTrackLocked(participant, track)
 	onParticipantChanged := r.onParticipantChanged
 	r.lock.RUnlock()
 
@@ -1208,6 +1538,22 @@ func (r *Room) onParticipantUpdate(p types.Participant) {
 	if r.onParticipantChanged != nil {
 		r.onParticipantChanged(p)
 	}
+	if lp, ok := p.(types.LocalParticipant); ok {
+		r.reevaluateSubscriptionRules(lp)
+		// subscribe permission decides who shares the room budget
+		r.maybeApplySubscriptionLimits()
+	}
+	r.reconcileSubscriptionPolicy(p)
+}
+
+// onFullyEstablished sends the room state and replays data history once the data channels of the participant are open,
+// participants in the lobby get them once admitted
+func (r *Room) onFullyEstablished(p types.LocalParticipant) {
+	if r.IsPending(p.Identity()) {
+		return
+	}
+	r.sendRoomStateSnapshot(p)
+	r.replayDataHistory(p)
 }
 
 func (r *Room) onStateChange(p types.LocalParticipant) {
@@ -1221,8 +1567,10 @@ func (r *Room) onStateChange(p types.LocalParticipant) {
 
 	switch p.State() {
 	case livekit.ParticipantInfo_ACTIVE:
-		// subscribe participant to existing published tracks
-		r.subscribeToExistingTracks(p, false)
+		// subscribe participant to existing published tracks, participants in the lobby subscribe once admitted
+		if !r.IsPending(p.Identity()) {
+			r.subscribeToExistingTracks(p, false)
+		}
 
 		connectTime := time.Since(p.ConnectedAt())
 		meta := &livekit.AnalyticsClientMeta{
@@ -1237,13 +1585,15 @@ func (r *Room) onStateChange(p types.LocalParticipant) {
 				break
 			}
 		}
-		r.telemetry.ParticipantActive(context.Background(),
-			r.ToProto(),
-			p.ToProto(),
-			meta,
-			false,
-			p.TelemetryGuard(),
-		)
+		if !r.deferParticipantActive(p, meta) {
+			r.telemetry.ParticipantActive(context.Background(),
+				r.ToProto(),
+				p.ToProto(),
+				meta,
+				false,
+				p.TelemetryGuard(),
+			)
+		}
 
 		p.GetReporter().Tx(func(tx roomobs.ParticipantSessionTx) {
 			tx.ReportClientConnectTime(uint16(connectTime.Milliseconds()))
@@ -1265,6 +1615,11 @@ func (r *Room) onStateChange(p types.LocalParticipant) {
 }
 
 func (r *Room) onDataMessage(source types.LocalParticipant, kind livekit.DataPacket_Kind, dp *livekit.DataPacket) {
+	if !r.applyDataPolicy(source, dp) {
+		return
+	}
+	r.retainDataMessage(source, dp)
+
 	if kind == livekit.DataPacket_RELIABLE && source != nil && dp.GetSequence() > 0 {
 		data, err := proto.Marshal(dp)
 		if err != nil {
@@ -1279,6 +1634,10 @@ func (r *Room) onDataMessage(source types.LocalParticipant, kind livekit.DataPac
 		}, len(data))
 	}
 	BroadcastDataPacketForRoom(r, source, kind, dp, r.logger)
+
+	if relay := r.getRelay(); relay != nil {
+		relay.DataPacket(kind, dp)
+	}
 }
 
 func (r *Room) onDataMessageUnlabeled(source types.LocalParticipant, data []byte) {
@@ -1394,12 +1753,20 @@ func (r *Room) RemoveParticipant(
 	}
 
 	agentJob := r.agentParticpants[identity]
+	_, wasPending := r.pendingParticipants[identity]
+	publishRequest := r.publishRequests[identity]
 
 	delete(r.participants, identity)
 	delete(r.participantOpts, identity)
 	delete(r.participantRequestSources, identity)
 	delete(r.hasPublished, identity)
 	delete(r.agentParticpants, identity)
+	delete(r.publishRequests, identity)
+	delete(r.subscriptionRuleInputs, p.ID())
+	r.leftMetadataVersions[identity] = p.MetadataVersions()
+	r.lobbyLock.Lock()
+	delete(r.pendingParticipants, identity)
+	r.lobbyLock.Unlock()
 	if !p.Hidden() {
 		r.protoRoom.NumParticipants--
 	}
@@ -1434,6 +1801,13 @@ func (r *Room) RemoveParticipant(
 	// send broadcast only if it's not already closed
 	sendUpdates := !p.IsDisconnected()
 
+	if wasPending {
+		r.onPendingParticipantRemoved(p, reason)
+	}
+	if publishRequest != nil {
+		r.onPublishRequesterRemoved(p, publishRequest)
+	}
+
 	// remove all published tracks
 	for _, t := range p.GetPublishedTracks() {
 		r.trackManager.RemoveTrack(t)
@@ -1444,6 +1818,13 @@ func (r *Room) RemoveParticipant(
 		r.trackManager.RemoveDataTrack(t)
 	}
 
+	r.agentLaunchLock.Lock()
+	if pac, ok := r.participantAgentCtx[p.ID()]; ok {
+		pac.cancel()
+		delete(r.participantAgentCtx, p.ID())
+	}
+	r.agentLaunchLock.Unlock()
+
 	if agentJob != nil {
 		agentJob.participantLeft()
 
@@ -1462,6 +1843,12 @@ func (r *Room) RemoveParticipant(
 
 	r.leftAt.Store(time.Now().Unix())
 
+	if relay := r.getRelay(); relay != nil {
+		relay.ParticipantLeft(p)
+	}
+
+	r.maybeApplySubscriptionLimits()
+
 	if sendUpdates {
 		if r.onParticipantChanged != nil {
 			r.onParticipantChanged(p)
@@ -1472,20 +1859,22 @@ func (r *Room) RemoveParticipant(
 
 func (r *Room) subscribeToExistingTracks(p types.LocalParticipant, isSync bool) {
 	r.lock.RLock()
-	autoSubscribe := r.autoSubscribe(p)
 	autoSubscribeDataTrack := r.autoSubscribeDataTrack(p)
 	r.lock.RUnlock()
 
 	var trackIDs []livekit.TrackID
-	for _, op := range r.GetParticipants() {
+	for _, op := range append(toParticipants(r.GetParticipants()), r.GetRelayedParticipants()...) {
 		if p.ID() == op.ID() {
 			// don't send to itself
 			continue
 		}
 
-		// subscribe to all
-		if autoSubscribe {
-			for _, track := range op.GetPublishedTracks() {
+		// subscribe to all allowed by subscription policy
+		for _, track := range op.GetPublishedTracks() {
+			r.lock.RLock()
+			shouldSubscribe := r.shouldSubscribeLocked(p, op, track)
+			r.lock.RUnlock()
+			if shouldSubscribe {
 				trackIDs = append(trackIDs, track.ID())
 				p.SubscribeToTrack(track.ID(), isSync)
 			}
@@ -1507,6 +1896,12 @@ func (r *Room) subscribeToExistingTracks(p types.LocalParticipant, isSync bool)
 func (r *Room) broadcastParticipantState(p types.Participant, opts broadcastOptions) {
 	pi := p.ToProto()
 
+	if lp, ok := p.(types.LocalParticipant); ok && pi.State != livekit.ParticipantInfo_DISCONNECTED {
+		if relay := r.getRelay(); relay != nil {
+			relay.ParticipantChanged(lp)
+		}
+	}
+
 	// send it to the same participant immediately
 	selfSent := false
 	if !opts.skipSource {
@@ -1530,24 +1925,31 @@ func (r *Room) broadcastParticipantState(p types.Participant, opts broadcastOpti
 		return
 	}
 
+	var existingParticipant types.Participant
+	if lp := r.GetParticipant(livekit.ParticipantIdentity(pi.Identity)); lp != nil {
+		existingParticipant = lp
+	} else if rp := r.getRelayedParticipant(livekit.ParticipantIdentity(pi.Identity)); rp != nil {
+		existingParticipant = rp
+	}
+
 	r.batchedUpdatesMu.Lock()
 	updates := PushAndDequeueUpdates(
 		pi,
 		p.CloseReason(),
 		opts.immediate,
-		r.GetParticipant(livekit.ParticipantIdentity(pi.Identity)),
+		existingParticipant,
 		r.batchedUpdates,
 	)
 	r.batchedUpdatesMu.Unlock()
 	if len(updates) != 0 {
 		selfSent = true
-		SendParticipantUpdates(updates, r.GetParticipants(), r.roomConfig.UpdateBatchTargetSize)
+		SendParticipantUpdates(updates, r.GetLocalParticipants(), r.roomConfig.UpdateBatchTargetSize)
 	}
 }
 
 // for protocol 3, send only changed updates
 func (r *Room) sendSpeakerChanges(speakers []*livekit.SpeakerInfo) {
-	for _, p := range r.GetParticipants() {
+	for _, p := range r.GetLocalParticipants() {
 		if p.ProtocolVersion().SupportsSpeakerChanged() {
 			_ = p.SendSpeakerUpdate(speakers, false)
 		}
@@ -1561,7 +1963,7 @@ func (r *Room) updateProto() *livekit.Room {
 
 	room.NumPublishers = 0
 	room.NumParticipants = 0
-	for _, p := range r.GetParticipants() {
+	for _, p := range r.GetLocalParticipants() {
 		if !p.IsDependent() {
 			room.NumParticipants++
 		}
@@ -1598,7 +2000,7 @@ func (r *Room) changeUpdateWorker() {
 			r.batchedUpdates = make(map[livekit.ParticipantIdentity]*ParticipantUpdate)
 			r.batchedUpdatesMu.Unlock()
 
-			SendParticipantUpdates(maps.Values(updatesMap), r.GetParticipants(), r.roomConfig.UpdateBatchTargetSize)
+			SendParticipantUpdates(maps.Values(updatesMap), r.GetLocalParticipants(), r.roomConfig.UpdateBatchTargetSize)
 
 		case <-cleanDataMessageTicker.C:
 			r.dataMessageCache.Prune()
@@ -1759,7 +2161,7 @@ func (r *Room) launchRoomAgents(ads []*agentDispatch) {
 		done := ad.jobsLaunching()
 
 		go func() {
-			inc := r.agentClient.LaunchJob(context.Background(), &agent.JobRequest{
+			inc := r.agentClient.LaunchJob(ad.ctx, &agent.JobRequest{
 				JobType:    livekit.JobType_JT_ROOM,
 				Room:       r.ToProto(),
 				Metadata:   ad.Metadata,
@@ -1777,11 +2179,19 @@ func (r *Room) launchTargetAgents(ads []*agentDispatch, p types.Participant, job
 		return
 	}
 
+	participantCtx := r.participantAgentContext(p.ID())
 	for _, ad := range ads {
 		done := ad.jobsLaunching()
 
 		go func() {
-			inc := r.agentClient.LaunchJob(context.Background(), &agent.JobRequest{
+			ctx, cancel := context.WithCancel(ad.ctx)
+			stop := context.AfterFunc(participantCtx, cancel)
+			defer func() {
+				stop()
+				cancel()
+			}()
+
+			inc := r.agentClient.LaunchJob(ctx, &agent.JobRequest{
 				JobType:     jobType,
 				Room:        r.ToProto(),
 				Participant: p.ToProto(),
@@ -1795,11 +2205,34 @@ func (r *Room) launchTargetAgents(ads []*agentDispatch, p types.Participant, job
 	}
 }
 
+// participantAgentContext returns a context cancelled when the participant leaves the room
+func (r *Room) participantAgentContext(pID livekit.ParticipantID) context.Context {
+	r.agentLaunchLock.Lock()
+	defer r.agentLaunchLock.Unlock()
+
+	pac, ok := r.participantAgentCtx[pID]
+	if !ok {
+		pac.ctx, pac.cancel = context.WithCancel(r.agentCtx)
+		r.participantAgentCtx[pID] = pac
+	}
+	return pac.ctx
+}
+
+// handleNewJobs records launched jobs in the dispatch state, jobs waiting for a worker
+// are dispatched again once their final state is known and replace the pending entry
 func (r *Room) handleNewJobs(ad *livekit.AgentDispatch, inc *sutils.IncrementalDispatcher[*livekit.Job]) {
 	inc.ForEach(func(job *livekit.Job) {
+		if job.State.GetStatus() == livekit.JobStatus_JS_FAILED && job.State.GetWorkerId() == "" {
+			// never reached a worker, e.g. timed out in the job queue. jobs assigned to a worker are reported by the agent service
+			r.telemetry.AgentJobEvent(context.Background(), telemetry.EventAgentJobFailed, job)
+		}
 		r.agentStore.StoreAgentJob(context.Background(), job)
 		r.lock.Lock()
-		ad.State.Jobs = append(ad.State.Jobs, job)
+		if idx := slices.IndexFunc(ad.State.Jobs, func(j *livekit.Job) bool { return j.Id == job.Id }); idx != -1 {
+			ad.State.Jobs[idx] = job
+		} else {
+			ad.State.Jobs = append(ad.State.Jobs, job)
+		}
 		if job.State != nil && job.State.ParticipantIdentity != "" {
 			r.agentParticpants[livekit.ParticipantIdentity(job.State.ParticipantIdentity)] = newAgentJob(job)
 		}
@@ -1807,6 +2240,59 @@ func (r *Room) handleNewJobs(ad *livekit.AgentDispatch, inc *sutils.IncrementalD
 	})
 }
 
+// HandOffAgentJob registers the job replacing a job of a dispatch, e.g. when the worker running it drains, and waits
+// for the agent of the replacement to join. The replacement is tracked from now on, it is terminated with the dispatch
+// or when its agent leaves. Once its agent joined it takes the place of the job, which can then be released.
+// The replacement is dropped when its agent does not join before the context is done.
+func (r *Room) HandOffAgentJob(ctx context.Context, jobID livekit.JobID, replacement *livekit.Job) error {
+	identity := livekit.ParticipantIdentity(replacement.GetState().GetParticipantIdentity())
+	if identity == "" {
+		return ErrNoJobParticipant
+	}
+
+	r.lock.Lock()
+	ad := r.agentDispatches[replacement.DispatchId]
+	if ad == nil || !slices.ContainsFunc(ad.State.GetJobs(), func(j *livekit.Job) bool { return j.Id == string(jobID) }) {
+		r.lock.Unlock()
+		return ErrAgentJobNotFound
+	}
+	ad.State.Jobs = append(ad.State.Jobs, replacement)
+	agentJob := newAgentJob(replacement)
+	r.agentParticpants[identity] = agentJob
+	if r.participants[identity] != nil {
+		agentJob.participantJoined()
+	}
+	r.lock.Unlock()
+
+	if r.agentStore != nil {
+		r.agentStore.StoreAgentJob(context.Background(), replacement)
+	}
+
+	err := agentJob.waitForParticipantJoining(ctx)
+
+	var released *livekit.Job
+	r.lock.Lock()
+	if err != nil {
+		ad.State.Jobs = slices.DeleteFunc(ad.State.Jobs, func(j *livekit.Job) bool { return j.Id == replacement.Id })
+		if r.agentParticpants[identity] == agentJob {
+			delete(r.agentParticpants, identity)
+		}
+	} else if idx := slices.IndexFunc(ad.State.Jobs, func(j *livekit.Job) bool { return j.Id == string(jobID) }); idx != -1 {
+		released = ad.State.Jobs[idx]
+		ad.State.Jobs = slices.Delete(ad.State.Jobs, idx, idx+1)
+	}
+	r.lock.Unlock()
+
+	if r.agentStore != nil {
+		if err != nil {
+			r.agentStore.DeleteAgentJob(context.Background(), replacement)
+		} else if released != nil {
+			r.agentStore.DeleteAgentJob(context.Background(), released)
+		}
+	}
+	return err
+}
+
 func (r *Room) DebugInfo() map[string]any {
 	info := map[string]any{
 		"Name":      r.protoRoom.Name,
@@ -1828,7 +2314,7 @@ func (r *Room) createAgentDispatch(dispatch *livekit.AgentDispatch) (*agentDispa
 	dispatch.State = &livekit.AgentDispatchState{
 		CreatedAt: time.Now().UnixNano(),
 	}
-	ad := newAgentDispatch(dispatch)
+	ad := newAgentDispatch(r.agentCtx, dispatch)
 
 	r.lock.Lock()
 	r.agentDispatches[ad.Id] = ad
@@ -1871,6 +2357,20 @@ func (r *Room) createAgentDispatchesFromRoomAgent() {
 	}
 }
 
+func (r *Room) restoreAgentDispatches(dispatches []*livekit.AgentDispatch) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, dispatch := range dispatches {
+		r.agentDispatches[dispatch.Id] = newAgentDispatch(r.agentCtx, utils.CloneProto(dispatch))
+		for _, job := range dispatch.GetState().GetJobs() {
+			if identity := job.GetState().GetParticipantIdentity(); identity != "" {
+				r.agentParticpants[livekit.ParticipantIdentity(identity)] = newAgentJob(job)
+			}
+		}
+	}
+}
+
 func (r *Room) IsDataMessageUserPacketDuplicate(up *livekit.UserPacket) bool {
 	return r.userPacketDeduper.IsDuplicate(up)
 }
@@ -1937,6 +2437,10 @@ func (l *localParticipantListener) OnSubscriberReady(p types.LocalParticipant) {
 	l.room.subscribeToExistingTracks(p, false)
 }
 
+func (l *localParticipantListener) OnFullyEstablished(p types.LocalParticipant) {
+	l.room.onFullyEstablished(p)
+}
+
 func (l *localParticipantListener) OnMigrateStateChange(_p types.LocalParticipant, _migrateState types.MigrateState) {
 }
 
@@ -1981,6 +2485,14 @@ func (l *localParticipantListener) OnLeave(p types.LocalParticipant, closeReason
 	l.room.onLeave(p, closeReason)
 }
 
+func (l *localParticipantListener) OnPublishPermissionRequest(p types.LocalParticipant, req *types.PublishPermissionRequest) {
+	l.room.onPublishPermissionRequest(p, req)
+}
+
+func (l *localParticipantListener) OnRoomStateRequest(p types.LocalParticipant, req *types.RoomStateRequest) {
+	l.room.onRoomStateRequest(p, req)
+}
+
 // ------------------------------------------------------------
 
 func BroadcastDataPacketForRoom(
@@ -0,0 +1,306 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	sutils "github.com/livekit/livekit-server/pkg/utils"
+)
+
+var (
+	ErrInvalidDataRetention = errors.New("invalid data retention")
+	ErrDataHistoryDisabled  = errors.New("data history is not enabled")
+)
+
+// Duplicate the service.DataHistoryStore interface to avoid a rtc -> service -> rtc import cycle
+type DataHistoryStore interface {
+	// StoreDataMessage appends a message to the history of its topic, keeping at most maxMessages for at most maxAge
+	StoreDataMessage(ctx context.Context, roomName livekit.RoomName, msg *DataHistoryMessage, maxMessages int, maxAge time.Duration) error
+	// ListDataMessages returns the history of a topic, oldest first
+	ListDataMessages(ctx context.Context, roomName livekit.RoomName, topic string) ([]*DataHistoryMessage, error)
+	DeleteDataHistory(ctx context.Context, roomName livekit.RoomName) error
+}
+
+// DataRetention is set by room admins to keep user packets of selected topics, e.g. chat.
+// Kept messages are replayed to participants joining the room and can be fetched with the server API.
+type DataRetention struct {
+	Topics []*DataRetentionTopic `json:"topics,omitempty"`
+}
+
+// DataRetentionTopic bounds the history of a topic to the last MaxMessages, to those sent in the last MaxAgeSeconds, or both.
+// Bounds not set, or larger than the server allows, are those of the server.
+type DataRetentionTopic struct {
+	Topic         string `json:"topic"`
+	MaxMessages   int    `json:"max_messages,omitempty"`
+	MaxAgeSeconds int    `json:"max_age_seconds,omitempty"`
+}
+
+// DataHistoryMessage is a user packet kept in the history of its topic
+type DataHistoryMessage struct {
+	Topic string `json:"topic"`
+	// identity of the sending participant, empty for messages sent with the server API
+	Identity string `json:"identity,omitempty"`
+	// identities the message was sent to, everyone when empty
+	DestinationIdentities []string `json:"destination_identities,omitempty"`
+	Payload               []byte   `json:"payload"`
+	// unix time in milliseconds
+	Timestamp int64 `json:"timestamp"`
+}
+
+func (d *DataRetention) Validate() error {
+	topics := make(map[string]struct{}, len(d.Topics))
+	for _, t := range d.Topics {
+		if t == nil || t.Topic == "" {
+			return fmt.Errorf("%w: topic is required", ErrInvalidDataRetention)
+		}
+		if _, ok := topics[t.Topic]; ok {
+			return fmt.Errorf("%w: duplicate topic %q", ErrInvalidDataRetention, t.Topic)
+		}
+		topics[t.Topic] = struct{}{}
+		if t.MaxMessages < 0 || t.MaxAgeSeconds < 0 {
+			return fmt.Errorf("%w: negative bound for topic %q", ErrInvalidDataRetention, t.Topic)
+		}
+	}
+	return nil
+}
+
+func (d *DataRetention) getTopic(topic string) *DataRetentionTopic {
+	if d == nil {
+		return nil
+	}
+	for _, t := range d.Topics {
+		if t.Topic == topic {
+			return t
+		}
+	}
+	return nil
+}
+
+// SetDataHistoryStore enables data retention for the room, messages are written to the store in the background
+func (r *Room) SetDataHistoryStore(store DataHistoryStore) {
+	queue := sutils.NewOpsQueue(sutils.OpsQueueParams{
+		Name:        "data-history",
+		MinSize:     64,
+		FlushOnStop: true,
+		Logger:      r.logger,
+	})
+	queue.Start()
+
+	r.lock.Lock()
+	r.dataHistoryStore = store
+	r.dataHistoryQueue = queue
+	r.lock.Unlock()
+}
+
+// SetDataRetention replaces the data retention of the room, a nil retention or one without topics removes it.
+// History of topics no longer retained stays in the store till the room closes, it is not replayed nor exported.
+func (r *Room) SetDataRetention(retention *DataRetention) error {
+	if retention != nil {
+		if err := retention.Validate(); err != nil {
+			return err
+		}
+		if len(retention.Topics) == 0 {
+			retention = nil
+		}
+	}
+
+	r.lock.Lock()
+	if r.dataHistoryStore == nil {
+		r.lock.Unlock()
+		return ErrDataHistoryDisabled
+	}
+	r.dataRetention = retention
+	r.lock.Unlock()
+
+	r.logger.Infow("updated data retention", "retention", retention)
+	return nil
+}
+
+func (r *Room) GetDataRetention() *DataRetention {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.dataRetention
+}
+
+// GetDataHistory returns kept messages of a topic, or of all retained topics when topic is empty, oldest first
+func (r *Room) GetDataHistory(ctx context.Context, topic string) ([]*DataHistoryMessage, error) {
+	r.lock.RLock()
+	retention, store := r.dataRetention, r.dataHistoryStore
+	r.lock.RUnlock()
+	if store == nil {
+		return nil, ErrDataHistoryDisabled
+	}
+
+	var topics []string
+	if topic != "" {
+		topics = []string{topic}
+	} else if retention != nil {
+		for _, t := range retention.Topics {
+			topics = append(topics, t.Topic)
+		}
+	}
+
+	var history []*DataHistoryMessage
+	for _, t := range topics {
+		msgs, err := store.ListDataMessages(ctx, r.Name(), t)
+		if err != nil {
+			return nil, err
+		}
+		// the store expires messages lazily
+		if _, maxAge := r.dataHistoryBounds(retention.getTopic(t)); maxAge > 0 {
+			cutoff := time.Now().Add(-maxAge).UnixMilli()
+			msgs = slices.DeleteFunc(msgs, func(msg *DataHistoryMessage) bool {
+				return msg.Timestamp < cutoff
+			})
+		}
+		history = append(history, msgs...)
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Timestamp < history[j].Timestamp
+	})
+	return history, nil
+}
+
+// dataHistoryBounds returns the bounds of a topic, capped by those of the server
+func (r *Room) dataHistoryBounds(t *DataRetentionTopic) (int, time.Duration) {
+	maxMessages, maxAge := r.roomConfig.DataHistory.MaxMessages, r.roomConfig.DataHistory.MaxAge
+	if t == nil {
+		return maxMessages, maxAge
+	}
+	if t.MaxMessages > 0 && (maxMessages == 0 || t.MaxMessages < maxMessages) {
+		maxMessages = t.MaxMessages
+	}
+	if age := time.Duration(t.MaxAgeSeconds) * time.Second; age > 0 && (maxAge == 0 || age < maxAge) {
+		maxAge = age
+	}
+	return maxMessages, maxAge
+}
+
+// retainDataMessage keeps a user packet of a retained topic in the history of the room
+func (r *Room) retainDataMessage(source types.LocalParticipant, dp *livekit.DataPacket) {
+	user := dp.GetUser()
+	if user == nil {
+		return
+	}
+
+	r.lock.RLock()
+	retention, store, queue := r.dataRetention, r.dataHistoryStore, r.dataHistoryQueue
+	r.lock.RUnlock()
+	t := retention.getTopic(user.GetTopic())
+	if store == nil || t == nil {
+		return
+	}
+
+	destinations := dp.DestinationIdentities
+	if len(destinations) == 0 {
+		destinations = user.DestinationIdentities
+	}
+	// messages addressed by participant sid only cannot be matched to participants joining later
+	if len(destinations) == 0 && len(user.DestinationSids) != 0 {
+		return
+	}
+
+	msg := &DataHistoryMessage{
+		Topic:                 t.Topic,
+		Identity:              dp.ParticipantIdentity,
+		DestinationIdentities: slices.Clone(destinations),
+		Payload:               slices.Clone(user.Payload),
+		Timestamp:             time.Now().UnixMilli(),
+	}
+	if source != nil {
+		msg.Identity = string(source.Identity())
+	} else if msg.Identity == "" {
+		msg.Identity = user.ParticipantIdentity
+	}
+	maxMessages, maxAge := r.dataHistoryBounds(t)
+	roomName := r.Name()
+
+	queue.Enqueue(func() {
+		if err := store.StoreDataMessage(context.Background(), roomName, msg, maxMessages, maxAge); err != nil {
+			r.logger.Warnw("could not store data message", err, "topic", msg.Topic)
+		}
+	})
+}
+
+// replayDataHistory sends kept messages of retained topics to a participant which joined the room, once its data channels are open.
+// Migrating participants are skipped, they received them before.
+func (r *Room) replayDataHistory(p types.LocalParticipant) {
+	if p.IsMigration() || r.GetDataRetention() == nil {
+		return
+	}
+
+	history, err := r.GetDataHistory(context.Background(), "")
+	if err != nil {
+		p.GetLogger().Warnw("could not load data history", err)
+		return
+	}
+
+	identity := string(p.Identity())
+	replayed := 0
+	for _, msg := range history {
+		if len(msg.DestinationIdentities) != 0 && !slices.Contains(msg.DestinationIdentities, identity) {
+			continue
+		}
+
+		topic := msg.Topic
+		data, err := proto.Marshal(&livekit.DataPacket{
+			Kind:                  livekit.DataPacket_RELIABLE,
+			ParticipantIdentity:   msg.Identity,
+			DestinationIdentities: msg.DestinationIdentities,
+			Value: &livekit.DataPacket_User{
+				User: &livekit.UserPacket{
+					ParticipantIdentity:   msg.Identity,
+					DestinationIdentities: msg.DestinationIdentities,
+					Payload:               msg.Payload,
+					Topic:                 &topic,
+				},
+			},
+		})
+		if err != nil {
+			p.GetLogger().Errorw("could not marshal data history message", err)
+			return
+		}
+		if err := p.SendDataMessage(livekit.DataPacket_RELIABLE, data, "", 0); err != nil {
+			p.GetLogger().Infow("could not replay data history", "error", err, "replayed", replayed)
+			return
+		}
+		replayed++
+	}
+	if replayed != 0 {
+		p.GetLogger().Debugw("replayed data history", "messages", replayed)
+	}
+}
+
+// stopDataHistory waits for kept messages to be written to the store
+func (r *Room) stopDataHistory() {
+	r.lock.RLock()
+	queue := r.dataHistoryQueue
+	r.lock.RUnlock()
+	if queue != nil {
+		<-queue.Stop()
+	}
+}
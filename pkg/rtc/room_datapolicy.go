@@ -0,0 +1,291 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/stdlib"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+var (
+	ErrInvalidDataPolicy = errors.New("invalid data policy")
+)
+
+const (
+	DataPolicyActionForward  = "forward"
+	DataPolicyActionDrop     = "drop"
+	DataPolicyActionRedact   = "redact"
+	DataPolicyActionRedirect = "redirect"
+
+	// bounds the memory a data policy script can use on a single message
+	dataPolicyMaxAllocs = 100000
+)
+
+// modules data policy scripts can import, modules with side effects (os, fmt) are left out
+var dataPolicyModules = []string{"text", "math", "json", "enum", "base64", "hex", "times"}
+
+// DataPolicy is set by room admins to moderate data messages on the server, without an agent in the room.
+// The tengo script runs for every user packet and chat message sent by a participant, before it is forwarded.
+// It reads the message from these variables:
+//
+//	type          "user" or "chat"
+//	topic         topic of user packets, empty for chat messages
+//	sender        identity of the sending participant
+//	attributes    attributes of the sending participant, read only
+//	payload       payload of user packets or text of chat messages, as a string
+//	destinations  identities the message is sent to, everyone when empty
+//
+// and decides what happens to it by setting action:
+//
+//	"forward"   the message is forwarded unchanged, this is the default
+//	"drop"      the message is not forwarded
+//	"redact"    the message is forwarded with payload replaced by the value the script set it to
+//	"redirect"  the message is only forwarded to the identities the script set destinations to
+//
+// e.g. redact a word from chat and route questions to hosts only
+//
+//	text := import("text")
+//	if text.contains(payload, "darn") {
+//	  action = "redact"
+//	  payload = text.replace(payload, "darn", "****", -1)
+//	} else if topic == "question" {
+//	  action = "redirect"
+//	  destinations = ["host"]
+//	}
+type DataPolicy struct {
+	Script string `json:"script"`
+	// topics of user packets the script runs for, all messages when empty
+	Topics []string `json:"topics,omitempty"`
+	// drop messages the script fails on, they are forwarded unchanged otherwise
+	DropOnError bool `json:"drop_on_error,omitempty"`
+}
+
+type dataPolicy struct {
+	policy   *DataPolicy
+	compiled *tengo.Compiled
+}
+
+func newDataPolicy(policy *DataPolicy) (*dataPolicy, error) {
+	script := tengo.NewScript([]byte(policy.Script))
+	script.SetImports(stdlib.GetModuleMap(dataPolicyModules...))
+	script.SetMaxAllocs(dataPolicyMaxAllocs)
+	for name, value := range map[string]any{
+		"type":         "",
+		"topic":        "",
+		"sender":       "",
+		"attributes":   &tengo.ImmutableMap{},
+		"payload":      "",
+		"destinations": &tengo.Array{},
+		"action":       DataPolicyActionForward,
+	} {
+		if err := script.Add(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	compiled, err := script.Compile()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDataPolicy, err)
+	}
+	return &dataPolicy{policy: policy, compiled: compiled}, nil
+}
+
+type dataPolicyMessage struct {
+	packetType   string
+	topic        string
+	sender       livekit.ParticipantIdentity
+	attributes   map[string]string
+	payload      []byte
+	destinations []string
+}
+
+type dataPolicyResult struct {
+	action       string
+	payload      []byte
+	destinations []string
+}
+
+func (dp *dataPolicy) appliesTo(msg *dataPolicyMessage) bool {
+	return len(dp.policy.Topics) == 0 || (msg.packetType == "user" && slices.Contains(dp.policy.Topics, msg.topic))
+}
+
+func (dp *dataPolicy) run(ctx context.Context, msg *dataPolicyMessage) (*dataPolicyResult, error) {
+	attributes := make(map[string]tengo.Object, len(msg.attributes))
+	for k, v := range msg.attributes {
+		attributes[k] = &tengo.String{Value: v}
+	}
+	destinations := make([]tengo.Object, 0, len(msg.destinations))
+	for _, identity := range msg.destinations {
+		destinations = append(destinations, &tengo.String{Value: identity})
+	}
+
+	clone := dp.compiled.Clone()
+	for name, value := range map[string]any{
+		"type":         msg.packetType,
+		"topic":        msg.topic,
+		"sender":       string(msg.sender),
+		"attributes":   &tengo.ImmutableMap{Value: attributes},
+		"payload":      string(msg.payload),
+		"destinations": &tengo.Array{Value: destinations},
+	} {
+		if err := clone.Set(name, value); err != nil {
+			return nil, err
+		}
+	}
+	if err := clone.RunContext(ctx); err != nil {
+		return nil, err
+	}
+
+	res := &dataPolicyResult{}
+	action, ok := clone.Get("action").Value().(string)
+	if !ok {
+		return nil, errors.New("action is not a string")
+	}
+	res.action = action
+
+	switch res.action {
+	case DataPolicyActionForward, DataPolicyActionDrop:
+	case DataPolicyActionRedact:
+		switch payload := clone.Get("payload").Value().(type) {
+		case string:
+			res.payload = []byte(payload)
+		case []byte:
+			res.payload = payload
+		default:
+			return nil, errors.New("payload is not a string or bytes")
+		}
+	case DataPolicyActionRedirect:
+		for _, v := range clone.Get("destinations").Array() {
+			identity, ok := v.(string)
+			if !ok {
+				return nil, errors.New("destinations are not strings")
+			}
+			res.destinations = append(res.destinations, identity)
+		}
+	default:
+		return nil, fmt.Errorf("unknown action %q", res.action)
+	}
+	return res, nil
+}
+
+// SetDataPolicy replaces the data policy of the room, a nil policy or one without a script removes it
+func (r *Room) SetDataPolicy(policy *DataPolicy) error {
+	var dp *dataPolicy
+	if policy != nil && policy.Script != "" {
+		var err error
+		if dp, err = newDataPolicy(policy); err != nil {
+			return err
+		}
+	}
+
+	r.lock.Lock()
+	r.dataPolicy = dp
+	r.lock.Unlock()
+
+	r.logger.Infow("updated data policy", "enabled", dp != nil)
+	return nil
+}
+
+func (r *Room) GetDataPolicy() *DataPolicy {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if r.dataPolicy == nil {
+		return nil
+	}
+	return r.dataPolicy.policy
+}
+
+// applyDataPolicy runs the data policy on a data packet sent by a participant, modifying it as the script decided.
+// Returns false when the packet should not be forwarded.
+func (r *Room) applyDataPolicy(source types.LocalParticipant, dp *livekit.DataPacket) bool {
+	r.lock.RLock()
+	policy := r.dataPolicy
+	r.lock.RUnlock()
+	if policy == nil || source == nil {
+		return true
+	}
+
+	msg := &dataPolicyMessage{
+		sender:     source.Identity(),
+		attributes: source.ClaimGrants().Attributes,
+	}
+	switch payload := dp.Value.(type) {
+	case *livekit.DataPacket_User:
+		msg.packetType = "user"
+		msg.topic = payload.User.GetTopic()
+		msg.payload = payload.User.Payload
+		msg.destinations = dp.DestinationIdentities
+		if len(msg.destinations) == 0 {
+			msg.destinations = payload.User.DestinationIdentities
+		}
+	case *livekit.DataPacket_ChatMessage:
+		msg.packetType = "chat"
+		msg.payload = []byte(payload.ChatMessage.Message)
+		msg.destinations = dp.DestinationIdentities
+	default:
+		return true
+	}
+	if !policy.appliesTo(msg) {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.roomConfig.DataPolicy.Timeout)
+	res, err := policy.run(ctx, msg)
+	cancel()
+	if err != nil {
+		reason := "error"
+		if errors.Is(err, context.DeadlineExceeded) {
+			reason = "timeout"
+		}
+		prometheus.RecordDataPolicyFailure(reason)
+		r.logger.Infow("data policy failed", "error", err, "participant", source.Identity(), "dropped", policy.policy.DropOnError)
+		return !policy.policy.DropOnError
+	}
+	prometheus.RecordDataPolicyAction(res.action)
+
+	switch res.action {
+	case DataPolicyActionDrop:
+		return false
+	case DataPolicyActionRedact:
+		switch payload := dp.Value.(type) {
+		case *livekit.DataPacket_User:
+			payload.User.Payload = res.payload
+		case *livekit.DataPacket_ChatMessage:
+			payload.ChatMessage.Message = string(res.payload)
+		}
+	case DataPolicyActionRedirect:
+		// redirecting to no one would broadcast to everyone
+		if len(res.destinations) == 0 {
+			return false
+		}
+		dp.DestinationIdentities = res.destinations
+		if u := dp.GetUser(); u != nil {
+			u.DestinationIdentities = res.destinations
+			u.DestinationSids = nil
+		}
+	}
+	return true
+}
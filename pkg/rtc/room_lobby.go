@@ -0,0 +1,257 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// LobbyTopic is the data topic room admins are notified of lobby events on
+const LobbyTopic = "lk.lobby"
+
+const (
+	LobbyEventParticipantPending  = "participant_pending"
+	LobbyEventParticipantAdmitted = "participant_admitted"
+	LobbyEventParticipantRejected = "participant_rejected"
+	LobbyEventParticipantLeft     = "participant_left"
+)
+
+// LobbyEvent is sent to room admins as JSON payload on LobbyTopic
+type LobbyEvent struct {
+	Event       string            `json:"event"`
+	Sid         string            `json:"sid"`
+	Identity    string            `json:"identity"`
+	Name        string            `json:"name,omitempty"`
+	Metadata    string            `json:"metadata,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	NumPending  int               `json:"num_pending"`
+	PendingTime int64             `json:"pending_time_ms,omitempty"`
+}
+
+type pendingParticipant struct {
+	// permission granted on admission
+	permission *livekit.ParticipantPermission
+	heldAt     time.Time
+	// set when the participant connected while pending, it is reported as active once admitted
+	activeMeta *livekit.AnalyticsClientMeta
+}
+
+// LobbyPermission is the permission of a participant waiting in the lobby,
+// it is hidden from others and can neither publish nor subscribe
+func LobbyPermission() *livekit.ParticipantPermission {
+	return &livekit.ParticipantPermission{
+		Hidden: true,
+	}
+}
+
+func (r *Room) SetLobbyEnabled(enabled bool) {
+	r.lock.Lock()
+	r.lobbyEnabled = enabled
+	r.lock.Unlock()
+}
+
+func (r *Room) IsLobbyEnabled() bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.lobbyEnabled
+}
+
+// IsPending returns true if the participant is waiting in the lobby, safe to call from callbacks invoked holding the room lock
+func (r *Room) IsPending(identity livekit.ParticipantIdentity) bool {
+	r.lobbyLock.RLock()
+	defer r.lobbyLock.RUnlock()
+
+	_, ok := r.pendingParticipants[identity]
+	return ok
+}
+
+// GetPendingParticipants returns participants waiting in the lobby, in order of arrival
+func (r *Room) GetPendingParticipants() []*livekit.ParticipantInfo {
+	r.lock.RLock()
+	type pending struct {
+		p      types.LocalParticipant
+		heldAt time.Time
+	}
+	participants := make([]pending, 0, len(r.pendingParticipants))
+	for identity, pp := range r.pendingParticipants {
+		if p := r.participants[identity]; p != nil {
+			participants = append(participants, pending{p, pp.heldAt})
+		}
+	}
+	r.lock.RUnlock()
+
+	slices.SortFunc(participants, func(a, b pending) int {
+		return a.heldAt.Compare(b.heldAt)
+	})
+
+	infos := make([]*livekit.ParticipantInfo, 0, len(participants))
+	for _, pending := range participants {
+		infos = append(infos, pending.p.ToProto())
+	}
+	return infos
+}
+
+// AdmitParticipant lets a participant waiting in the lobby into the room
+func (r *Room) AdmitParticipant(identity livekit.ParticipantIdentity) (*livekit.ParticipantInfo, error) {
+	r.lock.Lock()
+	pp := r.pendingParticipants[identity]
+	p := r.participants[identity]
+	if pp == nil || p == nil {
+		r.lock.Unlock()
+		return nil, ErrParticipantNotPending
+	}
+	r.lobbyLock.Lock()
+	delete(r.pendingParticipants, identity)
+	r.lobbyLock.Unlock()
+	// others were not sent while pending
+	others := r.getOtherParticipantInfoLocked(p)
+	r.lock.Unlock()
+
+	p.GetLogger().Infow("admitting participant", "pendingTime", time.Since(pp.heldAt))
+	r.protoProxy.MarkDirty(false)
+
+	if err := p.SendParticipantUpdate(others); err != nil {
+		p.GetLogger().Warnw("could not send participants to admitted participant", err)
+	}
+
+	// restoring permission makes participant visible to others
+	p.SetPermission(pp.permission)
+
+	if p.State() == livekit.ParticipantInfo_ACTIVE {
+		r.subscribeToExistingTracks(p, false)
+	}
+	if p.IsFullyEstablished() {
+		go func() {
+			r.sendRoomStateSnapshot(p)
+			r.replayDataHistory(p)
+		}()
+	}
+	r.maybeApplySubscriptionLimits()
+	if pp.activeMeta != nil {
+		r.telemetry.ParticipantActive(context.Background(), r.ToProto(), p.ToProto(), pp.activeMeta, false, p.TelemetryGuard())
+	}
+
+	r.notifyLobbyAdmins(LobbyEventParticipantAdmitted, p, pp)
+	return p.ToProto(), nil
+}
+
+// RejectParticipant removes a participant waiting in the lobby
+func (r *Room) RejectParticipant(identity livekit.ParticipantIdentity) error {
+	r.lock.RLock()
+	pp := r.pendingParticipants[identity]
+	p := r.participants[identity]
+	r.lock.RUnlock()
+	if pp == nil || p == nil {
+		return ErrParticipantNotPending
+	}
+
+	p.GetLogger().Infow("rejecting participant", "pendingTime", time.Since(pp.heldAt))
+	r.telemetry.ParticipantRejected(context.Background(), r.ToProto(), p.ToProto())
+	r.RemoveParticipant(identity, p.ID(), types.ParticipantCloseReasonUserRejected)
+	return nil
+}
+
+// deferParticipantActive holds back reporting a pending participant as active till it is admitted,
+// returns false if the participant is not pending
+func (r *Room) deferParticipantActive(p types.LocalParticipant, meta *livekit.AnalyticsClientMeta) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	pp := r.pendingParticipants[p.Identity()]
+	if pp == nil {
+		return false
+	}
+	pp.activeMeta = meta
+	return true
+}
+
+func (r *Room) onParticipantPending(p types.LocalParticipant) {
+	r.lock.RLock()
+	pp := r.pendingParticipants[p.Identity()]
+	r.lock.RUnlock()
+	if pp == nil {
+		return
+	}
+
+	p.GetLogger().Infow("participant waiting in lobby")
+	r.telemetry.ParticipantPending(context.Background(), r.ToProto(), p.ToProto())
+	r.notifyLobbyAdmins(LobbyEventParticipantPending, p, pp)
+}
+
+func (r *Room) onPendingParticipantRemoved(p types.LocalParticipant, reason types.ParticipantCloseReason) {
+	event := LobbyEventParticipantLeft
+	if reason == types.ParticipantCloseReasonUserRejected {
+		event = LobbyEventParticipantRejected
+	}
+	r.notifyLobbyAdmins(event, p, nil)
+}
+
+func (r *Room) getAdmittedParticipantsLocked() []types.LocalParticipant {
+	participants := make([]types.LocalParticipant, 0, len(r.participants))
+	for identity, p := range r.participants {
+		if _, ok := r.pendingParticipants[identity]; !ok {
+			participants = append(participants, p)
+		}
+	}
+	return participants
+}
+
+func (r *Room) getOtherParticipantInfoLocked(p types.LocalParticipant) []*livekit.ParticipantInfo {
+	if _, ok := r.pendingParticipants[p.Identity()]; ok {
+		// participants waiting in the lobby do not see who is in the room
+		return nil
+	}
+
+	return GetOtherParticipantInfo(
+		p,
+		false, // isMigratingIn
+		append(toParticipants(r.getAdmittedParticipantsLocked()), r.getRelayedParticipantsLocked()...),
+		false, // skipSubscriberBroadcast
+	)
+}
+
+// notifyLobbyAdmins sends a lobby event to participants with room admin grant
+func (r *Room) notifyLobbyAdmins(event string, p types.LocalParticipant, pp *pendingParticipant) {
+	admins := r.getRoomAdminIdentities()
+	if len(admins) == 0 {
+		return
+	}
+
+	r.lock.RLock()
+	numPending := len(r.pendingParticipants)
+	r.lock.RUnlock()
+
+	pi := p.ToProto()
+	ev := &LobbyEvent{
+		Event:      event,
+		Sid:        pi.Sid,
+		Identity:   pi.Identity,
+		Name:       pi.Name,
+		Metadata:   pi.Metadata,
+		Attributes: pi.Attributes,
+		NumPending: numPending,
+	}
+	if pp != nil && event != LobbyEventParticipantPending {
+		ev.PendingTime = time.Since(pp.heldAt).Milliseconds()
+	}
+	r.sendServerData(LobbyTopic, ev, admins)
+}
@@ -0,0 +1,278 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// PublishRequestTopic is the data topic participants ask for permission to publish on,
+// messages on it are handled by the server and not forwarded. Room admins and requesting
+// participants are notified of changes to requests on the same topic.
+const PublishRequestTopic = "lk.publish_request"
+
+const (
+	PublishRequestEventRequested = "requested"
+	PublishRequestEventCancelled = "cancelled"
+	PublishRequestEventApproved  = "approved"
+	PublishRequestEventDenied    = "denied"
+	PublishRequestEventExpired   = "expired"
+)
+
+// PublishRequestMessage is the JSON payload sent by participants on PublishRequestTopic,
+// sources are named in lower case, e. g. "camera", "screen_share"
+type PublishRequestMessage struct {
+	Sources []string `json:"sources,omitempty"`
+	Cancel  bool     `json:"cancel,omitempty"`
+}
+
+// PublishRequestEvent is sent to room admins and the requesting participant as JSON payload on PublishRequestTopic
+type PublishRequestEvent struct {
+	Event      string   `json:"event"`
+	Sid        string   `json:"sid"`
+	Identity   string   `json:"identity"`
+	Name       string   `json:"name,omitempty"`
+	Sources    []string `json:"sources,omitempty"`
+	NumPending int      `json:"num_pending"`
+}
+
+type publishRequest struct {
+	sources     []livekit.TrackSource
+	requestedAt time.Time
+	expiry      *time.Timer
+}
+
+func (pr *publishRequest) stopExpiry() {
+	// requests do not expire without a timeout
+	if pr.expiry != nil {
+		pr.expiry.Stop()
+	}
+}
+
+func ParsePublishRequestMessage(payload []byte) (*types.PublishPermissionRequest, error) {
+	var msg PublishRequestMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return nil, err
+	}
+
+	req := &types.PublishPermissionRequest{
+		Cancel: msg.Cancel,
+	}
+	for _, name := range msg.Sources {
+		source, ok := livekit.TrackSource_value[strings.ToUpper(name)]
+		if !ok || livekit.TrackSource(source) == livekit.TrackSource_UNKNOWN {
+			return nil, fmt.Errorf("unknown track source %q", name)
+		}
+		if !slices.Contains(req.Sources, livekit.TrackSource(source)) {
+			req.Sources = append(req.Sources, livekit.TrackSource(source))
+		}
+	}
+	slices.Sort(req.Sources)
+	return req, nil
+}
+
+func (r *Room) onPublishPermissionRequest(p types.LocalParticipant, req *types.PublishPermissionRequest) {
+	if r.IsPending(p.Identity()) {
+		// participants in the lobby are admitted first
+		return
+	}
+
+	if req.Cancel {
+		r.lock.Lock()
+		pr := r.publishRequests[p.Identity()]
+		delete(r.publishRequests, p.Identity())
+		r.lock.Unlock()
+
+		if pr != nil {
+			pr.stopExpiry()
+			p.GetLogger().Infow("publish request cancelled")
+			r.notifyPublishRequest(PublishRequestEventCancelled, p, pr, false)
+		}
+		return
+	}
+
+	if canPublishSources(p, req.Sources) {
+		p.GetLogger().Debugw("ignoring publish request, already allowed to publish", "sources", req.Sources)
+		return
+	}
+
+	r.lock.Lock()
+	if existing := r.publishRequests[p.Identity()]; existing != nil {
+		if slices.Equal(existing.sources, req.Sources) {
+			r.lock.Unlock()
+			return
+		}
+		existing.stopExpiry()
+	}
+	pr := &publishRequest{
+		sources:     req.Sources,
+		requestedAt: time.Now(),
+	}
+	if timeout := r.roomConfig.PublishRequest.Timeout; timeout > 0 {
+		pr.expiry = time.AfterFunc(timeout, func() {
+			r.expirePublishRequest(p, pr)
+		})
+	}
+	r.publishRequests[p.Identity()] = pr
+	r.lock.Unlock()
+
+	p.GetLogger().Infow("participant requested to publish", "sources", req.Sources)
+	r.notifyPublishRequest(PublishRequestEventRequested, p, pr, false)
+}
+
+// ApprovePublishRequest allows a participant to publish the sources it asked for
+func (r *Room) ApprovePublishRequest(identity livekit.ParticipantIdentity) (*livekit.ParticipantInfo, error) {
+	r.lock.Lock()
+	pr := r.publishRequests[identity]
+	p := r.participants[identity]
+	if pr == nil || p == nil {
+		r.lock.Unlock()
+		return nil, ErrNoPublishRequest
+	}
+	if maxPublishers := r.roomConfig.PublishRequest.MaxPublishers; maxPublishers > 0 && r.getNumPublishersLocked(identity) >= maxPublishers {
+		r.lock.Unlock()
+		return nil, ErrMaxPublishersExceeded
+	}
+	delete(r.publishRequests, identity)
+	r.lock.Unlock()
+	pr.stopExpiry()
+
+	permission := p.ClaimGrants().Video.ToPermission()
+	permission.CanPublishSources = approvedPublishSources(permission, pr.sources)
+	permission.CanPublish = true
+
+	p.GetLogger().Infow("publish request approved", "sources", pr.sources, "waitTime", time.Since(pr.requestedAt))
+	p.SetPermission(permission)
+
+	r.notifyPublishRequest(PublishRequestEventApproved, p, pr, true)
+	return p.ToProto(), nil
+}
+
+// DenyPublishRequest drops the request of a participant to publish
+func (r *Room) DenyPublishRequest(identity livekit.ParticipantIdentity) (*livekit.ParticipantInfo, error) {
+	r.lock.Lock()
+	pr := r.publishRequests[identity]
+	p := r.participants[identity]
+	if pr == nil || p == nil {
+		r.lock.Unlock()
+		return nil, ErrNoPublishRequest
+	}
+	delete(r.publishRequests, identity)
+	r.lock.Unlock()
+	pr.stopExpiry()
+
+	p.GetLogger().Infow("publish request denied", "sources", pr.sources, "waitTime", time.Since(pr.requestedAt))
+	r.notifyPublishRequest(PublishRequestEventDenied, p, pr, true)
+	return p.ToProto(), nil
+}
+
+func (r *Room) expirePublishRequest(p types.LocalParticipant, pr *publishRequest) {
+	r.lock.Lock()
+	if r.publishRequests[p.Identity()] != pr {
+		r.lock.Unlock()
+		return
+	}
+	delete(r.publishRequests, p.Identity())
+	r.lock.Unlock()
+
+	p.GetLogger().Infow("publish request expired", "sources", pr.sources)
+	r.notifyPublishRequest(PublishRequestEventExpired, p, pr, true)
+}
+
+func (r *Room) onPublishRequesterRemoved(p types.LocalParticipant, pr *publishRequest) {
+	pr.stopExpiry()
+	r.notifyPublishRequest(PublishRequestEventCancelled, p, pr, false)
+}
+
+// getNumPublishersLocked returns the number of participants allowed to publish, not counting the given one
+func (r *Room) getNumPublishersLocked(skip livekit.ParticipantIdentity) int {
+	numPublishers := 0
+	for _, p := range r.getAdmittedParticipantsLocked() {
+		if p.Identity() != skip && !p.Hidden() && !p.IsDependent() && p.CanPublish() {
+			numPublishers++
+		}
+	}
+	return numPublishers
+}
+
+func (r *Room) notifyPublishRequest(event string, p types.LocalParticipant, pr *publishRequest, notifyRequester bool) {
+	recipients := r.getRoomAdminIdentities()
+	if notifyRequester && !slices.Contains(recipients, string(p.Identity())) {
+		recipients = append(recipients, string(p.Identity()))
+	}
+	if len(recipients) == 0 {
+		return
+	}
+
+	r.lock.RLock()
+	numPending := len(r.publishRequests)
+	r.lock.RUnlock()
+
+	ev := &PublishRequestEvent{
+		Event:      event,
+		Sid:        string(p.ID()),
+		Identity:   string(p.Identity()),
+		Name:       p.ToProto().Name,
+		NumPending: numPending,
+	}
+	for _, source := range pr.sources {
+		ev.Sources = append(ev.Sources, strings.ToLower(source.String()))
+	}
+	r.sendServerData(PublishRequestTopic, ev, recipients)
+}
+
+func canPublishSources(p types.LocalParticipant, sources []livekit.TrackSource) bool {
+	if !p.CanPublish() {
+		return false
+	}
+	if len(sources) == 0 {
+		// asking for all sources
+		grants := p.ClaimGrants()
+		return grants == nil || grants.Video == nil || len(grants.Video.CanPublishSources) == 0
+	}
+	for _, source := range sources {
+		if !p.CanPublishSource(source) {
+			return false
+		}
+	}
+	return true
+}
+
+// approvedPublishSources returns the sources a participant can publish once its request is approved,
+// empty meaning all sources
+func approvedPublishSources(permission *livekit.ParticipantPermission, requested []livekit.TrackSource) []livekit.TrackSource {
+	if len(requested) == 0 || (permission.CanPublish && len(permission.CanPublishSources) == 0) {
+		return nil
+	}
+	if !permission.CanPublish {
+		return requested
+	}
+
+	sources := slices.Clone(permission.CanPublishSources)
+	for _, source := range requested {
+		if !slices.Contains(sources, source) {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// SetRelay enables replication of the room with other nodes hosting it
+func (r *Room) SetRelay(relay *RoomRelay) {
+	r.lock.Lock()
+	r.relay = relay
+	r.lock.Unlock()
+}
+
+func (r *Room) getRelay() *RoomRelay {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.relay
+}
+
+// GetRelayedParticipants returns participants connected to other nodes hosting the room
+func (r *Room) GetRelayedParticipants() []types.Participant {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.getRelayedParticipantsLocked()
+}
+
+func (r *Room) getRelayedParticipantsLocked() []types.Participant {
+	participants := make([]types.Participant, 0, len(r.relayedParticipants))
+	for _, p := range r.relayedParticipants {
+		participants = append(participants, p)
+	}
+	return participants
+}
+
+func (r *Room) getRelayedParticipant(identity livekit.ParticipantIdentity) *RelayedParticipant {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.relayedParticipants[identity]
+}
+
+func (r *Room) getRelayedParticipantByID(participantID livekit.ParticipantID) *RelayedParticipant {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, p := range r.relayedParticipants {
+		if p.ID() == participantID {
+			return p
+		}
+	}
+	return nil
+}
+
+func (r *Room) addRelayedParticipant(p *RelayedParticipant) {
+	r.lock.Lock()
+	r.relayedParticipants[p.Identity()] = p
+	r.lock.Unlock()
+
+	p.GetLogger().Infow("relayed participant joined")
+}
+
+func (r *Room) removeRelayedParticipant(p *RelayedParticipant) {
+	r.lock.Lock()
+	if r.relayedParticipants[p.Identity()] != p {
+		r.lock.Unlock()
+		return
+	}
+	delete(r.relayedParticipants, p.Identity())
+	r.lock.Unlock()
+
+	for _, t := range p.GetPublishedTracks() {
+		r.trackManager.RemoveTrack(t)
+	}
+	_ = p.Close(false, types.ParticipantCloseReasonNone, false)
+
+	p.GetLogger().Infow("relayed participant left")
+	r.broadcastParticipantState(p, broadcastOptions{skipSource: true})
+}
+
+func (r *Room) removeRelayedParticipantsOfNode(nodeID livekit.NodeID) {
+	r.lock.RLock()
+	var participants []*RelayedParticipant
+	for _, p := range r.relayedParticipants {
+		if p.NodeID() == nodeID {
+			participants = append(participants, p)
+		}
+	}
+	r.lock.RUnlock()
+
+	for _, p := range participants {
+		r.removeRelayedParticipant(p)
+	}
+}
+
+// onRelayedParticipantChanged applies a replicated participant update to local participants
+func (r *Room) onRelayedParticipantChanged(
+	p *RelayedParticipant,
+	newTracks []types.MediaTrack,
+	removedTracks []types.MediaTrack,
+	permissionChanged bool,
+) {
+	for _, t := range removedTracks {
+		r.trackManager.RemoveTrack(t)
+	}
+	for _, t := range newTracks {
+		r.trackManager.AddTrack(t, p.Identity(), p.ID())
+	}
+	if permissionChanged {
+		for _, t := range p.GetPublishedTracks() {
+			r.trackManager.NotifyTrackChanged(t.ID())
+		}
+	}
+
+	r.broadcastParticipantState(p, broadcastOptions{skipSource: true})
+	r.reconcileSubscriptionPolicyForPublisher(p)
+
+	if len(newTracks) == 0 {
+		return
+	}
+
+	r.lock.RLock()
+	for _, t := range newTracks {
+		r.subscribeToNewTrackLocked(p, t)
+	}
+	r.lock.RUnlock()
+}
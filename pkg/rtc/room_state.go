@@ -0,0 +1,291 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// RoomStateTopic is the data topic the shared state of the room is synchronized on. Participants with the
+// room admin grant or the permission to update metadata (can_update_metadata, which room admins can change with
+// UpdateParticipant) send a RoomStateRequest to update it, messages on it are handled by the server and not forwarded.
+// The server sends RoomStateMessage on it: the snapshot of the state to participants joining once their data
+// channels are open, deltas to all participants when it is updated, and the result of a request to the participant which sent it.
+//
+// The state is not synchronized over signalling: the signal protocol has no message for it, so the snapshot is not part of
+// the join response and deltas are not signal messages. Clients read it from this topic until the protocol carries it.
+const RoomStateTopic = "lk.room_state"
+
+const (
+	RoomStateMessageSnapshot = "snapshot"
+	RoomStateMessageDelta    = "delta"
+	RoomStateMessageResult   = "result"
+)
+
+var (
+	ErrInvalidRoomState          = errors.New("invalid room state update")
+	ErrRoomStateConflict         = errors.New("room state conflict")
+	ErrRoomStateLimitExceeded    = errors.New("room state exceeds limits")
+	ErrRoomStatePermissionDenied = errors.New("not allowed to update room state")
+)
+
+// RoomStateMessage is sent by the server as JSON payload on RoomStateTopic
+type RoomStateMessage struct {
+	Type string `json:"type"`
+	// request of the participant the result is for
+	RequestID string `json:"request_id,omitempty"`
+	// version of the room state, incremented by every update
+	Version uint64 `json:"version"`
+	// whole state in snapshots, keys set by the update in deltas
+	Entries map[string]*types.RoomStateEntry `json:"entries,omitempty"`
+	// keys deleted by the update in deltas
+	Deleted []string `json:"deleted,omitempty"`
+	// reason the request failed in results: "invalid", "conflict", "limit_exceeded" or "permission_denied"
+	Error     string               `json:"error,omitempty"`
+	Conflicts []*RoomStateConflict `json:"conflicts,omitempty"`
+}
+
+type RoomStateConflict struct {
+	Key string `json:"key"`
+	// current version of the key, 0 when it does not exist
+	Version uint64 `json:"version"`
+}
+
+// RoomStateConflictError is returned when the expected version of keys did not match, with their current versions
+type RoomStateConflictError struct {
+	Conflicts []*RoomStateConflict
+}
+
+func (e *RoomStateConflictError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(ErrRoomStateConflict.Error())
+	for i, c := range e.Conflicts {
+		if i == 0 {
+			sb.WriteString(": ")
+		} else {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "key %q is at version %d", c.Key, c.Version)
+	}
+	return sb.String()
+}
+
+func (e *RoomStateConflictError) Unwrap() error {
+	return ErrRoomStateConflict
+}
+
+func ParseRoomStateRequest(payload []byte) (*types.RoomStateRequest, error) {
+	req := &types.RoomStateRequest{}
+	if err := json.Unmarshal(payload, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (r *Room) SetLimits(limits config.LimitConfig) {
+	r.roomStateLock.Lock()
+	defer r.roomStateLock.Unlock()
+
+	r.limits = limits
+}
+
+// GetRoomState returns the version of the room state and its entries
+func (r *Room) GetRoomState() (uint64, map[string]*types.RoomStateEntry) {
+	r.roomStateLock.Lock()
+	defer r.roomStateLock.Unlock()
+
+	return r.roomStateVersion, maps.Clone(r.roomState)
+}
+
+// UpdateRoomState applies updates to the room state, all of them or none when an expected version does not match
+// or the state would exceed limits. The delta is sent to all participants. Returns the version of the room state.
+func (r *Room) UpdateRoomState(updatedBy livekit.ParticipantIdentity, updates []*types.RoomStateUpdate) (uint64, error) {
+	if len(updates) == 0 {
+		return 0, fmt.Errorf("%w: no updates", ErrInvalidRoomState)
+	}
+	keys := make(map[string]struct{}, len(updates))
+	for _, u := range updates {
+		if u == nil || u.Key == "" {
+			return 0, fmt.Errorf("%w: key is required", ErrInvalidRoomState)
+		}
+		if _, ok := keys[u.Key]; ok {
+			return 0, fmt.Errorf("%w: duplicate key %q", ErrInvalidRoomState, u.Key)
+		}
+		keys[u.Key] = struct{}{}
+	}
+
+	r.roomStateLock.Lock()
+	defer r.roomStateLock.Unlock()
+
+	for _, u := range updates {
+		if !u.Delete && !r.limits.CheckRoomStateKeyLength(u.Key) {
+			return r.roomStateVersion, fmt.Errorf("%w: key %q is too long", ErrRoomStateLimitExceeded, u.Key)
+		}
+	}
+
+	var conflicts []*RoomStateConflict
+	for _, u := range updates {
+		if u.ExpectedVersion == nil {
+			continue
+		}
+		var current uint64
+		if entry := r.roomState[u.Key]; entry != nil {
+			current = entry.Version
+		}
+		if current != *u.ExpectedVersion {
+			conflicts = append(conflicts, &RoomStateConflict{Key: u.Key, Version: current})
+		}
+	}
+	if len(conflicts) != 0 {
+		return r.roomStateVersion, &RoomStateConflictError{Conflicts: conflicts}
+	}
+
+	numKeys, size := 0, 0
+	for key, entry := range r.roomState {
+		if _, ok := keys[key]; !ok {
+			numKeys++
+			size += len(key) + len(entry.Value)
+		}
+	}
+	for _, u := range updates {
+		if !u.Delete {
+			numKeys++
+			size += len(u.Key) + len(u.Value)
+		}
+	}
+	if !r.limits.CheckRoomStateSize(numKeys, size) {
+		return r.roomStateVersion, ErrRoomStateLimitExceeded
+	}
+
+	delta := &RoomStateMessage{
+		Type:    RoomStateMessageDelta,
+		Version: r.roomStateVersion + 1,
+		Entries: make(map[string]*types.RoomStateEntry, len(updates)),
+	}
+	now := time.Now().UnixMilli()
+	for _, u := range updates {
+		if u.Delete {
+			if _, ok := r.roomState[u.Key]; ok {
+				delete(r.roomState, u.Key)
+				delta.Deleted = append(delta.Deleted, u.Key)
+			}
+			continue
+		}
+		entry := &types.RoomStateEntry{
+			Value:     u.Value,
+			Version:   delta.Version,
+			UpdatedBy: string(updatedBy),
+			UpdatedAt: now,
+		}
+		if r.roomState == nil {
+			r.roomState = make(map[string]*types.RoomStateEntry)
+		}
+		r.roomState[u.Key] = entry
+		delta.Entries[u.Key] = entry
+	}
+	if len(delta.Entries) == 0 && len(delta.Deleted) == 0 {
+		// only deletes of keys which do not exist
+		return r.roomStateVersion, nil
+	}
+	r.roomStateVersion = delta.Version
+
+	r.logger.Debugw("updated room state", "version", delta.Version, "updatedBy", updatedBy, "keys", maps.Keys(keys))
+	// sent holding the lock so that participants receive deltas in order
+	r.sendServerData(RoomStateTopic, delta, nil)
+	return delta.Version, nil
+}
+
+// restoreRoomState replaces the room state, without notifying participants
+func (r *Room) restoreRoomState(version uint64, entries map[string]*types.RoomStateEntry) {
+	r.roomStateLock.Lock()
+	defer r.roomStateLock.Unlock()
+
+	r.roomStateVersion = version
+	r.roomState = maps.Clone(entries)
+}
+
+// sendRoomStateSnapshot sends the room state to a participant which joined the room, once its data channels are open.
+// Deltas sent before are dropped, the snapshot includes them.
+func (r *Room) sendRoomStateSnapshot(p types.LocalParticipant) {
+	r.roomStateLock.Lock()
+	defer r.roomStateLock.Unlock()
+
+	if r.roomStateVersion == 0 {
+		return
+	}
+	r.sendServerData(RoomStateTopic, &RoomStateMessage{
+		Type:    RoomStateMessageSnapshot,
+		Version: r.roomStateVersion,
+		Entries: r.roomState,
+	}, []string{string(p.Identity())})
+}
+
+// canUpdateRoomState returns true for room admins and participants with the permission to update metadata,
+// there is no dedicated permission for the room state
+func canUpdateRoomState(p types.LocalParticipant) bool {
+	grants := p.ClaimGrants()
+	if grants == nil || grants.Video == nil {
+		return false
+	}
+	return grants.Video.RoomAdmin || grants.Video.GetCanUpdateOwnMetadata()
+}
+
+func (r *Room) onRoomStateRequest(p types.LocalParticipant, req *types.RoomStateRequest) {
+	if r.IsPending(p.Identity()) {
+		// participants in the lobby get the state once admitted
+		return
+	}
+
+	res := &RoomStateMessage{
+		Type:      RoomStateMessageResult,
+		RequestID: req.RequestID,
+	}
+	var err error
+	if !canUpdateRoomState(p) {
+		err = ErrRoomStatePermissionDenied
+		res.Version, _ = r.GetRoomState()
+	} else {
+		res.Version, err = r.UpdateRoomState(p.Identity(), req.Updates)
+	}
+
+	var conflictErr *RoomStateConflictError
+	switch {
+	case err == nil:
+	case errors.As(err, &conflictErr):
+		res.Error = "conflict"
+		res.Conflicts = conflictErr.Conflicts
+	case errors.Is(err, ErrRoomStateLimitExceeded):
+		res.Error = "limit_exceeded"
+	case errors.Is(err, ErrRoomStatePermissionDenied):
+		res.Error = "permission_denied"
+	default:
+		res.Error = "invalid"
+	}
+	if err != nil {
+		p.GetLogger().Debugw("room state update failed", "error", err, "requestID", req.RequestID)
+	}
+	r.sendServerData(RoomStateTopic, res, []string{string(p.Identity())})
+}
@@ -0,0 +1,220 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+var (
+	ErrInvalidSubscriptionLimits = errors.New("invalid subscription limits")
+)
+
+// SubscriptionLimits bound what participants receive, they are set by room admins for a participant or for the room.
+// The bitrate is enforced by the stream allocator as a ceiling on the channel capacity of the subscriber, so it needs
+// congestion control to be enabled. Video quality is capped irrespective of what the subscriber asks for.
+//
+// For the room, MaxBitrate is a total egress budget shared by subscribers of the room. Subscribers with a lower limit
+// of their own leave the remainder to others, the rest share it equally.
+type SubscriptionLimits struct {
+	// max downstream bitrate in bps, no limit when 0
+	MaxBitrate int64 `json:"max_bitrate,omitempty"`
+	// max quality of subscribed video, "low", "medium" or "high", no limit when empty
+	MaxVideoQuality string `json:"max_video_quality,omitempty"`
+}
+
+func (l *SubscriptionLimits) IsEmpty() bool {
+	return l == nil || (l.MaxBitrate == 0 && l.MaxVideoQuality == "")
+}
+
+func (l *SubscriptionLimits) Validate() error {
+	if l == nil {
+		return nil
+	}
+	if l.MaxBitrate < 0 {
+		return fmt.Errorf("%w: negative max bitrate", ErrInvalidSubscriptionLimits)
+	}
+	if l.MaxVideoQuality != "" {
+		quality, ok := livekit.VideoQuality_value[strings.ToUpper(l.MaxVideoQuality)]
+		if !ok || livekit.VideoQuality(quality) == livekit.VideoQuality_OFF {
+			return fmt.Errorf("%w: unknown video quality %q", ErrInvalidSubscriptionLimits, l.MaxVideoQuality)
+		}
+	}
+	return nil
+}
+
+func (l *SubscriptionLimits) maxVideoQuality() livekit.VideoQuality {
+	if l == nil || l.MaxVideoQuality == "" {
+		return livekit.VideoQuality_HIGH
+	}
+	return livekit.VideoQuality(livekit.VideoQuality_value[strings.ToUpper(l.MaxVideoQuality)])
+}
+
+// SetSubscriptionLimits sets limits shared by all subscribers of the room, empty limits remove them
+func (r *Room) SetSubscriptionLimits(limits *SubscriptionLimits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	if limits.IsEmpty() {
+		r.subscriptionLimits = nil
+	} else {
+		r.subscriptionLimits = limits
+	}
+	r.lock.Unlock()
+
+	r.logger.Infow("updated room subscription limits", "limits", limits)
+	r.applySubscriptionLimits()
+	return nil
+}
+
+func (r *Room) GetSubscriptionLimits() *SubscriptionLimits {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.subscriptionLimits
+}
+
+// SetParticipantSubscriptionLimits sets limits of a participant, empty limits remove them.
+// Limits are kept by identity, so that they still apply when the participant reconnects.
+func (r *Room) SetParticipantSubscriptionLimits(identity livekit.ParticipantIdentity, limits *SubscriptionLimits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	p := r.participants[identity]
+	if p == nil {
+		r.lock.Unlock()
+		return ErrParticipantNotFound
+	}
+	if limits.IsEmpty() {
+		delete(r.participantSubscriptionLimits, identity)
+	} else {
+		r.participantSubscriptionLimits[identity] = limits
+	}
+	r.lock.Unlock()
+
+	p.GetLogger().Infow("updated participant subscription limits", "limits", limits)
+	r.applySubscriptionLimits()
+	return nil
+}
+
+func (r *Room) GetParticipantSubscriptionLimits(identity livekit.ParticipantIdentity) *SubscriptionLimits {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.participantSubscriptionLimits[identity]
+}
+
+func (r *Room) hasSubscriptionLimits() bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.subscriptionLimits != nil || len(r.participantSubscriptionLimits) != 0
+}
+
+// maybeApplySubscriptionLimits re-distributes the room budget when subscribers come and go
+func (r *Room) maybeApplySubscriptionLimits() {
+	if r.hasSubscriptionLimits() {
+		r.applySubscriptionLimits()
+	}
+}
+
+type subscriberLimits struct {
+	participant     types.LocalParticipant
+	maxBitrate      int64
+	maxVideoQuality livekit.VideoQuality
+}
+
+func (r *Room) applySubscriptionLimits() {
+	r.lock.RLock()
+	roomLimits := r.subscriptionLimits
+	var subscribers []*subscriberLimits
+	for identity, p := range r.participants {
+		// participants in the lobby are not receiving anything yet
+		if r.pendingParticipants[identity] != nil {
+			continue
+		}
+		limits := r.participantSubscriptionLimits[identity]
+		quality := limits.maxVideoQuality()
+		if roomQuality := roomLimits.maxVideoQuality(); roomQuality < quality {
+			quality = roomQuality
+		}
+		var maxBitrate int64
+		if limits != nil {
+			maxBitrate = limits.MaxBitrate
+		}
+		subscribers = append(subscribers, &subscriberLimits{
+			participant:     p,
+			maxBitrate:      maxBitrate,
+			maxVideoQuality: quality,
+		})
+	}
+	r.lock.RUnlock()
+
+	if roomLimits != nil && roomLimits.MaxBitrate > 0 {
+		distributeSubscriptionBudget(roomLimits.MaxBitrate, subscribers)
+	}
+
+	for _, s := range subscribers {
+		s.participant.SetSubscriberMaxChannelCapacity(s.maxBitrate)
+		s.participant.SetSubscriberMaxVideoQuality(s.maxVideoQuality)
+	}
+}
+
+// distributeSubscriptionBudget shares budget among subscribers that can subscribe, subscribers limited below
+// their fair share keep their limit and leave the remainder to others
+func distributeSubscriptionBudget(budget int64, subscribers []*subscriberLimits) {
+	var sharing []*subscriberLimits
+	for _, s := range subscribers {
+		if s.participant.CanSubscribe() {
+			sharing = append(sharing, s)
+		}
+	}
+	slices.SortFunc(sharing, func(a, b *subscriberLimits) int {
+		// unlimited last
+		switch {
+		case a.maxBitrate == b.maxBitrate:
+			return 0
+		case a.maxBitrate == 0:
+			return 1
+		case b.maxBitrate == 0:
+			return -1
+		case a.maxBitrate < b.maxBitrate:
+			return -1
+		default:
+			return 1
+		}
+	})
+
+	remaining := budget
+	for i, s := range sharing {
+		// at least a bit, as no max means no limit
+		share := max(remaining/int64(len(sharing)-i), 1)
+		if s.maxBitrate == 0 || s.maxBitrate > share {
+			s.maxBitrate = share
+		}
+		remaining -= s.maxBitrate
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+	"golang.org/x/exp/maps"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+var (
+	ErrInvalidSubscriptionPolicy = errors.New("invalid subscription policy")
+)
+
+// SubscriptionPolicy is set by room admins to decide which tracks participants of a room subscribe to.
+// Rules are evaluated in order for every subscriber and published track, the first matching rule decides
+// whether the subscriber is subscribed to the track. Tracks not matching any rule follow auto subscribe.
+//
+// e.g. everyone subscribes to the stage, audience audio only while speaking and never audience video
+//
+//	{"rules": [
+//	  {"publishers": "group == stage", "subscribe": true},
+//	  {"publishers": "group == audience && speaking == true", "kinds": ["audio"], "subscribe": true},
+//	  {"publishers": "group == audience", "subscribe": false}
+//	]}
+type SubscriptionPolicy struct {
+	Rules []*SubscriptionPolicyRule `json:"rules"`
+}
+
+func (p *SubscriptionPolicy) GetRules() []*SubscriptionPolicyRule {
+	if p == nil {
+		return nil
+	}
+	return p.Rules
+}
+
+type SubscriptionPolicyRule struct {
+	// rule over the publisher, any publisher when empty, see SubscriptionRulePrefix for the syntax
+	Publishers string `json:"publishers,omitempty"`
+	// rule over the subscriber, any subscriber when empty
+	Subscribers string `json:"subscribers,omitempty"`
+	// kinds of tracks, "audio" or "video", any kind when empty
+	Kinds []string `json:"kinds,omitempty"`
+	// sources of tracks, e.g. "camera" or "screen_share", any source when empty
+	Sources []string `json:"sources,omitempty"`
+	// whether matching subscribers are subscribed or unsubscribed
+	Subscribe bool `json:"subscribe"`
+}
+
+type subscriptionPolicy struct {
+	policy *SubscriptionPolicy
+	rules  []*subscriptionPolicyRule
+}
+
+type subscriptionPolicyRule struct {
+	publishers  *SubscriptionRule
+	subscribers *SubscriptionRule
+	kinds       []livekit.TrackType
+	sources     []livekit.TrackSource
+	subscribe   bool
+}
+
+func newSubscriptionPolicy(policy *SubscriptionPolicy) (*subscriptionPolicy, error) {
+	sp := &subscriptionPolicy{policy: policy}
+	for i, rule := range policy.Rules {
+		if rule == nil {
+			return nil, fmt.Errorf("%w: rule %d is empty", ErrInvalidSubscriptionPolicy, i)
+		}
+
+		r := &subscriptionPolicyRule{subscribe: rule.Subscribe}
+		var err error
+		if rule.Publishers != "" {
+			if r.publishers, err = ParseSubscriptionRule(rule.Publishers); err != nil {
+				return nil, fmt.Errorf("%w: rule %d: %w", ErrInvalidSubscriptionPolicy, i, err)
+			}
+		}
+		if rule.Subscribers != "" {
+			if r.subscribers, err = ParseSubscriptionRule(rule.Subscribers); err != nil {
+				return nil, fmt.Errorf("%w: rule %d: %w", ErrInvalidSubscriptionPolicy, i, err)
+			}
+		}
+		for _, name := range rule.Kinds {
+			kind, ok := livekit.TrackType_value[strings.ToUpper(name)]
+			if !ok || livekit.TrackType(kind) == livekit.TrackType_DATA {
+				return nil, fmt.Errorf("%w: rule %d: unknown track kind %q", ErrInvalidSubscriptionPolicy, i, name)
+			}
+			r.kinds = append(r.kinds, livekit.TrackType(kind))
+		}
+		for _, name := range rule.Sources {
+			source, ok := livekit.TrackSource_value[strings.ToUpper(name)]
+			if !ok {
+				return nil, fmt.Errorf("%w: rule %d: unknown track source %q", ErrInvalidSubscriptionPolicy, i, name)
+			}
+			r.sources = append(r.sources, livekit.TrackSource(source))
+		}
+		sp.rules = append(sp.rules, r)
+	}
+	return sp, nil
+}
+
+// shouldSubscribe returns whether sub should be subscribed to the track, ok is false when no rule matches
+func (sp *subscriptionPolicy) shouldSubscribe(sub types.LocalParticipant, pub types.Participant, track types.MediaTrack) (subscribe bool, ok bool) {
+	if sp == nil {
+		return false, false
+	}
+	for _, r := range sp.rules {
+		if len(r.kinds) != 0 && !slices.Contains(r.kinds, track.Kind()) {
+			continue
+		}
+		if len(r.sources) != 0 && !slices.Contains(r.sources, track.Source()) {
+			continue
+		}
+		if r.publishers != nil {
+			subject, isSubject := pub.(SubscriptionRuleSubject)
+			if !isSubject || !r.publishers.Matches(subject) {
+				continue
+			}
+		}
+		if r.subscribers != nil && !r.subscribers.Matches(sub) {
+			continue
+		}
+		return r.subscribe, true
+	}
+	return false, false
+}
+
+// SetSubscriptionPolicy replaces the subscription policy of the room and reconciles subscriptions of all
+// participants, a nil policy or one without rules removes it and tracks go back to auto subscribe
+func (r *Room) SetSubscriptionPolicy(policy *SubscriptionPolicy) error {
+	var sp *subscriptionPolicy
+	if policy != nil && len(policy.Rules) != 0 {
+		var err error
+		if sp, err = newSubscriptionPolicy(policy); err != nil {
+			return err
+		}
+	}
+
+	r.lock.Lock()
+	prev := r.subscriptionPolicy
+	r.subscriptionPolicy = sp
+	r.lock.Unlock()
+
+	r.logger.Infow("updated subscription policy", "numRules", len(policy.GetRules()))
+	if prev == nil && sp == nil {
+		return nil
+	}
+	for _, p := range r.GetLocalParticipants() {
+		r.applySubscriptionPolicyForSubscriber(sp, p)
+	}
+	return nil
+}
+
+func (r *Room) GetSubscriptionPolicy() *SubscriptionPolicy {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if r.subscriptionPolicy == nil {
+		return nil
+	}
+	return r.subscriptionPolicy.policy
+}
+
+// shouldSubscribeLocked checks if participant should be subscribed to a track by the subscription policy,
+// falling back to auto subscribe, assumes lock is already acquired
+func (r *Room) shouldSubscribeLocked(sub types.LocalParticipant, pub types.Participant, track types.MediaTrack) bool {
+	if subscribe, ok := r.subscriptionPolicy.shouldSubscribe(sub, pub, track); ok {
+		return subscribe
+	}
+	return r.autoSubscribe(sub)
+}
+
+// reconcileSubscriptionPolicy applies the subscription policy after participant p has changed, both to its
+// subscriptions and to subscriptions of others to its tracks
+func (r *Room) reconcileSubscriptionPolicy(p types.Participant) {
+	if lp, ok := p.(types.LocalParticipant); ok {
+		r.reconcileSubscriptionPolicyForSubscriber(lp)
+	}
+	r.reconcileSubscriptionPolicyForPublisher(p)
+}
+
+func (r *Room) reconcileSubscriptionPolicyForSubscriber(sub types.LocalParticipant) {
+	r.lock.RLock()
+	sp := r.subscriptionPolicy
+	r.lock.RUnlock()
+	if sp == nil {
+		return
+	}
+	r.applySubscriptionPolicyForSubscriber(sp, sub)
+}
+
+// applySubscriptionPolicyForSubscriber reconciles subscriptions of sub to tracks of all publishers with sp,
+// sp is nil when a policy has been removed
+func (r *Room) applySubscriptionPolicyForSubscriber(sp *subscriptionPolicy, sub types.LocalParticipant) {
+	r.lock.RLock()
+	if !r.isReadyForPolicyLocked(sub) {
+		r.lock.RUnlock()
+		return
+	}
+	autoSubscribe := r.autoSubscribe(sub)
+	publishers := toParticipants(maps.Values(r.participants))
+	publishers = append(publishers, r.getRelayedParticipantsLocked()...)
+	r.lock.RUnlock()
+
+	for _, pub := range publishers {
+		if pub.ID() == sub.ID() {
+			continue
+		}
+		for _, track := range pub.GetPublishedTracks() {
+			applySubscriptionPolicy(sp, autoSubscribe, sub, pub, track)
+		}
+	}
+}
+
+func (r *Room) reconcileSubscriptionPolicyForPublisher(pub types.Participant) {
+	r.lock.RLock()
+	sp := r.subscriptionPolicy
+	if sp == nil {
+		r.lock.RUnlock()
+		return
+	}
+	var subscribers []types.LocalParticipant
+	autoSubscribe := make(map[livekit.ParticipantID]bool)
+	for _, sub := range r.participants {
+		if sub.ID() != pub.ID() && r.isReadyForPolicyLocked(sub) {
+			subscribers = append(subscribers, sub)
+			autoSubscribe[sub.ID()] = r.autoSubscribe(sub)
+		}
+	}
+	r.lock.RUnlock()
+
+	tracks := pub.GetPublishedTracks()
+	for _, sub := range subscribers {
+		for _, track := range tracks {
+			applySubscriptionPolicy(sp, autoSubscribe[sub.ID()], sub, pub, track)
+		}
+	}
+}
+
+func (r *Room) isReadyForPolicyLocked(sub types.LocalParticipant) bool {
+	// participants in the lobby or still joining subscribe once they are active
+	return r.pendingParticipants[sub.Identity()] == nil && sub.State() == livekit.ParticipantInfo_ACTIVE
+}
+
+// applySubscriptionPolicy subscribes or unsubscribes sub by the first matching rule of sp. Tracks no rule
+// matches follow auto subscribe, so that tracks unsubscribed by a rule that no longer matches, or by a removed
+// policy, are subscribed again. Subscriptions of participants without auto subscribe are left to them.
+func applySubscriptionPolicy(sp *subscriptionPolicy, autoSubscribe bool, sub types.LocalParticipant, pub types.Participant, track types.MediaTrack) {
+	subscribe, ok := sp.shouldSubscribe(sub, pub, track)
+	if !ok {
+		if !autoSubscribe {
+			return
+		}
+		subscribe = true
+	}
+
+	isSubscriber := track.IsSubscriber(sub.ID())
+	switch {
+	case subscribe && !isSubscriber:
+		sub.GetLogger().Debugw("subscribing to track by subscription policy", "publisher", pub.Identity(), "trackID", track.ID())
+		sub.SubscribeToTrack(track.ID(), false)
+	case !subscribe && isSubscriber:
+		sub.GetLogger().Debugw("unsubscribing from track by subscription policy", "publisher", pub.Identity(), "trackID", track.ID())
+		sub.UnsubscribeFromTrack(track.ID())
+	}
+}
@@ -15,13 +15,18 @@
 package rtc
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/proto"
 
+	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/auth/authfakes"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/utils"
@@ -806,6 +811,956 @@ func TestRoomUpdate(t *testing.T) {
 	})
 }
 
+func TestLobby(t *testing.T) {
+	joinPending := func(t *testing.T, rm *Room) *typesfakes.FakeLocalParticipant {
+		p := NewMockParticipant("pending", types.CurrentProtocol, true, false, rm.LocalParticipantListener())
+		err := rm.Join(p, nil, &ParticipantOptions{
+			AutoSubscribe:   true,
+			LobbyPermission: &livekit.ParticipantPermission{CanPublish: true, CanSubscribe: true},
+		}, iceServersForRoom)
+		require.NoError(t, err)
+
+		p.StateReturns(livekit.ParticipantInfo_ACTIVE)
+		rm.LocalParticipantListener().OnStateChange(p)
+		return p
+	}
+
+	t.Run("pending participant does not see or subscribe to others", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+
+		p := joinPending(t, rm)
+
+		res := p.SendJoinResponseArgsForCall(0)
+		require.Empty(t, res.OtherParticipants)
+		require.Zero(t, p.SubscribeToTrackCallCount())
+		require.True(t, rm.IsPending(p.Identity()))
+		require.Len(t, rm.GetParticipants(), 3)
+		require.Len(t, rm.GetLocalParticipants(), 2)
+
+		pending := rm.GetPendingParticipants()
+		require.Len(t, pending, 1)
+		require.Equal(t, string(p.Identity()), pending[0].Identity)
+	})
+
+	t.Run("admins are notified of pending participants", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+
+		participants := rm.GetParticipants()
+		admin := participants[0].(*typesfakes.FakeLocalParticipant)
+		admin.ClaimGrantsReturns(&auth.ClaimGrants{Video: &auth.VideoGrant{RoomAdmin: true}})
+		other := participants[1].(*typesfakes.FakeLocalParticipant)
+
+		joinPending(t, rm)
+
+		require.Eventually(t, func() bool { return admin.SendDataMessageCallCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+		_, data, _, _ := admin.SendDataMessageArgsForCall(0)
+		dp := &livekit.DataPacket{}
+		require.NoError(t, proto.Unmarshal(data, dp))
+		require.Equal(t, LobbyTopic, dp.GetUser().GetTopic())
+		require.Contains(t, string(dp.GetUser().GetPayload()), LobbyEventParticipantPending)
+		require.Zero(t, other.SendDataMessageCallCount())
+	})
+
+	t.Run("admitted participant is granted permission and subscribes", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+
+		p := joinPending(t, rm)
+
+		_, err := rm.AdmitParticipant(p.Identity())
+		require.NoError(t, err)
+		require.False(t, rm.IsPending(p.Identity()))
+		require.Len(t, rm.GetLocalParticipants(), 3)
+
+		require.Equal(t, 1, p.SetPermissionCallCount())
+		require.True(t, p.SetPermissionArgsForCall(0).CanPublish)
+		require.Len(t, p.SendParticipantUpdateArgsForCall(0), 2)
+		require.Eventually(t, func() bool { return p.SubscribeToTrackCallCount() == 2 }, 5*time.Second, 10*time.Millisecond)
+
+		_, err = rm.AdmitParticipant(p.Identity())
+		require.ErrorIs(t, err, ErrParticipantNotPending)
+	})
+
+	t.Run("rejected participant is removed", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+
+		p := joinPending(t, rm)
+
+		require.NoError(t, rm.RejectParticipant(p.Identity()))
+		require.Nil(t, rm.GetParticipant(p.Identity()))
+		require.Empty(t, rm.GetPendingParticipants())
+		_, reason, _ := p.CloseArgsForCall(0)
+		require.Equal(t, types.ParticipantCloseReasonUserRejected, reason)
+
+		require.ErrorIs(t, rm.RejectParticipant(p.Identity()), ErrParticipantNotPending)
+	})
+}
+
+func TestPublishRequest(t *testing.T) {
+	newRoom := func(t *testing.T, maxPublishers int) (*Room, *typesfakes.FakeLocalParticipant, *typesfakes.FakeLocalParticipant) {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+		rm.roomConfig.PublishRequest = config.PublishRequestConfig{
+			Timeout:       time.Minute,
+			MaxPublishers: maxPublishers,
+		}
+
+		participants := rm.GetParticipants()
+		admin := participants[0].(*typesfakes.FakeLocalParticipant)
+		admin.ClaimGrantsReturns(&auth.ClaimGrants{Video: &auth.VideoGrant{RoomAdmin: true}})
+		admin.CanPublishReturns(true)
+		viewer := participants[1].(*typesfakes.FakeLocalParticipant)
+		viewer.ClaimGrantsReturns(&auth.ClaimGrants{Video: &auth.VideoGrant{CanPublish: new(bool)}})
+		return rm, admin, viewer
+	}
+
+	lastEvent := func(t *testing.T, p *typesfakes.FakeLocalParticipant) *PublishRequestEvent {
+		_, data, _, _ := p.SendDataMessageArgsForCall(p.SendDataMessageCallCount() - 1)
+		dp := &livekit.DataPacket{}
+		require.NoError(t, proto.Unmarshal(data, dp))
+		require.Equal(t, PublishRequestTopic, dp.GetUser().GetTopic())
+		ev := &PublishRequestEvent{}
+		require.NoError(t, json.Unmarshal(dp.GetUser().GetPayload(), ev))
+		return ev
+	}
+
+	t.Run("parses request", func(t *testing.T) {
+		req, err := ParsePublishRequestMessage([]byte(`{"sources":["microphone","camera","camera"]}`))
+		require.NoError(t, err)
+		require.Equal(t, []livekit.TrackSource{livekit.TrackSource_CAMERA, livekit.TrackSource_MICROPHONE}, req.Sources)
+
+		_, err = ParsePublishRequestMessage([]byte(`{"sources":["hologram"]}`))
+		require.Error(t, err)
+	})
+
+	t.Run("approved request grants requested sources", func(t *testing.T) {
+		rm, admin, viewer := newRoom(t, 0)
+		defer rm.Close(types.ParticipantCloseReasonNone)
+
+		rm.LocalParticipantListener().OnPublishPermissionRequest(viewer, &types.PublishPermissionRequest{
+			Sources: []livekit.TrackSource{livekit.TrackSource_CAMERA, livekit.TrackSource_MICROPHONE},
+		})
+		require.Eventually(t, func() bool { return admin.SendDataMessageCallCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+		ev := lastEvent(t, admin)
+		require.Equal(t, PublishRequestEventRequested, ev.Event)
+		require.Equal(t, []string{"camera", "microphone"}, ev.Sources)
+		require.Equal(t, 1, ev.NumPending)
+
+		_, err := rm.ApprovePublishRequest(viewer.Identity())
+		require.NoError(t, err)
+		require.Equal(t, 1, viewer.SetPermissionCallCount())
+		perm := viewer.SetPermissionArgsForCall(0)
+		require.True(t, perm.CanPublish)
+		require.Equal(t, []livekit.TrackSource{livekit.TrackSource_CAMERA, livekit.TrackSource_MICROPHONE}, perm.CanPublishSources)
+
+		require.Eventually(t, func() bool { return viewer.SendDataMessageCallCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+		require.Equal(t, PublishRequestEventApproved, lastEvent(t, viewer).Event)
+
+		_, err = rm.ApprovePublishRequest(viewer.Identity())
+		require.ErrorIs(t, err, ErrNoPublishRequest)
+	})
+
+	t.Run("denied request leaves permission unchanged", func(t *testing.T) {
+		rm, admin, viewer := newRoom(t, 0)
+		defer rm.Close(types.ParticipantCloseReasonNone)
+
+		rm.LocalParticipantListener().OnPublishPermissionRequest(viewer, &types.PublishPermissionRequest{})
+		_, err := rm.DenyPublishRequest(viewer.Identity())
+		require.NoError(t, err)
+		require.Zero(t, viewer.SetPermissionCallCount())
+
+		require.Eventually(t, func() bool { return viewer.SendDataMessageCallCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+		require.Equal(t, PublishRequestEventDenied, lastEvent(t, viewer).Event)
+		require.Eventually(t, func() bool { return admin.SendDataMessageCallCount() == 2 }, 5*time.Second, 10*time.Millisecond)
+		require.Equal(t, 0, lastEvent(t, admin).NumPending)
+	})
+
+	t.Run("cancelled request cannot be approved", func(t *testing.T) {
+		rm, _, viewer := newRoom(t, 0)
+		defer rm.Close(types.ParticipantCloseReasonNone)
+
+		rm.LocalParticipantListener().OnPublishPermissionRequest(viewer, &types.PublishPermissionRequest{})
+		rm.LocalParticipantListener().OnPublishPermissionRequest(viewer, &types.PublishPermissionRequest{Cancel: true})
+		_, err := rm.ApprovePublishRequest(viewer.Identity())
+		require.ErrorIs(t, err, ErrNoPublishRequest)
+	})
+
+	t.Run("approval is limited by max publishers", func(t *testing.T) {
+		rm, _, viewer := newRoom(t, 1)
+		defer rm.Close(types.ParticipantCloseReasonNone)
+
+		rm.LocalParticipantListener().OnPublishPermissionRequest(viewer, &types.PublishPermissionRequest{})
+		_, err := rm.ApprovePublishRequest(viewer.Identity())
+		require.ErrorIs(t, err, ErrMaxPublishersExceeded)
+		require.Zero(t, viewer.SetPermissionCallCount())
+	})
+
+	t.Run("request expires", func(t *testing.T) {
+		rm, _, viewer := newRoom(t, 0)
+		defer rm.Close(types.ParticipantCloseReasonNone)
+		rm.roomConfig.PublishRequest.Timeout = 10 * time.Millisecond
+
+		rm.LocalParticipantListener().OnPublishPermissionRequest(viewer, &types.PublishPermissionRequest{})
+		require.Eventually(t, func() bool { return viewer.SendDataMessageCallCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+		require.Equal(t, PublishRequestEventExpired, lastEvent(t, viewer).Event)
+		_, err := rm.ApprovePublishRequest(viewer.Identity())
+		require.ErrorIs(t, err, ErrNoPublishRequest)
+	})
+
+	t.Run("request does not expire without timeout", func(t *testing.T) {
+		rm, _, viewer := newRoom(t, 0)
+		defer rm.Close(types.ParticipantCloseReasonNone)
+		rm.roomConfig.PublishRequest.Timeout = 0
+
+		rm.LocalParticipantListener().OnPublishPermissionRequest(viewer, &types.PublishPermissionRequest{})
+		require.Never(t, func() bool { return viewer.SendDataMessageCallCount() != 0 }, 100*time.Millisecond, 10*time.Millisecond)
+		_, err := rm.ApprovePublishRequest(viewer.Identity())
+		require.NoError(t, err)
+		require.Equal(t, 1, viewer.SetPermissionCallCount())
+	})
+}
+
+func TestSubscriptionPolicy(t *testing.T) {
+	policy := &SubscriptionPolicy{
+		Rules: []*SubscriptionPolicyRule{
+			{Publishers: "group == stage", Subscribe: true},
+			{Publishers: "group == audience && speaking == true", Kinds: []string{"audio"}, Subscribe: true},
+			{Publishers: "group == audience", Subscribe: false},
+		},
+	}
+
+	joinPublisher := func(t *testing.T, rm *Room, identity livekit.ParticipantIdentity, group string) (*typesfakes.FakeLocalParticipant, *typesfakes.FakeMediaTrack, *typesfakes.FakeMediaTrack) {
+		p := NewMockParticipant(identity, types.CurrentProtocol, false, true, rm.LocalParticipantListener())
+		p.ClaimGrantsReturns(&auth.ClaimGrants{Attributes: map[string]string{"group": group}})
+		audio := NewMockTrack(livekit.TrackType_AUDIO, "audio")
+		video := NewMockTrack(livekit.TrackType_VIDEO, "video")
+		p.GetPublishedTracksReturns([]types.MediaTrack{audio, video})
+		require.NoError(t, rm.Join(p, nil, &ParticipantOptions{AutoSubscribe: true}, iceServersForRoom))
+		return p, audio, video
+	}
+
+	subscribedTracks := func(p *typesfakes.FakeLocalParticipant) []livekit.TrackID {
+		var trackIDs []livekit.TrackID
+		for i := 0; i < p.SubscribeToTrackCallCount(); i++ {
+			trackID, _ := p.SubscribeToTrackArgsForCall(i)
+			trackIDs = append(trackIDs, trackID)
+		}
+		return trackIDs
+	}
+
+	t.Run("invalid policy is rejected", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+
+		err := rm.SetSubscriptionPolicy(&SubscriptionPolicy{Rules: []*SubscriptionPolicyRule{{Kinds: []string{"hologram"}}}})
+		require.ErrorIs(t, err, ErrInvalidSubscriptionPolicy)
+		require.Nil(t, rm.GetSubscriptionPolicy())
+	})
+
+	t.Run("policy decides subscriptions on join and attribute changes", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+		require.NoError(t, rm.SetSubscriptionPolicy(policy))
+
+		_, stageAudio, stageVideo := joinPublisher(t, rm, "stage", "stage")
+		audience, audienceAudio, _ := joinPublisher(t, rm, "audience", "audience")
+
+		viewer := NewMockParticipant("viewer", types.CurrentProtocol, false, false, rm.LocalParticipantListener())
+		require.NoError(t, rm.Join(viewer, nil, &ParticipantOptions{AutoSubscribe: false}, iceServersForRoom))
+		viewer.StateReturns(livekit.ParticipantInfo_ACTIVE)
+		rm.LocalParticipantListener().OnStateChange(viewer)
+
+		// stage is subscribed regardless of auto subscribe, audience is not
+		require.ElementsMatch(t, []livekit.TrackID{stageAudio.ID(), stageVideo.ID()}, subscribedTracks(viewer))
+
+		// audience audio is subscribed while speaking
+		audience.ClaimGrantsReturns(&auth.ClaimGrants{Attributes: map[string]string{"group": "audience", "speaking": "true"}})
+		rm.LocalParticipantListener().OnParticipantUpdate(audience)
+		require.ElementsMatch(t, []livekit.TrackID{stageAudio.ID(), stageVideo.ID(), audienceAudio.ID()}, subscribedTracks(viewer))
+
+		audienceAudio.IsSubscriberReturns(true)
+		audience.ClaimGrantsReturns(&auth.ClaimGrants{Attributes: map[string]string{"group": "audience"}})
+		rm.LocalParticipantListener().OnParticipantUpdate(audience)
+		require.Equal(t, 1, viewer.UnsubscribeFromTrackCallCount())
+		require.Equal(t, audienceAudio.ID(), viewer.UnsubscribeFromTrackArgsForCall(0))
+	})
+
+	t.Run("updating policy reconciles existing participants", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+
+		_, _, audienceVideo := joinPublisher(t, rm, "audience", "audience")
+		viewer := NewMockParticipant("viewer", types.CurrentProtocol, false, false, rm.LocalParticipantListener())
+		require.NoError(t, rm.Join(viewer, nil, &ParticipantOptions{AutoSubscribe: true}, iceServersForRoom))
+		viewer.StateReturns(livekit.ParticipantInfo_ACTIVE)
+		rm.LocalParticipantListener().OnStateChange(viewer)
+		require.Len(t, subscribedTracks(viewer), 2)
+
+		audienceVideo.IsSubscriberReturns(true)
+		require.NoError(t, rm.SetSubscriptionPolicy(policy))
+		require.Equal(t, 1, viewer.UnsubscribeFromTrackCallCount())
+		require.Equal(t, audienceVideo.ID(), viewer.UnsubscribeFromTrackArgsForCall(0))
+		require.Equal(t, policy, rm.GetSubscriptionPolicy())
+	})
+
+	t.Run("removing policy restores auto subscribe", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+
+		_, audienceAudio, audienceVideo := joinPublisher(t, rm, "audience", "audience")
+		viewer := NewMockParticipant("viewer", types.CurrentProtocol, false, false, rm.LocalParticipantListener())
+		require.NoError(t, rm.Join(viewer, nil, &ParticipantOptions{AutoSubscribe: true}, iceServersForRoom))
+		viewer.StateReturns(livekit.ParticipantInfo_ACTIVE)
+		require.NoError(t, rm.SetSubscriptionPolicy(policy))
+		rm.LocalParticipantListener().OnStateChange(viewer)
+		require.Empty(t, subscribedTracks(viewer))
+
+		// narrowed policy no longer matches audience audio
+		require.NoError(t, rm.SetSubscriptionPolicy(&SubscriptionPolicy{
+			Rules: []*SubscriptionPolicyRule{{Publishers: "group == audience", Kinds: []string{"video"}, Subscribe: false}},
+		}))
+		require.ElementsMatch(t, []livekit.TrackID{audienceAudio.ID()}, subscribedTracks(viewer))
+		audienceAudio.IsSubscriberReturns(true)
+
+		require.NoError(t, rm.SetSubscriptionPolicy(nil))
+		require.Nil(t, rm.GetSubscriptionPolicy())
+		require.ElementsMatch(t, []livekit.TrackID{audienceAudio.ID(), audienceVideo.ID()}, subscribedTracks(viewer))
+		require.Zero(t, viewer.UnsubscribeFromTrackCallCount())
+	})
+}
+
+func TestSubscriptionRuleReevaluation(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+
+	pub := NewMockParticipant("pub", types.CurrentProtocol, false, true, rm.LocalParticipantListener())
+	pub.HasSubscriptionRulesReturns(true)
+	track := NewMockTrack(livekit.TrackType_VIDEO, "video")
+	pub.GetPublishedTracksReturns([]types.MediaTrack{track})
+	require.NoError(t, rm.Join(pub, nil, &ParticipantOptions{AutoSubscribe: true}, iceServersForRoom))
+
+	sub := NewMockParticipant("sub", types.CurrentProtocol, false, false, rm.LocalParticipantListener())
+	sub.ClaimGrantsReturns(&auth.ClaimGrants{Attributes: map[string]string{"role": "viewer"}, Video: &auth.VideoGrant{}})
+	require.NoError(t, rm.Join(sub, nil, &ParticipantOptions{AutoSubscribe: true}, iceServersForRoom))
+
+	rm.LocalParticipantListener().OnParticipantUpdate(sub)
+	evaluated := pub.HasPermissionCallCount()
+	require.NotZero(t, evaluated)
+
+	// metadata and name changes keep attributes and claims
+	sub.ClaimGrantsReturns(&auth.ClaimGrants{Attributes: map[string]string{"role": "viewer"}, Video: &auth.VideoGrant{}, Metadata: "updated"})
+	rm.LocalParticipantListener().OnParticipantUpdate(sub)
+	require.Equal(t, evaluated, pub.HasPermissionCallCount())
+
+	sub.ClaimGrantsReturns(&auth.ClaimGrants{Attributes: map[string]string{"role": "moderator"}, Video: &auth.VideoGrant{}})
+	rm.LocalParticipantListener().OnParticipantUpdate(sub)
+	require.Greater(t, pub.HasPermissionCallCount(), evaluated)
+	evaluated = pub.HasPermissionCallCount()
+
+	sub.ClaimGrantsReturns(&auth.ClaimGrants{Attributes: map[string]string{"role": "moderator"}, Video: &auth.VideoGrant{RoomAdmin: true}})
+	rm.LocalParticipantListener().OnParticipantUpdate(sub)
+	require.Greater(t, pub.HasPermissionCallCount(), evaluated)
+}
+
+func TestRoomSubscriptionLimits(t *testing.T) {
+	joinSubscriber := func(t *testing.T, rm *Room, identity livekit.ParticipantIdentity) *typesfakes.FakeLocalParticipant {
+		p := NewMockParticipant(identity, types.CurrentProtocol, false, false, rm.LocalParticipantListener())
+		require.NoError(t, rm.Join(p, nil, &ParticipantOptions{AutoSubscribe: true}, iceServersForRoom))
+		return p
+	}
+
+	lastLimits := func(p *typesfakes.FakeLocalParticipant) (int64, livekit.VideoQuality) {
+		require.NotZero(t, p.SetSubscriberMaxChannelCapacityCallCount())
+		require.NotZero(t, p.SetSubscriberMaxVideoQualityCallCount())
+		return p.SetSubscriberMaxChannelCapacityArgsForCall(p.SetSubscriberMaxChannelCapacityCallCount() - 1),
+			p.SetSubscriberMaxVideoQualityArgsForCall(p.SetSubscriberMaxVideoQualityCallCount() - 1)
+	}
+
+	t.Run("invalid limits are rejected", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+		joinSubscriber(t, rm, "mobile")
+
+		require.ErrorIs(t, rm.SetSubscriptionLimits(&SubscriptionLimits{MaxBitrate: -1}), ErrInvalidSubscriptionLimits)
+		require.ErrorIs(t, rm.SetSubscriptionLimits(&SubscriptionLimits{MaxVideoQuality: "off"}), ErrInvalidSubscriptionLimits)
+		require.ErrorIs(t, rm.SetParticipantSubscriptionLimits("mobile", &SubscriptionLimits{MaxVideoQuality: "ultra"}), ErrInvalidSubscriptionLimits)
+		require.ErrorIs(t, rm.SetParticipantSubscriptionLimits("unknown", &SubscriptionLimits{MaxBitrate: 1}), ErrParticipantNotFound)
+		require.Nil(t, rm.GetSubscriptionLimits())
+	})
+
+	t.Run("participant limits", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+		mobile := joinSubscriber(t, rm, "mobile")
+
+		limits := &SubscriptionLimits{MaxBitrate: 1_500_000, MaxVideoQuality: "medium"}
+		require.NoError(t, rm.SetParticipantSubscriptionLimits("mobile", limits))
+		require.Equal(t, limits, rm.GetParticipantSubscriptionLimits("mobile"))
+		maxBitrate, maxQuality := lastLimits(mobile)
+		require.EqualValues(t, 1_500_000, maxBitrate)
+		require.Equal(t, livekit.VideoQuality_MEDIUM, maxQuality)
+
+		// empty limits remove them
+		require.NoError(t, rm.SetParticipantSubscriptionLimits("mobile", &SubscriptionLimits{}))
+		require.Nil(t, rm.GetParticipantSubscriptionLimits("mobile"))
+		maxBitrate, maxQuality = lastLimits(mobile)
+		require.Zero(t, maxBitrate)
+		require.Equal(t, livekit.VideoQuality_HIGH, maxQuality)
+	})
+
+	t.Run("room budget is shared by subscribers", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+		mobile := joinSubscriber(t, rm, "mobile")
+		desktop1 := joinSubscriber(t, rm, "desktop1")
+		desktop2 := joinSubscriber(t, rm, "desktop2")
+		publisher := joinSubscriber(t, rm, "publisher")
+		publisher.CanSubscribeReturns(false)
+
+		require.NoError(t, rm.SetParticipantSubscriptionLimits("mobile", &SubscriptionLimits{MaxBitrate: 500_000, MaxVideoQuality: "low"}))
+		require.NoError(t, rm.SetSubscriptionLimits(&SubscriptionLimits{MaxBitrate: 3_000_000, MaxVideoQuality: "medium"}))
+
+		// mobile keeps its lower limit, the rest of the budget is shared equally by others that can subscribe
+		maxBitrate, maxQuality := lastLimits(mobile)
+		require.EqualValues(t, 500_000, maxBitrate)
+		require.Equal(t, livekit.VideoQuality_LOW, maxQuality)
+		for _, p := range []*typesfakes.FakeLocalParticipant{desktop1, desktop2} {
+			maxBitrate, maxQuality = lastLimits(p)
+			require.EqualValues(t, 1_250_000, maxBitrate)
+			require.Equal(t, livekit.VideoQuality_MEDIUM, maxQuality)
+		}
+		maxBitrate, _ = lastLimits(publisher)
+		require.Zero(t, maxBitrate)
+
+		// budget is re-distributed when a subscriber leaves
+		rm.RemoveParticipant("desktop2", "", types.ParticipantCloseReasonClientRequestLeave)
+		maxBitrate, _ = lastLimits(desktop1)
+		require.EqualValues(t, 2_500_000, maxBitrate)
+	})
+}
+
+func TestDataPolicy(t *testing.T) {
+	script := `
+text := import("text")
+if attributes.role == "muted" {
+  action = "drop"
+} else if text.contains(payload, "darn") {
+  action = "redact"
+  payload = text.replace(payload, "darn", "****", -1)
+} else if topic == "question" {
+  action = "redirect"
+  destinations = ["host"]
+} else if topic == "spin" {
+  for {}
+} else if topic == "broken" {
+  action = 42
+}`
+
+	setup := func(t *testing.T, policy *DataPolicy) (*Room, *typesfakes.FakeLocalParticipant, []*typesfakes.FakeLocalParticipant) {
+		rm := newRoomWithParticipants(t, testRoomOpts{})
+		t.Cleanup(func() { rm.Close(types.ParticipantCloseReasonNone) })
+		rm.roomConfig.DataPolicy.Timeout = 50 * time.Millisecond
+		require.NoError(t, rm.SetDataPolicy(policy))
+
+		var participants []*typesfakes.FakeLocalParticipant
+		for _, identity := range []livekit.ParticipantIdentity{"sender", "host", "viewer"} {
+			p := NewMockParticipant(identity, types.CurrentProtocol, false, false, rm.LocalParticipantListener())
+			p.ClaimGrantsReturns(&auth.ClaimGrants{Attributes: map[string]string{"role": "member"}})
+			require.NoError(t, rm.Join(p, nil, nil, iceServersForRoom))
+			participants = append(participants, p)
+		}
+		return rm, participants[0], participants[1:]
+	}
+
+	send := func(rm *Room, sender types.LocalParticipant, topic string, payload string) {
+		rm.LocalParticipantListener().OnDataMessage(sender, livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+			Value: &livekit.DataPacket_User{
+				User: &livekit.UserPacket{Topic: &topic, Payload: []byte(payload)},
+			},
+		})
+	}
+
+	received := func(t *testing.T, p *typesfakes.FakeLocalParticipant) []*livekit.DataPacket {
+		var packets []*livekit.DataPacket
+		for i := 0; i < p.SendDataMessageCallCount(); i++ {
+			_, data, _, _ := p.SendDataMessageArgsForCall(i)
+			dp := &livekit.DataPacket{}
+			require.NoError(t, proto.Unmarshal(data, dp))
+			packets = append(packets, dp)
+		}
+		return packets
+	}
+
+	t.Run("invalid script is rejected", func(t *testing.T) {
+		rm := newRoomWithParticipants(t, testRoomOpts{})
+		defer rm.Close(types.ParticipantCloseReasonNone)
+
+		require.ErrorIs(t, rm.SetDataPolicy(&DataPolicy{Script: "action = "}), ErrInvalidDataPolicy)
+		require.Nil(t, rm.GetDataPolicy())
+	})
+
+	t.Run("script decides what is forwarded", func(t *testing.T) {
+		rm, sender, others := setup(t, &DataPolicy{Script: script})
+
+		send(rm, sender, "chat", "hello")
+		send(rm, sender, "chat", "darn it")
+		send(rm, sender, "question", "when?")
+		sender.ClaimGrantsReturns(&auth.ClaimGrants{Attributes: map[string]string{"role": "muted"}})
+		send(rm, sender, "chat", "hello again")
+
+		host := received(t, others[0])
+		require.Len(t, host, 3)
+		require.Equal(t, "hello", string(host[0].GetUser().Payload))
+		require.Equal(t, "**** it", string(host[1].GetUser().Payload))
+		require.Equal(t, "when?", string(host[2].GetUser().Payload))
+		require.Equal(t, []string{"host"}, host[2].DestinationIdentities)
+
+		viewer := received(t, others[1])
+		require.Len(t, viewer, 2)
+		require.Equal(t, "**** it", string(viewer[1].GetUser().Payload))
+	})
+
+	t.Run("script failures forward or drop as set by the policy", func(t *testing.T) {
+		rm, sender, others := setup(t, &DataPolicy{Script: script})
+		send(rm, sender, "spin", "a")
+		send(rm, sender, "broken", "b")
+		require.Len(t, received(t, others[0]), 2)
+
+		rm, sender, others = setup(t, &DataPolicy{Script: script, DropOnError: true})
+		send(rm, sender, "spin", "a")
+		send(rm, sender, "broken", "b")
+		require.Empty(t, received(t, others[0]))
+	})
+
+	t.Run("script only runs for its topics", func(t *testing.T) {
+		rm, sender, others := setup(t, &DataPolicy{Script: script, Topics: []string{"question"}})
+		send(rm, sender, "chat", "darn it")
+		send(rm, sender, "question", "when?")
+
+		viewer := received(t, others[1])
+		require.Len(t, viewer, 1)
+		require.Equal(t, "darn it", string(viewer[0].GetUser().Payload))
+	})
+}
+
+type testDataHistoryStore struct {
+	lock    sync.Mutex
+	history map[string][]*DataHistoryMessage
+}
+
+func (s *testDataHistoryStore) StoreDataMessage(_ context.Context, _ livekit.RoomName, msg *DataHistoryMessage, maxMessages int, _ time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	msgs := append(s.history[msg.Topic], msg)
+	if maxMessages > 0 && len(msgs) > maxMessages {
+		msgs = msgs[len(msgs)-maxMessages:]
+	}
+	s.history[msg.Topic] = msgs
+	return nil
+}
+
+func (s *testDataHistoryStore) ListDataMessages(_ context.Context, _ livekit.RoomName, topic string) ([]*DataHistoryMessage, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return slices.Clone(s.history[topic]), nil
+}
+
+func (s *testDataHistoryStore) DeleteDataHistory(_ context.Context, _ livekit.RoomName) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	clear(s.history)
+	return nil
+}
+
+func TestDataHistory(t *testing.T) {
+	setup := func(t *testing.T) (*Room, *testDataHistoryStore) {
+		rm := newRoomWithParticipants(t, testRoomOpts{})
+		t.Cleanup(func() { rm.Close(types.ParticipantCloseReasonNone) })
+		store := &testDataHistoryStore{history: make(map[string][]*DataHistoryMessage)}
+		rm.SetDataHistoryStore(store)
+		return rm, store
+	}
+
+	join := func(t *testing.T, rm *Room, identity livekit.ParticipantIdentity) *typesfakes.FakeLocalParticipant {
+		p := NewMockParticipant(identity, types.CurrentProtocol, false, false, rm.LocalParticipantListener())
+		require.NoError(t, rm.Join(p, nil, nil, iceServersForRoom))
+		return p
+	}
+
+	send := func(rm *Room, sender types.LocalParticipant, topic string, payload string, destinations ...string) {
+		rm.LocalParticipantListener().OnDataMessage(sender, livekit.DataPacket_RELIABLE, &livekit.DataPacket{
+			DestinationIdentities: destinations,
+			Value: &livekit.DataPacket_User{
+				User: &livekit.UserPacket{Topic: &topic, Payload: []byte(payload)},
+			},
+		})
+	}
+
+	payloads := func(t *testing.T, p *typesfakes.FakeLocalParticipant, from int) []string {
+		var res []string
+		for i := from; i < p.SendDataMessageCallCount(); i++ {
+			_, data, _, _ := p.SendDataMessageArgsForCall(i)
+			dp := &livekit.DataPacket{}
+			require.NoError(t, proto.Unmarshal(data, dp))
+			res = append(res, dp.GetUser().GetTopic()+":"+string(dp.GetUser().Payload))
+		}
+		return res
+	}
+
+	t.Run("invalid retention is rejected", func(t *testing.T) {
+		rm, _ := setup(t)
+
+		require.ErrorIs(t, rm.SetDataRetention(&DataRetention{Topics: []*DataRetentionTopic{{Topic: ""}}}), ErrInvalidDataRetention)
+		require.ErrorIs(t, rm.SetDataRetention(&DataRetention{Topics: []*DataRetentionTopic{{Topic: "chat"}, {Topic: "chat"}}}), ErrInvalidDataRetention)
+		require.ErrorIs(t, rm.SetDataRetention(&DataRetention{Topics: []*DataRetentionTopic{{Topic: "chat", MaxMessages: -1}}}), ErrInvalidDataRetention)
+		require.Nil(t, rm.GetDataRetention())
+	})
+
+	t.Run("messages of retained topics are replayed on join", func(t *testing.T) {
+		rm, _ := setup(t)
+		require.NoError(t, rm.SetDataRetention(&DataRetention{Topics: []*DataRetentionTopic{
+			{Topic: "chat", MaxMessages: 2},
+			{Topic: "notes"},
+		}}))
+
+		sender := join(t, rm, "sender")
+		send(rm, sender, "chat", "one")
+		send(rm, sender, "notes", "for host", "host")
+		send(rm, sender, "cursor", "not kept")
+		send(rm, sender, "chat", "two")
+		send(rm, sender, "chat", "three")
+
+		require.Eventually(t, func() bool {
+			history, err := rm.GetDataHistory(context.Background(), "")
+			return err == nil && len(history) == 3
+		}, time.Second, 10*time.Millisecond)
+
+		history, err := rm.GetDataHistory(context.Background(), "chat")
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		require.Equal(t, "two", string(history[0].Payload))
+		require.Equal(t, "sender", history[0].Identity)
+
+		// replayed once the data channels are open, not when the participant becomes active
+		viewer := join(t, rm, "viewer")
+		sent := viewer.SendDataMessageCallCount()
+		rm.LocalParticipantListener().OnStateChange(viewer)
+		require.Empty(t, payloads(t, viewer, sent))
+		rm.LocalParticipantListener().OnFullyEstablished(viewer)
+		require.Equal(t, []string{"chat:two", "chat:three"}, payloads(t, viewer, sent))
+
+		host := join(t, rm, "host")
+		sent = host.SendDataMessageCallCount()
+		rm.replayDataHistory(host)
+		// messages sent within the same millisecond may be replayed in any order across topics
+		require.ElementsMatch(t, []string{"notes:for host", "chat:two", "chat:three"}, payloads(t, host, sent))
+
+		// migrating participants received the history before
+		host.IsMigrationReturns(true)
+		rm.replayDataHistory(host)
+		require.Len(t, payloads(t, host, sent), 3)
+	})
+
+	t.Run("messages older than the retention are not replayed", func(t *testing.T) {
+		rm, store := setup(t)
+		require.NoError(t, rm.SetDataRetention(&DataRetention{Topics: []*DataRetentionTopic{{Topic: "chat", MaxAgeSeconds: 60}}}))
+
+		store.history["chat"] = []*DataHistoryMessage{
+			{Topic: "chat", Payload: []byte("old"), Timestamp: time.Now().Add(-2 * time.Minute).UnixMilli()},
+			{Topic: "chat", Payload: []byte("recent"), Timestamp: time.Now().UnixMilli()},
+		}
+
+		viewer := join(t, rm, "viewer")
+		sent := viewer.SendDataMessageCallCount()
+		rm.replayDataHistory(viewer)
+		require.Equal(t, []string{"chat:recent"}, payloads(t, viewer, sent))
+	})
+}
+
+func TestRoomMetadataVersion(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+
+	expected := func(v uint64) *uint64 { return &v }
+
+	version, done, err := rm.UpdateMetadata("m1", expected(0))
+	require.NoError(t, err)
+	<-done
+	require.Equal(t, uint64(1), version)
+
+	_, _, err = rm.UpdateMetadata("m2", expected(0))
+	var conflictErr *MetadataConflictError
+	require.ErrorAs(t, err, &conflictErr)
+	require.Equal(t, []*MetadataConflict{{Version: 1}}, conflictErr.Conflicts)
+	require.Equal(t, types.MetadataVersions{Metadata: 1}, conflictErr.Versions)
+	require.Equal(t, "m1", rm.ToProto().Metadata)
+
+	// unconditional updates increment the version as well, unless metadata is unchanged
+	<-rm.SetMetadata("m2")
+	<-rm.SetMetadata("m2")
+	version, _, err = rm.UpdateMetadata("m3", expected(2))
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), version)
+}
+
+func TestParticipantMetadataVersions(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 1})
+	defer rm.Close(types.ParticipantCloseReasonNone)
+
+	p := rm.GetParticipants()[0].(*typesfakes.FakeLocalParticipant)
+	require.Nil(t, rm.ParticipantMetadataVersions(p.Identity(), nil))
+
+	p.MetadataVersionsReturns(types.MetadataVersions{Metadata: 2, Attributes: map[string]uint64{"slide": 3}})
+	rm.RemoveParticipant(p.Identity(), p.ID(), types.ParticipantCloseReasonClientRequestLeave)
+
+	// a new session starts past the versions of the earlier one, for its token attributes as well
+	require.Equal(t,
+		&types.MetadataVersions{Metadata: 3, Attributes: map[string]uint64{"slide": 4, "role": 1}},
+		rm.ParticipantMetadataVersions(p.Identity(), map[string]string{"role": "speaker"}),
+	)
+
+	snapshot := rm.Snapshot()
+	require.Equal(t, types.MetadataVersions{Metadata: 2, Attributes: map[string]uint64{"slide": 3}}, snapshot.LeftMetadataVersions[p.Identity()])
+}
+
+func TestRoomState(t *testing.T) {
+	setup := func(t *testing.T) (*Room, *typesfakes.FakeLocalParticipant, *typesfakes.FakeLocalParticipant) {
+		rm := newRoomWithParticipants(t, testRoomOpts{})
+		t.Cleanup(func() { rm.Close(types.ParticipantCloseReasonNone) })
+		rm.SetLimits(config.LimitConfig{MaxRoomStateKeys: 3, MaxRoomStateKeyLength: 16})
+
+		presenter := NewMockParticipant("presenter", types.CurrentProtocol, false, false, rm.LocalParticipantListener())
+		presenter.ClaimGrantsReturns(&auth.ClaimGrants{Video: &auth.VideoGrant{RoomAdmin: true}})
+		viewer := NewMockParticipant("viewer", types.CurrentProtocol, false, false, rm.LocalParticipantListener())
+		viewer.ClaimGrantsReturns(&auth.ClaimGrants{Video: &auth.VideoGrant{}})
+		for _, p := range []types.LocalParticipant{presenter, viewer} {
+			require.NoError(t, rm.Join(p, nil, nil, iceServersForRoom))
+		}
+		return rm, presenter, viewer
+	}
+
+	received := func(t *testing.T, p *typesfakes.FakeLocalParticipant, from int) []*RoomStateMessage {
+		var msgs []*RoomStateMessage
+		for i := from; i < p.SendDataMessageCallCount(); i++ {
+			_, data, _, _ := p.SendDataMessageArgsForCall(i)
+			dp := &livekit.DataPacket{}
+			require.NoError(t, proto.Unmarshal(data, dp))
+			require.Equal(t, RoomStateTopic, dp.GetUser().GetTopic())
+			msg := &RoomStateMessage{}
+			require.NoError(t, json.Unmarshal(dp.GetUser().Payload, msg))
+			msgs = append(msgs, msg)
+		}
+		return msgs
+	}
+
+	expected := func(version uint64) *uint64 {
+		return &version
+	}
+
+	request := func(rm *Room, p types.LocalParticipant, id string, updates ...*types.RoomStateUpdate) {
+		rm.LocalParticipantListener().OnRoomStateRequest(p, &types.RoomStateRequest{RequestID: id, Updates: updates})
+	}
+
+	t.Run("updates are broadcast as deltas", func(t *testing.T) {
+		rm, presenter, viewer := setup(t)
+
+		request(rm, presenter, "r1",
+			&types.RoomStateUpdate{Key: "slide", Value: "1", ExpectedVersion: expected(0)},
+			&types.RoomStateUpdate{Key: "presenter", Value: "presenter"},
+		)
+		msgs := received(t, viewer, 0)
+		require.Len(t, msgs, 1)
+		require.Equal(t, RoomStateMessageDelta, msgs[0].Type)
+		require.Equal(t, uint64(1), msgs[0].Version)
+		require.Equal(t, "1", msgs[0].Entries["slide"].Value)
+		require.Equal(t, "presenter", msgs[0].Entries["slide"].UpdatedBy)
+
+		msgs = received(t, presenter, 0)
+		require.Len(t, msgs, 2)
+		require.Equal(t, &RoomStateMessage{Type: RoomStateMessageResult, RequestID: "r1", Version: 1}, msgs[1])
+
+		// the server API updates keys as well, deleting keys which do not exist is a no-op
+		version, err := rm.UpdateRoomState("", []*types.RoomStateUpdate{
+			{Key: "slide", Value: "2", ExpectedVersion: expected(1)},
+			{Key: "presenter", Delete: true},
+			{Key: "missing", Delete: true},
+		})
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), version)
+		msgs = received(t, viewer, 1)
+		require.Len(t, msgs, 1)
+		require.Equal(t, []string{"presenter"}, msgs[0].Deleted)
+		require.Empty(t, msgs[0].Entries["slide"].UpdatedBy)
+
+		version, entries := rm.GetRoomState()
+		require.Equal(t, uint64(2), version)
+		require.Len(t, entries, 1)
+		require.Equal(t, "2", entries["slide"].Value)
+	})
+
+	t.Run("updates are compared and set", func(t *testing.T) {
+		rm, presenter, viewer := setup(t)
+		_, err := rm.UpdateRoomState("", []*types.RoomStateUpdate{{Key: "slide", Value: "1"}})
+		require.NoError(t, err)
+
+		sent := viewer.SendDataMessageCallCount()
+		request(rm, presenter, "r2",
+			&types.RoomStateUpdate{Key: "slide", Value: "2", ExpectedVersion: expected(0)},
+			&types.RoomStateUpdate{Key: "poll", Value: "open"},
+		)
+		msgs := received(t, presenter, 1)
+		require.Len(t, msgs, 1)
+		require.Equal(t, "conflict", msgs[0].Error)
+		require.Equal(t, []*RoomStateConflict{{Key: "slide", Version: 1}}, msgs[0].Conflicts)
+		require.Equal(t, sent, viewer.SendDataMessageCallCount())
+
+		_, err = rm.UpdateRoomState("", []*types.RoomStateUpdate{{Key: "slide", Value: "2", ExpectedVersion: expected(3)}})
+		var conflictErr *RoomStateConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		require.ErrorIs(t, err, ErrRoomStateConflict)
+		require.Equal(t, `room state conflict: key "slide" is at version 1`, err.Error())
+
+		_, entries := rm.GetRoomState()
+		require.Len(t, entries, 1)
+		require.Equal(t, "1", entries["slide"].Value)
+	})
+
+	t.Run("updates are permission gated and limited", func(t *testing.T) {
+		rm, presenter, viewer := setup(t)
+
+		request(rm, viewer, "r3", &types.RoomStateUpdate{Key: "slide", Value: "1"})
+		msgs := received(t, viewer, 0)
+		require.Len(t, msgs, 1)
+		require.Equal(t, "permission_denied", msgs[0].Error)
+
+		// the permission to update metadata allows participants to update the state
+		canUpdate := true
+		viewer.ClaimGrantsReturns(&auth.ClaimGrants{Video: &auth.VideoGrant{CanUpdateOwnMetadata: &canUpdate}})
+		request(rm, viewer, "r3", &types.RoomStateUpdate{Key: "slide", Value: "1"})
+		msgs = received(t, viewer, 1)
+		require.Len(t, msgs, 2)
+		require.Equal(t, RoomStateMessageDelta, msgs[0].Type)
+		require.Equal(t, &RoomStateMessage{Type: RoomStateMessageResult, RequestID: "r3", Version: 1}, msgs[1])
+
+		request(rm, presenter, "r4", &types.RoomStateUpdate{Key: "a_very_long_key_name", Value: "1"})
+		request(rm, presenter, "r5",
+			&types.RoomStateUpdate{Key: "a"}, &types.RoomStateUpdate{Key: "b"},
+			&types.RoomStateUpdate{Key: "c"}, &types.RoomStateUpdate{Key: "d"},
+		)
+		request(rm, presenter, "r6", &types.RoomStateUpdate{Key: "a"}, &types.RoomStateUpdate{Key: "a"})
+		// after the delta of the update by viewer
+		msgs = received(t, presenter, 1)
+		require.Len(t, msgs, 3)
+		require.Equal(t, "limit_exceeded", msgs[0].Error)
+		require.Equal(t, "limit_exceeded", msgs[1].Error)
+		require.Equal(t, "invalid", msgs[2].Error)
+	})
+
+	t.Run("joining participants get a snapshot", func(t *testing.T) {
+		rm, _, _ := setup(t)
+
+		late := NewMockParticipant("late", types.CurrentProtocol, false, false, rm.LocalParticipantListener())
+		require.NoError(t, rm.Join(late, nil, nil, iceServersForRoom))
+		rm.sendRoomStateSnapshot(late)
+		require.Zero(t, late.SendDataMessageCallCount())
+
+		_, err := rm.UpdateRoomState("", []*types.RoomStateUpdate{{Key: "slide", Value: "1"}})
+		require.NoError(t, err)
+
+		// sent once the data channels are open, not when the participant becomes active
+		sent := late.SendDataMessageCallCount()
+		rm.LocalParticipantListener().OnStateChange(late)
+		require.Equal(t, sent, late.SendDataMessageCallCount())
+		rm.LocalParticipantListener().OnFullyEstablished(late)
+		msgs := received(t, late, sent)
+		require.Len(t, msgs, 1)
+		require.Equal(t, RoomStateMessageSnapshot, msgs[0].Type)
+		require.Equal(t, uint64(1), msgs[0].Version)
+		require.Equal(t, "1", msgs[0].Entries["slide"].Value)
+	})
+}
+
+func TestHandOffAgentJob(t *testing.T) {
+	newJob := func(id string, identity string) *livekit.Job {
+		return &livekit.Job{
+			Id:         id,
+			DispatchId: "AD_1",
+			Type:       livekit.JobType_JT_ROOM,
+			State:      &livekit.JobState{ParticipantIdentity: identity},
+		}
+	}
+	setup := func(t *testing.T) *Room {
+		rm := newRoomWithParticipants(t, testRoomOpts{num: 1})
+		rm.restoreAgentDispatches([]*livekit.AgentDispatch{{
+			Id:    "AD_1",
+			State: &livekit.AgentDispatchState{Jobs: []*livekit.Job{newJob("AJ_1", "agent-1")}},
+		}})
+		return rm
+	}
+	jobIDs := func(rm *Room) []string {
+		dispatches, err := rm.GetAgentDispatches("AD_1")
+		require.NoError(t, err)
+		var ids []string
+		for _, j := range dispatches[0].State.Jobs {
+			ids = append(ids, j.Id)
+		}
+		return ids
+	}
+
+	t.Run("replacement takes the place of the job once its agent joined", func(t *testing.T) {
+		rm := setup(t)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- rm.HandOffAgentJob(context.Background(), "AJ_1", newJob("AJ_2", "agent-2"))
+		}()
+		require.Eventually(t, func() bool {
+			return slices.Contains(jobIDs(rm), "AJ_2")
+		}, time.Second, 10*time.Millisecond)
+		require.Empty(t, done)
+
+		agent := NewMockParticipant("agent-2", types.CurrentProtocol, false, false, rm.LocalParticipantListener())
+		require.NoError(t, rm.Join(agent, nil, nil, iceServersForRoom))
+
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			require.Fail(t, "handoff did not complete")
+		}
+		require.Equal(t, []string{"AJ_2"}, jobIDs(rm))
+
+		rm.lock.RLock()
+		require.NotNil(t, rm.agentParticpants["agent-2"])
+		rm.lock.RUnlock()
+	})
+
+	t.Run("replacement is dropped when its agent does not join", func(t *testing.T) {
+		rm := setup(t)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, rm.HandOffAgentJob(ctx, "AJ_1", newJob("AJ_2", "agent-2")), ErrJobHandoffTimeout)
+		require.Equal(t, []string{"AJ_1"}, jobIDs(rm))
+
+		rm.lock.RLock()
+		require.Nil(t, rm.agentParticpants["agent-2"])
+		rm.lock.RUnlock()
+	})
+
+	t.Run("unknown job", func(t *testing.T) {
+		rm := setup(t)
+
+		require.ErrorIs(t, rm.HandOffAgentJob(context.Background(), "AJ_3", newJob("AJ_2", "agent-2")), ErrAgentJobNotFound)
+		require.Equal(t, []string{"AJ_1"}, jobIDs(rm))
+	})
+}
+
 type testRoomOpts struct {
 	num                  int
 	numHidden            int
@@ -842,7 +1797,7 @@ func newRoomWithParticipants(t *testing.T, opts testRoomOpts) *Room {
 			Region:   "testregion",
 		},
 		telemetry.NewTelemetryService(n, &telemetryfakes.FakeAnalyticsService{}),
-		nil, nil, nil,
+		nil, nil, nil, nil,
 	)
 	for i := 0; i < opts.num+opts.numHidden; i++ {
 		identity := livekit.ParticipantIdentity(fmt.Sprintf("p%d", i))
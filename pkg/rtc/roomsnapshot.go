@@ -0,0 +1,338 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"encoding/json"
+	"time"
+
+	"golang.org/x/exp/maps"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// RoomSnapshot is the state of a room needed to let participants resume their sessions
+// after the server hosting the room has been restarted
+type RoomSnapshot struct {
+	Room            *livekit.Room
+	Internal        *livekit.RoomInternal
+	AgentDispatches []*livekit.AgentDispatch
+	Participants    []*ParticipantSnapshot
+	// reliable data messages, replayed to participants missing them when resuming
+	DataMessages []*types.DataMessageCache
+	// whether joining participants wait in the lobby, room admins may have changed it from the config
+	LobbyEnabled bool
+	// subscription policy set by room admins
+	SubscriptionPolicy *SubscriptionPolicy
+	// subscription limits set by room admins, for the room and by participant identity
+	SubscriptionLimits            *SubscriptionLimits
+	ParticipantSubscriptionLimits map[livekit.ParticipantIdentity]*SubscriptionLimits
+	// data policy set by room admins
+	DataPolicy *DataPolicy
+	// data retention set by room admins, history itself is kept in the store
+	DataRetention *DataRetention
+	// shared state of the room
+	StateVersion uint64
+	State        map[string]*types.RoomStateEntry
+	// version of the room metadata
+	MetadataVersion uint64
+	// versions of metadata and attributes of participants which left, by identity
+	LeftMetadataVersions map[livekit.ParticipantIdentity]types.MetadataVersions
+	CreatedAt            time.Time
+}
+
+type ParticipantSnapshot struct {
+	Info *livekit.ParticipantInfo
+	// last sequence of reliable data published by the participant
+	LastPubReliableSeq uint32
+	// state of forwarders of subscribed tracks, keeps RTP streams continuous for the subscriber after resume
+	ForwarderStates map[livekit.TrackID]*livekit.RTPForwarderState
+	// versions of metadata and attributes, updates expecting them keep working after resume
+	MetadataVersions *types.MetadataVersions
+}
+
+func (p *ParticipantSnapshot) GetLastPubReliableSeq() uint32 {
+	if p == nil {
+		return 0
+	}
+	return p.LastPubReliableSeq
+}
+
+func (p *ParticipantSnapshot) GetForwarderStates() map[livekit.TrackID]*livekit.RTPForwarderState {
+	if p == nil {
+		return nil
+	}
+	return p.ForwarderStates
+}
+
+func (p *ParticipantSnapshot) GetMetadataVersions() *types.MetadataVersions {
+	if p == nil {
+		return nil
+	}
+	return p.MetadataVersions
+}
+
+// Snapshot captures room state, participants stop forwarding to their subscribed tracks as their forwarder state is captured.
+// Participants not connected yet are skipped, they would not be able to resume. Agents are skipped as well,
+// their jobs do not survive the restart and are launched again when the room is restored.
+func (r *Room) Snapshot() *RoomSnapshot {
+	snapshot := &RoomSnapshot{
+		Room:      r.ToProto(),
+		CreatedAt: time.Now(),
+	}
+
+	r.lock.RLock()
+	if r.internal != nil {
+		snapshot.Internal = utils.CloneProto(r.internal)
+	}
+	for _, ad := range r.agentDispatches {
+		dispatch := utils.CloneProto(ad.AgentDispatch)
+		if dispatch.State != nil {
+			dispatch.State.Jobs = nil
+		}
+		snapshot.AgentDispatches = append(snapshot.AgentDispatches, dispatch)
+	}
+	snapshot.LobbyEnabled = r.lobbyEnabled
+	if r.subscriptionPolicy != nil {
+		snapshot.SubscriptionPolicy = r.subscriptionPolicy.policy
+	}
+	snapshot.SubscriptionLimits = r.subscriptionLimits
+	if len(r.participantSubscriptionLimits) != 0 {
+		snapshot.ParticipantSubscriptionLimits = maps.Clone(r.participantSubscriptionLimits)
+	}
+	if r.dataPolicy != nil {
+		snapshot.DataPolicy = r.dataPolicy.policy
+	}
+	snapshot.DataRetention = r.dataRetention
+	snapshot.MetadataVersion = r.metadataVersion
+	if len(r.leftMetadataVersions) != 0 {
+		snapshot.LeftMetadataVersions = maps.Clone(r.leftMetadataVersions)
+	}
+	participants := maps.Values(r.participants)
+	r.lock.RUnlock()
+
+	for _, p := range participants {
+		if p.State() != livekit.ParticipantInfo_ACTIVE || p.IsAgent() {
+			continue
+		}
+
+		versions := p.MetadataVersions()
+		snapshot.Participants = append(snapshot.Participants, &ParticipantSnapshot{
+			Info:               p.ToProto(),
+			LastPubReliableSeq: p.GetLastReliableSequence(true),
+			ForwarderStates:    p.StopAndGetSubscribedTracksForwarderState(),
+			MetadataVersions:   &versions,
+		})
+	}
+
+	snapshot.DataMessages = r.dataMessageCache.Get()
+	snapshot.StateVersion, snapshot.State = r.GetRoomState()
+	return snapshot
+}
+
+// RestoreSnapshot applies state of a snapshot to a room created with the snapshot's agent dispatches,
+// reliable data messages are cached for replay and agent jobs lost with the restart are launched again.
+func (r *Room) RestoreSnapshot(snapshot *RoomSnapshot) {
+	for _, msg := range snapshot.DataMessages {
+		r.dataMessageCache.Add(msg, len(msg.Data))
+	}
+	if snapshot.SubscriptionPolicy != nil {
+		if err := r.SetSubscriptionPolicy(snapshot.SubscriptionPolicy); err != nil {
+			r.logger.Warnw("could not restore subscription policy", err)
+		}
+	}
+	if snapshot.DataPolicy != nil {
+		if err := r.SetDataPolicy(snapshot.DataPolicy); err != nil {
+			r.logger.Warnw("could not restore data policy", err)
+		}
+	}
+	if snapshot.DataRetention != nil {
+		if err := r.SetDataRetention(snapshot.DataRetention); err != nil {
+			r.logger.Warnw("could not restore data retention", err)
+		}
+	}
+
+	r.restoreRoomState(snapshot.StateVersion, snapshot.State)
+
+	r.lock.Lock()
+	r.lobbyEnabled = snapshot.LobbyEnabled
+	r.metadataVersion = snapshot.MetadataVersion
+	for identity, versions := range snapshot.LeftMetadataVersions {
+		r.leftMetadataVersions[identity] = versions
+	}
+	r.subscriptionLimits = snapshot.SubscriptionLimits
+	for identity, limits := range snapshot.ParticipantSubscriptionLimits {
+		r.participantSubscriptionLimits[identity] = limits
+	}
+	ads := maps.Values(r.agentDispatches)
+	r.lock.Unlock()
+
+	r.launchRoomAgents(ads)
+}
+
+// ---------------------------------------
+
+type participantSnapshotJSON struct {
+	Info               []byte            `json:"info"`
+	LastPubReliableSeq uint32            `json:"last_pub_reliable_seq,omitempty"`
+	ForwarderStates    map[string][]byte `json:"forwarder_states,omitempty"`
+
+	MetadataVersions *types.MetadataVersions `json:"metadata_versions,omitempty"`
+}
+
+type roomSnapshotJSON struct {
+	Room               []byte                    `json:"room"`
+	Internal           []byte                    `json:"internal,omitempty"`
+	AgentDispatches    [][]byte                  `json:"agent_dispatches,omitempty"`
+	Participants       []participantSnapshotJSON `json:"participants,omitempty"`
+	DataMessages       []*types.DataMessageCache `json:"data_messages,omitempty"`
+	LobbyEnabled       bool                      `json:"lobby_enabled,omitempty"`
+	SubscriptionPolicy *SubscriptionPolicy       `json:"subscription_policy,omitempty"`
+
+	SubscriptionLimits            *SubscriptionLimits                                 `json:"subscription_limits,omitempty"`
+	ParticipantSubscriptionLimits map[livekit.ParticipantIdentity]*SubscriptionLimits `json:"participant_subscription_limits,omitempty"`
+
+	DataPolicy    *DataPolicy    `json:"data_policy,omitempty"`
+	DataRetention *DataRetention `json:"data_retention,omitempty"`
+
+	StateVersion uint64                           `json:"state_version,omitempty"`
+	State        map[string]*types.RoomStateEntry `json:"state,omitempty"`
+
+	MetadataVersion      uint64                                                 `json:"metadata_version,omitempty"`
+	LeftMetadataVersions map[livekit.ParticipantIdentity]types.MetadataVersions `json:"left_metadata_versions,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (s *RoomSnapshot) Marshal() ([]byte, error) {
+	var (
+		sj  roomSnapshotJSON
+		err error
+	)
+	if sj.Room, err = proto.Marshal(s.Room); err != nil {
+		return nil, err
+	}
+	if s.Internal != nil {
+		if sj.Internal, err = proto.Marshal(s.Internal); err != nil {
+			return nil, err
+		}
+	}
+	for _, ad := range s.AgentDispatches {
+		b, err := proto.Marshal(ad)
+		if err != nil {
+			return nil, err
+		}
+		sj.AgentDispatches = append(sj.AgentDispatches, b)
+	}
+	for _, ps := range s.Participants {
+		pj := participantSnapshotJSON{
+			LastPubReliableSeq: ps.LastPubReliableSeq,
+			ForwarderStates:    make(map[string][]byte, len(ps.ForwarderStates)),
+			MetadataVersions:   ps.MetadataVersions,
+		}
+		if pj.Info, err = proto.Marshal(ps.Info); err != nil {
+			return nil, err
+		}
+		for trackID, fs := range ps.ForwarderStates {
+			b, err := proto.Marshal(fs)
+			if err != nil {
+				return nil, err
+			}
+			pj.ForwarderStates[string(trackID)] = b
+		}
+		sj.Participants = append(sj.Participants, pj)
+	}
+	sj.DataMessages = s.DataMessages
+	sj.LobbyEnabled = s.LobbyEnabled
+	sj.SubscriptionPolicy = s.SubscriptionPolicy
+	sj.SubscriptionLimits = s.SubscriptionLimits
+	sj.ParticipantSubscriptionLimits = s.ParticipantSubscriptionLimits
+	sj.DataPolicy = s.DataPolicy
+	sj.DataRetention = s.DataRetention
+	sj.StateVersion = s.StateVersion
+	sj.State = s.State
+	sj.MetadataVersion = s.MetadataVersion
+	sj.LeftMetadataVersions = s.LeftMetadataVersions
+	sj.CreatedAt = s.CreatedAt
+
+	return json.Marshal(&sj)
+}
+
+func UnmarshalRoomSnapshot(data []byte) (*RoomSnapshot, error) {
+	var sj roomSnapshotJSON
+	if err := json.Unmarshal(data, &sj); err != nil {
+		return nil, err
+	}
+
+	s := &RoomSnapshot{
+		Room:               &livekit.Room{},
+		DataMessages:       sj.DataMessages,
+		LobbyEnabled:       sj.LobbyEnabled,
+		SubscriptionPolicy: sj.SubscriptionPolicy,
+		CreatedAt:          sj.CreatedAt,
+
+		SubscriptionLimits:            sj.SubscriptionLimits,
+		ParticipantSubscriptionLimits: sj.ParticipantSubscriptionLimits,
+
+		DataPolicy:    sj.DataPolicy,
+		DataRetention: sj.DataRetention,
+
+		StateVersion: sj.StateVersion,
+		State:        sj.State,
+
+		MetadataVersion:      sj.MetadataVersion,
+		LeftMetadataVersions: sj.LeftMetadataVersions,
+	}
+	if err := proto.Unmarshal(sj.Room, s.Room); err != nil {
+		return nil, err
+	}
+	if len(sj.Internal) != 0 {
+		s.Internal = &livekit.RoomInternal{}
+		if err := proto.Unmarshal(sj.Internal, s.Internal); err != nil {
+			return nil, err
+		}
+	}
+	for _, b := range sj.AgentDispatches {
+		ad := &livekit.AgentDispatch{}
+		if err := proto.Unmarshal(b, ad); err != nil {
+			return nil, err
+		}
+		s.AgentDispatches = append(s.AgentDispatches, ad)
+	}
+	for _, pj := range sj.Participants {
+		ps := &ParticipantSnapshot{
+			Info:               &livekit.ParticipantInfo{},
+			LastPubReliableSeq: pj.LastPubReliableSeq,
+			ForwarderStates:    make(map[livekit.TrackID]*livekit.RTPForwarderState, len(pj.ForwarderStates)),
+			MetadataVersions:   pj.MetadataVersions,
+		}
+		if err := proto.Unmarshal(pj.Info, ps.Info); err != nil {
+			return nil, err
+		}
+		for trackID, b := range pj.ForwarderStates {
+			fs := &livekit.RTPForwarderState{}
+			if err := proto.Unmarshal(b, fs); err != nil {
+				return nil, err
+			}
+			ps.ForwarderStates[livekit.TrackID(trackID)] = fs
+		}
+		s.Participants = append(s.Participants, ps)
+	}
+	return s, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+func TestRoomSnapshotMarshal(t *testing.T) {
+	snapshot := &RoomSnapshot{
+		Room:            &livekit.Room{Sid: "RM_room", Name: "room", Metadata: "meta"},
+		MetadataVersion: 2,
+		LeftMetadataVersions: map[livekit.ParticipantIdentity]types.MetadataVersions{
+			"p2": {Metadata: 1},
+		},
+		Internal: &livekit.RoomInternal{SyncStreams: true},
+		AgentDispatches: []*livekit.AgentDispatch{
+			{Id: "AD_1", AgentName: "agent", Room: "room"},
+		},
+		Participants: []*ParticipantSnapshot{
+			{
+				Info:               &livekit.ParticipantInfo{Sid: "PA_1", Identity: "p1"},
+				LastPubReliableSeq: 5,
+				ForwarderStates: map[livekit.TrackID]*livekit.RTPForwarderState{
+					"TR_1": {Started: true, PreStartTime: 10},
+				},
+				MetadataVersions: &types.MetadataVersions{Metadata: 2, Attributes: map[string]uint64{"slide": 3}},
+			},
+		},
+		DataMessages: []*types.DataMessageCache{
+			{SenderID: "PA_1", Seq: 4, Data: []byte("data"), DestIdentities: []livekit.ParticipantIdentity{"p2"}},
+		},
+		LobbyEnabled: true,
+		SubscriptionPolicy: &SubscriptionPolicy{
+			Rules: []*SubscriptionPolicyRule{{Publishers: "group == stage", Kinds: []string{"audio"}, Subscribe: true}},
+		},
+		SubscriptionLimits: &SubscriptionLimits{MaxBitrate: 10_000_000},
+		ParticipantSubscriptionLimits: map[livekit.ParticipantIdentity]*SubscriptionLimits{
+			"p1": {MaxBitrate: 1_500_000, MaxVideoQuality: "medium"},
+		},
+		DataPolicy:    &DataPolicy{Script: `action = "drop"`, Topics: []string{"chat"}},
+		DataRetention: &DataRetention{Topics: []*DataRetentionTopic{{Topic: "chat", MaxMessages: 50}}},
+		StateVersion:  3,
+		State:         map[string]*types.RoomStateEntry{"slide": {Value: "4", Version: 3, UpdatedBy: "p1", UpdatedAt: 1000}},
+		CreatedAt:     time.Now().Truncate(time.Millisecond),
+	}
+
+	data, err := snapshot.Marshal()
+	require.NoError(t, err)
+
+	restored, err := UnmarshalRoomSnapshot(data)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(snapshot.Room, restored.Room))
+	require.Equal(t, snapshot.MetadataVersion, restored.MetadataVersion)
+	require.Equal(t, snapshot.LeftMetadataVersions, restored.LeftMetadataVersions)
+	require.True(t, restored.Internal.SyncStreams)
+	require.Len(t, restored.AgentDispatches, 1)
+	require.Equal(t, "AD_1", restored.AgentDispatches[0].Id)
+	require.Len(t, restored.Participants, 1)
+	require.Equal(t, "p1", restored.Participants[0].Info.Identity)
+	require.Equal(t, uint32(5), restored.Participants[0].LastPubReliableSeq)
+	require.Equal(t, int64(10), restored.Participants[0].ForwarderStates["TR_1"].PreStartTime)
+	require.Equal(t, snapshot.Participants[0].MetadataVersions, restored.Participants[0].GetMetadataVersions())
+	require.Equal(t, snapshot.DataMessages, restored.DataMessages)
+	require.True(t, restored.LobbyEnabled)
+	require.Equal(t, snapshot.SubscriptionPolicy, restored.SubscriptionPolicy)
+	require.Equal(t, snapshot.SubscriptionLimits, restored.SubscriptionLimits)
+	require.Equal(t, snapshot.ParticipantSubscriptionLimits, restored.ParticipantSubscriptionLimits)
+	require.Equal(t, snapshot.DataPolicy, restored.DataPolicy)
+	require.Equal(t, snapshot.DataRetention, restored.DataRetention)
+	require.Equal(t, snapshot.StateVersion, restored.StateVersion)
+	require.Equal(t, snapshot.State, restored.State)
+	require.True(t, snapshot.CreatedAt.Equal(restored.CreatedAt))
+
+	var nilSnapshot *ParticipantSnapshot
+	require.Zero(t, nilSnapshot.GetLastPubReliableSeq())
+	require.Nil(t, nilSnapshot.GetForwarderStates())
+	require.Nil(t, nilSnapshot.GetMetadataVersions())
+}
@@ -131,7 +131,7 @@ func (s *signalhandler) HandleMessage(msg proto.Message) error {
 		}
 
 	case *livekit.SignalRequest_UpdateMetadata:
-		s.params.Participant.UpdateMetadata(msg.UpdateMetadata, false)
+		s.params.Participant.UpdateMetadata(msg.UpdateMetadata, false, nil)
 
 	case *livekit.SignalRequest_UpdateAudioTrack:
 		if err := s.params.Participant.UpdateAudioTrack(msg.UpdateAudioTrack); err != nil {
@@ -149,6 +149,7 @@ func NewSubscribedTrack(params SubscribedTrackParams) (*SubscribedTrack, error)
 		RTCPWriter:                     params.Subscriber.WriteSubscriberRTCP,
 		DisableSenderReportPassThrough: params.Subscriber.GetDisableSenderReportPassThrough(),
 		SupportsCodecChange:            params.Subscriber.SupportsCodecChange(),
+		FECConfig:                      params.SubscriberConfig.FEC,
 		Listener:                       s,
 	})
 	if err != nil {
@@ -343,6 +344,9 @@ func (t *SubscribedTrack) applySettings() {
 		if t.settings.Width > 0 {
 			quality = mt.GetQualityForDimension(mimeType, t.settings.Width, t.settings.Height)
 		}
+		if maxQuality := t.params.Subscriber.GetSubscriberMaxVideoQuality(); quality != livekit.VideoQuality_OFF && quality > maxQuality {
+			quality = maxQuality
+		}
 
 		spatial = buffer.GetSpatialLayerForVideoQuality(mimeType, quality, mt.ToProto())
 		if t.settings.Fps > 0 {
@@ -0,0 +1,309 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+)
+
+// SubscriptionRulePrefix marks a TrackPermission whose participant identity is a rule over subscribers
+// instead of an identity, e.g. "lk.rule:role in [moderator, interpreter]" or "lk.rule:kind == AGENT".
+//
+// A rule is one or more conditions joined by "&&", all of which must hold. A condition compares a field
+// of the participant with "==", "!=", "in [...]" or "not in [...]". Fields are
+//   - kind: participant kind, e.g. STANDARD, AGENT, SIP
+//   - identity: participant identity
+//   - attributes.<key>, or just <key>: participant attribute, empty when not set
+//   - claims.<grant>: video grant of the participant, "true" or "false", one of room_admin, can_publish,
+//     can_subscribe, can_publish_data, can_update_own_metadata, hidden, recorder, agent
+//
+// Values can be quoted with double quotes when they contain spaces, operators or separators.
+const SubscriptionRulePrefix = "lk.rule:"
+
+var (
+	ErrInvalidSubscriptionRule = errors.New("invalid subscription rule")
+)
+
+type subscriptionRuleOp int
+
+const (
+	subscriptionRuleOpEqual subscriptionRuleOp = iota
+	subscriptionRuleOpNotEqual
+	subscriptionRuleOpIn
+	subscriptionRuleOpNotIn
+)
+
+type subscriptionRuleCondition struct {
+	field  string
+	op     subscriptionRuleOp
+	values []string
+}
+
+// SubscriptionRuleSubject is a participant a SubscriptionRule is evaluated against
+type SubscriptionRuleSubject interface {
+	Identity() livekit.ParticipantIdentity
+	Kind() livekit.ParticipantInfo_Kind
+	ClaimGrants() *auth.ClaimGrants
+}
+
+type SubscriptionRule struct {
+	expr       string
+	conditions []subscriptionRuleCondition
+}
+
+var subscriptionRuleClaims = map[string]func(v *auth.VideoGrant) bool{
+	"room_admin":              func(v *auth.VideoGrant) bool { return v.RoomAdmin },
+	"can_publish":             func(v *auth.VideoGrant) bool { return v.GetCanPublish() },
+	"can_subscribe":           func(v *auth.VideoGrant) bool { return v.GetCanSubscribe() },
+	"can_publish_data":        func(v *auth.VideoGrant) bool { return v.GetCanPublishData() },
+	"can_update_own_metadata": func(v *auth.VideoGrant) bool { return v.GetCanUpdateOwnMetadata() },
+	"hidden":                  func(v *auth.VideoGrant) bool { return v.Hidden },
+	"recorder":                func(v *auth.VideoGrant) bool { return v.Recorder },
+	"agent":                   func(v *auth.VideoGrant) bool { return v.Agent },
+}
+
+func IsSubscriptionRule(identity string) bool {
+	return strings.HasPrefix(identity, SubscriptionRulePrefix)
+}
+
+func ParseSubscriptionRule(identity string) (*SubscriptionRule, error) {
+	expr := strings.TrimSpace(strings.TrimPrefix(identity, SubscriptionRulePrefix))
+	if expr == "" {
+		return nil, fmt.Errorf("%w: empty rule", ErrInvalidSubscriptionRule)
+	}
+
+	rule := &SubscriptionRule{expr: expr}
+	for _, part := range splitOutsideQuotes(expr, "&&") {
+		cond, err := parseSubscriptionRuleCondition(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalidSubscriptionRule, expr, err)
+		}
+		rule.conditions = append(rule.conditions, cond)
+	}
+	return rule, nil
+}
+
+func (r *SubscriptionRule) String() string {
+	return r.expr
+}
+
+// Matches returns true when the participant satisfies all conditions of the rule
+func (r *SubscriptionRule) Matches(p SubscriptionRuleSubject) bool {
+	grants := p.ClaimGrants()
+	for _, cond := range r.conditions {
+		value := subscriptionRuleFieldValue(cond.field, p, grants)
+		var matched bool
+		switch cond.op {
+		case subscriptionRuleOpEqual, subscriptionRuleOpIn:
+			matched = slices.Contains(cond.values, value)
+		case subscriptionRuleOpNotEqual, subscriptionRuleOpNotIn:
+			matched = !slices.Contains(cond.values, value)
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// subscriptionRuleInputs are the values of a participant rules are evaluated against which can change
+// while it is in the room, kind and identity do not
+type subscriptionRuleInputs struct {
+	attributes map[string]string
+	claims     map[string]bool
+}
+
+func newSubscriptionRuleInputs(p SubscriptionRuleSubject) subscriptionRuleInputs {
+	inputs := subscriptionRuleInputs{claims: make(map[string]bool, len(subscriptionRuleClaims))}
+	if grants := p.ClaimGrants(); grants != nil {
+		inputs.attributes = maps.Clone(grants.Attributes)
+		if grants.Video != nil {
+			for name, claim := range subscriptionRuleClaims {
+				inputs.claims[name] = claim(grants.Video)
+			}
+		}
+	}
+	return inputs
+}
+
+func (s subscriptionRuleInputs) equal(o subscriptionRuleInputs) bool {
+	return maps.Equal(s.attributes, o.attributes) && maps.Equal(s.claims, o.claims)
+}
+
+func subscriptionRuleFieldValue(field string, p SubscriptionRuleSubject, grants *auth.ClaimGrants) string {
+	switch {
+	case field == "kind":
+		return p.Kind().String()
+	case field == "identity":
+		return string(p.Identity())
+	case strings.HasPrefix(field, "claims."):
+		if grants == nil || grants.Video == nil {
+			return strconv.FormatBool(false)
+		}
+		return strconv.FormatBool(subscriptionRuleClaims[strings.TrimPrefix(field, "claims.")](grants.Video))
+	default:
+		if grants == nil {
+			return ""
+		}
+		return grants.Attributes[strings.TrimPrefix(field, "attributes.")]
+	}
+}
+
+func parseSubscriptionRuleCondition(s string) (subscriptionRuleCondition, error) {
+	var cond subscriptionRuleCondition
+
+	tokens, err := tokenizeSubscriptionRuleCondition(s)
+	if err != nil {
+		return cond, err
+	}
+
+	if len(tokens) == 0 || tokens[0].kind != ruleTokenWord {
+		return cond, fmt.Errorf("invalid field in %q", s)
+	}
+	cond.field = tokens[0].text
+	if claim, ok := strings.CutPrefix(cond.field, "claims."); ok && subscriptionRuleClaims[claim] == nil {
+		return cond, fmt.Errorf("unknown claim %q", claim)
+	}
+
+	rest := tokens[1:]
+	switch {
+	case len(rest) > 0 && rest[0].is(ruleTokenOp, "=="):
+		cond.op, rest = subscriptionRuleOpEqual, rest[1:]
+	case len(rest) > 0 && rest[0].is(ruleTokenOp, "!="):
+		cond.op, rest = subscriptionRuleOpNotEqual, rest[1:]
+	case len(rest) > 0 && rest[0].is(ruleTokenWord, "in"):
+		cond.op, rest = subscriptionRuleOpIn, rest[1:]
+	case len(rest) > 1 && rest[0].is(ruleTokenWord, "not") && rest[1].is(ruleTokenWord, "in"):
+		cond.op, rest = subscriptionRuleOpNotIn, rest[2:]
+	default:
+		return cond, fmt.Errorf("missing operator in %q", s)
+	}
+
+	if cond.op == subscriptionRuleOpIn || cond.op == subscriptionRuleOpNotIn {
+		if len(rest) < 2 || !rest[0].is(ruleTokenPunct, "[") || !rest[len(rest)-1].is(ruleTokenPunct, "]") {
+			return cond, fmt.Errorf("expected list in %q", s)
+		}
+		items := rest[1 : len(rest)-1]
+		for i, t := range items {
+			if i%2 == 1 {
+				if !t.is(ruleTokenPunct, ",") || i == len(items)-1 {
+					return cond, fmt.Errorf("invalid list in %q", s)
+				}
+				continue
+			}
+			if !t.isValue() {
+				return cond, fmt.Errorf("invalid list in %q", s)
+			}
+			cond.values = append(cond.values, t.text)
+		}
+	} else {
+		if len(rest) != 1 || !rest[0].isValue() {
+			return cond, fmt.Errorf("expected a single value in %q", s)
+		}
+		cond.values = []string{rest[0].text}
+	}
+
+	if cond.field == "kind" {
+		for i, v := range cond.values {
+			kind, ok := livekit.ParticipantInfo_Kind_value[strings.ToUpper(v)]
+			if !ok {
+				return cond, fmt.Errorf("unknown participant kind %q", v)
+			}
+			cond.values[i] = livekit.ParticipantInfo_Kind(kind).String()
+		}
+	}
+	return cond, nil
+}
+
+type ruleTokenKind int
+
+const (
+	ruleTokenWord ruleTokenKind = iota
+	ruleTokenQuoted
+	ruleTokenOp
+	ruleTokenPunct
+)
+
+type ruleToken struct {
+	kind ruleTokenKind
+	text string
+}
+
+func (t ruleToken) is(kind ruleTokenKind, text string) bool {
+	return t.kind == kind && t.text == text
+}
+
+func (t ruleToken) isValue() bool {
+	return t.kind == ruleTokenWord || t.kind == ruleTokenQuoted
+}
+
+// tokenizeSubscriptionRuleCondition splits a condition into words, quoted values, operators and list punctuation,
+// so operators and separators inside quoted values are taken as part of the value
+func tokenizeSubscriptionRuleCondition(s string) ([]ruleToken, error) {
+	var tokens []ruleToken
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"':
+			quoted, err := strconv.QuotedPrefix(s[i:])
+			if err != nil {
+				return nil, fmt.Errorf("unterminated quote in %q", s)
+			}
+			text, _ := strconv.Unquote(quoted)
+			tokens = append(tokens, ruleToken{ruleTokenQuoted, text})
+			i += len(quoted)
+		case strings.HasPrefix(s[i:], "==") || strings.HasPrefix(s[i:], "!="):
+			tokens = append(tokens, ruleToken{ruleTokenOp, s[i : i+2]})
+			i += 2
+		case c == '[' || c == ']' || c == ',':
+			tokens = append(tokens, ruleToken{ruleTokenPunct, s[i : i+1]})
+			i++
+		default:
+			start := i
+			for i < len(s) && !strings.ContainsRune(" \t\"[],", rune(s[i])) &&
+				!strings.HasPrefix(s[i:], "==") && !strings.HasPrefix(s[i:], "!=") {
+				i++
+			}
+			tokens = append(tokens, ruleToken{ruleTokenWord, s[start:i]})
+		}
+	}
+	return tokens, nil
+}
+
+func splitOutsideQuotes(s string, sep string) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '"' && (i == 0 || s[i-1] != '\\'):
+			inQuotes = !inQuotes
+		case !inQuotes && strings.HasPrefix(s[i:], sep):
+			parts = append(parts, s[start:i])
+			start = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	return append(parts, s[start:])
+}
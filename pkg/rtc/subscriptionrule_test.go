@@ -0,0 +1,87 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+)
+
+func newRuleSubscriber(identity string, kind livekit.ParticipantInfo_Kind, attributes map[string]string, video *auth.VideoGrant) *typesfakes.FakeLocalParticipant {
+	sub := &typesfakes.FakeLocalParticipant{}
+	sub.IdentityReturns(livekit.ParticipantIdentity(identity))
+	sub.KindReturns(kind)
+	sub.ClaimGrantsReturns(&auth.ClaimGrants{Attributes: attributes, Video: video})
+	return sub
+}
+
+func TestSubscriptionRule(t *testing.T) {
+	moderator := newRuleSubscriber("mod", livekit.ParticipantInfo_STANDARD, map[string]string{"role": "moderator", "lang": "en us", "expr": "a==b"}, &auth.VideoGrant{RoomAdmin: true})
+	interpreter := newRuleSubscriber("int", livekit.ParticipantInfo_STANDARD, map[string]string{"role": "interpreter"}, &auth.VideoGrant{})
+	agent := newRuleSubscriber("agent", livekit.ParticipantInfo_AGENT, nil, nil)
+
+	testCases := []struct {
+		rule    string
+		matches []bool // moderator, interpreter, agent
+	}{
+		{"lk.rule:role in [moderator, interpreter]", []bool{true, true, false}},
+		{"lk.rule:attributes.role == moderator", []bool{true, false, false}},
+		{"lk.rule:role not in [moderator]", []bool{false, true, true}},
+		{"lk.rule:kind == agent", []bool{false, false, true}},
+		{"lk.rule:kind != AGENT && role != interpreter", []bool{true, false, false}},
+		{"lk.rule:claims.room_admin == true", []bool{true, false, false}},
+		{`lk.rule:lang == "en us"`, []bool{true, false, false}},
+		{"lk.rule:identity in [int, agent]", []bool{false, true, true}},
+		{`lk.rule:expr == "a==b"`, []bool{true, false, false}},
+		{`lk.rule:expr != "a!=b"`, []bool{true, true, true}},
+		{`lk.rule:expr in ["x != y", "a==b"]`, []bool{true, false, false}},
+		{`lk.rule:role not in ["a, b", "]", interpreter]`, []bool{true, false, true}},
+		{`lk.rule:role in []`, []bool{false, false, false}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.rule, func(t *testing.T) {
+			rule, err := ParseSubscriptionRule(tc.rule)
+			require.NoError(t, err)
+			require.Equal(t, tc.matches[0], rule.Matches(moderator))
+			require.Equal(t, tc.matches[1], rule.Matches(interpreter))
+			require.Equal(t, tc.matches[2], rule.Matches(agent))
+		})
+	}
+
+	t.Run("invalid rules", func(t *testing.T) {
+		for _, expr := range []string{
+			"lk.rule:",
+			"lk.rule:role",
+			"lk.rule:role in moderator",
+			"lk.rule:kind == robot",
+			"lk.rule:claims.superuser == true",
+			"lk.rule:== moderator",
+			`lk.rule:"role" == moderator`,
+			"lk.rule:role == moderator interpreter",
+			`lk.rule:role == "moderator`,
+			"lk.rule:role in [moderator,]",
+			"lk.rule:role in [moderator interpreter]",
+		} {
+			_, err := ParseSubscriptionRule(expr)
+			require.ErrorIs(t, err, ErrInvalidSubscriptionRule, expr)
+		}
+	})
+}
@@ -45,6 +45,7 @@ import (
 	"github.com/livekit/livekit-server/pkg/rtc/types"
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
 	"github.com/livekit/livekit-server/pkg/sfu/bwe"
+	"github.com/livekit/livekit-server/pkg/sfu/bwe/delaybasedbwe"
 	"github.com/livekit/livekit-server/pkg/sfu/bwe/remotebwe"
 	"github.com/livekit/livekit-server/pkg/sfu/bwe/sendsidebwe"
 	"github.com/livekit/livekit-server/pkg/sfu/datachannel"
@@ -421,7 +422,7 @@ func newPeerConnection(
 
 	ir := &interceptor.Registry{}
 	if params.IsSendSide {
-		if params.CongestionControlConfig.UseSendSideBWEInterceptor && !params.CongestionControlConfig.UseSendSideBWE {
+		if params.CongestionControlConfig.UseSendSideBWEInterceptor && !params.CongestionControlConfig.UseSendSideBWE && !params.CongestionControlConfig.UseDelayBasedBWE {
 			params.Logger.Infow("using send side BWE - interceptor")
 			gf, err := cc.NewInterceptor(func() (cc.BandwidthEstimator, error) {
 				return gcc.NewSendSideBWE(
@@ -537,17 +538,27 @@ func NewPCTransport(params TransportParams) (*PCTransport, error) {
 	}
 
 	if params.IsSendSide {
-		if params.CongestionControlConfig.UseSendSideBWE {
-			params.Logger.Infow("using send side BWE", "pacerBehavior", params.CongestionControlConfig.SendSideBWEPacer)
-			t.bwe = sendsidebwe.NewSendSideBWE(sendsidebwe.SendSideBWEParams{
-				Config: params.CongestionControlConfig.SendSideBWE,
-				Logger: params.Logger,
-			})
+		if params.CongestionControlConfig.UseSendSideBWE || params.CongestionControlConfig.UseDelayBasedBWE {
+			if params.CongestionControlConfig.UseSendSideBWE {
+				params.Logger.Infow("using send side BWE", "pacerBehavior", params.CongestionControlConfig.SendSideBWEPacer)
+				t.bwe = sendsidebwe.NewSendSideBWE(sendsidebwe.SendSideBWEParams{
+					Config: params.CongestionControlConfig.SendSideBWE,
+					Logger: params.Logger,
+				})
+			} else {
+				params.Logger.Infow("using delay based BWE", "pacerBehavior", params.CongestionControlConfig.SendSideBWEPacer)
+				t.bwe = delaybasedbwe.NewDelayBasedBWE(delaybasedbwe.DelayBasedBWEParams{
+					Config: params.CongestionControlConfig.DelayBasedBWE,
+					Logger: params.Logger,
+				})
+			}
 			switch pacer.PacerBehavior(params.CongestionControlConfig.SendSideBWEPacer) {
 			case pacer.PacerBehaviorPassThrough:
 				t.pacer = pacer.NewPassThrough(params.Logger, t.bwe)
 			case pacer.PacerBehaviorNoQueue:
 				t.pacer = pacer.NewNoQueue(params.Logger, t.bwe)
+			case pacer.PacerBehaviorPriority:
+				t.pacer = pacer.NewPriority(params.Logger, t.bwe, params.CongestionControlConfig.PriorityPacer)
 			default:
 				t.pacer = pacer.NewNoQueue(params.Logger, t.bwe)
 			}
@@ -2025,6 +2036,30 @@ func (t *PCTransport) SetChannelCapacityOfStreamAllocator(channelCapacity int64)
 	t.streamAllocator.SetChannelCapacity(channelCapacity)
 }
 
+func (t *PCTransport) SetMaxChannelCapacityOfStreamAllocator(maxChannelCapacity int64) {
+	if t.streamAllocator == nil {
+		return
+	}
+
+	t.streamAllocator.SetMaxChannelCapacity(maxChannelCapacity)
+}
+
+func (t *PCTransport) SetNodeChannelCapacityOfStreamAllocator(nodeChannelCapacity int64) {
+	if t.streamAllocator == nil {
+		return
+	}
+
+	t.streamAllocator.SetNodeChannelCapacity(nodeChannelCapacity)
+}
+
+func (t *PCTransport) GetBandwidthDemandOfStreamAllocator() int64 {
+	if t.streamAllocator == nil {
+		return 0
+	}
+
+	return t.streamAllocator.GetBandwidthDemand()
+}
+
 func (t *PCTransport) preparePC(previousAnswer webrtc.SessionDescription) error {
 	// sticky data channel to first m-lines, if someday we don't send sdp without media streams to
 	// client's subscribe pc after joining, should change this step
@@ -1021,6 +1021,29 @@ func (t *TransportManager) SetSubscriberChannelCapacity(channelCapacity int64) {
 	}
 }
 
+func (t *TransportManager) SetSubscriberMaxChannelCapacity(maxChannelCapacity int64) {
+	if t.params.UseOneShotSignallingMode || t.params.UseSinglePeerConnection {
+		t.publisher.SetMaxChannelCapacityOfStreamAllocator(maxChannelCapacity)
+	} else {
+		t.subscriber.SetMaxChannelCapacityOfStreamAllocator(maxChannelCapacity)
+	}
+}
+
+func (t *TransportManager) SetSubscriberNodeChannelCapacity(nodeChannelCapacity int64) {
+	if t.params.UseOneShotSignallingMode || t.params.UseSinglePeerConnection {
+		t.publisher.SetNodeChannelCapacityOfStreamAllocator(nodeChannelCapacity)
+	} else {
+		t.subscriber.SetNodeChannelCapacityOfStreamAllocator(nodeChannelCapacity)
+	}
+}
+
+func (t *TransportManager) GetSubscriberBandwidthDemand() int64 {
+	if t.params.UseOneShotSignallingMode || t.params.UseSinglePeerConnection {
+		return t.publisher.GetBandwidthDemandOfStreamAllocator()
+	}
+	return t.subscriber.GetBandwidthDemandOfStreamAllocator()
+}
+
 func (t *TransportManager) hasRecentSignalLocked() bool {
 	return time.Since(t.lastSignalAt) < PingTimeoutSeconds*time.Second
 }
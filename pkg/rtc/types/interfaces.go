@@ -305,9 +305,11 @@ type Participant interface {
 
 	GetAudioLevel() (smoothedLevel float64, active bool)
 
-	// HasPermission checks permission of the subscriber by identity. Returns true if subscriber is allowed to subscribe
-	// to the track with trackID
-	HasPermission(trackID livekit.TrackID, subIdentity livekit.ParticipantIdentity) bool
+	// HasPermission checks permission of the subscriber by identity and attributes. Returns true if subscriber is allowed
+	// to subscribe to the track with trackID
+	HasPermission(trackID livekit.TrackID, sub LocalParticipant) bool
+	// HasSubscriptionRules returns true when permission of subscribers depends on their attributes
+	HasSubscriptionRules() bool
 
 	// permissions
 	Hidden() bool
@@ -384,6 +386,7 @@ type LocalParticipant interface {
 	SupportsTransceiverReuse() bool
 	IsUsingSinglePeerConnection() bool
 	IsReady() bool
+	IsFullyEstablished() bool
 	ActiveAt() time.Time
 	Disconnected() <-chan struct{}
 	IsIdle() bool
@@ -408,7 +411,8 @@ type LocalParticipant interface {
 	HandleSignalSourceClose()
 
 	// updates
-	UpdateMetadata(update *livekit.UpdateParticipantMetadata, fromAdmin bool) error
+	UpdateMetadata(update *livekit.UpdateParticipantMetadata, fromAdmin bool, expected *ExpectedVersions) error
+	MetadataVersions() MetadataVersions
 	SetName(name string)
 	SetMetadata(metadata string)
 	SetAttributes(attributes map[string]string)
@@ -497,6 +501,7 @@ type LocalParticipant interface {
 		dataTracks []*livekit.PublishDataTrackResponse,
 	)
 	IsReconnect() bool
+	IsMigration() bool
 	MoveToRoom(params MoveToRoomParams)
 
 	UpdateMediaRTT(rtt uint32)
@@ -517,6 +522,11 @@ type LocalParticipant interface {
 	// down stream bandwidth management
 	SetSubscriberAllowPause(allowPause bool)
 	SetSubscriberChannelCapacity(channelCapacity int64)
+	SetSubscriberMaxChannelCapacity(maxChannelCapacity int64)
+	SetSubscriberNodeChannelCapacity(nodeChannelCapacity int64)
+	GetSubscriberBandwidthDemand() int64
+	SetSubscriberMaxVideoQuality(quality livekit.VideoQuality)
+	GetSubscriberMaxVideoQuality() livekit.VideoQuality
 
 	GetPacer() pacer.Pacer
 
@@ -583,6 +593,8 @@ type LocalParticipantListener interface {
 
 	OnStateChange(LocalParticipant)
 	OnSubscriberReady(LocalParticipant)
+	// participant is active and its data channels are open, it can be sent reliable data
+	OnFullyEstablished(LocalParticipant)
 	OnMigrateStateChange(LocalParticipant, MigrateState)
 	OnDataMessage(LocalParticipant, livekit.DataPacket_Kind, *livekit.DataPacket)
 	OnDataMessageUnlabeled(LocalParticipant, []byte)
@@ -598,6 +610,58 @@ type LocalParticipantListener interface {
 	OnSyncState(LocalParticipant, *livekit.SyncState) error
 	OnSimulateScenario(LocalParticipant, *livekit.SimulateScenario) error
 	OnLeave(LocalParticipant, ParticipantCloseReason)
+	OnPublishPermissionRequest(LocalParticipant, *PublishPermissionRequest)
+	OnRoomStateRequest(LocalParticipant, *RoomStateRequest)
+}
+
+// RoomStateRequest is sent by a participant to update the shared state of the room
+type RoomStateRequest struct {
+	// echoed in the result sent back to the participant
+	RequestID string             `json:"request_id,omitempty"`
+	Updates   []*RoomStateUpdate `json:"updates"`
+}
+
+// RoomStateUpdate sets or deletes a key of the room state. Updates of a request are applied together or not at all.
+type RoomStateUpdate struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Delete bool   `json:"delete,omitempty"`
+	// when set, the update applies only if the key is at this version, 0 when the key must not exist
+	ExpectedVersion *uint64 `json:"expected_version,omitempty"`
+}
+
+// ExpectedVersions are preconditions of an update of participant metadata and attributes, the update is applied
+// only when all of them match. A version of 0 expects metadata or an attribute not to have been updated,
+// i. e. it is the one of the token or the attribute never existed.
+type ExpectedVersions struct {
+	Metadata   *uint64           `json:"metadata,omitempty"`
+	Attributes map[string]uint64 `json:"attributes,omitempty"`
+}
+
+// MetadataVersions are the versions of participant metadata and attributes, incremented by every update changing them.
+// Deleted attributes keep their version, an update expecting an earlier one fails.
+type MetadataVersions struct {
+	Metadata   uint64            `json:"metadata,omitempty"`
+	Attributes map[string]uint64 `json:"attributes,omitempty"`
+}
+
+// RoomStateEntry is the value of a key of the room state
+type RoomStateEntry struct {
+	Value string `json:"value"`
+	// version of the room state the key was last updated at
+	Version uint64 `json:"version"`
+	// identity of the participant which updated the key, empty when updated with the server API
+	UpdatedBy string `json:"updated_by,omitempty"`
+	// unix time in milliseconds
+	UpdatedAt int64 `json:"updated_at"`
+}
+
+// PublishPermissionRequest is sent by a participant asking room admins to be allowed to publish
+type PublishPermissionRequest struct {
+	// sources to publish, any source when empty
+	Sources []livekit.TrackSource
+	// withdraws a pending request
+	Cancel bool
 }
 
 var _ LocalParticipantListener = (*NullLocalParticipantListener)(nil)
@@ -608,6 +672,7 @@ type NullLocalParticipantListener struct {
 
 func (*NullLocalParticipantListener) OnStateChange(LocalParticipant)                      {}
 func (*NullLocalParticipantListener) OnSubscriberReady(LocalParticipant)                  {}
+func (*NullLocalParticipantListener) OnFullyEstablished(LocalParticipant)                 {}
 func (*NullLocalParticipantListener) OnMigrateStateChange(LocalParticipant, MigrateState) {}
 func (*NullLocalParticipantListener) OnDataMessage(LocalParticipant, livekit.DataPacket_Kind, *livekit.DataPacket) {
 }
@@ -633,6 +698,9 @@ func (*NullLocalParticipantListener) OnSimulateScenario(LocalParticipant, *livek
 	return nil
 }
 func (*NullLocalParticipantListener) OnLeave(LocalParticipant, ParticipantCloseReason) {}
+func (*NullLocalParticipantListener) OnPublishPermissionRequest(LocalParticipant, *PublishPermissionRequest) {
+}
+func (*NullLocalParticipantListener) OnRoomStateRequest(LocalParticipant, *RoomStateRequest) {}
 
 // ---------------------------------------------
 
@@ -690,7 +758,7 @@ type MediaTrack interface {
 	AddSubscriber(participant LocalParticipant) (SubscribedTrack, error)
 	RemoveSubscriber(participantID livekit.ParticipantID, isExpectedToResume bool)
 	IsSubscriber(subID livekit.ParticipantID) bool
-	RevokeDisallowedSubscribers(allowedSubscriberIdentities []livekit.ParticipantIdentity) []livekit.ParticipantIdentity
+	RevokeDisallowedSubscribers(isAllowed func(sub LocalParticipant) bool) []livekit.ParticipantIdentity
 	GetAllSubscribers() []livekit.ParticipantID
 	GetNumSubscribers() int
 	OnTrackSubscribed()
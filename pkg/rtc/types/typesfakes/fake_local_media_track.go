@@ -299,10 +299,10 @@ type FakeLocalMediaTrack struct {
 	restartMutex       sync.RWMutex
 	restartArgsForCall []struct {
 	}
-	RevokeDisallowedSubscribersStub        func([]livekit.ParticipantIdentity) []livekit.ParticipantIdentity
+	RevokeDisallowedSubscribersStub        func(func(sub types.LocalParticipant) bool) []livekit.ParticipantIdentity
 	revokeDisallowedSubscribersMutex       sync.RWMutex
 	revokeDisallowedSubscribersArgsForCall []struct {
-		arg1 []livekit.ParticipantIdentity
+		arg1 func(sub types.LocalParticipant) bool
 	}
 	revokeDisallowedSubscribersReturns struct {
 		result1 []livekit.ParticipantIdentity
@@ -1906,20 +1906,15 @@ func (fake *FakeLocalMediaTrack) RestartCalls(stub func()) {
 	fake.RestartStub = stub
 }
 
-func (fake *FakeLocalMediaTrack) RevokeDisallowedSubscribers(arg1 []livekit.ParticipantIdentity) []livekit.ParticipantIdentity {
-	var arg1Copy []livekit.ParticipantIdentity
-	if arg1 != nil {
-		arg1Copy = make([]livekit.ParticipantIdentity, len(arg1))
-		copy(arg1Copy, arg1)
-	}
+func (fake *FakeLocalMediaTrack) RevokeDisallowedSubscribers(arg1 func(sub types.LocalParticipant) bool) []livekit.ParticipantIdentity {
 	fake.revokeDisallowedSubscribersMutex.Lock()
 	ret, specificReturn := fake.revokeDisallowedSubscribersReturnsOnCall[len(fake.revokeDisallowedSubscribersArgsForCall)]
 	fake.revokeDisallowedSubscribersArgsForCall = append(fake.revokeDisallowedSubscribersArgsForCall, struct {
-		arg1 []livekit.ParticipantIdentity
-	}{arg1Copy})
+		arg1 func(sub types.LocalParticipant) bool
+	}{arg1})
 	stub := fake.RevokeDisallowedSubscribersStub
 	fakeReturns := fake.revokeDisallowedSubscribersReturns
-	fake.recordInvocation("RevokeDisallowedSubscribers", []interface{}{arg1Copy})
+	fake.recordInvocation("RevokeDisallowedSubscribers", []interface{}{arg1})
 	fake.revokeDisallowedSubscribersMutex.Unlock()
 	if stub != nil {
 		return stub(arg1)
@@ -1936,13 +1931,13 @@ func (fake *FakeLocalMediaTrack) RevokeDisallowedSubscribersCallCount() int {
 	return len(fake.revokeDisallowedSubscribersArgsForCall)
 }
 
-func (fake *FakeLocalMediaTrack) RevokeDisallowedSubscribersCalls(stub func([]livekit.ParticipantIdentity) []livekit.ParticipantIdentity) {
+func (fake *FakeLocalMediaTrack) RevokeDisallowedSubscribersCalls(stub func(func(sub types.LocalParticipant) bool) []livekit.ParticipantIdentity) {
 	fake.revokeDisallowedSubscribersMutex.Lock()
 	defer fake.revokeDisallowedSubscribersMutex.Unlock()
 	fake.RevokeDisallowedSubscribersStub = stub
 }
 
-func (fake *FakeLocalMediaTrack) RevokeDisallowedSubscribersArgsForCall(i int) []livekit.ParticipantIdentity {
+func (fake *FakeLocalMediaTrack) RevokeDisallowedSubscribersArgsForCall(i int) func(sub types.LocalParticipant) bool {
 	fake.revokeDisallowedSubscribersMutex.RLock()
 	defer fake.revokeDisallowedSubscribersMutex.RUnlock()
 	argsForCall := fake.revokeDisallowedSubscribersArgsForCall[i]
@@ -541,6 +541,26 @@ type FakeLocalParticipant struct {
 	getSubscribedTracksReturnsOnCall map[int]struct {
 		result1 []types.SubscribedTrack
 	}
+	GetSubscriberBandwidthDemandStub        func() int64
+	getSubscriberBandwidthDemandMutex       sync.RWMutex
+	getSubscriberBandwidthDemandArgsForCall []struct {
+	}
+	getSubscriberBandwidthDemandReturns struct {
+		result1 int64
+	}
+	getSubscriberBandwidthDemandReturnsOnCall map[int]struct {
+		result1 int64
+	}
+	GetSubscriberMaxVideoQualityStub        func() livekit.VideoQuality
+	getSubscriberMaxVideoQualityMutex       sync.RWMutex
+	getSubscriberMaxVideoQualityArgsForCall []struct {
+	}
+	getSubscriberMaxVideoQualityReturns struct {
+		result1 livekit.VideoQuality
+	}
+	getSubscriberMaxVideoQualityReturnsOnCall map[int]struct {
+		result1 livekit.VideoQuality
+	}
 	GetTrailerStub        func() []byte
 	getTrailerMutex       sync.RWMutex
 	getTrailerArgsForCall []struct {
@@ -718,11 +738,11 @@ type FakeLocalParticipant struct {
 	hasConnectedReturnsOnCall map[int]struct {
 		result1 bool
 	}
-	HasPermissionStub        func(livekit.TrackID, livekit.ParticipantIdentity) bool
+	HasPermissionStub        func(livekit.TrackID, types.LocalParticipant) bool
 	hasPermissionMutex       sync.RWMutex
 	hasPermissionArgsForCall []struct {
 		arg1 livekit.TrackID
-		arg2 livekit.ParticipantIdentity
+		arg2 types.LocalParticipant
 	}
 	hasPermissionReturns struct {
 		result1 bool
@@ -730,6 +750,16 @@ type FakeLocalParticipant struct {
 	hasPermissionReturnsOnCall map[int]struct {
 		result1 bool
 	}
+	HasSubscriptionRulesStub        func() bool
+	hasSubscriptionRulesMutex       sync.RWMutex
+	hasSubscriptionRulesArgsForCall []struct {
+	}
+	hasSubscriptionRulesReturns struct {
+		result1 bool
+	}
+	hasSubscriptionRulesReturnsOnCall map[int]struct {
+		result1 bool
+	}
 	HiddenStub        func() bool
 	hiddenMutex       sync.RWMutex
 	hiddenArgsForCall []struct {
@@ -805,6 +835,16 @@ type FakeLocalParticipant struct {
 	isDisconnectedReturnsOnCall map[int]struct {
 		result1 bool
 	}
+	IsFullyEstablishedStub        func() bool
+	isFullyEstablishedMutex       sync.RWMutex
+	isFullyEstablishedArgsForCall []struct {
+	}
+	isFullyEstablishedReturns struct {
+		result1 bool
+	}
+	isFullyEstablishedReturnsOnCall map[int]struct {
+		result1 bool
+	}
 	IsIdleStub        func() bool
 	isIdleMutex       sync.RWMutex
 	isIdleArgsForCall []struct {
@@ -815,6 +855,16 @@ type FakeLocalParticipant struct {
 	isIdleReturnsOnCall map[int]struct {
 		result1 bool
 	}
+	IsMigrationStub        func() bool
+	isMigrationMutex       sync.RWMutex
+	isMigrationArgsForCall []struct {
+	}
+	isMigrationReturns struct {
+		result1 bool
+	}
+	isMigrationReturnsOnCall map[int]struct {
+		result1 bool
+	}
 	IsPublisherStub        func() bool
 	isPublisherMutex       sync.RWMutex
 	isPublisherArgsForCall []struct {
@@ -915,6 +965,16 @@ type FakeLocalParticipant struct {
 	maybeStartMigrationReturnsOnCall map[int]struct {
 		result1 bool
 	}
+	MetadataVersionsStub        func() types.MetadataVersions
+	metadataVersionsMutex       sync.RWMutex
+	metadataVersionsArgsForCall []struct {
+	}
+	metadataVersionsReturns struct {
+		result1 types.MetadataVersions
+	}
+	metadataVersionsReturnsOnCall map[int]struct {
+		result1 types.MetadataVersions
+	}
 	MigrateStateStub        func() types.MigrateState
 	migrateStateMutex       sync.RWMutex
 	migrateStateArgsForCall []struct {
@@ -1178,6 +1238,21 @@ type FakeLocalParticipant struct {
 	setSubscriberChannelCapacityArgsForCall []struct {
 		arg1 int64
 	}
+	SetSubscriberMaxChannelCapacityStub        func(int64)
+	setSubscriberMaxChannelCapacityMutex       sync.RWMutex
+	setSubscriberMaxChannelCapacityArgsForCall []struct {
+		arg1 int64
+	}
+	SetSubscriberMaxVideoQualityStub        func(livekit.VideoQuality)
+	setSubscriberMaxVideoQualityMutex       sync.RWMutex
+	setSubscriberMaxVideoQualityArgsForCall []struct {
+		arg1 livekit.VideoQuality
+	}
+	SetSubscriberNodeChannelCapacityStub        func(int64)
+	setSubscriberNodeChannelCapacityMutex       sync.RWMutex
+	setSubscriberNodeChannelCapacityArgsForCall []struct {
+		arg1 int64
+	}
 	SetTrackMutedStub        func(*livekit.MuteTrackRequest, bool) *livekit.TrackInfo
 	setTrackMutedMutex       sync.RWMutex
 	setTrackMutedArgsForCall []struct {
@@ -1375,11 +1450,12 @@ type FakeLocalParticipant struct {
 	updateMediaRTTArgsForCall []struct {
 		arg1 uint32
 	}
-	UpdateMetadataStub        func(*livekit.UpdateParticipantMetadata, bool) error
+	UpdateMetadataStub        func(*livekit.UpdateParticipantMetadata, bool, *types.ExpectedVersions) error
 	updateMetadataMutex       sync.RWMutex
 	updateMetadataArgsForCall []struct {
 		arg1 *livekit.UpdateParticipantMetadata
 		arg2 bool
+		arg3 *types.ExpectedVersions
 	}
 	updateMetadataReturns struct {
 		result1 error
@@ -4196,6 +4272,112 @@ func (fake *FakeLocalParticipant) GetSubscribedTracksReturnsOnCall(i int, result
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) GetSubscriberBandwidthDemand() int64 {
+	fake.getSubscriberBandwidthDemandMutex.Lock()
+	ret, specificReturn := fake.getSubscriberBandwidthDemandReturnsOnCall[len(fake.getSubscriberBandwidthDemandArgsForCall)]
+	fake.getSubscriberBandwidthDemandArgsForCall = append(fake.getSubscriberBandwidthDemandArgsForCall, struct {
+	}{})
+	stub := fake.GetSubscriberBandwidthDemandStub
+	fakeReturns := fake.getSubscriberBandwidthDemandReturns
+	fake.recordInvocation("GetSubscriberBandwidthDemand", []interface{}{})
+	fake.getSubscriberBandwidthDemandMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) GetSubscriberBandwidthDemandCallCount() int {
+	fake.getSubscriberBandwidthDemandMutex.RLock()
+	defer fake.getSubscriberBandwidthDemandMutex.RUnlock()
+	return len(fake.getSubscriberBandwidthDemandArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) GetSubscriberBandwidthDemandCalls(stub func() int64) {
+	fake.getSubscriberBandwidthDemandMutex.Lock()
+	defer fake.getSubscriberBandwidthDemandMutex.Unlock()
+	fake.GetSubscriberBandwidthDemandStub = stub
+}
+
+func (fake *FakeLocalParticipant) GetSubscriberBandwidthDemandReturns(result1 int64) {
+	fake.getSubscriberBandwidthDemandMutex.Lock()
+	defer fake.getSubscriberBandwidthDemandMutex.Unlock()
+	fake.GetSubscriberBandwidthDemandStub = nil
+	fake.getSubscriberBandwidthDemandReturns = struct {
+		result1 int64
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetSubscriberBandwidthDemandReturnsOnCall(i int, result1 int64) {
+	fake.getSubscriberBandwidthDemandMutex.Lock()
+	defer fake.getSubscriberBandwidthDemandMutex.Unlock()
+	fake.GetSubscriberBandwidthDemandStub = nil
+	if fake.getSubscriberBandwidthDemandReturnsOnCall == nil {
+		fake.getSubscriberBandwidthDemandReturnsOnCall = make(map[int]struct {
+			result1 int64
+		})
+	}
+	fake.getSubscriberBandwidthDemandReturnsOnCall[i] = struct {
+		result1 int64
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetSubscriberMaxVideoQuality() livekit.VideoQuality {
+	fake.getSubscriberMaxVideoQualityMutex.Lock()
+	ret, specificReturn := fake.getSubscriberMaxVideoQualityReturnsOnCall[len(fake.getSubscriberMaxVideoQualityArgsForCall)]
+	fake.getSubscriberMaxVideoQualityArgsForCall = append(fake.getSubscriberMaxVideoQualityArgsForCall, struct {
+	}{})
+	stub := fake.GetSubscriberMaxVideoQualityStub
+	fakeReturns := fake.getSubscriberMaxVideoQualityReturns
+	fake.recordInvocation("GetSubscriberMaxVideoQuality", []interface{}{})
+	fake.getSubscriberMaxVideoQualityMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) GetSubscriberMaxVideoQualityCallCount() int {
+	fake.getSubscriberMaxVideoQualityMutex.RLock()
+	defer fake.getSubscriberMaxVideoQualityMutex.RUnlock()
+	return len(fake.getSubscriberMaxVideoQualityArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) GetSubscriberMaxVideoQualityCalls(stub func() livekit.VideoQuality) {
+	fake.getSubscriberMaxVideoQualityMutex.Lock()
+	defer fake.getSubscriberMaxVideoQualityMutex.Unlock()
+	fake.GetSubscriberMaxVideoQualityStub = stub
+}
+
+func (fake *FakeLocalParticipant) GetSubscriberMaxVideoQualityReturns(result1 livekit.VideoQuality) {
+	fake.getSubscriberMaxVideoQualityMutex.Lock()
+	defer fake.getSubscriberMaxVideoQualityMutex.Unlock()
+	fake.GetSubscriberMaxVideoQualityStub = nil
+	fake.getSubscriberMaxVideoQualityReturns = struct {
+		result1 livekit.VideoQuality
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetSubscriberMaxVideoQualityReturnsOnCall(i int, result1 livekit.VideoQuality) {
+	fake.getSubscriberMaxVideoQualityMutex.Lock()
+	defer fake.getSubscriberMaxVideoQualityMutex.Unlock()
+	fake.GetSubscriberMaxVideoQualityStub = nil
+	if fake.getSubscriberMaxVideoQualityReturnsOnCall == nil {
+		fake.getSubscriberMaxVideoQualityReturnsOnCall = make(map[int]struct {
+			result1 livekit.VideoQuality
+		})
+	}
+	fake.getSubscriberMaxVideoQualityReturnsOnCall[i] = struct {
+		result1 livekit.VideoQuality
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) GetTrailer() []byte {
 	fake.getTrailerMutex.Lock()
 	ret, specificReturn := fake.getTrailerReturnsOnCall[len(fake.getTrailerArgsForCall)]
@@ -5188,12 +5370,12 @@ func (fake *FakeLocalParticipant) HasConnectedReturnsOnCall(i int, result1 bool)
 	}{result1}
 }
 
-func (fake *FakeLocalParticipant) HasPermission(arg1 livekit.TrackID, arg2 livekit.ParticipantIdentity) bool {
+func (fake *FakeLocalParticipant) HasPermission(arg1 livekit.TrackID, arg2 types.LocalParticipant) bool {
 	fake.hasPermissionMutex.Lock()
 	ret, specificReturn := fake.hasPermissionReturnsOnCall[len(fake.hasPermissionArgsForCall)]
 	fake.hasPermissionArgsForCall = append(fake.hasPermissionArgsForCall, struct {
 		arg1 livekit.TrackID
-		arg2 livekit.ParticipantIdentity
+		arg2 types.LocalParticipant
 	}{arg1, arg2})
 	stub := fake.HasPermissionStub
 	fakeReturns := fake.hasPermissionReturns
@@ -5214,13 +5396,13 @@ func (fake *FakeLocalParticipant) HasPermissionCallCount() int {
 	return len(fake.hasPermissionArgsForCall)
 }
 
-func (fake *FakeLocalParticipant) HasPermissionCalls(stub func(livekit.TrackID, livekit.ParticipantIdentity) bool) {
+func (fake *FakeLocalParticipant) HasPermissionCalls(stub func(livekit.TrackID, types.LocalParticipant) bool) {
 	fake.hasPermissionMutex.Lock()
 	defer fake.hasPermissionMutex.Unlock()
 	fake.HasPermissionStub = stub
 }
 
-func (fake *FakeLocalParticipant) HasPermissionArgsForCall(i int) (livekit.TrackID, livekit.ParticipantIdentity) {
+func (fake *FakeLocalParticipant) HasPermissionArgsForCall(i int) (livekit.TrackID, types.LocalParticipant) {
 	fake.hasPermissionMutex.RLock()
 	defer fake.hasPermissionMutex.RUnlock()
 	argsForCall := fake.hasPermissionArgsForCall[i]
@@ -5250,6 +5432,59 @@ func (fake *FakeLocalParticipant) HasPermissionReturnsOnCall(i int, result1 bool
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) HasSubscriptionRules() bool {
+	fake.hasSubscriptionRulesMutex.Lock()
+	ret, specificReturn := fake.hasSubscriptionRulesReturnsOnCall[len(fake.hasSubscriptionRulesArgsForCall)]
+	fake.hasSubscriptionRulesArgsForCall = append(fake.hasSubscriptionRulesArgsForCall, struct {
+	}{})
+	stub := fake.HasSubscriptionRulesStub
+	fakeReturns := fake.hasSubscriptionRulesReturns
+	fake.recordInvocation("HasSubscriptionRules", []interface{}{})
+	fake.hasSubscriptionRulesMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) HasSubscriptionRulesCallCount() int {
+	fake.hasSubscriptionRulesMutex.RLock()
+	defer fake.hasSubscriptionRulesMutex.RUnlock()
+	return len(fake.hasSubscriptionRulesArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) HasSubscriptionRulesCalls(stub func() bool) {
+	fake.hasSubscriptionRulesMutex.Lock()
+	defer fake.hasSubscriptionRulesMutex.Unlock()
+	fake.HasSubscriptionRulesStub = stub
+}
+
+func (fake *FakeLocalParticipant) HasSubscriptionRulesReturns(result1 bool) {
+	fake.hasSubscriptionRulesMutex.Lock()
+	defer fake.hasSubscriptionRulesMutex.Unlock()
+	fake.HasSubscriptionRulesStub = nil
+	fake.hasSubscriptionRulesReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) HasSubscriptionRulesReturnsOnCall(i int, result1 bool) {
+	fake.hasSubscriptionRulesMutex.Lock()
+	defer fake.hasSubscriptionRulesMutex.Unlock()
+	fake.HasSubscriptionRulesStub = nil
+	if fake.hasSubscriptionRulesReturnsOnCall == nil {
+		fake.hasSubscriptionRulesReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.hasSubscriptionRulesReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) Hidden() bool {
 	fake.hiddenMutex.Lock()
 	ret, specificReturn := fake.hiddenReturnsOnCall[len(fake.hiddenArgsForCall)]
@@ -5653,6 +5888,59 @@ func (fake *FakeLocalParticipant) IsDisconnectedReturnsOnCall(i int, result1 boo
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) IsFullyEstablished() bool {
+	fake.isFullyEstablishedMutex.Lock()
+	ret, specificReturn := fake.isFullyEstablishedReturnsOnCall[len(fake.isFullyEstablishedArgsForCall)]
+	fake.isFullyEstablishedArgsForCall = append(fake.isFullyEstablishedArgsForCall, struct {
+	}{})
+	stub := fake.IsFullyEstablishedStub
+	fakeReturns := fake.isFullyEstablishedReturns
+	fake.recordInvocation("IsFullyEstablished", []interface{}{})
+	fake.isFullyEstablishedMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) IsFullyEstablishedCallCount() int {
+	fake.isFullyEstablishedMutex.RLock()
+	defer fake.isFullyEstablishedMutex.RUnlock()
+	return len(fake.isFullyEstablishedArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) IsFullyEstablishedCalls(stub func() bool) {
+	fake.isFullyEstablishedMutex.Lock()
+	defer fake.isFullyEstablishedMutex.Unlock()
+	fake.IsFullyEstablishedStub = stub
+}
+
+func (fake *FakeLocalParticipant) IsFullyEstablishedReturns(result1 bool) {
+	fake.isFullyEstablishedMutex.Lock()
+	defer fake.isFullyEstablishedMutex.Unlock()
+	fake.IsFullyEstablishedStub = nil
+	fake.isFullyEstablishedReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) IsFullyEstablishedReturnsOnCall(i int, result1 bool) {
+	fake.isFullyEstablishedMutex.Lock()
+	defer fake.isFullyEstablishedMutex.Unlock()
+	fake.IsFullyEstablishedStub = nil
+	if fake.isFullyEstablishedReturnsOnCall == nil {
+		fake.isFullyEstablishedReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.isFullyEstablishedReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) IsIdle() bool {
 	fake.isIdleMutex.Lock()
 	ret, specificReturn := fake.isIdleReturnsOnCall[len(fake.isIdleArgsForCall)]
@@ -5706,6 +5994,59 @@ func (fake *FakeLocalParticipant) IsIdleReturnsOnCall(i int, result1 bool) {
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) IsMigration() bool {
+	fake.isMigrationMutex.Lock()
+	ret, specificReturn := fake.isMigrationReturnsOnCall[len(fake.isMigrationArgsForCall)]
+	fake.isMigrationArgsForCall = append(fake.isMigrationArgsForCall, struct {
+	}{})
+	stub := fake.IsMigrationStub
+	fakeReturns := fake.isMigrationReturns
+	fake.recordInvocation("IsMigration", []interface{}{})
+	fake.isMigrationMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) IsMigrationCallCount() int {
+	fake.isMigrationMutex.RLock()
+	defer fake.isMigrationMutex.RUnlock()
+	return len(fake.isMigrationArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) IsMigrationCalls(stub func() bool) {
+	fake.isMigrationMutex.Lock()
+	defer fake.isMigrationMutex.Unlock()
+	fake.IsMigrationStub = stub
+}
+
+func (fake *FakeLocalParticipant) IsMigrationReturns(result1 bool) {
+	fake.isMigrationMutex.Lock()
+	defer fake.isMigrationMutex.Unlock()
+	fake.IsMigrationStub = nil
+	fake.isMigrationReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) IsMigrationReturnsOnCall(i int, result1 bool) {
+	fake.isMigrationMutex.Lock()
+	defer fake.isMigrationMutex.Unlock()
+	fake.IsMigrationStub = nil
+	if fake.isMigrationReturnsOnCall == nil {
+		fake.isMigrationReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.isMigrationReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) IsPublisher() bool {
 	fake.isPublisherMutex.Lock()
 	ret, specificReturn := fake.isPublisherReturnsOnCall[len(fake.isPublisherArgsForCall)]
@@ -6241,6 +6582,59 @@ func (fake *FakeLocalParticipant) MaybeStartMigrationReturnsOnCall(i int, result
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) MetadataVersions() types.MetadataVersions {
+	fake.metadataVersionsMutex.Lock()
+	ret, specificReturn := fake.metadataVersionsReturnsOnCall[len(fake.metadataVersionsArgsForCall)]
+	fake.metadataVersionsArgsForCall = append(fake.metadataVersionsArgsForCall, struct {
+	}{})
+	stub := fake.MetadataVersionsStub
+	fakeReturns := fake.metadataVersionsReturns
+	fake.recordInvocation("MetadataVersions", []interface{}{})
+	fake.metadataVersionsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) MetadataVersionsCallCount() int {
+	fake.metadataVersionsMutex.RLock()
+	defer fake.metadataVersionsMutex.RUnlock()
+	return len(fake.metadataVersionsArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) MetadataVersionsCalls(stub func() types.MetadataVersions) {
+	fake.metadataVersionsMutex.Lock()
+	defer fake.metadataVersionsMutex.Unlock()
+	fake.MetadataVersionsStub = stub
+}
+
+func (fake *FakeLocalParticipant) MetadataVersionsReturns(result1 types.MetadataVersions) {
+	fake.metadataVersionsMutex.Lock()
+	defer fake.metadataVersionsMutex.Unlock()
+	fake.MetadataVersionsStub = nil
+	fake.metadataVersionsReturns = struct {
+		result1 types.MetadataVersions
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) MetadataVersionsReturnsOnCall(i int, result1 types.MetadataVersions) {
+	fake.metadataVersionsMutex.Lock()
+	defer fake.metadataVersionsMutex.Unlock()
+	fake.MetadataVersionsStub = nil
+	if fake.metadataVersionsReturnsOnCall == nil {
+		fake.metadataVersionsReturnsOnCall = make(map[int]struct {
+			result1 types.MetadataVersions
+		})
+	}
+	fake.metadataVersionsReturnsOnCall[i] = struct {
+		result1 types.MetadataVersions
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) MigrateState() types.MigrateState {
 	fake.migrateStateMutex.Lock()
 	ret, specificReturn := fake.migrateStateReturnsOnCall[len(fake.migrateStateArgsForCall)]
@@ -7732,6 +8126,102 @@ func (fake *FakeLocalParticipant) SetSubscriberChannelCapacityArgsForCall(i int)
 	return argsForCall.arg1
 }
 
+func (fake *FakeLocalParticipant) SetSubscriberMaxChannelCapacity(arg1 int64) {
+	fake.setSubscriberMaxChannelCapacityMutex.Lock()
+	fake.setSubscriberMaxChannelCapacityArgsForCall = append(fake.setSubscriberMaxChannelCapacityArgsForCall, struct {
+		arg1 int64
+	}{arg1})
+	stub := fake.SetSubscriberMaxChannelCapacityStub
+	fake.recordInvocation("SetSubscriberMaxChannelCapacity", []interface{}{arg1})
+	fake.setSubscriberMaxChannelCapacityMutex.Unlock()
+	if stub != nil {
+		fake.SetSubscriberMaxChannelCapacityStub(arg1)
+	}
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberMaxChannelCapacityCallCount() int {
+	fake.setSubscriberMaxChannelCapacityMutex.RLock()
+	defer fake.setSubscriberMaxChannelCapacityMutex.RUnlock()
+	return len(fake.setSubscriberMaxChannelCapacityArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberMaxChannelCapacityCalls(stub func(int64)) {
+	fake.setSubscriberMaxChannelCapacityMutex.Lock()
+	defer fake.setSubscriberMaxChannelCapacityMutex.Unlock()
+	fake.SetSubscriberMaxChannelCapacityStub = stub
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberMaxChannelCapacityArgsForCall(i int) int64 {
+	fake.setSubscriberMaxChannelCapacityMutex.RLock()
+	defer fake.setSubscriberMaxChannelCapacityMutex.RUnlock()
+	argsForCall := fake.setSubscriberMaxChannelCapacityArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberMaxVideoQuality(arg1 livekit.VideoQuality) {
+	fake.setSubscriberMaxVideoQualityMutex.Lock()
+	fake.setSubscriberMaxVideoQualityArgsForCall = append(fake.setSubscriberMaxVideoQualityArgsForCall, struct {
+		arg1 livekit.VideoQuality
+	}{arg1})
+	stub := fake.SetSubscriberMaxVideoQualityStub
+	fake.recordInvocation("SetSubscriberMaxVideoQuality", []interface{}{arg1})
+	fake.setSubscriberMaxVideoQualityMutex.Unlock()
+	if stub != nil {
+		fake.SetSubscriberMaxVideoQualityStub(arg1)
+	}
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberMaxVideoQualityCallCount() int {
+	fake.setSubscriberMaxVideoQualityMutex.RLock()
+	defer fake.setSubscriberMaxVideoQualityMutex.RUnlock()
+	return len(fake.setSubscriberMaxVideoQualityArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberMaxVideoQualityCalls(stub func(livekit.VideoQuality)) {
+	fake.setSubscriberMaxVideoQualityMutex.Lock()
+	defer fake.setSubscriberMaxVideoQualityMutex.Unlock()
+	fake.SetSubscriberMaxVideoQualityStub = stub
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberMaxVideoQualityArgsForCall(i int) livekit.VideoQuality {
+	fake.setSubscriberMaxVideoQualityMutex.RLock()
+	defer fake.setSubscriberMaxVideoQualityMutex.RUnlock()
+	argsForCall := fake.setSubscriberMaxVideoQualityArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberNodeChannelCapacity(arg1 int64) {
+	fake.setSubscriberNodeChannelCapacityMutex.Lock()
+	fake.setSubscriberNodeChannelCapacityArgsForCall = append(fake.setSubscriberNodeChannelCapacityArgsForCall, struct {
+		arg1 int64
+	}{arg1})
+	stub := fake.SetSubscriberNodeChannelCapacityStub
+	fake.recordInvocation("SetSubscriberNodeChannelCapacity", []interface{}{arg1})
+	fake.setSubscriberNodeChannelCapacityMutex.Unlock()
+	if stub != nil {
+		fake.SetSubscriberNodeChannelCapacityStub(arg1)
+	}
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberNodeChannelCapacityCallCount() int {
+	fake.setSubscriberNodeChannelCapacityMutex.RLock()
+	defer fake.setSubscriberNodeChannelCapacityMutex.RUnlock()
+	return len(fake.setSubscriberNodeChannelCapacityArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberNodeChannelCapacityCalls(stub func(int64)) {
+	fake.setSubscriberNodeChannelCapacityMutex.Lock()
+	defer fake.setSubscriberNodeChannelCapacityMutex.Unlock()
+	fake.SetSubscriberNodeChannelCapacityStub = stub
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberNodeChannelCapacityArgsForCall(i int) int64 {
+	fake.setSubscriberNodeChannelCapacityMutex.RLock()
+	defer fake.setSubscriberNodeChannelCapacityMutex.RUnlock()
+	argsForCall := fake.setSubscriberNodeChannelCapacityArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeLocalParticipant) SetTrackMuted(arg1 *livekit.MuteTrackRequest, arg2 bool) *livekit.TrackInfo {
 	fake.setTrackMutedMutex.Lock()
 	ret, specificReturn := fake.setTrackMutedReturnsOnCall[len(fake.setTrackMutedArgsForCall)]
@@ -8790,19 +9280,20 @@ func (fake *FakeLocalParticipant) UpdateMediaRTTArgsForCall(i int) uint32 {
 	return argsForCall.arg1
 }
 
-func (fake *FakeLocalParticipant) UpdateMetadata(arg1 *livekit.UpdateParticipantMetadata, arg2 bool) error {
+func (fake *FakeLocalParticipant) UpdateMetadata(arg1 *livekit.UpdateParticipantMetadata, arg2 bool, arg3 *types.ExpectedVersions) error {
 	fake.updateMetadataMutex.Lock()
 	ret, specificReturn := fake.updateMetadataReturnsOnCall[len(fake.updateMetadataArgsForCall)]
 	fake.updateMetadataArgsForCall = append(fake.updateMetadataArgsForCall, struct {
 		arg1 *livekit.UpdateParticipantMetadata
 		arg2 bool
-	}{arg1, arg2})
+		arg3 *types.ExpectedVersions
+	}{arg1, arg2, arg3})
 	stub := fake.UpdateMetadataStub
 	fakeReturns := fake.updateMetadataReturns
-	fake.recordInvocation("UpdateMetadata", []interface{}{arg1, arg2})
+	fake.recordInvocation("UpdateMetadata", []interface{}{arg1, arg2, arg3})
 	fake.updateMetadataMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
@@ -8816,17 +9307,17 @@ func (fake *FakeLocalParticipant) UpdateMetadataCallCount() int {
 	return len(fake.updateMetadataArgsForCall)
 }
 
-func (fake *FakeLocalParticipant) UpdateMetadataCalls(stub func(*livekit.UpdateParticipantMetadata, bool) error) {
+func (fake *FakeLocalParticipant) UpdateMetadataCalls(stub func(*livekit.UpdateParticipantMetadata, bool, *types.ExpectedVersions) error) {
 	fake.updateMetadataMutex.Lock()
 	defer fake.updateMetadataMutex.Unlock()
 	fake.UpdateMetadataStub = stub
 }
 
-func (fake *FakeLocalParticipant) UpdateMetadataArgsForCall(i int) (*livekit.UpdateParticipantMetadata, bool) {
+func (fake *FakeLocalParticipant) UpdateMetadataArgsForCall(i int) (*livekit.UpdateParticipantMetadata, bool, *types.ExpectedVersions) {
 	fake.updateMetadataMutex.RLock()
 	defer fake.updateMetadataMutex.RUnlock()
 	argsForCall := fake.updateMetadataArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeLocalParticipant) UpdateMetadataReturns(result1 error) {
@@ -42,6 +42,11 @@ type FakeLocalParticipantListener struct {
 		arg1 types.Participant
 		arg2 types.DataTrack
 	}
+	OnFullyEstablishedStub        func(types.LocalParticipant)
+	onFullyEstablishedMutex       sync.RWMutex
+	onFullyEstablishedArgsForCall []struct {
+		arg1 types.LocalParticipant
+	}
 	OnLeaveStub        func(types.LocalParticipant, types.ParticipantCloseReason)
 	onLeaveMutex       sync.RWMutex
 	onLeaveArgsForCall []struct {
@@ -65,6 +70,18 @@ type FakeLocalParticipantListener struct {
 	onParticipantUpdateArgsForCall []struct {
 		arg1 types.Participant
 	}
+	OnPublishPermissionRequestStub        func(types.LocalParticipant, *types.PublishPermissionRequest)
+	onPublishPermissionRequestMutex       sync.RWMutex
+	onPublishPermissionRequestArgsForCall []struct {
+		arg1 types.LocalParticipant
+		arg2 *types.PublishPermissionRequest
+	}
+	OnRoomStateRequestStub        func(types.LocalParticipant, *types.RoomStateRequest)
+	onRoomStateRequestMutex       sync.RWMutex
+	onRoomStateRequestArgsForCall []struct {
+		arg1 types.LocalParticipant
+		arg2 *types.RoomStateRequest
+	}
 	OnSimulateScenarioStub        func(types.LocalParticipant, *livekit.SimulateScenario) error
 	onSimulateScenarioMutex       sync.RWMutex
 	onSimulateScenarioArgsForCall []struct {
@@ -331,6 +348,38 @@ func (fake *FakeLocalParticipantListener) OnDataTrackUnpublishedArgsForCall(i in
 	return argsForCall.arg1, argsForCall.arg2
 }
 
+func (fake *FakeLocalParticipantListener) OnFullyEstablished(arg1 types.LocalParticipant) {
+	fake.onFullyEstablishedMutex.Lock()
+	fake.onFullyEstablishedArgsForCall = append(fake.onFullyEstablishedArgsForCall, struct {
+		arg1 types.LocalParticipant
+	}{arg1})
+	stub := fake.OnFullyEstablishedStub
+	fake.recordInvocation("OnFullyEstablished", []interface{}{arg1})
+	fake.onFullyEstablishedMutex.Unlock()
+	if stub != nil {
+		fake.OnFullyEstablishedStub(arg1)
+	}
+}
+
+func (fake *FakeLocalParticipantListener) OnFullyEstablishedCallCount() int {
+	fake.onFullyEstablishedMutex.RLock()
+	defer fake.onFullyEstablishedMutex.RUnlock()
+	return len(fake.onFullyEstablishedArgsForCall)
+}
+
+func (fake *FakeLocalParticipantListener) OnFullyEstablishedCalls(stub func(types.LocalParticipant)) {
+	fake.onFullyEstablishedMutex.Lock()
+	defer fake.onFullyEstablishedMutex.Unlock()
+	fake.OnFullyEstablishedStub = stub
+}
+
+func (fake *FakeLocalParticipantListener) OnFullyEstablishedArgsForCall(i int) types.LocalParticipant {
+	fake.onFullyEstablishedMutex.RLock()
+	defer fake.onFullyEstablishedMutex.RUnlock()
+	argsForCall := fake.onFullyEstablishedArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeLocalParticipantListener) OnLeave(arg1 types.LocalParticipant, arg2 types.ParticipantCloseReason) {
 	fake.onLeaveMutex.Lock()
 	fake.onLeaveArgsForCall = append(fake.onLeaveArgsForCall, struct {
@@ -462,6 +511,72 @@ func (fake *FakeLocalParticipantListener) OnParticipantUpdateArgsForCall(i int)
 	return argsForCall.arg1
 }
 
+func (fake *FakeLocalParticipantListener) OnPublishPermissionRequest(arg1 types.LocalParticipant, arg2 *types.PublishPermissionRequest) {
+	fake.onPublishPermissionRequestMutex.Lock()
+	fake.onPublishPermissionRequestArgsForCall = append(fake.onPublishPermissionRequestArgsForCall, struct {
+		arg1 types.LocalParticipant
+		arg2 *types.PublishPermissionRequest
+	}{arg1, arg2})
+	stub := fake.OnPublishPermissionRequestStub
+	fake.recordInvocation("OnPublishPermissionRequest", []interface{}{arg1, arg2})
+	fake.onPublishPermissionRequestMutex.Unlock()
+	if stub != nil {
+		fake.OnPublishPermissionRequestStub(arg1, arg2)
+	}
+}
+
+func (fake *FakeLocalParticipantListener) OnPublishPermissionRequestCallCount() int {
+	fake.onPublishPermissionRequestMutex.RLock()
+	defer fake.onPublishPermissionRequestMutex.RUnlock()
+	return len(fake.onPublishPermissionRequestArgsForCall)
+}
+
+func (fake *FakeLocalParticipantListener) OnPublishPermissionRequestCalls(stub func(types.LocalParticipant, *types.PublishPermissionRequest)) {
+	fake.onPublishPermissionRequestMutex.Lock()
+	defer fake.onPublishPermissionRequestMutex.Unlock()
+	fake.OnPublishPermissionRequestStub = stub
+}
+
+func (fake *FakeLocalParticipantListener) OnPublishPermissionRequestArgsForCall(i int) (types.LocalParticipant, *types.PublishPermissionRequest) {
+	fake.onPublishPermissionRequestMutex.RLock()
+	defer fake.onPublishPermissionRequestMutex.RUnlock()
+	argsForCall := fake.onPublishPermissionRequestArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeLocalParticipantListener) OnRoomStateRequest(arg1 types.LocalParticipant, arg2 *types.RoomStateRequest) {
+	fake.onRoomStateRequestMutex.Lock()
+	fake.onRoomStateRequestArgsForCall = append(fake.onRoomStateRequestArgsForCall, struct {
+		arg1 types.LocalParticipant
+		arg2 *types.RoomStateRequest
+	}{arg1, arg2})
+	stub := fake.OnRoomStateRequestStub
+	fake.recordInvocation("OnRoomStateRequest", []interface{}{arg1, arg2})
+	fake.onRoomStateRequestMutex.Unlock()
+	if stub != nil {
+		fake.OnRoomStateRequestStub(arg1, arg2)
+	}
+}
+
+func (fake *FakeLocalParticipantListener) OnRoomStateRequestCallCount() int {
+	fake.onRoomStateRequestMutex.RLock()
+	defer fake.onRoomStateRequestMutex.RUnlock()
+	return len(fake.onRoomStateRequestArgsForCall)
+}
+
+func (fake *FakeLocalParticipantListener) OnRoomStateRequestCalls(stub func(types.LocalParticipant, *types.RoomStateRequest)) {
+	fake.onRoomStateRequestMutex.Lock()
+	defer fake.onRoomStateRequestMutex.Unlock()
+	fake.OnRoomStateRequestStub = stub
+}
+
+func (fake *FakeLocalParticipantListener) OnRoomStateRequestArgsForCall(i int) (types.LocalParticipant, *types.RoomStateRequest) {
+	fake.onRoomStateRequestMutex.RLock()
+	defer fake.onRoomStateRequestMutex.RUnlock()
+	argsForCall := fake.onRoomStateRequestArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
 func (fake *FakeLocalParticipantListener) OnSimulateScenario(arg1 types.LocalParticipant, arg2 *livekit.SimulateScenario) error {
 	fake.onSimulateScenarioMutex.Lock()
 	ret, specificReturn := fake.onSimulateScenarioReturnsOnCall[len(fake.onSimulateScenarioArgsForCall)]
@@ -147,11 +147,11 @@ type FakeParticipant struct {
 		arg2 *datatrack.Packet
 		arg3 int64
 	}
-	HasPermissionStub        func(livekit.TrackID, livekit.ParticipantIdentity) bool
+	HasPermissionStub        func(livekit.TrackID, types.LocalParticipant) bool
 	hasPermissionMutex       sync.RWMutex
 	hasPermissionArgsForCall []struct {
 		arg1 livekit.TrackID
-		arg2 livekit.ParticipantIdentity
+		arg2 types.LocalParticipant
 	}
 	hasPermissionReturns struct {
 		result1 bool
@@ -159,6 +159,16 @@ type FakeParticipant struct {
 	hasPermissionReturnsOnCall map[int]struct {
 		result1 bool
 	}
+	HasSubscriptionRulesStub        func() bool
+	hasSubscriptionRulesMutex       sync.RWMutex
+	hasSubscriptionRulesArgsForCall []struct {
+	}
+	hasSubscriptionRulesReturns struct {
+		result1 bool
+	}
+	hasSubscriptionRulesReturnsOnCall map[int]struct {
+		result1 bool
+	}
 	HiddenStub        func() bool
 	hiddenMutex       sync.RWMutex
 	hiddenArgsForCall []struct {
@@ -1055,12 +1065,12 @@ func (fake *FakeParticipant) HandleReceivedDataTrackMessageArgsForCall(i int) ([
 	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
-func (fake *FakeParticipant) HasPermission(arg1 livekit.TrackID, arg2 livekit.ParticipantIdentity) bool {
+func (fake *FakeParticipant) HasPermission(arg1 livekit.TrackID, arg2 types.LocalParticipant) bool {
 	fake.hasPermissionMutex.Lock()
 	ret, specificReturn := fake.hasPermissionReturnsOnCall[len(fake.hasPermissionArgsForCall)]
 	fake.hasPermissionArgsForCall = append(fake.hasPermissionArgsForCall, struct {
 		arg1 livekit.TrackID
-		arg2 livekit.ParticipantIdentity
+		arg2 types.LocalParticipant
 	}{arg1, arg2})
 	stub := fake.HasPermissionStub
 	fakeReturns := fake.hasPermissionReturns
@@ -1081,13 +1091,13 @@ func (fake *FakeParticipant) HasPermissionCallCount() int {
 	return len(fake.hasPermissionArgsForCall)
 }
 
-func (fake *FakeParticipant) HasPermissionCalls(stub func(livekit.TrackID, livekit.ParticipantIdentity) bool) {
+func (fake *FakeParticipant) HasPermissionCalls(stub func(livekit.TrackID, types.LocalParticipant) bool) {
 	fake.hasPermissionMutex.Lock()
 	defer fake.hasPermissionMutex.Unlock()
 	fake.HasPermissionStub = stub
 }
 
-func (fake *FakeParticipant) HasPermissionArgsForCall(i int) (livekit.TrackID, livekit.ParticipantIdentity) {
+func (fake *FakeParticipant) HasPermissionArgsForCall(i int) (livekit.TrackID, types.LocalParticipant) {
 	fake.hasPermissionMutex.RLock()
 	defer fake.hasPermissionMutex.RUnlock()
 	argsForCall := fake.hasPermissionArgsForCall[i]
@@ -1117,6 +1127,59 @@ func (fake *FakeParticipant) HasPermissionReturnsOnCall(i int, result1 bool) {
 	}{result1}
 }
 
+func (fake *FakeParticipant) HasSubscriptionRules() bool {
+	fake.hasSubscriptionRulesMutex.Lock()
+	ret, specificReturn := fake.hasSubscriptionRulesReturnsOnCall[len(fake.hasSubscriptionRulesArgsForCall)]
+	fake.hasSubscriptionRulesArgsForCall = append(fake.hasSubscriptionRulesArgsForCall, struct {
+	}{})
+	stub := fake.HasSubscriptionRulesStub
+	fakeReturns := fake.hasSubscriptionRulesReturns
+	fake.recordInvocation("HasSubscriptionRules", []interface{}{})
+	fake.hasSubscriptionRulesMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeParticipant) HasSubscriptionRulesCallCount() int {
+	fake.hasSubscriptionRulesMutex.RLock()
+	defer fake.hasSubscriptionRulesMutex.RUnlock()
+	return len(fake.hasSubscriptionRulesArgsForCall)
+}
+
+func (fake *FakeParticipant) HasSubscriptionRulesCalls(stub func() bool) {
+	fake.hasSubscriptionRulesMutex.Lock()
+	defer fake.hasSubscriptionRulesMutex.Unlock()
+	fake.HasSubscriptionRulesStub = stub
+}
+
+func (fake *FakeParticipant) HasSubscriptionRulesReturns(result1 bool) {
+	fake.hasSubscriptionRulesMutex.Lock()
+	defer fake.hasSubscriptionRulesMutex.Unlock()
+	fake.HasSubscriptionRulesStub = nil
+	fake.hasSubscriptionRulesReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeParticipant) HasSubscriptionRulesReturnsOnCall(i int, result1 bool) {
+	fake.hasSubscriptionRulesMutex.Lock()
+	defer fake.hasSubscriptionRulesMutex.Unlock()
+	fake.HasSubscriptionRulesStub = nil
+	if fake.hasSubscriptionRulesReturnsOnCall == nil {
+		fake.hasSubscriptionRulesReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.hasSubscriptionRulesReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
 func (fake *FakeParticipant) Hidden() bool {
 	fake.hiddenMutex.Lock()
 	ret, specificReturn := fake.hiddenReturnsOnCall[len(fake.hiddenArgsForCall)]
@@ -50,6 +50,8 @@ type UpTrackManager struct {
 	subscriptionPermission *livekit.SubscriptionPermission
 	// subscriber permission for published tracks
 	subscriberPermissions map[livekit.ParticipantIdentity]*livekit.TrackPermission // subscriberIdentity => *livekit.TrackPermission
+	// subscriber permission for published tracks granted by rules over subscriber attributes
+	subscriberRules []*subscriberRulePermission
 
 	lock sync.RWMutex
 
@@ -58,6 +60,11 @@ type UpTrackManager struct {
 	onTrackUpdated func(track types.MediaTrack)
 }
 
+type subscriberRulePermission struct {
+	rule  *SubscriptionRule
+	perms *livekit.TrackPermission
+}
+
 func NewUpTrackManager(params UpTrackManagerParams) *UpTrackManager {
 	return &UpTrackManager{
 		params:          params,
@@ -227,11 +234,19 @@ func (u *UpTrackManager) SubscriptionPermission() (*livekit.SubscriptionPermissi
 	return u.subscriptionPermission, u.subscriptionPermissionVersion.Load()
 }
 
-func (u *UpTrackManager) HasPermission(trackID livekit.TrackID, subIdentity livekit.ParticipantIdentity) bool {
+func (u *UpTrackManager) HasPermission(trackID livekit.TrackID, sub types.LocalParticipant) bool {
 	u.lock.RLock()
 	defer u.lock.RUnlock()
 
-	return u.hasPermissionLocked(trackID, subIdentity)
+	return u.hasPermissionLocked(trackID, sub.Identity()) || u.hasRulePermissionLocked(trackID, sub)
+}
+
+// HasSubscriptionRules returns true when subscription permission depends on attributes of subscribers
+func (u *UpTrackManager) HasSubscriptionRules() bool {
+	u.lock.RLock()
+	defer u.lock.RUnlock()
+
+	return len(u.subscriberRules) != 0
 }
 
 func (u *UpTrackManager) UpdatePublishedAudioTrack(update *livekit.UpdateLocalAudioTrack) types.MediaTrack {
@@ -296,13 +311,27 @@ func (u *UpTrackManager) parseSubscriptionPermissionsLocked(
 	if subscriptionPermission.AllParticipants {
 		// everything is allowed, nothing else to do
 		u.subscriberPermissions = nil
+		u.subscriberRules = nil
 		return nil
 	}
 
 	// per participant permissions
 	subscriberPermissions := make(map[livekit.ParticipantIdentity]*livekit.TrackPermission)
+	var subscriberRules []*subscriberRulePermission
 	for _, trackPerms := range subscriptionPermission.TrackPermissions {
 		subscriberIdentity := livekit.ParticipantIdentity(trackPerms.ParticipantIdentity)
+		if IsSubscriptionRule(trackPerms.ParticipantIdentity) {
+			rule, err := ParseSubscriptionRule(trackPerms.ParticipantIdentity)
+			if err != nil {
+				return err
+			}
+
+			subscriberRules = append(subscriberRules, &subscriberRulePermission{
+				rule:  rule,
+				perms: trackPerms,
+			})
+			continue
+		}
 		if subscriberIdentity == "" {
 			if trackPerms.ParticipantSid == "" {
 				return ErrSubscriptionPermissionNeedsId
@@ -331,6 +360,7 @@ func (u *UpTrackManager) parseSubscriptionPermissionsLocked(
 	}
 
 	u.subscriberPermissions = subscriberPermissions
+	u.subscriberRules = subscriberRules
 
 	return nil
 }
@@ -345,12 +375,12 @@ func (u *UpTrackManager) hasPermissionLocked(trackID livekit.TrackID, subscriber
 		return false
 	}
 
-	if perms.AllTracks {
-		return true
-	}
+	return trackPermissionIncludes(perms, trackID)
+}
 
-	for _, sid := range perms.TrackSids {
-		if livekit.TrackID(sid) == trackID {
+func (u *UpTrackManager) hasRulePermissionLocked(trackID livekit.TrackID, sub types.LocalParticipant) bool {
+	for _, rp := range u.subscriberRules {
+		if trackPermissionIncludes(rp.perms, trackID) && rp.rule.Matches(sub) {
 			return true
 		}
 	}
@@ -358,43 +388,33 @@ func (u *UpTrackManager) hasPermissionLocked(trackID livekit.TrackID, subscriber
 	return false
 }
 
-// returns a list of participants that are allowed to subscribe to the track. if nil is returned, it means everyone is
-// allowed to subscribe to this track
-func (u *UpTrackManager) getAllowedSubscribersLocked(trackID livekit.TrackID) []livekit.ParticipantIdentity {
-	if u.subscriberPermissions == nil {
-		return nil
+func trackPermissionIncludes(perms *livekit.TrackPermission, trackID livekit.TrackID) bool {
+	if perms.AllTracks {
+		return true
 	}
 
-	allowed := make([]livekit.ParticipantIdentity, 0)
-	for subscriberIdentity, perms := range u.subscriberPermissions {
-		if perms.AllTracks {
-			allowed = append(allowed, subscriberIdentity)
-			continue
-		}
-
-		for _, sid := range perms.TrackSids {
-			if livekit.TrackID(sid) == trackID {
-				allowed = append(allowed, subscriberIdentity)
-				break
-			}
+	for _, sid := range perms.TrackSids {
+		if livekit.TrackID(sid) == trackID {
+			return true
 		}
 	}
 
-	return allowed
+	return false
 }
 
 func (u *UpTrackManager) maybeRevokeSubscriptions() {
 	u.lock.Lock()
 	defer u.lock.Unlock()
 
-	for trackID, track := range u.publishedTracks {
-		allowed := u.getAllowedSubscribersLocked(trackID)
-		if allowed == nil {
-			// no restrictions
-			continue
-		}
+	if u.subscriberPermissions == nil {
+		// no restrictions
+		return
+	}
 
-		track.RevokeDisallowedSubscribers(allowed)
+	for trackID, track := range u.publishedTracks {
+		track.RevokeDisallowedSubscribers(func(sub types.LocalParticipant) bool {
+			return u.hasPermissionLocked(trackID, sub.Identity()) || u.hasRulePermissionLocked(trackID, sub)
+		})
 	}
 }
 
@@ -19,6 +19,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/utils"
@@ -327,3 +328,73 @@ func TestSubscriptionPermission(t *testing.T) {
 		require.False(t, um.hasPermissionLocked("watch", "p3"))
 	})
 }
+
+func TestSubscriptionRulePermission(t *testing.T) {
+	um := NewUpTrackManager(defaultUptrackManagerParams)
+	vg := utils.NewDefaultTimedVersionGenerator()
+
+	tra := &typesfakes.FakeMediaTrack{}
+	tra.IDReturns("audio")
+	um.publishedTracks["audio"] = tra
+
+	trv := &typesfakes.FakeMediaTrack{}
+	trv.IDReturns("video")
+	um.publishedTracks["video"] = trv
+
+	moderator := newRuleSubscriber("p1", livekit.ParticipantInfo_STANDARD, map[string]string{"role": "moderator"}, nil)
+	interpreter := newRuleSubscriber("p2", livekit.ParticipantInfo_STANDARD, map[string]string{"role": "interpreter"}, nil)
+	viewer := newRuleSubscriber("p3", livekit.ParticipantInfo_STANDARD, nil, nil)
+
+	subscriptionPermission := &livekit.SubscriptionPermission{
+		TrackPermissions: []*livekit.TrackPermission{
+			{
+				ParticipantIdentity: "lk.rule:role in [moderator, interpreter]",
+				TrackSids:           []string{"audio"},
+			},
+			{
+				ParticipantIdentity: "lk.rule:role == moderator",
+				AllTracks:           true,
+			},
+			{
+				ParticipantIdentity: "p3",
+				TrackSids:           []string{"video"},
+			},
+		},
+	}
+	require.NoError(t, um.UpdateSubscriptionPermission(subscriptionPermission, vg.Next(), nil))
+	require.True(t, um.HasSubscriptionRules())
+
+	require.True(t, um.HasPermission("audio", moderator))
+	require.True(t, um.HasPermission("video", moderator))
+	require.True(t, um.HasPermission("audio", interpreter))
+	require.False(t, um.HasPermission("video", interpreter))
+	require.False(t, um.HasPermission("audio", viewer))
+	require.True(t, um.HasPermission("video", viewer))
+
+	// subscribers are revoked based on their attributes
+	require.Equal(t, 1, tra.RevokeDisallowedSubscribersCallCount())
+	isAllowed := tra.RevokeDisallowedSubscribersArgsForCall(0)
+	require.True(t, isAllowed(interpreter))
+	require.False(t, isAllowed(viewer))
+
+	// attribute changes are reflected without a permission update
+	interpreter.ClaimGrantsReturns(&auth.ClaimGrants{Attributes: map[string]string{"role": "viewer"}})
+	require.False(t, um.HasPermission("audio", interpreter))
+
+	// invalid rules do not override existing permission
+	subscriptionPermission = &livekit.SubscriptionPermission{
+		TrackPermissions: []*livekit.TrackPermission{
+			{
+				ParticipantIdentity: "lk.rule:role",
+				AllTracks:           true,
+			},
+		},
+	}
+	require.ErrorIs(t, um.UpdateSubscriptionPermission(subscriptionPermission, vg.Next(), nil), ErrInvalidSubscriptionRule)
+	require.True(t, um.HasPermission("audio", moderator))
+
+	// all participants clears rules
+	require.NoError(t, um.UpdateSubscriptionPermission(&livekit.SubscriptionPermission{AllParticipants: true}, vg.Next(), nil))
+	require.False(t, um.HasSubscriptionRules())
+	require.True(t, um.HasPermission("audio", viewer))
+}
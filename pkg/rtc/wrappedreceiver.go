@@ -332,6 +332,13 @@ func (d *DummyReceiver) SendPLI(layer int32, force bool) {
 	}
 }
 
+func (d *DummyReceiver) PrimeKeyFrame(layer int32, track sfu.TrackSender) bool {
+	if receiver := d.getReceiver(); receiver != nil {
+		return receiver.PrimeKeyFrame(layer, track)
+	}
+	return false
+}
+
 func (d *DummyReceiver) SetUpTrackPaused(paused bool) {
 	d.settingsLock.Lock()
 	receiver := d.getReceiver()
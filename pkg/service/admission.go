@@ -0,0 +1,306 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/stdlib"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+var (
+	ErrAdmissionDenied = errors.New("join denied by admission policy")
+	ErrAdmissionFailed = errors.New("admission policy failed")
+)
+
+// modules admission scripts can import, modules with side effects (os, fmt) are left out
+var admissionModules = []string{"text", "math", "json", "enum", "times"}
+
+// AdmissionRequest describes a participant joining a room, it is the JSON body POSTed to the admission URL
+// and the request variable of admission scripts
+type AdmissionRequest struct {
+	Room string `json:"room"`
+	// grants of the token, room configuration is left out as it may hold credentials
+	Claims *auth.ClaimGrants `json:"claims"`
+	Client *AdmissionClient  `json:"client,omitempty"`
+	IP     string            `json:"ip,omitempty"`
+	// ISO country code of the client, from the header of a trusted proxy when one is configured (see AdmissionConfig.CountryHeader)
+	Country string `json:"country,omitempty"`
+	// region of the node the participant connected to
+	Region string `json:"region,omitempty"`
+	// reconnect of a participant no longer in the room, those resuming their session are not decided on again
+	Reconnect bool                `json:"reconnect,omitempty"`
+	RoomState *AdmissionRoomState `json:"room_state"`
+}
+
+type AdmissionClient struct {
+	SDK            string `json:"sdk,omitempty"`
+	Version        string `json:"version,omitempty"`
+	Protocol       int32  `json:"protocol,omitempty"`
+	OS             string `json:"os,omitempty"`
+	OSVersion      string `json:"os_version,omitempty"`
+	DeviceModel    string `json:"device_model,omitempty"`
+	Browser        string `json:"browser,omitempty"`
+	BrowserVersion string `json:"browser_version,omitempty"`
+}
+
+// AdmissionRoomState is the state of the room being joined, as last reported by the node hosting it
+type AdmissionRoomState struct {
+	Exists          bool   `json:"exists"`
+	NumParticipants uint32 `json:"num_participants"`
+	NumPublishers   uint32 `json:"num_publishers"`
+	MaxParticipants uint32 `json:"max_participants"`
+	Metadata        string `json:"metadata,omitempty"`
+}
+
+// AdmissionDecision is the response of the admission URL and the decision variable of admission scripts.
+// Empty, the join is admitted as granted by the token. Permissions, metadata and attributes which are set
+// override those of the token, attributes are merged.
+type AdmissionDecision struct {
+	Deny           bool              `json:"deny,omitempty"`
+	Reason         string            `json:"reason,omitempty"`
+	CanPublish     *bool             `json:"can_publish,omitempty"`
+	CanPublishData *bool             `json:"can_publish_data,omitempty"`
+	CanSubscribe   *bool             `json:"can_subscribe,omitempty"`
+	Hidden         *bool             `json:"hidden,omitempty"`
+	Metadata       *string           `json:"metadata,omitempty"`
+	Attributes     map[string]string `json:"attributes,omitempty"`
+}
+
+// Apply returns grants modified by the decision
+func (d *AdmissionDecision) Apply(grants *auth.ClaimGrants) *auth.ClaimGrants {
+	grants = grants.Clone()
+	if grants.Video == nil {
+		grants.Video = &auth.VideoGrant{}
+	}
+	if d.CanPublish != nil {
+		grants.Video.SetCanPublish(*d.CanPublish)
+	}
+	if d.CanPublishData != nil {
+		grants.Video.SetCanPublishData(*d.CanPublishData)
+	}
+	if d.CanSubscribe != nil {
+		grants.Video.SetCanSubscribe(*d.CanSubscribe)
+	}
+	if d.Hidden != nil {
+		grants.Video.Hidden = *d.Hidden
+	}
+	if d.Metadata != nil {
+		grants.Metadata = *d.Metadata
+	}
+	if len(d.Attributes) != 0 {
+		if grants.Attributes == nil {
+			grants.Attributes = make(map[string]string, len(d.Attributes))
+		}
+		for k, v := range d.Attributes {
+			grants.Attributes[k] = v
+		}
+	}
+	return grants
+}
+
+// AdmissionHook decides on participants joining rooms, after their token has been validated.
+// Hooks are configured with a tengo script reading request and filling decision, e.g.
+//
+//	if request.country == "XX" {
+//	  decision.deny = true
+//	  decision.reason = "not available in your country"
+//	} else if request.client.sdk == "js" && request.room_state.num_participants > 100 {
+//	  decision.can_publish = false
+//	  decision.attributes = {tier: "audience"}
+//	}
+//
+// or with a URL, which is POSTed the request as JSON and responds with the decision.
+//
+//counterfeiter:generate . AdmissionHook
+type AdmissionHook interface {
+	Admit(ctx context.Context, req *AdmissionRequest) (*AdmissionDecision, error)
+}
+
+// NewAdmissionHook creates the hook configured, nil when admission is not enabled
+func NewAdmissionHook(conf *config.Config) (AdmissionHook, error) {
+	ac := conf.Admission
+	switch {
+	case ac.Script != "" && ac.URL != "":
+		return nil, errors.New("admission can be decided by a script or a URL, not both")
+	case ac.Script != "":
+		return NewScriptAdmissionHook(ac.Script)
+	case ac.URL != "":
+		return NewHTTPAdmissionHook(ac.URL, http.DefaultClient), nil
+	default:
+		return nil, nil
+	}
+}
+
+// ------------------------------------------------
+
+type ScriptAdmissionHook struct {
+	compiled *tengo.Compiled
+}
+
+func NewScriptAdmissionHook(src string) (*ScriptAdmissionHook, error) {
+	script := tengo.NewScript([]byte(src))
+	script.SetImports(stdlib.GetModuleMap(admissionModules...))
+	if err := script.Add("request", &tengo.ImmutableMap{}); err != nil {
+		return nil, err
+	}
+	if err := script.Add("decision", &tengo.Map{}); err != nil {
+		return nil, err
+	}
+	compiled, err := script.Compile()
+	if err != nil {
+		return nil, fmt.Errorf("invalid admission script: %w", err)
+	}
+	return &ScriptAdmissionHook{compiled}, nil
+}
+
+func (h *ScriptAdmissionHook) Admit(ctx context.Context, req *AdmissionRequest) (*AdmissionDecision, error) {
+	// the script sees the request as the URL would
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var reqMap map[string]any
+	if err := json.Unmarshal(data, &reqMap); err != nil {
+		return nil, err
+	}
+	reqObj, err := tengo.FromInterface(reqMap)
+	if err != nil {
+		return nil, err
+	}
+
+	clone := h.compiled.Clone()
+	if err := clone.Set("request", &tengo.ImmutableMap{Value: reqObj.(*tengo.Map).Value}); err != nil {
+		return nil, err
+	}
+	if err := clone.Set("decision", &tengo.Map{Value: map[string]tengo.Object{}}); err != nil {
+		return nil, err
+	}
+	if err := clone.RunContext(ctx); err != nil {
+		return nil, err
+	}
+
+	decisionMap := clone.Get("decision").Map()
+	if decisionMap == nil {
+		return nil, errors.New("decision is not a map")
+	}
+	if data, err = json.Marshal(decisionMap); err != nil {
+		return nil, err
+	}
+	decision := &AdmissionDecision{}
+	if err := json.Unmarshal(data, decision); err != nil {
+		return nil, fmt.Errorf("invalid decision: %w", err)
+	}
+	return decision, nil
+}
+
+// ------------------------------------------------
+
+type HTTPAdmissionHook struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPAdmissionHook(url string, client *http.Client) *HTTPAdmissionHook {
+	return &HTTPAdmissionHook{url: url, client: client}
+}
+
+func (h *HTTPAdmissionHook) Admit(ctx context.Context, req *AdmissionRequest) (*AdmissionDecision, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	res, err := h.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	decision := &AdmissionDecision{}
+	if len(bytes.TrimSpace(data)) != 0 {
+		if err := json.Unmarshal(data, decision); err != nil {
+			return nil, fmt.Errorf("invalid decision: %w", err)
+		}
+	}
+	return decision, nil
+}
+
+// ------------------------------------------------
+
+func newAdmissionRequest(
+	r *http.Request,
+	countryHeader string,
+	roomName livekit.RoomName,
+	region string,
+	grants *auth.ClaimGrants,
+	ci *livekit.ClientInfo,
+	reconnect bool,
+) *AdmissionRequest {
+	claims := grants.Clone()
+	claims.RoomConfig = nil
+	claims.Sha256 = ""
+
+	req := &AdmissionRequest{
+		Room:      string(roomName),
+		Claims:    claims,
+		IP:        GetClientIP(r),
+		Region:    region,
+		Reconnect: reconnect,
+		RoomState: &AdmissionRoomState{},
+	}
+	if countryHeader != "" {
+		// clients can set any header, it is only trusted when set by the proxy in front of the server
+		req.Country = r.Header.Get(countryHeader)
+	}
+	if ci != nil {
+		req.Client = &AdmissionClient{
+			SDK:            strings.ToLower(ci.Sdk.String()),
+			Version:        ci.Version,
+			Protocol:       ci.Protocol,
+			OS:             strings.ToLower(ci.Os),
+			OSVersion:      ci.OsVersion,
+			DeviceModel:    ci.DeviceModel,
+			Browser:        strings.ToLower(ci.Browser),
+			BrowserVersion: ci.BrowserVersion,
+		}
+	}
+	return req
+}
@@ -0,0 +1,137 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestAdmissionHook(t *testing.T) {
+	newRequest := func(role string, country string, numParticipants uint32) *service.AdmissionRequest {
+		return &service.AdmissionRequest{
+			Room: "event",
+			Claims: &auth.ClaimGrants{
+				Identity:   "p1",
+				Video:      &auth.VideoGrant{RoomJoin: true, Room: "event"},
+				Attributes: map[string]string{"role": role},
+			},
+			Client:    &service.AdmissionClient{SDK: "js"},
+			Country:   country,
+			RoomState: &service.AdmissionRoomState{Exists: true, NumParticipants: numParticipants, MaxParticipants: 20},
+		}
+	}
+
+	t.Run("script decides on joins", func(t *testing.T) {
+		hook, err := service.NewScriptAdmissionHook(`
+state := request.room_state
+if request.country == "XX" {
+  decision.deny = true
+  decision.reason = "geofenced"
+} else if state.num_participants >= state.max_participants - 5 && request.claims.attributes.role != "host" {
+  decision.can_publish = false
+  decision.hidden = true
+  decision.attributes = {tier: "overflow"}
+}`)
+		require.NoError(t, err)
+
+		decision, err := hook.Admit(context.Background(), newRequest("viewer", "XX", 0))
+		require.NoError(t, err)
+		require.True(t, decision.Deny)
+		require.Equal(t, "geofenced", decision.Reason)
+
+		decision, err = hook.Admit(context.Background(), newRequest("host", "", 18))
+		require.NoError(t, err)
+		require.Equal(t, &service.AdmissionDecision{}, decision)
+
+		req := newRequest("viewer", "", 18)
+		decision, err = hook.Admit(context.Background(), req)
+		require.NoError(t, err)
+		require.False(t, decision.Deny)
+
+		grants := decision.Apply(req.Claims)
+		require.False(t, grants.Video.GetCanPublish())
+		require.True(t, grants.Video.GetCanSubscribe())
+		require.True(t, grants.Video.Hidden)
+		require.Equal(t, map[string]string{"role": "viewer", "tier": "overflow"}, grants.Attributes)
+		// grants of the request are left as they are
+		require.False(t, req.Claims.Video.Hidden)
+	})
+
+	t.Run("invalid script is rejected", func(t *testing.T) {
+		_, err := service.NewScriptAdmissionHook("decision.deny = ")
+		require.Error(t, err)
+	})
+
+	t.Run("script is stopped at the deadline", func(t *testing.T) {
+		hook, err := service.NewScriptAdmissionHook("for {}")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err = hook.Admit(ctx, newRequest("viewer", "", 0))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("url decides on joins", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req service.AdmissionRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			if req.Claims.Attributes["role"] == "banned" {
+				_, _ = w.Write([]byte(`{"deny": true, "reason": "banned"}`))
+			} else if req.RoomState.NumParticipants > 10 {
+				_, _ = w.Write([]byte(`{"can_publish": false}`))
+			}
+		}))
+		defer server.Close()
+
+		hook, err := service.NewAdmissionHook(&config.Config{Admission: config.AdmissionConfig{URL: server.URL}})
+		require.NoError(t, err)
+
+		decision, err := hook.Admit(context.Background(), newRequest("banned", "", 0))
+		require.NoError(t, err)
+		require.True(t, decision.Deny)
+
+		decision, err = hook.Admit(context.Background(), newRequest("viewer", "", 0))
+		require.NoError(t, err)
+		require.Equal(t, &service.AdmissionDecision{}, decision)
+
+		decision, err = hook.Admit(context.Background(), newRequest("viewer", "", 11))
+		require.NoError(t, err)
+		require.NotNil(t, decision.CanPublish)
+		require.False(t, *decision.CanPublish)
+	})
+
+	t.Run("url failures are errors", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		_, err := service.NewHTTPAdmissionHook(server.URL, http.DefaultClient).Admit(context.Background(), newRequest("viewer", "", 0))
+		require.Error(t, err)
+	})
+}
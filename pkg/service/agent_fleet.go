@@ -0,0 +1,172 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+	"github.com/livekit/protocol/livekit"
+)
+
+// AgentFleetStats describes the workers of an agent registered on this node, across job types
+type AgentFleetStats struct {
+	AgentName        string  `json:"agent_name"`
+	Namespace        string  `json:"namespace"`
+	Workers          int     `json:"workers"`
+	AvailableWorkers int     `json:"available_workers"`
+	Load             float64 `json:"load"`
+	RunningJobs      int     `json:"running_jobs"`
+	// jobs whose requests found no worker within the demand window, a job retried while queued counts once
+	UnmetJobRequests   int `json:"unmet_job_requests"`
+	RecommendedWorkers int `json:"recommended_workers"`
+}
+
+type fleetKey struct {
+	agentName string
+	namespace string
+}
+
+// FleetStats returns the stats of every agent with workers or unmet demand on this node
+func (h *AgentHandler) FleetStats() []AgentFleetStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	keys := make(map[fleetKey]struct{})
+	for _, w := range h.workers {
+		keys[fleetKey{w.AgentName, w.Namespace}] = struct{}{}
+	}
+	for key := range h.unmetDemand {
+		keys[key] = struct{}{}
+	}
+
+	stats := make([]AgentFleetStats, 0, len(keys))
+	for key := range keys {
+		stats = append(stats, h.fleetStatsLocked(key))
+	}
+	slices.SortFunc(stats, func(a, b AgentFleetStats) int {
+		if c := strings.Compare(a.AgentName, b.AgentName); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Namespace, b.Namespace)
+	})
+	return stats
+}
+
+// ServeScaling reports the fleet stats along with a recommended worker count on GET, filtered by
+// the agent_name and namespace query parameters. Workers connect to a single node, an autoscaler
+// sums the recommendations of all nodes and bounds the sum by the reported min_workers and max_workers.
+func (h *AgentHandler) ServeScaling(w http.ResponseWriter, r *http.Request) {
+	if err := EnsureListPermission(r.Context()); err != nil {
+		HandleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	stats := slices.DeleteFunc(h.FleetStats(), func(s AgentFleetStats) bool {
+		if query.Has("agent_name") && s.AgentName != query.Get("agent_name") {
+			return true
+		}
+		return query.Has("namespace") && s.Namespace != query.Get("namespace")
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		Agents     []AgentFleetStats `json:"agents"`
+		MinWorkers int               `json:"min_workers"`
+		MaxWorkers int               `json:"max_workers"`
+	}{stats, h.autoscaling.MinWorkers, h.autoscaling.MaxWorkers})
+}
+
+// recordUnmetDemand counts a job whose request found no worker, requests of the same job
+// (retries of a queued job) keep it in the demand window but are not counted again
+func (h *AgentHandler) recordUnmetDemand(job *livekit.Job) {
+	prometheus.RecordAgentJobRequestFailed(job.AgentName, job.Namespace, "no_worker")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := fleetKey{job.AgentName, job.Namespace}
+	unmet := h.unmetDemand[key]
+	if unmet == nil {
+		unmet = make(map[string]time.Time)
+		h.unmetDemand[key] = unmet
+	}
+	unmet[job.Id] = time.Now()
+	h.updateFleetMetricsLocked(key)
+}
+
+func (h *AgentHandler) updateFleetMetrics(agentName string, namespace string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.updateFleetMetricsLocked(fleetKey{agentName, namespace})
+}
+
+func (h *AgentHandler) updateFleetMetricsLocked(key fleetKey) {
+	stats := h.fleetStatsLocked(key)
+	if stats.Workers == 0 && stats.UnmetJobRequests == 0 {
+		prometheus.DeleteAgentFleetStats(key.agentName, key.namespace)
+		return
+	}
+	prometheus.SetAgentFleetStats(
+		key.agentName,
+		key.namespace,
+		stats.Workers,
+		stats.AvailableWorkers,
+		stats.Load,
+		stats.RunningJobs,
+		stats.RecommendedWorkers,
+	)
+}
+
+func (h *AgentHandler) fleetStatsLocked(key fleetKey) AgentFleetStats {
+	stats := AgentFleetStats{
+		AgentName: key.agentName,
+		Namespace: key.namespace,
+	}
+	for _, w := range h.workers {
+		if w.AgentName != key.agentName || w.Namespace != key.namespace {
+			continue
+		}
+		stats.Workers++
+		if w.HasCapacity() {
+			stats.AvailableWorkers++
+		}
+		stats.Load += float64(w.Load())
+		stats.RunningJobs += w.RunningJobCount()
+	}
+
+	cutoff := time.Now().Add(-h.autoscaling.DemandWindow)
+	unmet := h.unmetDemand[key]
+	maps.DeleteFunc(unmet, func(_ string, t time.Time) bool { return t.Before(cutoff) })
+	if len(unmet) == 0 {
+		delete(h.unmetDemand, key)
+	}
+	stats.UnmetJobRequests = len(unmet)
+
+	stats.RecommendedWorkers = h.autoscaling.RecommendWorkers(stats.Load, stats.RunningJobs, stats.UnmetJobRequests)
+	return stats
+}
@@ -0,0 +1,153 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/livekit-server/pkg/agent"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/protocol/utils"
+	"github.com/livekit/protocol/utils/guid"
+	"github.com/livekit/psrpc"
+)
+
+var (
+	ErrJobHandoffTimeout     = errors.New("agent of replacement job did not join in time")
+	ErrJobHandoffDeclined    = errors.New("replacement job declined by worker")
+	ErrJobHandoffEnded       = errors.New("job ended during handoff")
+	ErrJobHandoffUnavailable = errors.New("no worker available for replacement job")
+)
+
+// drainJobs hands off jobs of a draining worker to other workers, all of its running jobs when jobIDs is empty
+func (h *AgentHandler) drainJobs(w *agent.Worker, jobIDs []string) {
+	ids := make([]livekit.JobID, 0, len(jobIDs))
+	if len(jobIDs) == 0 {
+		for jobID := range w.RunningJobs() {
+			ids = append(ids, jobID)
+		}
+	} else {
+		for _, jobID := range jobIDs {
+			ids = append(ids, livekit.JobID(jobID))
+		}
+	}
+
+	for _, jobID := range ids {
+		go h.handoffJob(w, jobID)
+	}
+}
+
+// handoffJob assigns a replacement of the job to another worker and registers it with the room, which tracks it in place
+// of the job once the agent of the replacement joined. The job is then terminated, so the agent of the replacement is in
+// the room before the previous one leaves. The job stays on the draining worker when no worker takes the replacement or
+// its agent does not join in time.
+func (h *AgentHandler) handoffJob(w *agent.Worker, jobID livekit.JobID) {
+	job, err := w.GetJob(jobID)
+	if err != nil {
+		w.Logger().Infow("cannot hand off job", "error", err, "jobID", jobID)
+		return
+	}
+
+	replacement := utils.CloneProto(job)
+	replacement.Id = guid.New(guid.AgentJobPrefix)
+	replacement.State = nil
+	replacementID := livekit.JobID(replacement.Id)
+
+	logger := w.Logger().WithUnlikelyValues("jobID", jobID, "replacementJobID", replacementID)
+	logger.Infow("handing off job")
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.handoffTimeout)
+	defer cancel()
+
+	replacementWorker, state, err := h.assignJob(ctx, logger, replacement, map[*agent.Worker]struct{}{w: {}})
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = ErrJobHandoffTimeout
+		} else {
+			err = ErrJobHandoffUnavailable
+		}
+		h.handoffFailed(job, nil, err)
+		return
+	}
+	replacement = jobWithState(replacement, state)
+	if state.Status != livekit.JobStatus_JS_RUNNING {
+		h.handoffFailed(job, replacement, ErrJobHandoffDeclined)
+		return
+	}
+	if err = h.agentServer.RegisterJobTerminateTopic(replacement.Id); err != nil {
+		logger.Errorw("failed to register JobTerminate handler", err)
+	}
+
+	if err = h.registerReplacement(ctx, job, replacement); err == nil {
+		if _, err = w.GetJob(jobID); err != nil {
+			err = ErrJobHandoffEnded
+		}
+	}
+	if err != nil {
+		if _, terr := h.terminateJob(replacementWorker, replacementID, rpc.JobTerminateReason_TERMINATION_REQUESTED); terr != nil && !errors.Is(terr, agent.ErrJobNotFound) {
+			logger.Warnw("failed to terminate replacement job", terr)
+		}
+		h.handoffFailed(job, replacement, err)
+		return
+	}
+
+	// the agent of the replacement joined, let the previous one leave
+	if _, err = h.terminateJob(w, jobID, rpc.JobTerminateReason_TERMINATION_REQUESTED); err != nil {
+		logger.Warnw("failed to terminate handed off job", err)
+	}
+
+	logger.Infow("handed off job", "replacementWorkerID", replacementWorker.ID)
+	h.telemetry.AgentJobHandoff(context.Background(), job, replacement, nil)
+}
+
+// registerReplacement asks the room to track the replacement in place of the job, once the agent of the replacement joined
+func (h *AgentHandler) registerReplacement(ctx context.Context, job *livekit.Job, replacement *livekit.Job) error {
+	data, err := protojson.Marshal(replacement)
+	if err != nil {
+		return err
+	}
+	req, err := toStruct(&AgentJobHandoffRequest{
+		Room:        job.GetRoom().GetName(),
+		JobID:       job.Id,
+		Replacement: data,
+	})
+	if err != nil {
+		return err
+	}
+
+	var opts []psrpc.RequestOption
+	if deadline, ok := ctx.Deadline(); ok {
+		opts = append(opts, psrpc.WithRequestTimeout(time.Until(deadline)))
+	}
+	roomName := livekit.RoomName(job.GetRoom().GetName())
+	if _, err = h.roomClient.HandOffAgentJob(ctx, h.topicFormatter.RoomTopic(ctx, roomName), req, opts...); err != nil {
+		var perr psrpc.Error
+		if errors.As(err, &perr) && perr.Code() == psrpc.DeadlineExceeded {
+			return ErrJobHandoffTimeout
+		}
+		return err
+	}
+	return nil
+}
+
+func (h *AgentHandler) handoffFailed(job *livekit.Job, replacement *livekit.Job, err error) {
+	h.logger.Infow("job handoff failed, job stays on draining worker", "error", err, "jobID", job.Id, "workerID", job.GetState().GetWorkerId())
+	h.telemetry.AgentJobHandoff(context.Background(), job, replacement, err)
+}
@@ -0,0 +1,145 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/livekit-server/pkg/agent"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+)
+
+var ErrAgentJobHistoryDisabled = errors.New("agent job history is disabled")
+
+// AgentJobHistory keeps ended agent jobs for the agent jobs admin API, within the configured retention
+type AgentJobHistory struct {
+	store  AgentJobHistoryStore
+	config agent.JobHistoryConfig
+}
+
+func NewAgentJobHistory(store AgentJobHistoryStore, config agent.JobHistoryConfig) *AgentJobHistory {
+	return &AgentJobHistory{
+		store:  store,
+		config: config,
+	}
+}
+
+func (h *AgentJobHistory) Enabled() bool {
+	return h != nil && h.store != nil && h.config.MaxJobs > 0
+}
+
+// Record stores an ended job, it is a no-op when job history is disabled
+func (h *AgentJobHistory) Record(ctx context.Context, job *livekit.Job) {
+	if !h.Enabled() {
+		return
+	}
+
+	if err := h.store.StoreAgentJobHistory(ctx, job, h.config.MaxJobs, h.config.TTL); err != nil {
+		logger.Warnw("failed to store agent job history", err, "jobID", job.Id, "agentName", job.AgentName)
+	}
+}
+
+func (h *AgentJobHistory) ListJobs(ctx context.Context, filter AgentJobFilter) ([]*livekit.Job, error) {
+	if !h.Enabled() {
+		return nil, ErrAgentJobHistoryDisabled
+	}
+	return h.store.ListAgentJobHistory(ctx, filter)
+}
+
+// ServeHTTP lists ended jobs on GET, filtered by the room, agent_name, status and limit query parameters
+func (h *AgentJobHistory) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := EnsureListPermission(r.Context()); err != nil {
+		HandleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := AgentJobFilter{
+		RoomName:  livekit.RoomName(query.Get("room")),
+		AgentName: query.Get("agent_name"),
+	}
+	for _, s := range query["status"] {
+		status, ok := livekit.JobStatus_value[s]
+		if !ok {
+			HandleErrorJson(w, r, http.StatusBadRequest, errors.New("invalid job status: "+s))
+			return
+		}
+		filter.Statuses = append(filter.Statuses, livekit.JobStatus(status))
+	}
+	if l := query.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 0 {
+			HandleErrorJson(w, r, http.StatusBadRequest, errors.New("invalid limit: "+l))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	jobs, err := h.ListJobs(r.Context(), filter)
+	if errors.Is(err, ErrAgentJobHistoryDisabled) {
+		HandleErrorJson(w, r, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		HandleErrorJson(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := struct {
+		Jobs []json.RawMessage `json:"jobs"`
+	}{
+		Jobs: make([]json.RawMessage, 0, len(jobs)),
+	}
+	for _, job := range jobs {
+		data, err := protojson.Marshal(job)
+		if err != nil {
+			HandleErrorJson(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		res.Jobs = append(res.Jobs, data)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// agentJobHistoryEntry strips a job down to what is kept in the history
+func agentJobHistoryEntry(job *livekit.Job) *livekit.Job {
+	entry := utils.CloneProto(job)
+	if entry.Room != nil {
+		entry.Room = &livekit.Room{
+			Name: entry.Room.Name,
+			Sid:  entry.Room.Sid,
+		}
+	}
+	if entry.Participant != nil {
+		entry.Participant = &livekit.ParticipantInfo{
+			Identity: entry.Participant.Identity,
+		}
+	}
+	return entry
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/rtc"
 	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/livekit-server/version"
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
@@ -89,6 +90,11 @@ func (u AgentSocketUpgrader) Upgrade(
 	if pv, err := strconv.Atoi(r.FormValue("protocol")); err == nil {
 		registration.Protocol = agent.WorkerProtocolVersion(pv)
 	}
+	// used by worker selection strategies, workers which do not pass them are warned about when registering
+	registration.Region = r.FormValue("region")
+	if maxJobs, err := strconv.Atoi(r.FormValue("max_jobs")); err == nil && maxJobs > 0 {
+		registration.MaxJobs = maxJobs
+	}
 
 	return conn, registration, true
 }
@@ -143,8 +149,15 @@ type AgentHandler struct {
 	workers     map[string]*agent.Worker
 	jobToWorker map[livekit.JobID]*agent.Worker
 	keyProvider auth.KeyProvider
-
-	namespaceWorkers    map[workerKey][]*agent.Worker
+	telemetry   telemetry.TelemetryService
+	jobHistory  *AgentJobHistory
+
+	namespaceWorkers map[workerKey][]*agent.Worker
+	selection        agent.WorkerSelectionConfig
+	selectors        map[workerKey]agent.WorkerSelector
+	autoscaling      agent.AutoscalingConfig
+	// job ID => last request which found no worker
+	unmetDemand         map[fleetKey]map[string]time.Time
 	roomKeyCount        int
 	publisherKeyCount   int
 	participantKeyCount int
@@ -154,6 +167,11 @@ type AgentHandler struct {
 	roomTopic        string
 	publisherTopic   string
 	participantTopic string
+
+	handoffTimeout time.Duration
+	// registers replacements of handed off jobs with their room
+	roomClient     ModerationClient
+	topicFormatter rpc.TopicFormatter
 }
 
 type workerKey struct {
@@ -167,7 +185,15 @@ func NewAgentService(
 	currentNode routing.LocalNode,
 	bus psrpc.MessageBus,
 	keyProvider auth.KeyProvider,
+	telemetry telemetry.TelemetryService,
+	jobHistoryStore AgentJobHistoryStore,
+	roomClient ModerationClient,
+	topicFormatter rpc.TopicFormatter,
 ) (*AgentService, error) {
+	if err := conf.Agents.WorkerSelection.Validate(); err != nil {
+		return nil, err
+	}
+
 	s := &AgentService{}
 
 	serverInfo := &livekit.ServerInfo{
@@ -191,6 +217,11 @@ func NewAgentService(
 		agent.RoomAgentTopic,
 		agent.PublisherAgentTopic,
 		agent.ParticipantAgentTopic,
+		conf.Agents,
+		telemetry,
+		NewAgentJobHistory(jobHistoryStore, conf.Agents.JobHistory),
+		roomClient,
+		topicFormatter,
 	)
 	return s, nil
 }
@@ -210,6 +241,11 @@ func NewAgentHandler(
 	roomTopic string,
 	publisherTopic string,
 	participantTopic string,
+	conf agent.Config,
+	telemetry telemetry.TelemetryService,
+	jobHistory *AgentJobHistory,
+	roomClient ModerationClient,
+	topicFormatter rpc.TopicFormatter,
 ) *AgentHandler {
 	return &AgentHandler{
 		agentServer:      agentServer,
@@ -217,14 +253,27 @@ func NewAgentHandler(
 		workers:          make(map[string]*agent.Worker),
 		jobToWorker:      make(map[livekit.JobID]*agent.Worker),
 		namespaceWorkers: make(map[workerKey][]*agent.Worker),
+		selection:        conf.WorkerSelection,
+		selectors:        make(map[workerKey]agent.WorkerSelector),
+		autoscaling:      conf.Autoscaling,
+		unmetDemand:      make(map[fleetKey]map[string]time.Time),
+		handoffTimeout:   conf.JobHandoffTimeout,
+		roomClient:       roomClient,
+		topicFormatter:   topicFormatter,
 		serverInfo:       serverInfo,
 		keyProvider:      keyProvider,
+		telemetry:        telemetry,
+		jobHistory:       jobHistory,
 		roomTopic:        roomTopic,
 		publisherTopic:   publisherTopic,
 		participantTopic: participantTopic,
 	}
 }
 
+func (h *AgentHandler) JobHistory() *AgentJobHistory {
+	return h.jobHistory
+}
+
 func (h *AgentHandler) HandleConnection(ctx context.Context, conn agent.SignalConn, registration agent.WorkerRegistration) {
 	registration, ok := HandshakeAgentWorker(conn, h.serverInfo, registration, h.logger)
 	if !ok {
@@ -293,6 +342,7 @@ func (h *AgentHandler) registerWorker(w *agent.Worker) {
 	}
 
 	h.namespaceWorkers[key] = append(workers, w)
+	h.updateFleetMetricsLocked(fleetKey{w.AgentName, w.Namespace})
 	h.mu.Unlock()
 
 	h.logger.Infow("worker registered",
@@ -301,12 +351,42 @@ func (h *AgentHandler) registerWorker(w *agent.Worker) {
 		"agentName", w.AgentName,
 		"workerID", w.ID,
 	)
+	h.checkSelectionParams(w)
 	if created {
 		err := h.agentServer.PublishWorkerRegistered(context.Background(), agent.DefaultHandlerNamespace, &emptypb.Empty{})
 		// TODO: when this happens, should we disconnect the worker so it'll retry?
 		if err != nil {
 			w.Logger().Errorw("failed to publish worker registered", err, "namespace", w.Namespace, "jobType", w.JobType, "agentName", w.AgentName)
 		}
+	} else {
+		h.publishWorkerAvailable(w)
+	}
+}
+
+// checkSelectionParams warns about workers missing connection parameters used by the worker selection strategy of their agent
+func (h *AgentHandler) checkSelectionParams(w *agent.Worker) {
+	switch strategy := h.selection.StrategyFor(w.AgentName); strategy {
+	case agent.WorkerSelectionRegion:
+		if w.Region == "" {
+			w.Logger().Warnw("worker connected without region query parameter, it is only selected when no worker is in the region", nil,
+				"strategy", strategy,
+				"serverRegion", h.serverInfo.GetRegion(),
+			)
+		}
+	case agent.WorkerSelectionBinPacking:
+		if w.MaxJobs == 0 {
+			w.Logger().Warnw("worker connected without max_jobs query parameter, it is packed by load instead of job count", nil,
+				"strategy", strategy,
+			)
+		}
+	}
+}
+
+// publishWorkerAvailable lets room nodes retry queued jobs, agent caches are left as is
+func (h *AgentHandler) publishWorkerAvailable(w *agent.Worker) {
+	err := h.agentServer.PublishWorkerRegistered(context.Background(), agent.WorkerAvailableTopic, &emptypb.Empty{})
+	if err != nil {
+		w.Logger().Errorw("failed to publish worker available", err, "namespace", w.Namespace, "jobType", w.JobType, "agentName", w.AgentName)
 	}
 }
 
@@ -315,6 +395,7 @@ func (h *AgentHandler) deregisterWorker(w *agent.Worker) {
 	defer h.mu.Unlock()
 
 	delete(h.workers, w.ID)
+	defer h.updateFleetMetricsLocked(fleetKey{w.AgentName, w.Namespace})
 
 	key := workerKey{w.AgentName, w.Namespace, w.JobType}
 
@@ -337,6 +418,7 @@ func (h *AgentHandler) deregisterWorker(w *agent.Worker) {
 			"workerID", w.ID,
 		)
 		delete(h.namespaceWorkers, key)
+		delete(h.selectors, key)
 
 		topic := agent.GetAgentTopic(w.AgentName, w.Namespace)
 
@@ -364,6 +446,15 @@ func (h *AgentHandler) deregisterWorker(w *agent.Worker) {
 	jobs := w.RunningJobs()
 	for jobID := range jobs {
 		h.deregisterJob(jobID)
+
+		if job, err := w.GetJob(jobID); err == nil {
+			now := time.Now().UnixNano()
+			job.State.Status = livekit.JobStatus_JS_FAILED
+			job.State.Error = "agent worker disconnected"
+			job.State.EndedAt = now
+			job.State.UpdatedAt = now
+			h.jobEnded(job)
+		}
 	}
 }
 
@@ -375,6 +466,24 @@ func (h *AgentHandler) deregisterJob(jobID livekit.JobID) {
 	// TODO update dispatch state
 }
 
+// jobEnded reports an ended job and adds it to the job history
+func (h *AgentHandler) jobEnded(job *livekit.Job) {
+	event := telemetry.EventAgentJobEnded
+	if job.State.GetStatus() == livekit.JobStatus_JS_FAILED {
+		event = telemetry.EventAgentJobFailed
+	}
+	h.telemetry.AgentJobEvent(context.Background(), event, job)
+
+	// may be called with h.mu held
+	go h.jobHistory.Record(context.Background(), job)
+}
+
+func jobWithState(job *livekit.Job, state *livekit.JobState) *livekit.Job {
+	job = utils.CloneProto(job)
+	job.State = state
+	return job
+}
+
 func (h *AgentHandler) JobRequest(ctx context.Context, job *livekit.Job) (*rpc.JobRequestResponse, error) {
 	logger := h.logger.WithUnlikelyValues(
 		"jobID", job.Id,
@@ -389,13 +498,36 @@ func (h *AgentHandler) JobRequest(ctx context.Context, job *livekit.Job) (*rpc.J
 		logger = logger.WithValues("participant", job.Participant.Identity)
 	}
 
+	_, state, err := h.assignJob(ctx, logger, job, make(map[*agent.Worker]struct{}))
+	if err != nil {
+		return nil, err
+	}
+
+	if state.Status == livekit.JobStatus_JS_RUNNING {
+		err = h.agentServer.RegisterJobTerminateTopic(job.Id)
+		if err != nil {
+			logger.Errorw("failed to register JobTerminate handler", err)
+		}
+	}
+	return &rpc.JobRequestResponse{
+		State: state,
+	}, nil
+}
+
+// assignJob offers the job to workers until one accepts it, skipping the attempted workers
+func (h *AgentHandler) assignJob(
+	ctx context.Context,
+	logger logger.UnlikelyLogger,
+	job *livekit.Job,
+	attempted map[*agent.Worker]struct{},
+) (*agent.Worker, *livekit.JobState, error) {
 	key := workerKey{job.AgentName, job.Namespace, job.Type}
-	attempted := make(map[*agent.Worker]struct{})
 	for {
-		selected, err := h.selectWorkerWeightedByLoad(key, attempted)
+		selected, err := h.selectWorker(key, job, attempted)
 		if err != nil {
 			logger.Warnw("no worker available to handle job", err)
-			return nil, psrpc.NewError(psrpc.ResourceExhausted, err)
+			h.recordUnmetDemand(job)
+			return nil, nil, psrpc.NewError(psrpc.ResourceExhausted, err)
 		}
 
 		logger := logger.WithValues("workerID", selected.ID)
@@ -407,22 +539,20 @@ func (h *AgentHandler) JobRequest(ctx context.Context, job *livekit.Job) (*rpc.J
 			logger.Infow("assigned job to worker")
 			h.mu.Lock()
 			h.jobToWorker[livekit.JobID(job.Id)] = selected
+			h.updateFleetMetricsLocked(fleetKey{job.AgentName, job.Namespace})
 			h.mu.Unlock()
 
-			err = h.agentServer.RegisterJobTerminateTopic(job.Id)
-			if err != nil {
-				logger.Errorw("failed to register JobTerminate handler", err)
-			}
-			fallthrough
+			h.telemetry.AgentJobEvent(context.Background(), telemetry.EventAgentJobAssigned, jobWithState(job, state))
+			return selected, state, nil
 		case livekit.JobStatus_JS_SUCCESS:
-			return &rpc.JobRequestResponse{
-				State: state,
-			}, nil
+			// worker asked to terminate the job instead of accepting it
+			h.jobEnded(jobWithState(job, state))
+			return selected, state, nil
 		default:
 			retry := utils.ErrorIsOneOf(err, agent.ErrWorkerNotAvailable, agent.ErrWorkerClosed)
 			logger.Warnw("failed to assign job to worker", err, "retry", retry)
 			if !retry {
-				return nil, err
+				return nil, nil, err
 			}
 		}
 	}
@@ -438,8 +568,8 @@ func (h *AgentHandler) JobRequestAffinity(ctx context.Context, job *livekit.Job)
 			continue
 		}
 
-		if w.Status() == livekit.WorkerStatus_WS_AVAILABLE {
-			affinity += max(0, 1-w.Load())
+		if w.HasCapacity() {
+			affinity += w.SpareCapacity()
 		}
 	}
 
@@ -447,15 +577,17 @@ func (h *AgentHandler) JobRequestAffinity(ctx context.Context, job *livekit.Job)
 }
 
 func (h *AgentHandler) JobTerminate(ctx context.Context, req *rpc.JobTerminateRequest) (*rpc.JobTerminateResponse, error) {
+	jobID := livekit.JobID(req.JobId)
+
 	h.mu.Lock()
-	w := h.jobToWorker[livekit.JobID(req.JobId)]
+	w := h.jobToWorker[jobID]
 	h.mu.Unlock()
 
 	if w == nil {
 		return nil, psrpc.NewErrorf(psrpc.NotFound, "no worker for jobID")
 	}
 
-	state, err := w.TerminateJob(livekit.JobID(req.JobId), req.Reason)
+	state, err := h.terminateJob(w, jobID, req.Reason)
 	if err != nil {
 		return nil, err
 	}
@@ -465,6 +597,26 @@ func (h *AgentHandler) JobTerminate(ctx context.Context, req *rpc.JobTerminateRe
 	}, nil
 }
 
+// terminateJob asks the worker to end a running job
+func (h *AgentHandler) terminateJob(w *agent.Worker, jobID livekit.JobID, reason rpc.JobTerminateReason) (*livekit.JobState, error) {
+	job, err := w.GetJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := w.TerminateJob(jobID, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	h.deregisterJob(jobID)
+	h.updateFleetMetricsLocked(fleetKey{w.AgentName, w.Namespace})
+	h.mu.Unlock()
+	h.jobEnded(jobWithState(job, state))
+	return state, nil
+}
+
 func (h *AgentHandler) CheckEnabled(ctx context.Context, req *rpc.CheckEnabledRequest) (*rpc.CheckEnabledResponse, error) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -496,7 +648,7 @@ func (h *AgentHandler) DrainConnections(interval time.Duration) {
 	}
 }
 
-func (h *AgentHandler) selectWorkerWeightedByLoad(key workerKey, ignore map[*agent.Worker]struct{}) (*agent.Worker, error) {
+func (h *AgentHandler) selectWorker(key workerKey, job *livekit.Job, ignore map[*agent.Worker]struct{}) (*agent.Worker, error) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -505,26 +657,23 @@ func (h *AgentHandler) selectWorkerWeightedByLoad(key workerKey, ignore map[*age
 		return nil, errors.New("no workers available")
 	}
 
-	normalizedLoads := make(map[*agent.Worker]float32)
-	var availableSum float32
+	candidates := make([]*agent.Worker, 0, len(workers))
 	for _, w := range workers {
-		if _, ok := ignore[w]; !ok && w.Status() == livekit.WorkerStatus_WS_AVAILABLE {
-			normalizedLoads[w] = max(0, 1-w.Load())
-			availableSum += normalizedLoads[w]
+		if _, ok := ignore[w]; !ok && w.HasCapacity() {
+			candidates = append(candidates, w)
 		}
 	}
 
-	if availableSum == 0 {
+	if len(candidates) == 0 {
 		return nil, errors.New("no workers with sufficient capacity")
 	}
 
-	currentSum := rand.Float32() * availableSum
-	for w, load := range normalizedLoads {
-		if currentSum -= load; currentSum <= 0 {
-			return w, nil
-		}
+	selector, ok := h.selectors[key]
+	if !ok {
+		selector = agent.NewWorkerSelector(h.selection.StrategyFor(key.agentName), h.serverInfo.GetRegion())
+		h.selectors[key] = selector
 	}
-	return workers[0], nil
+	return selector.SelectWorker(job, candidates), nil
 }
 
 var _ agent.WorkerSignalHandler = (*agentHandlerWorker)(nil)
@@ -535,14 +684,56 @@ type agentHandlerWorker struct {
 }
 
 func (w *agentHandlerWorker) HandleUpdateJob(update *livekit.UpdateJobStatus) error {
-	if err := w.Worker.HandleUpdateJob(update); err != nil {
-		return err
+	job, err := w.Worker.GetJob(livekit.JobID(update.JobId))
+	if err == nil {
+		var state *livekit.JobState
+		var started bool
+		if state, started, err = w.Worker.ReportJobStatus(update); err == nil {
+			switch {
+			case agent.JobStatusIsEnded(state.Status):
+				w.h.jobEnded(jobWithState(job, state))
+			case started:
+				w.h.telemetry.AgentJobEvent(context.Background(), telemetry.EventAgentJobStarted, jobWithState(job, state))
+			}
+		}
+	}
+	if err != nil {
+		// treating this as a debug message only
+		// this can happen if the Room closes first, which would delete the agent dispatch
+		// that would mark the job as successful. subsequent updates from the same worker
+		// would not be able to find the same jobID.
+		w.Logger().Debugw("received job update for unknown job", "jobID", update.JobId)
 	}
 
 	if agent.JobStatusIsEnded(update.Status) {
 		w.h.mu.Lock()
 		w.h.deregisterJob(livekit.JobID(update.JobId))
+		w.h.updateFleetMetricsLocked(fleetKey{w.AgentName, w.Namespace})
 		w.h.mu.Unlock()
 	}
 	return nil
 }
+
+func (w *agentHandlerWorker) HandleMigrateJob(req *livekit.MigrateJobRequest) error {
+	if err := w.Worker.HandleMigrateJob(req); err != nil {
+		return err
+	}
+	w.h.updateFleetMetrics(w.AgentName, w.Namespace)
+
+	w.h.drainJobs(w.Worker, req.JobIds)
+	return nil
+}
+
+func (w *agentHandlerWorker) HandleUpdateWorker(update *livekit.UpdateWorkerStatus) error {
+	wasAvailable := w.Worker.Status() == livekit.WorkerStatus_WS_AVAILABLE
+	if err := w.Worker.HandleUpdateWorker(update); err != nil {
+		return err
+	}
+	w.h.updateFleetMetrics(w.AgentName, w.Namespace)
+
+	if !wasAvailable && w.Worker.Status() == livekit.WorkerStatus_WS_AVAILABLE {
+		// let room nodes retry jobs queued while workers were full
+		w.h.publishWorkerAvailable(w.Worker)
+	}
+	return nil
+}
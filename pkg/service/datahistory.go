@@ -0,0 +1,73 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const dataHistoryFileExt = ".jsonl"
+
+// dataHistoryRecord is a line of a data history export, records carry the room so that exports can be concatenated
+type dataHistoryRecord struct {
+	Room    string `json:"room"`
+	RoomSid string `json:"room_sid"`
+	*rtc.DataHistoryMessage
+}
+
+// exportDataHistory writes kept messages of a room to a file of the directory, one JSON record per line
+func exportDataHistory(dir string, room *livekit.Room, msgs []*rtc.DataHistoryMessage) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	// write to a temporary file first, a partially written export should never be picked up
+	path := filepath.Join(dir, url.PathEscape(room.Name)+"_"+room.Sid+dataHistoryFileExt)
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, msg := range msgs {
+		if err = enc.Encode(&dataHistoryRecord{Room: room.Name, RoomSid: room.Sid, DataHistoryMessage: msg}); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -49,4 +49,18 @@ var (
 	ErrNoConnectRequest                 = psrpc.NewErrorf(psrpc.InvalidArgument, "no connect request")
 	ErrNoConnectResponse                = psrpc.NewErrorf(psrpc.InvalidArgument, "no connect response")
 	ErrDestinationIdentityRequired      = psrpc.NewErrorf(psrpc.InvalidArgument, "destination identity is required")
+	ErrNodeShuttingDown                 = psrpc.NewErrorf(psrpc.Unavailable, "node is shutting down")
+	ErrParticipantNotPending            = psrpc.NewErrorf(psrpc.NotFound, "participant is not waiting in the lobby")
+	ErrLobbyRequiresSignalConnection    = psrpc.NewErrorf(psrpc.FailedPrecondition, "room lobby requires a signal connection")
+	ErrInvalidLobby                     = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid lobby request")
+	ErrNoPublishRequest                 = psrpc.NewErrorf(psrpc.NotFound, "participant has not requested to publish")
+	ErrMaxPublishersExceeded            = psrpc.NewErrorf(psrpc.ResourceExhausted, "room has reached its maximum number of publishers")
+	ErrInvalidSubscriptionPermission    = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid subscription permission")
+	ErrInvalidSubscriptionPolicy        = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid subscription policy")
+	ErrInvalidDataPolicy                = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid data policy")
+	ErrInvalidSubscriptionLimits        = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid subscription limits")
+	ErrInvalidDataRetention             = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid data retention")
+	ErrInvalidRoomState                 = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid room state update")
+	ErrInvalidJobHandoff                = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid job handoff request")
+	ErrInvalidMetadataVersions          = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid metadata versions request")
 )
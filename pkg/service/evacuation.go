@@ -0,0 +1,405 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	evacuationCheckInterval = 250 * time.Millisecond
+)
+
+var (
+	ErrEvacuationInProgress = errors.New("evacuation already in progress")
+	ErrNoEvacuationTarget   = errors.New("no node available to evacuate to")
+)
+
+type EvacuationState string
+
+const (
+	EvacuationStateIdle      EvacuationState = "idle"
+	EvacuationStateRunning   EvacuationState = "running"
+	EvacuationStateCompleted EvacuationState = "completed"
+	EvacuationStateFailed    EvacuationState = "failed"
+)
+
+type RoomEvacuationStatus struct {
+	Room                  livekit.RoomName `json:"room"`
+	TargetNodeID          livekit.NodeID   `json:"target_node_id,omitempty"`
+	Participants          int              `json:"participants"`
+	ParticipantsRemaining int              `json:"participants_remaining"`
+	Migrated              int              `json:"migrated"`
+	Closed                int              `json:"closed"`
+	Error                 string           `json:"error,omitempty"`
+	StartedAt             time.Time        `json:"started_at"`
+	EndedAt               time.Time        `json:"ended_at,omitempty"`
+
+	// participants counted as migrated, set before waiting for the room migration
+	migrated map[livekit.ParticipantID]struct{}
+}
+
+type EvacuationStatus struct {
+	NodeID         livekit.NodeID          `json:"node_id"`
+	State          EvacuationState         `json:"state"`
+	StartedAt      time.Time               `json:"started_at,omitempty"`
+	EndedAt        time.Time               `json:"ended_at,omitempty"`
+	RoomsTotal     int                     `json:"rooms_total"`
+	RoomsRemaining int                     `json:"rooms_remaining"`
+	Error          string                  `json:"error,omitempty"`
+	Rooms          []*RoomEvacuationStatus `json:"rooms,omitempty"`
+}
+
+// NodeEvacuator moves rooms hosted on this node to other nodes, room by room.
+// Participants are migrated using the session migration path, i. e. they are asked to
+// resume and the resume is routed to the node newly assigned to the room.
+type NodeEvacuator struct {
+	config      config.EvacuationConfig
+	currentNode routing.LocalNode
+	router      routing.Router
+	selector    selector.NodeSelector
+	getRooms    func() []*rtc.Room
+
+	lock   sync.RWMutex
+	status *EvacuationStatus
+	done   chan struct{}
+	// rooms which have been handed off to another node, state of those rooms is owned by the target node
+	evacuatedRooms map[livekit.RoomName]livekit.NodeID
+	// participants asked to migrate, their session continues on another node
+	migratingOut map[livekit.ParticipantID]struct{}
+}
+
+func NewNodeEvacuator(
+	conf *config.Config,
+	currentNode routing.LocalNode,
+	router routing.Router,
+	getRooms func() []*rtc.Room,
+) (*NodeEvacuator, error) {
+	ns, err := selector.CreateNodeSelector(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NodeEvacuator{
+		config:      conf.Evacuation,
+		currentNode: currentNode,
+		router:      router,
+		selector:    ns,
+		getRooms:    getRooms,
+		status: &EvacuationStatus{
+			NodeID: currentNode.NodeID(),
+			State:  EvacuationStateIdle,
+		},
+		evacuatedRooms: make(map[livekit.RoomName]livekit.NodeID),
+		migratingOut:   make(map[livekit.ParticipantID]struct{}),
+	}, nil
+}
+
+// Start begins evacuation in the background, progress is available via Status
+func (e *NodeEvacuator) Start() error {
+	e.lock.Lock()
+	if e.status.State == EvacuationStateRunning {
+		e.lock.Unlock()
+		return ErrEvacuationInProgress
+	}
+
+	rooms := e.getRooms()
+	e.status = &EvacuationStatus{
+		NodeID:         e.currentNode.NodeID(),
+		State:          EvacuationStateRunning,
+		StartedAt:      time.Now(),
+		RoomsTotal:     len(rooms),
+		RoomsRemaining: len(rooms),
+	}
+	e.done = make(chan struct{})
+	e.lock.Unlock()
+
+	logger.Infow("starting node evacuation", "nodeID", e.currentNode.NodeID(), "numRooms", len(rooms))
+
+	// stop new rooms from being assigned to this node
+	e.router.Drain()
+
+	go e.evacuate(rooms)
+	return nil
+}
+
+// Wait blocks till the evacuation in progress ends
+func (e *NodeEvacuator) Wait() {
+	e.lock.RLock()
+	done := e.done
+	e.lock.RUnlock()
+
+	if done != nil {
+		<-done
+	}
+}
+
+func (e *NodeEvacuator) Status() *EvacuationStatus {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	status := *e.status
+	status.Rooms = make([]*RoomEvacuationStatus, 0, len(e.status.Rooms))
+	for _, rs := range e.status.Rooms {
+		roomStatus := *rs
+		status.Rooms = append(status.Rooms, &roomStatus)
+	}
+	return &status
+}
+
+// ReleaseRoom is called when a room closes, returns true if the room had been evacuated,
+// i. e. the room continues on another node and its shared state should not be cleaned up
+func (e *NodeEvacuator) ReleaseRoom(roomName livekit.RoomName) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	_, ok := e.evacuatedRooms[roomName]
+	delete(e.evacuatedRooms, roomName)
+	return ok
+}
+
+// ReleaseParticipant is called when a participant closes, returns true if the participant
+// had been migrated out, i. e. the participant session continues on another node
+func (e *NodeEvacuator) ReleaseParticipant(participantID livekit.ParticipantID) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	_, ok := e.migratingOut[participantID]
+	delete(e.migratingOut, participantID)
+	return ok
+}
+
+func (e *NodeEvacuator) evacuate(rooms []*rtc.Room) {
+	var (
+		wg  sync.WaitGroup
+		err error
+	)
+	for i, room := range rooms {
+		if i != 0 && e.config.RoomInterval > 0 {
+			time.Sleep(e.config.RoomInterval)
+		}
+
+		var rs *RoomEvacuationStatus
+		rs, err = e.startRoomMigration(room)
+		if err != nil {
+			e.endRoomMigration(rs, err)
+			if errors.Is(err, ErrNoEvacuationTarget) {
+				// no point trying other rooms
+				break
+			}
+			room.Logger().Warnw("could not evacuate room", err)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.waitForRoomMigration(room, rs)
+			e.endRoomMigration(rs, nil)
+		}()
+	}
+	wg.Wait()
+
+	e.lock.Lock()
+	e.status.EndedAt = time.Now()
+	if errors.Is(err, ErrNoEvacuationTarget) {
+		e.status.State = EvacuationStateFailed
+		e.status.Error = err.Error()
+	} else {
+		e.status.State = EvacuationStateCompleted
+	}
+	close(e.done)
+	status := e.status
+	e.lock.Unlock()
+
+	logger.Infow(
+		"node evacuation ended",
+		"nodeID", e.currentNode.NodeID(),
+		"state", status.State,
+		"numRooms", status.RoomsTotal,
+		"numRoomsRemaining", status.RoomsRemaining,
+		"duration", status.EndedAt.Sub(status.StartedAt),
+	)
+}
+
+func (e *NodeEvacuator) startRoomMigration(room *rtc.Room) (*RoomEvacuationStatus, error) {
+	participants := room.GetParticipants()
+	rs := &RoomEvacuationStatus{
+		Room:                  room.Name(),
+		Participants:          len(participants),
+		ParticipantsRemaining: len(participants),
+		StartedAt:             time.Now(),
+	}
+	e.lock.Lock()
+	e.status.Rooms = append(e.status.Rooms, rs)
+	e.lock.Unlock()
+
+	if room.IsClosed() || len(participants) == 0 {
+		// nothing to migrate, let the room close normally
+		return rs, nil
+	}
+
+	target, err := e.selectTargetNode()
+	if err != nil {
+		return rs, err
+	}
+
+	e.lock.Lock()
+	rs.TargetNodeID = target
+	e.evacuatedRooms[room.Name()] = target
+	e.lock.Unlock()
+
+	// route new sessions and resumes for the room to the target node
+	if err := e.router.SetNodeForRoom(context.Background(), room.Name(), target); err != nil {
+		e.lock.Lock()
+		delete(e.evacuatedRooms, room.Name())
+		e.lock.Unlock()
+		return rs, err
+	}
+
+	room.Logger().Infow("evacuating room", "targetNodeID", target, "numParticipants", len(participants))
+
+	migrated := make(map[livekit.ParticipantID]struct{}, len(participants))
+	for _, p := range participants {
+		if p.MaybeStartMigration(true, func() {
+			e.lock.Lock()
+			e.migratingOut[p.ID()] = struct{}{}
+			e.lock.Unlock()
+		}) {
+			migrated[p.ID()] = struct{}{}
+			continue
+		}
+
+		// participants which cannot resume (e. g. one shot signalling) have to reconnect
+		p.IssueFullReconnect(types.ParticipantCloseReasonMigrationRequested)
+	}
+
+	e.lock.Lock()
+	rs.Migrated = len(migrated)
+	rs.migrated = migrated
+	e.lock.Unlock()
+	return rs, nil
+}
+
+// waitForRoomMigration waits for participant sessions to move off this node,
+// sessions still here after the migration timeout are closed, only the ones which did not start
+// migrating count as closed as the others continue on the target node
+func (e *NodeEvacuator) waitForRoomMigration(room *rtc.Room, rs *RoomEvacuationStatus) {
+	deadline := time.Now().Add(e.config.MigrationTimeout)
+	for {
+		remaining := room.GetParticipants()
+		e.lock.Lock()
+		rs.ParticipantsRemaining = len(remaining)
+		e.lock.Unlock()
+
+		if len(remaining) == 0 {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			closed := 0
+			for _, p := range remaining {
+				if _, ok := rs.migrated[p.ID()]; !ok {
+					closed++
+				}
+				room.RemoveParticipant(p.Identity(), p.ID(), types.ParticipantCloseReasonMigrationComplete)
+			}
+
+			e.lock.Lock()
+			rs.Closed = closed
+			rs.ParticipantsRemaining = 0
+			e.lock.Unlock()
+			return
+		}
+
+		time.Sleep(evacuationCheckInterval)
+	}
+}
+
+func (e *NodeEvacuator) endRoomMigration(rs *RoomEvacuationStatus, err error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if err != nil {
+		rs.Error = err.Error()
+	}
+	rs.EndedAt = time.Now()
+	e.status.RoomsRemaining--
+}
+
+func (e *NodeEvacuator) selectTargetNode() (livekit.NodeID, error) {
+	nodes, err := e.router.ListNodes()
+	if err != nil {
+		return "", err
+	}
+
+	candidates := make([]*livekit.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if livekit.NodeID(node.Id) != e.currentNode.NodeID() {
+			candidates = append(candidates, node)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", ErrNoEvacuationTarget
+	}
+
+	node, err := e.selector.SelectNode(candidates)
+	if err != nil {
+		if errors.Is(err, selector.ErrNoAvailableNodes) {
+			return "", ErrNoEvacuationTarget
+		}
+		return "", err
+	}
+
+	return livekit.NodeID(node.Id), nil
+}
+
+// ServeHTTP starts an evacuation on POST and reports progress on GET
+func (e *NodeEvacuator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := EnsureCreatePermission(r.Context()); err != nil {
+		HandleError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if err := e.Start(); err != nil {
+			HandleError(w, r, http.StatusConflict, err)
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(e.Status())
+}
@@ -0,0 +1,199 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func TestNodeEvacuator(t *testing.T) {
+	t.Run("drains node and completes without rooms", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+
+		node, err := routing.NewLocalNode(conf)
+		require.NoError(t, err)
+
+		router := &routingfakes.FakeRouter{}
+		evacuator, err := service.NewNodeEvacuator(conf, node, router, func() []*rtc.Room { return nil })
+		require.NoError(t, err)
+		require.Equal(t, service.EvacuationStateIdle, evacuator.Status().State)
+
+		require.NoError(t, evacuator.Start())
+		evacuator.Wait()
+
+		status := evacuator.Status()
+		require.Equal(t, service.EvacuationStateCompleted, status.State)
+		require.Equal(t, node.NodeID(), status.NodeID)
+		require.Zero(t, status.RoomsRemaining)
+		require.Equal(t, 1, router.DrainCallCount())
+	})
+
+	t.Run("does not claim unknown rooms or participants", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+
+		node, err := routing.NewLocalNode(conf)
+		require.NoError(t, err)
+
+		evacuator, err := service.NewNodeEvacuator(conf, node, &routingfakes.FakeRouter{}, func() []*rtc.Room { return nil })
+		require.NoError(t, err)
+		require.False(t, evacuator.ReleaseRoom("room"))
+		require.False(t, evacuator.ReleaseParticipant("PA_participant"))
+	})
+	t.Run("migrates rooms within the deadline", func(t *testing.T) {
+		conf, node, router := newEvacuationTest(t)
+		conf.Evacuation.MigrationTimeout = 5 * time.Second
+
+		room, participants := newEvacuationTestRoom(t, conf, "room", 2)
+		for _, p := range participants {
+			p.MaybeStartMigrationCalls(func(force bool, onStart func()) bool {
+				onStart()
+				// the session resumes on the target node and leaves this one
+				go room.RemoveParticipant(p.Identity(), p.ID(), types.ParticipantCloseReasonMigrationComplete)
+				return true
+			})
+		}
+
+		evacuator, err := service.NewNodeEvacuator(conf, node, router, func() []*rtc.Room { return []*rtc.Room{room} })
+		require.NoError(t, err)
+		require.NoError(t, evacuator.Start())
+		evacuator.Wait()
+
+		status := evacuator.Status()
+		require.Equal(t, service.EvacuationStateCompleted, status.State)
+		require.Equal(t, 1, status.RoomsTotal)
+		require.Zero(t, status.RoomsRemaining)
+		require.Len(t, status.Rooms, 1)
+		rs := status.Rooms[0]
+		require.Equal(t, livekit.NodeID("target"), rs.TargetNodeID)
+		require.Equal(t, 2, rs.Participants)
+		require.Equal(t, 2, rs.Migrated)
+		require.Zero(t, rs.Closed)
+		require.Equal(t, rs.Participants, rs.Migrated+rs.Closed)
+		require.Zero(t, rs.ParticipantsRemaining)
+		require.Empty(t, rs.Error)
+
+		require.Equal(t, 1, router.SetNodeForRoomCallCount())
+		_, roomName, nodeID := router.SetNodeForRoomArgsForCall(0)
+		require.Equal(t, livekit.RoomName("room"), roomName)
+		require.Equal(t, livekit.NodeID("target"), nodeID)
+
+		// sessions and the room continue on the target node
+		for _, p := range participants {
+			require.True(t, evacuator.ReleaseParticipant(p.ID()))
+		}
+		require.True(t, evacuator.ReleaseRoom("room"))
+	})
+
+	t.Run("closes sessions not migrated by the deadline", func(t *testing.T) {
+		conf, node, router := newEvacuationTest(t)
+		conf.Evacuation.MigrationTimeout = 100 * time.Millisecond
+
+		room, participants := newEvacuationTestRoom(t, conf, "room", 2)
+		participants[0].MaybeStartMigrationReturns(true)
+		// cannot resume, has to reconnect and does not
+		participants[1].MaybeStartMigrationReturns(false)
+
+		evacuator, err := service.NewNodeEvacuator(conf, node, router, func() []*rtc.Room { return []*rtc.Room{room} })
+		require.NoError(t, err)
+		require.NoError(t, evacuator.Start())
+		evacuator.Wait()
+
+		status := evacuator.Status()
+		require.Equal(t, service.EvacuationStateCompleted, status.State)
+		require.Len(t, status.Rooms, 1)
+		rs := status.Rooms[0]
+		require.Equal(t, 1, rs.Migrated)
+		require.Equal(t, 1, rs.Closed)
+		require.Equal(t, rs.Participants, rs.Migrated+rs.Closed)
+		require.Zero(t, rs.ParticipantsRemaining)
+		require.Equal(t, 1, participants[1].IssueFullReconnectCallCount())
+		require.Empty(t, room.GetParticipants())
+		for _, p := range participants {
+			require.Equal(t, 1, p.CloseCallCount())
+		}
+	})
+
+	t.Run("fails without a target node", func(t *testing.T) {
+		conf, node, router := newEvacuationTest(t)
+		router.ListNodesReturns(nil, nil)
+
+		room, participants := newEvacuationTestRoom(t, conf, "room", 1)
+		evacuator, err := service.NewNodeEvacuator(conf, node, router, func() []*rtc.Room { return []*rtc.Room{room} })
+		require.NoError(t, err)
+		require.NoError(t, evacuator.Start())
+		evacuator.Wait()
+
+		status := evacuator.Status()
+		require.Equal(t, service.EvacuationStateFailed, status.State)
+		require.Equal(t, service.ErrNoEvacuationTarget.Error(), status.Error)
+		require.Zero(t, participants[0].MaybeStartMigrationCallCount())
+		require.False(t, evacuator.ReleaseRoom("room"))
+	})
+}
+
+func newEvacuationTest(t *testing.T) (*config.Config, routing.LocalNode, *routingfakes.FakeRouter) {
+	conf, err := config.NewConfig("", true, nil, nil)
+	require.NoError(t, err)
+	conf.Evacuation.RoomInterval = 0
+
+	node, err := routing.NewLocalNode(conf)
+	require.NoError(t, err)
+
+	router := &routingfakes.FakeRouter{}
+	router.ListNodesReturns([]*livekit.Node{
+		{Id: string(node.NodeID()), State: livekit.NodeState_SERVING},
+		{Id: "target", State: livekit.NodeState_SERVING},
+	}, nil)
+	return conf, node, router
+}
+
+func newEvacuationTestRoom(t *testing.T, conf *config.Config, name livekit.RoomName, numParticipants int) (*rtc.Room, []*typesfakes.FakeLocalParticipant) {
+	room := rtc.NewRoom(
+		&livekit.Room{Name: string(name)},
+		nil,
+		rtc.WebRTCConfig{},
+		conf.Room,
+		&conf.Audio,
+		&livekit.ServerInfo{},
+		&telemetryfakes.FakeTelemetryService{},
+		nil, nil, nil, nil,
+	)
+	t.Cleanup(func() { room.Close(types.ParticipantCloseReasonNone) })
+
+	participants := make([]*typesfakes.FakeLocalParticipant, 0, numParticipants)
+	for i := 0; i < numParticipants; i++ {
+		p := rtc.NewMockParticipant(livekit.ParticipantIdentity(fmt.Sprintf("p%d", i)), types.CurrentProtocol, false, false, room.LocalParticipantListener())
+		require.NoError(t, room.Join(p, nil, nil, nil))
+		participants = append(participants, p)
+	}
+	return room, participants
+}
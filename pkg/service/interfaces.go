@@ -16,9 +16,12 @@ package service
 
 import (
 	"context"
+	"slices"
 	"time"
 
 	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
@@ -29,6 +32,7 @@ import (
 type ObjectStore interface {
 	ServiceStore
 	OSSServiceStore
+	DataHistoryStore
 
 	// enable locking on a specific room to prevent race
 	// returns a (lock uuid, error)
@@ -58,6 +62,23 @@ type OSSServiceStore interface {
 	HasParticipant(context.Context, livekit.RoomName, livekit.ParticipantIdentity) (bool, error)
 }
 
+//counterfeiter:generate . DataHistoryStore
+type DataHistoryStore interface {
+	// StoreDataMessage appends a message to the history of its topic, keeping at most maxMessages for at most maxAge
+	StoreDataMessage(ctx context.Context, roomName livekit.RoomName, msg *rtc.DataHistoryMessage, maxMessages int, maxAge time.Duration) error
+	// ListDataMessages returns the history of a topic, oldest first
+	ListDataMessages(ctx context.Context, roomName livekit.RoomName, topic string) ([]*rtc.DataHistoryMessage, error)
+	DeleteDataHistory(ctx context.Context, roomName livekit.RoomName) error
+}
+
+//counterfeiter:generate . RoomSnapshotStore
+type RoomSnapshotStore interface {
+	// StoreRoomSnapshot saves a snapshot for the node identified by nodeKey
+	StoreRoomSnapshot(ctx context.Context, nodeKey string, snapshot *rtc.RoomSnapshot) error
+	// TakeRoomSnapshots returns snapshots stored by the node and removes them from the store, a snapshot is taken only once
+	TakeRoomSnapshots(ctx context.Context, nodeKey string) ([]*rtc.RoomSnapshot, error)
+}
+
 //counterfeiter:generate . EgressStore
 type EgressStore interface {
 	StoreEgress(ctx context.Context, info *livekit.EgressInfo) error
@@ -81,6 +102,7 @@ type IngressStore interface {
 type RoomAllocator interface {
 	AutoCreateEnabled(ctx context.Context) bool
 	SelectRoomNode(ctx context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) error
+	SelectParticipantNode(ctx context.Context, roomName livekit.RoomName, region string) (livekit.NodeID, error)
 	CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest, isExplicit bool) (*livekit.Room, *livekit.RoomInternal, bool, error)
 	ValidateCreateRoom(ctx context.Context, roomName livekit.RoomName) error
 }
@@ -113,3 +135,30 @@ type AgentStore interface {
 	StoreAgentJob(ctx context.Context, job *livekit.Job) error
 	DeleteAgentJob(ctx context.Context, job *livekit.Job) error
 }
+
+//counterfeiter:generate . AgentJobHistoryStore
+type AgentJobHistoryStore interface {
+	// StoreAgentJobHistory stores an ended job, dropping jobs which ended before ttl or beyond the latest maxJobs
+	StoreAgentJobHistory(ctx context.Context, job *livekit.Job, maxJobs int, ttl time.Duration) error
+	// ListAgentJobHistory returns matching jobs, most recently ended first
+	ListAgentJobHistory(ctx context.Context, filter AgentJobFilter) ([]*livekit.Job, error)
+}
+
+type AgentJobFilter struct {
+	RoomName  livekit.RoomName
+	AgentName string
+	// any status when empty
+	Statuses []livekit.JobStatus
+	// all matching jobs when 0
+	Limit int
+}
+
+func (f AgentJobFilter) Match(job *livekit.Job) bool {
+	if f.RoomName != "" && job.GetRoom().GetName() != string(f.RoomName) {
+		return false
+	}
+	if f.AgentName != "" && job.AgentName != f.AgentName {
+		return false
+	}
+	return len(f.Statuses) == 0 || slices.Contains(f.Statuses, job.GetState().GetStatus())
+}
@@ -16,6 +16,7 @@ package service
 
 import (
 	"context"
+	"slices"
 	"sync"
 	"time"
 
@@ -23,6 +24,8 @@ import (
 
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
 )
 
 // encapsulates CRUD operations for room settings
@@ -35,6 +38,13 @@ type LocalStore struct {
 
 	agentDispatches map[livekit.RoomName]map[string]*livekit.AgentDispatch
 	agentJobs       map[livekit.RoomName]map[string]*livekit.Job
+	// ended jobs, oldest first
+	agentJobHistory []*livekit.Job
+
+	// node key => room snapshots
+	roomSnapshots map[string]map[livekit.RoomName]*rtc.RoomSnapshot
+	// map of roomName => { topic: messages, oldest first }
+	dataHistory map[livekit.RoomName]map[string][]*rtc.DataHistoryMessage
 
 	lock       sync.RWMutex
 	globalLock sync.Mutex
@@ -47,6 +57,8 @@ func NewLocalStore() *LocalStore {
 		participants:    make(map[livekit.RoomName]map[livekit.ParticipantIdentity]*livekit.ParticipantInfo),
 		agentDispatches: make(map[livekit.RoomName]map[string]*livekit.AgentDispatch),
 		agentJobs:       make(map[livekit.RoomName]map[string]*livekit.Job),
+		roomSnapshots:   make(map[string]map[livekit.RoomName]*rtc.RoomSnapshot),
+		dataHistory:     make(map[livekit.RoomName]map[string][]*rtc.DataHistoryMessage),
 		lock:            sync.RWMutex{},
 	}
 }
@@ -294,3 +306,99 @@ func (s *LocalStore) DeleteAgentJob(ctx context.Context, job *livekit.Job) error
 
 	return nil
 }
+
+func (s *LocalStore) StoreAgentJobHistory(ctx context.Context, job *livekit.Job, maxJobs int, ttl time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.agentJobHistory = slices.DeleteFunc(s.agentJobHistory, func(j *livekit.Job) bool {
+		return j.Id == job.Id || (ttl > 0 && time.Since(time.Unix(0, j.GetState().GetEndedAt())) > ttl)
+	})
+	s.agentJobHistory = append(s.agentJobHistory, agentJobHistoryEntry(job))
+	if maxJobs > 0 && len(s.agentJobHistory) > maxJobs {
+		s.agentJobHistory = slices.Delete(s.agentJobHistory, 0, len(s.agentJobHistory)-maxJobs)
+	}
+
+	return nil
+}
+
+func (s *LocalStore) ListAgentJobHistory(ctx context.Context, filter AgentJobFilter) ([]*livekit.Job, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var jobs []*livekit.Job
+	for _, job := range slices.Backward(s.agentJobHistory) {
+		if !filter.Match(job) {
+			continue
+		}
+		jobs = append(jobs, utils.CloneProto(job))
+		if filter.Limit > 0 && len(jobs) == filter.Limit {
+			break
+		}
+	}
+	return jobs, nil
+}
+
+func (s *LocalStore) StoreRoomSnapshot(_ context.Context, nodeKey string, snapshot *rtc.RoomSnapshot) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	snapshots := s.roomSnapshots[nodeKey]
+	if snapshots == nil {
+		snapshots = make(map[livekit.RoomName]*rtc.RoomSnapshot)
+		s.roomSnapshots[nodeKey] = snapshots
+	}
+	snapshots[livekit.RoomName(snapshot.Room.Name)] = snapshot
+	return nil
+}
+
+func (s *LocalStore) TakeRoomSnapshots(_ context.Context, nodeKey string) ([]*rtc.RoomSnapshot, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	snapshots := make([]*rtc.RoomSnapshot, 0, len(s.roomSnapshots[nodeKey]))
+	for _, snapshot := range s.roomSnapshots[nodeKey] {
+		snapshots = append(snapshots, snapshot)
+	}
+	delete(s.roomSnapshots, nodeKey)
+	return snapshots, nil
+}
+
+func (s *LocalStore) StoreDataMessage(_ context.Context, roomName livekit.RoomName, msg *rtc.DataHistoryMessage, maxMessages int, maxAge time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	history := s.dataHistory[roomName]
+	if history == nil {
+		history = make(map[string][]*rtc.DataHistoryMessage)
+		s.dataHistory[roomName] = history
+	}
+
+	msgs := append(history[msg.Topic], msg)
+	if maxAge > 0 {
+		cutoff := time.Now().Add(-maxAge).UnixMilli()
+		msgs = slices.DeleteFunc(msgs, func(m *rtc.DataHistoryMessage) bool {
+			return m.Timestamp < cutoff
+		})
+	}
+	if maxMessages > 0 && len(msgs) > maxMessages {
+		msgs = slices.Delete(msgs, 0, len(msgs)-maxMessages)
+	}
+	history[msg.Topic] = msgs
+	return nil
+}
+
+func (s *LocalStore) ListDataMessages(_ context.Context, roomName livekit.RoomName, topic string) ([]*rtc.DataHistoryMessage, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return slices.Clone(s.dataHistory[roomName][topic]), nil
+}
+
+func (s *LocalStore) DeleteDataHistory(_ context.Context, roomName livekit.RoomName) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.dataHistory, roomName)
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/metadata"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	// ExpectedVersionsHeader makes UpdateParticipant and UpdateRoomMetadata conditional. Its value is a JSON encoded
+	// types.ExpectedVersions, e.g. {"metadata": 3, "attributes": {"slide": 7}}, only the metadata version applies to
+	// UpdateRoomMetadata. When they do not match, the request fails as aborted with the current versions in the
+	// VersionsErrorMeta of the error.
+	ExpectedVersionsHeader = "X-LiveKit-Expected-Versions"
+	// VersionsErrorMeta is the meta of conflict errors holding the current versions, a JSON encoded types.MetadataVersions
+	VersionsErrorMeta = "versions"
+	// VersionsHeader is the response header of successful UpdateParticipant and UpdateRoomMetadata requests holding the
+	// current versions, a JSON encoded types.MetadataVersions, so that the next update can be conditional without
+	// a failed write. GetMetadataVersions returns them without an update.
+	VersionsHeader = "X-LiveKit-Versions"
+
+	// carries the header to the node of the room
+	expectedVersionsMetadataKey = "expected_versions"
+)
+
+type expectedVersionsKey struct{}
+
+// ExpectedVersionsMiddleware keeps the expected versions of an API request in its context
+func ExpectedVersionsMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if value := r.Header.Get(ExpectedVersionsHeader); value != "" {
+		r = r.WithContext(context.WithValue(r.Context(), expectedVersionsKey{}, value))
+	}
+	next(w, r)
+}
+
+// withExpectedVersions forwards expected versions of an API request with the request to the node of the room
+func withExpectedVersions(ctx context.Context) context.Context {
+	if value, ok := ctx.Value(expectedVersionsKey{}).(string); ok {
+		return metadata.AppendMetadataToOutgoingContext(ctx, expectedVersionsMetadataKey, value)
+	}
+	return ctx
+}
+
+// expectedVersionsFromRequest returns the expected versions forwarded with a request, nil when it is unconditional
+func expectedVersionsFromRequest(ctx context.Context) (*types.ExpectedVersions, error) {
+	head := metadata.IncomingHeader(ctx)
+	if head == nil {
+		return nil, nil
+	}
+	value, ok := head.Metadata[expectedVersionsMetadataKey]
+	if !ok {
+		return nil, nil
+	}
+	expected, err := rtc.ParseExpectedVersions(value)
+	if err != nil {
+		return nil, psrpc.NewError(psrpc.InvalidArgument, err)
+	}
+	return expected, nil
+}
+
+// metadataVersionsError returns conflicts with the current versions as details
+func metadataVersionsError(err error) error {
+	var conflictErr *rtc.MetadataConflictError
+	switch {
+	case errors.As(err, &conflictErr):
+		versions, serr := toStruct(&conflictErr.Versions)
+		if serr != nil {
+			return psrpc.NewError(psrpc.Aborted, err)
+		}
+		return psrpc.NewError(psrpc.Aborted, err, versions)
+	case errors.Is(err, rtc.ErrVersionsAttributeNotAllowed):
+		return psrpc.NewError(psrpc.InvalidArgument, err)
+	default:
+		return err
+	}
+}
+
+// twirpMetadataVersionsError moves the current versions of a conflict to the meta of the twirp error
+func twirpMetadataVersionsError(err error) error {
+	var psrpcErr psrpc.Error
+	if !errors.As(err, &psrpcErr) || psrpcErr.Code() != psrpc.Aborted {
+		return err
+	}
+	for _, detail := range psrpcErr.DetailsProto() {
+		versions := &structpb.Struct{}
+		if detail.UnmarshalTo(versions) != nil {
+			continue
+		}
+		data, jerr := versions.MarshalJSON()
+		if jerr != nil {
+			continue
+		}
+		return twirp.NewError(twirp.Aborted, psrpcErr.Error()).WithMeta(VersionsErrorMeta, string(data))
+	}
+	return err
+}
+
+// setVersionsHeader returns the current versions of the participant, or of the room metadata when there is no identity,
+// with a successful update. The update is applied, so failing to get the versions only leaves the header out.
+func (s *RoomService) setVersionsHeader(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) {
+	req, err := toStruct(&MetadataVersionsRequest{Room: string(roomName), Identity: string(identity)})
+	if err != nil {
+		return
+	}
+	res, err := s.moderationClient.GetMetadataVersions(ctx, s.topicFormatter.RoomTopic(ctx, roomName), req)
+	if err != nil {
+		logger.Warnw("could not get metadata versions", err, "room", roomName, "participant", identity)
+		return
+	}
+	versionsRes, err := requestFromStruct[MetadataVersionsResponse](res)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(&versionsRes.Versions)
+	if err != nil {
+		return
+	}
+	_ = twirp.SetHTTPResponseHeader(ctx, VersionsHeader, string(data))
+}
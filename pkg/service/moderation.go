@@ -0,0 +1,527 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/protocol/utils/xtwirp"
+	"github.com/livekit/psrpc"
+	"github.com/livekit/psrpc/pkg/client"
+	"github.com/livekit/psrpc/pkg/info"
+	"github.com/livekit/psrpc/pkg/rand"
+	"github.com/livekit/psrpc/pkg/server"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// Moderation methods (lobby, publish requests, subscription policy, limits and permissions, data policy and retention, room state,
+// metadata versions) are served by the node hosting the room,
+// they are exposed next to RoomService under the same twirp prefix. HandOffAgentJob is only used by the agent service.
+
+const moderationServiceName = "Moderation"
+
+var moderationMethods = []string{
+	"ListPendingParticipants",
+	"AdmitParticipant",
+	"RejectParticipant",
+	"UpdateLobby",
+	"GetLobby",
+	"ApprovePublishRequest",
+	"DenyPublishRequest",
+	"UpdateSubscriptionPolicy",
+	"GetSubscriptionPolicy",
+	"UpdateSubscriptionLimits",
+	"GetSubscriptionLimits",
+	"UpdateParticipantSubscriptionPermission",
+	"UpdateDataPolicy",
+	"GetDataPolicy",
+	"UpdateDataRetention",
+	"GetDataRetention",
+	"GetDataHistory",
+	"UpdateRoomState",
+	"GetRoomState",
+	"GetMetadataVersions",
+	"HandOffAgentJob",
+}
+
+// LobbyRequest is the JSON body of UpdateLobby and the response of lobby methods, it overrides for the room whether
+// joining participants wait in the lobby, which is otherwise decided by the lobby config, e.g. {"room": "event", "enabled": true}.
+// GetLobby only needs the room. Participants already waiting stay in the lobby until admitted or rejected.
+type LobbyRequest struct {
+	Room    string `json:"room"`
+	Enabled bool   `json:"enabled"`
+}
+
+// SubscriptionPolicyRequest is the JSON body of UpdateSubscriptionPolicy and the response of subscription policy methods,
+// e.g. {"room": "event", "rules": [{"publishers": "group == stage", "subscribe": true}]}.
+// GetSubscriptionPolicy only needs the room. As there is no protobuf message for it, it is carried as a google.protobuf.Struct.
+type SubscriptionPolicyRequest struct {
+	Room string `json:"room"`
+	rtc.SubscriptionPolicy
+}
+
+// SubscriptionLimitsRequest is the JSON body of UpdateSubscriptionLimits and the response of subscription limits methods,
+// limits apply to the participant with the identity, or to the room when there is no identity,
+// e.g. {"room": "event", "identity": "mobile-user", "max_bitrate": 1500000, "max_video_quality": "medium"}.
+// GetSubscriptionLimits only needs the room and identity. Empty limits remove them.
+type SubscriptionLimitsRequest struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity,omitempty"`
+	rtc.SubscriptionLimits
+}
+
+// ParticipantSubscriptionPermissionRequest is the JSON body of UpdateParticipantSubscriptionPermission, the permission
+// replaces the one the participant set for its tracks. It is a livekit.SubscriptionPermission in its JSON form and may
+// include rules over subscribers (see rtc.SubscriptionRulePrefix), e.g. {"room": "event", "identity": "speaker",
+// "permission": {"trackPermissions": [{"participantIdentity": "lk.rule:role == moderator", "allTracks": true}]}}.
+type ParticipantSubscriptionPermissionRequest struct {
+	Room       string          `json:"room"`
+	Identity   string          `json:"identity"`
+	Permission json.RawMessage `json:"permission"`
+}
+
+// DataPolicyRequest is the JSON body of UpdateDataPolicy and the response of data policy methods,
+// e.g. {"room": "event", "script": "if topic == \"question\" { action = \"redirect\"; destinations = [\"host\"] }"}.
+// GetDataPolicy only needs the room. An empty script removes the policy.
+type DataPolicyRequest struct {
+	Room string `json:"room"`
+	rtc.DataPolicy
+}
+
+// DataRetentionRequest is the JSON body of UpdateDataRetention and the response of data retention methods,
+// e.g. {"room": "event", "topics": [{"topic": "chat", "max_messages": 100, "max_age_seconds": 3600}]}.
+// GetDataRetention only needs the room. No topics removes the retention.
+type DataRetentionRequest struct {
+	Room string `json:"room"`
+	rtc.DataRetention
+}
+
+// DataHistoryRequest is the JSON body of GetDataHistory, messages of all retained topics are returned when there is no topic,
+// e.g. {"room": "event", "topic": "chat"}
+type DataHistoryRequest struct {
+	Room  string `json:"room"`
+	Topic string `json:"topic,omitempty"`
+}
+
+// DataHistoryResponse is the response of GetDataHistory, messages are oldest first and payloads are base64 encoded
+type DataHistoryResponse struct {
+	Room     string                    `json:"room"`
+	Messages []*rtc.DataHistoryMessage `json:"messages"`
+}
+
+// RoomStateRequest is the JSON body of UpdateRoomState, updates are applied together or not at all,
+// e.g. {"room": "event", "updates": [{"key": "slide", "value": "4", "expected_version": 12}, {"key": "poll", "delete": true}]}.
+// GetRoomState only needs the room.
+type RoomStateRequest struct {
+	Room    string                   `json:"room"`
+	Updates []*types.RoomStateUpdate `json:"updates,omitempty"`
+}
+
+// RoomStateResponse is the response of room state methods, the version and entries of the room state after the update
+type RoomStateResponse struct {
+	Room    string                           `json:"room"`
+	Version uint64                           `json:"version"`
+	Entries map[string]*types.RoomStateEntry `json:"entries"`
+}
+
+// MetadataVersionsRequest is the JSON body of GetMetadataVersions, the versions of the room metadata are returned
+// when there is no identity, e.g. {"room": "event", "identity": "speaker"}
+type MetadataVersionsRequest struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity,omitempty"`
+}
+
+// MetadataVersionsResponse is the response of GetMetadataVersions, versions to expect in a conditional update of the
+// participant (see ExpectedVersionsHeader), only the metadata version is set for the room
+type MetadataVersionsResponse struct {
+	Room     string                 `json:"room"`
+	Identity string                 `json:"identity,omitempty"`
+	Versions types.MetadataVersions `json:"versions"`
+}
+
+// AgentJobHandoffRequest is the body of HandOffAgentJob, the replacement is a livekit.Job in its JSON form taking over
+// the job with the id, it is assigned to a worker and has the identity of its agent. The response is empty once the agent
+// of the replacement joined the room, e.g. {"room": "event", "job_id": "AJ_1", "replacement": {"id": "AJ_2", ...}}.
+type AgentJobHandoffRequest struct {
+	Room        string          `json:"room"`
+	JobID       string          `json:"job_id"`
+	Replacement json.RawMessage `json:"replacement"`
+}
+
+func requestFromStruct[T any](s *structpb.Struct) (*T, error) {
+	data, err := s.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	req := new(T)
+	if err := json.Unmarshal(data, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func toStruct(v any) (*structpb.Struct, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	s := &structpb.Struct{}
+	if err := s.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+//counterfeiter:generate . ModerationClient
+type ModerationClient interface {
+	ListPendingParticipants(ctx context.Context, room rpc.RoomTopic, req *livekit.ListParticipantsRequest, opts ...psrpc.RequestOption) (*livekit.ListParticipantsResponse, error)
+	AdmitParticipant(ctx context.Context, room rpc.RoomTopic, req *livekit.RoomParticipantIdentity, opts ...psrpc.RequestOption) (*livekit.ParticipantInfo, error)
+	RejectParticipant(ctx context.Context, room rpc.RoomTopic, req *livekit.RoomParticipantIdentity, opts ...psrpc.RequestOption) (*livekit.RemoveParticipantResponse, error)
+	UpdateLobby(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error)
+	GetLobby(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error)
+	ApprovePublishRequest(ctx context.Context, room rpc.RoomTopic, req *livekit.RoomParticipantIdentity, opts ...psrpc.RequestOption) (*livekit.ParticipantInfo, error)
+	DenyPublishRequest(ctx context.Context, room rpc.RoomTopic, req *livekit.RoomParticipantIdentity, opts ...psrpc.RequestOption) (*livekit.ParticipantInfo, error)
+	UpdateSubscriptionPolicy(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error)
+	GetSubscriptionPolicy(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error)
+	UpdateSubscriptionLimits(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error)
+	GetSubscriptionLimits(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error)
+	UpdateParticipantSubscriptionPermission(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*livekit.ParticipantInfo, error)
+	UpdateDataPolicy(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error)
+	GetDataPolicy(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error)
+	UpdateDataRetention(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error)
+	GetDataRetention(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error)
+	GetDataHistory(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error)
+	UpdateRoomState(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error)
+	GetRoomState(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error)
+	GetMetadataVersions(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error)
+	HandOffAgentJob(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error)
+}
+
+type ModerationServerImpl interface {
+	ListPendingParticipants(context.Context, *livekit.ListParticipantsRequest) (*livekit.ListParticipantsResponse, error)
+	AdmitParticipant(context.Context, *livekit.RoomParticipantIdentity) (*livekit.ParticipantInfo, error)
+	RejectParticipant(context.Context, *livekit.RoomParticipantIdentity) (*livekit.RemoveParticipantResponse, error)
+	UpdateLobby(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	GetLobby(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	ApprovePublishRequest(context.Context, *livekit.RoomParticipantIdentity) (*livekit.ParticipantInfo, error)
+	DenyPublishRequest(context.Context, *livekit.RoomParticipantIdentity) (*livekit.ParticipantInfo, error)
+	UpdateSubscriptionPolicy(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	GetSubscriptionPolicy(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	UpdateSubscriptionLimits(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	GetSubscriptionLimits(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	UpdateParticipantSubscriptionPermission(context.Context, *structpb.Struct) (*livekit.ParticipantInfo, error)
+	UpdateDataPolicy(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	GetDataPolicy(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	UpdateDataRetention(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	GetDataRetention(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	GetDataHistory(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	UpdateRoomState(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	GetRoomState(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	GetMetadataVersions(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	HandOffAgentJob(context.Context, *structpb.Struct) (*structpb.Struct, error)
+}
+
+type moderationClient struct {
+	client *client.RPCClient
+}
+
+func NewModerationClient(params rpc.ClientParams) (ModerationClient, error) {
+	sd := &info.ServiceDefinition{
+		Name: moderationServiceName,
+		ID:   rand.NewClientID(),
+	}
+	for _, method := range moderationMethods {
+		sd.RegisterMethod(method, false, false, true, true)
+	}
+
+	rpcClient, err := client.NewRPCClient(sd, params.Bus, params.Options()...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &moderationClient{
+		client: rpcClient,
+	}, nil
+}
+
+func (c *moderationClient) ListPendingParticipants(ctx context.Context, room rpc.RoomTopic, req *livekit.ListParticipantsRequest, opts ...psrpc.RequestOption) (*livekit.ListParticipantsResponse, error) {
+	return client.RequestSingle[*livekit.ListParticipantsResponse](ctx, c.client, "ListPendingParticipants", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) AdmitParticipant(ctx context.Context, room rpc.RoomTopic, req *livekit.RoomParticipantIdentity, opts ...psrpc.RequestOption) (*livekit.ParticipantInfo, error) {
+	return client.RequestSingle[*livekit.ParticipantInfo](ctx, c.client, "AdmitParticipant", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) RejectParticipant(ctx context.Context, room rpc.RoomTopic, req *livekit.RoomParticipantIdentity, opts ...psrpc.RequestOption) (*livekit.RemoveParticipantResponse, error) {
+	return client.RequestSingle[*livekit.RemoveParticipantResponse](ctx, c.client, "RejectParticipant", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) UpdateLobby(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error) {
+	return client.RequestSingle[*structpb.Struct](ctx, c.client, "UpdateLobby", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) GetLobby(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error) {
+	return client.RequestSingle[*structpb.Struct](ctx, c.client, "GetLobby", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) ApprovePublishRequest(ctx context.Context, room rpc.RoomTopic, req *livekit.RoomParticipantIdentity, opts ...psrpc.RequestOption) (*livekit.ParticipantInfo, error) {
+	return client.RequestSingle[*livekit.ParticipantInfo](ctx, c.client, "ApprovePublishRequest", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) DenyPublishRequest(ctx context.Context, room rpc.RoomTopic, req *livekit.RoomParticipantIdentity, opts ...psrpc.RequestOption) (*livekit.ParticipantInfo, error) {
+	return client.RequestSingle[*livekit.ParticipantInfo](ctx, c.client, "DenyPublishRequest", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) UpdateSubscriptionPolicy(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error) {
+	return client.RequestSingle[*structpb.Struct](ctx, c.client, "UpdateSubscriptionPolicy", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) GetSubscriptionPolicy(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error) {
+	return client.RequestSingle[*structpb.Struct](ctx, c.client, "GetSubscriptionPolicy", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) UpdateSubscriptionLimits(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error) {
+	return client.RequestSingle[*structpb.Struct](ctx, c.client, "UpdateSubscriptionLimits", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) GetSubscriptionLimits(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error) {
+	return client.RequestSingle[*structpb.Struct](ctx, c.client, "GetSubscriptionLimits", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) UpdateParticipantSubscriptionPermission(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*livekit.ParticipantInfo, error) {
+	return client.RequestSingle[*livekit.ParticipantInfo](ctx, c.client, "UpdateParticipantSubscriptionPermission", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) UpdateDataPolicy(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error) {
+	return client.RequestSingle[*structpb.Struct](ctx, c.client, "UpdateDataPolicy", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) GetDataPolicy(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error) {
+	return client.RequestSingle[*structpb.Struct](ctx, c.client, "GetDataPolicy", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) UpdateDataRetention(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error) {
+	return client.RequestSingle[*structpb.Struct](ctx, c.client, "UpdateDataRetention", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) GetDataRetention(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error) {
+	return client.RequestSingle[*structpb.Struct](ctx, c.client, "GetDataRetention", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) GetDataHistory(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error) {
+	return client.RequestSingle[*structpb.Struct](ctx, c.client, "GetDataHistory", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) UpdateRoomState(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error) {
+	return client.RequestSingle[*structpb.Struct](ctx, c.client, "UpdateRoomState", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) GetRoomState(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error) {
+	return client.RequestSingle[*structpb.Struct](ctx, c.client, "GetRoomState", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) GetMetadataVersions(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error) {
+	return client.RequestSingle[*structpb.Struct](ctx, c.client, "GetMetadataVersions", []string{string(room)}, req, opts...)
+}
+
+func (c *moderationClient) HandOffAgentJob(ctx context.Context, room rpc.RoomTopic, req *structpb.Struct, opts ...psrpc.RequestOption) (*structpb.Struct, error) {
+	return client.RequestSingle[*structpb.Struct](ctx, c.client, "HandOffAgentJob", []string{string(room)}, req, opts...)
+}
+
+type moderationServer struct {
+	svc ModerationServerImpl
+	rpc *server.RPCServer
+}
+
+func newModerationServer(svc ModerationServerImpl, bus psrpc.MessageBus) *moderationServer {
+	sd := &info.ServiceDefinition{
+		Name: moderationServiceName,
+		ID:   rand.NewServerID(),
+	}
+
+	s := server.NewRPCServer(sd, bus)
+	for _, method := range moderationMethods {
+		sd.RegisterMethod(method, false, false, true, true)
+	}
+	return &moderationServer{
+		svc: svc,
+		rpc: s,
+	}
+}
+
+// RegisterAllRoomTopics registers handlers for the room, server is expected to be killed on failure
+func (s *moderationServer) RegisterAllRoomTopics(room rpc.RoomTopic) error {
+	topic := []string{string(room)}
+	if err := server.RegisterHandler(s.rpc, "ListPendingParticipants", topic, s.svc.ListPendingParticipants, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, "AdmitParticipant", topic, s.svc.AdmitParticipant, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, "RejectParticipant", topic, s.svc.RejectParticipant, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, "UpdateLobby", topic, s.svc.UpdateLobby, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, "GetLobby", topic, s.svc.GetLobby, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, "ApprovePublishRequest", topic, s.svc.ApprovePublishRequest, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, "DenyPublishRequest", topic, s.svc.DenyPublishRequest, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, "UpdateSubscriptionPolicy", topic, s.svc.UpdateSubscriptionPolicy, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, "GetSubscriptionPolicy", topic, s.svc.GetSubscriptionPolicy, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, "UpdateSubscriptionLimits", topic, s.svc.UpdateSubscriptionLimits, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, "GetSubscriptionLimits", topic, s.svc.GetSubscriptionLimits, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, "UpdateParticipantSubscriptionPermission", topic, s.svc.UpdateParticipantSubscriptionPermission, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, "UpdateDataPolicy", topic, s.svc.UpdateDataPolicy, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, "GetDataPolicy", topic, s.svc.GetDataPolicy, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, "UpdateDataRetention", topic, s.svc.UpdateDataRetention, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, "GetDataRetention", topic, s.svc.GetDataRetention, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, "GetDataHistory", topic, s.svc.GetDataHistory, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, "UpdateRoomState", topic, s.svc.UpdateRoomState, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, "GetRoomState", topic, s.svc.GetRoomState, nil); err != nil {
+		return err
+	}
+	if err := server.RegisterHandler(s.rpc, "GetMetadataVersions", topic, s.svc.GetMetadataVersions, nil); err != nil {
+		return err
+	}
+	return server.RegisterHandler(s.rpc, "HandOffAgentJob", topic, s.svc.HandOffAgentJob, nil)
+}
+
+func (s *moderationServer) Kill() {
+	s.rpc.Close(true)
+}
+
+// -------------------------------------------
+
+// SetupModerationRoutes serves the moderation methods of RoomService with the twirp wire protocol
+func SetupModerationRoutes(mux *http.ServeMux, svc *RoomService) {
+	prefix := livekit.RoomServicePathPrefix
+	mux.Handle(prefix+"ListPendingParticipants", twirpMethodHandler(svc.ListPendingParticipants))
+	mux.Handle(prefix+"AdmitParticipant", twirpMethodHandler(svc.AdmitParticipant))
+	mux.Handle(prefix+"RejectParticipant", twirpMethodHandler(svc.RejectParticipant))
+	mux.Handle(prefix+"UpdateLobby", twirpMethodHandler(svc.UpdateLobby))
+	mux.Handle(prefix+"GetLobby", twirpMethodHandler(svc.GetLobby))
+	mux.Handle(prefix+"ApprovePublishRequest", twirpMethodHandler(svc.ApprovePublishRequest))
+	mux.Handle(prefix+"DenyPublishRequest", twirpMethodHandler(svc.DenyPublishRequest))
+	mux.Handle(prefix+"UpdateSubscriptionPolicy", twirpMethodHandler(svc.UpdateSubscriptionPolicy))
+	mux.Handle(prefix+"GetSubscriptionPolicy", twirpMethodHandler(svc.GetSubscriptionPolicy))
+	mux.Handle(prefix+"UpdateSubscriptionLimits", twirpMethodHandler(svc.UpdateSubscriptionLimits))
+	mux.Handle(prefix+"GetSubscriptionLimits", twirpMethodHandler(svc.GetSubscriptionLimits))
+	mux.Handle(prefix+"UpdateParticipantSubscriptionPermission", twirpMethodHandler(svc.UpdateParticipantSubscriptionPermission))
+	mux.Handle(prefix+"UpdateDataPolicy", twirpMethodHandler(svc.UpdateDataPolicy))
+	mux.Handle(prefix+"GetDataPolicy", twirpMethodHandler(svc.GetDataPolicy))
+	mux.Handle(prefix+"UpdateDataRetention", twirpMethodHandler(svc.UpdateDataRetention))
+	mux.Handle(prefix+"GetDataRetention", twirpMethodHandler(svc.GetDataRetention))
+	mux.Handle(prefix+"GetDataHistory", twirpMethodHandler(svc.GetDataHistory))
+	mux.Handle(prefix+"UpdateRoomState", twirpMethodHandler(svc.UpdateRoomState))
+	mux.Handle(prefix+"GetRoomState", twirpMethodHandler(svc.GetRoomState))
+	mux.Handle(prefix+"GetMetadataVersions", twirpMethodHandler(svc.GetMetadataVersions))
+}
+
+func twirpMethodHandler[Req any, Res proto.Message, ReqPtr interface {
+	*Req
+	proto.Message
+}](method func(context.Context, ReqPtr) (Res, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			_ = twirp.WriteError(w, twirp.NewError(twirp.BadRoute, "unsupported method "+r.Method))
+			return
+		}
+
+		contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		isJSON := contentType == "application/json"
+		if !isJSON && contentType != "application/protobuf" {
+			_ = twirp.WriteError(w, twirp.NewError(twirp.BadRoute, "unexpected Content-Type: "+contentType))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			_ = twirp.WriteError(w, twirp.WrapError(twirp.NewError(twirp.Malformed, "failed to read request body"), err))
+			return
+		}
+		req := ReqPtr(new(Req))
+		if isJSON {
+			err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, req)
+		} else {
+			err = proto.Unmarshal(body, req)
+		}
+		if err != nil {
+			_ = twirp.WriteError(w, twirp.WrapError(twirp.NewError(twirp.Malformed, "failed to parse request body"), err))
+			return
+		}
+
+		res, err := method(r.Context(), req)
+		if err != nil {
+			_ = twirp.WriteError(w, xtwirp.ToError(err))
+			return
+		}
+
+		var out []byte
+		if isJSON {
+			out, err = protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(res)
+		} else {
+			out, err = proto.Marshal(res)
+		}
+		if err != nil {
+			_ = twirp.WriteError(w, twirp.InternalErrorWith(err))
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(out)
+	})
+}
@@ -16,7 +16,9 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"slices"
 	"strconv"
 	"strings"
@@ -34,6 +36,7 @@ import (
 	"github.com/livekit/protocol/utils/guid"
 	"github.com/livekit/psrpc"
 
+	"github.com/livekit/livekit-server/pkg/rtc"
 	"github.com/livekit/livekit-server/version"
 )
 
@@ -65,6 +68,19 @@ const (
 	AgentDispatchPrefix = "agent_dispatch:"
 	AgentJobPrefix      = "agent_job:"
 
+	// AgentJobHistoryKey is hash of job_id => ended Job
+	AgentJobHistoryKey = "agent_job_history"
+	// AgentJobHistoryIndexKey is sorted set of job_id, scored by end time in milliseconds
+	AgentJobHistoryIndexKey = "agent_job_history_index"
+
+	// RoomSnapshotsPrefix is hash of room_name => room snapshot, per node key
+	RoomSnapshotsPrefix = "room_snapshots:"
+
+	// DataHistoryPrefix is list of JSON encoded data messages of a room topic, oldest first
+	DataHistoryPrefix = "data_history:"
+	// DataHistoryTopicsPrefix is set of topics of a room with a history
+	DataHistoryTopicsPrefix = "data_history_topics:"
+
 	maxRetries = 5
 )
 
@@ -941,6 +957,83 @@ func (s *RedisStore) DeleteAgentJob(_ context.Context, job *livekit.Job) error {
 	return s.rc.HDel(s.ctx, key, job.Id).Err()
 }
 
+func (s *RedisStore) StoreAgentJobHistory(_ context.Context, job *livekit.Job, maxJobs int, ttl time.Duration) error {
+	data, err := proto.Marshal(agentJobHistoryEntry(job))
+	if err != nil {
+		return err
+	}
+
+	endedAt := time.Unix(0, job.GetState().GetEndedAt())
+	if endedAt.UnixNano() == 0 {
+		endedAt = time.Now()
+	}
+
+	tx := s.rc.TxPipeline()
+	tx.HSet(s.ctx, AgentJobHistoryKey, job.Id, data)
+	tx.ZAdd(s.ctx, AgentJobHistoryIndexKey, redis.Z{Score: float64(endedAt.UnixMilli()), Member: job.Id})
+	if _, err := tx.Exec(s.ctx); err != nil {
+		return err
+	}
+
+	var expired []string
+	if ttl > 0 {
+		cutoff := strconv.FormatInt(time.Now().Add(-ttl).UnixMilli(), 10)
+		expired, err = s.rc.ZRangeByScore(s.ctx, AgentJobHistoryIndexKey, &redis.ZRangeBy{Min: "-inf", Max: "(" + cutoff}).Result()
+		if err != nil {
+			return err
+		}
+	}
+	if maxJobs > 0 {
+		// oldest first, beyond the latest maxJobs
+		excess, err := s.rc.ZRange(s.ctx, AgentJobHistoryIndexKey, 0, int64(-maxJobs-1)).Result()
+		if err != nil {
+			return err
+		}
+		expired = append(expired, excess...)
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	pp := s.rc.Pipeline()
+	pp.HDel(s.ctx, AgentJobHistoryKey, expired...)
+	pp.ZRem(s.ctx, AgentJobHistoryIndexKey, expired)
+	_, err = pp.Exec(s.ctx)
+	return err
+}
+
+func (s *RedisStore) ListAgentJobHistory(_ context.Context, filter AgentJobFilter) ([]*livekit.Job, error) {
+	ids, err := s.rc.ZRevRange(s.ctx, AgentJobHistoryIndexKey, 0, -1).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	data, err := s.rc.HMGet(s.ctx, AgentJobHistoryKey, ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*livekit.Job
+	for _, d := range data {
+		// removed between reads
+		if d == nil {
+			continue
+		}
+		job := &livekit.Job{}
+		if err = proto.Unmarshal([]byte(d.(string)), job); err != nil {
+			return nil, err
+		}
+		if !filter.Match(job) {
+			continue
+		}
+		jobs = append(jobs, job)
+		if filter.Limit > 0 && len(jobs) == filter.Limit {
+			break
+		}
+	}
+	return jobs, nil
+}
+
 func redisStoreOne(ctx context.Context, s *RedisStore, key, id string, p proto.Message) error {
 	if id == "" {
 		return errors.New("id is not set")
@@ -972,6 +1065,102 @@ func redisLoadOne[T any, P protoMsg[T]](ctx context.Context, s *RedisStore, key,
 	return p, err
 }
 
+func (s *RedisStore) StoreRoomSnapshot(_ context.Context, nodeKey string, snapshot *rtc.RoomSnapshot) error {
+	data, err := snapshot.Marshal()
+	if err != nil {
+		return err
+	}
+
+	return s.rc.HSet(s.ctx, RoomSnapshotsPrefix+nodeKey, snapshot.Room.Name, data).Err()
+}
+
+func (s *RedisStore) TakeRoomSnapshots(_ context.Context, nodeKey string) ([]*rtc.RoomSnapshot, error) {
+	key := RoomSnapshotsPrefix + nodeKey
+	data, err := s.rc.HGetAll(s.ctx, key).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]*rtc.RoomSnapshot, 0, len(data))
+	for roomName, d := range data {
+		// only the node removing the snapshot gets it
+		if removed, err := s.rc.HDel(s.ctx, key, roomName).Result(); err != nil || removed == 0 {
+			continue
+		}
+
+		snapshot, err := rtc.UnmarshalRoomSnapshot([]byte(d))
+		if err != nil {
+			logger.Warnw("could not decode room snapshot", err, "room", roomName)
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// the topic is escaped so that it cannot contain the separator, keys of different rooms cannot collide
+func dataHistoryKey(roomName livekit.RoomName, topic string) string {
+	return DataHistoryPrefix + string(roomName) + ":" + url.QueryEscape(topic)
+}
+
+func (s *RedisStore) StoreDataMessage(_ context.Context, roomName livekit.RoomName, msg *rtc.DataHistoryMessage, maxMessages int, maxAge time.Duration) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	key := dataHistoryKey(roomName, msg.Topic)
+	topicsKey := DataHistoryTopicsPrefix + string(roomName)
+	tx := s.rc.TxPipeline()
+	tx.RPush(s.ctx, key, data)
+	if maxMessages > 0 {
+		tx.LTrim(s.ctx, key, int64(-maxMessages), -1)
+	}
+	tx.SAdd(s.ctx, topicsKey, msg.Topic)
+	if maxAge > 0 {
+		// older messages are filtered out when read, the history expires once nothing was sent for maxAge
+		tx.Expire(s.ctx, key, maxAge)
+		tx.Expire(s.ctx, topicsKey, maxAge)
+	}
+	_, err = tx.Exec(s.ctx)
+	return err
+}
+
+func (s *RedisStore) ListDataMessages(_ context.Context, roomName livekit.RoomName, topic string) ([]*rtc.DataHistoryMessage, error) {
+	data, err := s.rc.LRange(s.ctx, dataHistoryKey(roomName, topic), 0, -1).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]*rtc.DataHistoryMessage, 0, len(data))
+	for _, d := range data {
+		msg := &rtc.DataHistoryMessage{}
+		if err := json.Unmarshal([]byte(d), msg); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+func (s *RedisStore) DeleteDataHistory(_ context.Context, roomName livekit.RoomName) error {
+	topicsKey := DataHistoryTopicsPrefix + string(roomName)
+	topics, err := s.rc.SMembers(s.ctx, topicsKey).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	keys := []string{topicsKey}
+	for _, topic := range topics {
+		keys = append(keys, dataHistoryKey(roomName, topic))
+	}
+	return s.rc.Del(s.ctx, keys...).Err()
+}
+
 func redisLoadAll[T any, P protoMsg[T]](ctx context.Context, s *RedisStore, key string) ([]P, error) {
 	data, err := s.rc.HVals(s.ctx, key).Result()
 	if err == redis.Nil {
@@ -29,6 +29,7 @@ import (
 	"github.com/livekit/protocol/utils"
 	"github.com/livekit/protocol/utils/guid"
 
+	"github.com/livekit/livekit-server/pkg/rtc"
 	"github.com/livekit/livekit-server/pkg/service"
 )
 
@@ -384,6 +385,103 @@ func TestAgentStore(t *testing.T) {
 	require.Equal(t, 0, len(rd))
 }
 
+func TestAgentJobHistory(t *testing.T) {
+	ctx := context.Background()
+
+	endedJob := func(id, roomName, agentName string, status livekit.JobStatus, endedAt time.Time) *livekit.Job {
+		return &livekit.Job{
+			Id:        id,
+			Type:      livekit.JobType_JT_ROOM,
+			Room:      &livekit.Room{Name: roomName, Sid: "RM_" + roomName, Metadata: "metadata"},
+			AgentName: agentName,
+			State: &livekit.JobState{
+				Status:  status,
+				EndedAt: endedAt.UnixNano(),
+			},
+		}
+	}
+
+	stores := map[string]service.AgentJobHistoryStore{
+		"redis": redisStore(t),
+		"local": service.NewLocalStore(),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			jobs := []*livekit.Job{
+				endedJob("expired", "room_a", "agent_a", livekit.JobStatus_JS_SUCCESS, now.Add(-2*time.Hour)),
+				endedJob("job_1", "room_a", "agent_a", livekit.JobStatus_JS_SUCCESS, now.Add(-3*time.Minute)),
+				endedJob("job_2", "room_a", "agent_b", livekit.JobStatus_JS_FAILED, now.Add(-2*time.Minute)),
+				endedJob("job_3", "room_b", "agent_a", livekit.JobStatus_JS_FAILED, now.Add(-time.Minute)),
+				endedJob("job_4", "room_b", "agent_b", livekit.JobStatus_JS_SUCCESS, now),
+			}
+			for _, job := range jobs {
+				require.NoError(t, store.StoreAgentJobHistory(ctx, job, 3, time.Hour))
+			}
+
+			ids := func(filter service.AgentJobFilter) []string {
+				res, err := store.ListAgentJobHistory(ctx, filter)
+				require.NoError(t, err)
+				var ids []string
+				for _, job := range res {
+					ids = append(ids, job.Id)
+				}
+				return ids
+			}
+
+			require.Equal(t, []string{"job_4", "job_3", "job_2"}, ids(service.AgentJobFilter{}))
+			require.Equal(t, []string{"job_2"}, ids(service.AgentJobFilter{RoomName: "room_a"}))
+			require.Equal(t, []string{"job_3"}, ids(service.AgentJobFilter{AgentName: "agent_a"}))
+			require.Equal(t, []string{"job_3", "job_2"}, ids(service.AgentJobFilter{Statuses: []livekit.JobStatus{livekit.JobStatus_JS_FAILED}}))
+			require.Equal(t, []string{"job_4"}, ids(service.AgentJobFilter{Limit: 1}))
+
+			res, err := store.ListAgentJobHistory(ctx, service.AgentJobFilter{Limit: 1})
+			require.NoError(t, err)
+			require.True(t, proto.Equal(&livekit.Room{Name: "room_b", Sid: "RM_room_b"}, res[0].Room))
+		})
+	}
+}
+
+func TestDataHistoryStore(t *testing.T) {
+	ctx := context.Background()
+	roomName := livekit.RoomName("data_history:" + guid.New("RM_"))
+
+	stores := map[string]service.DataHistoryStore{
+		"redis": redisStore(t),
+		"local": service.NewLocalStore(),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			msgs := []*rtc.DataHistoryMessage{
+				{Topic: "chat", Identity: "p1", Payload: []byte("expired"), Timestamp: now.Add(-2 * time.Hour).UnixMilli()},
+				{Topic: "chat", Identity: "p1", Payload: []byte("one"), Timestamp: now.Add(-2 * time.Minute).UnixMilli()},
+				{Topic: "notes:private", Identity: "p2", DestinationIdentities: []string{"p1"}, Payload: []byte("note"), Timestamp: now.UnixMilli()},
+				{Topic: "chat", Identity: "p2", Payload: []byte("two"), Timestamp: now.Add(-time.Minute).UnixMilli()},
+				{Topic: "chat", Payload: []byte("three"), Timestamp: now.UnixMilli()},
+			}
+			for _, msg := range msgs {
+				require.NoError(t, store.StoreDataMessage(ctx, roomName, msg, 3, time.Hour))
+			}
+
+			chat, err := store.ListDataMessages(ctx, roomName, "chat")
+			require.NoError(t, err)
+			require.Len(t, chat, 3)
+			require.Equal(t, "two", string(chat[1].Payload))
+			require.Equal(t, "p2", chat[1].Identity)
+
+			notes, err := store.ListDataMessages(ctx, roomName, "notes:private")
+			require.NoError(t, err)
+			require.Equal(t, msgs[2:3], notes)
+
+			require.NoError(t, store.DeleteDataHistory(ctx, roomName))
+			chat, err = store.ListDataMessages(ctx, roomName, "chat")
+			require.NoError(t, err)
+			require.Empty(t, chat)
+		})
+	}
+}
+
 func compareIngressInfo(t *testing.T, expected, v *livekit.IngressInfo) {
 	require.Equal(t, expected.IngressId, v.IngressId)
 	require.Equal(t, expected.StreamKey, v.StreamKey)
@@ -0,0 +1,91 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/relay"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+var (
+	ErrRelayNodeNotFound = errors.New("relay node not found")
+	ErrRelaySecretNotSet = errors.New("relay requires relay.secret to be configured")
+)
+
+func newRelayManager(
+	conf *config.Config,
+	rtcConf *rtc.WebRTCConfig,
+	currentNode routing.LocalNode,
+	router routing.Router,
+	registry relay.Registry,
+	telemetry telemetry.TelemetryService,
+	versionGenerator utils.TimedVersionGenerator,
+) (*rtc.RelayManager, error) {
+	if conf.Relay.Secret == "" {
+		return nil, ErrRelaySecretNotSet
+	}
+	if len(conf.Relay.Secret) < 32 && !conf.Development {
+		logger.Errorw("relay secret is too short, should be at least 32 characters for security", nil)
+	}
+
+	relayLogger := logger.GetLogger().WithComponent("relay")
+	transport := relay.NewTransport(relay.TransportParams{
+		NodeID:        currentNode.NodeID(),
+		ListenAddress: fmt.Sprintf(":%d", conf.Relay.Port),
+		Secret:        conf.Relay.Secret,
+		ResolveNode: func(nodeID livekit.NodeID) (string, error) {
+			nodes, err := router.ListNodes()
+			if err != nil {
+				return "", err
+			}
+			for _, node := range nodes {
+				if livekit.NodeID(node.Id) == nodeID {
+					return net.JoinHostPort(node.Ip, strconv.Itoa(int(conf.Relay.Port))), nil
+				}
+			}
+			return "", ErrRelayNodeNotFound
+		},
+		Logger: relayLogger,
+	})
+	if err := transport.Start(); err != nil {
+		return nil, err
+	}
+
+	return rtc.NewRelayManager(rtc.RelayManagerParams{
+		NodeID:           currentNode.NodeID(),
+		Transport:        transport,
+		Registry:         registry,
+		ReceiverConfig:   rtcConf.Receiver,
+		SubscriberConfig: rtcConf.Subscriber,
+		PLIThrottle:      conf.RTC.PLIThrottle,
+		AudioConfig:      conf.Audio,
+		VideoConfig:      conf.Video,
+		Telemetry:        telemetry,
+		VersionGenerator: versionGenerator,
+		Logger:           relayLogger,
+	}), nil
+}
@@ -17,8 +17,11 @@ package service
 import (
 	"context"
 	"errors"
+	"slices"
 	"time"
 
+	"golang.org/x/exp/maps"
+
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/utils"
@@ -26,28 +29,31 @@ import (
 	"github.com/livekit/psrpc"
 
 	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/relay"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/routing/selector"
 )
 
 type StandardRoomAllocator struct {
-	config    *config.Config
-	router    routing.Router
-	selector  selector.NodeSelector
-	roomStore ObjectStore
+	config        *config.Config
+	router        routing.Router
+	selector      selector.NodeSelector
+	roomStore     ObjectStore
+	relayRegistry relay.Registry
 }
 
-func NewRoomAllocator(conf *config.Config, router routing.Router, rs ObjectStore) (RoomAllocator, error) {
+func NewRoomAllocator(conf *config.Config, router routing.Router, rs ObjectStore, relayRegistry relay.Registry) (RoomAllocator, error) {
 	ns, err := selector.CreateNodeSelector(conf)
 	if err != nil {
 		return nil, err
 	}
 
 	return &StandardRoomAllocator{
-		config:    conf,
-		router:    router,
-		selector:  ns,
-		roomStore: rs,
+		config:        conf,
+		router:        router,
+		selector:      ns,
+		roomStore:     rs,
+		relayRegistry: relayRegistry,
 	}, nil
 }
 
@@ -172,6 +178,54 @@ func (r *StandardRoomAllocator) SelectRoomNode(ctx context.Context, roomName liv
 	return nil
 }
 
+// SelectParticipantNode selects the node a participant connects to. With relay enabled, participants from a region
+// other than the one of the node hosting the room connect to a node in their region, preferring nodes already hosting the room.
+func (r *StandardRoomAllocator) SelectParticipantNode(ctx context.Context, roomName livekit.RoomName, region string) (livekit.NodeID, error) {
+	roomNode, err := r.router.GetNodeForRoom(ctx, roomName)
+	if err != nil {
+		return "", err
+	}
+
+	roomNodeID := livekit.NodeID(roomNode.Id)
+	if !r.config.Relay.Enabled || region == "" || roomNode.Region == region {
+		return roomNodeID, nil
+	}
+
+	nodes, err := r.router.ListNodes()
+	if err != nil {
+		return "", err
+	}
+
+	candidates := make(map[livekit.NodeID]*livekit.Node)
+	for _, node := range selector.GetAvailableNodes(nodes) {
+		if node.Region == region && !selector.LimitsReached(r.config.Limit, node.Stats) {
+			candidates[livekit.NodeID(node.Id)] = node
+		}
+	}
+	if len(candidates) == 0 {
+		return roomNodeID, nil
+	}
+
+	hosting, err := r.relayRegistry.GetNodes(ctx, roomName)
+	if err != nil {
+		logger.Warnw("could not get room nodes", err, "room", roomName)
+	}
+	slices.Sort(hosting)
+	for _, nodeID := range hosting {
+		if _, ok := candidates[nodeID]; ok {
+			return nodeID, nil
+		}
+	}
+
+	node, err := r.selector.SelectNode(maps.Values(candidates))
+	if err != nil {
+		return roomNodeID, nil
+	}
+
+	logger.Infow("selected relay node for participant", "room", roomName, "region", region, "selectedNodeID", node.Id)
+	return livekit.NodeID(node.Id), nil
+}
+
 func (r *StandardRoomAllocator) ValidateCreateRoom(ctx context.Context, roomName livekit.RoomName) error {
 	// when auto create is disabled, we'll check to ensure it's already created
 	if !r.config.Room.AutoCreate {
@@ -23,6 +23,7 @@ import (
 	"github.com/livekit/protocol/livekit"
 
 	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/relay"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
 	"github.com/livekit/livekit-server/pkg/service"
@@ -85,6 +86,65 @@ func SelectRoomNode(t *testing.T) {
 	})
 }
 
+func TestSelectParticipantNode(t *testing.T) {
+	newAllocator := func(t *testing.T, relayEnabled bool, registry relay.Registry) service.RoomAllocator {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.Relay.Enabled = relayEnabled
+
+		router := &routingfakes.FakeRouter{}
+		router.GetNodeForRoomReturns(&livekit.Node{Id: "ND_us", Region: "us", State: livekit.NodeState_SERVING}, nil)
+		router.ListNodesReturns([]*livekit.Node{
+			{Id: "ND_us", Region: "us", State: livekit.NodeState_SERVING},
+			{Id: "ND_eu1", Region: "eu", State: livekit.NodeState_SERVING},
+			{Id: "ND_eu2", Region: "eu", State: livekit.NodeState_SERVING},
+			{Id: "ND_ap", Region: "ap", State: livekit.NodeState_SHUTTING_DOWN},
+		}, nil)
+
+		ra, err := service.NewRoomAllocator(conf, router, &servicefakes.FakeObjectStore{}, registry)
+		require.NoError(t, err)
+		return ra
+	}
+
+	t.Run("room node when relay is disabled", func(t *testing.T) {
+		ra := newAllocator(t, false, relay.NewLocalRegistry())
+		nodeID, err := ra.SelectParticipantNode(context.Background(), "room", "eu")
+		require.NoError(t, err)
+		require.Equal(t, livekit.NodeID("ND_us"), nodeID)
+	})
+
+	t.Run("room node in same region", func(t *testing.T) {
+		ra := newAllocator(t, true, relay.NewLocalRegistry())
+		nodeID, err := ra.SelectParticipantNode(context.Background(), "room", "us")
+		require.NoError(t, err)
+		require.Equal(t, livekit.NodeID("ND_us"), nodeID)
+	})
+
+	t.Run("node in participant region", func(t *testing.T) {
+		ra := newAllocator(t, true, relay.NewLocalRegistry())
+		nodeID, err := ra.SelectParticipantNode(context.Background(), "room", "eu")
+		require.NoError(t, err)
+		require.Contains(t, []livekit.NodeID{"ND_eu1", "ND_eu2"}, nodeID)
+	})
+
+	t.Run("prefers node already hosting the room", func(t *testing.T) {
+		registry := relay.NewLocalRegistry()
+		require.NoError(t, registry.AddNode(context.Background(), "room", "ND_eu2"))
+
+		ra := newAllocator(t, true, registry)
+		nodeID, err := ra.SelectParticipantNode(context.Background(), "room", "eu")
+		require.NoError(t, err)
+		require.Equal(t, livekit.NodeID("ND_eu2"), nodeID)
+	})
+
+	t.Run("room node when no node is available in region", func(t *testing.T) {
+		ra := newAllocator(t, true, relay.NewLocalRegistry())
+		nodeID, err := ra.SelectParticipantNode(context.Background(), "room", "ap")
+		require.NoError(t, err)
+		require.Equal(t, livekit.NodeID("ND_us"), nodeID)
+	})
+}
+
 func newTestRoomAllocator(t *testing.T, conf *config.Config, node *livekit.Node) (service.RoomAllocator, *config.Config) {
 	store := &servicefakes.FakeObjectStore{}
 	store.LoadRoomReturns(nil, nil, service.ErrRoomNotFound)
@@ -92,7 +152,7 @@ func newTestRoomAllocator(t *testing.T, conf *config.Config, node *livekit.Node)
 
 	router.GetNodeForRoomReturns(node, nil)
 
-	ra, err := service.NewRoomAllocator(conf, router, store)
+	ra, err := service.NewRoomAllocator(conf, router, store, relay.NewLocalRegistry())
 	require.NoError(t, err)
 	return ra, conf
 }
@@ -26,6 +26,8 @@ import (
 
 	"github.com/pkg/errors"
 	"golang.org/x/exp/maps"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
 
 	"github.com/livekit/mediatransportutil/pkg/rtcconfig"
 	"github.com/livekit/protocol/auth"
@@ -45,6 +47,7 @@ import (
 
 	"github.com/livekit/livekit-server/pkg/clientconfiguration"
 	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/relay"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/rtc"
 	"github.com/livekit/livekit-server/pkg/rtc/types"
@@ -90,6 +93,7 @@ type RoomManager struct {
 
 	roomServers                  utils.MultitonService[rpc.RoomTopic]
 	agentDispatchServers         utils.MultitonService[rpc.RoomTopic]
+	moderationServers            utils.MultitonService[rpc.RoomTopic]
 	participantServers           utils.MultitonService[rpc.ParticipantTopic]
 	httpSignalParticipantServers utils.MultitonService[rpc.ParticipantTopic]
 	whipParticipantServers       utils.MultitonService[rpc.ParticipantTopic]
@@ -98,6 +102,14 @@ type RoomManager struct {
 
 	forwardStats *sfu.ForwardStats
 
+	evacuator   *NodeEvacuator
+	snapshotter *RoomSnapshotter
+
+	// set when relay is enabled, rooms then span the nodes participants connect to
+	relayManager *rtc.RelayManager
+
+	nodeBandwidthArbiter *rtc.NodeBandwidthArbiter
+
 	rpc.UnimplementedParticipantServer
 	rpc.UnimplementedRoomServer
 	rpc.UnimplementedRoomManagerServer
@@ -109,9 +121,11 @@ func NewLocalRoomManager(
 	currentNode routing.LocalNode,
 	router routing.Router,
 	roomAllocator RoomAllocator,
+	relayRegistry relay.Registry,
 	telemetry telemetry.TelemetryService,
 	agentClient agent.Client,
 	agentStore AgentStore,
+	snapshotStore RoomSnapshotStore,
 	egressLauncher rtc.EgressLauncher,
 	versionGenerator utils.TimedVersionGenerator,
 	turnAuthHandler *TURNAuthHandler,
@@ -174,9 +188,75 @@ func NewLocalRoomManager(
 		return nil, err
 	}
 
+	r.evacuator, err = NewNodeEvacuator(conf, currentNode, router, r.getRooms)
+	if err != nil {
+		return nil, err
+	}
+
+	r.snapshotter = NewRoomSnapshotter(conf, snapshotStore, roomStore, router, currentNode, r.onRestoredRoomExpired)
+
+	r.nodeBandwidthArbiter, err = rtc.NewNodeBandwidthArbiter(rtc.NodeBandwidthArbiterParams{
+		Config:       conf.RTC.CongestionControl.NodeBandwidth,
+		Participants: r.getLocalParticipants,
+		Logger:       logger.GetLogger(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.nodeBandwidthArbiter.Start()
+
+	if conf.Relay.Enabled {
+		r.relayManager, err = newRelayManager(conf, rtcConf, currentNode, router, relayRegistry, telemetry, versionGenerator)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return r, nil
 }
 
+func (r *RoomManager) getRooms() []*rtc.Room {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return maps.Values(r.rooms)
+}
+
+func (r *RoomManager) getLocalParticipants() []types.LocalParticipant {
+	var participants []types.LocalParticipant
+	for _, room := range r.getRooms() {
+		participants = append(participants, room.GetLocalParticipants()...)
+	}
+	return participants
+}
+
+// Evacuator moves rooms on this node to other nodes, used when taking a node out of service
+func (r *RoomManager) Evacuator() *NodeEvacuator {
+	return r.evacuator
+}
+
+// SnapshotRooms saves rooms on this node for participants to resume after a restart
+func (r *RoomManager) SnapshotRooms() {
+	r.snapshotter.Snapshot(r.getRooms())
+}
+
+// RestoreRooms restores rooms saved before a restart
+func (r *RoomManager) RestoreRooms(ctx context.Context) error {
+	return r.snapshotter.Restore(ctx)
+}
+
+func (r *RoomManager) onRestoredRoomExpired(roomName livekit.RoomName) {
+	if r.GetRoom(context.Background(), roomName) != nil {
+		// some participants resumed, room closes as usual
+		return
+	}
+
+	// nobody resumed, room ends without having been created
+	r.telemetry.RoomEnded(context.Background(), &livekit.Room{Name: string(roomName)})
+	if err := r.deleteRoom(context.Background(), roomName); err != nil {
+		logger.Errorw("could not delete room", err, "room", roomName)
+	}
+}
+
 func (r *RoomManager) GetRoom(_ context.Context, roomName livekit.RoomName) *rtc.Room {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
@@ -248,6 +328,7 @@ func (r *RoomManager) Stop() {
 	r.whipServer.Kill()
 	r.roomServers.Kill()
 	r.agentDispatchServers.Kill()
+	r.moderationServers.Kill()
 	r.participantServers.Kill()
 	r.httpSignalParticipantServers.Kill()
 	r.whipParticipantServers.Kill()
@@ -262,6 +343,11 @@ func (r *RoomManager) Stop() {
 	}
 
 	r.iceConfigCache.Stop()
+	r.nodeBandwidthArbiter.Stop()
+
+	if r.relayManager != nil {
+		r.relayManager.Stop()
+	}
 
 	if r.forwardStats != nil {
 		r.forwardStats.Stop()
@@ -269,7 +355,7 @@ func (r *RoomManager) Stop() {
 }
 
 func (r *RoomManager) CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (*livekit.Room, error) {
-	room, err := r.getOrCreateRoom(ctx, req)
+	room, err := r.getOrCreateRoom(ctx, req, false)
 	if err != nil {
 		return nil, err
 	}
@@ -288,8 +374,14 @@ func (r *RoomManager) StartSession(
 ) error {
 	sessionStartTime := time.Now()
 
+	if r.snapshotter.IsSnapshotting() {
+		// rooms have been saved for restart, sessions started now would be lost
+		return ErrNodeShuttingDown
+	}
+
 	createRoom := pi.CreateRoom
-	room, err := r.getOrCreateRoom(ctx, createRoom)
+	migration := r.isMigratingIn(ctx, livekit.RoomName(createRoom.Name), &pi)
+	room, err := r.getOrCreateRoom(ctx, createRoom, migration)
 	if err != nil {
 		return err
 	}
@@ -379,7 +471,8 @@ func (r *RoomManager) StartSession(
 		// we need to clean up the existing participant, so a new one can join
 		participant.GetLogger().Infow("removing duplicate participant")
 		room.RemoveParticipant(participant.Identity(), participant.ID(), types.ParticipantCloseReasonDuplicateIdentity)
-	} else if pi.Reconnect {
+		migration = false
+	} else if pi.Reconnect && !migration {
 		// send leave request if participant is trying to reconnect without keep subscribe state
 		// but missing from the room
 		var leave *livekit.LeaveRequest
@@ -405,6 +498,18 @@ func (r *RoomManager) StartSession(
 	}
 
 	sid := livekit.ParticipantID(guid.New(utils.ParticipantPrefix))
+	var participantSnapshot *rtc.ParticipantSnapshot
+	if migration {
+		// session continues from another node, keep the participant SID
+		sid = pi.ID
+		// or from before a restart of this node
+		participantSnapshot = r.snapshotter.TakeParticipant(room.Name(), pi.Identity, pi.ID)
+	}
+	metadataVersions := participantSnapshot.GetMetadataVersions()
+	if metadataVersions == nil {
+		// continues from the versions an earlier session of the identity left with
+		metadataVersions = room.ParticipantMetadataVersions(pi.Identity, pi.Grants.Attributes)
+	}
 	pLogger := rtc.LoggerWithParticipant(
 		rtc.LoggerWithRoom(logger.GetLogger(), room.Name(), room.ID()),
 		pi.Identity,
@@ -417,8 +522,22 @@ func (r *RoomManager) StartSession(
 		"nodeID", r.currentNode.NodeID(),
 		"numParticipants", room.GetParticipantCount(),
 		"participantInit", &pi,
+		"migration", migration,
 	)
 
+	// new participants are held in the lobby till a room admin admits them,
+	// they join with restricted grants and are given the grants of their token once admitted
+	grants := pi.Grants
+	var lobbyPermission *livekit.ParticipantPermission
+	if !migration && room.IsLobbyEnabled() && !bypassesLobby(pi.Grants) {
+		if useOneShotSignallingMode {
+			return ErrLobbyRequiresSignalConnection
+		}
+		lobbyPermission = pi.Grants.Video.ToPermission()
+		grants = pi.Grants.Clone()
+		grants.Video.UpdateFromPermission(rtc.LobbyPermission())
+	}
+
 	clientConf := r.clientConfManager.GetConfiguration(pi.Client)
 
 	pv := types.ProtocolVersion(pi.Client.Protocol)
@@ -472,11 +591,13 @@ func (r *RoomManager) StartSession(
 		Telemetry:               r.telemetry,
 		Trailer:                 room.Trailer(),
 		PLIThrottleConfig:       r.config.RTC.PLIThrottle,
+		KeyFrameCacheConfig:     r.config.RTC.KeyFrameCache,
 		CongestionControlConfig: r.config.RTC.CongestionControl,
 		PublishEnabledCodecs:    protoRoom.EnabledCodecs,
 		SubscribeEnabledCodecs:  protoRoom.EnabledCodecs,
-		Grants:                  pi.Grants,
+		Grants:                  grants,
 		Reconnect:               pi.Reconnect,
+		Migration:               migration,
 		Logger:                  pLogger,
 		Reporter:                roomobs.NewNoopParticipantSessionReporter(),
 		ClientConf:              clientConf,
@@ -489,7 +610,10 @@ func (r *RoomManager) StartSession(
 		ParticipantHelper: &roomManagerParticipantHelper{
 			room:                     room,
 			codecRegressionThreshold: r.config.Video.CodecRegressionThreshold,
+			forwarderStates:          participantSnapshot.GetForwarderStates(),
 		},
+		LastPubReliableSeq:              participantSnapshot.GetLastPubReliableSeq(),
+		MetadataVersions:                metadataVersions,
 		ReconnectOnPublicationError:     reconnectOnPublicationError,
 		ReconnectOnSubscriptionError:    reconnectOnSubscriptionError,
 		ReconnectOnDataChannelError:     reconnectOnDataChannelError,
@@ -517,7 +641,8 @@ func (r *RoomManager) StartSession(
 
 	// join room
 	opts := rtc.ParticipantOptions{
-		AutoSubscribe: pi.AutoSubscribe,
+		AutoSubscribe:   pi.AutoSubscribe,
+		LobbyPermission: lobbyPermission,
 	}
 	if pi.AutoSubscribeDataTrack != nil {
 		opts.AutoSubscribeDataTrack = *pi.AutoSubscribeDataTrack
@@ -551,7 +676,16 @@ func (r *RoomManager) StartSession(
 		}
 	}
 
-	if err = r.roomStore.StoreParticipant(ctx, room.Name(), participant.ToProto()); err != nil {
+	if lobbyPermission != nil {
+		if timeout := r.config.Room.Lobby.Timeout; timeout > 0 {
+			time.AfterFunc(timeout, func() {
+				if room.IsPending(participant.Identity()) {
+					pLogger.Infow("participant not admitted in time, removing from lobby")
+					room.RemoveParticipant(participant.Identity(), participant.ID(), types.ParticipantCloseReasonJoinTimeout)
+				}
+			})
+		}
+	} else if err = r.roomStore.StoreParticipant(ctx, room.Name(), participant.ToProto()); err != nil {
 		pLogger.Errorw("could not store participant", err)
 	}
 
@@ -568,18 +702,24 @@ func (r *RoomManager) StartSession(
 	persistRoomForParticipantCount(room.ToProto())
 
 	clientMeta := &livekit.AnalyticsClientMeta{Region: r.currentNode.Region(), Node: string(r.currentNode.NodeID())}
-	r.telemetry.ParticipantJoined(ctx, protoRoom, participant.ToProto(), pi.Client, clientMeta, true, participant.TelemetryGuard())
+	r.telemetry.ParticipantJoined(ctx, protoRoom, participant.ToProto(), pi.Client, clientMeta, !migration, participant.TelemetryGuard())
 	participant.AddOnClose(types.ParticipantCloseKeyNormal, func(p types.LocalParticipant) {
 		participantServerClosers.Close()
 
-		if err := r.roomStore.DeleteParticipant(ctx, room.Name(), p.Identity()); err != nil {
-			pLogger.Errorw("could not delete participant", err)
+		// a participant migrated out continues its session on another node, that node owns its state
+		migratedOut := r.evacuator.ReleaseParticipant(p.ID()) || r.snapshotter.ReleaseParticipant(p.ID())
+		if !migratedOut {
+			if err := r.roomStore.DeleteParticipant(ctx, room.Name(), p.Identity()); err != nil {
+				pLogger.Errorw("could not delete participant", err)
+			}
 		}
 
 		// update room store with new numParticipants
 		proto := room.ToProto()
-		persistRoomForParticipantCount(proto)
-		r.telemetry.ParticipantLeft(ctx, proto, p.ToProto(), true, participant.TelemetryGuard())
+		if !migratedOut {
+			persistRoomForParticipantCount(proto)
+		}
+		r.telemetry.ParticipantLeft(ctx, proto, p.ToProto(), !migratedOut, participant.TelemetryGuard())
 	})
 	participant.OnClaimsChanged(func(participant types.LocalParticipant) {
 		pLogger.Debugw("refreshing client token after claims change")
@@ -597,13 +737,49 @@ func (r *RoomManager) StartSession(
 	if pi.PublisherOffer != nil {
 		participant.HandleOffer(pi.PublisherOffer)
 	}
+	if migration {
+		// when not available here, client sends sync state after resuming
+		go room.HandleSyncState(participant, pi.SyncState)
+	}
 
 	go r.rtcSessionWorker(room, participant, requestSource)
 	return nil
 }
 
+// bypassesLobby returns true for participants which join without waiting in the lobby
+func bypassesLobby(grants *auth.ClaimGrants) bool {
+	if video := grants.Video; video != nil && (video.RoomAdmin || video.Hidden || video.Recorder || video.Agent) {
+		return true
+	}
+
+	switch grants.GetParticipantKind() {
+	case livekit.ParticipantInfo_AGENT, livekit.ParticipantInfo_EGRESS, livekit.ParticipantInfo_INGRESS:
+		return true
+	}
+	return false
+}
+
+// isMigratingIn returns true when a participant resumes a session which was started on another node,
+// i. e. the room has been moved to this node while the participant was connected
+func (r *RoomManager) isMigratingIn(ctx context.Context, roomName livekit.RoomName, pi *routing.ParticipantInit) bool {
+	if !pi.Reconnect || pi.ID == "" || pi.Identity == "" {
+		return false
+	}
+
+	if room := r.GetRoom(ctx, roomName); room != nil && room.GetParticipant(pi.Identity) != nil {
+		return false
+	}
+
+	// session state of a participant migrating out is kept in the store
+	p, err := r.roomStore.LoadParticipant(ctx, roomName, pi.Identity)
+	if err != nil {
+		return false
+	}
+	return livekit.ParticipantID(p.Sid) == pi.ID
+}
+
 // create the actual room object, to be used on RTC node
-func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.CreateRoomRequest) (*rtc.Room, error) {
+func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.CreateRoomRequest, migration bool) (*rtc.Room, error) {
 	roomName := livekit.RoomName(createRoom.Name)
 
 	r.lock.RLock()
@@ -620,6 +796,22 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.C
 		return nil, err
 	}
 
+	// room may already be running on other nodes participants are connected to
+	var relayNodes []livekit.NodeID
+	if r.relayManager != nil {
+		if relayNodes, err = r.relayManager.RoomNodes(ctx, roomName); err != nil {
+			logger.Warnw("could not get room nodes", err, "room", roomName)
+		}
+	}
+
+	// agents of a room moved from another node or running on other nodes are already running
+	var restoredAgentDispatches []*livekit.AgentDispatch
+	if migration || len(relayNodes) != 0 {
+		if restoredAgentDispatches, err = r.agentStore.ListAgentDispatches(ctx, roomName); err != nil {
+			logger.Warnw("could not load agent dispatches", err, "room", roomName)
+		}
+	}
+
 	r.lock.Lock()
 
 	currentRoom := r.rooms[roomName]
@@ -634,8 +826,20 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.C
 		currentRoom = r.rooms[roomName]
 	}
 
+	var snapshot *rtc.RoomSnapshot
+	if migration {
+		snapshot = r.snapshotter.TakeRoom(roomName)
+	}
+	if snapshot != nil {
+		// room saved before a restart of this node, agents are launched again
+		restoredAgentDispatches = snapshot.AgentDispatches
+	}
+
 	// construct ice servers
-	newRoom := rtc.NewRoom(ri, internal, *r.rtcConfig, r.config.Room, &r.config.Audio, r.serverInfo, r.telemetry, r.agentClient, r.agentStore, r.egressLauncher)
+	newRoom := rtc.NewRoom(ri, internal, *r.rtcConfig, r.config.Room, &r.config.Audio, r.serverInfo, r.telemetry, r.agentClient, r.agentStore, r.egressLauncher, restoredAgentDispatches)
+	newRoom.SetLobbyEnabled(r.config.Room.Lobby.IsEnabledForPreset(createRoom.RoomPreset))
+	newRoom.SetDataHistoryStore(r.roomStore)
+	newRoom.SetLimits(r.config.Limit)
 
 	roomTopic := rpc.FormatRoomTopic(roomName)
 	roomServer := must.Get(rpc.NewTypedRoomServer(r, r.bus))
@@ -653,10 +857,59 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.C
 		r.lock.Unlock()
 		return nil, err
 	}
+	moderationServer := newModerationServer(r, r.bus)
+	killModerationServer := r.moderationServers.Replace(roomTopic, moderationServer)
+	if err := moderationServer.RegisterAllRoomTopics(roomTopic); err != nil {
+		killRoomServer()
+		killDispServer()
+		killModerationServer()
+		r.lock.Unlock()
+		return nil, err
+	}
 
 	newRoom.OnClose(func() {
 		killRoomServer()
 		killDispServer()
+		killModerationServer()
+
+		var remainingNodes []livekit.NodeID
+		if r.relayManager != nil {
+			remainingNodes = r.relayManager.RemoveRoom(context.Background(), newRoom)
+		}
+
+		if r.evacuator.ReleaseRoom(roomName) || r.snapshotter.ReleaseRoom(roomName) {
+			// room continues on another node or after a restart, leave routing and stored state to it
+			r.lock.Lock()
+			if r.rooms[roomName] == newRoom {
+				delete(r.rooms, roomName)
+			}
+			r.lock.Unlock()
+
+			prometheus.RoomEnded(time.Unix(newRoom.ToProto().CreationTime, 0))
+			newRoom.Logger().Infow("room closed, continues elsewhere")
+			return
+		}
+
+		if len(remainingNodes) != 0 {
+			// room continues on other nodes, hand over routing if this node was hosting it
+			r.lock.Lock()
+			if r.rooms[roomName] == newRoom {
+				delete(r.rooms, roomName)
+			}
+			r.lock.Unlock()
+
+			if node, err := r.router.GetNodeForRoom(context.Background(), roomName); err == nil && livekit.NodeID(node.Id) == r.currentNode.NodeID() {
+				if err := r.router.SetNodeForRoom(context.Background(), roomName, remainingNodes[0]); err != nil {
+					newRoom.Logger().Warnw("could not hand over room", err, "nodeID", remainingNodes[0])
+				}
+			}
+
+			prometheus.RoomEnded(time.Unix(newRoom.ToProto().CreationTime, 0))
+			newRoom.Logger().Infow("room closed on node, continues on other nodes", "nodes", remainingNodes)
+			return
+		}
+
+		r.closeDataHistory(newRoom)
 
 		roomInfo := newRoom.ToProto()
 		r.telemetry.RoomEnded(ctx, roomInfo)
@@ -675,7 +928,8 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.C
 	})
 
 	newRoom.OnParticipantChanged(func(p types.Participant) {
-		if !p.IsDisconnected() {
+		// participants waiting in the lobby are not listed till admitted
+		if !p.IsDisconnected() && !newRoom.IsPending(p.Identity()) {
 			if err := r.roomStore.StoreParticipant(ctx, roomName, p.ToProto()); err != nil {
 				newRoom.Logger().Errorw("could not handle participant change", err)
 			}
@@ -688,7 +942,17 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, createRoom *livekit.C
 
 	newRoom.Hold()
 
-	r.telemetry.RoomStarted(ctx, newRoom.ToProto())
+	if snapshot != nil {
+		newRoom.RestoreSnapshot(snapshot)
+	}
+
+	if r.relayManager != nil {
+		r.relayManager.AddRoom(ctx, newRoom)
+	}
+
+	if len(relayNodes) == 0 {
+		r.telemetry.RoomStarted(ctx, newRoom.ToProto())
+	}
 	prometheus.RoomStarted()
 
 	if created && createRoom.GetEgress().GetRoom() != nil {
@@ -810,12 +1074,16 @@ func (r *RoomManager) UpdateParticipant(ctx context.Context, req *livekit.Update
 		return nil, err
 	}
 
+	expected, err := expectedVersionsFromRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
 	if err = participant.UpdateMetadata(&livekit.UpdateParticipantMetadata{
 		Name:       req.Name,
 		Metadata:   req.Metadata,
 		Attributes: req.Attributes,
-	}, true); err != nil {
-		return nil, err
+	}, true, expected); err != nil {
+		return nil, metadataVersionsError(err)
 	}
 
 	if req.Permission != nil {
@@ -924,8 +1192,20 @@ func (r *RoomManager) UpdateRoomMetadata(ctx context.Context, req *livekit.Updat
 		return nil, ErrRoomNotFound
 	}
 
-	room.Logger().Debugw("updating room")
-	done := room.SetMetadata(req.Metadata)
+	expected, err := expectedVersionsFromRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var expectedVersion *uint64
+	if expected != nil {
+		expectedVersion = expected.Metadata
+	}
+
+	room.Logger().Debugw("updating room", "expectedVersion", expectedVersion)
+	_, done, err := room.UpdateMetadata(req.Metadata, expectedVersion)
+	if err != nil {
+		return nil, metadataVersionsError(err)
+	}
 	// wait till the update is applied
 	<-done
 	return room.ToProto(), nil
@@ -977,6 +1257,419 @@ func (r *RoomManager) DeleteDispatch(ctx context.Context, req *livekit.DeleteAge
 	return disp, nil
 }
 
+func (r *RoomManager) ListPendingParticipants(ctx context.Context, req *livekit.ListParticipantsRequest) (*livekit.ListParticipantsResponse, error) {
+	room := r.GetRoom(ctx, livekit.RoomName(req.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	return &livekit.ListParticipantsResponse{
+		Participants: room.GetPendingParticipants(),
+	}, nil
+}
+
+func (r *RoomManager) AdmitParticipant(ctx context.Context, req *livekit.RoomParticipantIdentity) (*livekit.ParticipantInfo, error) {
+	room := r.GetRoom(ctx, livekit.RoomName(req.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	pi, err := room.AdmitParticipant(livekit.ParticipantIdentity(req.Identity))
+	if errors.Is(err, rtc.ErrParticipantNotPending) {
+		return nil, ErrParticipantNotPending
+	}
+	return pi, err
+}
+
+func (r *RoomManager) RejectParticipant(ctx context.Context, req *livekit.RoomParticipantIdentity) (*livekit.RemoveParticipantResponse, error) {
+	room := r.GetRoom(ctx, livekit.RoomName(req.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	if err := room.RejectParticipant(livekit.ParticipantIdentity(req.Identity)); err != nil {
+		if errors.Is(err, rtc.ErrParticipantNotPending) {
+			return nil, ErrParticipantNotPending
+		}
+		return nil, err
+	}
+	return &livekit.RemoveParticipantResponse{}, nil
+}
+
+func (r *RoomManager) UpdateLobby(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	lobbyReq, err := requestFromStruct[LobbyRequest](req)
+	if err != nil {
+		return nil, ErrInvalidLobby
+	}
+
+	room := r.GetRoom(ctx, livekit.RoomName(lobbyReq.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	room.SetLobbyEnabled(lobbyReq.Enabled)
+	return toStruct(lobbyReq)
+}
+
+func (r *RoomManager) GetLobby(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	lobbyReq, err := requestFromStruct[LobbyRequest](req)
+	if err != nil {
+		return nil, ErrInvalidLobby
+	}
+
+	room := r.GetRoom(ctx, livekit.RoomName(lobbyReq.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	return toStruct(&LobbyRequest{Room: lobbyReq.Room, Enabled: room.IsLobbyEnabled()})
+}
+
+func (r *RoomManager) ApprovePublishRequest(ctx context.Context, req *livekit.RoomParticipantIdentity) (*livekit.ParticipantInfo, error) {
+	room := r.GetRoom(ctx, livekit.RoomName(req.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	pi, err := room.ApprovePublishRequest(livekit.ParticipantIdentity(req.Identity))
+	return pi, publishRequestError(err)
+}
+
+func (r *RoomManager) DenyPublishRequest(ctx context.Context, req *livekit.RoomParticipantIdentity) (*livekit.ParticipantInfo, error) {
+	room := r.GetRoom(ctx, livekit.RoomName(req.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	pi, err := room.DenyPublishRequest(livekit.ParticipantIdentity(req.Identity))
+	return pi, publishRequestError(err)
+}
+
+func (r *RoomManager) UpdateSubscriptionPolicy(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	policyReq, err := requestFromStruct[SubscriptionPolicyRequest](req)
+	if err != nil {
+		return nil, ErrInvalidSubscriptionPolicy
+	}
+
+	room := r.GetRoom(ctx, livekit.RoomName(policyReq.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	if err := room.SetSubscriptionPolicy(&policyReq.SubscriptionPolicy); err != nil {
+		return nil, psrpc.NewError(psrpc.InvalidArgument, err)
+	}
+	return toStruct(policyReq)
+}
+
+func (r *RoomManager) GetSubscriptionPolicy(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	policyReq, err := requestFromStruct[SubscriptionPolicyRequest](req)
+	if err != nil {
+		return nil, ErrInvalidSubscriptionPolicy
+	}
+
+	room := r.GetRoom(ctx, livekit.RoomName(policyReq.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	res := &SubscriptionPolicyRequest{Room: policyReq.Room}
+	if policy := room.GetSubscriptionPolicy(); policy != nil {
+		res.SubscriptionPolicy = *policy
+	}
+	return toStruct(res)
+}
+
+func (r *RoomManager) UpdateSubscriptionLimits(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	limitsReq, err := requestFromStruct[SubscriptionLimitsRequest](req)
+	if err != nil {
+		return nil, ErrInvalidSubscriptionLimits
+	}
+
+	room := r.GetRoom(ctx, livekit.RoomName(limitsReq.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	if limitsReq.Identity == "" {
+		err = room.SetSubscriptionLimits(&limitsReq.SubscriptionLimits)
+	} else {
+		err = room.SetParticipantSubscriptionLimits(livekit.ParticipantIdentity(limitsReq.Identity), &limitsReq.SubscriptionLimits)
+	}
+	switch {
+	case errors.Is(err, rtc.ErrParticipantNotFound):
+		return nil, ErrParticipantNotFound
+	case err != nil:
+		return nil, psrpc.NewError(psrpc.InvalidArgument, err)
+	}
+	return toStruct(limitsReq)
+}
+
+func (r *RoomManager) GetSubscriptionLimits(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	limitsReq, err := requestFromStruct[SubscriptionLimitsRequest](req)
+	if err != nil {
+		return nil, ErrInvalidSubscriptionLimits
+	}
+
+	room := r.GetRoom(ctx, livekit.RoomName(limitsReq.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	res := &SubscriptionLimitsRequest{Room: limitsReq.Room, Identity: limitsReq.Identity}
+	limits := room.GetSubscriptionLimits()
+	if limitsReq.Identity != "" {
+		limits = room.GetParticipantSubscriptionLimits(livekit.ParticipantIdentity(limitsReq.Identity))
+	}
+	if limits != nil {
+		res.SubscriptionLimits = *limits
+	}
+	return toStruct(res)
+}
+
+func (r *RoomManager) UpdateParticipantSubscriptionPermission(ctx context.Context, req *structpb.Struct) (*livekit.ParticipantInfo, error) {
+	permissionReq, err := requestFromStruct[ParticipantSubscriptionPermissionRequest](req)
+	if err != nil {
+		return nil, ErrInvalidSubscriptionPermission
+	}
+
+	subscriptionPermission := &livekit.SubscriptionPermission{}
+	if err := protojson.Unmarshal(permissionReq.Permission, subscriptionPermission); err != nil {
+		return nil, ErrInvalidSubscriptionPermission
+	}
+
+	room := r.GetRoom(ctx, livekit.RoomName(permissionReq.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	participant := room.GetParticipant(livekit.ParticipantIdentity(permissionReq.Identity))
+	if participant == nil {
+		return nil, ErrParticipantNotFound
+	}
+
+	participant.GetLogger().Debugw(
+		"updating subscription permission",
+		"permission", logger.Proto(subscriptionPermission),
+	)
+	if err := participant.HandleUpdateSubscriptionPermission(subscriptionPermission); err != nil {
+		return nil, psrpc.NewError(psrpc.InvalidArgument, err)
+	}
+	return participant.ToProto(), nil
+}
+
+func (r *RoomManager) UpdateDataPolicy(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	policyReq, err := requestFromStruct[DataPolicyRequest](req)
+	if err != nil {
+		return nil, ErrInvalidDataPolicy
+	}
+
+	room := r.GetRoom(ctx, livekit.RoomName(policyReq.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	if err := room.SetDataPolicy(&policyReq.DataPolicy); err != nil {
+		return nil, psrpc.NewError(psrpc.InvalidArgument, err)
+	}
+	return toStruct(policyReq)
+}
+
+func (r *RoomManager) GetDataPolicy(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	policyReq, err := requestFromStruct[DataPolicyRequest](req)
+	if err != nil {
+		return nil, ErrInvalidDataPolicy
+	}
+
+	room := r.GetRoom(ctx, livekit.RoomName(policyReq.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	res := &DataPolicyRequest{Room: policyReq.Room}
+	if policy := room.GetDataPolicy(); policy != nil {
+		res.DataPolicy = *policy
+	}
+	return toStruct(res)
+}
+
+func (r *RoomManager) UpdateDataRetention(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	retentionReq, err := requestFromStruct[DataRetentionRequest](req)
+	if err != nil {
+		return nil, ErrInvalidDataRetention
+	}
+
+	room := r.GetRoom(ctx, livekit.RoomName(retentionReq.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	if err := room.SetDataRetention(&retentionReq.DataRetention); err != nil {
+		return nil, psrpc.NewError(psrpc.InvalidArgument, err)
+	}
+	return toStruct(retentionReq)
+}
+
+func (r *RoomManager) GetDataRetention(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	retentionReq, err := requestFromStruct[DataRetentionRequest](req)
+	if err != nil {
+		return nil, ErrInvalidDataRetention
+	}
+
+	room := r.GetRoom(ctx, livekit.RoomName(retentionReq.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	res := &DataRetentionRequest{Room: retentionReq.Room}
+	if retention := room.GetDataRetention(); retention != nil {
+		res.DataRetention = *retention
+	}
+	return toStruct(res)
+}
+
+func (r *RoomManager) GetDataHistory(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	historyReq, err := requestFromStruct[DataHistoryRequest](req)
+	if err != nil {
+		return nil, ErrInvalidDataRetention
+	}
+
+	room := r.GetRoom(ctx, livekit.RoomName(historyReq.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	msgs, err := room.GetDataHistory(ctx, historyReq.Topic)
+	if err != nil {
+		return nil, err
+	}
+	return toStruct(&DataHistoryResponse{Room: historyReq.Room, Messages: msgs})
+}
+
+func (r *RoomManager) UpdateRoomState(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	stateReq, err := requestFromStruct[RoomStateRequest](req)
+	if err != nil {
+		return nil, ErrInvalidRoomState
+	}
+
+	room := r.GetRoom(ctx, livekit.RoomName(stateReq.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	if _, err := room.UpdateRoomState("", stateReq.Updates); err != nil {
+		return nil, roomStateError(err)
+	}
+	version, entries := room.GetRoomState()
+	return toStruct(&RoomStateResponse{Room: stateReq.Room, Version: version, Entries: entries})
+}
+
+func (r *RoomManager) GetRoomState(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	stateReq, err := requestFromStruct[RoomStateRequest](req)
+	if err != nil {
+		return nil, ErrInvalidRoomState
+	}
+
+	room := r.GetRoom(ctx, livekit.RoomName(stateReq.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	version, entries := room.GetRoomState()
+	return toStruct(&RoomStateResponse{Room: stateReq.Room, Version: version, Entries: entries})
+}
+
+func (r *RoomManager) GetMetadataVersions(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	versionsReq, err := requestFromStruct[MetadataVersionsRequest](req)
+	if err != nil {
+		return nil, ErrInvalidMetadataVersions
+	}
+
+	room := r.GetRoom(ctx, livekit.RoomName(versionsReq.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	res := &MetadataVersionsResponse{Room: versionsReq.Room, Identity: versionsReq.Identity}
+	if versionsReq.Identity == "" {
+		res.Versions.Metadata = room.MetadataVersion()
+	} else {
+		participant := room.GetParticipant(livekit.ParticipantIdentity(versionsReq.Identity))
+		if participant == nil {
+			return nil, ErrParticipantNotFound
+		}
+		res.Versions = participant.MetadataVersions()
+	}
+	return toStruct(res)
+}
+
+// HandOffAgentJob registers the replacement of an agent job with the room, it returns once the agent of the
+// replacement joined or fails when it did not before the request times out
+func (r *RoomManager) HandOffAgentJob(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	handoffReq, err := requestFromStruct[AgentJobHandoffRequest](req)
+	if err != nil {
+		return nil, ErrInvalidJobHandoff
+	}
+
+	replacement := &livekit.Job{}
+	if err := protojson.Unmarshal(handoffReq.Replacement, replacement); err != nil {
+		return nil, ErrInvalidJobHandoff
+	}
+
+	room := r.GetRoom(ctx, livekit.RoomName(handoffReq.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	if err := room.HandOffAgentJob(ctx, livekit.JobID(handoffReq.JobID), replacement); err != nil {
+		return nil, err
+	}
+	return &structpb.Struct{}, nil
+}
+
+// closeDataHistory exports kept messages of a room which ended when an export path is configured, and removes them from the store
+func (r *RoomManager) closeDataHistory(room *rtc.Room) {
+	ctx := context.Background()
+	if exportPath := r.config.Room.DataHistory.ExportPath; exportPath != "" && room.GetDataRetention() != nil {
+		msgs, err := room.GetDataHistory(ctx, "")
+		if err != nil {
+			room.Logger().Errorw("could not load data history", err)
+			// kept in the store till it expires, to be exported by other means
+			return
+		}
+		if err := exportDataHistory(exportPath, room.ToProto(), msgs); err != nil {
+			room.Logger().Errorw("could not export data history", err)
+			return
+		}
+	}
+
+	if err := r.roomStore.DeleteDataHistory(ctx, room.Name()); err != nil {
+		room.Logger().Warnw("could not delete data history", err)
+	}
+}
+
+func roomStateError(err error) error {
+	switch {
+	case errors.Is(err, rtc.ErrRoomStateConflict):
+		// the message carries the current version of conflicting keys
+		return psrpc.NewError(psrpc.Aborted, err)
+	case errors.Is(err, rtc.ErrRoomStateLimitExceeded):
+		return psrpc.NewError(psrpc.ResourceExhausted, err)
+	default:
+		return psrpc.NewError(psrpc.InvalidArgument, err)
+	}
+}
+
+func publishRequestError(err error) error {
+	switch {
+	case errors.Is(err, rtc.ErrNoPublishRequest):
+		return ErrNoPublishRequest
+	case errors.Is(err, rtc.ErrMaxPublishersExceeded):
+		return ErrMaxPublishersExceeded
+	default:
+		return err
+	}
+}
+
 func (r *RoomManager) iceServersForParticipant(apiKey string, participant types.LocalParticipant, tlsOnly bool) []*livekit.ICEServer {
 	var iceServers []*livekit.ICEServer
 	rtcConf := r.config.RTC
@@ -1129,6 +1822,8 @@ func iceServerForStunServers(servers []string) *livekit.ICEServer {
 type roomManagerParticipantHelper struct {
 	room                     *rtc.Room
 	codecRegressionThreshold int
+	// forwarder states of subscribed tracks when resuming a session saved before a restart
+	forwarderStates map[livekit.TrackID]*livekit.RTPForwarderState
 }
 
 func (h *roomManagerParticipantHelper) GetParticipantInfo(pID livekit.ParticipantID) *livekit.ParticipantInfo {
@@ -1143,7 +1838,7 @@ func (h *roomManagerParticipantHelper) GetRegionSettings(ip string) *livekit.Reg
 }
 
 func (h *roomManagerParticipantHelper) GetSubscriberForwarderState(lp types.LocalParticipant) (map[livekit.TrackID]*livekit.RTPForwarderState, error) {
-	return nil, nil
+	return h.forwarderStates, nil
 }
 
 func (h *roomManagerParticipantHelper) ResolveMediaTrack(lp types.LocalParticipant, trackID livekit.TrackID) types.MediaResolverResult {
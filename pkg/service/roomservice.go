@@ -20,6 +20,7 @@ import (
 	"strconv"
 
 	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/types/known/structpb"
 
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
@@ -39,6 +40,7 @@ type RoomService struct {
 	topicFormatter    rpc.TopicFormatter
 	roomClient        rpc.TypedRoomClient
 	participantClient rpc.TypedParticipantClient
+	moderationClient  ModerationClient
 
 	rpc.UnimplementedRoomServer
 	rpc.UnimplementedParticipantServer
@@ -54,6 +56,7 @@ func NewRoomService(
 	topicFormatter rpc.TopicFormatter,
 	roomClient rpc.TypedRoomClient,
 	participantClient rpc.TypedParticipantClient,
+	moderationClient ModerationClient,
 ) (svc *RoomService, err error) {
 	svc = &RoomService{
 		limitConf:         limitConf,
@@ -65,6 +68,7 @@ func NewRoomService(
 		topicFormatter:    topicFormatter,
 		roomClient:        roomClient,
 		participantClient: participantClient,
+		moderationClient:  moderationClient,
 	}
 	return
 }
@@ -251,9 +255,13 @@ func (s *RoomService) UpdateParticipant(ctx context.Context, req *livekit.Update
 		}
 	}
 
-	res, err := s.participantClient.UpdateParticipant(ctx, s.topicFormatter.ParticipantTopic(ctx, livekit.RoomName(req.Room), livekit.ParticipantIdentity(req.Identity)), req)
+	res, err := s.participantClient.UpdateParticipant(withExpectedVersions(ctx), s.topicFormatter.ParticipantTopic(ctx, livekit.RoomName(req.Room), livekit.ParticipantIdentity(req.Identity)), req)
+	if err != nil {
+		return nil, twirpMetadataVersionsError(err)
+	}
+	s.setVersionsHeader(ctx, livekit.RoomName(req.Room), livekit.ParticipantIdentity(req.Identity))
 	RecordResponse(ctx, res)
-	return res, err
+	return res, nil
 }
 
 func (s *RoomService) UpdateSubscriptions(ctx context.Context, req *livekit.UpdateSubscriptionsRequest) (*livekit.UpdateSubscriptionsResponse, error) {
@@ -313,10 +321,11 @@ func (s *RoomService) UpdateRoomMetadata(ctx context.Context, req *livekit.Updat
 		return nil, ErrRoomNotFound
 	}
 
-	room, err := s.roomClient.UpdateRoomMetadata(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(req.Room)), req)
+	room, err := s.roomClient.UpdateRoomMetadata(withExpectedVersions(ctx), s.topicFormatter.RoomTopic(ctx, livekit.RoomName(req.Room)), req)
 	if err != nil {
-		return nil, err
+		return nil, twirpMetadataVersionsError(err)
 	}
+	s.setVersionsHeader(ctx, livekit.RoomName(req.Room), "")
 
 	RecordResponse(ctx, room)
 	return room, nil
@@ -375,3 +384,320 @@ func (s *RoomService) PerformRpc(ctx context.Context, req *livekit.PerformRpcReq
 	RecordResponse(ctx, res)
 	return res, err
 }
+
+// ListPendingParticipants lists participants waiting in the lobby of the room, in order of arrival
+func (s *RoomService) ListPendingParticipants(ctx context.Context, req *livekit.ListParticipantsRequest) (*livekit.ListParticipantsResponse, error) {
+	RecordRequest(ctx, req)
+
+	AppendLogFields(ctx, "room", req.Room)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(req.Room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+
+	res, err := s.moderationClient.ListPendingParticipants(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(req.Room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
+
+// AdmitParticipant lets a participant waiting in the lobby into the room with the permissions of its token
+func (s *RoomService) AdmitParticipant(ctx context.Context, req *livekit.RoomParticipantIdentity) (*livekit.ParticipantInfo, error) {
+	RecordRequest(ctx, req)
+
+	AppendLogFields(ctx, "room", req.Room, "participant", req.Identity)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(req.Room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+	if req.Identity == "" {
+		return nil, ErrIdentityEmpty
+	}
+
+	res, err := s.moderationClient.AdmitParticipant(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(req.Room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
+
+// RejectParticipant removes a participant waiting in the lobby
+func (s *RoomService) RejectParticipant(ctx context.Context, req *livekit.RoomParticipantIdentity) (*livekit.RemoveParticipantResponse, error) {
+	RecordRequest(ctx, req)
+
+	AppendLogFields(ctx, "room", req.Room, "participant", req.Identity)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(req.Room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+	if req.Identity == "" {
+		return nil, ErrIdentityEmpty
+	}
+
+	res, err := s.moderationClient.RejectParticipant(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(req.Room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
+
+// UpdateLobby enables or disables the lobby of the room, overriding the lobby config, see LobbyRequest for the request body
+func (s *RoomService) UpdateLobby(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	RecordRequest(ctx, req)
+
+	room := req.GetFields()["room"].GetStringValue()
+	AppendLogFields(ctx, "room", room)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+
+	res, err := s.moderationClient.UpdateLobby(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
+
+// GetLobby returns whether participants joining the room wait in the lobby
+func (s *RoomService) GetLobby(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	RecordRequest(ctx, req)
+
+	room := req.GetFields()["room"].GetStringValue()
+	AppendLogFields(ctx, "room", room)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+
+	res, err := s.moderationClient.GetLobby(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
+
+// ApprovePublishRequest allows a participant that raised its hand to publish the sources it asked for
+func (s *RoomService) ApprovePublishRequest(ctx context.Context, req *livekit.RoomParticipantIdentity) (*livekit.ParticipantInfo, error) {
+	RecordRequest(ctx, req)
+
+	AppendLogFields(ctx, "room", req.Room, "participant", req.Identity)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(req.Room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+	if req.Identity == "" {
+		return nil, ErrIdentityEmpty
+	}
+
+	res, err := s.moderationClient.ApprovePublishRequest(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(req.Room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
+
+// DenyPublishRequest drops the request of a participant to publish, its permissions are left unchanged
+func (s *RoomService) DenyPublishRequest(ctx context.Context, req *livekit.RoomParticipantIdentity) (*livekit.ParticipantInfo, error) {
+	RecordRequest(ctx, req)
+
+	AppendLogFields(ctx, "room", req.Room, "participant", req.Identity)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(req.Room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+	if req.Identity == "" {
+		return nil, ErrIdentityEmpty
+	}
+
+	res, err := s.moderationClient.DenyPublishRequest(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(req.Room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
+
+// UpdateSubscriptionPolicy replaces the policy deciding which tracks participants of the room subscribe to,
+// see SubscriptionPolicyRequest for the request body
+func (s *RoomService) UpdateSubscriptionPolicy(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	RecordRequest(ctx, req)
+
+	room := req.GetFields()["room"].GetStringValue()
+	AppendLogFields(ctx, "room", room)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+
+	res, err := s.moderationClient.UpdateSubscriptionPolicy(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
+
+// GetSubscriptionPolicy returns the subscription policy of the room, without rules when none is set
+func (s *RoomService) GetSubscriptionPolicy(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	RecordRequest(ctx, req)
+
+	room := req.GetFields()["room"].GetStringValue()
+	AppendLogFields(ctx, "room", room)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+
+	res, err := s.moderationClient.GetSubscriptionPolicy(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
+
+// UpdateSubscriptionLimits caps the downstream bitrate and video quality of a participant, or of the room when no
+// identity is given, see SubscriptionLimitsRequest for the request body
+func (s *RoomService) UpdateSubscriptionLimits(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	RecordRequest(ctx, req)
+
+	room := req.GetFields()["room"].GetStringValue()
+	identity := req.GetFields()["identity"].GetStringValue()
+	AppendLogFields(ctx, "room", room, "participant", identity)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+
+	res, err := s.moderationClient.UpdateSubscriptionLimits(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
+
+// GetSubscriptionLimits returns the subscription limits of a participant, or of the room when no identity is given
+func (s *RoomService) GetSubscriptionLimits(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	RecordRequest(ctx, req)
+
+	room := req.GetFields()["room"].GetStringValue()
+	identity := req.GetFields()["identity"].GetStringValue()
+	AppendLogFields(ctx, "room", room, "participant", identity)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+
+	res, err := s.moderationClient.GetSubscriptionLimits(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
+
+// UpdateParticipantSubscriptionPermission replaces the subscription permission of a participant's tracks,
+// it may grant subscribers by rules over their attributes
+func (s *RoomService) UpdateParticipantSubscriptionPermission(ctx context.Context, req *structpb.Struct) (*livekit.ParticipantInfo, error) {
+	RecordRequest(ctx, req)
+
+	room := req.GetFields()["room"].GetStringValue()
+	identity := req.GetFields()["identity"].GetStringValue()
+	AppendLogFields(ctx, "room", room, "participant", identity)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+	if identity == "" {
+		return nil, ErrIdentityEmpty
+	}
+
+	res, err := s.moderationClient.UpdateParticipantSubscriptionPermission(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
+
+// UpdateDataPolicy replaces the script moderating data messages of the room, see DataPolicyRequest for the request body
+func (s *RoomService) UpdateDataPolicy(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	RecordRequest(ctx, req)
+
+	room := req.GetFields()["room"].GetStringValue()
+	AppendLogFields(ctx, "room", room)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+
+	res, err := s.moderationClient.UpdateDataPolicy(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
+
+// GetDataPolicy returns the data policy of the room, without a script when none is set
+func (s *RoomService) GetDataPolicy(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	RecordRequest(ctx, req)
+
+	room := req.GetFields()["room"].GetStringValue()
+	AppendLogFields(ctx, "room", room)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+
+	res, err := s.moderationClient.GetDataPolicy(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
+
+// UpdateDataRetention replaces the topics of the room whose messages are kept, see DataRetentionRequest for the request body
+func (s *RoomService) UpdateDataRetention(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	RecordRequest(ctx, req)
+
+	room := req.GetFields()["room"].GetStringValue()
+	AppendLogFields(ctx, "room", room)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+
+	res, err := s.moderationClient.UpdateDataRetention(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
+
+// GetDataRetention returns the data retention of the room, without topics when none is set
+func (s *RoomService) GetDataRetention(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	RecordRequest(ctx, req)
+
+	room := req.GetFields()["room"].GetStringValue()
+	AppendLogFields(ctx, "room", room)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+
+	res, err := s.moderationClient.GetDataRetention(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
+
+// GetDataHistory returns kept messages of the room, see DataHistoryRequest for the request body
+func (s *RoomService) GetDataHistory(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	RecordRequest(ctx, req)
+
+	room := req.GetFields()["room"].GetStringValue()
+	AppendLogFields(ctx, "room", room)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+
+	res, err := s.moderationClient.GetDataHistory(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
+
+// UpdateRoomState sets or deletes keys of the shared state of the room, see RoomStateRequest for the request body
+func (s *RoomService) UpdateRoomState(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	RecordRequest(ctx, req)
+
+	room := req.GetFields()["room"].GetStringValue()
+	AppendLogFields(ctx, "room", room)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+
+	res, err := s.moderationClient.UpdateRoomState(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
+
+// GetRoomState returns the shared state of the room
+func (s *RoomService) GetRoomState(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	RecordRequest(ctx, req)
+
+	room := req.GetFields()["room"].GetStringValue()
+	AppendLogFields(ctx, "room", room)
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+
+	res, err := s.moderationClient.GetRoomState(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
+
+// GetMetadataVersions returns the versions of metadata and attributes of a participant, or of the room metadata,
+// see MetadataVersionsRequest for the request body
+func (s *RoomService) GetMetadataVersions(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	RecordRequest(ctx, req)
+
+	room := req.GetFields()["room"].GetStringValue()
+	AppendLogFields(ctx, "room", room, "participant", req.GetFields()["identity"].GetStringValue())
+	if err := EnsureAdminPermission(ctx, livekit.RoomName(room)); err != nil {
+		return nil, twirpAuthError(err)
+	}
+
+	res, err := s.moderationClient.GetMetadataVersions(ctx, s.topicFormatter.RoomTopic(ctx, livekit.RoomName(room)), req)
+	RecordResponse(ctx, res)
+	return res, err
+}
@@ -16,15 +16,20 @@ package service_test
 
 import (
 	"context"
+	"errors"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	"github.com/twitchtv/twirp"
+	"github.com/twitchtv/twirp/ctxsetters"
+	"google.golang.org/protobuf/types/known/structpb"
 
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/rpc"
 	"github.com/livekit/protocol/rpc/rpcfakes"
+	"github.com/livekit/psrpc"
 
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
@@ -104,10 +109,92 @@ func TestMetaDataLimits(t *testing.T) {
 	}
 }
 
+func TestUpdateRoomMetadataConflict(t *testing.T) {
+	svc := newTestRoomService(config.LimitConfig{})
+	svc.store.RoomExistsReturns(true, nil)
+	versions, err := structpb.NewStruct(map[string]any{"metadata": 3})
+	require.NoError(t, err)
+	svc.roomClient.UpdateRoomMetadataReturns(nil, psrpc.NewError(psrpc.Aborted, errors.New("metadata version conflict"), versions))
+
+	grant := &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomAdmin: true, Room: "testroom"},
+	}
+	ctx := service.WithGrants(context.Background(), grant, "")
+	_, err = svc.UpdateRoomMetadata(ctx, &livekit.UpdateRoomMetadataRequest{
+		Room:     "testroom",
+		Metadata: "abc",
+	})
+	var terr twirp.Error
+	require.ErrorAs(t, err, &terr)
+	require.Equal(t, twirp.Aborted, terr.Code())
+	require.JSONEq(t, `{"metadata": 3}`, terr.Meta(service.VersionsErrorMeta))
+}
+
+func TestUpdateRoomMetadataVersions(t *testing.T) {
+	svc := newTestRoomService(config.LimitConfig{})
+	svc.store.RoomExistsReturns(true, nil)
+	svc.roomClient.UpdateRoomMetadataReturns(&livekit.Room{Name: "testroom", Metadata: "abc"}, nil)
+	versions, err := structpb.NewStruct(map[string]any{"room": "testroom", "versions": map[string]any{"metadata": 4}})
+	require.NoError(t, err)
+	svc.moderationClient.GetMetadataVersionsReturns(versions, nil)
+
+	grant := &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomAdmin: true, Room: "testroom"},
+	}
+	w := httptest.NewRecorder()
+	ctx := ctxsetters.WithResponseWriter(service.WithGrants(context.Background(), grant, ""), w)
+	_, err = svc.UpdateRoomMetadata(ctx, &livekit.UpdateRoomMetadataRequest{
+		Room:     "testroom",
+		Metadata: "abc",
+	})
+	require.NoError(t, err)
+
+	// versions after the update are returned without a conditional write
+	require.Equal(t, 1, svc.moderationClient.GetMetadataVersionsCallCount())
+	_, _, req, _ := svc.moderationClient.GetMetadataVersionsArgsForCall(0)
+	require.Equal(t, "testroom", req.GetFields()["room"].GetStringValue())
+	require.NotContains(t, req.GetFields(), "identity")
+	require.JSONEq(t, `{"metadata": 4}`, w.Header().Get(service.VersionsHeader))
+}
+
+func TestUpdateLobby(t *testing.T) {
+	req, err := structpb.NewStruct(map[string]any{"room": "testroom", "enabled": true})
+	require.NoError(t, err)
+
+	t.Run("missing permissions", func(t *testing.T) {
+		svc := newTestRoomService(config.LimitConfig{})
+		grant := &auth.ClaimGrants{
+			Video: &auth.VideoGrant{Room: "testroom"},
+		}
+		ctx := service.WithGrants(context.Background(), grant, "")
+		_, err := svc.UpdateLobby(ctx, req)
+		require.Error(t, err)
+		require.Zero(t, svc.moderationClient.UpdateLobbyCallCount())
+	})
+
+	t.Run("enabled for the room", func(t *testing.T) {
+		svc := newTestRoomService(config.LimitConfig{})
+		svc.moderationClient.UpdateLobbyReturns(req, nil)
+		grant := &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomAdmin: true, Room: "testroom"},
+		}
+		ctx := service.WithGrants(context.Background(), grant, "")
+		res, err := svc.UpdateLobby(ctx, req)
+		require.NoError(t, err)
+		require.True(t, res.GetFields()["enabled"].GetBoolValue())
+
+		require.Equal(t, 1, svc.moderationClient.UpdateLobbyCallCount())
+		_, topic, _, _ := svc.moderationClient.UpdateLobbyArgsForCall(0)
+		require.Equal(t, rpc.NewTopicFormatter().RoomTopic(context.Background(), "testroom"), topic)
+	})
+}
+
 func newTestRoomService(limitConf config.LimitConfig) *TestRoomService {
 	router := &routingfakes.FakeRouter{}
 	allocator := &servicefakes.FakeRoomAllocator{}
 	store := &servicefakes.FakeServiceStore{}
+	roomClient := &rpcfakes.FakeTypedRoomClient{}
+	moderationClient := &servicefakes.FakeModerationClient{}
 	svc, err := service.NewRoomService(
 		limitConf,
 		config.APIConfig{ExecutionTimeout: 2},
@@ -116,8 +203,9 @@ func newTestRoomService(limitConf config.LimitConfig) *TestRoomService {
 		store,
 		nil,
 		rpc.NewTopicFormatter(),
-		&rpcfakes.FakeTypedRoomClient{},
+		roomClient,
 		&rpcfakes.FakeTypedParticipantClient{},
+		moderationClient,
 	)
 	if err != nil {
 		panic(err)
@@ -127,12 +215,18 @@ func newTestRoomService(limitConf config.LimitConfig) *TestRoomService {
 		router:      router,
 		allocator:   allocator,
 		store:       store,
+		roomClient:  roomClient,
+
+		moderationClient: moderationClient,
 	}
 }
 
 type TestRoomService struct {
 	service.RoomService
-	router    *routingfakes.FakeRouter
-	allocator *servicefakes.FakeRoomAllocator
-	store     *servicefakes.FakeServiceStore
+	router     *routingfakes.FakeRouter
+	allocator  *servicefakes.FakeRoomAllocator
+	store      *servicefakes.FakeServiceStore
+	roomClient *rpcfakes.FakeTypedRoomClient
+
+	moderationClient *servicefakes.FakeModerationClient
 }
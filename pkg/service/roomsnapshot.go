@@ -0,0 +1,273 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+type restoredRoom struct {
+	snapshot     *rtc.RoomSnapshot
+	roomTaken    bool
+	participants map[livekit.ParticipantIdentity]*rtc.ParticipantSnapshot
+	expiry       *time.Timer
+}
+
+// RoomSnapshotter saves rooms on shutdown and restores them on startup, so that a server restart
+// looks like a migration to participants, i. e. they resume their sessions on the restarted server.
+type RoomSnapshotter struct {
+	config config.RoomSnapshotConfig
+	store  RoomSnapshotStore
+	// snapshots are stored per node, only the restarted node restores its rooms
+	nodeKey     string
+	roomStore   ObjectStore
+	router      routing.Router
+	currentNode routing.LocalNode
+	// called when participants of a restored room did not resume in time
+	onExpired func(roomName livekit.RoomName)
+
+	lock         sync.Mutex
+	snapshotting bool
+	// rooms and participants saved to a snapshot, their stored state is owned by the next server
+	snapshottedRooms        map[livekit.RoomName]struct{}
+	snapshottedParticipants map[livekit.ParticipantID]struct{}
+	// rooms restored from snapshots, waiting for participants to resume
+	restored map[livekit.RoomName]*restoredRoom
+}
+
+func NewRoomSnapshotter(
+	conf *config.Config,
+	store RoomSnapshotStore,
+	roomStore ObjectStore,
+	router routing.Router,
+	currentNode routing.LocalNode,
+	onExpired func(roomName livekit.RoomName),
+) *RoomSnapshotter {
+	return &RoomSnapshotter{
+		config:                  conf.RoomSnapshot,
+		store:                   store,
+		nodeKey:                 roomSnapshotNodeKey(conf),
+		roomStore:               roomStore,
+		router:                  router,
+		currentNode:             currentNode,
+		onExpired:               onExpired,
+		snapshottedRooms:        make(map[livekit.RoomName]struct{}),
+		snapshottedParticipants: make(map[livekit.ParticipantID]struct{}),
+		restored:                make(map[livekit.RoomName]*restoredRoom),
+	}
+}
+
+// node IDs are generated on start, a restarted node is identified by its address unless a key is configured
+func roomSnapshotNodeKey(conf *config.Config) string {
+	if conf.RoomSnapshot.NodeKey != "" {
+		return conf.RoomSnapshot.NodeKey
+	}
+	return net.JoinHostPort(conf.RTC.NodeIP, strconv.Itoa(int(conf.Port)))
+}
+
+// IsSnapshotting returns true once rooms have been saved, no new sessions should be started after that
+func (s *RoomSnapshotter) IsSnapshotting() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.snapshotting
+}
+
+// Snapshot saves the rooms and closes their participants asking them to resume,
+// resumes are served by the restarted server
+func (s *RoomSnapshotter) Snapshot(rooms []*rtc.Room) {
+	s.lock.Lock()
+	s.snapshotting = true
+	s.lock.Unlock()
+
+	for _, room := range rooms {
+		if room.IsClosed() || len(room.GetParticipants()) == 0 {
+			continue
+		}
+
+		snapshot := room.Snapshot()
+		if err := s.store.StoreRoomSnapshot(context.Background(), s.nodeKey, snapshot); err != nil {
+			room.Logger().Warnw("could not store room snapshot", err)
+			continue
+		}
+
+		s.lock.Lock()
+		s.snapshottedRooms[room.Name()] = struct{}{}
+		for _, ps := range snapshot.Participants {
+			s.snapshottedParticipants[livekit.ParticipantID(ps.Info.Sid)] = struct{}{}
+		}
+		s.lock.Unlock()
+
+		room.Logger().Infow("saved room snapshot", "numParticipants", len(snapshot.Participants))
+
+		for _, p := range room.GetParticipants() {
+			// leave request with resume action is only understood by newer clients,
+			// others resume on their own when the connection drops
+			sendLeave := p.ProtocolVersion().SupportsRegionsInLeaveRequest()
+			_ = p.Close(sendLeave, types.ParticipantCloseReasonMigrationRequested, true)
+		}
+	}
+}
+
+// ReleaseRoom is called when a room closes, returns true if the room had been saved to a snapshot,
+// i. e. the room continues after restart and its shared state should not be cleaned up
+func (s *RoomSnapshotter) ReleaseRoom(roomName livekit.RoomName) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	_, ok := s.snapshottedRooms[roomName]
+	delete(s.snapshottedRooms, roomName)
+	return ok
+}
+
+// ReleaseParticipant is called when a participant closes, returns true if the participant
+// had been saved to a snapshot, i. e. the participant session continues after restart
+func (s *RoomSnapshotter) ReleaseParticipant(participantID livekit.ParticipantID) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	_, ok := s.snapshottedParticipants[participantID]
+	delete(s.snapshottedParticipants, participantID)
+	return ok
+}
+
+// Restore loads saved rooms and prepares them for participants to resume,
+// rooms are created when the first participant resumes
+func (s *RoomSnapshotter) Restore(ctx context.Context) error {
+	snapshots, err := s.store.TakeRoomSnapshots(ctx, s.nodeKey)
+	if err != nil {
+		return err
+	}
+
+	for _, snapshot := range snapshots {
+		roomName := livekit.RoomName(snapshot.Room.Name)
+		ttl := s.config.TTL - time.Since(snapshot.CreatedAt)
+		if ttl <= 0 {
+			logger.Infow("discarding expired room snapshot", "room", roomName, "createdAt", snapshot.CreatedAt)
+			s.onExpired(roomName)
+			continue
+		}
+
+		if err := s.restoreRoomState(ctx, snapshot); err != nil {
+			logger.Warnw("could not restore room", err, "room", roomName)
+			continue
+		}
+
+		rr := &restoredRoom{
+			snapshot:     snapshot,
+			participants: make(map[livekit.ParticipantIdentity]*rtc.ParticipantSnapshot, len(snapshot.Participants)),
+		}
+		for _, ps := range snapshot.Participants {
+			rr.participants[livekit.ParticipantIdentity(ps.Info.Identity)] = ps
+		}
+		rr.expiry = time.AfterFunc(ttl, func() {
+			s.expire(roomName, rr)
+		})
+
+		s.lock.Lock()
+		s.restored[roomName] = rr
+		s.lock.Unlock()
+
+		logger.Infow("restored room from snapshot", "room", roomName, "numParticipants", len(snapshot.Participants))
+	}
+	return nil
+}
+
+// TakeRoom returns the snapshot a room should be created from, only once
+func (s *RoomSnapshotter) TakeRoom(roomName livekit.RoomName) *rtc.RoomSnapshot {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	rr := s.restored[roomName]
+	if rr == nil || rr.roomTaken {
+		return nil
+	}
+	rr.roomTaken = true
+	return rr.snapshot
+}
+
+// TakeParticipant returns the snapshot of a participant resuming its session, only once
+func (s *RoomSnapshotter) TakeParticipant(
+	roomName livekit.RoomName,
+	identity livekit.ParticipantIdentity,
+	participantID livekit.ParticipantID,
+) *rtc.ParticipantSnapshot {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	rr := s.restored[roomName]
+	if rr == nil {
+		return nil
+	}
+
+	ps := rr.participants[identity]
+	if ps == nil || livekit.ParticipantID(ps.Info.Sid) != participantID {
+		return nil
+	}
+	delete(rr.participants, identity)
+
+	if len(rr.participants) == 0 {
+		// everyone is back, room continues as usual
+		rr.expiry.Stop()
+		delete(s.restored, roomName)
+	}
+	return ps
+}
+
+// restoreRoomState stores the room and its participants so that resuming participants are recognized as migrating in
+func (s *RoomSnapshotter) restoreRoomState(ctx context.Context, snapshot *rtc.RoomSnapshot) error {
+	roomName := livekit.RoomName(snapshot.Room.Name)
+	if err := s.roomStore.StoreRoom(ctx, snapshot.Room, snapshot.Internal); err != nil {
+		return err
+	}
+	for _, ps := range snapshot.Participants {
+		if err := s.roomStore.StoreParticipant(ctx, roomName, ps.Info); err != nil {
+			return err
+		}
+	}
+	return s.router.SetNodeForRoom(ctx, roomName, s.currentNode.NodeID())
+}
+
+func (s *RoomSnapshotter) expire(roomName livekit.RoomName, rr *restoredRoom) {
+	s.lock.Lock()
+	if s.restored[roomName] != rr {
+		s.lock.Unlock()
+		return
+	}
+	delete(s.restored, roomName)
+	s.lock.Unlock()
+
+	ctx := context.Background()
+	for identity := range rr.participants {
+		if err := s.roomStore.DeleteParticipant(ctx, roomName, identity); err != nil {
+			logger.Warnw("could not delete participant", err, "room", roomName, "participant", identity)
+		}
+	}
+
+	logger.Infow("room snapshot expired", "room", roomName, "numParticipantsNotResumed", len(rr.participants))
+	s.onExpired(roomName)
+}
@@ -0,0 +1,166 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func newTestRoomSnapshot(createdAt time.Time) *rtc.RoomSnapshot {
+	return &rtc.RoomSnapshot{
+		Room: &livekit.Room{Sid: "RM_room", Name: "room"},
+		Participants: []*rtc.ParticipantSnapshot{
+			{
+				Info:               &livekit.ParticipantInfo{Sid: "PA_1", Identity: "p1"},
+				LastPubReliableSeq: 10,
+			},
+			{
+				Info: &livekit.ParticipantInfo{Sid: "PA_2", Identity: "p2"},
+			},
+		},
+		CreatedAt: createdAt,
+	}
+}
+
+func TestRoomSnapshotter(t *testing.T) {
+	t.Run("restores rooms for resuming participants", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+
+		conf.RoomSnapshot.NodeKey = "node"
+
+		node, err := routing.NewLocalNode(conf)
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		store := service.NewFileRoomSnapshotStore(t.TempDir())
+		require.NoError(t, store.StoreRoomSnapshot(ctx, "node", newTestRoomSnapshot(time.Now())))
+
+		roomStore := service.NewLocalStore()
+		router := &routingfakes.FakeRouter{}
+		snapshotter := service.NewRoomSnapshotter(conf, store, roomStore, router, node, func(livekit.RoomName) {})
+		require.NoError(t, snapshotter.Restore(ctx))
+
+		// snapshot is taken only once
+		snapshots, err := store.TakeRoomSnapshots(ctx, "node")
+		require.NoError(t, err)
+		require.Empty(t, snapshots)
+
+		room, _, err := roomStore.LoadRoom(ctx, "room", false)
+		require.NoError(t, err)
+		require.Equal(t, "RM_room", room.Sid)
+		p, err := roomStore.LoadParticipant(ctx, "room", "p1")
+		require.NoError(t, err)
+		require.Equal(t, "PA_1", p.Sid)
+		require.Equal(t, 1, router.SetNodeForRoomCallCount())
+
+		require.NotNil(t, snapshotter.TakeRoom("room"))
+		require.Nil(t, snapshotter.TakeRoom("room"))
+
+		require.Nil(t, snapshotter.TakeParticipant("room", "p1", "PA_other"))
+		ps := snapshotter.TakeParticipant("room", "p1", "PA_1")
+		require.NotNil(t, ps)
+		require.Equal(t, uint32(10), ps.LastPubReliableSeq)
+		require.Nil(t, snapshotter.TakeParticipant("room", "p1", "PA_1"))
+	})
+
+	t.Run("expires rooms not resumed", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.RoomSnapshot.TTL = 100 * time.Millisecond
+		conf.RoomSnapshot.NodeKey = "node"
+
+		node, err := routing.NewLocalNode(conf)
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		store := service.NewLocalStore()
+		require.NoError(t, store.StoreRoomSnapshot(ctx, "node", newTestRoomSnapshot(time.Now().Add(-time.Minute))))
+		require.NoError(t, store.StoreRoomSnapshot(ctx, "node", &rtc.RoomSnapshot{
+			Room:         &livekit.Room{Sid: "RM_fresh", Name: "fresh"},
+			Participants: newTestRoomSnapshot(time.Now()).Participants,
+			CreatedAt:    time.Now(),
+		}))
+
+		expired := make(chan livekit.RoomName, 2)
+		snapshotter := service.NewRoomSnapshotter(conf, store, store, &routingfakes.FakeRouter{}, node, func(roomName livekit.RoomName) {
+			expired <- roomName
+		})
+		require.NoError(t, snapshotter.Restore(ctx))
+
+		// stale snapshot is discarded right away
+		require.Equal(t, livekit.RoomName("room"), <-expired)
+		require.Nil(t, snapshotter.TakeRoom("room"))
+
+		// participants not resuming in time are dropped
+		require.NotNil(t, snapshotter.TakeParticipant("fresh", "p1", "PA_1"))
+		select {
+		case roomName := <-expired:
+			require.Equal(t, livekit.RoomName("fresh"), roomName)
+		case <-time.After(time.Second):
+			t.Fatal("restored room did not expire")
+		}
+		_, err = store.LoadParticipant(ctx, "fresh", "p2")
+		require.ErrorIs(t, err, service.ErrParticipantNotFound)
+		require.Nil(t, snapshotter.TakeParticipant("fresh", "p2", "PA_2"))
+	})
+
+	t.Run("restores only rooms of the node", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.RoomSnapshot.NodeKey = "node"
+
+		node, err := routing.NewLocalNode(conf)
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		store := service.NewLocalStore()
+		require.NoError(t, store.StoreRoomSnapshot(ctx, "other", newTestRoomSnapshot(time.Now())))
+
+		snapshotter := service.NewRoomSnapshotter(conf, store, store, &routingfakes.FakeRouter{}, node, func(livekit.RoomName) {})
+		require.NoError(t, snapshotter.Restore(ctx))
+		require.Nil(t, snapshotter.TakeRoom("room"))
+
+		// snapshot of the other node is left for it
+		snapshots, err := store.TakeRoomSnapshots(ctx, "other")
+		require.NoError(t, err)
+		require.Len(t, snapshots, 1)
+	})
+
+	t.Run("does not claim unknown rooms or participants", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+
+		node, err := routing.NewLocalNode(conf)
+		require.NoError(t, err)
+
+		snapshotter := service.NewRoomSnapshotter(conf, service.NewLocalStore(), service.NewLocalStore(), &routingfakes.FakeRouter{}, node, func(livekit.RoomName) {})
+		require.False(t, snapshotter.IsSnapshotting())
+		require.False(t, snapshotter.ReleaseRoom("room"))
+		require.False(t, snapshotter.ReleaseParticipant("PA_participant"))
+	})
+}
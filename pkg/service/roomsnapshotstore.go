@@ -0,0 +1,95 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const roomSnapshotFileExt = ".snapshot"
+
+// FileRoomSnapshotStore keeps room snapshots in a directory, one file per room.
+// Used on single node deployments where snapshots need to survive the process, the directory is local to the node
+// so the node key is not needed to tell snapshots of nodes apart.
+type FileRoomSnapshotStore struct {
+	dir string
+}
+
+func NewFileRoomSnapshotStore(dir string) *FileRoomSnapshotStore {
+	return &FileRoomSnapshotStore{
+		dir: dir,
+	}
+}
+
+func (s *FileRoomSnapshotStore) StoreRoomSnapshot(_ context.Context, _ string, snapshot *rtc.RoomSnapshot) error {
+	data, err := snapshot.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+
+	// write to a temporary file first, a partially written snapshot should never be restored
+	path := filepath.Join(s.dir, url.PathEscape(snapshot.Room.Name)+roomSnapshotFileExt)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *FileRoomSnapshotStore) TakeRoomSnapshots(_ context.Context, _ string) ([]*rtc.RoomSnapshot, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var snapshots []*rtc.RoomSnapshot
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), roomSnapshotFileExt) {
+			continue
+		}
+
+		path := filepath.Join(s.dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			logger.Warnw("could not read room snapshot", err, "path", path)
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			logger.Warnw("could not remove room snapshot", err, "path", path)
+		}
+
+		snapshot, err := rtc.UnmarshalRoomSnapshot(data)
+		if err != nil {
+			logger.Warnw("could not decode room snapshot", err, "path", path)
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
@@ -55,6 +55,8 @@ type RTCService struct {
 	isDev         bool
 	limits        config.LimitConfig
 	telemetry     telemetry.TelemetryService
+	store         ServiceStore
+	admission     AdmissionHook
 
 	mu          sync.Mutex
 	connections map[*websocket.Conn]struct{}
@@ -65,6 +67,8 @@ func NewRTCService(
 	ra RoomAllocator,
 	router routing.MessageRouter,
 	telemetry telemetry.TelemetryService,
+	store ServiceStore,
+	admission AdmissionHook,
 ) *RTCService {
 	s := &RTCService{
 		router:        router,
@@ -73,6 +77,8 @@ func NewRTCService(
 		isDev:         conf.Development,
 		limits:        conf.Limit,
 		telemetry:     telemetry,
+		store:         store,
+		admission:     admission,
 		connections:   map[*websocket.Conn]struct{}{},
 	}
 
@@ -271,9 +277,65 @@ func (s *RTCService) validateInternal(
 		pi.ID = livekit.ParticipantID(joinRequest.ParticipantSid)
 	}
 
+	if s.admission != nil && !s.isAdmitted(r.Context(), res.roomName, &pi) {
+		if code, err := s.admit(lgr, r, res.roomName, res.region, &pi); err != nil {
+			return res.roomName, routing.ParticipantInit{}, code, err
+		}
+	}
+
 	return res.roomName, pi, code, err
 }
 
+// isAdmitted returns true when the join resumes the session of a participant in the room,
+// which went through admission when it joined
+func (s *RTCService) isAdmitted(ctx context.Context, roomName livekit.RoomName, pi *routing.ParticipantInit) bool {
+	if !pi.Reconnect || pi.ID == "" {
+		return false
+	}
+	p, err := s.store.LoadParticipant(ctx, roomName, pi.Identity)
+	return err == nil && livekit.ParticipantID(p.Sid) == pi.ID
+}
+
+// admit runs the admission hook on the join, the grants of the participant are replaced by those it decided on
+func (s *RTCService) admit(lgr logger.Logger, r *http.Request, roomName livekit.RoomName, region string, pi *routing.ParticipantInit) (int, error) {
+	req := newAdmissionRequest(r, s.config.Admission.CountryHeader, roomName, region, pi.Grants, pi.Client, pi.Reconnect)
+	room, _, err := s.store.LoadRoom(r.Context(), roomName, false)
+	switch {
+	case err == nil:
+		req.RoomState = &AdmissionRoomState{
+			Exists:          true,
+			NumParticipants: room.NumParticipants,
+			NumPublishers:   room.NumPublishers,
+			MaxParticipants: room.MaxParticipants,
+			Metadata:        room.Metadata,
+		}
+	case !errors.Is(err, ErrRoomNotFound):
+		lgr.Warnw("could not load room for admission", err)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.config.Admission.Timeout)
+	defer cancel()
+	decision, err := s.admission.Admit(ctx, req)
+	if err != nil {
+		if s.config.Admission.FailOpen {
+			lgr.Warnw("admission failed, admitting join", err)
+			return http.StatusOK, nil
+		}
+		lgr.Warnw("admission failed", err)
+		return http.StatusServiceUnavailable, ErrAdmissionFailed
+	}
+
+	if decision.Deny {
+		lgr.Infow("join denied by admission", "reason", decision.Reason)
+		if decision.Reason != "" {
+			return http.StatusForbidden, fmt.Errorf("%w: %s", ErrAdmissionDenied, decision.Reason)
+		}
+		return http.StatusForbidden, ErrAdmissionDenied
+	}
+	pi.Grants = decision.Apply(pi.Grants)
+	return http.StatusOK, nil
+}
+
 func (s *RTCService) v0(w http.ResponseWriter, r *http.Request) {
 	s.serve(w, r, false)
 }
@@ -623,8 +685,13 @@ func (s *RTCService) startConnection(
 		return cr, nil, err
 	}
 
+	nodeID, err := s.roomAllocator.SelectParticipantNode(ctx, roomName, pi.Region)
+	if err != nil {
+		return cr, nil, err
+	}
+
 	// this needs to be started first *before* using router functions on this node
-	cr.StartParticipantSignalResults, err = s.router.StartParticipantSignal(ctx, roomName, pi)
+	cr.StartParticipantSignalResults, err = s.router.StartParticipantSignalWithNodeID(ctx, roomName, pi, nodeID)
 	if err != nil {
 		return cr, nil, err
 	}
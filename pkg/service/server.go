@@ -64,7 +64,7 @@ type LivekitServer struct {
 }
 
 func NewLivekitServer(conf *config.Config,
-	roomService livekit.RoomService,
+	roomService *RoomService,
 	agentDispatchService *AgentDispatchService,
 	egressService *EgressService,
 	ingressService *IngressService,
@@ -110,6 +110,7 @@ func NewLivekitServer(conf *config.Config,
 			MaxAge: 86400,
 		}),
 		negroni.HandlerFunc(RemoveDoubleSlashes),
+		negroni.HandlerFunc(ExpectedVersionsMiddleware),
 	}
 	if keyProvider != nil {
 		middlewares = append(middlewares, NewAPIKeyAuthMiddleware(keyProvider))
@@ -140,6 +141,7 @@ func NewLivekitServer(conf *config.Config,
 	}
 
 	xtwirp.RegisterServer(mux, roomServer)
+	SetupModerationRoutes(mux, roomService)
 	xtwirp.RegisterServer(mux, agentDispatchServer)
 	xtwirp.RegisterServer(mux, egressServer)
 	xtwirp.RegisterServer(mux, ingressServer)
@@ -147,6 +149,9 @@ func NewLivekitServer(conf *config.Config,
 	rtcService.SetupRoutes(mux)
 	whipService.SetupRoutes(mux)
 	mux.Handle("/agent", agentService)
+	mux.Handle("/admin/evacuation", roomManager.Evacuator())
+	mux.Handle("/admin/agent_jobs", agentService.JobHistory())
+	mux.HandleFunc("/admin/agent_scaling", agentService.ServeScaling)
 	mux.HandleFunc("/", s.defaultHandler)
 
 	s.httpServer = &http.Server{
@@ -275,6 +280,13 @@ func (s *LivekitServer) Start() error {
 		return err
 	}
 
+	if s.config.RoomSnapshot.Enabled {
+		// restore before serving, resuming participants need to find their rooms
+		if err := s.roomManager.RestoreRooms(context.Background()); err != nil {
+			logger.Warnw("could not restore rooms", err)
+		}
+	}
+
 	httpGroup := &errgroup.Group{}
 	for _, ln := range listeners {
 		l := ln
@@ -315,7 +327,24 @@ func (s *LivekitServer) Start() error {
 	return nil
 }
 
+// Evacuate moves rooms hosted on this node to other nodes, node stops accepting new rooms
+func (s *LivekitServer) Evacuate() error {
+	return s.roomManager.Evacuator().Start()
+}
+
 func (s *LivekitServer) Stop(force bool) {
+	if !force && s.config.Evacuation.OnShutdown && s.roomManager.HasParticipants() {
+		if err := s.Evacuate(); err != nil && !errors.Is(err, ErrEvacuationInProgress) {
+			logger.Warnw("could not evacuate node", err)
+		}
+		s.roomManager.Evacuator().Wait()
+	}
+
+	if !force && s.config.RoomSnapshot.Enabled && s.roomManager.HasParticipants() {
+		// participants resume their sessions once the server is back
+		s.roomManager.SnapshotRooms()
+	}
+
 	// wait for all participants to exit
 	s.router.Drain()
 	partTicker := time.NewTicker(5 * time.Second)
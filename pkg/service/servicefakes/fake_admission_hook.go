@@ -0,0 +1,117 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package servicefakes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+type FakeAdmissionHook struct {
+	AdmitStub        func(context.Context, *service.AdmissionRequest) (*service.AdmissionDecision, error)
+	admitMutex       sync.RWMutex
+	admitArgsForCall []struct {
+		arg1 context.Context
+		arg2 *service.AdmissionRequest
+	}
+	admitReturns struct {
+		result1 *service.AdmissionDecision
+		result2 error
+	}
+	admitReturnsOnCall map[int]struct {
+		result1 *service.AdmissionDecision
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeAdmissionHook) Admit(arg1 context.Context, arg2 *service.AdmissionRequest) (*service.AdmissionDecision, error) {
+	fake.admitMutex.Lock()
+	ret, specificReturn := fake.admitReturnsOnCall[len(fake.admitArgsForCall)]
+	fake.admitArgsForCall = append(fake.admitArgsForCall, struct {
+		arg1 context.Context
+		arg2 *service.AdmissionRequest
+	}{arg1, arg2})
+	stub := fake.AdmitStub
+	fakeReturns := fake.admitReturns
+	fake.recordInvocation("Admit", []interface{}{arg1, arg2})
+	fake.admitMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeAdmissionHook) AdmitCallCount() int {
+	fake.admitMutex.RLock()
+	defer fake.admitMutex.RUnlock()
+	return len(fake.admitArgsForCall)
+}
+
+func (fake *FakeAdmissionHook) AdmitCalls(stub func(context.Context, *service.AdmissionRequest) (*service.AdmissionDecision, error)) {
+	fake.admitMutex.Lock()
+	defer fake.admitMutex.Unlock()
+	fake.AdmitStub = stub
+}
+
+func (fake *FakeAdmissionHook) AdmitArgsForCall(i int) (context.Context, *service.AdmissionRequest) {
+	fake.admitMutex.RLock()
+	defer fake.admitMutex.RUnlock()
+	argsForCall := fake.admitArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeAdmissionHook) AdmitReturns(result1 *service.AdmissionDecision, result2 error) {
+	fake.admitMutex.Lock()
+	defer fake.admitMutex.Unlock()
+	fake.AdmitStub = nil
+	fake.admitReturns = struct {
+		result1 *service.AdmissionDecision
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAdmissionHook) AdmitReturnsOnCall(i int, result1 *service.AdmissionDecision, result2 error) {
+	fake.admitMutex.Lock()
+	defer fake.admitMutex.Unlock()
+	fake.AdmitStub = nil
+	if fake.admitReturnsOnCall == nil {
+		fake.admitReturnsOnCall = make(map[int]struct {
+			result1 *service.AdmissionDecision
+			result2 error
+		})
+	}
+	fake.admitReturnsOnCall[i] = struct {
+		result1 *service.AdmissionDecision
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAdmissionHook) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeAdmissionHook) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ service.AdmissionHook = new(FakeAdmissionHook)
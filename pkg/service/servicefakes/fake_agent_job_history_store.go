@@ -0,0 +1,197 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package servicefakes
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/protocol/livekit"
+)
+
+type FakeAgentJobHistoryStore struct {
+	ListAgentJobHistoryStub        func(context.Context, service.AgentJobFilter) ([]*livekit.Job, error)
+	listAgentJobHistoryMutex       sync.RWMutex
+	listAgentJobHistoryArgsForCall []struct {
+		arg1 context.Context
+		arg2 service.AgentJobFilter
+	}
+	listAgentJobHistoryReturns struct {
+		result1 []*livekit.Job
+		result2 error
+	}
+	listAgentJobHistoryReturnsOnCall map[int]struct {
+		result1 []*livekit.Job
+		result2 error
+	}
+	StoreAgentJobHistoryStub        func(context.Context, *livekit.Job, int, time.Duration) error
+	storeAgentJobHistoryMutex       sync.RWMutex
+	storeAgentJobHistoryArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.Job
+		arg3 int
+		arg4 time.Duration
+	}
+	storeAgentJobHistoryReturns struct {
+		result1 error
+	}
+	storeAgentJobHistoryReturnsOnCall map[int]struct {
+		result1 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeAgentJobHistoryStore) ListAgentJobHistory(arg1 context.Context, arg2 service.AgentJobFilter) ([]*livekit.Job, error) {
+	fake.listAgentJobHistoryMutex.Lock()
+	ret, specificReturn := fake.listAgentJobHistoryReturnsOnCall[len(fake.listAgentJobHistoryArgsForCall)]
+	fake.listAgentJobHistoryArgsForCall = append(fake.listAgentJobHistoryArgsForCall, struct {
+		arg1 context.Context
+		arg2 service.AgentJobFilter
+	}{arg1, arg2})
+	stub := fake.ListAgentJobHistoryStub
+	fakeReturns := fake.listAgentJobHistoryReturns
+	fake.recordInvocation("ListAgentJobHistory", []interface{}{arg1, arg2})
+	fake.listAgentJobHistoryMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeAgentJobHistoryStore) ListAgentJobHistoryCallCount() int {
+	fake.listAgentJobHistoryMutex.RLock()
+	defer fake.listAgentJobHistoryMutex.RUnlock()
+	return len(fake.listAgentJobHistoryArgsForCall)
+}
+
+func (fake *FakeAgentJobHistoryStore) ListAgentJobHistoryCalls(stub func(context.Context, service.AgentJobFilter) ([]*livekit.Job, error)) {
+	fake.listAgentJobHistoryMutex.Lock()
+	defer fake.listAgentJobHistoryMutex.Unlock()
+	fake.ListAgentJobHistoryStub = stub
+}
+
+func (fake *FakeAgentJobHistoryStore) ListAgentJobHistoryArgsForCall(i int) (context.Context, service.AgentJobFilter) {
+	fake.listAgentJobHistoryMutex.RLock()
+	defer fake.listAgentJobHistoryMutex.RUnlock()
+	argsForCall := fake.listAgentJobHistoryArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeAgentJobHistoryStore) ListAgentJobHistoryReturns(result1 []*livekit.Job, result2 error) {
+	fake.listAgentJobHistoryMutex.Lock()
+	defer fake.listAgentJobHistoryMutex.Unlock()
+	fake.ListAgentJobHistoryStub = nil
+	fake.listAgentJobHistoryReturns = struct {
+		result1 []*livekit.Job
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAgentJobHistoryStore) ListAgentJobHistoryReturnsOnCall(i int, result1 []*livekit.Job, result2 error) {
+	fake.listAgentJobHistoryMutex.Lock()
+	defer fake.listAgentJobHistoryMutex.Unlock()
+	fake.ListAgentJobHistoryStub = nil
+	if fake.listAgentJobHistoryReturnsOnCall == nil {
+		fake.listAgentJobHistoryReturnsOnCall = make(map[int]struct {
+			result1 []*livekit.Job
+			result2 error
+		})
+	}
+	fake.listAgentJobHistoryReturnsOnCall[i] = struct {
+		result1 []*livekit.Job
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAgentJobHistoryStore) StoreAgentJobHistory(arg1 context.Context, arg2 *livekit.Job, arg3 int, arg4 time.Duration) error {
+	fake.storeAgentJobHistoryMutex.Lock()
+	ret, specificReturn := fake.storeAgentJobHistoryReturnsOnCall[len(fake.storeAgentJobHistoryArgsForCall)]
+	fake.storeAgentJobHistoryArgsForCall = append(fake.storeAgentJobHistoryArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.Job
+		arg3 int
+		arg4 time.Duration
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.StoreAgentJobHistoryStub
+	fakeReturns := fake.storeAgentJobHistoryReturns
+	fake.recordInvocation("StoreAgentJobHistory", []interface{}{arg1, arg2, arg3, arg4})
+	fake.storeAgentJobHistoryMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeAgentJobHistoryStore) StoreAgentJobHistoryCallCount() int {
+	fake.storeAgentJobHistoryMutex.RLock()
+	defer fake.storeAgentJobHistoryMutex.RUnlock()
+	return len(fake.storeAgentJobHistoryArgsForCall)
+}
+
+func (fake *FakeAgentJobHistoryStore) StoreAgentJobHistoryCalls(stub func(context.Context, *livekit.Job, int, time.Duration) error) {
+	fake.storeAgentJobHistoryMutex.Lock()
+	defer fake.storeAgentJobHistoryMutex.Unlock()
+	fake.StoreAgentJobHistoryStub = stub
+}
+
+func (fake *FakeAgentJobHistoryStore) StoreAgentJobHistoryArgsForCall(i int) (context.Context, *livekit.Job, int, time.Duration) {
+	fake.storeAgentJobHistoryMutex.RLock()
+	defer fake.storeAgentJobHistoryMutex.RUnlock()
+	argsForCall := fake.storeAgentJobHistoryArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeAgentJobHistoryStore) StoreAgentJobHistoryReturns(result1 error) {
+	fake.storeAgentJobHistoryMutex.Lock()
+	defer fake.storeAgentJobHistoryMutex.Unlock()
+	fake.StoreAgentJobHistoryStub = nil
+	fake.storeAgentJobHistoryReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAgentJobHistoryStore) StoreAgentJobHistoryReturnsOnCall(i int, result1 error) {
+	fake.storeAgentJobHistoryMutex.Lock()
+	defer fake.storeAgentJobHistoryMutex.Unlock()
+	fake.StoreAgentJobHistoryStub = nil
+	if fake.storeAgentJobHistoryReturnsOnCall == nil {
+		fake.storeAgentJobHistoryReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeAgentJobHistoryReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAgentJobHistoryStore) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeAgentJobHistoryStore) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ service.AgentJobHistoryStore = new(FakeAgentJobHistoryStore)
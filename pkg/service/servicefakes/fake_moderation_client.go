@@ -0,0 +1,1785 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package servicefakes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/rpc"
+	"github.com/livekit/psrpc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type FakeModerationClient struct {
+	AdmitParticipantStub        func(context.Context, rpc.RoomTopic, *livekit.RoomParticipantIdentity, ...psrpc.RequestOption) (*livekit.ParticipantInfo, error)
+	admitParticipantMutex       sync.RWMutex
+	admitParticipantArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *livekit.RoomParticipantIdentity
+		arg4 []psrpc.RequestOption
+	}
+	admitParticipantReturns struct {
+		result1 *livekit.ParticipantInfo
+		result2 error
+	}
+	admitParticipantReturnsOnCall map[int]struct {
+		result1 *livekit.ParticipantInfo
+		result2 error
+	}
+	ApprovePublishRequestStub        func(context.Context, rpc.RoomTopic, *livekit.RoomParticipantIdentity, ...psrpc.RequestOption) (*livekit.ParticipantInfo, error)
+	approvePublishRequestMutex       sync.RWMutex
+	approvePublishRequestArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *livekit.RoomParticipantIdentity
+		arg4 []psrpc.RequestOption
+	}
+	approvePublishRequestReturns struct {
+		result1 *livekit.ParticipantInfo
+		result2 error
+	}
+	approvePublishRequestReturnsOnCall map[int]struct {
+		result1 *livekit.ParticipantInfo
+		result2 error
+	}
+	DenyPublishRequestStub        func(context.Context, rpc.RoomTopic, *livekit.RoomParticipantIdentity, ...psrpc.RequestOption) (*livekit.ParticipantInfo, error)
+	denyPublishRequestMutex       sync.RWMutex
+	denyPublishRequestArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *livekit.RoomParticipantIdentity
+		arg4 []psrpc.RequestOption
+	}
+	denyPublishRequestReturns struct {
+		result1 *livekit.ParticipantInfo
+		result2 error
+	}
+	denyPublishRequestReturnsOnCall map[int]struct {
+		result1 *livekit.ParticipantInfo
+		result2 error
+	}
+	GetDataHistoryStub        func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)
+	getDataHistoryMutex       sync.RWMutex
+	getDataHistoryArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}
+	getDataHistoryReturns struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	getDataHistoryReturnsOnCall map[int]struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	GetDataPolicyStub        func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)
+	getDataPolicyMutex       sync.RWMutex
+	getDataPolicyArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}
+	getDataPolicyReturns struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	getDataPolicyReturnsOnCall map[int]struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	GetDataRetentionStub        func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)
+	getDataRetentionMutex       sync.RWMutex
+	getDataRetentionArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}
+	getDataRetentionReturns struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	getDataRetentionReturnsOnCall map[int]struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	GetLobbyStub        func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)
+	getLobbyMutex       sync.RWMutex
+	getLobbyArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}
+	getLobbyReturns struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	getLobbyReturnsOnCall map[int]struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	GetMetadataVersionsStub        func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)
+	getMetadataVersionsMutex       sync.RWMutex
+	getMetadataVersionsArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}
+	getMetadataVersionsReturns struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	getMetadataVersionsReturnsOnCall map[int]struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	GetRoomStateStub        func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)
+	getRoomStateMutex       sync.RWMutex
+	getRoomStateArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}
+	getRoomStateReturns struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	getRoomStateReturnsOnCall map[int]struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	GetSubscriptionLimitsStub        func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)
+	getSubscriptionLimitsMutex       sync.RWMutex
+	getSubscriptionLimitsArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}
+	getSubscriptionLimitsReturns struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	getSubscriptionLimitsReturnsOnCall map[int]struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	GetSubscriptionPolicyStub        func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)
+	getSubscriptionPolicyMutex       sync.RWMutex
+	getSubscriptionPolicyArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}
+	getSubscriptionPolicyReturns struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	getSubscriptionPolicyReturnsOnCall map[int]struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	HandOffAgentJobStub        func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)
+	handOffAgentJobMutex       sync.RWMutex
+	handOffAgentJobArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}
+	handOffAgentJobReturns struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	handOffAgentJobReturnsOnCall map[int]struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	ListPendingParticipantsStub        func(context.Context, rpc.RoomTopic, *livekit.ListParticipantsRequest, ...psrpc.RequestOption) (*livekit.ListParticipantsResponse, error)
+	listPendingParticipantsMutex       sync.RWMutex
+	listPendingParticipantsArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *livekit.ListParticipantsRequest
+		arg4 []psrpc.RequestOption
+	}
+	listPendingParticipantsReturns struct {
+		result1 *livekit.ListParticipantsResponse
+		result2 error
+	}
+	listPendingParticipantsReturnsOnCall map[int]struct {
+		result1 *livekit.ListParticipantsResponse
+		result2 error
+	}
+	RejectParticipantStub        func(context.Context, rpc.RoomTopic, *livekit.RoomParticipantIdentity, ...psrpc.RequestOption) (*livekit.RemoveParticipantResponse, error)
+	rejectParticipantMutex       sync.RWMutex
+	rejectParticipantArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *livekit.RoomParticipantIdentity
+		arg4 []psrpc.RequestOption
+	}
+	rejectParticipantReturns struct {
+		result1 *livekit.RemoveParticipantResponse
+		result2 error
+	}
+	rejectParticipantReturnsOnCall map[int]struct {
+		result1 *livekit.RemoveParticipantResponse
+		result2 error
+	}
+	UpdateDataPolicyStub        func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)
+	updateDataPolicyMutex       sync.RWMutex
+	updateDataPolicyArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}
+	updateDataPolicyReturns struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	updateDataPolicyReturnsOnCall map[int]struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	UpdateDataRetentionStub        func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)
+	updateDataRetentionMutex       sync.RWMutex
+	updateDataRetentionArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}
+	updateDataRetentionReturns struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	updateDataRetentionReturnsOnCall map[int]struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	UpdateLobbyStub        func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)
+	updateLobbyMutex       sync.RWMutex
+	updateLobbyArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}
+	updateLobbyReturns struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	updateLobbyReturnsOnCall map[int]struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	UpdateParticipantSubscriptionPermissionStub        func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*livekit.ParticipantInfo, error)
+	updateParticipantSubscriptionPermissionMutex       sync.RWMutex
+	updateParticipantSubscriptionPermissionArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}
+	updateParticipantSubscriptionPermissionReturns struct {
+		result1 *livekit.ParticipantInfo
+		result2 error
+	}
+	updateParticipantSubscriptionPermissionReturnsOnCall map[int]struct {
+		result1 *livekit.ParticipantInfo
+		result2 error
+	}
+	UpdateRoomStateStub        func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)
+	updateRoomStateMutex       sync.RWMutex
+	updateRoomStateArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}
+	updateRoomStateReturns struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	updateRoomStateReturnsOnCall map[int]struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	UpdateSubscriptionLimitsStub        func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)
+	updateSubscriptionLimitsMutex       sync.RWMutex
+	updateSubscriptionLimitsArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}
+	updateSubscriptionLimitsReturns struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	updateSubscriptionLimitsReturnsOnCall map[int]struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	UpdateSubscriptionPolicyStub        func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)
+	updateSubscriptionPolicyMutex       sync.RWMutex
+	updateSubscriptionPolicyArgsForCall []struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}
+	updateSubscriptionPolicyReturns struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	updateSubscriptionPolicyReturnsOnCall map[int]struct {
+		result1 *structpb.Struct
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeModerationClient) AdmitParticipant(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *livekit.RoomParticipantIdentity, arg4 ...psrpc.RequestOption) (*livekit.ParticipantInfo, error) {
+	fake.admitParticipantMutex.Lock()
+	ret, specificReturn := fake.admitParticipantReturnsOnCall[len(fake.admitParticipantArgsForCall)]
+	fake.admitParticipantArgsForCall = append(fake.admitParticipantArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *livekit.RoomParticipantIdentity
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.AdmitParticipantStub
+	fakeReturns := fake.admitParticipantReturns
+	fake.recordInvocation("AdmitParticipant", []interface{}{arg1, arg2, arg3, arg4})
+	fake.admitParticipantMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) AdmitParticipantCallCount() int {
+	fake.admitParticipantMutex.RLock()
+	defer fake.admitParticipantMutex.RUnlock()
+	return len(fake.admitParticipantArgsForCall)
+}
+
+func (fake *FakeModerationClient) AdmitParticipantCalls(stub func(context.Context, rpc.RoomTopic, *livekit.RoomParticipantIdentity, ...psrpc.RequestOption) (*livekit.ParticipantInfo, error)) {
+	fake.admitParticipantMutex.Lock()
+	defer fake.admitParticipantMutex.Unlock()
+	fake.AdmitParticipantStub = stub
+}
+
+func (fake *FakeModerationClient) AdmitParticipantArgsForCall(i int) (context.Context, rpc.RoomTopic, *livekit.RoomParticipantIdentity, []psrpc.RequestOption) {
+	fake.admitParticipantMutex.RLock()
+	defer fake.admitParticipantMutex.RUnlock()
+	argsForCall := fake.admitParticipantArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) AdmitParticipantReturns(result1 *livekit.ParticipantInfo, result2 error) {
+	fake.admitParticipantMutex.Lock()
+	defer fake.admitParticipantMutex.Unlock()
+	fake.AdmitParticipantStub = nil
+	fake.admitParticipantReturns = struct {
+		result1 *livekit.ParticipantInfo
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) AdmitParticipantReturnsOnCall(i int, result1 *livekit.ParticipantInfo, result2 error) {
+	fake.admitParticipantMutex.Lock()
+	defer fake.admitParticipantMutex.Unlock()
+	fake.AdmitParticipantStub = nil
+	if fake.admitParticipantReturnsOnCall == nil {
+		fake.admitParticipantReturnsOnCall = make(map[int]struct {
+			result1 *livekit.ParticipantInfo
+			result2 error
+		})
+	}
+	fake.admitParticipantReturnsOnCall[i] = struct {
+		result1 *livekit.ParticipantInfo
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) ApprovePublishRequest(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *livekit.RoomParticipantIdentity, arg4 ...psrpc.RequestOption) (*livekit.ParticipantInfo, error) {
+	fake.approvePublishRequestMutex.Lock()
+	ret, specificReturn := fake.approvePublishRequestReturnsOnCall[len(fake.approvePublishRequestArgsForCall)]
+	fake.approvePublishRequestArgsForCall = append(fake.approvePublishRequestArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *livekit.RoomParticipantIdentity
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.ApprovePublishRequestStub
+	fakeReturns := fake.approvePublishRequestReturns
+	fake.recordInvocation("ApprovePublishRequest", []interface{}{arg1, arg2, arg3, arg4})
+	fake.approvePublishRequestMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) ApprovePublishRequestCallCount() int {
+	fake.approvePublishRequestMutex.RLock()
+	defer fake.approvePublishRequestMutex.RUnlock()
+	return len(fake.approvePublishRequestArgsForCall)
+}
+
+func (fake *FakeModerationClient) ApprovePublishRequestCalls(stub func(context.Context, rpc.RoomTopic, *livekit.RoomParticipantIdentity, ...psrpc.RequestOption) (*livekit.ParticipantInfo, error)) {
+	fake.approvePublishRequestMutex.Lock()
+	defer fake.approvePublishRequestMutex.Unlock()
+	fake.ApprovePublishRequestStub = stub
+}
+
+func (fake *FakeModerationClient) ApprovePublishRequestArgsForCall(i int) (context.Context, rpc.RoomTopic, *livekit.RoomParticipantIdentity, []psrpc.RequestOption) {
+	fake.approvePublishRequestMutex.RLock()
+	defer fake.approvePublishRequestMutex.RUnlock()
+	argsForCall := fake.approvePublishRequestArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) ApprovePublishRequestReturns(result1 *livekit.ParticipantInfo, result2 error) {
+	fake.approvePublishRequestMutex.Lock()
+	defer fake.approvePublishRequestMutex.Unlock()
+	fake.ApprovePublishRequestStub = nil
+	fake.approvePublishRequestReturns = struct {
+		result1 *livekit.ParticipantInfo
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) ApprovePublishRequestReturnsOnCall(i int, result1 *livekit.ParticipantInfo, result2 error) {
+	fake.approvePublishRequestMutex.Lock()
+	defer fake.approvePublishRequestMutex.Unlock()
+	fake.ApprovePublishRequestStub = nil
+	if fake.approvePublishRequestReturnsOnCall == nil {
+		fake.approvePublishRequestReturnsOnCall = make(map[int]struct {
+			result1 *livekit.ParticipantInfo
+			result2 error
+		})
+	}
+	fake.approvePublishRequestReturnsOnCall[i] = struct {
+		result1 *livekit.ParticipantInfo
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) DenyPublishRequest(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *livekit.RoomParticipantIdentity, arg4 ...psrpc.RequestOption) (*livekit.ParticipantInfo, error) {
+	fake.denyPublishRequestMutex.Lock()
+	ret, specificReturn := fake.denyPublishRequestReturnsOnCall[len(fake.denyPublishRequestArgsForCall)]
+	fake.denyPublishRequestArgsForCall = append(fake.denyPublishRequestArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *livekit.RoomParticipantIdentity
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.DenyPublishRequestStub
+	fakeReturns := fake.denyPublishRequestReturns
+	fake.recordInvocation("DenyPublishRequest", []interface{}{arg1, arg2, arg3, arg4})
+	fake.denyPublishRequestMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) DenyPublishRequestCallCount() int {
+	fake.denyPublishRequestMutex.RLock()
+	defer fake.denyPublishRequestMutex.RUnlock()
+	return len(fake.denyPublishRequestArgsForCall)
+}
+
+func (fake *FakeModerationClient) DenyPublishRequestCalls(stub func(context.Context, rpc.RoomTopic, *livekit.RoomParticipantIdentity, ...psrpc.RequestOption) (*livekit.ParticipantInfo, error)) {
+	fake.denyPublishRequestMutex.Lock()
+	defer fake.denyPublishRequestMutex.Unlock()
+	fake.DenyPublishRequestStub = stub
+}
+
+func (fake *FakeModerationClient) DenyPublishRequestArgsForCall(i int) (context.Context, rpc.RoomTopic, *livekit.RoomParticipantIdentity, []psrpc.RequestOption) {
+	fake.denyPublishRequestMutex.RLock()
+	defer fake.denyPublishRequestMutex.RUnlock()
+	argsForCall := fake.denyPublishRequestArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) DenyPublishRequestReturns(result1 *livekit.ParticipantInfo, result2 error) {
+	fake.denyPublishRequestMutex.Lock()
+	defer fake.denyPublishRequestMutex.Unlock()
+	fake.DenyPublishRequestStub = nil
+	fake.denyPublishRequestReturns = struct {
+		result1 *livekit.ParticipantInfo
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) DenyPublishRequestReturnsOnCall(i int, result1 *livekit.ParticipantInfo, result2 error) {
+	fake.denyPublishRequestMutex.Lock()
+	defer fake.denyPublishRequestMutex.Unlock()
+	fake.DenyPublishRequestStub = nil
+	if fake.denyPublishRequestReturnsOnCall == nil {
+		fake.denyPublishRequestReturnsOnCall = make(map[int]struct {
+			result1 *livekit.ParticipantInfo
+			result2 error
+		})
+	}
+	fake.denyPublishRequestReturnsOnCall[i] = struct {
+		result1 *livekit.ParticipantInfo
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) GetDataHistory(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *structpb.Struct, arg4 ...psrpc.RequestOption) (*structpb.Struct, error) {
+	fake.getDataHistoryMutex.Lock()
+	ret, specificReturn := fake.getDataHistoryReturnsOnCall[len(fake.getDataHistoryArgsForCall)]
+	fake.getDataHistoryArgsForCall = append(fake.getDataHistoryArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.GetDataHistoryStub
+	fakeReturns := fake.getDataHistoryReturns
+	fake.recordInvocation("GetDataHistory", []interface{}{arg1, arg2, arg3, arg4})
+	fake.getDataHistoryMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) GetDataHistoryCallCount() int {
+	fake.getDataHistoryMutex.RLock()
+	defer fake.getDataHistoryMutex.RUnlock()
+	return len(fake.getDataHistoryArgsForCall)
+}
+
+func (fake *FakeModerationClient) GetDataHistoryCalls(stub func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)) {
+	fake.getDataHistoryMutex.Lock()
+	defer fake.getDataHistoryMutex.Unlock()
+	fake.GetDataHistoryStub = stub
+}
+
+func (fake *FakeModerationClient) GetDataHistoryArgsForCall(i int) (context.Context, rpc.RoomTopic, *structpb.Struct, []psrpc.RequestOption) {
+	fake.getDataHistoryMutex.RLock()
+	defer fake.getDataHistoryMutex.RUnlock()
+	argsForCall := fake.getDataHistoryArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) GetDataHistoryReturns(result1 *structpb.Struct, result2 error) {
+	fake.getDataHistoryMutex.Lock()
+	defer fake.getDataHistoryMutex.Unlock()
+	fake.GetDataHistoryStub = nil
+	fake.getDataHistoryReturns = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) GetDataHistoryReturnsOnCall(i int, result1 *structpb.Struct, result2 error) {
+	fake.getDataHistoryMutex.Lock()
+	defer fake.getDataHistoryMutex.Unlock()
+	fake.GetDataHistoryStub = nil
+	if fake.getDataHistoryReturnsOnCall == nil {
+		fake.getDataHistoryReturnsOnCall = make(map[int]struct {
+			result1 *structpb.Struct
+			result2 error
+		})
+	}
+	fake.getDataHistoryReturnsOnCall[i] = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) GetDataPolicy(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *structpb.Struct, arg4 ...psrpc.RequestOption) (*structpb.Struct, error) {
+	fake.getDataPolicyMutex.Lock()
+	ret, specificReturn := fake.getDataPolicyReturnsOnCall[len(fake.getDataPolicyArgsForCall)]
+	fake.getDataPolicyArgsForCall = append(fake.getDataPolicyArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.GetDataPolicyStub
+	fakeReturns := fake.getDataPolicyReturns
+	fake.recordInvocation("GetDataPolicy", []interface{}{arg1, arg2, arg3, arg4})
+	fake.getDataPolicyMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) GetDataPolicyCallCount() int {
+	fake.getDataPolicyMutex.RLock()
+	defer fake.getDataPolicyMutex.RUnlock()
+	return len(fake.getDataPolicyArgsForCall)
+}
+
+func (fake *FakeModerationClient) GetDataPolicyCalls(stub func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)) {
+	fake.getDataPolicyMutex.Lock()
+	defer fake.getDataPolicyMutex.Unlock()
+	fake.GetDataPolicyStub = stub
+}
+
+func (fake *FakeModerationClient) GetDataPolicyArgsForCall(i int) (context.Context, rpc.RoomTopic, *structpb.Struct, []psrpc.RequestOption) {
+	fake.getDataPolicyMutex.RLock()
+	defer fake.getDataPolicyMutex.RUnlock()
+	argsForCall := fake.getDataPolicyArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) GetDataPolicyReturns(result1 *structpb.Struct, result2 error) {
+	fake.getDataPolicyMutex.Lock()
+	defer fake.getDataPolicyMutex.Unlock()
+	fake.GetDataPolicyStub = nil
+	fake.getDataPolicyReturns = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) GetDataPolicyReturnsOnCall(i int, result1 *structpb.Struct, result2 error) {
+	fake.getDataPolicyMutex.Lock()
+	defer fake.getDataPolicyMutex.Unlock()
+	fake.GetDataPolicyStub = nil
+	if fake.getDataPolicyReturnsOnCall == nil {
+		fake.getDataPolicyReturnsOnCall = make(map[int]struct {
+			result1 *structpb.Struct
+			result2 error
+		})
+	}
+	fake.getDataPolicyReturnsOnCall[i] = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) GetDataRetention(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *structpb.Struct, arg4 ...psrpc.RequestOption) (*structpb.Struct, error) {
+	fake.getDataRetentionMutex.Lock()
+	ret, specificReturn := fake.getDataRetentionReturnsOnCall[len(fake.getDataRetentionArgsForCall)]
+	fake.getDataRetentionArgsForCall = append(fake.getDataRetentionArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.GetDataRetentionStub
+	fakeReturns := fake.getDataRetentionReturns
+	fake.recordInvocation("GetDataRetention", []interface{}{arg1, arg2, arg3, arg4})
+	fake.getDataRetentionMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) GetDataRetentionCallCount() int {
+	fake.getDataRetentionMutex.RLock()
+	defer fake.getDataRetentionMutex.RUnlock()
+	return len(fake.getDataRetentionArgsForCall)
+}
+
+func (fake *FakeModerationClient) GetDataRetentionCalls(stub func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)) {
+	fake.getDataRetentionMutex.Lock()
+	defer fake.getDataRetentionMutex.Unlock()
+	fake.GetDataRetentionStub = stub
+}
+
+func (fake *FakeModerationClient) GetDataRetentionArgsForCall(i int) (context.Context, rpc.RoomTopic, *structpb.Struct, []psrpc.RequestOption) {
+	fake.getDataRetentionMutex.RLock()
+	defer fake.getDataRetentionMutex.RUnlock()
+	argsForCall := fake.getDataRetentionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) GetDataRetentionReturns(result1 *structpb.Struct, result2 error) {
+	fake.getDataRetentionMutex.Lock()
+	defer fake.getDataRetentionMutex.Unlock()
+	fake.GetDataRetentionStub = nil
+	fake.getDataRetentionReturns = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) GetDataRetentionReturnsOnCall(i int, result1 *structpb.Struct, result2 error) {
+	fake.getDataRetentionMutex.Lock()
+	defer fake.getDataRetentionMutex.Unlock()
+	fake.GetDataRetentionStub = nil
+	if fake.getDataRetentionReturnsOnCall == nil {
+		fake.getDataRetentionReturnsOnCall = make(map[int]struct {
+			result1 *structpb.Struct
+			result2 error
+		})
+	}
+	fake.getDataRetentionReturnsOnCall[i] = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) GetLobby(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *structpb.Struct, arg4 ...psrpc.RequestOption) (*structpb.Struct, error) {
+	fake.getLobbyMutex.Lock()
+	ret, specificReturn := fake.getLobbyReturnsOnCall[len(fake.getLobbyArgsForCall)]
+	fake.getLobbyArgsForCall = append(fake.getLobbyArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.GetLobbyStub
+	fakeReturns := fake.getLobbyReturns
+	fake.recordInvocation("GetLobby", []interface{}{arg1, arg2, arg3, arg4})
+	fake.getLobbyMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) GetLobbyCallCount() int {
+	fake.getLobbyMutex.RLock()
+	defer fake.getLobbyMutex.RUnlock()
+	return len(fake.getLobbyArgsForCall)
+}
+
+func (fake *FakeModerationClient) GetLobbyCalls(stub func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)) {
+	fake.getLobbyMutex.Lock()
+	defer fake.getLobbyMutex.Unlock()
+	fake.GetLobbyStub = stub
+}
+
+func (fake *FakeModerationClient) GetLobbyArgsForCall(i int) (context.Context, rpc.RoomTopic, *structpb.Struct, []psrpc.RequestOption) {
+	fake.getLobbyMutex.RLock()
+	defer fake.getLobbyMutex.RUnlock()
+	argsForCall := fake.getLobbyArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) GetLobbyReturns(result1 *structpb.Struct, result2 error) {
+	fake.getLobbyMutex.Lock()
+	defer fake.getLobbyMutex.Unlock()
+	fake.GetLobbyStub = nil
+	fake.getLobbyReturns = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) GetLobbyReturnsOnCall(i int, result1 *structpb.Struct, result2 error) {
+	fake.getLobbyMutex.Lock()
+	defer fake.getLobbyMutex.Unlock()
+	fake.GetLobbyStub = nil
+	if fake.getLobbyReturnsOnCall == nil {
+		fake.getLobbyReturnsOnCall = make(map[int]struct {
+			result1 *structpb.Struct
+			result2 error
+		})
+	}
+	fake.getLobbyReturnsOnCall[i] = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) GetMetadataVersions(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *structpb.Struct, arg4 ...psrpc.RequestOption) (*structpb.Struct, error) {
+	fake.getMetadataVersionsMutex.Lock()
+	ret, specificReturn := fake.getMetadataVersionsReturnsOnCall[len(fake.getMetadataVersionsArgsForCall)]
+	fake.getMetadataVersionsArgsForCall = append(fake.getMetadataVersionsArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.GetMetadataVersionsStub
+	fakeReturns := fake.getMetadataVersionsReturns
+	fake.recordInvocation("GetMetadataVersions", []interface{}{arg1, arg2, arg3, arg4})
+	fake.getMetadataVersionsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) GetMetadataVersionsCallCount() int {
+	fake.getMetadataVersionsMutex.RLock()
+	defer fake.getMetadataVersionsMutex.RUnlock()
+	return len(fake.getMetadataVersionsArgsForCall)
+}
+
+func (fake *FakeModerationClient) GetMetadataVersionsCalls(stub func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)) {
+	fake.getMetadataVersionsMutex.Lock()
+	defer fake.getMetadataVersionsMutex.Unlock()
+	fake.GetMetadataVersionsStub = stub
+}
+
+func (fake *FakeModerationClient) GetMetadataVersionsArgsForCall(i int) (context.Context, rpc.RoomTopic, *structpb.Struct, []psrpc.RequestOption) {
+	fake.getMetadataVersionsMutex.RLock()
+	defer fake.getMetadataVersionsMutex.RUnlock()
+	argsForCall := fake.getMetadataVersionsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) GetMetadataVersionsReturns(result1 *structpb.Struct, result2 error) {
+	fake.getMetadataVersionsMutex.Lock()
+	defer fake.getMetadataVersionsMutex.Unlock()
+	fake.GetMetadataVersionsStub = nil
+	fake.getMetadataVersionsReturns = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) GetMetadataVersionsReturnsOnCall(i int, result1 *structpb.Struct, result2 error) {
+	fake.getMetadataVersionsMutex.Lock()
+	defer fake.getMetadataVersionsMutex.Unlock()
+	fake.GetMetadataVersionsStub = nil
+	if fake.getMetadataVersionsReturnsOnCall == nil {
+		fake.getMetadataVersionsReturnsOnCall = make(map[int]struct {
+			result1 *structpb.Struct
+			result2 error
+		})
+	}
+	fake.getMetadataVersionsReturnsOnCall[i] = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) GetRoomState(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *structpb.Struct, arg4 ...psrpc.RequestOption) (*structpb.Struct, error) {
+	fake.getRoomStateMutex.Lock()
+	ret, specificReturn := fake.getRoomStateReturnsOnCall[len(fake.getRoomStateArgsForCall)]
+	fake.getRoomStateArgsForCall = append(fake.getRoomStateArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.GetRoomStateStub
+	fakeReturns := fake.getRoomStateReturns
+	fake.recordInvocation("GetRoomState", []interface{}{arg1, arg2, arg3, arg4})
+	fake.getRoomStateMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) GetRoomStateCallCount() int {
+	fake.getRoomStateMutex.RLock()
+	defer fake.getRoomStateMutex.RUnlock()
+	return len(fake.getRoomStateArgsForCall)
+}
+
+func (fake *FakeModerationClient) GetRoomStateCalls(stub func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)) {
+	fake.getRoomStateMutex.Lock()
+	defer fake.getRoomStateMutex.Unlock()
+	fake.GetRoomStateStub = stub
+}
+
+func (fake *FakeModerationClient) GetRoomStateArgsForCall(i int) (context.Context, rpc.RoomTopic, *structpb.Struct, []psrpc.RequestOption) {
+	fake.getRoomStateMutex.RLock()
+	defer fake.getRoomStateMutex.RUnlock()
+	argsForCall := fake.getRoomStateArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) GetRoomStateReturns(result1 *structpb.Struct, result2 error) {
+	fake.getRoomStateMutex.Lock()
+	defer fake.getRoomStateMutex.Unlock()
+	fake.GetRoomStateStub = nil
+	fake.getRoomStateReturns = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) GetRoomStateReturnsOnCall(i int, result1 *structpb.Struct, result2 error) {
+	fake.getRoomStateMutex.Lock()
+	defer fake.getRoomStateMutex.Unlock()
+	fake.GetRoomStateStub = nil
+	if fake.getRoomStateReturnsOnCall == nil {
+		fake.getRoomStateReturnsOnCall = make(map[int]struct {
+			result1 *structpb.Struct
+			result2 error
+		})
+	}
+	fake.getRoomStateReturnsOnCall[i] = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) GetSubscriptionLimits(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *structpb.Struct, arg4 ...psrpc.RequestOption) (*structpb.Struct, error) {
+	fake.getSubscriptionLimitsMutex.Lock()
+	ret, specificReturn := fake.getSubscriptionLimitsReturnsOnCall[len(fake.getSubscriptionLimitsArgsForCall)]
+	fake.getSubscriptionLimitsArgsForCall = append(fake.getSubscriptionLimitsArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.GetSubscriptionLimitsStub
+	fakeReturns := fake.getSubscriptionLimitsReturns
+	fake.recordInvocation("GetSubscriptionLimits", []interface{}{arg1, arg2, arg3, arg4})
+	fake.getSubscriptionLimitsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) GetSubscriptionLimitsCallCount() int {
+	fake.getSubscriptionLimitsMutex.RLock()
+	defer fake.getSubscriptionLimitsMutex.RUnlock()
+	return len(fake.getSubscriptionLimitsArgsForCall)
+}
+
+func (fake *FakeModerationClient) GetSubscriptionLimitsCalls(stub func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)) {
+	fake.getSubscriptionLimitsMutex.Lock()
+	defer fake.getSubscriptionLimitsMutex.Unlock()
+	fake.GetSubscriptionLimitsStub = stub
+}
+
+func (fake *FakeModerationClient) GetSubscriptionLimitsArgsForCall(i int) (context.Context, rpc.RoomTopic, *structpb.Struct, []psrpc.RequestOption) {
+	fake.getSubscriptionLimitsMutex.RLock()
+	defer fake.getSubscriptionLimitsMutex.RUnlock()
+	argsForCall := fake.getSubscriptionLimitsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) GetSubscriptionLimitsReturns(result1 *structpb.Struct, result2 error) {
+	fake.getSubscriptionLimitsMutex.Lock()
+	defer fake.getSubscriptionLimitsMutex.Unlock()
+	fake.GetSubscriptionLimitsStub = nil
+	fake.getSubscriptionLimitsReturns = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) GetSubscriptionLimitsReturnsOnCall(i int, result1 *structpb.Struct, result2 error) {
+	fake.getSubscriptionLimitsMutex.Lock()
+	defer fake.getSubscriptionLimitsMutex.Unlock()
+	fake.GetSubscriptionLimitsStub = nil
+	if fake.getSubscriptionLimitsReturnsOnCall == nil {
+		fake.getSubscriptionLimitsReturnsOnCall = make(map[int]struct {
+			result1 *structpb.Struct
+			result2 error
+		})
+	}
+	fake.getSubscriptionLimitsReturnsOnCall[i] = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) GetSubscriptionPolicy(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *structpb.Struct, arg4 ...psrpc.RequestOption) (*structpb.Struct, error) {
+	fake.getSubscriptionPolicyMutex.Lock()
+	ret, specificReturn := fake.getSubscriptionPolicyReturnsOnCall[len(fake.getSubscriptionPolicyArgsForCall)]
+	fake.getSubscriptionPolicyArgsForCall = append(fake.getSubscriptionPolicyArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.GetSubscriptionPolicyStub
+	fakeReturns := fake.getSubscriptionPolicyReturns
+	fake.recordInvocation("GetSubscriptionPolicy", []interface{}{arg1, arg2, arg3, arg4})
+	fake.getSubscriptionPolicyMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) GetSubscriptionPolicyCallCount() int {
+	fake.getSubscriptionPolicyMutex.RLock()
+	defer fake.getSubscriptionPolicyMutex.RUnlock()
+	return len(fake.getSubscriptionPolicyArgsForCall)
+}
+
+func (fake *FakeModerationClient) GetSubscriptionPolicyCalls(stub func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)) {
+	fake.getSubscriptionPolicyMutex.Lock()
+	defer fake.getSubscriptionPolicyMutex.Unlock()
+	fake.GetSubscriptionPolicyStub = stub
+}
+
+func (fake *FakeModerationClient) GetSubscriptionPolicyArgsForCall(i int) (context.Context, rpc.RoomTopic, *structpb.Struct, []psrpc.RequestOption) {
+	fake.getSubscriptionPolicyMutex.RLock()
+	defer fake.getSubscriptionPolicyMutex.RUnlock()
+	argsForCall := fake.getSubscriptionPolicyArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) GetSubscriptionPolicyReturns(result1 *structpb.Struct, result2 error) {
+	fake.getSubscriptionPolicyMutex.Lock()
+	defer fake.getSubscriptionPolicyMutex.Unlock()
+	fake.GetSubscriptionPolicyStub = nil
+	fake.getSubscriptionPolicyReturns = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) GetSubscriptionPolicyReturnsOnCall(i int, result1 *structpb.Struct, result2 error) {
+	fake.getSubscriptionPolicyMutex.Lock()
+	defer fake.getSubscriptionPolicyMutex.Unlock()
+	fake.GetSubscriptionPolicyStub = nil
+	if fake.getSubscriptionPolicyReturnsOnCall == nil {
+		fake.getSubscriptionPolicyReturnsOnCall = make(map[int]struct {
+			result1 *structpb.Struct
+			result2 error
+		})
+	}
+	fake.getSubscriptionPolicyReturnsOnCall[i] = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) HandOffAgentJob(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *structpb.Struct, arg4 ...psrpc.RequestOption) (*structpb.Struct, error) {
+	fake.handOffAgentJobMutex.Lock()
+	ret, specificReturn := fake.handOffAgentJobReturnsOnCall[len(fake.handOffAgentJobArgsForCall)]
+	fake.handOffAgentJobArgsForCall = append(fake.handOffAgentJobArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.HandOffAgentJobStub
+	fakeReturns := fake.handOffAgentJobReturns
+	fake.recordInvocation("HandOffAgentJob", []interface{}{arg1, arg2, arg3, arg4})
+	fake.handOffAgentJobMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) HandOffAgentJobCallCount() int {
+	fake.handOffAgentJobMutex.RLock()
+	defer fake.handOffAgentJobMutex.RUnlock()
+	return len(fake.handOffAgentJobArgsForCall)
+}
+
+func (fake *FakeModerationClient) HandOffAgentJobCalls(stub func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)) {
+	fake.handOffAgentJobMutex.Lock()
+	defer fake.handOffAgentJobMutex.Unlock()
+	fake.HandOffAgentJobStub = stub
+}
+
+func (fake *FakeModerationClient) HandOffAgentJobArgsForCall(i int) (context.Context, rpc.RoomTopic, *structpb.Struct, []psrpc.RequestOption) {
+	fake.handOffAgentJobMutex.RLock()
+	defer fake.handOffAgentJobMutex.RUnlock()
+	argsForCall := fake.handOffAgentJobArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) HandOffAgentJobReturns(result1 *structpb.Struct, result2 error) {
+	fake.handOffAgentJobMutex.Lock()
+	defer fake.handOffAgentJobMutex.Unlock()
+	fake.HandOffAgentJobStub = nil
+	fake.handOffAgentJobReturns = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) HandOffAgentJobReturnsOnCall(i int, result1 *structpb.Struct, result2 error) {
+	fake.handOffAgentJobMutex.Lock()
+	defer fake.handOffAgentJobMutex.Unlock()
+	fake.HandOffAgentJobStub = nil
+	if fake.handOffAgentJobReturnsOnCall == nil {
+		fake.handOffAgentJobReturnsOnCall = make(map[int]struct {
+			result1 *structpb.Struct
+			result2 error
+		})
+	}
+	fake.handOffAgentJobReturnsOnCall[i] = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) ListPendingParticipants(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *livekit.ListParticipantsRequest, arg4 ...psrpc.RequestOption) (*livekit.ListParticipantsResponse, error) {
+	fake.listPendingParticipantsMutex.Lock()
+	ret, specificReturn := fake.listPendingParticipantsReturnsOnCall[len(fake.listPendingParticipantsArgsForCall)]
+	fake.listPendingParticipantsArgsForCall = append(fake.listPendingParticipantsArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *livekit.ListParticipantsRequest
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.ListPendingParticipantsStub
+	fakeReturns := fake.listPendingParticipantsReturns
+	fake.recordInvocation("ListPendingParticipants", []interface{}{arg1, arg2, arg3, arg4})
+	fake.listPendingParticipantsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) ListPendingParticipantsCallCount() int {
+	fake.listPendingParticipantsMutex.RLock()
+	defer fake.listPendingParticipantsMutex.RUnlock()
+	return len(fake.listPendingParticipantsArgsForCall)
+}
+
+func (fake *FakeModerationClient) ListPendingParticipantsCalls(stub func(context.Context, rpc.RoomTopic, *livekit.ListParticipantsRequest, ...psrpc.RequestOption) (*livekit.ListParticipantsResponse, error)) {
+	fake.listPendingParticipantsMutex.Lock()
+	defer fake.listPendingParticipantsMutex.Unlock()
+	fake.ListPendingParticipantsStub = stub
+}
+
+func (fake *FakeModerationClient) ListPendingParticipantsArgsForCall(i int) (context.Context, rpc.RoomTopic, *livekit.ListParticipantsRequest, []psrpc.RequestOption) {
+	fake.listPendingParticipantsMutex.RLock()
+	defer fake.listPendingParticipantsMutex.RUnlock()
+	argsForCall := fake.listPendingParticipantsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) ListPendingParticipantsReturns(result1 *livekit.ListParticipantsResponse, result2 error) {
+	fake.listPendingParticipantsMutex.Lock()
+	defer fake.listPendingParticipantsMutex.Unlock()
+	fake.ListPendingParticipantsStub = nil
+	fake.listPendingParticipantsReturns = struct {
+		result1 *livekit.ListParticipantsResponse
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) ListPendingParticipantsReturnsOnCall(i int, result1 *livekit.ListParticipantsResponse, result2 error) {
+	fake.listPendingParticipantsMutex.Lock()
+	defer fake.listPendingParticipantsMutex.Unlock()
+	fake.ListPendingParticipantsStub = nil
+	if fake.listPendingParticipantsReturnsOnCall == nil {
+		fake.listPendingParticipantsReturnsOnCall = make(map[int]struct {
+			result1 *livekit.ListParticipantsResponse
+			result2 error
+		})
+	}
+	fake.listPendingParticipantsReturnsOnCall[i] = struct {
+		result1 *livekit.ListParticipantsResponse
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) RejectParticipant(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *livekit.RoomParticipantIdentity, arg4 ...psrpc.RequestOption) (*livekit.RemoveParticipantResponse, error) {
+	fake.rejectParticipantMutex.Lock()
+	ret, specificReturn := fake.rejectParticipantReturnsOnCall[len(fake.rejectParticipantArgsForCall)]
+	fake.rejectParticipantArgsForCall = append(fake.rejectParticipantArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *livekit.RoomParticipantIdentity
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.RejectParticipantStub
+	fakeReturns := fake.rejectParticipantReturns
+	fake.recordInvocation("RejectParticipant", []interface{}{arg1, arg2, arg3, arg4})
+	fake.rejectParticipantMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) RejectParticipantCallCount() int {
+	fake.rejectParticipantMutex.RLock()
+	defer fake.rejectParticipantMutex.RUnlock()
+	return len(fake.rejectParticipantArgsForCall)
+}
+
+func (fake *FakeModerationClient) RejectParticipantCalls(stub func(context.Context, rpc.RoomTopic, *livekit.RoomParticipantIdentity, ...psrpc.RequestOption) (*livekit.RemoveParticipantResponse, error)) {
+	fake.rejectParticipantMutex.Lock()
+	defer fake.rejectParticipantMutex.Unlock()
+	fake.RejectParticipantStub = stub
+}
+
+func (fake *FakeModerationClient) RejectParticipantArgsForCall(i int) (context.Context, rpc.RoomTopic, *livekit.RoomParticipantIdentity, []psrpc.RequestOption) {
+	fake.rejectParticipantMutex.RLock()
+	defer fake.rejectParticipantMutex.RUnlock()
+	argsForCall := fake.rejectParticipantArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) RejectParticipantReturns(result1 *livekit.RemoveParticipantResponse, result2 error) {
+	fake.rejectParticipantMutex.Lock()
+	defer fake.rejectParticipantMutex.Unlock()
+	fake.RejectParticipantStub = nil
+	fake.rejectParticipantReturns = struct {
+		result1 *livekit.RemoveParticipantResponse
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) RejectParticipantReturnsOnCall(i int, result1 *livekit.RemoveParticipantResponse, result2 error) {
+	fake.rejectParticipantMutex.Lock()
+	defer fake.rejectParticipantMutex.Unlock()
+	fake.RejectParticipantStub = nil
+	if fake.rejectParticipantReturnsOnCall == nil {
+		fake.rejectParticipantReturnsOnCall = make(map[int]struct {
+			result1 *livekit.RemoveParticipantResponse
+			result2 error
+		})
+	}
+	fake.rejectParticipantReturnsOnCall[i] = struct {
+		result1 *livekit.RemoveParticipantResponse
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) UpdateDataPolicy(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *structpb.Struct, arg4 ...psrpc.RequestOption) (*structpb.Struct, error) {
+	fake.updateDataPolicyMutex.Lock()
+	ret, specificReturn := fake.updateDataPolicyReturnsOnCall[len(fake.updateDataPolicyArgsForCall)]
+	fake.updateDataPolicyArgsForCall = append(fake.updateDataPolicyArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.UpdateDataPolicyStub
+	fakeReturns := fake.updateDataPolicyReturns
+	fake.recordInvocation("UpdateDataPolicy", []interface{}{arg1, arg2, arg3, arg4})
+	fake.updateDataPolicyMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) UpdateDataPolicyCallCount() int {
+	fake.updateDataPolicyMutex.RLock()
+	defer fake.updateDataPolicyMutex.RUnlock()
+	return len(fake.updateDataPolicyArgsForCall)
+}
+
+func (fake *FakeModerationClient) UpdateDataPolicyCalls(stub func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)) {
+	fake.updateDataPolicyMutex.Lock()
+	defer fake.updateDataPolicyMutex.Unlock()
+	fake.UpdateDataPolicyStub = stub
+}
+
+func (fake *FakeModerationClient) UpdateDataPolicyArgsForCall(i int) (context.Context, rpc.RoomTopic, *structpb.Struct, []psrpc.RequestOption) {
+	fake.updateDataPolicyMutex.RLock()
+	defer fake.updateDataPolicyMutex.RUnlock()
+	argsForCall := fake.updateDataPolicyArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) UpdateDataPolicyReturns(result1 *structpb.Struct, result2 error) {
+	fake.updateDataPolicyMutex.Lock()
+	defer fake.updateDataPolicyMutex.Unlock()
+	fake.UpdateDataPolicyStub = nil
+	fake.updateDataPolicyReturns = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) UpdateDataPolicyReturnsOnCall(i int, result1 *structpb.Struct, result2 error) {
+	fake.updateDataPolicyMutex.Lock()
+	defer fake.updateDataPolicyMutex.Unlock()
+	fake.UpdateDataPolicyStub = nil
+	if fake.updateDataPolicyReturnsOnCall == nil {
+		fake.updateDataPolicyReturnsOnCall = make(map[int]struct {
+			result1 *structpb.Struct
+			result2 error
+		})
+	}
+	fake.updateDataPolicyReturnsOnCall[i] = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) UpdateDataRetention(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *structpb.Struct, arg4 ...psrpc.RequestOption) (*structpb.Struct, error) {
+	fake.updateDataRetentionMutex.Lock()
+	ret, specificReturn := fake.updateDataRetentionReturnsOnCall[len(fake.updateDataRetentionArgsForCall)]
+	fake.updateDataRetentionArgsForCall = append(fake.updateDataRetentionArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.UpdateDataRetentionStub
+	fakeReturns := fake.updateDataRetentionReturns
+	fake.recordInvocation("UpdateDataRetention", []interface{}{arg1, arg2, arg3, arg4})
+	fake.updateDataRetentionMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) UpdateDataRetentionCallCount() int {
+	fake.updateDataRetentionMutex.RLock()
+	defer fake.updateDataRetentionMutex.RUnlock()
+	return len(fake.updateDataRetentionArgsForCall)
+}
+
+func (fake *FakeModerationClient) UpdateDataRetentionCalls(stub func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)) {
+	fake.updateDataRetentionMutex.Lock()
+	defer fake.updateDataRetentionMutex.Unlock()
+	fake.UpdateDataRetentionStub = stub
+}
+
+func (fake *FakeModerationClient) UpdateDataRetentionArgsForCall(i int) (context.Context, rpc.RoomTopic, *structpb.Struct, []psrpc.RequestOption) {
+	fake.updateDataRetentionMutex.RLock()
+	defer fake.updateDataRetentionMutex.RUnlock()
+	argsForCall := fake.updateDataRetentionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) UpdateDataRetentionReturns(result1 *structpb.Struct, result2 error) {
+	fake.updateDataRetentionMutex.Lock()
+	defer fake.updateDataRetentionMutex.Unlock()
+	fake.UpdateDataRetentionStub = nil
+	fake.updateDataRetentionReturns = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) UpdateDataRetentionReturnsOnCall(i int, result1 *structpb.Struct, result2 error) {
+	fake.updateDataRetentionMutex.Lock()
+	defer fake.updateDataRetentionMutex.Unlock()
+	fake.UpdateDataRetentionStub = nil
+	if fake.updateDataRetentionReturnsOnCall == nil {
+		fake.updateDataRetentionReturnsOnCall = make(map[int]struct {
+			result1 *structpb.Struct
+			result2 error
+		})
+	}
+	fake.updateDataRetentionReturnsOnCall[i] = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) UpdateLobby(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *structpb.Struct, arg4 ...psrpc.RequestOption) (*structpb.Struct, error) {
+	fake.updateLobbyMutex.Lock()
+	ret, specificReturn := fake.updateLobbyReturnsOnCall[len(fake.updateLobbyArgsForCall)]
+	fake.updateLobbyArgsForCall = append(fake.updateLobbyArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.UpdateLobbyStub
+	fakeReturns := fake.updateLobbyReturns
+	fake.recordInvocation("UpdateLobby", []interface{}{arg1, arg2, arg3, arg4})
+	fake.updateLobbyMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) UpdateLobbyCallCount() int {
+	fake.updateLobbyMutex.RLock()
+	defer fake.updateLobbyMutex.RUnlock()
+	return len(fake.updateLobbyArgsForCall)
+}
+
+func (fake *FakeModerationClient) UpdateLobbyCalls(stub func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)) {
+	fake.updateLobbyMutex.Lock()
+	defer fake.updateLobbyMutex.Unlock()
+	fake.UpdateLobbyStub = stub
+}
+
+func (fake *FakeModerationClient) UpdateLobbyArgsForCall(i int) (context.Context, rpc.RoomTopic, *structpb.Struct, []psrpc.RequestOption) {
+	fake.updateLobbyMutex.RLock()
+	defer fake.updateLobbyMutex.RUnlock()
+	argsForCall := fake.updateLobbyArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) UpdateLobbyReturns(result1 *structpb.Struct, result2 error) {
+	fake.updateLobbyMutex.Lock()
+	defer fake.updateLobbyMutex.Unlock()
+	fake.UpdateLobbyStub = nil
+	fake.updateLobbyReturns = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) UpdateLobbyReturnsOnCall(i int, result1 *structpb.Struct, result2 error) {
+	fake.updateLobbyMutex.Lock()
+	defer fake.updateLobbyMutex.Unlock()
+	fake.UpdateLobbyStub = nil
+	if fake.updateLobbyReturnsOnCall == nil {
+		fake.updateLobbyReturnsOnCall = make(map[int]struct {
+			result1 *structpb.Struct
+			result2 error
+		})
+	}
+	fake.updateLobbyReturnsOnCall[i] = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) UpdateParticipantSubscriptionPermission(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *structpb.Struct, arg4 ...psrpc.RequestOption) (*livekit.ParticipantInfo, error) {
+	fake.updateParticipantSubscriptionPermissionMutex.Lock()
+	ret, specificReturn := fake.updateParticipantSubscriptionPermissionReturnsOnCall[len(fake.updateParticipantSubscriptionPermissionArgsForCall)]
+	fake.updateParticipantSubscriptionPermissionArgsForCall = append(fake.updateParticipantSubscriptionPermissionArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.UpdateParticipantSubscriptionPermissionStub
+	fakeReturns := fake.updateParticipantSubscriptionPermissionReturns
+	fake.recordInvocation("UpdateParticipantSubscriptionPermission", []interface{}{arg1, arg2, arg3, arg4})
+	fake.updateParticipantSubscriptionPermissionMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) UpdateParticipantSubscriptionPermissionCallCount() int {
+	fake.updateParticipantSubscriptionPermissionMutex.RLock()
+	defer fake.updateParticipantSubscriptionPermissionMutex.RUnlock()
+	return len(fake.updateParticipantSubscriptionPermissionArgsForCall)
+}
+
+func (fake *FakeModerationClient) UpdateParticipantSubscriptionPermissionCalls(stub func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*livekit.ParticipantInfo, error)) {
+	fake.updateParticipantSubscriptionPermissionMutex.Lock()
+	defer fake.updateParticipantSubscriptionPermissionMutex.Unlock()
+	fake.UpdateParticipantSubscriptionPermissionStub = stub
+}
+
+func (fake *FakeModerationClient) UpdateParticipantSubscriptionPermissionArgsForCall(i int) (context.Context, rpc.RoomTopic, *structpb.Struct, []psrpc.RequestOption) {
+	fake.updateParticipantSubscriptionPermissionMutex.RLock()
+	defer fake.updateParticipantSubscriptionPermissionMutex.RUnlock()
+	argsForCall := fake.updateParticipantSubscriptionPermissionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) UpdateParticipantSubscriptionPermissionReturns(result1 *livekit.ParticipantInfo, result2 error) {
+	fake.updateParticipantSubscriptionPermissionMutex.Lock()
+	defer fake.updateParticipantSubscriptionPermissionMutex.Unlock()
+	fake.UpdateParticipantSubscriptionPermissionStub = nil
+	fake.updateParticipantSubscriptionPermissionReturns = struct {
+		result1 *livekit.ParticipantInfo
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) UpdateParticipantSubscriptionPermissionReturnsOnCall(i int, result1 *livekit.ParticipantInfo, result2 error) {
+	fake.updateParticipantSubscriptionPermissionMutex.Lock()
+	defer fake.updateParticipantSubscriptionPermissionMutex.Unlock()
+	fake.UpdateParticipantSubscriptionPermissionStub = nil
+	if fake.updateParticipantSubscriptionPermissionReturnsOnCall == nil {
+		fake.updateParticipantSubscriptionPermissionReturnsOnCall = make(map[int]struct {
+			result1 *livekit.ParticipantInfo
+			result2 error
+		})
+	}
+	fake.updateParticipantSubscriptionPermissionReturnsOnCall[i] = struct {
+		result1 *livekit.ParticipantInfo
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) UpdateRoomState(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *structpb.Struct, arg4 ...psrpc.RequestOption) (*structpb.Struct, error) {
+	fake.updateRoomStateMutex.Lock()
+	ret, specificReturn := fake.updateRoomStateReturnsOnCall[len(fake.updateRoomStateArgsForCall)]
+	fake.updateRoomStateArgsForCall = append(fake.updateRoomStateArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.UpdateRoomStateStub
+	fakeReturns := fake.updateRoomStateReturns
+	fake.recordInvocation("UpdateRoomState", []interface{}{arg1, arg2, arg3, arg4})
+	fake.updateRoomStateMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) UpdateRoomStateCallCount() int {
+	fake.updateRoomStateMutex.RLock()
+	defer fake.updateRoomStateMutex.RUnlock()
+	return len(fake.updateRoomStateArgsForCall)
+}
+
+func (fake *FakeModerationClient) UpdateRoomStateCalls(stub func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)) {
+	fake.updateRoomStateMutex.Lock()
+	defer fake.updateRoomStateMutex.Unlock()
+	fake.UpdateRoomStateStub = stub
+}
+
+func (fake *FakeModerationClient) UpdateRoomStateArgsForCall(i int) (context.Context, rpc.RoomTopic, *structpb.Struct, []psrpc.RequestOption) {
+	fake.updateRoomStateMutex.RLock()
+	defer fake.updateRoomStateMutex.RUnlock()
+	argsForCall := fake.updateRoomStateArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) UpdateRoomStateReturns(result1 *structpb.Struct, result2 error) {
+	fake.updateRoomStateMutex.Lock()
+	defer fake.updateRoomStateMutex.Unlock()
+	fake.UpdateRoomStateStub = nil
+	fake.updateRoomStateReturns = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) UpdateRoomStateReturnsOnCall(i int, result1 *structpb.Struct, result2 error) {
+	fake.updateRoomStateMutex.Lock()
+	defer fake.updateRoomStateMutex.Unlock()
+	fake.UpdateRoomStateStub = nil
+	if fake.updateRoomStateReturnsOnCall == nil {
+		fake.updateRoomStateReturnsOnCall = make(map[int]struct {
+			result1 *structpb.Struct
+			result2 error
+		})
+	}
+	fake.updateRoomStateReturnsOnCall[i] = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) UpdateSubscriptionLimits(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *structpb.Struct, arg4 ...psrpc.RequestOption) (*structpb.Struct, error) {
+	fake.updateSubscriptionLimitsMutex.Lock()
+	ret, specificReturn := fake.updateSubscriptionLimitsReturnsOnCall[len(fake.updateSubscriptionLimitsArgsForCall)]
+	fake.updateSubscriptionLimitsArgsForCall = append(fake.updateSubscriptionLimitsArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.UpdateSubscriptionLimitsStub
+	fakeReturns := fake.updateSubscriptionLimitsReturns
+	fake.recordInvocation("UpdateSubscriptionLimits", []interface{}{arg1, arg2, arg3, arg4})
+	fake.updateSubscriptionLimitsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) UpdateSubscriptionLimitsCallCount() int {
+	fake.updateSubscriptionLimitsMutex.RLock()
+	defer fake.updateSubscriptionLimitsMutex.RUnlock()
+	return len(fake.updateSubscriptionLimitsArgsForCall)
+}
+
+func (fake *FakeModerationClient) UpdateSubscriptionLimitsCalls(stub func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)) {
+	fake.updateSubscriptionLimitsMutex.Lock()
+	defer fake.updateSubscriptionLimitsMutex.Unlock()
+	fake.UpdateSubscriptionLimitsStub = stub
+}
+
+func (fake *FakeModerationClient) UpdateSubscriptionLimitsArgsForCall(i int) (context.Context, rpc.RoomTopic, *structpb.Struct, []psrpc.RequestOption) {
+	fake.updateSubscriptionLimitsMutex.RLock()
+	defer fake.updateSubscriptionLimitsMutex.RUnlock()
+	argsForCall := fake.updateSubscriptionLimitsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) UpdateSubscriptionLimitsReturns(result1 *structpb.Struct, result2 error) {
+	fake.updateSubscriptionLimitsMutex.Lock()
+	defer fake.updateSubscriptionLimitsMutex.Unlock()
+	fake.UpdateSubscriptionLimitsStub = nil
+	fake.updateSubscriptionLimitsReturns = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) UpdateSubscriptionLimitsReturnsOnCall(i int, result1 *structpb.Struct, result2 error) {
+	fake.updateSubscriptionLimitsMutex.Lock()
+	defer fake.updateSubscriptionLimitsMutex.Unlock()
+	fake.UpdateSubscriptionLimitsStub = nil
+	if fake.updateSubscriptionLimitsReturnsOnCall == nil {
+		fake.updateSubscriptionLimitsReturnsOnCall = make(map[int]struct {
+			result1 *structpb.Struct
+			result2 error
+		})
+	}
+	fake.updateSubscriptionLimitsReturnsOnCall[i] = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) UpdateSubscriptionPolicy(arg1 context.Context, arg2 rpc.RoomTopic, arg3 *structpb.Struct, arg4 ...psrpc.RequestOption) (*structpb.Struct, error) {
+	fake.updateSubscriptionPolicyMutex.Lock()
+	ret, specificReturn := fake.updateSubscriptionPolicyReturnsOnCall[len(fake.updateSubscriptionPolicyArgsForCall)]
+	fake.updateSubscriptionPolicyArgsForCall = append(fake.updateSubscriptionPolicyArgsForCall, struct {
+		arg1 context.Context
+		arg2 rpc.RoomTopic
+		arg3 *structpb.Struct
+		arg4 []psrpc.RequestOption
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.UpdateSubscriptionPolicyStub
+	fakeReturns := fake.updateSubscriptionPolicyReturns
+	fake.recordInvocation("UpdateSubscriptionPolicy", []interface{}{arg1, arg2, arg3, arg4})
+	fake.updateSubscriptionPolicyMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeModerationClient) UpdateSubscriptionPolicyCallCount() int {
+	fake.updateSubscriptionPolicyMutex.RLock()
+	defer fake.updateSubscriptionPolicyMutex.RUnlock()
+	return len(fake.updateSubscriptionPolicyArgsForCall)
+}
+
+func (fake *FakeModerationClient) UpdateSubscriptionPolicyCalls(stub func(context.Context, rpc.RoomTopic, *structpb.Struct, ...psrpc.RequestOption) (*structpb.Struct, error)) {
+	fake.updateSubscriptionPolicyMutex.Lock()
+	defer fake.updateSubscriptionPolicyMutex.Unlock()
+	fake.UpdateSubscriptionPolicyStub = stub
+}
+
+func (fake *FakeModerationClient) UpdateSubscriptionPolicyArgsForCall(i int) (context.Context, rpc.RoomTopic, *structpb.Struct, []psrpc.RequestOption) {
+	fake.updateSubscriptionPolicyMutex.RLock()
+	defer fake.updateSubscriptionPolicyMutex.RUnlock()
+	argsForCall := fake.updateSubscriptionPolicyArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeModerationClient) UpdateSubscriptionPolicyReturns(result1 *structpb.Struct, result2 error) {
+	fake.updateSubscriptionPolicyMutex.Lock()
+	defer fake.updateSubscriptionPolicyMutex.Unlock()
+	fake.UpdateSubscriptionPolicyStub = nil
+	fake.updateSubscriptionPolicyReturns = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) UpdateSubscriptionPolicyReturnsOnCall(i int, result1 *structpb.Struct, result2 error) {
+	fake.updateSubscriptionPolicyMutex.Lock()
+	defer fake.updateSubscriptionPolicyMutex.Unlock()
+	fake.UpdateSubscriptionPolicyStub = nil
+	if fake.updateSubscriptionPolicyReturnsOnCall == nil {
+		fake.updateSubscriptionPolicyReturnsOnCall = make(map[int]struct {
+			result1 *structpb.Struct
+			result2 error
+		})
+	}
+	fake.updateSubscriptionPolicyReturnsOnCall[i] = struct {
+		result1 *structpb.Struct
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeModerationClient) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeModerationClient) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ service.ModerationClient = new(FakeModerationClient)
@@ -6,11 +6,24 @@ import (
 	"sync"
 	"time"
 
+	"github.com/livekit/livekit-server/pkg/rtc"
 	"github.com/livekit/livekit-server/pkg/service"
 	"github.com/livekit/protocol/livekit"
 )
 
 type FakeObjectStore struct {
+	DeleteDataHistoryStub        func(context.Context, livekit.RoomName) error
+	deleteDataHistoryMutex       sync.RWMutex
+	deleteDataHistoryArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}
+	deleteDataHistoryReturns struct {
+		result1 error
+	}
+	deleteDataHistoryReturnsOnCall map[int]struct {
+		result1 error
+	}
 	DeleteParticipantStub        func(context.Context, livekit.RoomName, livekit.ParticipantIdentity) error
 	deleteParticipantMutex       sync.RWMutex
 	deleteParticipantArgsForCall []struct {
@@ -51,6 +64,21 @@ type FakeObjectStore struct {
 		result1 bool
 		result2 error
 	}
+	ListDataMessagesStub        func(context.Context, livekit.RoomName, string) ([]*rtc.DataHistoryMessage, error)
+	listDataMessagesMutex       sync.RWMutex
+	listDataMessagesArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 string
+	}
+	listDataMessagesReturns struct {
+		result1 []*rtc.DataHistoryMessage
+		result2 error
+	}
+	listDataMessagesReturnsOnCall map[int]struct {
+		result1 []*rtc.DataHistoryMessage
+		result2 error
+	}
 	ListParticipantsStub        func(context.Context, livekit.RoomName) ([]*livekit.ParticipantInfo, error)
 	listParticipantsMutex       sync.RWMutex
 	listParticipantsArgsForCall []struct {
@@ -140,6 +168,21 @@ type FakeObjectStore struct {
 		result1 bool
 		result2 error
 	}
+	StoreDataMessageStub        func(context.Context, livekit.RoomName, *rtc.DataHistoryMessage, int, time.Duration) error
+	storeDataMessageMutex       sync.RWMutex
+	storeDataMessageArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 *rtc.DataHistoryMessage
+		arg4 int
+		arg5 time.Duration
+	}
+	storeDataMessageReturns struct {
+		result1 error
+	}
+	storeDataMessageReturnsOnCall map[int]struct {
+		result1 error
+	}
 	StoreParticipantStub        func(context.Context, livekit.RoomName, *livekit.ParticipantInfo) error
 	storeParticipantMutex       sync.RWMutex
 	storeParticipantArgsForCall []struct {
@@ -183,6 +226,68 @@ type FakeObjectStore struct {
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeObjectStore) DeleteDataHistory(arg1 context.Context, arg2 livekit.RoomName) error {
+	fake.deleteDataHistoryMutex.Lock()
+	ret, specificReturn := fake.deleteDataHistoryReturnsOnCall[len(fake.deleteDataHistoryArgsForCall)]
+	fake.deleteDataHistoryArgsForCall = append(fake.deleteDataHistoryArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}{arg1, arg2})
+	stub := fake.DeleteDataHistoryStub
+	fakeReturns := fake.deleteDataHistoryReturns
+	fake.recordInvocation("DeleteDataHistory", []interface{}{arg1, arg2})
+	fake.deleteDataHistoryMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeObjectStore) DeleteDataHistoryCallCount() int {
+	fake.deleteDataHistoryMutex.RLock()
+	defer fake.deleteDataHistoryMutex.RUnlock()
+	return len(fake.deleteDataHistoryArgsForCall)
+}
+
+func (fake *FakeObjectStore) DeleteDataHistoryCalls(stub func(context.Context, livekit.RoomName) error) {
+	fake.deleteDataHistoryMutex.Lock()
+	defer fake.deleteDataHistoryMutex.Unlock()
+	fake.DeleteDataHistoryStub = stub
+}
+
+func (fake *FakeObjectStore) DeleteDataHistoryArgsForCall(i int) (context.Context, livekit.RoomName) {
+	fake.deleteDataHistoryMutex.RLock()
+	defer fake.deleteDataHistoryMutex.RUnlock()
+	argsForCall := fake.deleteDataHistoryArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeObjectStore) DeleteDataHistoryReturns(result1 error) {
+	fake.deleteDataHistoryMutex.Lock()
+	defer fake.deleteDataHistoryMutex.Unlock()
+	fake.DeleteDataHistoryStub = nil
+	fake.deleteDataHistoryReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) DeleteDataHistoryReturnsOnCall(i int, result1 error) {
+	fake.deleteDataHistoryMutex.Lock()
+	defer fake.deleteDataHistoryMutex.Unlock()
+	fake.DeleteDataHistoryStub = nil
+	if fake.deleteDataHistoryReturnsOnCall == nil {
+		fake.deleteDataHistoryReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteDataHistoryReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeObjectStore) DeleteParticipant(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.ParticipantIdentity) error {
 	fake.deleteParticipantMutex.Lock()
 	ret, specificReturn := fake.deleteParticipantReturnsOnCall[len(fake.deleteParticipantArgsForCall)]
@@ -374,6 +479,72 @@ func (fake *FakeObjectStore) HasParticipantReturnsOnCall(i int, result1 bool, re
 	}{result1, result2}
 }
 
+func (fake *FakeObjectStore) ListDataMessages(arg1 context.Context, arg2 livekit.RoomName, arg3 string) ([]*rtc.DataHistoryMessage, error) {
+	fake.listDataMessagesMutex.Lock()
+	ret, specificReturn := fake.listDataMessagesReturnsOnCall[len(fake.listDataMessagesArgsForCall)]
+	fake.listDataMessagesArgsForCall = append(fake.listDataMessagesArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.ListDataMessagesStub
+	fakeReturns := fake.listDataMessagesReturns
+	fake.recordInvocation("ListDataMessages", []interface{}{arg1, arg2, arg3})
+	fake.listDataMessagesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeObjectStore) ListDataMessagesCallCount() int {
+	fake.listDataMessagesMutex.RLock()
+	defer fake.listDataMessagesMutex.RUnlock()
+	return len(fake.listDataMessagesArgsForCall)
+}
+
+func (fake *FakeObjectStore) ListDataMessagesCalls(stub func(context.Context, livekit.RoomName, string) ([]*rtc.DataHistoryMessage, error)) {
+	fake.listDataMessagesMutex.Lock()
+	defer fake.listDataMessagesMutex.Unlock()
+	fake.ListDataMessagesStub = stub
+}
+
+func (fake *FakeObjectStore) ListDataMessagesArgsForCall(i int) (context.Context, livekit.RoomName, string) {
+	fake.listDataMessagesMutex.RLock()
+	defer fake.listDataMessagesMutex.RUnlock()
+	argsForCall := fake.listDataMessagesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeObjectStore) ListDataMessagesReturns(result1 []*rtc.DataHistoryMessage, result2 error) {
+	fake.listDataMessagesMutex.Lock()
+	defer fake.listDataMessagesMutex.Unlock()
+	fake.ListDataMessagesStub = nil
+	fake.listDataMessagesReturns = struct {
+		result1 []*rtc.DataHistoryMessage
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeObjectStore) ListDataMessagesReturnsOnCall(i int, result1 []*rtc.DataHistoryMessage, result2 error) {
+	fake.listDataMessagesMutex.Lock()
+	defer fake.listDataMessagesMutex.Unlock()
+	fake.ListDataMessagesStub = nil
+	if fake.listDataMessagesReturnsOnCall == nil {
+		fake.listDataMessagesReturnsOnCall = make(map[int]struct {
+			result1 []*rtc.DataHistoryMessage
+			result2 error
+		})
+	}
+	fake.listDataMessagesReturnsOnCall[i] = struct {
+		result1 []*rtc.DataHistoryMessage
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeObjectStore) ListParticipants(arg1 context.Context, arg2 livekit.RoomName) ([]*livekit.ParticipantInfo, error) {
 	fake.listParticipantsMutex.Lock()
 	ret, specificReturn := fake.listParticipantsReturnsOnCall[len(fake.listParticipantsArgsForCall)]
@@ -775,6 +946,71 @@ func (fake *FakeObjectStore) RoomExistsReturnsOnCall(i int, result1 bool, result
 	}{result1, result2}
 }
 
+func (fake *FakeObjectStore) StoreDataMessage(arg1 context.Context, arg2 livekit.RoomName, arg3 *rtc.DataHistoryMessage, arg4 int, arg5 time.Duration) error {
+	fake.storeDataMessageMutex.Lock()
+	ret, specificReturn := fake.storeDataMessageReturnsOnCall[len(fake.storeDataMessageArgsForCall)]
+	fake.storeDataMessageArgsForCall = append(fake.storeDataMessageArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 *rtc.DataHistoryMessage
+		arg4 int
+		arg5 time.Duration
+	}{arg1, arg2, arg3, arg4, arg5})
+	stub := fake.StoreDataMessageStub
+	fakeReturns := fake.storeDataMessageReturns
+	fake.recordInvocation("StoreDataMessage", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.storeDataMessageMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4, arg5)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeObjectStore) StoreDataMessageCallCount() int {
+	fake.storeDataMessageMutex.RLock()
+	defer fake.storeDataMessageMutex.RUnlock()
+	return len(fake.storeDataMessageArgsForCall)
+}
+
+func (fake *FakeObjectStore) StoreDataMessageCalls(stub func(context.Context, livekit.RoomName, *rtc.DataHistoryMessage, int, time.Duration) error) {
+	fake.storeDataMessageMutex.Lock()
+	defer fake.storeDataMessageMutex.Unlock()
+	fake.StoreDataMessageStub = stub
+}
+
+func (fake *FakeObjectStore) StoreDataMessageArgsForCall(i int) (context.Context, livekit.RoomName, *rtc.DataHistoryMessage, int, time.Duration) {
+	fake.storeDataMessageMutex.RLock()
+	defer fake.storeDataMessageMutex.RUnlock()
+	argsForCall := fake.storeDataMessageArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeObjectStore) StoreDataMessageReturns(result1 error) {
+	fake.storeDataMessageMutex.Lock()
+	defer fake.storeDataMessageMutex.Unlock()
+	fake.StoreDataMessageStub = nil
+	fake.storeDataMessageReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) StoreDataMessageReturnsOnCall(i int, result1 error) {
+	fake.storeDataMessageMutex.Lock()
+	defer fake.storeDataMessageMutex.Unlock()
+	fake.StoreDataMessageStub = nil
+	if fake.storeDataMessageReturnsOnCall == nil {
+		fake.storeDataMessageReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeDataMessageReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeObjectStore) StoreParticipant(arg1 context.Context, arg2 livekit.RoomName, arg3 *livekit.ParticipantInfo) error {
 	fake.storeParticipantMutex.Lock()
 	ret, specificReturn := fake.storeParticipantReturnsOnCall[len(fake.storeParticipantArgsForCall)]
@@ -0,0 +1,194 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package servicefakes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+type FakeRoomSnapshotStore struct {
+	StoreRoomSnapshotStub        func(context.Context, string, *rtc.RoomSnapshot) error
+	storeRoomSnapshotMutex       sync.RWMutex
+	storeRoomSnapshotArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 *rtc.RoomSnapshot
+	}
+	storeRoomSnapshotReturns struct {
+		result1 error
+	}
+	storeRoomSnapshotReturnsOnCall map[int]struct {
+		result1 error
+	}
+	TakeRoomSnapshotsStub        func(context.Context, string) ([]*rtc.RoomSnapshot, error)
+	takeRoomSnapshotsMutex       sync.RWMutex
+	takeRoomSnapshotsArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	takeRoomSnapshotsReturns struct {
+		result1 []*rtc.RoomSnapshot
+		result2 error
+	}
+	takeRoomSnapshotsReturnsOnCall map[int]struct {
+		result1 []*rtc.RoomSnapshot
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeRoomSnapshotStore) StoreRoomSnapshot(arg1 context.Context, arg2 string, arg3 *rtc.RoomSnapshot) error {
+	fake.storeRoomSnapshotMutex.Lock()
+	ret, specificReturn := fake.storeRoomSnapshotReturnsOnCall[len(fake.storeRoomSnapshotArgsForCall)]
+	fake.storeRoomSnapshotArgsForCall = append(fake.storeRoomSnapshotArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 *rtc.RoomSnapshot
+	}{arg1, arg2, arg3})
+	stub := fake.StoreRoomSnapshotStub
+	fakeReturns := fake.storeRoomSnapshotReturns
+	fake.recordInvocation("StoreRoomSnapshot", []interface{}{arg1, arg2, arg3})
+	fake.storeRoomSnapshotMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoomSnapshotStore) StoreRoomSnapshotCallCount() int {
+	fake.storeRoomSnapshotMutex.RLock()
+	defer fake.storeRoomSnapshotMutex.RUnlock()
+	return len(fake.storeRoomSnapshotArgsForCall)
+}
+
+func (fake *FakeRoomSnapshotStore) StoreRoomSnapshotCalls(stub func(context.Context, string, *rtc.RoomSnapshot) error) {
+	fake.storeRoomSnapshotMutex.Lock()
+	defer fake.storeRoomSnapshotMutex.Unlock()
+	fake.StoreRoomSnapshotStub = stub
+}
+
+func (fake *FakeRoomSnapshotStore) StoreRoomSnapshotArgsForCall(i int) (context.Context, string, *rtc.RoomSnapshot) {
+	fake.storeRoomSnapshotMutex.RLock()
+	defer fake.storeRoomSnapshotMutex.RUnlock()
+	argsForCall := fake.storeRoomSnapshotArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeRoomSnapshotStore) StoreRoomSnapshotReturns(result1 error) {
+	fake.storeRoomSnapshotMutex.Lock()
+	defer fake.storeRoomSnapshotMutex.Unlock()
+	fake.StoreRoomSnapshotStub = nil
+	fake.storeRoomSnapshotReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomSnapshotStore) StoreRoomSnapshotReturnsOnCall(i int, result1 error) {
+	fake.storeRoomSnapshotMutex.Lock()
+	defer fake.storeRoomSnapshotMutex.Unlock()
+	fake.StoreRoomSnapshotStub = nil
+	if fake.storeRoomSnapshotReturnsOnCall == nil {
+		fake.storeRoomSnapshotReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeRoomSnapshotReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomSnapshotStore) TakeRoomSnapshots(arg1 context.Context, arg2 string) ([]*rtc.RoomSnapshot, error) {
+	fake.takeRoomSnapshotsMutex.Lock()
+	ret, specificReturn := fake.takeRoomSnapshotsReturnsOnCall[len(fake.takeRoomSnapshotsArgsForCall)]
+	fake.takeRoomSnapshotsArgsForCall = append(fake.takeRoomSnapshotsArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.TakeRoomSnapshotsStub
+	fakeReturns := fake.takeRoomSnapshotsReturns
+	fake.recordInvocation("TakeRoomSnapshots", []interface{}{arg1, arg2})
+	fake.takeRoomSnapshotsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRoomSnapshotStore) TakeRoomSnapshotsCallCount() int {
+	fake.takeRoomSnapshotsMutex.RLock()
+	defer fake.takeRoomSnapshotsMutex.RUnlock()
+	return len(fake.takeRoomSnapshotsArgsForCall)
+}
+
+func (fake *FakeRoomSnapshotStore) TakeRoomSnapshotsCalls(stub func(context.Context, string) ([]*rtc.RoomSnapshot, error)) {
+	fake.takeRoomSnapshotsMutex.Lock()
+	defer fake.takeRoomSnapshotsMutex.Unlock()
+	fake.TakeRoomSnapshotsStub = stub
+}
+
+func (fake *FakeRoomSnapshotStore) TakeRoomSnapshotsArgsForCall(i int) (context.Context, string) {
+	fake.takeRoomSnapshotsMutex.RLock()
+	defer fake.takeRoomSnapshotsMutex.RUnlock()
+	argsForCall := fake.takeRoomSnapshotsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRoomSnapshotStore) TakeRoomSnapshotsReturns(result1 []*rtc.RoomSnapshot, result2 error) {
+	fake.takeRoomSnapshotsMutex.Lock()
+	defer fake.takeRoomSnapshotsMutex.Unlock()
+	fake.TakeRoomSnapshotsStub = nil
+	fake.takeRoomSnapshotsReturns = struct {
+		result1 []*rtc.RoomSnapshot
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRoomSnapshotStore) TakeRoomSnapshotsReturnsOnCall(i int, result1 []*rtc.RoomSnapshot, result2 error) {
+	fake.takeRoomSnapshotsMutex.Lock()
+	defer fake.takeRoomSnapshotsMutex.Unlock()
+	fake.TakeRoomSnapshotsStub = nil
+	if fake.takeRoomSnapshotsReturnsOnCall == nil {
+		fake.takeRoomSnapshotsReturnsOnCall = make(map[int]struct {
+			result1 []*rtc.RoomSnapshot
+			result2 error
+		})
+	}
+	fake.takeRoomSnapshotsReturnsOnCall[i] = struct {
+		result1 []*rtc.RoomSnapshot
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRoomSnapshotStore) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeRoomSnapshotStore) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ service.RoomSnapshotStore = new(FakeRoomSnapshotStore)
@@ -39,6 +39,7 @@ import (
 
 	"github.com/livekit/livekit-server/pkg/agent"
 	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/relay"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/telemetry"
@@ -76,8 +77,10 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 		getSIPStore,
 		getSIPConfig,
 		NewSIPService,
+		relay.NewRegistry,
 		NewRoomAllocator,
 		NewRoomService,
+		NewAdmissionHook,
 		NewRTCService,
 		NewWHIPService,
 		NewAgentService,
@@ -85,6 +88,8 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 		getAgentConfig,
 		agent.NewAgentClient,
 		getAgentStore,
+		getRoomSnapshotStore,
+		getAgentJobHistoryStore,
 		getSignalRelayConfig,
 		NewDefaultSignalServer,
 		routing.NewSignalClient,
@@ -96,6 +101,7 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 		rpc.NewTopicFormatter,
 		rpc.NewTypedRoomClient,
 		rpc.NewTypedParticipantClient,
+		NewModerationClient,
 		rpc.NewTypedWHIPParticipantClient,
 		rpc.NewTypedAgentDispatchInternalClient,
 		NewLocalRoomManager,
@@ -221,6 +227,32 @@ func getAgentStore(s ObjectStore) AgentStore {
 	}
 }
 
+func getAgentJobHistoryStore(s ObjectStore) AgentJobHistoryStore {
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	case *LocalStore:
+		return store
+	default:
+		return nil
+	}
+}
+
+func getRoomSnapshotStore(conf *config.Config, s ObjectStore) RoomSnapshotStore {
+	if conf.RoomSnapshot.Path != "" {
+		return NewFileRoomSnapshotStore(conf.RoomSnapshot.Path)
+	}
+
+	switch store := s.(type) {
+	case *RedisStore:
+		return store
+	case *LocalStore:
+		return store
+	default:
+		return nil
+	}
+}
+
 func getIngressConfig(conf *config.Config) *config.IngressConfig {
 	return &conf.Ingress
 }
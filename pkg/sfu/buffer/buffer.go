@@ -18,12 +18,14 @@ import (
 	"encoding/binary"
 	"errors"
 	"io"
+	"time"
 
 	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v4"
 
 	sutils "github.com/livekit/livekit-server/pkg/utils"
+	util "github.com/livekit/mediatransportutil"
 	"github.com/livekit/mediatransportutil/pkg/bucket"
 	"github.com/livekit/mediatransportutil/pkg/twcc"
 	"github.com/livekit/protocol/livekit"
@@ -396,6 +398,16 @@ func (b *Buffer) getRTCP() []rtcp.Packet {
 			SSRC:    b.BufferBase.SSRC(),
 			Reports: []rtcp.ReceptionReport{*rr},
 		})
+
+		// RTCP-XR Receiver Reference Time, publishers supporting it respond with DLRR which gives up stream RTT
+		pkts = append(pkts, &rtcp.ExtendedReport{
+			SenderSSRC: b.BufferBase.SSRC(),
+			Reports: []rtcp.ReportBlock{
+				&rtcp.ReceiverReferenceTimeReportBlock{
+					NTPTimestamp: uint64(util.ToNtpTime(time.Now())),
+				},
+			},
+		})
 	}
 
 	return pkts
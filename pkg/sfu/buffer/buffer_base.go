@@ -33,6 +33,7 @@ import (
 	"github.com/livekit/livekit-server/pkg/sfu/audio"
 	act "github.com/livekit/livekit-server/pkg/sfu/rtpextension/abscapturetime"
 	dd "github.com/livekit/livekit-server/pkg/sfu/rtpextension/dependencydescriptor"
+	fm "github.com/livekit/livekit-server/pkg/sfu/rtpextension/framemarking"
 	"github.com/livekit/livekit-server/pkg/sfu/rtpstats"
 	"github.com/livekit/livekit-server/pkg/sfu/utils"
 	"github.com/livekit/mediatransportutil/pkg/bucket"
@@ -75,6 +76,7 @@ type ExtPacket struct {
 	RawPacket            []byte
 	DependencyDescriptor *ExtDependencyDescriptor
 	AbsCaptureTimeExt    *act.AbsCaptureTime
+	FrameMarking         *fm.FrameMarking
 	IsOutOfOrder         bool
 	IsBuffered           bool
 }
@@ -213,6 +215,7 @@ type BufferBase struct {
 	extPacketTooMuchCount atomic.Uint32
 
 	absCaptureTimeExtID uint8
+	frameMarkingExtID   uint8
 
 	keyFrameSeederGeneration atomic.Int32
 
@@ -337,6 +340,9 @@ func (b *BufferBase) BindLocked(rtpParameters webrtc.RTPParameters, codec webrtc
 
 		case act.AbsCaptureTimeURI:
 			b.absCaptureTimeExtID = uint8(ext.ID)
+
+		case fm.FrameMarkingURI:
+			b.frameMarkingExtID = uint8(ext.ID)
 		}
 	}
 
@@ -539,7 +545,7 @@ func (b *BufferBase) restartStreamLocked(reason string, isDetected bool) {
 }
 
 func (b *BufferBase) createDDParserAndFrameRateCalculator() {
-	if mime.IsMimeTypeSVCCapable(b.mime) || b.mime == mime.MimeTypeVP8 {
+	if mime.IsMimeTypeSVCCapable(b.mime) || b.mime == mime.MimeTypeVP8 || b.mime == mime.MimeTypeH264 || b.mime == mime.MimeTypeH265 {
 		frc := NewFrameRateCalculatorDD(b.clockRate, b.logger)
 		for i := range b.frameRateCalculator {
 			b.frameRateCalculator[i] = frc.GetFrameRateCalculatorForSpatial(int32(i))
@@ -568,9 +574,31 @@ func (b *BufferBase) createFrameRateCalculator() {
 
 	case mime.MimeTypeH265:
 		b.frameRateCalculator[0] = NewFrameRateCalculatorH26x(b.clockRate, b.logger)
+
+	case mime.MimeTypeH264:
+		if b.frameMarkingExtID != 0 {
+			b.frameRateCalculator[0] = NewFrameRateCalculatorH26x(b.clockRate, b.logger)
+		}
 	}
 }
 
+func (b *BufferBase) getFrameMarking(pkt *rtp.Packet) *fm.FrameMarking {
+	if b.frameMarkingExtID == 0 {
+		return nil
+	}
+
+	extData := pkt.GetExtension(b.frameMarkingExtID)
+	if extData == nil {
+		return nil
+	}
+
+	var frameMarking fm.FrameMarking
+	if err := frameMarking.Unmarshal(extData); err != nil {
+		return nil
+	}
+	return &frameMarking
+}
+
 func (b *BufferBase) ReadExtended(buf []byte) (*ExtPacket, error) {
 	b.Lock()
 	for {
@@ -1025,7 +1053,7 @@ func (b *BufferBase) processVideoPacket(ep *ExtPacket) error {
 		ddVal, videoLayer, err := b.ddParser.Parse(ep.Packet)
 		if err != nil {
 			if errors.Is(err, ErrDDExtentionNotFound) {
-				if b.mime == mime.MimeTypeVP8 || b.mime == mime.MimeTypeVP9 {
+				if b.mime == mime.MimeTypeVP8 || b.mime == mime.MimeTypeVP9 || b.mime == mime.MimeTypeH264 || b.mime == mime.MimeTypeH265 {
 					b.logger.Infow("dd extension not found, disable dd parser")
 					b.ddParser = nil
 					b.createFrameRateCalculator()
@@ -1093,6 +1121,12 @@ func (b *BufferBase) processVideoPacket(ep *ExtPacket) error {
 
 	case mime.MimeTypeH264:
 		ep.IsKeyFrame = IsH264KeyFrame(ep.Packet.Payload)
+		if ep.DependencyDescriptor == nil {
+			// h.264 payload does not carry temporal layer, use frame marking if available
+			if ep.FrameMarking = b.getFrameMarking(ep.Packet); ep.FrameMarking != nil {
+				ep.Temporal = int32(ep.FrameMarking.TID)
+			}
+		}
 		ep.Spatial = InvalidLayerSpatial // h.264 don't have spatial scalability, reset to invalid
 
 		// Check H264 key frame video size
@@ -1116,6 +1150,7 @@ func (b *BufferBase) processVideoPacket(ep *ExtPacket) error {
 				Temporal: int32(ep.Packet.Payload[1]&0x07) - 1,
 			}
 			ep.Spatial = InvalidLayerSpatial
+			ep.FrameMarking = b.getFrameMarking(ep.Packet)
 
 			if ep.IsKeyFrame {
 				if sz := ExtractH265VideoSize(ep.Packet.Payload); sz.Width > 0 && sz.Height > 0 {
@@ -326,6 +326,49 @@ func TestFractionLostReport(t *testing.T) {
 	wg.Wait()
 }
 
+func TestReceiverReferenceTimeReport(t *testing.T) {
+	buff := NewBuffer(123, 1, 1)
+	require.NotNil(t, buff)
+
+	buff.Bind(webrtc.RTPParameters{
+		HeaderExtensions: nil,
+		Codecs:           []webrtc.RTPCodecParameters{opusCodec},
+	}, opusCodec.RTPCodecCapability, 0)
+	pkt := rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    111,
+			SequenceNumber: 1,
+			Timestamp:      1,
+			SSRC:           123,
+		},
+		Payload: []byte{0xff, 0xff, 0xff, 0xfd, 0xb4, 0x9f, 0x94, 0x1},
+	}
+	b, err := pkt.Marshal()
+	require.NoError(t, err)
+	_, err = buff.Write(b)
+	require.NoError(t, err)
+
+	buff.Lock()
+	pkts := buff.getRTCP()
+	buff.Unlock()
+
+	var rrtr *rtcp.ReceiverReferenceTimeReportBlock
+	for _, pkt := range pkts {
+		if xr, ok := pkt.(*rtcp.ExtendedReport); ok {
+			// DLRR in response is routed by the SSRC the report is sent with
+			require.EqualValues(t, 123, xr.SenderSSRC)
+			for _, report := range xr.Reports {
+				if r, ok := report.(*rtcp.ReceiverReferenceTimeReportBlock); ok {
+					rrtr = r
+				}
+			}
+		}
+	}
+	require.NotNil(t, rrtr)
+	require.NotZero(t, rrtr.NTPTimestamp)
+}
+
 func TestCodecChange(t *testing.T) {
 	// codec change before bind
 	buff := NewBuffer(123, 1, 1)
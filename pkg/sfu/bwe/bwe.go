@@ -37,6 +37,7 @@ const (
 	BWETypeNone BWEType = iota
 	BWETypeRemote
 	BWETypeSendSide
+	BWETypeDelayBased
 )
 
 func (b BWEType) String() string {
@@ -47,6 +48,8 @@ func (b BWEType) String() string {
 		return "REMOTE"
 	case BWETypeSendSide:
 		return "SEND_SIDE"
+	case BWETypeDelayBased:
+		return "DELAY_BASED"
 	default:
 		return fmt.Sprintf("%d", int(b))
 	}
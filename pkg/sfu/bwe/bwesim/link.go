@@ -0,0 +1,185 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bwesim
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+const (
+	cReferenceTimeResolution = 64 * time.Millisecond
+	cDeltaResolution         = 250 // micro seconds
+)
+
+// LinkConfig describes a bottleneck link with a drop tail queue,
+// media is sent at a constant bitrate and the link capacity can drop mid-way
+type LinkConfig struct {
+	Duration    time.Duration
+	SendBitrate int64
+	PacketSize  int
+
+	Capacity         int64
+	CapacityChangeAt time.Duration // 0 for no change
+	ChangedCapacity  int64
+
+	PropagationDelay time.Duration
+	MaxQueuingDelay  time.Duration
+	FeedbackInterval time.Duration
+}
+
+type linkPacket struct {
+	sequenceNumber uint16
+	recvTime       int64 // -1 if lost
+}
+
+// SimulateLink generates a trace of sends and TWCC feedback as seen by the sender over the link
+func SimulateLink(config LinkConfig) *Trace {
+	trace := &Trace{}
+
+	sendInterval := int64(config.PacketSize*8) * 1_000_000 / config.SendBitrate
+	duration := config.Duration.Microseconds()
+	feedbackInterval := config.FeedbackInterval.Microseconds()
+	propagationDelay := config.PropagationDelay.Microseconds()
+
+	var (
+		sn            uint16
+		linkFreeAt    int64
+		pending       []linkPacket
+		nextFeedback  = feedbackInterval
+		feedbackCount uint8
+	)
+	emitFeedback := func(at int64) {
+		// report packets received by now, losses are known once a later packet is received
+		lastReceived := -1
+		for idx, lp := range pending {
+			if lp.recvTime >= 0 && lp.recvTime <= at {
+				lastReceived = idx
+			}
+		}
+		if lastReceived < 0 {
+			return
+		}
+
+		report := buildReport(pending[:lastReceived+1], feedbackCount)
+		feedbackCount++
+		pending = pending[lastReceived+1:]
+		if feedback, err := report.Marshal(); err == nil {
+			trace.Events = append(trace.Events, TraceEvent{
+				At:       at + propagationDelay,
+				Feedback: feedback,
+			})
+		}
+	}
+
+	for at := int64(0); at < duration; at += sendInterval {
+		for nextFeedback <= at {
+			emitFeedback(nextFeedback)
+			nextFeedback += feedbackInterval
+		}
+
+		trace.Events = append(trace.Events, TraceEvent{
+			At: at,
+			Send: &SendEvent{
+				SequenceNumber: sn,
+				Size:           config.PacketSize,
+			},
+		})
+
+		capacity := config.Capacity
+		if config.CapacityChangeAt != 0 && at >= config.CapacityChangeAt.Microseconds() {
+			capacity = config.ChangedCapacity
+		}
+		serializationTime := int64(config.PacketSize*8) * 1_000_000 / capacity
+
+		lp := linkPacket{sequenceNumber: sn, recvTime: -1}
+		startAt := max(at, linkFreeAt)
+		if config.MaxQueuingDelay == 0 || startAt-at <= config.MaxQueuingDelay.Microseconds() {
+			linkFreeAt = startAt + serializationTime
+			lp.recvTime = linkFreeAt + propagationDelay
+		}
+		pending = append(pending, lp)
+		sn++
+	}
+
+	// feedback is delayed by propagation, order events by time as the sender would see them
+	sort.SliceStable(trace.Events, func(i, j int) bool {
+		return trace.Events[i].At < trace.Events[j].At
+	})
+	return trace
+}
+
+func buildReport(packets []linkPacket, feedbackCount uint8) *rtcp.TransportLayerCC {
+	var firstRecvTime int64 = -1
+	for _, lp := range packets {
+		if lp.recvTime >= 0 {
+			firstRecvTime = lp.recvTime
+			break
+		}
+	}
+	referenceTime := firstRecvTime / cReferenceTimeResolution.Microseconds()
+
+	report := &rtcp.TransportLayerCC{
+		BaseSequenceNumber: packets[0].sequenceNumber,
+		PacketStatusCount:  uint16(len(packets)),
+		ReferenceTime:      uint32(referenceTime),
+		FbPktCount:         feedbackCount,
+	}
+
+	lastTime := referenceTime * cReferenceTimeResolution.Microseconds()
+	var run *rtcp.RunLengthChunk
+	for _, lp := range packets {
+		symbol := uint16(rtcp.TypeTCCPacketNotReceived)
+		if lp.recvTime >= 0 {
+			delta := (lp.recvTime - lastTime) / cDeltaResolution * cDeltaResolution
+			lastTime += delta
+
+			symbol = rtcp.TypeTCCPacketReceivedSmallDelta
+			if delta < 0 || delta > 255*cDeltaResolution {
+				symbol = rtcp.TypeTCCPacketReceivedLargeDelta
+			}
+			report.RecvDeltas = append(report.RecvDeltas, &rtcp.RecvDelta{
+				Type:  symbol,
+				Delta: delta,
+			})
+		}
+
+		if run == nil || run.PacketStatusSymbol != symbol || run.RunLength == 0x1fff {
+			run = &rtcp.RunLengthChunk{
+				PacketStatusSymbol: symbol,
+			}
+			report.PacketChunks = append(report.PacketChunks, run)
+		}
+		run.RunLength++
+	}
+
+	size := 20 + 2*len(report.PacketChunks)
+	for _, delta := range report.RecvDeltas {
+		if delta.Type == rtcp.TypeTCCPacketReceivedSmallDelta {
+			size++
+		} else {
+			size += 2
+		}
+	}
+	report.Header = rtcp.Header{
+		Padding: size%4 != 0,
+		Count:   rtcp.FormatTCC,
+		Type:    rtcp.TypeTransportSpecificFeedback,
+		Length:  uint16(report.MarshalSize()/4 - 1),
+	}
+	return report
+}
@@ -0,0 +1,159 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bwesim
+
+import (
+	"github.com/pion/rtcp"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/livekit/livekit-server/pkg/sfu/bwe"
+	"github.com/livekit/livekit-server/pkg/sfu/ccutils"
+	"github.com/livekit/protocol/utils/mono"
+)
+
+// ------------------------------------------------
+
+type StateChange struct {
+	At                                int64
+	FromState                         bwe.CongestionState
+	ToState                           bwe.CongestionState
+	EstimatedAvailableChannelCapacity int64
+}
+
+func (s StateChange) MarshalLogObject(e zapcore.ObjectEncoder) error {
+	e.AddInt64("At", s.At)
+	e.AddString("FromState", s.FromState.String())
+	e.AddString("ToState", s.ToState.String())
+	e.AddInt64("EstimatedAvailableChannelCapacity", s.EstimatedAvailableChannelCapacity)
+	return nil
+}
+
+type ReplayResult struct {
+	Type         bwe.BWEType
+	StateChanges []StateChange
+	FinalState   bwe.CongestionState
+}
+
+// FirstChangeTo returns the first change into given state
+func (r *ReplayResult) FirstChangeTo(state bwe.CongestionState) (StateChange, bool) {
+	for _, sc := range r.StateChanges {
+		if sc.ToState == state && sc.FromState != state {
+			return sc, true
+		}
+	}
+
+	return StateChange{}, false
+}
+
+func (r *ReplayResult) MarshalLogObject(e zapcore.ObjectEncoder) error {
+	if r == nil {
+		return nil
+	}
+
+	e.AddString("Type", r.Type.String())
+	e.AddArray("StateChanges", zapcore.ArrayMarshalerFunc(func(ae zapcore.ArrayEncoder) error {
+		for _, sc := range r.StateChanges {
+			if err := ae.AppendObject(sc); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	e.AddString("FinalState", r.FinalState.String())
+	return nil
+}
+
+// ------------------------------------------------
+
+type replayListener struct {
+	at     int64
+	result *ReplayResult
+}
+
+func (r *replayListener) OnCongestionStateChange(fromState bwe.CongestionState, toState bwe.CongestionState, estimatedAvailableChannelCapacity int64) {
+	r.result.StateChanges = append(r.result.StateChanges, StateChange{
+		At:                                r.at,
+		FromState:                         fromState,
+		ToState:                           toState,
+		EstimatedAvailableChannelCapacity: estimatedAvailableChannelCapacity,
+	})
+}
+
+// Replay feeds a trace through an estimator as fast as possible.
+//
+// Send times are re-based to current time so that estimators pruning history against
+// the wall clock do not drop packets of the trace. Estimators assign their own
+// TWCC sequence numbers, feedback is re-mapped to those. Probe cluster association
+// is not part of the trace, probe packets are replayed as regular packets.
+func Replay(trace *Trace, estimator bwe.BWE) *ReplayResult {
+	result := &ReplayResult{
+		Type: estimator.Type(),
+	}
+	listener := &replayListener{
+		result: result,
+	}
+	estimator.SetBWEListener(listener)
+
+	baseMicro := mono.UnixMicro()
+	var snOffset uint16
+	isSNOffsetSet := false
+	for _, event := range trace.Events {
+		listener.at = event.At
+
+		switch {
+		case event.Send != nil:
+			sn := estimator.RecordPacketSendAndGetSequenceNumber(
+				baseMicro+event.At,
+				event.Send.Size,
+				event.Send.IsRTX,
+				ccutils.ProbeClusterIdInvalid,
+				event.Send.IsProbe,
+			)
+			if !isSNOffsetSet {
+				snOffset = sn - event.Send.SequenceNumber
+				isSNOffsetSet = true
+			}
+
+		case len(event.Feedback) != 0:
+			pkts, err := rtcp.Unmarshal(event.Feedback)
+			if err != nil {
+				continue
+			}
+
+			for _, pkt := range pkts {
+				if report, ok := pkt.(*rtcp.TransportLayerCC); ok {
+					report.BaseSequenceNumber += snOffset
+					estimator.HandleTWCCFeedback(report)
+				}
+			}
+
+		case event.RTT != 0:
+			estimator.UpdateRTT(event.RTT)
+		}
+	}
+
+	estimator.SetBWEListener(nil)
+	result.FinalState = estimator.CongestionState()
+	return result
+}
+
+// Compare replays a trace through each of the estimators
+func Compare(trace *Trace, estimators ...bwe.BWE) []*ReplayResult {
+	results := make([]*ReplayResult, 0, len(estimators))
+	for _, estimator := range estimators {
+		results = append(results, Replay(trace, estimator))
+	}
+	return results
+}
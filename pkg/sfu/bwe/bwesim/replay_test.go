@@ -0,0 +1,104 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bwesim
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/sfu/bwe"
+	"github.com/livekit/livekit-server/pkg/sfu/bwe/delaybasedbwe"
+	"github.com/livekit/livekit-server/pkg/sfu/bwe/sendsidebwe"
+	"github.com/livekit/protocol/logger"
+)
+
+func TestReplay(t *testing.T) {
+	trace := SimulateLink(LinkConfig{
+		Duration:    10 * time.Second,
+		SendBitrate: 1_500_000,
+		PacketSize:  1200,
+
+		Capacity:         3_000_000,
+		CapacityChangeAt: 5 * time.Second,
+		ChangedCapacity:  1_000_000,
+
+		PropagationDelay: 20 * time.Millisecond,
+		MaxQueuingDelay:  500 * time.Millisecond,
+		FeedbackInterval: 50 * time.Millisecond,
+	})
+
+	// round trip through serialization
+	var buf bytes.Buffer
+	require.NoError(t, trace.Write(&buf))
+	trace, err := ReadTrace(&buf)
+	require.NoError(t, err)
+	require.Greater(t, trace.Duration(), int64(9*time.Second/time.Microsecond))
+
+	results := Compare(
+		trace,
+		delaybasedbwe.NewDelayBasedBWE(delaybasedbwe.DelayBasedBWEParams{
+			Config: delaybasedbwe.DefaultDelayBasedBWEConfig,
+			Logger: logger.GetLogger(),
+		}),
+		sendsidebwe.NewSendSideBWE(sendsidebwe.SendSideBWEParams{
+			Config: sendsidebwe.DefaultSendSideBWEConfig,
+			Logger: logger.GetLogger(),
+		}),
+	)
+	require.Len(t, results, 2)
+	require.Equal(t, bwe.BWETypeDelayBased, results[0].Type)
+	require.Equal(t, bwe.BWETypeSendSide, results[1].Type)
+
+	// no congestion before capacity drop, congestion detected after with estimate close to capacity
+	delayBased := results[0]
+	sc, ok := delayBased.FirstChangeTo(bwe.CongestionStateEarlyWarning)
+	require.True(t, ok)
+	require.Greater(t, sc.At, (5 * time.Second).Microseconds())
+
+	sc, ok = delayBased.FirstChangeTo(bwe.CongestionStateCongested)
+	require.True(t, ok)
+	require.Less(t, sc.At, (6 * time.Second).Microseconds())
+	require.InDelta(t, 900_000, sc.EstimatedAvailableChannelCapacity, 200_000)
+	require.Equal(t, bwe.CongestionStateCongested, delayBased.FinalState)
+}
+
+func TestTraceRecorder(t *testing.T) {
+	trace := SimulateLink(LinkConfig{
+		Duration:         time.Second,
+		SendBitrate:      500_000,
+		PacketSize:       1000,
+		Capacity:         1_000_000,
+		FeedbackInterval: 100 * time.Millisecond,
+	})
+
+	recorder := NewTraceRecorder(delaybasedbwe.NewDelayBasedBWE(delaybasedbwe.DelayBasedBWEParams{
+		Config: delaybasedbwe.DefaultDelayBasedBWEConfig,
+		Logger: logger.GetLogger(),
+	}))
+	result := Replay(trace, recorder)
+	require.Equal(t, bwe.BWETypeDelayBased, result.Type)
+	require.Empty(t, result.StateChanges)
+
+	recorded := recorder.Trace()
+	require.Len(t, recorded.Events, len(trace.Events))
+	for idx, event := range recorded.Events {
+		require.Equal(t, trace.Events[idx].Send != nil, event.Send != nil)
+		require.Equal(t, len(trace.Events[idx].Feedback) != 0, len(event.Feedback) != 0)
+	}
+	require.Empty(t, recorder.Trace().Events)
+}
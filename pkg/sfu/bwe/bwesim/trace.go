@@ -0,0 +1,190 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bwesim replays recorded congestion control traces (packet sends and TWCC feedback)
+// through bandwidth estimators so that they can be compared offline.
+package bwesim
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/pion/rtcp"
+
+	"github.com/livekit/livekit-server/pkg/sfu/bwe"
+	"github.com/livekit/livekit-server/pkg/sfu/ccutils"
+	"github.com/livekit/protocol/utils/mono"
+)
+
+// ------------------------------------------------
+
+type SendEvent struct {
+	SequenceNumber uint16 `json:"sn"`
+	Size           int    `json:"size"`
+	IsRTX          bool   `json:"isRTX,omitempty"`
+	IsProbe        bool   `json:"isProbe,omitempty"`
+}
+
+// TraceEvent is one of packet send, TWCC feedback (marshalled RTCP) or an RTT update,
+// At is in micro seconds from start of the trace
+type TraceEvent struct {
+	At       int64      `json:"at"`
+	Send     *SendEvent `json:"send,omitempty"`
+	Feedback []byte     `json:"feedback,omitempty"`
+	RTT      float64    `json:"rtt,omitempty"`
+}
+
+type Trace struct {
+	Events []TraceEvent
+}
+
+// ReadTrace reads a trace stored as one JSON encoded event per line
+func ReadTrace(r io.Reader) (*Trace, error) {
+	t := &Trace{}
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var event TraceEvent
+		if err := decoder.Decode(&event); err != nil {
+			if err == io.EOF {
+				return t, nil
+			}
+			return nil, err
+		}
+		t.Events = append(t.Events, event)
+	}
+}
+
+func LoadTrace(path string) (*Trace, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadTrace(f)
+}
+
+func (t *Trace) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	encoder := json.NewEncoder(bw)
+	for idx := range t.Events {
+		if err := encoder.Encode(&t.Events[idx]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func (t *Trace) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return t.Write(f)
+}
+
+// Duration returns duration of the trace in micro seconds
+func (t *Trace) Duration() int64 {
+	if len(t.Events) == 0 {
+		return 0
+	}
+
+	return t.Events[len(t.Events)-1].At - t.Events[0].At
+}
+
+// ------------------------------------------------
+
+var _ bwe.BWE = (*TraceRecorder)(nil)
+
+// TraceRecorder wraps an estimator and records packet sends, TWCC feedback and RTT updates
+// going through it, meant for capturing traces from a live session for offline analysis,
+// events are held in memory till the trace is taken.
+type TraceRecorder struct {
+	bwe.BWE
+
+	lock       sync.Mutex
+	startMicro int64
+	trace      *Trace
+}
+
+func NewTraceRecorder(estimator bwe.BWE) *TraceRecorder {
+	return &TraceRecorder{
+		BWE:        estimator,
+		startMicro: mono.UnixMicro(),
+		trace:      &Trace{},
+	}
+}
+
+func (t *TraceRecorder) RecordPacketSendAndGetSequenceNumber(
+	atMicro int64,
+	size int,
+	isRTX bool,
+	probeClusterId ccutils.ProbeClusterId,
+	isProbe bool,
+) uint16 {
+	sn := t.BWE.RecordPacketSendAndGetSequenceNumber(atMicro, size, isRTX, probeClusterId, isProbe)
+
+	t.record(TraceEvent{
+		At: atMicro - t.startMicro,
+		Send: &SendEvent{
+			SequenceNumber: sn,
+			Size:           size,
+			IsRTX:          isRTX,
+			IsProbe:        isProbe,
+		},
+	})
+	return sn
+}
+
+func (t *TraceRecorder) HandleTWCCFeedback(report *rtcp.TransportLayerCC) {
+	if feedback, err := report.Marshal(); err == nil {
+		t.record(TraceEvent{
+			At:       mono.UnixMicro() - t.startMicro,
+			Feedback: feedback,
+		})
+	}
+
+	t.BWE.HandleTWCCFeedback(report)
+}
+
+func (t *TraceRecorder) UpdateRTT(rtt float64) {
+	t.record(TraceEvent{
+		At:  mono.UnixMicro() - t.startMicro,
+		RTT: rtt,
+	})
+
+	t.BWE.UpdateRTT(rtt)
+}
+
+// Trace returns events recorded so far and starts a new trace
+func (t *TraceRecorder) Trace() *Trace {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	trace := t.trace
+	t.trace = &Trace{}
+	return trace
+}
+
+func (t *TraceRecorder) record(event TraceEvent) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.trace.Events = append(t.trace.Events, event)
+}
@@ -0,0 +1,508 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delaybasedbwe
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/livekit/livekit-server/pkg/sfu/bwe"
+	"github.com/livekit/livekit-server/pkg/sfu/ccutils"
+	"github.com/livekit/protocol/logger"
+)
+
+var _ bwe.BWE = (*DelayBasedBWE)(nil)
+
+//
+// Based on Google Congestion Control (GCC)
+// (https://datatracker.ietf.org/doc/html/draft-ietf-rmcat-gcc-02)
+//
+// TWCC feedback is used to measure delay variation between bursts of packets.
+// A trendline filter (linear regression over smoothed accumulated delay) gives
+// the trend of queuing along the path and an adaptive threshold on the trend
+// signals whether the channel is being over-used, under-used or is normal.
+//
+// The usage signal drives an AIMD (additive increase, multiplicative decrease)
+// rate controller anchored to the acknowledged bitrate. A loss based controller
+// runs alongside and the lower of the two is the channel capacity estimate.
+//
+// All timing is in the time base of the remote receiver (as reported in TWCC feedback),
+// so the estimator gives the same result when feedback is replayed offline.
+//
+
+// ---------------------------------------------------------------------------
+
+type DelayBasedBWEConfig struct {
+	Trendline      TrendlineConfig              `yaml:"trendline,omitempty"`
+	RateController RateControllerConfig         `yaml:"rate_controller,omitempty"`
+	ProbeRegulator ccutils.ProbeRegulatorConfig `yaml:"probe_regulator,omitempty"`
+
+	// over-use has to persist for this long to move from early warning to congested
+	CongestedMinDuration time.Duration `yaml:"congested_min_duration,omitempty"`
+	// no over-use for this long to clear congestion
+	ClearDuration time.Duration `yaml:"clear_duration,omitempty"`
+	// while congested, a drop in estimate by more than this ratio is notified
+	EstimateChangeThreshold float64 `yaml:"estimate_change_threshold,omitempty"`
+
+	ProbeSettleWaitNumRTT float64       `yaml:"probe_settle_wait_num_rtt,omitempty"`
+	ProbeSettleWaitMin    time.Duration `yaml:"probe_settle_wait_min,omitempty"`
+	ProbeSettleWaitMax    time.Duration `yaml:"probe_settle_wait_max,omitempty"`
+}
+
+var (
+	DefaultDelayBasedBWEConfig = DelayBasedBWEConfig{
+		Trendline:      defaultTrendlineConfig,
+		RateController: defaultRateControllerConfig,
+		ProbeRegulator: ccutils.DefaultProbeRegulatorConfig,
+
+		CongestedMinDuration:    100 * time.Millisecond,
+		ClearDuration:           500 * time.Millisecond,
+		EstimateChangeThreshold: 0.05,
+
+		ProbeSettleWaitNumRTT: 5,
+		ProbeSettleWaitMin:    250 * time.Millisecond,
+		ProbeSettleWaitMax:    5 * time.Second,
+	}
+)
+
+// ---------------------------------------------------------------------------
+
+type probeTracker struct {
+	pci                   ccutils.ProbeClusterInfo
+	doneAt                time.Time
+	sentMaxSequenceNumber uint64
+
+	maxSequenceNumber uint64
+	firstRecvTime     int64
+	lastRecvTime      int64
+	bytes             int
+	numPackets        int
+	numLost           int
+	isOverusing       bool
+}
+
+func (p *probeTracker) Add(pf *packetFeedback, usage bandwidthUsage) {
+	if pf.probeClusterId != p.pci.Id {
+		return
+	}
+
+	p.maxSequenceNumber = max(p.maxSequenceNumber, pf.sequenceNumber)
+	p.numPackets++
+	if pf.isLost {
+		p.numLost++
+		return
+	}
+
+	if p.firstRecvTime == 0 {
+		p.firstRecvTime = pf.recvTime
+	}
+	p.lastRecvTime = max(p.lastRecvTime, pf.recvTime)
+	p.bytes += int(pf.size)
+
+	if usage == bandwidthUsageOverusing {
+		p.isOverusing = true
+	}
+}
+
+func (p *probeTracker) IsValid() bool {
+	return p.bytes > p.pci.Goal.DesiredBytes/2 && time.Duration(p.lastRecvTime-p.firstRecvTime)*time.Microsecond > p.pci.Goal.Duration/2
+}
+
+func (p *probeTracker) AcknowledgedBitrate() int64 {
+	duration := p.lastRecvTime - p.firstRecvTime
+	if duration <= 0 {
+		return 0
+	}
+
+	return int64(float64(p.bytes*8) * 1e6 / float64(duration))
+}
+
+func (p *probeTracker) LossFraction() float64 {
+	if p.numPackets == 0 {
+		return 0
+	}
+
+	return float64(p.numLost) / float64(p.numPackets)
+}
+
+func (p *probeTracker) MarshalLogObject(e zapcore.ObjectEncoder) error {
+	if p == nil {
+		return nil
+	}
+
+	e.AddObject("pci", p.pci)
+	e.AddTime("doneAt", p.doneAt)
+	e.AddUint64("sentMaxSequenceNumber", p.sentMaxSequenceNumber)
+	e.AddUint64("maxSequenceNumber", p.maxSequenceNumber)
+	e.AddInt("bytes", p.bytes)
+	e.AddInt("numPackets", p.numPackets)
+	e.AddInt("numLost", p.numLost)
+	e.AddInt64("acknowledgedBitrate", p.AcknowledgedBitrate())
+	e.AddBool("isOverusing", p.isOverusing)
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+
+type DelayBasedBWEParams struct {
+	Config DelayBasedBWEConfig
+	Logger logger.Logger
+}
+
+type DelayBasedBWE struct {
+	bwe.NullBWE
+
+	params DelayBasedBWEParams
+
+	lock sync.Mutex
+
+	rtt float64
+
+	packetTracker *packetTracker
+
+	interArrival   *interArrival
+	trendline      *trendlineEstimator
+	ackedBitrate   *ackedBitrateEstimator
+	rateController *rateController
+	bandwidthUsage bandwidthUsage
+	lossFraction   float64
+	lastRecvTime   int64
+
+	probeTracker   *probeTracker
+	probeRegulator *ccutils.ProbeRegulator
+
+	estimatedAvailableChannelCapacity int64
+
+	congestionState           bwe.CongestionState
+	congestionStateSwitchedAt int64
+	lastOveruseAt             int64
+
+	bweListener bwe.BWEListener
+}
+
+func NewDelayBasedBWE(params DelayBasedBWEParams) *DelayBasedBWE {
+	d := &DelayBasedBWE{
+		params:        params,
+		packetTracker: newPacketTracker(),
+	}
+	d.Reset()
+
+	return d
+}
+
+func (d *DelayBasedBWE) Type() bwe.BWEType {
+	return bwe.BWETypeDelayBased
+}
+
+func (d *DelayBasedBWE) SetBWEListener(bweListener bwe.BWEListener) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.bweListener = bweListener
+}
+
+func (d *DelayBasedBWE) getBWEListener() bwe.BWEListener {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.bweListener
+}
+
+func (d *DelayBasedBWE) Reset() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.rtt = bwe.DefaultRTT
+
+	d.interArrival = newInterArrival(d.params.Config.Trendline.BurstInterval)
+	d.trendline = newTrendlineEstimator(d.params.Config.Trendline)
+	d.ackedBitrate = newAckedBitrateEstimator(d.params.Config.RateController.AckedBitrateWindow)
+	d.rateController = newRateController(d.params.Config.RateController)
+	d.bandwidthUsage = bandwidthUsageNormal
+	d.lossFraction = 0
+
+	d.probeTracker = nil
+	d.probeRegulator = ccutils.NewProbeRegulator(ccutils.ProbeRegulatorParams{
+		Config: d.params.Config.ProbeRegulator,
+		Logger: d.params.Logger,
+	})
+
+	d.estimatedAvailableChannelCapacity = 100_000_000
+
+	d.congestionState = bwe.CongestionStateNone
+	d.congestionStateSwitchedAt = d.lastRecvTime
+	d.lastOveruseAt = 0
+}
+
+func (d *DelayBasedBWE) RecordPacketSendAndGetSequenceNumber(
+	atMicro int64,
+	size int,
+	isRTX bool,
+	probeClusterId ccutils.ProbeClusterId,
+	isProbe bool,
+) uint16 {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.packetTracker.RecordPacketSend(atMicro, size, probeClusterId, isProbe)
+}
+
+func (d *DelayBasedBWE) HandleTWCCFeedback(report *rtcp.TransportLayerCC) {
+	d.lock.Lock()
+	feedbacks := d.packetTracker.ProcessReport(report)
+	if len(feedbacks) == 0 {
+		d.lock.Unlock()
+		return
+	}
+
+	numLost := 0
+	for idx := range feedbacks {
+		pf := &feedbacks[idx]
+		if pf.isLost {
+			numLost++
+		} else {
+			d.lastRecvTime = max(d.lastRecvTime, pf.recvTime)
+			d.ackedBitrate.Add(pf.recvTime, int(pf.size))
+			if sendDelta, recvDelta, ok := d.interArrival.AddPacket(pf.sendTime, pf.recvTime); ok {
+				d.bandwidthUsage = d.trendline.Update(sendDelta, recvDelta, pf.recvTime)
+			}
+		}
+
+		if d.probeTracker != nil {
+			d.probeTracker.Add(pf, d.bandwidthUsage)
+		}
+	}
+	d.lossFraction = float64(numLost) / float64(len(feedbacks))
+
+	ackedBitrate, _ := d.ackedBitrate.Bitrate()
+	d.rateController.Update(d.bandwidthUsage, d.lossFraction, ackedBitrate, d.lastRecvTime, d.rtt)
+
+	shouldNotify, fromState, toState, committedChannelCapacity := d.congestionDetectionStateMachine()
+	d.lock.Unlock()
+
+	if shouldNotify {
+		if bweListener := d.getBWEListener(); bweListener != nil {
+			bweListener.OnCongestionStateChange(fromState, toState, committedChannelCapacity)
+		}
+	}
+}
+
+func (d *DelayBasedBWE) UpdateRTT(rtt float64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if rtt == 0 {
+		d.rtt = bwe.DefaultRTT
+	} else {
+		if d.rtt == 0 {
+			d.rtt = rtt
+		} else {
+			d.rtt = bwe.RTTSmoothingFactor*rtt + (1.0-bwe.RTTSmoothingFactor)*d.rtt
+		}
+	}
+}
+
+func (d *DelayBasedBWE) CongestionState() bwe.CongestionState {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.congestionState
+}
+
+func (d *DelayBasedBWE) CanProbe() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.congestionState == bwe.CongestionStateNone && d.probeTracker == nil && d.probeRegulator.CanProbe()
+}
+
+func (d *DelayBasedBWE) ProbeDuration() time.Duration {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.probeRegulator.ProbeDuration()
+}
+
+func (d *DelayBasedBWE) ProbeClusterStarting(pci ccutils.ProbeClusterInfo) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.probeTracker = &probeTracker{
+		pci: pci,
+	}
+
+	d.packetTracker.ProbeClusterStarting(pci.Id)
+}
+
+func (d *DelayBasedBWE) ProbeClusterDone(pci ccutils.ProbeClusterInfo) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	sentMaxSequenceNumber := d.packetTracker.ProbeClusterDone(pci.Id)
+	if d.probeTracker != nil && d.probeTracker.pci.Id == pci.Id {
+		d.probeTracker.pci = pci
+		d.probeTracker.doneAt = time.Now()
+		d.probeTracker.sentMaxSequenceNumber = sentMaxSequenceNumber
+	}
+}
+
+func (d *DelayBasedBWE) ProbeClusterIsGoalReached() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.probeTracker == nil || d.congestionState != bwe.CongestionStateNone {
+		return false
+	}
+
+	if !d.probeTracker.IsValid() || d.probeTracker.isOverusing {
+		return false
+	}
+
+	return d.probeTracker.AcknowledgedBitrate() > int64(d.probeTracker.pci.Goal.DesiredBps)
+}
+
+func (d *DelayBasedBWE) ProbeClusterFinalize() (ccutils.ProbeSignal, int64, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.probeTracker == nil || d.probeTracker.doneAt.IsZero() {
+		return ccutils.ProbeSignalInconclusive, 0, false
+	}
+
+	if d.probeTracker.sentMaxSequenceNumber == 0 || d.probeTracker.maxSequenceNumber < d.probeTracker.sentMaxSequenceNumber {
+		// not all feedback of probe has been received, wait for it to settle
+		settleWait := time.Duration(d.params.Config.ProbeSettleWaitNumRTT * d.rtt * float64(time.Second))
+		settleWait = min(max(settleWait, d.params.Config.ProbeSettleWaitMin), d.params.Config.ProbeSettleWaitMax)
+		if time.Since(d.probeTracker.doneAt) < settleWait {
+			return ccutils.ProbeSignalInconclusive, 0, false
+		}
+	}
+
+	isSignalValid := d.probeTracker.IsValid()
+	d.params.Logger.Infow(
+		"delay based bwe: probe finalized",
+		"isSignalValid", isSignalValid,
+		"probeTracker", d.probeTracker,
+		"congestionState", d.congestionState,
+		"rateController", d.rateController,
+		"rtt", d.rtt,
+	)
+
+	pci := d.probeTracker.pci
+	probeSignal := ccutils.ProbeSignalInconclusive
+	switch {
+	case d.congestionState != bwe.CongestionStateNone:
+		// if congestion signal changed during probe, defer to that signal
+		probeSignal = ccutils.ProbeSignalCongesting
+
+	case !isSignalValid:
+
+	case d.probeTracker.isOverusing || d.probeTracker.LossFraction() > d.params.Config.RateController.HighLoss:
+		probeSignal = ccutils.ProbeSignalCongesting
+
+	default:
+		probeSignal = ccutils.ProbeSignalNotCongesting
+		d.estimatedAvailableChannelCapacity = max(d.probeTracker.AcknowledgedBitrate(), d.rateController.Estimate())
+	}
+
+	d.probeRegulator.ProbeSignal(probeSignal, pci.CreatedAt)
+	d.probeTracker = nil
+	return probeSignal, d.estimatedAvailableChannelCapacity, true
+}
+
+func (d *DelayBasedBWE) isOverusing() bool {
+	return d.bandwidthUsage == bandwidthUsageOverusing || d.lossFraction > d.params.Config.RateController.HighLoss
+}
+
+func (d *DelayBasedBWE) congestionDetectionStateMachine() (bool, bwe.CongestionState, bwe.CongestionState, int64) {
+	now := d.lastRecvTime
+	isOverusing := d.isOverusing()
+	if isOverusing {
+		d.lastOveruseAt = now
+	}
+	isCleared := now-d.lastOveruseAt >= d.params.Config.ClearDuration.Microseconds()
+
+	fromState := d.congestionState
+	toState := d.congestionState
+
+	switch fromState {
+	case bwe.CongestionStateNone:
+		if isOverusing {
+			toState = bwe.CongestionStateEarlyWarning
+		}
+
+	case bwe.CongestionStateEarlyWarning:
+		if isOverusing && now-d.congestionStateSwitchedAt >= d.params.Config.CongestedMinDuration.Microseconds() {
+			toState = bwe.CongestionStateCongested
+		} else if isCleared {
+			toState = bwe.CongestionStateNone
+		}
+
+	case bwe.CongestionStateCongested:
+		if isCleared {
+			toState = bwe.CongestionStateNone
+		}
+	}
+
+	shouldNotify := false
+	if toState != fromState {
+		if toState == bwe.CongestionStateCongested {
+			if estimate := d.rateController.Estimate(); estimate != 0 {
+				d.estimatedAvailableChannelCapacity = estimate
+			}
+		}
+		fromState, toState = d.updateCongestionState(toState)
+		shouldNotify = true
+	} else if d.congestionState == bwe.CongestionStateCongested {
+		// while congested, follow the estimate down so that allocations can relieve congestion
+		estimate := d.rateController.Estimate()
+		if estimate != 0 && float64(estimate) < (1.0-d.params.Config.EstimateChangeThreshold)*float64(d.estimatedAvailableChannelCapacity) {
+			d.params.Logger.Infow(
+				"delay based bwe: estimate dropped while congested",
+				"from", d.estimatedAvailableChannelCapacity,
+				"to", estimate,
+				"trendline", d.trendline,
+				"rateController", d.rateController,
+			)
+			d.estimatedAvailableChannelCapacity = estimate
+			shouldNotify = true
+		}
+	}
+
+	return shouldNotify, fromState, toState, d.estimatedAvailableChannelCapacity
+}
+
+func (d *DelayBasedBWE) updateCongestionState(state bwe.CongestionState) (bwe.CongestionState, bwe.CongestionState) {
+	d.params.Logger.Infow(
+		"delay based bwe: congestion state change",
+		"from", d.congestionState,
+		"to", state,
+		"bandwidthUsage", d.bandwidthUsage,
+		"lossFraction", d.lossFraction,
+		"trendline", d.trendline,
+		"rateController", d.rateController,
+		"estimatedAvailableChannelCapacity", d.estimatedAvailableChannelCapacity,
+	)
+
+	fromState := d.congestionState
+	d.congestionState = state
+	d.congestionStateSwitchedAt = d.lastRecvTime
+	return fromState, d.congestionState
+}
+
+// ------------------------------------------------
@@ -0,0 +1,180 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delaybasedbwe
+
+import (
+	"math/rand"
+
+	"github.com/pion/rtcp"
+
+	"github.com/livekit/livekit-server/pkg/sfu/ccutils"
+)
+
+const (
+	cReferenceTimeMask       = (1 << 24) - 1
+	cReferenceTimeResolution = 64 // 64 ms
+)
+
+// -------------------------------------------------------------------------------
+
+type packetInfo struct {
+	sequenceNumber uint64
+	sendTime       int64
+	recvTime       int64
+	probeClusterId ccutils.ProbeClusterId
+	size           uint16
+	isProbe        bool
+}
+
+// packetFeedback is a packet acknowledged (or reported lost) by a TWCC feedback report
+type packetFeedback struct {
+	packetInfo
+	isLost bool
+}
+
+// -------------------------------------------------------------------------------
+
+type packetTracker struct {
+	sequenceNumber uint64
+	packetInfos    [2048]packetInfo
+
+	probeClusterId         ccutils.ProbeClusterId
+	probeMaxSequenceNumber uint64
+
+	referenceTimeCycles  int64
+	highestReferenceTime uint32
+	numReports           int
+}
+
+func newPacketTracker() *packetTracker {
+	return &packetTracker{
+		sequenceNumber: uint64(rand.Intn(1<<14)) + uint64(1<<15), // a random number in third quartile of sequence number space
+	}
+}
+
+func (p *packetTracker) RecordPacketSend(atMicro int64, size int, probeClusterId ccutils.ProbeClusterId, isProbe bool) uint16 {
+	pi := &p.packetInfos[int(uint16(p.sequenceNumber))%len(p.packetInfos)]
+	*pi = packetInfo{
+		sequenceNumber: p.sequenceNumber,
+		sendTime:       atMicro,
+		size:           uint16(size),
+		probeClusterId: probeClusterId,
+		isProbe:        isProbe,
+	}
+	p.sequenceNumber++
+
+	if p.probeClusterId != ccutils.ProbeClusterIdInvalid && p.probeClusterId == pi.probeClusterId {
+		p.probeMaxSequenceNumber = pi.sequenceNumber
+	}
+
+	return uint16(pi.sequenceNumber)
+}
+
+func (p *packetTracker) ProbeClusterStarting(probeClusterId ccutils.ProbeClusterId) {
+	p.probeClusterId = probeClusterId
+	p.probeMaxSequenceNumber = 0
+}
+
+func (p *packetTracker) ProbeClusterDone(probeClusterId ccutils.ProbeClusterId) uint64 {
+	if p.probeClusterId == probeClusterId {
+		p.probeClusterId = ccutils.ProbeClusterIdInvalid
+	}
+	return p.probeMaxSequenceNumber
+}
+
+// ProcessReport returns packets covered by a feedback report in sequence number order,
+// receive times are in micro seconds in the time base of the remote
+func (p *packetTracker) ProcessReport(report *rtcp.TransportLayerCC) []packetFeedback {
+	recvRefTime := p.referenceTime(report.ReferenceTime) * cReferenceTimeResolution * 1000
+
+	var feedbacks []packetFeedback
+	sequenceNumber := report.BaseSequenceNumber
+	endSequenceNumberExclusive := sequenceNumber + report.PacketStatusCount
+	deltaIdx := 0
+	processSymbol := func(symbol uint16) {
+		isLost := symbol == rtcp.TypeTCCPacketNotReceived
+		if !isLost {
+			if deltaIdx >= len(report.RecvDeltas) {
+				return
+			}
+			recvRefTime += report.RecvDeltas[deltaIdx].Delta
+			deltaIdx++
+		}
+
+		pi := &p.packetInfos[int(sequenceNumber)%len(p.packetInfos)]
+		if uint16(pi.sequenceNumber) == sequenceNumber && pi.sendTime != 0 {
+			switch {
+			case !isLost:
+				pi.recvTime = recvRefTime
+				feedbacks = append(feedbacks, packetFeedback{packetInfo: *pi})
+
+			case pi.recvTime == 0:
+				// a packet received earlier could be reported lost in a later report, those are ignored
+				feedbacks = append(feedbacks, packetFeedback{packetInfo: *pi, isLost: true})
+			}
+		}
+		sequenceNumber++
+	}
+	for _, chunk := range report.PacketChunks {
+		if sequenceNumber == endSequenceNumberExclusive {
+			break
+		}
+
+		switch chunk := chunk.(type) {
+		case *rtcp.RunLengthChunk:
+			for i := uint16(0); i < chunk.RunLength; i++ {
+				if sequenceNumber == endSequenceNumberExclusive {
+					break
+				}
+
+				processSymbol(chunk.PacketStatusSymbol)
+			}
+
+		case *rtcp.StatusVectorChunk:
+			for _, symbol := range chunk.SymbolList {
+				if sequenceNumber == endSequenceNumberExclusive {
+					break
+				}
+
+				processSymbol(symbol)
+			}
+		}
+	}
+
+	return feedbacks
+}
+
+func (p *packetTracker) referenceTime(referenceTime uint32) int64 {
+	p.numReports++
+	if p.numReports == 1 {
+		p.highestReferenceTime = referenceTime
+		return int64(referenceTime)
+	}
+
+	// reference time wrap around handling
+	if (referenceTime-p.highestReferenceTime)&cReferenceTimeMask < (1 << 23) {
+		if referenceTime < p.highestReferenceTime {
+			p.referenceTimeCycles += (1 << 24)
+		}
+		p.highestReferenceTime = referenceTime
+		return p.referenceTimeCycles + int64(referenceTime)
+	}
+
+	cycles := p.referenceTimeCycles
+	if referenceTime > p.highestReferenceTime && cycles >= (1<<24) {
+		cycles -= (1 << 24)
+	}
+	return cycles + int64(referenceTime)
+}
@@ -0,0 +1,251 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delaybasedbwe
+
+import (
+	"math"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// -------------------------------------------------------------------------------
+
+type RateControllerConfig struct {
+	// on overuse, estimate is set to acknowledged bitrate scaled by this factor
+	DecreaseFactor float64 `yaml:"decrease_factor,omitempty"`
+	// rate of increase per second when far from the last known link capacity
+	MultiplicativeIncreaseRate float64 `yaml:"multiplicative_increase_rate,omitempty"`
+	// close to the last known link capacity, estimate increases by about a packet of this size per RTT
+	AdditiveIncreasePacketSize int `yaml:"additive_increase_packet_size,omitempty"`
+	// estimate is not allowed to grow beyond acknowledged bitrate scaled by this ratio
+	MaxAckedBitrateRatio float64 `yaml:"max_acked_bitrate_ratio,omitempty"`
+	// window over which acknowledged bitrate is measured
+	AckedBitrateWindow time.Duration `yaml:"acked_bitrate_window,omitempty"`
+
+	// loss based control, estimate increases below low loss and decreases above high loss
+	LowLoss            float64 `yaml:"low_loss,omitempty"`
+	HighLoss           float64 `yaml:"high_loss,omitempty"`
+	LossIncreaseFactor float64 `yaml:"loss_increase_factor,omitempty"`
+}
+
+var (
+	defaultRateControllerConfig = RateControllerConfig{
+		DecreaseFactor:             0.85,
+		MultiplicativeIncreaseRate: 0.08,
+		AdditiveIncreasePacketSize: 1200,
+		MaxAckedBitrateRatio:       1.5,
+		AckedBitrateWindow:         500 * time.Millisecond,
+
+		LowLoss:            0.02,
+		HighLoss:           0.1,
+		LossIncreaseFactor: 1.05,
+	}
+)
+
+const (
+	cMinEstimate = 10_000
+
+	// number of standard deviations from link capacity considered as near convergence
+	cLinkCapacityDeviations = 3.0
+	// smoothing of link capacity measured on decrease
+	cLinkCapacitySmoothing = 0.05
+)
+
+// -------------------------------------------------------------------------------
+
+type ackedSample struct {
+	recvTime int64
+	size     int
+}
+
+// ackedBitrateEstimator measures the bitrate acknowledged by the remote over a sliding window of receive time
+type ackedBitrateEstimator struct {
+	window int64
+
+	samples []ackedSample
+	bytes   int
+}
+
+func newAckedBitrateEstimator(window time.Duration) *ackedBitrateEstimator {
+	return &ackedBitrateEstimator{
+		window: window.Microseconds(),
+	}
+}
+
+func (a *ackedBitrateEstimator) Add(recvTime int64, size int) {
+	a.samples = append(a.samples, ackedSample{recvTime: recvTime, size: size})
+	a.bytes += size
+
+	idx := 0
+	for idx < len(a.samples) && recvTime-a.samples[idx].recvTime > a.window {
+		a.bytes -= a.samples[idx].size
+		idx++
+	}
+	a.samples = a.samples[idx:]
+}
+
+// Bitrate returns acknowledged bitrate, it is not valid till measured over at least half a window
+func (a *ackedBitrateEstimator) Bitrate() (int64, bool) {
+	if len(a.samples) < 2 {
+		return 0, false
+	}
+
+	duration := a.samples[len(a.samples)-1].recvTime - a.samples[0].recvTime
+	if duration < a.window/2 {
+		return 0, false
+	}
+
+	return int64(float64(a.bytes*8) * 1e6 / float64(duration)), true
+}
+
+// -------------------------------------------------------------------------------
+
+// rateController is an AIMD controller driven by delay based bandwidth usage signal,
+// combined with a loss based controller, the lower of the two is the estimate
+type rateController struct {
+	config RateControllerConfig
+
+	delayBasedEstimate int64
+	lossBasedEstimate  int64
+	lastUpdateAt       int64
+	lastDecreaseAt     int64
+
+	linkCapacity         float64
+	linkCapacityVariance float64
+}
+
+func newRateController(config RateControllerConfig) *rateController {
+	return &rateController{
+		config: config,
+	}
+}
+
+// Update updates estimate with bandwidth usage signal and loss of a feedback report, times are in micro seconds
+func (r *rateController) Update(usage bandwidthUsage, lossFraction float64, ackedBitrate int64, now int64, rtt float64) int64 {
+	if r.delayBasedEstimate == 0 {
+		if ackedBitrate == 0 {
+			return 0
+		}
+
+		// start at acknowledged bitrate
+		r.delayBasedEstimate = ackedBitrate
+		r.lossBasedEstimate = ackedBitrate
+		r.lastUpdateAt = now
+	}
+
+	r.updateDelayBased(usage, ackedBitrate, now, rtt)
+	r.updateLossBased(lossFraction)
+	r.lastUpdateAt = now
+
+	return r.Estimate()
+}
+
+func (r *rateController) Estimate() int64 {
+	return min(r.delayBasedEstimate, r.lossBasedEstimate)
+}
+
+func (r *rateController) updateDelayBased(usage bandwidthUsage, ackedBitrate int64, now int64, rtt float64) {
+	rttMicro := int64(rtt * 1e6)
+	switch usage {
+	case bandwidthUsageOverusing:
+		if r.lastDecreaseAt != 0 && now-r.lastDecreaseAt < rttMicro {
+			// give previous decrease a round trip to take effect
+			return
+		}
+
+		base := r.delayBasedEstimate
+		if ackedBitrate != 0 {
+			base = ackedBitrate
+			r.updateLinkCapacity(float64(ackedBitrate))
+		}
+		r.delayBasedEstimate = max(int64(r.config.DecreaseFactor*float64(base)), cMinEstimate)
+		r.lastDecreaseAt = now
+
+	case bandwidthUsageUnderusing:
+		// hold while queues drain
+
+	default:
+		elapsed := min(now-r.lastUpdateAt, time.Second.Microseconds())
+		if elapsed <= 0 {
+			return
+		}
+
+		if r.isNearLinkCapacity(ackedBitrate) {
+			// additive increase of about a packet per round trip
+			bitsPerRTT := float64(r.config.AdditiveIncreasePacketSize * 8)
+			r.delayBasedEstimate += int64(bitsPerRTT * float64(elapsed) / float64(max(rttMicro, 1)))
+		} else {
+			factor := math.Pow(1.0+r.config.MultiplicativeIncreaseRate, float64(elapsed)/1e6)
+			r.delayBasedEstimate = int64(factor * float64(r.delayBasedEstimate))
+		}
+
+		if ackedBitrate != 0 {
+			r.delayBasedEstimate = min(r.delayBasedEstimate, int64(r.config.MaxAckedBitrateRatio*float64(ackedBitrate))+cMinEstimate)
+		}
+	}
+}
+
+func (r *rateController) updateLossBased(lossFraction float64) {
+	switch {
+	case lossFraction > r.config.HighLoss:
+		r.lossBasedEstimate = max(int64(float64(r.lossBasedEstimate)*(1.0-0.5*lossFraction)), cMinEstimate)
+
+	case lossFraction < r.config.LowLoss:
+		r.lossBasedEstimate = int64(float64(r.lossBasedEstimate) * r.config.LossIncreaseFactor)
+	}
+
+	// loss based estimate does not lead delay based estimate by much, so that it does not take long to come down on loss
+	r.lossBasedEstimate = min(r.lossBasedEstimate, int64(r.config.LossIncreaseFactor*float64(r.delayBasedEstimate)))
+}
+
+func (r *rateController) updateLinkCapacity(ackedBitrate float64) {
+	if r.linkCapacity == 0 {
+		r.linkCapacity = ackedBitrate
+		return
+	}
+
+	if r.linkCapacityVariance != 0 && math.Abs(ackedBitrate-r.linkCapacity) > cLinkCapacityDeviations*math.Sqrt(r.linkCapacityVariance) {
+		// link capacity changed, start afresh
+		r.linkCapacity = ackedBitrate
+		r.linkCapacityVariance = 0
+		return
+	}
+
+	diff := ackedBitrate - r.linkCapacity
+	r.linkCapacity += cLinkCapacitySmoothing * diff
+	r.linkCapacityVariance = (1-cLinkCapacitySmoothing)*r.linkCapacityVariance + cLinkCapacitySmoothing*diff*diff
+}
+
+func (r *rateController) isNearLinkCapacity(ackedBitrate int64) bool {
+	if r.linkCapacity == 0 || ackedBitrate == 0 {
+		return false
+	}
+
+	deviation := max(cLinkCapacityDeviations*math.Sqrt(r.linkCapacityVariance), 0.1*r.linkCapacity)
+	return math.Abs(float64(ackedBitrate)-r.linkCapacity) < deviation
+}
+
+func (r *rateController) MarshalLogObject(e zapcore.ObjectEncoder) error {
+	if r == nil {
+		return nil
+	}
+
+	e.AddInt64("delayBasedEstimate", r.delayBasedEstimate)
+	e.AddInt64("lossBasedEstimate", r.lossBasedEstimate)
+	e.AddFloat64("linkCapacity", r.linkCapacity)
+	e.AddFloat64("linkCapacityStdDev", math.Sqrt(r.linkCapacityVariance))
+	return nil
+}
@@ -0,0 +1,311 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delaybasedbwe
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// -------------------------------------------------------------------------------
+
+type bandwidthUsage int
+
+const (
+	bandwidthUsageNormal bandwidthUsage = iota
+	bandwidthUsageUnderusing
+	bandwidthUsageOverusing
+)
+
+func (b bandwidthUsage) String() string {
+	switch b {
+	case bandwidthUsageNormal:
+		return "NORMAL"
+	case bandwidthUsageUnderusing:
+		return "UNDERUSING"
+	case bandwidthUsageOverusing:
+		return "OVERUSING"
+	default:
+		return fmt.Sprintf("%d", int(b))
+	}
+}
+
+// -------------------------------------------------------------------------------
+
+type TrendlineConfig struct {
+	// packets sent within this interval are grouped together as a burst, delay variation is measured between bursts
+	BurstInterval time.Duration `yaml:"burst_interval,omitempty"`
+	// number of delay samples used in the linear regression
+	WindowSize int `yaml:"window_size,omitempty"`
+	// smoothing of accumulated delay before regression
+	SmoothingCoefficient float64 `yaml:"smoothing_coefficient,omitempty"`
+	// slope of the regression is scaled by this gain and number of samples before comparing against threshold
+	ThresholdGain float64 `yaml:"threshold_gain,omitempty"`
+
+	// adaptive threshold, in ms, of modified trend
+	InitialThreshold float64 `yaml:"initial_threshold,omitempty"`
+	MinThreshold     float64 `yaml:"min_threshold,omitempty"`
+	MaxThreshold     float64 `yaml:"max_threshold,omitempty"`
+	ThresholdGainUp  float64 `yaml:"threshold_gain_up,omitempty"`
+	// threshold adapts faster when going down
+	ThresholdGainDown float64 `yaml:"threshold_gain_down,omitempty"`
+	// trend has to stay above threshold for this long to signal overuse
+	OveruseTime time.Duration `yaml:"overuse_time,omitempty"`
+}
+
+var (
+	defaultTrendlineConfig = TrendlineConfig{
+		BurstInterval:        5 * time.Millisecond,
+		WindowSize:           20,
+		SmoothingCoefficient: 0.9,
+		ThresholdGain:        4.0,
+
+		InitialThreshold:  12.5,
+		MinThreshold:      6.0,
+		MaxThreshold:      600.0,
+		ThresholdGainUp:   0.0087,
+		ThresholdGainDown: 0.039,
+		OveruseTime:       10 * time.Millisecond,
+	}
+)
+
+const (
+	cMaxNumDeltas               = 60
+	cMaxThresholdAdaptationTime = 100 // ms
+	cMaxThresholdTrendGap       = 15.0
+)
+
+// -------------------------------------------------------------------------------
+
+type burst struct {
+	firstSendTime int64
+	lastSendTime  int64
+	lastRecvTime  int64
+}
+
+// interArrival groups packets into bursts based on send time and
+// gives send/receive time deltas between consecutive bursts
+type interArrival struct {
+	burstInterval int64
+
+	current  *burst
+	previous *burst
+}
+
+func newInterArrival(burstInterval time.Duration) *interArrival {
+	return &interArrival{
+		burstInterval: burstInterval.Microseconds(),
+	}
+}
+
+func (i *interArrival) AddPacket(sendTime int64, recvTime int64) (sendDelta int64, recvDelta int64, ok bool) {
+	if i.current == nil {
+		i.current = &burst{
+			firstSendTime: sendTime,
+			lastSendTime:  sendTime,
+			lastRecvTime:  recvTime,
+		}
+		return
+	}
+
+	if sendTime < i.current.firstSendTime {
+		// re-ordered packet of an older burst
+		return
+	}
+
+	if sendTime-i.current.firstSendTime <= i.burstInterval {
+		i.current.lastSendTime = max(i.current.lastSendTime, sendTime)
+		i.current.lastRecvTime = max(i.current.lastRecvTime, recvTime)
+		return
+	}
+
+	// new burst, current is complete
+	if i.previous != nil {
+		sendDelta = i.current.lastSendTime - i.previous.lastSendTime
+		recvDelta = i.current.lastRecvTime - i.previous.lastRecvTime
+		ok = true
+	}
+	i.previous = i.current
+	i.current = &burst{
+		firstSendTime: sendTime,
+		lastSendTime:  sendTime,
+		lastRecvTime:  recvTime,
+	}
+	return
+}
+
+// -------------------------------------------------------------------------------
+
+type trendSample struct {
+	arrivalTime   float64
+	smoothedDelay float64
+}
+
+// trendlineEstimator estimates the trend of one way delay variation using linear regression over smoothed accumulated delay,
+// a positive trend indicates queues building up along the path
+type trendlineEstimator struct {
+	config TrendlineConfig
+
+	numDeltas        int
+	firstArrivalTime int64
+	accumulatedDelay float64
+	smoothedDelay    float64
+	samples          []trendSample
+
+	modifiedTrend     float64
+	prevModifiedTrend float64
+
+	threshold       float64
+	lastThresholdAt int64
+	timeOverUsing   float64
+	overuseCounter  int
+	bandwidthUsage  bandwidthUsage
+}
+
+func newTrendlineEstimator(config TrendlineConfig) *trendlineEstimator {
+	return &trendlineEstimator{
+		config:          config,
+		threshold:       config.InitialThreshold,
+		timeOverUsing:   -1,
+		lastThresholdAt: -1,
+	}
+}
+
+// Update adds delay variation between two bursts, all times are in micro seconds
+func (t *trendlineEstimator) Update(sendDelta int64, recvDelta int64, arrivalTime int64) bandwidthUsage {
+	t.numDeltas = min(t.numDeltas+1, cMaxNumDeltas)
+	if t.numDeltas == 1 {
+		t.firstArrivalTime = arrivalTime
+	}
+
+	delayMs := float64(recvDelta-sendDelta) / 1000.0
+	t.accumulatedDelay += delayMs
+	t.smoothedDelay = t.config.SmoothingCoefficient*t.smoothedDelay + (1.0-t.config.SmoothingCoefficient)*t.accumulatedDelay
+
+	t.samples = append(t.samples, trendSample{
+		arrivalTime:   float64(arrivalTime-t.firstArrivalTime) / 1000.0,
+		smoothedDelay: t.smoothedDelay,
+	})
+	if len(t.samples) > t.config.WindowSize {
+		t.samples = t.samples[1:]
+	}
+
+	trend := t.prevModifiedTrend
+	if len(t.samples) == t.config.WindowSize {
+		if slope, ok := linearFitSlope(t.samples); ok {
+			trend = slope
+		}
+	}
+	t.modifiedTrend = float64(t.numDeltas) * trend * t.config.ThresholdGain
+
+	t.detect(float64(sendDelta)/1000.0, arrivalTime/1000)
+	return t.bandwidthUsage
+}
+
+func (t *trendlineEstimator) detect(sendDeltaMs float64, nowMs int64) {
+	if t.numDeltas < 2 {
+		t.bandwidthUsage = bandwidthUsageNormal
+		return
+	}
+
+	switch {
+	case t.modifiedTrend > t.threshold:
+		if t.timeOverUsing == -1 {
+			// initialize to half the send delta, assuming overuse started half way through the burst
+			t.timeOverUsing = sendDeltaMs / 2
+		} else {
+			t.timeOverUsing += sendDeltaMs
+		}
+		t.overuseCounter++
+		if t.timeOverUsing > float64(t.config.OveruseTime.Milliseconds()) && t.overuseCounter > 1 && t.modifiedTrend >= t.prevModifiedTrend {
+			t.timeOverUsing = 0
+			t.overuseCounter = 0
+			t.bandwidthUsage = bandwidthUsageOverusing
+		}
+
+	case t.modifiedTrend < -t.threshold:
+		t.timeOverUsing = -1
+		t.overuseCounter = 0
+		t.bandwidthUsage = bandwidthUsageUnderusing
+
+	default:
+		t.timeOverUsing = -1
+		t.overuseCounter = 0
+		t.bandwidthUsage = bandwidthUsageNormal
+	}
+	t.prevModifiedTrend = t.modifiedTrend
+
+	t.updateThreshold(nowMs)
+}
+
+func (t *trendlineEstimator) updateThreshold(nowMs int64) {
+	if t.lastThresholdAt == -1 {
+		t.lastThresholdAt = nowMs
+	}
+
+	absTrend := math.Abs(t.modifiedTrend)
+	if absTrend > t.threshold+cMaxThresholdTrendGap {
+		// avoid adapting to spikes, for example, due to route changes
+		t.lastThresholdAt = nowMs
+		return
+	}
+
+	gain := t.config.ThresholdGainUp
+	if absTrend < t.threshold {
+		gain = t.config.ThresholdGainDown
+	}
+	elapsed := min(nowMs-t.lastThresholdAt, cMaxThresholdAdaptationTime)
+	t.threshold += gain * (absTrend - t.threshold) * float64(elapsed)
+	t.threshold = min(max(t.threshold, t.config.MinThreshold), t.config.MaxThreshold)
+	t.lastThresholdAt = nowMs
+}
+
+func (t *trendlineEstimator) MarshalLogObject(e zapcore.ObjectEncoder) error {
+	if t == nil {
+		return nil
+	}
+
+	e.AddInt("numDeltas", t.numDeltas)
+	e.AddFloat64("accumulatedDelay", t.accumulatedDelay)
+	e.AddFloat64("smoothedDelay", t.smoothedDelay)
+	e.AddFloat64("modifiedTrend", t.modifiedTrend)
+	e.AddFloat64("threshold", t.threshold)
+	e.AddString("bandwidthUsage", t.bandwidthUsage.String())
+	return nil
+}
+
+func linearFitSlope(samples []trendSample) (float64, bool) {
+	var sumX, sumY float64
+	for _, s := range samples {
+		sumX += s.arrivalTime
+		sumY += s.smoothedDelay
+	}
+	meanX := sumX / float64(len(samples))
+	meanY := sumY / float64(len(samples))
+
+	var numerator, denominator float64
+	for _, s := range samples {
+		dx := s.arrivalTime - meanX
+		numerator += dx * (s.smoothedDelay - meanY)
+		denominator += dx * dx
+	}
+	if denominator == 0 {
+		return 0, false
+	}
+	return numerator / denominator, true
+}
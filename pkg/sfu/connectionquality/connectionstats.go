@@ -113,6 +113,11 @@ func (cs *ConnectionStats) UpdateCodec(codecMimeType mime.MimeType, isFECEnabled
 	cs.scorer.UpdatePacketLossWeight(getPacketLossWeight(codecMimeType, isFECEnabled))
 }
 
+// SetIncludeRTT enables RTT in scoring, used in the up stream once RTT is measured using RTCP-XR
+func (cs *ConnectionStats) SetIncludeRTT(includeRTT bool) {
+	cs.scorer.SetIncludeRTT(includeRTT)
+}
+
 func (cs *ConnectionStats) OnStatsUpdate(fn func(cs *ConnectionStats, stat *livekit.AnalyticsStat)) {
 	cs.onStatsUpdate = fn
 }
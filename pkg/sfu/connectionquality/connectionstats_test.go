@@ -505,6 +505,25 @@ func TestConnectionQuality(t *testing.T) {
 		mos, quality := cs.GetScoreAndQuality()
 		require.Greater(t, float32(4.6), mos)
 		require.Equal(t, livekit.ConnectionQuality_EXCELLENT, quality)
+
+		// once RTT is measured independently (for example, using RTCP-XR in the up stream), it should be taken into account
+		cs.SetIncludeRTT(true)
+		now = now.Add(duration)
+		trp.setStreams(map[uint32]*buffer.StreamStatsWithLayers{
+			1: {
+				RTPStats: &rtpstats.RTPDeltaInfo{
+					StartTime:   now,
+					EndTime:     now.Add(duration),
+					Packets:     250,
+					PacketsLost: 5,
+					RttMax:      700,
+				},
+			},
+		})
+		cs.updateScoreAt(now.Add(duration))
+		mos, quality = cs.GetScoreAndQuality()
+		require.Greater(t, float32(4.1), mos)
+		require.Equal(t, livekit.ConnectionQuality_GOOD, quality)
 	})
 
 	t.Run("quality scorer dependent jitter", func(t *testing.T) {
@@ -75,7 +75,8 @@ func (w *windowStat) calculatePacketScore(aplw float64, includeRTT bool, include
 	effectiveDelay := 0.0
 	// discount the dependent factors if dependency indicated.
 	// for example,
-	// 1. in the up stream, RTT cannot be measured without RTCP-XR, it is using down stream RTT.
+	// 1. in the up stream, RTT cannot be measured without RTCP-XR, it is using down stream RTT till
+	//    publisher responds to RTCP-XR Receiver Reference Time reports with DLRR.
 	// 2. in the down stream, up stream jitter affects it. although jitter can be adjusted to account for up stream
 	//    jitter, this lever can be used to discount jitter in scoring.
 	if includeRTT {
@@ -244,6 +245,13 @@ func (q *qualityScorer) startAtLocked(packetLossWeight float64, at time.Time) {
 	q.lastUpdateAt = at
 }
 
+func (q *qualityScorer) SetIncludeRTT(includeRTT bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.params.IncludeRTT = includeRTT
+}
+
 func (q *qualityScorer) StartAt(packetLossWeight float64, at time.Time) {
 	q.lock.Lock()
 	defer q.lock.Unlock()
@@ -242,6 +242,9 @@ type DownTrackStreamAllocatorListener interface {
 	// stream resumed
 	OnResume(dt *DownTrack)
 
+	// FEC protection level changed
+	OnFECProtectionChanged(dt *DownTrack)
+
 	// check if track should participate in BWE
 	IsBWEEnabled(dt *DownTrack) bool
 
@@ -309,6 +312,7 @@ type DownTrackParams struct {
 	RTCPWriter                     func([]rtcp.Packet) error
 	DisableSenderReportPassThrough bool
 	SupportsCodecChange            bool
+	FECConfig                      FECConfig
 	Listener                       DownTrackListener
 }
 
@@ -330,6 +334,11 @@ type DownTrack struct {
 	payloadTypeRTX    atomic.Uint32
 	sequencer         *sequencer
 	rtxSequenceNumber atomic.Uint64
+	ssrcFEC           uint32
+	fecGenerator      *FECGenerator
+	// set with fecGenerator, bound once to avoid allocating a method value per packet
+	onFECProtectedPacketSent func(hdr *rtp.Header, payload []byte)
+	fecPacketsSent           atomic.Uint32
 
 	receiverLock sync.RWMutex
 	receiver     TrackReceiver
@@ -600,6 +609,7 @@ func (d *DownTrack) Bind(t webrtc.TrackLocalContext) (webrtc.RTPCodecParameters,
 			"matchCodec", codec,
 			"ssrc", t.SSRC(),
 			"ssrcRTX", t.SSRCRetransmission(),
+			"ssrcFEC", t.SSRCForwardErrorCorrection(),
 			"isFECEnabled", isFECEnabled,
 		}
 		if d.isRED {
@@ -615,6 +625,14 @@ func (d *DownTrack) Bind(t webrtc.TrackLocalContext) (webrtc.RTPCodecParameters,
 		d.ssrcRTX = uint32(t.SSRCRetransmission())
 		d.payloadType.Store(uint32(codec.PayloadType))
 		d.payloadTypeRTX.Store(uint32(utils.FindRTXPayloadType(codec.PayloadType, d.negotiatedCodecParameters)))
+		if d.kind == webrtc.RTPCodecTypeVideo && d.params.FECConfig.Enabled && t.SSRCForwardErrorCorrection() != 0 {
+			if payloadTypeFEC := findFlexFECPayloadType(d.negotiatedCodecParameters); payloadTypeFEC != 0 {
+				d.ssrcFEC = uint32(t.SSRCForwardErrorCorrection())
+				d.fecGenerator = NewFECGenerator(d.params.FECConfig, payloadTypeFEC, d.ssrcFEC)
+				d.onFECProtectedPacketSent = d.writeFECForPacket
+				logFields = append(logFields, "payloadTypeFEC", payloadTypeFEC)
+			}
+		}
 		logFields = append(
 			logFields,
 			"payloadType", d.payloadType.Load(),
@@ -895,7 +913,7 @@ func (d *DownTrack) setRTPHeaderExtensions() {
 		case pd.PlayoutDelayURI:
 			d.playoutDelayExtID = ext.ID
 		case sdp.TransportCCURI:
-			if isBWEEnabled && bweType == bwe.BWETypeSendSide {
+			if isBWEEnabled && (bweType == bwe.BWETypeSendSide || bweType == bwe.BWETypeDelayBased) {
 				d.transportWideExtID = ext.ID
 			} else {
 				d.transportWideExtID = 0
@@ -968,6 +986,8 @@ func (d *DownTrack) keyFrameRequester() {
 
 	defer timer.Stop()
 
+	// layer primed from the key frame cache of the receiver, falls back to PLI if it does not lock
+	primedLayer := buffer.InvalidLayerSpatial
 	for !d.IsClosed() {
 		timer.Reset(getInterval())
 
@@ -983,7 +1003,16 @@ func (d *DownTrack) keyFrameRequester() {
 		}
 
 		locked, layer := d.forwarder.CheckSync()
+		if locked {
+			primedLayer = buffer.InvalidLayerSpatial
+		}
 		if !locked && layer != buffer.InvalidLayerSpatial && d.writable.Load() {
+			if layer != primedLayer && d.Receiver().PrimeKeyFrame(layer, d) {
+				d.params.Logger.Debugw("priming from key frame cache for layer lock", "layer", layer)
+				primedLayer = layer
+				continue
+			}
+
 			d.params.Logger.Debugw("sending PLI for layer lock", "layer", layer)
 			d.Receiver().SendPLI(layer, false)
 			d.rtpStats.UpdateLayerLockPliAndTime(1)
@@ -1145,11 +1174,14 @@ func (d *DownTrack) WriteRTP(extPkt *buffer.ExtPacket, layer int32) int32 {
 		HeaderSize:         headerSize,
 		Payload:            payload,
 		ProbeClusterId:     ccutils.ProbeClusterId(d.probeClusterId.Load()),
+		IsAudio:            d.kind == webrtc.RTPCodecTypeAudio,
+		IsKeyFrame:         extPkt.IsKeyFrame,
 		AbsSendTimeExtID:   uint8(d.absSendTimeExtID),
 		TransportWideExtID: uint8(d.transportWideExtID),
 		WriteStream:        d.writeStream,
 		Pool:               PacketFactory,
 		PoolEntity:         poolEntity,
+		OnSent:             d.onFECProtectedPacketSent,
 	}
 	d.pacer.Enqueue(pacerPacket)
 
@@ -1598,6 +1630,11 @@ func (d *DownTrack) BandwidthRequested() int64 {
 	return d.forwarder.BandwidthRequested(brs)
 }
 
+func (d *DownTrack) OptimalBandwidthNeeded() int64 {
+	_, brs := d.Receiver().GetLayeredBitrate()
+	return d.forwarder.GetOptimalBandwidthNeeded(brs)
+}
+
 func (d *DownTrack) DistanceToDesired() float64 {
 	al, brs := d.Receiver().GetLayeredBitrate()
 	return d.forwarder.DistanceToDesired(al, brs)
@@ -1827,6 +1864,7 @@ func (d *DownTrack) writeBlankFrameRTP(duration float32, generation uint32) chan
 					HeaderSize:         headerSize,
 					Payload:            payload,
 					ProbeClusterId:     ccutils.ProbeClusterId(d.probeClusterId.Load()),
+					IsAudio:            d.kind == webrtc.RTPCodecTypeAudio,
 					AbsSendTimeExtID:   uint8(d.absSendTimeExtID),
 					TransportWideExtID: uint8(d.transportWideExtID),
 					WriteStream:        d.writeStream,
@@ -1980,6 +2018,17 @@ func (d *DownTrack) handleRTCP(bytes []byte) {
 					rttToReport = rtt
 				}
 
+				if d.fecGenerator != nil && d.fecGenerator.UpdateLoss(r.FractionLost) {
+					d.params.Logger.Debugw(
+						"FEC protection changed",
+						"fractionLost", r.FractionLost,
+						"protection", d.fecGenerator.Protection(),
+					)
+					if sal := d.getStreamAllocatorListener(); sal != nil {
+						sal.OnFECProtectionChanged(d)
+					}
+				}
+
 				if d.playoutDelay != nil {
 					d.playoutDelay.OnSeqAcked(uint16(r.LastSequenceNumber))
 					// screen share track has inaccuracy jitter due to its low frame rate and bursty traffic
@@ -2229,6 +2278,52 @@ func (d *DownTrack) retransmitPacket(epm *extPacketMeta, sourcePkt []byte, isPro
 	return headerSize + len(payload), nil
 }
 
+// writeFECForPacket protects a media packet as sent, FEC has to cover the header extensions
+// written by the pacer as the subscriber recovers packets from the bytes on the wire
+func (d *DownTrack) writeFECForPacket(hdr *rtp.Header, payload []byte) {
+	d.writeFECPackets(d.fecGenerator.Push(hdr, payload))
+}
+
+func (d *DownTrack) writeFECPackets(fecPackets []rtp.Packet) {
+	for _, fecPacket := range fecPackets {
+		hdr := RTPHeaderFactory.Get().(*rtp.Header)
+		*hdr = fecPacket.Header
+		d.addDummyExtensions(hdr)
+
+		pacerPacket := pacer.PacketFactory.Get().(*pacer.Packet)
+		*pacerPacket = pacer.Packet{
+			Header:             hdr,
+			HeaderPool:         RTPHeaderFactory,
+			HeaderSize:         hdr.MarshalSize(),
+			Payload:            fecPacket.Payload,
+			ProbeClusterId:     ccutils.ProbeClusterId(d.probeClusterId.Load()),
+			AbsSendTimeExtID:   uint8(d.absSendTimeExtID),
+			TransportWideExtID: uint8(d.transportWideExtID),
+			WriteStream:        d.writeStream,
+		}
+		d.pacer.Enqueue(pacerPacket)
+	}
+	d.fecPacketsSent.Add(uint32(len(fecPackets)))
+}
+
+// FECBandwidthNeeded returns the bandwidth used by FEC packets protecting the allocated layers
+func (d *DownTrack) FECBandwidthNeeded() int64 {
+	if d.fecGenerator == nil {
+		return 0
+	}
+
+	return int64(float64(d.BandwidthRequested()) * d.fecGenerator.Protection())
+}
+
+func findFlexFECPayloadType(codecs []webrtc.RTPCodecParameters) uint8 {
+	for _, c := range codecs {
+		if mime.IsMimeTypeStringEqual(c.MimeType, webrtc.MimeTypeFlexFEC03) {
+			return uint8(c.PayloadType)
+		}
+	}
+	return 0
+}
+
 func (d *DownTrack) retransmitPackets(nacks []uint16) {
 	if d.sequencer == nil {
 		return
@@ -2414,6 +2509,10 @@ func (d *DownTrack) DebugInfo() map[string]any {
 		stats["RTPTime"] = senderReport.RTPTime
 		stats["PacketCount"] = senderReport.PacketCount
 	}
+	if d.fecGenerator != nil {
+		stats["FECProtection"] = d.fecGenerator.Protection()
+		stats["FECPacketsSent"] = d.fecPacketsSent.Load()
+	}
 
 	return map[string]any{
 		"SubscriberID":        d.params.SubID,
@@ -2587,6 +2686,7 @@ func (d *DownTrack) sendSilentFrameOnMuteForAudio() {
 				HeaderSize:         headerSize,
 				Payload:            payload,
 				ProbeClusterId:     ccutils.ProbeClusterId(d.probeClusterId.Load()),
+				IsAudio:            d.kind == webrtc.RTPCodecTypeAudio,
 				AbsSendTimeExtID:   uint8(d.absSendTimeExtID),
 				TransportWideExtID: uint8(d.transportWideExtID),
 				WriteStream:        d.writeStream,
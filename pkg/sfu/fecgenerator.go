@@ -0,0 +1,144 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sfu
+
+import (
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/pion/interceptor/pkg/flexfec"
+	"github.com/pion/rtp"
+)
+
+type FECConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// FEC is not generated for subscribers with loss fraction below this
+	MinLoss float64 `yaml:"min_loss,omitempty"`
+	// FEC packets per media packet is the loss fraction scaled by this multiplier
+	LossMultiplier float64 `yaml:"loss_multiplier,omitempty"`
+	// max FEC packets per media packet
+	MaxProtection float64 `yaml:"max_protection,omitempty"`
+	// max media packets protected together, a group is also closed at the end of a frame
+	MaxGroupSize int `yaml:"max_group_size,omitempty"`
+}
+
+var (
+	DefaultFECConfig = FECConfig{
+		Enabled:        false,
+		MinLoss:        0.02,
+		LossMultiplier: 2.0,
+		MaxProtection:  0.5,
+		MaxGroupSize:   12,
+	}
+)
+
+const (
+	// weight of latest loss when loss goes down, loss going up is applied immediately
+	fecLossDecayFactor = 0.25
+	// protection levels are quantized to avoid re-allocating on every receiver report
+	fecProtectionStep = 0.05
+)
+
+// --------------------------------------
+
+// FECGenerator generates FlexFEC-03 packets protecting the media packets of a down track.
+// Protection level follows the loss reported by the subscriber.
+type FECGenerator struct {
+	config FECConfig
+
+	lock         sync.Mutex
+	encoder      flexfec.FlexEncoder
+	smoothedLoss float64
+	protection   float64
+	mediaPackets []rtp.Packet
+}
+
+func NewFECGenerator(config FECConfig, payloadType uint8, ssrc uint32) *FECGenerator {
+	return &FECGenerator{
+		config:  config,
+		encoder: flexfec.NewFlexEncoder03(payloadType, ssrc),
+	}
+}
+
+// UpdateLoss updates protection level with fraction lost from a receiver report, returns true if protection changed
+func (f *FECGenerator) UpdateLoss(fractionLost uint8) bool {
+	loss := float64(fractionLost) / 256.0
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if loss > f.smoothedLoss {
+		f.smoothedLoss = loss
+	} else {
+		f.smoothedLoss += fecLossDecayFactor * (loss - f.smoothedLoss)
+	}
+
+	protection := 0.0
+	if f.smoothedLoss >= f.config.MinLoss {
+		protection = math.Ceil(f.smoothedLoss*f.config.LossMultiplier/fecProtectionStep) * fecProtectionStep
+		protection = min(protection, f.config.MaxProtection)
+	}
+	if protection == f.protection {
+		return false
+	}
+
+	f.protection = protection
+	if protection == 0 {
+		f.mediaPackets = f.mediaPackets[:0]
+	}
+	return true
+}
+
+// Protection returns FEC packets sent per media packet
+func (f *FECGenerator) Protection() float64 {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.protection
+}
+
+// Push adds a media packet as sent, i. e. with all header extensions, and returns FEC packets when a
+// group of media packets is complete
+func (f *FECGenerator) Push(hdr *rtp.Header, payload []byte) []rtp.Packet {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.protection == 0 {
+		return nil
+	}
+
+	var fecPackets []rtp.Packet
+	if n := len(f.mediaPackets); n != 0 && f.mediaPackets[n-1].SequenceNumber+1 != hdr.SequenceNumber {
+		// packets of a group have to be consecutive, close the group on a gap, for example, due to padding
+		fecPackets = f.encodeLocked()
+	}
+
+	f.mediaPackets = append(f.mediaPackets, rtp.Packet{
+		Header:  hdr.Clone(),
+		Payload: slices.Clone(payload),
+	})
+	if hdr.Marker || len(f.mediaPackets) >= f.config.MaxGroupSize {
+		fecPackets = append(fecPackets, f.encodeLocked()...)
+	}
+	return fecPackets
+}
+
+func (f *FECGenerator) encodeLocked() []rtp.Packet {
+	numFECPackets := uint32(math.Ceil(float64(len(f.mediaPackets)) * f.protection))
+	fecPackets := f.encoder.EncodeFec(f.mediaPackets, numFECPackets)
+	f.mediaPackets = f.mediaPackets[:0]
+	return fecPackets
+}
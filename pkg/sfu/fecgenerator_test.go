@@ -0,0 +1,186 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sfu
+
+import (
+	"slices"
+	"testing"
+	"unsafe"
+
+	"github.com/pion/logging"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/sfu/pacer"
+	"github.com/livekit/protocol/logger"
+)
+
+func TestFECGenerator(t *testing.T) {
+	f := NewFECGenerator(DefaultFECConfig, 118, 0x1234)
+
+	push := func(sn uint16, marker bool) []rtp.Packet {
+		return f.Push(&rtp.Header{
+			Version:        2,
+			PayloadType:    96,
+			SequenceNumber: sn,
+			Timestamp:      3000,
+			SSRC:           0x5678,
+			Marker:         marker,
+		}, make([]byte, 100))
+	}
+
+	// no FEC without loss
+	require.False(t, f.UpdateLoss(0))
+	require.Nil(t, push(1, true))
+
+	// loss below threshold
+	require.False(t, f.UpdateLoss(2))
+	require.Zero(t, f.Protection())
+
+	// 10% loss -> 20% protection, rounded up to a step
+	require.True(t, f.UpdateLoss(26))
+	require.InDelta(t, 0.25, f.Protection(), 0.001)
+
+	// group closes at end of frame
+	require.Nil(t, push(10, false))
+	require.Nil(t, push(11, false))
+	require.Nil(t, push(12, false))
+	fecPackets := push(13, true)
+	require.Len(t, fecPackets, 1)
+	require.EqualValues(t, 118, fecPackets[0].PayloadType)
+	require.EqualValues(t, 0x1234, fecPackets[0].SSRC)
+
+	// gap in sequence numbers closes group
+	require.Nil(t, push(14, false))
+	require.Len(t, push(16, false), 1)
+
+	// group closes at max size
+	var numFECPackets int
+	for sn := uint16(17); sn < 17+uint16(DefaultFECConfig.MaxGroupSize); sn++ {
+		numFECPackets += len(push(sn, false))
+	}
+	require.Equal(t, 3, numFECPackets)
+
+	// protection capped
+	require.True(t, f.UpdateLoss(255))
+	require.InDelta(t, DefaultFECConfig.MaxProtection, f.Protection(), 0.001)
+
+	// loss decays slowly
+	require.False(t, f.UpdateLoss(128))
+	require.InDelta(t, DefaultFECConfig.MaxProtection, f.Protection(), 0.001)
+	for range 20 {
+		f.UpdateLoss(0)
+	}
+	require.Zero(t, f.Protection())
+}
+
+// pion's FlexFEC-03 decoder is not exported, it is the reference the generated packets are checked against
+//
+//go:linkname newFlexFECDecoder github.com/pion/interceptor/pkg/flexfec.newFECDecoder
+func newFlexFECDecoder(ssrc uint32, protectedStreamSSRC uint32, loggerFactory logging.LoggerFactory) unsafe.Pointer
+
+//go:linkname decodeFlexFEC github.com/pion/interceptor/pkg/flexfec.(*fecDecoder).DecodeFec
+func decodeFlexFEC(decoder unsafe.Pointer, receivedPacket rtp.Packet) []rtp.Packet
+
+type fecTestWriteStream struct {
+	packets [][]byte
+}
+
+func (w *fecTestWriteStream) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	buf, err := (&rtp.Packet{Header: *header, Payload: payload}).Marshal()
+	if err != nil {
+		return 0, err
+	}
+	w.packets = append(w.packets, buf)
+	return len(buf), nil
+}
+
+func (w *fecTestWriteStream) Write(b []byte) (int, error) {
+	w.packets = append(w.packets, slices.Clone(b))
+	return len(b), nil
+}
+
+func TestFECGeneratorRecovery(t *testing.T) {
+	const (
+		absSendTimeExtID = 3
+		ssrcFEC          = 0x1234
+		ssrc             = 0x5678
+	)
+
+	f := NewFECGenerator(DefaultFECConfig, 118, ssrcFEC)
+	require.True(t, f.UpdateLoss(64))
+
+	w := &fecTestWriteStream{}
+	p := pacer.NewPassThrough(logger.GetLogger(), nil)
+	enqueue := func(hdr *rtp.Header, payload []byte, onSent func(hdr *rtp.Header, payload []byte)) {
+		pkt := pacer.PacketFactory.Get().(*pacer.Packet)
+		*pkt = pacer.Packet{
+			Header:           hdr,
+			HeaderSize:       hdr.MarshalSize(),
+			Payload:          payload,
+			AbsSendTimeExtID: absSendTimeExtID,
+			WriteStream:      w,
+			OnSent:           onSent,
+		}
+		p.Enqueue(pkt)
+	}
+	onSent := func(hdr *rtp.Header, payload []byte) {
+		for _, fecPacket := range f.Push(hdr, payload) {
+			enqueue(&fecPacket.Header, fecPacket.Payload, nil)
+		}
+	}
+
+	for sn := uint16(100); sn < 104; sn++ {
+		hdr := &rtp.Header{
+			Version:        2,
+			PayloadType:    96,
+			SequenceNumber: sn,
+			Timestamp:      3000,
+			SSRC:           ssrc,
+			Marker:         sn == 103,
+		}
+		// dummy extension as added by the down track, the pacer writes the actual send time
+		require.NoError(t, hdr.SetExtension(absSendTimeExtID, make([]byte, 3)))
+		payload := make([]byte, 100+int(sn))
+		for i := range payload {
+			payload[i] = byte(int(sn) + i)
+		}
+		enqueue(hdr, payload, onSent)
+	}
+
+	// four media packets at 50% protection followed by two FEC packets
+	require.Len(t, w.packets, 6)
+
+	var lost []byte
+	var recovered []rtp.Packet
+	decoder := newFlexFECDecoder(ssrcFEC, ssrc, logging.NewDefaultLoggerFactory())
+	for _, buf := range w.packets {
+		var pkt rtp.Packet
+		require.NoError(t, pkt.Unmarshal(buf))
+		if pkt.SSRC == ssrc {
+			require.NotEqual(t, make([]byte, 3), pkt.GetExtension(absSendTimeExtID))
+			if pkt.SequenceNumber == 101 {
+				lost = buf
+				continue
+			}
+		}
+		recovered = append(recovered, decodeFlexFEC(decoder, pkt)...)
+	}
+
+	require.Len(t, recovered, 1)
+	buf, err := recovered[0].Marshal()
+	require.NoError(t, err)
+	require.Equal(t, lost, buf)
+}
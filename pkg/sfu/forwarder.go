@@ -36,6 +36,7 @@ import (
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
 	"github.com/livekit/livekit-server/pkg/sfu/codecmunger"
 	dd "github.com/livekit/livekit-server/pkg/sfu/rtpextension/dependencydescriptor"
+	fm "github.com/livekit/livekit-server/pkg/sfu/rtpextension/framemarking"
 	"github.com/livekit/livekit-server/pkg/sfu/rtpstats"
 	sfuutils "github.com/livekit/livekit-server/pkg/sfu/utils"
 	"github.com/livekit/livekit-server/pkg/sfu/videolayerselector"
@@ -238,6 +239,7 @@ type Forwarder struct {
 	refInfos                 [buffer.DefaultMaxLayerSpatial + 1]refInfo
 	refVideoLayerMode        livekit.VideoLayer_Mode
 	isDDAvailable            bool
+	isTemporalFilterEnabled  bool
 
 	provisional *VideoAllocationProvisional
 
@@ -331,6 +333,16 @@ func (f *Forwarder) DetermineCodec(codec webrtc.RTPCodecCapability, extensions [
 		}
 		return false
 	}
+	frameMarkingAvailable := func(exts []webrtc.RTPHeaderExtensionParameter) bool {
+		for _, ext := range exts {
+			if ext.URI == fm.FrameMarkingURI {
+				return true
+			}
+		}
+		return false
+	}
+
+	f.isTemporalFilterEnabled = false
 
 	switch f.mime {
 	case mime.MimeTypeVP8:
@@ -357,6 +369,15 @@ func (f *Forwarder) DetermineCodec(codec webrtc.RTPCodecCapability, extensions [
 		} else {
 			f.vls = videolayerselector.NewSimulcast(f.logger)
 		}
+		// temporal layers can be selected only if switching points are signalled,
+		// filtering is done by forwarder as there is no codec munger to do it,
+		// packets which do not carry the signalling although negotiated are passed through
+		f.isTemporalFilterEnabled = frameMarkingAvailable(extensions) || ddAvailable(extensions)
+		if f.isTemporalFilterEnabled {
+			f.vls.SetTemporalLayerSelector(temporallayerselector.NewFrameMarking(f.logger))
+		} else {
+			f.vls.SetTemporalLayerSelector(nil)
+		}
 
 	case mime.MimeTypeVP9:
 		f.codecMunger = codecmunger.NewNull(f.logger)
@@ -1587,7 +1608,7 @@ func (f *Forwarder) Pause(availableLayers []int32, brs Bitrates) VideoAllocation
 
 func (f *Forwarder) updateAllocation(alloc VideoAllocation, reason string) VideoAllocation {
 	// restrict target temporal to 0 if codec does not support temporal layers
-	if alloc.TargetLayer.IsValid() && f.mime == mime.MimeTypeH264 {
+	if alloc.TargetLayer.IsValid() && f.mime == mime.MimeTypeH264 && !f.isTemporalFilterEnabled {
 		alloc.TargetLayer.Temporal = 0
 	}
 
@@ -2154,6 +2175,13 @@ func (f *Forwarder) getTranslationParamsVideo(extPkt *buffer.ExtPacket, layer in
 func (f *Forwarder) translateCodecHeader(extPkt *buffer.ExtPacket, tp *TranslationParams) error {
 	// codec specific forwarding check and any needed packet munging
 	tl := f.vls.SelectTemporal(extPkt)
+	if f.isTemporalFilterEnabled && hasSwitchingPointSignalling(extPkt) && extPkt.Temporal > tl {
+		// filtered temporal layer, update sequence number offset to prevent holes
+		tp.shouldDrop = true
+		f.rtpMunger.PacketDropped(extPkt)
+		return nil
+	}
+
 	inputSize, codecBytes, err := f.codecMunger.UpdateAndGet(
 		extPkt,
 		tp.rtp.snOrdering == SequenceNumberOrderingOutOfOrder,
@@ -2397,3 +2425,9 @@ done:
 
 	return float64(distance) / float64(maxSeenLayer.Temporal+1)
 }
+
+// hasSwitchingPointSignalling returns whether the packet carries frame marking or dependency descriptor,
+// h.265 packets always have a temporal layer from the payload header, but switching points only with those
+func hasSwitchingPointSignalling(extPkt *buffer.ExtPacket) bool {
+	return extPkt.FrameMarking != nil || extPkt.DependencyDescriptor != nil
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/livekit/protocol/logger"
 
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	fm "github.com/livekit/livekit-server/pkg/sfu/rtpextension/framemarking"
 	"github.com/livekit/livekit-server/pkg/sfu/testutils"
 )
 
@@ -1193,6 +1194,79 @@ func TestForwarderAllocateNextHigher(t *testing.T) {
 	require.True(t, boosted)
 }
 
+func TestForwarderTemporalFilterH264(t *testing.T) {
+	h264Codec := webrtc.RTPCodecCapability{
+		MimeType:  "video/H264",
+		ClockRate: 90000,
+	}
+
+	// without signalling of switching points, temporal layer is restricted to 0
+	f := newForwarder(h264Codec, webrtc.RTPCodecTypeVideo)
+	require.False(t, f.isTemporalFilterEnabled)
+	alloc := f.updateAllocation(VideoAllocation{TargetLayer: buffer.VideoLayer{Spatial: 1, Temporal: 2}}, "test")
+	require.Equal(t, buffer.VideoLayer{Spatial: 1, Temporal: 0}, alloc.TargetLayer)
+
+	// frame marking enables temporal layer selection
+	f.DetermineCodec(
+		h264Codec,
+		[]webrtc.RTPHeaderExtensionParameter{{URI: fm.FrameMarkingURI, ID: 5}},
+		livekit.VideoLayer_MODE_UNUSED,
+	)
+	require.True(t, f.isTemporalFilterEnabled)
+	alloc = f.updateAllocation(VideoAllocation{TargetLayer: buffer.VideoLayer{Spatial: 1, Temporal: 2}}, "test")
+	require.Equal(t, buffer.VideoLayer{Spatial: 1, Temporal: 2}, alloc.TargetLayer)
+
+	// packets of temporal layers above current are dropped
+	f.vls.SetTarget(buffer.VideoLayer{Spatial: 0, Temporal: 0})
+	f.vls.SetCurrent(buffer.VideoLayer{Spatial: 0, Temporal: 0})
+	extPkt, _ := testutils.GetTestExtPacket(&testutils.TestExtPacketParams{
+		SequenceNumber: 23333,
+		Timestamp:      0xabcdef,
+		SSRC:           0x12345678,
+		PayloadSize:    20,
+		VideoLayer:     buffer.VideoLayer{Spatial: 0, Temporal: 1},
+	})
+	extPkt.FrameMarking = &fm.FrameMarking{TID: 1}
+	tp := TranslationParams{}
+	require.NoError(t, f.translateCodecHeader(extPkt, &tp))
+	require.True(t, tp.shouldDrop)
+
+	extPkt.Temporal = 0
+	tp = TranslationParams{}
+	require.NoError(t, f.translateCodecHeader(extPkt, &tp))
+	require.False(t, tp.shouldDrop)
+}
+
+func TestForwarderTemporalFilterH265WithoutSignalling(t *testing.T) {
+	h265Codec := webrtc.RTPCodecCapability{
+		MimeType:  "video/H265",
+		ClockRate: 90000,
+	}
+
+	// frame marking is negotiated, but not sent by the publisher
+	f := newForwarder(h265Codec, webrtc.RTPCodecTypeVideo)
+	f.DetermineCodec(
+		h265Codec,
+		[]webrtc.RTPHeaderExtensionParameter{{URI: fm.FrameMarkingURI, ID: 5}},
+		livekit.VideoLayer_MODE_UNUSED,
+	)
+	require.True(t, f.isTemporalFilterEnabled)
+	f.vls.SetTarget(buffer.VideoLayer{Spatial: 0, Temporal: 2})
+	f.vls.SetCurrent(buffer.VideoLayer{Spatial: 0, Temporal: 0})
+
+	// temporal layer from the payload header is passed through without switching points
+	extPkt, _ := testutils.GetTestExtPacket(&testutils.TestExtPacketParams{
+		SequenceNumber: 23333,
+		Timestamp:      0xabcdef,
+		SSRC:           0x12345678,
+		PayloadSize:    20,
+		VideoLayer:     buffer.VideoLayer{Spatial: 0, Temporal: 2},
+	})
+	tp := TranslationParams{}
+	require.NoError(t, f.translateCodecHeader(extPkt, &tp))
+	require.False(t, tp.shouldDrop)
+}
+
 func TestForwarderPause(t *testing.T) {
 	f := newForwarder(testutils.TestVP8Codec, webrtc.RTPCodecTypeVideo)
 	f.SetMaxSpatialLayer(buffer.DefaultMaxLayerSpatial)
@@ -0,0 +1,159 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sfu
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/pion/rtp"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+type KeyFrameCacheConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// max bytes cached for a layer of a track, a keyframe with its following frames is dropped when it grows beyond this
+	MaxBytesPerLayer int `yaml:"max_bytes_per_layer,omitempty"`
+	// max bytes cached across all tracks of the node
+	MaxBytes int64 `yaml:"max_bytes,omitempty"`
+}
+
+var (
+	DefaultKeyFrameCacheConfig = KeyFrameCacheConfig{
+		Enabled:          false,
+		MaxBytesPerLayer: 2 * 1024 * 1024,
+		MaxBytes:         256 * 1024 * 1024,
+	}
+)
+
+// bytes held by all key frame caches of the node
+var keyFrameCacheBytes atomic.Int64
+
+// --------------------------------------
+
+// KeyFrameCache retains the most recent key frame of a layer along with the frames that followed it,
+// so that a subscriber starting on the layer can be primed without waiting for the publisher to send a key frame.
+// Packets are copied as buffers of the receiver are re-used.
+type KeyFrameCache struct {
+	config KeyFrameCacheConfig
+
+	lock        sync.RWMutex
+	packets     []*buffer.ExtPacket
+	keyFrameTS  uint64
+	cachedBytes int
+}
+
+func NewKeyFrameCache(config KeyFrameCacheConfig) *KeyFrameCache {
+	return &KeyFrameCache{
+		config: config,
+	}
+}
+
+// Add caches a packet if it starts a key frame or follows a cached key frame
+func (k *KeyFrameCache) Add(extPkt *buffer.ExtPacket) {
+	if len(extPkt.Packet.Payload) == 0 {
+		// padding only packet, not needed to decode
+		return
+	}
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if extPkt.IsKeyFrame && (len(k.packets) == 0 || extPkt.ExtTimestamp != k.keyFrameTS) {
+		k.resetLocked()
+		k.keyFrameTS = extPkt.ExtTimestamp
+	} else if len(k.packets) == 0 {
+		return
+	}
+
+	size := len(extPkt.RawPacket)
+	if k.cachedBytes+size > k.config.MaxBytesPerLayer || keyFrameCacheBytes.Load()+int64(size) > k.config.MaxBytes {
+		k.resetLocked()
+		prometheus.RecordKeyFrameCacheDrop()
+		return
+	}
+
+	cachedPkt, err := copyExtPacket(extPkt)
+	if err != nil {
+		k.resetLocked()
+		return
+	}
+	k.packets = append(k.packets, cachedPkt)
+	k.cachedBytes += size
+	keyFrameCacheBytes.Add(int64(size))
+	prometheus.AddKeyFrameCacheBytes(int64(size))
+}
+
+// Packets returns the cached key frame and the packets that followed it, cached packets are not modified,
+// so they can be written to down tracks concurrently
+func (k *KeyFrameCache) Packets() []*buffer.ExtPacket {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	return k.packets
+}
+
+func (k *KeyFrameCache) HasKeyFrame() bool {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	return len(k.packets) != 0
+}
+
+func (k *KeyFrameCache) Reset() {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.resetLocked()
+}
+
+func (k *KeyFrameCache) resetLocked() {
+	if k.cachedBytes != 0 {
+		keyFrameCacheBytes.Sub(int64(k.cachedBytes))
+		prometheus.AddKeyFrameCacheBytes(-int64(k.cachedBytes))
+	}
+	k.packets = nil
+	k.keyFrameTS = 0
+	k.cachedBytes = 0
+}
+
+func copyExtPacket(extPkt *buffer.ExtPacket) (*buffer.ExtPacket, error) {
+	rawPacket := slices.Clone(extPkt.RawPacket)
+	pkt := &rtp.Packet{}
+	if err := pkt.Unmarshal(rawPacket); err != nil {
+		return nil, err
+	}
+
+	cachedPkt := &buffer.ExtPacket{
+		VideoLayer:        extPkt.VideoLayer,
+		Arrival:           extPkt.Arrival,
+		ExtSequenceNumber: extPkt.ExtSequenceNumber,
+		ExtTimestamp:      extPkt.ExtTimestamp,
+		Packet:            pkt,
+		Payload:           extPkt.Payload,
+		IsKeyFrame:        extPkt.IsKeyFrame,
+		RawPacket:         rawPacket,
+		FrameMarking:      extPkt.FrameMarking,
+		IsBuffered:        true,
+	}
+	if extPkt.AbsCaptureTimeExt != nil {
+		act := *extPkt.AbsCaptureTimeExt
+		cachedPkt.AbsCaptureTimeExt = &act
+	}
+	return cachedPkt, nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sfu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/testutils"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+func TestKeyFrameCache(t *testing.T) {
+	prometheus.Init("test", livekit.NodeType_SERVER)
+
+	newPacket := func(sn uint16, ts uint32, isKeyFrame bool) *buffer.ExtPacket {
+		extPkt, err := testutils.GetTestExtPacket(&testutils.TestExtPacketParams{
+			IsKeyFrame:     isKeyFrame,
+			SequenceNumber: sn,
+			Timestamp:      ts,
+			PayloadSize:    100,
+		})
+		require.NoError(t, err)
+		return extPkt
+	}
+	sequenceNumbers := func(packets []*buffer.ExtPacket) []uint64 {
+		var sns []uint64
+		for _, pkt := range packets {
+			sns = append(sns, pkt.ExtSequenceNumber)
+		}
+		return sns
+	}
+
+	k := NewKeyFrameCache(KeyFrameCacheConfig{
+		Enabled:          true,
+		MaxBytesPerLayer: 1000,
+		MaxBytes:         1 << 20,
+	})
+
+	// nothing cached until a key frame
+	k.Add(newPacket(1, 1000, false))
+	require.False(t, k.HasKeyFrame())
+
+	// key frame spanning two packets and a following frame
+	k.Add(newPacket(2, 2000, true))
+	k.Add(newPacket(3, 2000, true))
+	k.Add(newPacket(4, 3000, false))
+	require.True(t, k.HasKeyFrame())
+	require.Equal(t, []uint64{2, 3, 4}, sequenceNumbers(k.Packets()))
+
+	// cached packets are copies, not affected by re-use of receiver buffers
+	extPkt := newPacket(5, 4000, false)
+	k.Add(extPkt)
+	extPkt.RawPacket[2] = 0xff
+	extPkt.Packet.SequenceNumber = 100
+	cached := k.Packets()[3]
+	require.EqualValues(t, 5, cached.Packet.SequenceNumber)
+	require.NotEqual(t, byte(0xff), cached.RawPacket[2])
+
+	// new key frame replaces cached packets
+	packets := k.Packets()
+	k.Add(newPacket(6, 5000, true))
+	require.Equal(t, []uint64{6}, sequenceNumbers(k.Packets()))
+	require.Len(t, packets, 4)
+
+	// exceeding memory budget drops key frame till the next one
+	for sn := uint16(7); sn < 20; sn++ {
+		k.Add(newPacket(sn, 6000, false))
+	}
+	require.False(t, k.HasKeyFrame())
+	k.Add(newPacket(20, 7000, false))
+	require.False(t, k.HasKeyFrame())
+	k.Add(newPacket(21, 8000, true))
+	require.Equal(t, []uint64{21}, sequenceNumbers(k.Packets()))
+
+	k.Reset()
+	require.False(t, k.HasKeyFrame())
+	require.Zero(t, keyFrameCacheBytes.Load())
+}
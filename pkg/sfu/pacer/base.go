@@ -56,19 +56,7 @@ func (b *Base) TimeSinceLastSentPacket() time.Duration {
 }
 
 func (b *Base) SendPacket(p *Packet) (int, error) {
-	defer func() {
-		if p.HeaderPool != nil && p.Header != nil {
-			*p.Header = rtp.Header{}
-			p.HeaderPool.Put(p.Header)
-		}
-
-		if p.Pool != nil && p.PoolEntity != nil {
-			p.Pool.Put(p.PoolEntity)
-		}
-
-		*p = Packet{}
-		PacketFactory.Put(p)
-	}()
+	defer b.ReleasePacket(p)
 
 	err := b.patchRTPHeaderExtensions(p)
 	if err != nil {
@@ -85,9 +73,27 @@ func (b *Base) SendPacket(p *Packet) (int, error) {
 		return 0, err
 	}
 
+	if p.OnSent != nil {
+		p.OnSent(p.Header, p.Payload)
+	}
 	return written, nil
 }
 
+// ReleasePacket returns resources held by a packet to their pools, used for packets dropped without sending
+func (b *Base) ReleasePacket(p *Packet) {
+	if p.HeaderPool != nil && p.Header != nil {
+		*p.Header = rtp.Header{}
+		p.HeaderPool.Put(p.Header)
+	}
+
+	if p.Pool != nil && p.PoolEntity != nil {
+		p.Pool.Put(p.PoolEntity)
+	}
+
+	*p = Packet{}
+	PacketFactory.Put(p)
+}
+
 // patch just abs-send-time and transport-cc extensions if applicable
 func (b *Base) patchRTPHeaderExtensions(p *Packet) error {
 	sendingAt := mono.Now()
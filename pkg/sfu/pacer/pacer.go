@@ -15,6 +15,7 @@
 package pacer
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
@@ -39,8 +40,42 @@ const (
 	PacerBehaviorPassThrough PacerBehavior = "pass-through"
 	PacerBehaviorNoQueue     PacerBehavior = "no-queue"
 	PacerBehaviorLeakybucket PacerBehavior = "leaky-bucket"
+	PacerBehaviorPriority    PacerBehavior = "priority"
 )
 
+// --------------------------------------
+
+// PacketClass is the scheduling class of a packet, lower value is higher priority
+type PacketClass int
+
+const (
+	PacketClassAudio PacketClass = iota
+	PacketClassRetransmission
+	PacketClassKeyFrame
+	PacketClassVideo
+	PacketClassPadding
+	numPacketClasses
+)
+
+func (p PacketClass) String() string {
+	switch p {
+	case PacketClassAudio:
+		return "audio"
+	case PacketClassRetransmission:
+		return "retransmission"
+	case PacketClassKeyFrame:
+		return "keyframe"
+	case PacketClassVideo:
+		return "video"
+	case PacketClassPadding:
+		return "padding"
+	default:
+		return fmt.Sprintf("%d", int(p))
+	}
+}
+
+// --------------------------------------
+
 type Packet struct {
 	Header             *rtp.Header
 	HeaderPool         *sync.Pool
@@ -49,11 +84,30 @@ type Packet struct {
 	IsRTX              bool
 	ProbeClusterId     ccutils.ProbeClusterId
 	IsProbe            bool
+	IsAudio            bool
+	IsKeyFrame         bool
 	AbsSendTimeExtID   uint8
 	TransportWideExtID uint8
 	WriteStream        webrtc.TrackLocalWriter
 	Pool               *sync.Pool
 	PoolEntity         *[]byte
+	// called with the packet as written, i. e. with all header extensions, before it is released
+	OnSent func(hdr *rtp.Header, payload []byte)
+}
+
+func (p *Packet) Class() PacketClass {
+	switch {
+	case p.IsProbe:
+		return PacketClassPadding
+	case p.IsRTX:
+		return PacketClassRetransmission
+	case p.IsAudio:
+		return PacketClassAudio
+	case p.IsKeyFrame:
+		return PacketClassKeyFrame
+	default:
+		return PacketClassVideo
+	}
 }
 
 type Pacer interface {
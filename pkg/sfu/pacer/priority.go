@@ -0,0 +1,445 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pacer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+	"github.com/gammazero/deque"
+	"github.com/livekit/livekit-server/pkg/sfu/bwe"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils/mono"
+)
+
+// PriorityBudgets is the maximum share of pacing rate each class can use,
+// a class can use less than its share when higher priority classes use the rate
+type PriorityBudgets struct {
+	Audio          float64 `yaml:"audio,omitempty"`
+	Retransmission float64 `yaml:"retransmission,omitempty"`
+	KeyFrame       float64 `yaml:"keyframe,omitempty"`
+	Video          float64 `yaml:"video,omitempty"`
+	Padding        float64 `yaml:"padding,omitempty"`
+}
+
+func (p PriorityBudgets) share(class PacketClass) float64 {
+	switch class {
+	case PacketClassAudio:
+		return p.Audio
+	case PacketClassRetransmission:
+		return p.Retransmission
+	case PacketClassKeyFrame:
+		return p.KeyFrame
+	case PacketClassVideo:
+		return p.Video
+	case PacketClassPadding:
+		return p.Padding
+	default:
+		return 0
+	}
+}
+
+type PriorityConfig struct {
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// pacing rate is the channel capacity scaled by this factor, there is no pacing till capacity is known
+	PacingFactor float64         `yaml:"pacing_factor,omitempty"`
+	Budgets      PriorityBudgets `yaml:"budgets,omitempty"`
+	// video and padding queued for longer than this are dropped, 0 to disable
+	MaxVideoQueueTime time.Duration `yaml:"max_video_queue_time,omitempty"`
+}
+
+var (
+	DefaultPriorityConfig = PriorityConfig{
+		Interval:     5 * time.Millisecond,
+		PacingFactor: 2.5,
+		Budgets: PriorityBudgets{
+			Audio:          1.0,
+			Retransmission: 0.5,
+			KeyFrame:       1.0,
+			Video:          1.0,
+			Padding:        1.0,
+		},
+		MaxVideoQueueTime: 300 * time.Millisecond,
+	}
+)
+
+// --------------------------------------
+
+type queuedPacket struct {
+	packet     *Packet
+	class      PacketClass
+	enqueuedAt time.Time
+}
+
+// queueKey separates retransmissions from media of the SSRC when they are not sent on an RTX SSRC,
+// so that they do not wait behind media
+type queueKey struct {
+	ssrc  uint32
+	isRTX bool
+}
+
+// ssrcQueue holds packets of an SSRC, they are always sent in the order they were queued
+type ssrcQueue struct {
+	packets deque.Deque[queuedPacket]
+	// packets of the keyframe being queued, all of them are classified as keyframe, not only the first
+	inKeyFrame        bool
+	keyFrameTimestamp uint32
+	numKeyFrame       int
+	// frame of the last video packet sent, it is not dropped while it is partly sent
+	sentTimestamp uint32
+	sentPartly    bool
+	// frame dropped before all its packets were queued, the rest of it is dropped when queued
+	dropping          bool
+	droppingTimestamp uint32
+}
+
+func (q *ssrcQueue) classify(pkt *Packet) PacketClass {
+	class := pkt.Class()
+	switch class {
+	case PacketClassKeyFrame:
+		q.inKeyFrame = true
+		q.keyFrameTimestamp = pkt.Header.Timestamp
+	case PacketClassVideo:
+		if q.inKeyFrame && pkt.Header.Timestamp == q.keyFrameTimestamp {
+			class = PacketClassKeyFrame
+		} else {
+			q.inKeyFrame = false
+		}
+	}
+	if class == PacketClassKeyFrame && pkt.Header.Marker {
+		// last packet of the frame
+		q.inKeyFrame = false
+	}
+	return class
+}
+
+func (q *ssrcQueue) push(qp queuedPacket) {
+	if qp.class == PacketClassKeyFrame {
+		q.numKeyFrame++
+	}
+	q.packets.PushBack(qp)
+}
+
+func (q *ssrcQueue) pop() queuedPacket {
+	qp := q.packets.PopFront()
+	if qp.class == PacketClassKeyFrame {
+		q.numKeyFrame--
+	}
+	return qp
+}
+
+// sent records the frame of a sent video packet
+func (q *ssrcQueue) sent(qp queuedPacket) {
+	if qp.class != PacketClassKeyFrame && qp.class != PacketClassVideo {
+		return
+	}
+	q.sentTimestamp = qp.packet.Header.Timestamp
+	q.sentPartly = !qp.packet.Header.Marker
+}
+
+// isDropping returns true for the rest of a frame dropped while it was being queued
+func (q *ssrcQueue) isDropping(pkt *Packet, class PacketClass) bool {
+	if !q.dropping {
+		return false
+	}
+	if class != PacketClassVideo || pkt.Header.Timestamp != q.droppingTimestamp {
+		q.dropping = false
+		return false
+	}
+	if pkt.Header.Marker {
+		q.dropping = false
+	}
+	return true
+}
+
+// headClass is the class the SSRC is scheduled at, video queued ahead of a keyframe
+// is sent with the priority of the keyframe as the keyframe cannot go before it
+func (q *ssrcQueue) headClass() PacketClass {
+	class := q.packets.Front().class
+	if class == PacketClassVideo && q.numKeyFrame != 0 {
+		return PacketClassKeyFrame
+	}
+	return class
+}
+
+// Priority sends packets in class priority order
+// (audio > retransmissions > video keyframes > video > padding/probe)
+// within the pacing rate, each class limited to its share of the rate.
+// Media packets of an SSRC are never reordered, priority applies between SSRCs and retransmissions,
+// among SSRCs of the same class the one waiting the longest goes first.
+type Priority struct {
+	*Base
+
+	logger logger.Logger
+	config PriorityConfig
+
+	lock         sync.Mutex
+	queues       map[queueKey]*ssrcQueue
+	bitrate      int
+	tokens       float64
+	classTokens  [numPacketClasses]float64
+	lastRefillAt time.Time
+	wake         chan struct{}
+	stop         core.Fuse
+}
+
+func NewPriority(logger logger.Logger, bwe bwe.BWE, config PriorityConfig) *Priority {
+	p := &Priority{
+		Base:         NewBase(logger, bwe),
+		logger:       logger,
+		config:       config,
+		queues:       make(map[queueKey]*ssrcQueue),
+		lastRefillAt: mono.Now(),
+		wake:         make(chan struct{}, 1),
+	}
+
+	go p.sendWorker()
+	return p
+}
+
+func (p *Priority) SetInterval(interval time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.config.Interval = interval
+}
+
+// SetBitrate sets the channel capacity, pacing rate is derived from it, 0 disables pacing
+func (p *Priority) SetBitrate(bitrate int) {
+	p.lock.Lock()
+	p.bitrate = int(float64(bitrate) * p.config.PacingFactor)
+	p.lock.Unlock()
+
+	p.signal()
+}
+
+func (p *Priority) Stop() {
+	p.stop.Break()
+	p.signal()
+}
+
+func (p *Priority) Enqueue(pkt *Packet) {
+	p.lock.Lock()
+	if p.stop.IsBroken() {
+		// queues are drained when stopping, nothing is sent anymore
+		p.lock.Unlock()
+		p.Base.ReleasePacket(pkt)
+		return
+	}
+
+	key := queueKey{pkt.Header.SSRC, pkt.IsRTX}
+	q := p.queues[key]
+	if q == nil {
+		q = &ssrcQueue{}
+		p.queues[key] = q
+	}
+	class := q.classify(pkt)
+	if q.isDropping(pkt, class) {
+		p.lock.Unlock()
+		p.Base.ReleasePacket(pkt)
+		prometheus.RecordPacerDrop(class.String())
+		return
+	}
+	q.push(queuedPacket{
+		packet:     pkt,
+		class:      class,
+		enqueuedAt: mono.Now(),
+	})
+	p.lock.Unlock()
+
+	p.signal()
+}
+
+func (p *Priority) signal() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Priority) sendWorker() {
+	// timer runs only when packets are waiting for pacing budget
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-p.wake:
+		case <-timer.C:
+		}
+
+		if p.stop.IsBroken() {
+			p.drain()
+			return
+		}
+
+		for !p.stop.IsBroken() {
+			qp, ok := p.next()
+			if !ok {
+				break
+			}
+
+			p.Base.SendPacket(qp.packet)
+		}
+
+		if interval, isPending := p.pending(); isPending {
+			timer.Reset(interval)
+		}
+	}
+}
+
+func (p *Priority) pending() (time.Duration, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, q := range p.queues {
+		if q.packets.Len() != 0 {
+			return p.config.Interval, true
+		}
+	}
+	return 0, false
+}
+
+// next returns the head of the SSRC with the highest priority that can be sent within budget,
+// dropping stale packets along the way
+func (p *Priority) next() (queuedPacket, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := mono.Now()
+	p.refill(now)
+
+	var heads [numPacketClasses]*ssrcQueue
+	for key, q := range p.queues {
+		p.dropStale(q, now)
+		if q.packets.Len() == 0 {
+			if !q.inKeyFrame && !q.sentPartly && !q.dropping {
+				delete(p.queues, key)
+			}
+			continue
+		}
+
+		class := q.headClass()
+		if head := heads[class]; head == nil || q.packets.Front().enqueuedAt.Before(head.packets.Front().enqueuedAt) {
+			heads[class] = q
+		}
+	}
+
+	for class := PacketClass(0); class < numPacketClasses; class++ {
+		q := heads[class]
+		if q == nil {
+			continue
+		}
+
+		if p.bitrate != 0 && (p.tokens <= 0 || p.classTokens[class] <= 0) {
+			if p.tokens <= 0 {
+				// out of budget for this interval, lower priority classes cannot send either
+				break
+			}
+			continue
+		}
+
+		qp := q.pop()
+		q.sent(qp)
+		size := float64(qp.packet.HeaderSize + len(qp.packet.Payload))
+		if p.bitrate != 0 {
+			p.tokens -= size
+			p.classTokens[class] -= size
+		}
+		prometheus.RecordPacerQueueDelay(class.String(), now.Sub(qp.enqueuedAt))
+		return qp, true
+	}
+
+	return queuedPacket{}, false
+}
+
+func (p *Priority) refill(now time.Time) {
+	elapsed := now.Sub(p.lastRefillAt).Seconds()
+	p.lastRefillAt = now
+	if p.bitrate == 0 {
+		return
+	}
+
+	// allow a burst of a couple of intervals so that a late wake up does not lose budget
+	bytesPerSecond := float64(p.bitrate) / 8.0
+	burst := bytesPerSecond * p.config.Interval.Seconds() * maxOvershootFactor
+	p.tokens = min(p.tokens+bytesPerSecond*elapsed, burst)
+	for class := PacketClass(0); class < numPacketClasses; class++ {
+		share := p.config.Budgets.share(class)
+		p.classTokens[class] = min(p.classTokens[class]+share*bytesPerSecond*elapsed, share*burst)
+	}
+}
+
+// dropStale drops video frames and padding at the head of the queue, keyframes and anything queued behind them are kept.
+// Video is dropped a whole frame at a time, a frame partly sent already is sent in full.
+func (p *Priority) dropStale(q *ssrcQueue, now time.Time) {
+	if p.config.MaxVideoQueueTime == 0 {
+		return
+	}
+
+	for q.packets.Len() != 0 {
+		qp := q.packets.Front()
+		if (qp.class != PacketClassVideo && qp.class != PacketClassPadding) || now.Sub(qp.enqueuedAt) <= p.config.MaxVideoQueueTime {
+			return
+		}
+
+		if qp.class == PacketClassPadding {
+			q.pop()
+			p.Base.ReleasePacket(qp.packet)
+			prometheus.RecordPacerDrop(qp.class.String())
+			continue
+		}
+
+		ts := qp.packet.Header.Timestamp
+		if q.sentPartly && q.sentTimestamp == ts {
+			return
+		}
+
+		marker := false
+		for q.packets.Len() != 0 && !marker {
+			fp := q.packets.Front()
+			if fp.class != PacketClassVideo || fp.packet.Header.Timestamp != ts {
+				break
+			}
+
+			q.pop()
+			marker = fp.packet.Header.Marker
+			p.Base.ReleasePacket(fp.packet)
+			prometheus.RecordPacerDrop(fp.class.String())
+		}
+		if !marker && q.packets.Len() == 0 {
+			// rest of the frame is not queued yet
+			q.dropping = true
+			q.droppingTimestamp = ts
+		}
+	}
+}
+
+func (p *Priority) drain() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for key, q := range p.queues {
+		for q.packets.Len() != 0 {
+			p.Base.ReleasePacket(q.pop().packet)
+		}
+		delete(p.queues, key)
+	}
+}
+
+// ------------------------------------------------
@@ -0,0 +1,251 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pacer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils/mono"
+)
+
+// newTestPriority creates a pacer without the send worker so that scheduling can be stepped
+func newTestPriority(config PriorityConfig) *Priority {
+	return &Priority{
+		Base:         NewBase(logger.GetLogger(), nil),
+		logger:       logger.GetLogger(),
+		config:       config,
+		queues:       make(map[queueKey]*ssrcQueue),
+		lastRefillAt: mono.Now(),
+	}
+}
+
+// packets of each class are on their own SSRC
+func newTestPacket(class PacketClass, size int) *Packet {
+	return &Packet{
+		Header:     &rtp.Header{SSRC: uint32(class) + 1},
+		HeaderSize: 12,
+		Payload:    make([]byte, size-12),
+		IsProbe:    class == PacketClassPadding,
+		IsRTX:      class == PacketClassRetransmission,
+		IsAudio:    class == PacketClassAudio,
+		IsKeyFrame: class == PacketClassKeyFrame,
+	}
+}
+
+func newTestVideoPacket(ssrc uint32, sn uint16, ts uint32, isKeyFrame bool, marker bool) *Packet {
+	return &Packet{
+		Header:     &rtp.Header{SSRC: ssrc, SequenceNumber: sn, Timestamp: ts, Marker: marker},
+		HeaderSize: 12,
+		Payload:    make([]byte, 88),
+		IsKeyFrame: isKeyFrame,
+	}
+}
+
+func TestPriority(t *testing.T) {
+	prometheus.Init("test", livekit.NodeType_SERVER)
+
+	t.Run("class order", func(t *testing.T) {
+		p := newTestPriority(DefaultPriorityConfig)
+
+		classes := []PacketClass{PacketClassPadding, PacketClassVideo, PacketClassKeyFrame, PacketClassRetransmission, PacketClassAudio}
+		for _, class := range classes {
+			p.Enqueue(newTestPacket(class, 100))
+		}
+
+		for class := PacketClass(0); class < numPacketClasses; class++ {
+			qp, ok := p.next()
+			require.True(t, ok)
+			require.Equal(t, class, qp.packet.Class())
+		}
+		_, ok := p.next()
+		require.False(t, ok)
+	})
+
+	t.Run("class budget", func(t *testing.T) {
+		config := DefaultPriorityConfig
+		config.Interval = 10 * time.Millisecond
+		config.PacingFactor = 1.0
+		p := newTestPriority(config)
+		// 10 KBps, burst of 200 bytes, retransmissions get half of it
+		p.SetBitrate(80_000)
+		p.lastRefillAt = mono.Now().Add(-time.Second)
+
+		p.Enqueue(newTestPacket(PacketClassRetransmission, 150))
+		p.Enqueue(newTestPacket(PacketClassRetransmission, 150))
+		p.Enqueue(newTestPacket(PacketClassVideo, 60))
+		p.Enqueue(newTestPacket(PacketClassVideo, 60))
+
+		qp, ok := p.next()
+		require.True(t, ok)
+		require.Equal(t, PacketClassRetransmission, qp.packet.Class())
+
+		// retransmission budget exhausted, video can still use the remaining rate
+		qp, ok = p.next()
+		require.True(t, ok)
+		require.Equal(t, PacketClassVideo, qp.packet.Class())
+
+		// overall budget exhausted
+		_, ok = p.next()
+		require.False(t, ok)
+
+		// no pacing sends everything
+		p.SetBitrate(0)
+		for range 2 {
+			_, ok = p.next()
+			require.True(t, ok)
+		}
+	})
+
+	t.Run("stale video", func(t *testing.T) {
+		config := DefaultPriorityConfig
+		config.MaxVideoQueueTime = time.Millisecond
+		p := newTestPriority(config)
+
+		p.Enqueue(newTestPacket(PacketClassVideo, 100))
+		p.Enqueue(newTestPacket(PacketClassPadding, 100))
+		p.Enqueue(newTestPacket(PacketClassAudio, 100))
+		time.Sleep(5 * time.Millisecond)
+
+		// audio is not dropped for queuing time
+		qp, ok := p.next()
+		require.True(t, ok)
+		require.Equal(t, PacketClassAudio, qp.packet.Class())
+
+		_, ok = p.next()
+		require.False(t, ok)
+		_, isPending := p.pending()
+		require.False(t, isPending)
+	})
+
+	t.Run("keyframe", func(t *testing.T) {
+		config := DefaultPriorityConfig
+		config.MaxVideoQueueTime = 10 * time.Millisecond
+		p := newTestPriority(config)
+
+		p.Enqueue(newTestVideoPacket(2, 1, 1000, false, true))
+		time.Sleep(time.Millisecond)
+		p.Enqueue(newTestVideoPacket(1, 1, 1000, false, true))
+		// only the first packet of a keyframe is marked
+		p.Enqueue(newTestVideoPacket(1, 2, 2000, true, false))
+		p.Enqueue(newTestVideoPacket(1, 3, 2000, false, false))
+		p.Enqueue(newTestVideoPacket(1, 4, 2000, false, true))
+		p.Enqueue(newTestVideoPacket(1, 5, 3000, false, true))
+
+		// SSRC with a keyframe goes first, in order, video ahead of the keyframe included
+		expected := []struct {
+			ssrc  uint32
+			sn    uint16
+			class PacketClass
+		}{
+			{1, 1, PacketClassVideo},
+			{1, 2, PacketClassKeyFrame},
+			{1, 3, PacketClassKeyFrame},
+			{1, 4, PacketClassKeyFrame},
+			{2, 1, PacketClassVideo},
+			{1, 5, PacketClassVideo},
+		}
+		for _, e := range expected {
+			qp, ok := p.next()
+			require.True(t, ok)
+			require.Equal(t, e.ssrc, qp.packet.Header.SSRC)
+			require.Equal(t, e.sn, qp.packet.Header.SequenceNumber)
+			require.Equal(t, e.class, qp.class)
+		}
+		_, ok := p.next()
+		require.False(t, ok)
+
+		// whole keyframe is kept when stale, video after it is dropped once at the head
+		p.Enqueue(newTestVideoPacket(1, 6, 4000, true, false))
+		p.Enqueue(newTestVideoPacket(1, 7, 4000, false, true))
+		p.Enqueue(newTestVideoPacket(1, 8, 5000, false, true))
+		time.Sleep(20 * time.Millisecond)
+
+		for _, sn := range []uint16{6, 7} {
+			qp, ok := p.next()
+			require.True(t, ok)
+			require.Equal(t, sn, qp.packet.Header.SequenceNumber)
+		}
+		_, ok = p.next()
+		require.False(t, ok)
+	})
+	t.Run("retransmission on media SSRC", func(t *testing.T) {
+		p := newTestPriority(DefaultPriorityConfig)
+
+		p.Enqueue(newTestVideoPacket(1, 1, 1000, false, false))
+		p.Enqueue(newTestVideoPacket(1, 2, 1000, false, true))
+		rtx := newTestVideoPacket(1, 0, 500, false, true)
+		rtx.IsRTX = true
+		p.Enqueue(rtx)
+
+		// retransmission without an RTX SSRC does not wait behind media
+		for _, sn := range []uint16{0, 1, 2} {
+			qp, ok := p.next()
+			require.True(t, ok)
+			require.Equal(t, sn, qp.packet.Header.SequenceNumber)
+		}
+	})
+
+	t.Run("stale video is dropped by frame", func(t *testing.T) {
+		config := DefaultPriorityConfig
+		config.MaxVideoQueueTime = 10 * time.Millisecond
+		p := newTestPriority(config)
+
+		// frame 1000 starts sending before it is stale
+		p.Enqueue(newTestVideoPacket(1, 1, 1000, false, false))
+		qp, ok := p.next()
+		require.True(t, ok)
+		require.EqualValues(t, 1, qp.packet.Header.SequenceNumber)
+
+		p.Enqueue(newTestVideoPacket(1, 2, 1000, false, true))
+		p.Enqueue(newTestVideoPacket(1, 3, 2000, false, false))
+		time.Sleep(20 * time.Millisecond)
+		// not stale yet, dropped with the rest of its frame
+		p.Enqueue(newTestVideoPacket(1, 4, 2000, false, false))
+
+		// partly sent frame is sent in full, stale frame is dropped
+		qp, ok = p.next()
+		require.True(t, ok)
+		require.EqualValues(t, 2, qp.packet.Header.SequenceNumber)
+		_, ok = p.next()
+		require.False(t, ok)
+
+		// rest of the dropped frame is dropped when queued
+		p.Enqueue(newTestVideoPacket(1, 5, 2000, false, true))
+		p.Enqueue(newTestVideoPacket(1, 6, 3000, false, true))
+		qp, ok = p.next()
+		require.True(t, ok)
+		require.EqualValues(t, 6, qp.packet.Header.SequenceNumber)
+		_, ok = p.next()
+		require.False(t, ok)
+	})
+
+	t.Run("packets queued after stop are released", func(t *testing.T) {
+		p := newTestPriority(DefaultPriorityConfig)
+		p.Stop()
+
+		pkt := newTestPacket(PacketClassVideo, 100)
+		p.Enqueue(pkt)
+		require.Nil(t, pkt.Header)
+		_, ok := p.next()
+		require.False(t, ok)
+	})
+}
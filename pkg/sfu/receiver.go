@@ -21,6 +21,7 @@ import (
 
 	"github.com/pion/rtcp"
 	"github.com/pion/webrtc/v4"
+	"go.uber.org/atomic"
 
 	"github.com/livekit/protocol/codecs/mime"
 	"github.com/livekit/protocol/livekit"
@@ -47,6 +48,8 @@ type WebRTCReceiver struct {
 
 	connectionStats *connectionquality.ConnectionStats
 	onStatsUpdate   func(w *WebRTCReceiver, stat *livekit.AnalyticsStat)
+
+	upstreamRTT atomic.Uint32
 }
 
 type ReceiverOpts func(w *WebRTCReceiver) *WebRTCReceiver
@@ -86,6 +89,14 @@ func WithLoadBalanceThreshold(downTracks int) ReceiverOpts {
 	}
 }
 
+// WithKeyFrameCacheConfig enables caching of key frames to start subscribers without waiting for a key frame
+func WithKeyFrameCacheConfig(keyFrameCacheConfig KeyFrameCacheConfig) ReceiverOpts {
+	return func(w *WebRTCReceiver) *WebRTCReceiver {
+		w.ReceiverBase.SetKeyFrameCacheConfig(keyFrameCacheConfig)
+		return w
+	}
+}
+
 func WithForwardStats(forwardStats *ForwardStats) ReceiverOpts {
 	return func(w *WebRTCReceiver) *WebRTCReceiver {
 		w.ReceiverBase.SetForwardStats(forwardStats)
@@ -156,6 +167,20 @@ func (w *WebRTCReceiver) GetConnectionScoreAndQuality() (float32, livekit.Connec
 	return w.connectionStats.GetScoreAndQuality()
 }
 
+// SetUpstreamRTT sets RTT of the publisher connection measured using RTCP-XR (DLRR in response to the
+// Receiver Reference Time reports sent by buffers), it applies to buffers of all layers for NACK timing
+// and RTP stats, i. e. it is reported in track stats, and is included in up stream quality scoring
+func (w *WebRTCReceiver) SetUpstreamRTT(rtt uint32) {
+	if rtt == 0 {
+		return
+	}
+
+	w.ReceiverBase.SetRTT(rtt)
+	if w.upstreamRTT.Swap(rtt) == 0 {
+		w.connectionStats.SetIncludeRTT(true)
+	}
+}
+
 func (w *WebRTCReceiver) ssrc(layer int) uint32 {
 	if track := w.upTracks[layer]; track != nil {
 		return uint32(track.SSRC())
@@ -345,6 +370,7 @@ func (w *WebRTCReceiver) DebugInfo() map[string]any {
 	}
 	w.upTracksMu.Unlock()
 	info["UpTracks"] = upTrackInfo
+	info["UpstreamRTT"] = w.upstreamRTT.Load()
 
 	return info
 }
@@ -38,6 +38,7 @@ import (
 	"github.com/livekit/livekit-server/pkg/sfu/rtpstats"
 	"github.com/livekit/livekit-server/pkg/sfu/streamtracker"
 	sfuutils "github.com/livekit/livekit-server/pkg/sfu/utils"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 )
 
 var (
@@ -115,6 +116,9 @@ type TrackReceiver interface {
 	GetAudioLevel() (float64, bool)
 
 	SendPLI(layer int32, force bool)
+	// PrimeKeyFrame queues the cached key frame of the layer to be written to the track,
+	// returns false if there is no cached key frame and the track has to wait for one from the publisher
+	PrimeKeyFrame(layer int32, track TrackSender) bool
 
 	SetUpTrackPaused(paused bool)
 	SetMaxExpectedSpatialLayer(layer int32)
@@ -199,6 +203,10 @@ type ReceiverBase struct {
 	lbThreshold                     int
 	forwardStats                    *ForwardStats
 
+	keyFrameCaches       [buffer.DefaultMaxLayerSpatial + 1]*KeyFrameCache
+	keyFrameCacheMu      sync.Mutex
+	keyFrameCachePending [buffer.DefaultMaxLayerSpatial + 1][]TrackSender
+
 	codecStateLock     sync.Mutex
 	codecState         ReceiverCodecState
 	onCodecStateChange []func(webrtc.RTPCodecParameters, ReceiverCodecState)
@@ -269,6 +277,7 @@ func (r *ReceiverBase) Close(reason string, clearBuffers bool) {
 		r.ClearAllBuffers(reason)
 	}
 	r.streamTrackerManager.Close()
+	r.resetKeyFrameCaches()
 
 	closeTrackSenders(r.downTrackSpreader.ResetAndGetDownTracks())
 
@@ -317,6 +326,24 @@ func (r *ReceiverBase) SetForwardStats(forwardStats *ForwardStats) {
 	r.forwardStats = forwardStats
 }
 
+func (r *ReceiverBase) SetKeyFrameCacheConfig(keyFrameCacheConfig KeyFrameCacheConfig) {
+	if !keyFrameCacheConfig.Enabled || r.Kind() != webrtc.RTPCodecTypeVideo {
+		return
+	}
+
+	// codecs where the following frames of a key frame can be forwarded as is,
+	// scalable streams need dependency descriptor based selection and are not cached
+	switch r.Mime() {
+	case mime.MimeTypeVP8, mime.MimeTypeH264:
+	default:
+		return
+	}
+
+	for layer := range r.keyFrameCaches {
+		r.keyFrameCaches[layer] = NewKeyFrameCache(keyFrameCacheConfig)
+	}
+}
+
 func (r *ReceiverBase) Logger() logger.Logger {
 	return r.params.Logger
 }
@@ -403,6 +430,7 @@ func (r *ReceiverBase) restartInternal(reason string, isDetected bool) {
 
 	// 4. wait for the forwarders to finish
 	r.waitForForwardersStop()
+	r.resetKeyFrameCaches()
 
 	// 5. reset stream tracker
 	r.streamTrackerManager.RemoveAllTrackers()
@@ -650,6 +678,59 @@ func (r *ReceiverBase) SendPLI(layer int32, force bool) {
 	buff.SendPLI(force)
 }
 
+func (r *ReceiverBase) PrimeKeyFrame(layer int32, track TrackSender) bool {
+	if layer < 0 || int(layer) >= len(r.keyFrameCaches) || r.keyFrameCaches[layer] == nil {
+		return false
+	}
+
+	if !r.keyFrameCaches[layer].HasKeyFrame() {
+		prometheus.RecordKeyFrameCachePrime(false)
+		return false
+	}
+
+	// written by the forwarder of the layer before the next packet to keep packets in order
+	r.keyFrameCacheMu.Lock()
+	if !slices.Contains(r.keyFrameCachePending[layer], track) {
+		r.keyFrameCachePending[layer] = append(r.keyFrameCachePending[layer], track)
+	}
+	r.keyFrameCacheMu.Unlock()
+
+	prometheus.RecordKeyFrameCachePrime(true)
+	return true
+}
+
+func (r *ReceiverBase) primeDownTracks(layer int32) {
+	r.keyFrameCacheMu.Lock()
+	pending := r.keyFrameCachePending[layer]
+	r.keyFrameCachePending[layer] = nil
+	r.keyFrameCacheMu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	packets := r.keyFrameCaches[layer].Packets()
+	for _, dt := range pending {
+		for _, pkt := range packets {
+			dt.WriteRTP(pkt, layer)
+		}
+	}
+}
+
+func (r *ReceiverBase) resetKeyFrameCaches() {
+	r.keyFrameCacheMu.Lock()
+	for layer := range r.keyFrameCachePending {
+		r.keyFrameCachePending[layer] = nil
+	}
+	r.keyFrameCacheMu.Unlock()
+
+	for _, kfc := range r.keyFrameCaches {
+		if kfc != nil {
+			kfc.Reset()
+		}
+	}
+}
+
 func (r *ReceiverBase) getBuffer(layer int32) (buffer.BufferProvider, int32) {
 	r.bufferMu.RLock()
 	defer r.bufferMu.RUnlock()
@@ -995,10 +1076,18 @@ func (r *ReceiverBase) forwardRTP(
 			continue
 		}
 
+		kfc := r.keyFrameCaches[spatialLayer]
+		if kfc != nil {
+			r.primeDownTracks(spatialLayer)
+		}
+
 		var writeCount atomic.Int32
 		r.downTrackSpreader.Broadcast(func(dt TrackSender) {
 			writeCount.Add(dt.WriteRTP(extPkt, spatialLayer))
 		})
+		if kfc != nil && extPkt.DependencyDescriptor == nil {
+			kfc.Add(extPkt)
+		}
 		if rt := r.loadREDTransformer(); rt != nil {
 			writeCount.Add(rt.ForwardRTP(extPkt, spatialLayer))
 		}
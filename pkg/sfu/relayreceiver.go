@@ -0,0 +1,186 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sfu
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v4"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+var errInvalidRelayPacket = errors.New("invalid relay packet")
+
+var _ TrackReceiver = (*RelayReceiver)(nil)
+
+type RelayReceiverParams struct {
+	TrackInfo                  *livekit.TrackInfo
+	Codec                      webrtc.RTPCodecParameters
+	HeaderExtensions           []webrtc.RTPHeaderExtensionParameter
+	MaxVideoPkts               int
+	MaxAudioPkts               int
+	PLIThrottleConfig          PLIThrottleConfig
+	AudioConfig                AudioConfig
+	StreamTrackerManagerConfig StreamTrackerManagerConfig
+	Logger                     logger.Logger
+	// called to request a key frame of a layer from the node hosting the publisher
+	OnPLI func(layer int32)
+}
+
+// RelayReceiver receives media of a track published on another node. Packets are fed by the relay
+// transport and go through a buffer per layer, just like packets received from a publisher.
+type RelayReceiver struct {
+	*ReceiverBase
+
+	params RelayReceiverParams
+
+	lock           sync.Mutex
+	onCloseHandler func()
+}
+
+func NewRelayReceiver(params RelayReceiverParams) *RelayReceiver {
+	r := &RelayReceiver{
+		params: params,
+	}
+
+	r.ReceiverBase = NewReceiverBase(
+		ReceiverBaseParams{
+			TrackID:                    livekit.TrackID(params.TrackInfo.Sid),
+			StreamID:                   params.TrackInfo.Stream,
+			Kind:                       kindForTrackType(params.TrackInfo.Type),
+			Codec:                      params.Codec,
+			HeaderExtensions:           params.HeaderExtensions,
+			Logger:                     params.Logger,
+			StreamTrackerManagerConfig: params.StreamTrackerManagerConfig,
+			IsSelfClosing:              false,
+			OnClosed:                   r.onClosed,
+		},
+		params.TrackInfo,
+		ReceiverCodecStateNormal,
+	)
+	r.ReceiverBase.SetPLIThrottleConfig(params.PLIThrottleConfig)
+	r.ReceiverBase.SetAudioConfig(params.AudioConfig)
+
+	return r
+}
+
+func (r *RelayReceiver) Close(reason string) {
+	r.ReceiverBase.Close(reason, true)
+}
+
+func kindForTrackType(trackType livekit.TrackType) webrtc.RTPCodecType {
+	if trackType == livekit.TrackType_VIDEO {
+		return webrtc.RTPCodecTypeVideo
+	}
+	return webrtc.RTPCodecTypeAudio
+}
+
+// OnCloseHandler method to be called when the receiver closes
+func (r *RelayReceiver) OnCloseHandler(fn func()) {
+	r.lock.Lock()
+	r.onCloseHandler = fn
+	r.lock.Unlock()
+}
+
+// WriteRTP feeds a relayed packet of a layer
+func (r *RelayReceiver) WriteRTP(layer int32, pkt []byte) error {
+	if r.IsClosed() {
+		return ErrReceiverClosed
+	}
+
+	if len(pkt) < 12 {
+		return errInvalidRelayPacket
+	}
+
+	buff, err := r.getOrCreateRelayBuffer(layer, binary.BigEndian.Uint32(pkt[8:12]))
+	if err != nil {
+		return err
+	}
+
+	_, err = buff.Write(pkt)
+	return err
+}
+
+// SetSenderReportData applies the publisher's sender report of a layer
+func (r *RelayReceiver) SetSenderReportData(layer int32, srData *livekit.RTCPSenderReportState) {
+	if buff, _ := r.ReceiverBase.getBuffer(layer); buff != nil {
+		buff.SetSenderReportData(srData)
+	}
+}
+
+func (r *RelayReceiver) getOrCreateRelayBuffer(layer int32, ssrc uint32) (*buffer.Buffer, error) {
+	if layer < 0 || layer > buffer.DefaultMaxLayerSpatial {
+		return nil, ErrInvalidLayer
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if buff, _ := r.ReceiverBase.getBuffer(layer); buff != nil {
+		return buff.(*buffer.Buffer), nil
+	}
+
+	buff := buffer.NewBuffer(ssrc, r.params.MaxVideoPkts, r.params.MaxAudioPkts)
+	var bitrate int
+	if layers := buffer.GetVideoLayersForMimeType(r.Mime(), r.params.TrackInfo); int(layer) < len(layers) {
+		bitrate = int(layers[layer].GetBitrate())
+	}
+	if err := buff.Bind(
+		webrtc.RTPParameters{
+			HeaderExtensions: r.params.HeaderExtensions,
+			Codecs:           []webrtc.RTPCodecParameters{r.params.Codec},
+		},
+		r.params.Codec.RTPCodecCapability,
+		bitrate,
+	); err != nil {
+		return nil, err
+	}
+	buff.OnRtcpFeedback(func(pkts []rtcp.Packet) {
+		r.handleRTCP(layer, pkts)
+	})
+
+	r.ReceiverBase.AddBuffer(buff, layer)
+	r.ReceiverBase.StartBuffer(buff, layer)
+	return buff, nil
+}
+
+func (r *RelayReceiver) handleRTCP(layer int32, pkts []rtcp.Packet) {
+	// only key frame requests go upstream, losses between nodes are not repaired
+	for _, pkt := range pkts {
+		if _, ok := pkt.(*rtcp.PictureLossIndication); ok {
+			if r.params.OnPLI != nil {
+				r.params.OnPLI(layer)
+			}
+			return
+		}
+	}
+}
+
+func (r *RelayReceiver) onClosed() {
+	r.lock.Lock()
+	onCloseHandler := r.onCloseHandler
+	r.lock.Unlock()
+
+	if onCloseHandler != nil {
+		onCloseHandler()
+	}
+}
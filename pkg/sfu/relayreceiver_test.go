@@ -0,0 +1,99 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sfu
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+func TestRelayReceiverToRelaySender(t *testing.T) {
+	codec := webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2},
+		PayloadType:        111,
+	}
+	receiver := NewRelayReceiver(RelayReceiverParams{
+		TrackInfo: &livekit.TrackInfo{
+			Sid:      "TR_audio",
+			Type:     livekit.TrackType_AUDIO,
+			MimeType: webrtc.MimeTypeOpus,
+		},
+		Codec:                      codec,
+		MaxVideoPkts:               200,
+		MaxAudioPkts:               200,
+		StreamTrackerManagerConfig: DefaultStreamTrackerManagerConfig,
+		Logger:                     logger.GetLogger(),
+	})
+	defer receiver.Close("test")
+
+	var (
+		lock     sync.Mutex
+		received []uint16
+		closed   bool
+	)
+	sender := NewRelaySender(RelaySenderParams{
+		SubscriberID: "RELAY_node_stream",
+		Logger:       logger.GetLogger(),
+		OnPacket: func(layer int32, pkt []byte) {
+			var p rtp.Packet
+			if err := p.Unmarshal(pkt); err != nil || layer != 0 {
+				return
+			}
+
+			lock.Lock()
+			received = append(received, p.SequenceNumber)
+			lock.Unlock()
+		},
+		OnClose: func() {
+			lock.Lock()
+			closed = true
+			lock.Unlock()
+		},
+	}, receiver)
+	require.NoError(t, receiver.AddDownTrack(sender))
+
+	header := rtp.Header{Version: 2, SequenceNumber: 100, Timestamp: 1000, PayloadType: 111, SSRC: 1234}
+	for _, pkt := range generatePkts(header, 5, tsStep) {
+		buf, err := pkt.Marshal()
+		require.NoError(t, err)
+		require.NoError(t, receiver.WriteRTP(0, buf))
+	}
+
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(received) == 5
+	}, 5*time.Second, 10*time.Millisecond)
+	lock.Lock()
+	require.Equal(t, []uint16{100, 101, 102, 103, 104}, received)
+	lock.Unlock()
+
+	require.ErrorIs(t, receiver.WriteRTP(0, []byte{0x80}), errInvalidRelayPacket)
+
+	sender.Close()
+	require.True(t, sender.IsClosed())
+	require.Empty(t, receiver.GetDownTracks())
+	lock.Lock()
+	require.True(t, closed)
+	lock.Unlock()
+}
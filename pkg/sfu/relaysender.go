@@ -0,0 +1,124 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sfu
+
+import (
+	"github.com/pion/webrtc/v4"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+var _ TrackSender = (*RelaySender)(nil)
+
+type RelaySenderParams struct {
+	// unique among senders of a receiver, identifies the relayed stream
+	SubscriberID livekit.ParticipantID
+	Logger       logger.Logger
+	// called with every packet received from the publisher, packet is only valid for the duration of the call
+	OnPacket func(layer int32, pkt []byte)
+	// called with sender reports received from the publisher
+	OnSenderReport func(layer int32, srData *livekit.RTCPSenderReportState)
+	OnClose        func()
+}
+
+// RelaySender forwards packets of a published track as is, including all layers, to another node.
+// Layer selection and sequence number rewriting happen on the receiving node's down tracks.
+type RelaySender struct {
+	params RelaySenderParams
+
+	receiver TrackReceiver
+
+	isClosed atomic.Bool
+}
+
+func NewRelaySender(params RelaySenderParams, receiver TrackReceiver) *RelaySender {
+	return &RelaySender{
+		params:   params,
+		receiver: receiver,
+	}
+}
+
+func (s *RelaySender) UpTrackLayersChange() {}
+
+func (s *RelaySender) UpTrackBitrateAvailabilityChange() {}
+
+func (s *RelaySender) UpTrackMaxPublishedLayerChange(_maxPublishedLayer int32) {}
+
+func (s *RelaySender) UpTrackMaxTemporalLayerSeenChange(_maxTemporalLayerSeen int32) {}
+
+func (s *RelaySender) UpTrackBitrateReport(_availableLayers []int32, _bitrates Bitrates) {}
+
+func (s *RelaySender) WriteRTP(p *buffer.ExtPacket, layer int32) int32 {
+	if s.isClosed.Load() || len(p.RawPacket) == 0 {
+		return 0
+	}
+
+	// svc layers share a stream, the receiving node parses layers from the packet
+	if s.receiver.VideoLayerMode() == livekit.VideoLayer_MULTIPLE_SPATIAL_LAYERS_PER_STREAM {
+		layer = 0
+	}
+
+	s.params.OnPacket(layer, p.RawPacket)
+	return 1
+}
+
+func (s *RelaySender) Close() {
+	if s.isClosed.Swap(true) {
+		return
+	}
+
+	s.receiver.DeleteDownTrack(s.params.SubscriberID)
+
+	if s.params.OnClose != nil {
+		s.params.OnClose()
+	}
+}
+
+func (s *RelaySender) IsClosed() bool {
+	return s.isClosed.Load()
+}
+
+func (s *RelaySender) ID() string {
+	return string(s.params.SubscriberID)
+}
+
+func (s *RelaySender) SubscriberID() livekit.ParticipantID {
+	return s.params.SubscriberID
+}
+
+func (s *RelaySender) HandleRTCPSenderReportData(
+	_payloadType webrtc.PayloadType,
+	layer int32,
+	publisherSRData *livekit.RTCPSenderReportState,
+) error {
+	if s.isClosed.Load() || publisherSRData == nil || s.params.OnSenderReport == nil {
+		return nil
+	}
+
+	s.params.OnSenderReport(layer, publisherSRData)
+	return nil
+}
+
+func (s *RelaySender) Resync() {}
+
+// relayed streams are per codec, a codec change of the publisher is replicated through
+// track info and the receiving node subscribes to the new codec instead
+func (s *RelaySender) SetReceiver(_receiver TrackReceiver) {}
+
+func (s *RelaySender) ReceiverRestart(_receiver TrackReceiver) {}
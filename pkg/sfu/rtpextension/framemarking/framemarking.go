@@ -0,0 +1,105 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framemarking
+
+import (
+	"errors"
+)
+
+const (
+	FrameMarkingURI = "urn:ietf:params:rtp-hdrext:framemarking"
+)
+
+var (
+	errTooSmall = errors.New("buffer too small")
+)
+
+// non-scalable streams
+//
+//  0 1 2 3 4 5 6 7
+// +-+-+-+-+-+-+-+-+
+// |S|E|I|D|0 0 0 0|
+// +-+-+-+-+-+-+-+-+
+//
+// scalable streams, TL0PICIDX is optional
+//
+//  0                   1                   2
+//  0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3
+// +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+// |S|E|I|D|B| TID |      LID      |   TL0PICIDX   |
+// +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+type FrameMarking struct {
+	StartOfFrame  bool
+	EndOfFrame    bool
+	Independent   bool
+	Discardable   bool
+	BaseLayerSync bool
+	TID           uint8
+	LID           uint8
+	TL0PICIDX     uint8
+	IsScalable    bool
+}
+
+func (f FrameMarking) Marshal() ([]byte, error) {
+	b0 := byte(0)
+	if f.StartOfFrame {
+		b0 |= 0x80
+	}
+	if f.EndOfFrame {
+		b0 |= 0x40
+	}
+	if f.Independent {
+		b0 |= 0x20
+	}
+	if f.Discardable {
+		b0 |= 0x10
+	}
+	if !f.IsScalable {
+		return []byte{b0}, nil
+	}
+
+	if f.BaseLayerSync {
+		b0 |= 0x08
+	}
+	b0 |= f.TID & 0x07
+	return []byte{b0, f.LID, f.TL0PICIDX}, nil
+}
+
+func (f *FrameMarking) Unmarshal(rawData []byte) error {
+	if len(rawData) < 1 {
+		return errTooSmall
+	}
+
+	b0 := rawData[0]
+	*f = FrameMarking{
+		StartOfFrame: b0&0x80 != 0,
+		EndOfFrame:   b0&0x40 != 0,
+		Independent:  b0&0x20 != 0,
+		Discardable:  b0&0x10 != 0,
+	}
+	if len(rawData) == 1 {
+		return nil
+	}
+
+	f.IsScalable = true
+	f.BaseLayerSync = b0&0x08 != 0
+	f.TID = b0 & 0x07
+	f.LID = rawData[1]
+	if len(rawData) > 2 {
+		f.TL0PICIDX = rawData[2]
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package framemarking
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrameMarking(t *testing.T) {
+	// non-scalable
+	f1 := FrameMarking{StartOfFrame: true, Independent: true}
+	b, err := f1.Marshal()
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xa0}, b)
+	var f2 FrameMarking
+	require.NoError(t, f2.Unmarshal(b))
+	require.Equal(t, f1, f2)
+
+	// scalable
+	f3 := FrameMarking{
+		EndOfFrame:    true,
+		Discardable:   true,
+		BaseLayerSync: true,
+		TID:           2,
+		LID:           1,
+		TL0PICIDX:     100,
+		IsScalable:    true,
+	}
+	b, err = f3.Marshal()
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x5a, 0x01, 0x64}, b)
+	var f4 FrameMarking
+	require.NoError(t, f4.Unmarshal(b))
+	require.Equal(t, f3, f4)
+
+	// scalable without TL0PICIDX
+	var f5 FrameMarking
+	require.NoError(t, f5.Unmarshal([]byte{0x82, 0x00}))
+	require.True(t, f5.StartOfFrame)
+	require.True(t, f5.IsScalable)
+	require.EqualValues(t, 2, f5.TID)
+	require.Zero(t, f5.TL0PICIDX)
+
+	// too small
+	var f6 FrameMarking
+	require.ErrorIs(t, f6.Unmarshal(nil), errTooSmall)
+}
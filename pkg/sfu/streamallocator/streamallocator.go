@@ -94,6 +94,9 @@ const (
 	streamAllocatorSignalResume
 	streamAllocatorSignalSetAllowPause
 	streamAllocatorSignalSetChannelCapacity
+	streamAllocatorSignalSetMaxChannelCapacity
+	streamAllocatorSignalSetNodeChannelCapacity
+	streamAllocatorSignalUpdateBandwidthDemand
 	streamAllocatorSignalCongestionStateChange
 )
 
@@ -123,6 +126,12 @@ func (s streamAllocatorSignal) String() string {
 		return "SET_ALLOW_PAUSE"
 	case streamAllocatorSignalSetChannelCapacity:
 		return "SET_CHANNEL_CAPACITY"
+	case streamAllocatorSignalSetMaxChannelCapacity:
+		return "SET_MAX_CHANNEL_CAPACITY"
+	case streamAllocatorSignalSetNodeChannelCapacity:
+		return "SET_NODE_CHANNEL_CAPACITY"
+	case streamAllocatorSignalUpdateBandwidthDemand:
+		return "UPDATE_BANDWIDTH_DEMAND"
 	case streamAllocatorSignalCongestionStateChange:
 		return "CONGESTION_STATE_CHANGE"
 	default:
@@ -200,6 +209,8 @@ type StreamAllocator struct {
 
 	committedChannelCapacity  int64
 	overriddenChannelCapacity int64
+	maxChannelCapacity        int64
+	nodeChannelCapacity       int64
 
 	prober *ccutils.Prober
 
@@ -220,6 +231,9 @@ type StreamAllocator struct {
 
 	pingGeneration atomic.Uint32
 
+	// computed on the event loop, read by GetBandwidthDemand
+	bandwidthDemand atomic.Int64
+
 	isStopped atomic.Bool
 }
 
@@ -350,6 +364,35 @@ func (s *StreamAllocator) SetChannelCapacity(channelCapacity int64) {
 	})
 }
 
+// SetMaxChannelCapacity sets a ceiling on the channel capacity used for allocation irrespective of the estimate,
+// tracks are allocated within it even when the channel could carry more. A value <= 0 removes the ceiling.
+func (s *StreamAllocator) SetMaxChannelCapacity(maxChannelCapacity int64) {
+	s.postEvent(Event{
+		Signal: streamAllocatorSignalSetMaxChannelCapacity,
+		Data:   maxChannelCapacity,
+	})
+}
+
+// SetNodeChannelCapacity sets the share of node egress capacity given to this subscriber, it is a ceiling like
+// max channel capacity and the lower of the two applies. A value <= 0 removes the ceiling.
+func (s *StreamAllocator) SetNodeChannelCapacity(nodeChannelCapacity int64) {
+	s.postEvent(Event{
+		Signal: streamAllocatorSignalSetNodeChannelCapacity,
+		Data:   nodeChannelCapacity,
+	})
+}
+
+// GetBandwidthDemand returns the bandwidth needed to stream all tracks at their optimal allocation,
+// irrespective of channel capacity. Track state is owned by the event loop, so the demand is computed there
+// and the value returned is the one computed on the previous call, callers are expected to poll.
+func (s *StreamAllocator) GetBandwidthDemand() int64 {
+	s.postEvent(Event{
+		Signal: streamAllocatorSignalUpdateBandwidthDemand,
+	})
+
+	return s.bandwidthDemand.Load()
+}
+
 // called when a new REMB is received (receive side bandwidth estimation)
 func (s *StreamAllocator) OnREMB(downTrack *sfu.DownTrack, remb *rtcp.ReceiverEstimatedMaximumBitrate) {
 	//
@@ -490,6 +533,11 @@ func (s *StreamAllocator) OnMaxPublishedTemporalChanged(downTrack *sfu.DownTrack
 	s.maybePostEventAllocateTrack(downTrack)
 }
 
+// called when FEC protection level of a down track changes, FEC is budgeted from channel capacity
+func (s *StreamAllocator) OnFECProtectionChanged(downTrack *sfu.DownTrack) {
+	s.maybePostEventAllocateTrack(downTrack)
+}
+
 // called when subscription settings changes (muting/unmuting of track)
 func (s *StreamAllocator) OnSubscriptionChanged(downTrack *sfu.DownTrack) {
 	s.maybePostEventAllocateTrack(downTrack)
@@ -638,6 +686,12 @@ func (s *StreamAllocator) postEvent(event Event) {
 			event.handleSignalSetAllowPause(event)
 		case streamAllocatorSignalSetChannelCapacity:
 			event.handleSignalSetChannelCapacity(event)
+		case streamAllocatorSignalSetMaxChannelCapacity:
+			event.handleSignalSetMaxChannelCapacity(event)
+		case streamAllocatorSignalSetNodeChannelCapacity:
+			event.handleSignalSetNodeChannelCapacity(event)
+		case streamAllocatorSignalUpdateBandwidthDemand:
+			event.handleSignalUpdateBandwidthDemand(event)
 		case streamAllocatorSignalCongestionStateChange:
 			s.handleSignalCongestionStateChange(event)
 		}
@@ -729,7 +783,7 @@ func (s *StreamAllocator) handleSignalPeriodicPing(Event) {
 
 			if probeSignal != ccutils.ProbeSignalCongesting {
 				if channelCapacity > s.committedChannelCapacity {
-					s.committedChannelCapacity = channelCapacity
+					s.setCommittedChannelCapacity(channelCapacity)
 				}
 
 				s.maybeBoostDeficientTracks()
@@ -826,6 +880,64 @@ func (s *StreamAllocator) handleSignalSetChannelCapacity(event Event) {
 	}
 }
 
+func (s *StreamAllocator) handleSignalSetMaxChannelCapacity(event Event) {
+	maxChannelCapacity := event.Data.(int64)
+	if maxChannelCapacity < 0 {
+		maxChannelCapacity = 0
+	}
+	if s.maxChannelCapacity == maxChannelCapacity {
+		return
+	}
+
+	s.maxChannelCapacity = maxChannelCapacity
+	if s.maxChannelCapacity > 0 {
+		s.params.Logger.Infow("setting max channel capacity", "max", s.maxChannelCapacity)
+	} else {
+		s.params.Logger.Infow("clearing max channel capacity")
+	}
+	s.allocateOnChannelCapacityCeiling()
+}
+
+func (s *StreamAllocator) handleSignalSetNodeChannelCapacity(event Event) {
+	nodeChannelCapacity := max(event.Data.(int64), 0)
+	if s.nodeChannelCapacity == nodeChannelCapacity {
+		return
+	}
+
+	// updated periodically while node is short of capacity, not logging at info level
+	s.nodeChannelCapacity = nodeChannelCapacity
+	s.params.Logger.Debugw("setting node channel capacity", "node", s.nodeChannelCapacity)
+	s.allocateOnChannelCapacityCeiling()
+}
+
+func (s *StreamAllocator) handleSignalUpdateBandwidthDemand(Event) {
+	demand := int64(0)
+	for _, track := range s.getTracks() {
+		demand += track.OptimalBandwidthNeeded()
+	}
+	s.bandwidthDemand.Store(demand)
+}
+
+func (s *StreamAllocator) allocateOnChannelCapacityCeiling() {
+	if s.getChannelCapacityCeiling() > 0 {
+		s.allocateAllTracks()
+		return
+	}
+
+	if !s.enabled {
+		return
+	}
+
+	// tracks may have been held below optimal by the ceiling, give them a chance to go back up
+	update := NewStreamStateUpdate()
+	for _, track := range s.getTracks() {
+		allocation := track.AllocateOptimal(cFlagAllowOvershootWhileOptimal, false)
+		updateStreamStateChange(track, allocation, update)
+	}
+	s.maybeSendUpdate(update)
+	s.adjustState()
+}
+
 func (s *StreamAllocator) handleSignalCongestionStateChange(event Event) {
 	cscd := event.Data.(congestionStateChangeData)
 	if cscd.toState != bwe.CongestionStateNone {
@@ -836,6 +948,12 @@ func (s *StreamAllocator) handleSignalCongestionStateChange(event Event) {
 	// some tracks may have been held at sub-optimal allocation
 	// during early warning hold (if there was one)
 	if isHoldableCongestionState(cscd.fromState) && cscd.toState == bwe.CongestionStateNone && s.state == streamAllocatorStateStable {
+		if s.getChannelCapacityCeiling() > 0 {
+			// optimal allocation could go over the ceiling
+			s.allocateAllTracks()
+			return
+		}
+
 		update := NewStreamStateUpdate()
 		for _, track := range s.getTracks() {
 			allocation := track.AllocateOptimal(cFlagAllowOvershootWhileOptimal, false)
@@ -862,7 +980,7 @@ func (s *StreamAllocator) handleSignalCongestionStateChange(event Event) {
 				"new(bps)", cscd.estimatedAvailableChannelCapacity,
 				"expectedUsage(bps)", s.getExpectedBandwidthUsage(),
 			)
-			s.committedChannelCapacity = cscd.estimatedAvailableChannelCapacity
+			s.setCommittedChannelCapacity(cscd.estimatedAvailableChannelCapacity)
 
 			s.allocateAllTracks()
 		}
@@ -905,9 +1023,10 @@ func (s *StreamAllocator) allocateTrack(track *Track) {
 	// end/abort any probe that may be running when a track specific change needs allocation
 	s.maybeStopProbe()
 
-	// if not deficient, free pass allocate track
+	// if not deficient, free pass allocate track, a ceiling on channel capacity has to be respected even when stable
 	bweCongestionState := s.params.BWE.CongestionState()
-	if !s.enabled || (s.state == streamAllocatorStateStable && !isDeficientCongestionState(bweCongestionState)) || !track.IsManaged() {
+	isStable := s.state == streamAllocatorStateStable && !isDeficientCongestionState(bweCongestionState) && s.getChannelCapacityCeiling() == 0
+	if !s.enabled || isStable || !track.IsManaged() {
 		update := NewStreamStateUpdate()
 		allocation := track.AllocateOptimal(cFlagAllowOvershootWhileOptimal, isHoldableCongestionState(bweCongestionState))
 		updateStreamStateChange(track, allocation, update)
@@ -1227,6 +1346,13 @@ func (s *StreamAllocator) maybeSendUpdate(update *StreamStateUpdate) {
 	}
 }
 
+func (s *StreamAllocator) setCommittedChannelCapacity(channelCapacity int64) {
+	s.committedChannelCapacity = channelCapacity
+
+	// pacers that shape traffic derive pacing rate from channel capacity
+	s.params.Pacer.SetBitrate(int(channelCapacity))
+}
+
 func (s *StreamAllocator) getAvailableChannelCapacity(allowOverride bool) int64 {
 	availableChannelCapacity := s.committedChannelCapacity
 	if s.params.Config.MinChannelCapacity > availableChannelCapacity {
@@ -1245,10 +1371,42 @@ func (s *StreamAllocator) getAvailableChannelCapacity(allowOverride bool) int64
 			"override", availableChannelCapacity,
 		)
 	}
+	if ceiling := s.getChannelCapacityCeiling(); ceiling > 0 && availableChannelCapacity > ceiling {
+		availableChannelCapacity = ceiling
+		s.params.Logger.Debugw(
+			"stream allocator: limiting channel capacity to ceiling",
+			"actual", s.committedChannelCapacity,
+			"max", s.maxChannelCapacity,
+			"node", s.nodeChannelCapacity,
+		)
+	}
+	if fecBandwidth := s.getFECBandwidthNeeded(); fecBandwidth > 0 {
+		availableChannelCapacity = max(availableChannelCapacity-fecBandwidth, 0)
+	}
 
 	return availableChannelCapacity
 }
 
+func (s *StreamAllocator) getFECBandwidthNeeded() int64 {
+	fecBandwidth := int64(0)
+	for _, track := range s.getTracks() {
+		fecBandwidth += track.FECBandwidthNeeded()
+	}
+
+	return fecBandwidth
+}
+
+func (s *StreamAllocator) getChannelCapacityCeiling() int64 {
+	switch {
+	case s.maxChannelCapacity == 0:
+		return s.nodeChannelCapacity
+	case s.nodeChannelCapacity == 0:
+		return s.maxChannelCapacity
+	default:
+		return min(s.maxChannelCapacity, s.nodeChannelCapacity)
+	}
+}
+
 func (s *StreamAllocator) getExpectedBandwidthUsage() int64 {
 	expected := int64(0)
 	for _, track := range s.getTracks() {
@@ -1302,6 +1460,11 @@ func (s *StreamAllocator) maybeProbe() {
 		return
 	}
 
+	if ceiling := s.getChannelCapacityCeiling(); ceiling > 0 && s.committedChannelCapacity >= ceiling {
+		// channel can already carry the ceiling, probing for more will not help
+		return
+	}
+
 	if !s.params.BWE.CanProbe() {
 		return
 	}
@@ -186,6 +186,14 @@ func (t *Track) BandwidthRequested() int64 {
 	return t.downTrack.BandwidthRequested()
 }
 
+func (t *Track) OptimalBandwidthNeeded() int64 {
+	return t.downTrack.OptimalBandwidthNeeded()
+}
+
+func (t *Track) FECBandwidthNeeded() int64 {
+	return t.downTrack.FECBandwidthNeeded()
+}
+
 func (t *Track) DistanceToDesired() float64 {
 	return t.downTrack.DistanceToDesired()
 }
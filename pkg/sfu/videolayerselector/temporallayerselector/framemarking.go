@@ -0,0 +1,78 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package temporallayerselector
+
+import (
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	dd "github.com/livekit/livekit-server/pkg/sfu/rtpextension/dependencydescriptor"
+	"github.com/livekit/protocol/logger"
+)
+
+// FrameMarking selects temporal layers of codecs whose payload does not carry switching points (H.264/H.265)
+// using frame boundaries and switching points signalled by the frame marking extension or the dependency descriptor.
+type FrameMarking struct {
+	logger logger.Logger
+}
+
+func NewFrameMarking(logger logger.Logger) *FrameMarking {
+	return &FrameMarking{
+		logger: logger,
+	}
+}
+
+func (f *FrameMarking) Select(extPkt *buffer.ExtPacket, current int32, target int32) (this int32, next int32) {
+	this = current
+	next = current
+	if current == target {
+		return
+	}
+
+	tid := extPkt.Temporal
+	if current < target {
+		if tid > current && tid <= target && isSwitchingPoint(extPkt) {
+			this = tid
+			next = tid
+		}
+	} else {
+		if extPkt.Packet.Marker {
+			next = target
+		}
+	}
+	return
+}
+
+// a higher temporal layer can be switched to at the start of a frame which does not depend on
+// previous frames of that layer
+func isSwitchingPoint(extPkt *buffer.ExtPacket) bool {
+	if extPkt.DependencyDescriptor != nil {
+		descriptor := extPkt.DependencyDescriptor.Descriptor
+		if !descriptor.FirstPacketInFrame || descriptor.FrameDependencies == nil {
+			return false
+		}
+		for _, dti := range descriptor.FrameDependencies.DecodeTargetIndications {
+			if dti == dd.DecodeTargetSwitch {
+				return true
+			}
+		}
+		return false
+	}
+
+	if fm := extPkt.FrameMarking; fm != nil {
+		return fm.StartOfFrame && (fm.Independent || fm.BaseLayerSync)
+	}
+
+	// without signalling, switching points are not known
+	return false
+}
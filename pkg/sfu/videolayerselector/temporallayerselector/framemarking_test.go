@@ -0,0 +1,99 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package temporallayerselector
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	dd "github.com/livekit/livekit-server/pkg/sfu/rtpextension/dependencydescriptor"
+	fm "github.com/livekit/livekit-server/pkg/sfu/rtpextension/framemarking"
+	"github.com/livekit/protocol/logger"
+)
+
+func TestFrameMarking(t *testing.T) {
+	f := NewFrameMarking(logger.GetLogger())
+
+	newPacket := func(tid int32, marker bool, frameMarking *fm.FrameMarking) *buffer.ExtPacket {
+		return &buffer.ExtPacket{
+			VideoLayer: buffer.VideoLayer{
+				Spatial:  buffer.InvalidLayerSpatial,
+				Temporal: tid,
+			},
+			Packet: &rtp.Packet{
+				Header: rtp.Header{
+					Marker: marker,
+				},
+			},
+			FrameMarking: frameMarking,
+		}
+	}
+
+	// no switch without target change
+	this, next := f.Select(newPacket(1, false, &fm.FrameMarking{StartOfFrame: true, BaseLayerSync: true, TID: 1}), 0, 0)
+	require.EqualValues(t, 0, this)
+	require.EqualValues(t, 0, next)
+
+	// no switch up in the middle of a frame
+	this, next = f.Select(newPacket(1, false, &fm.FrameMarking{BaseLayerSync: true, TID: 1}), 0, 2)
+	require.EqualValues(t, 0, this)
+	require.EqualValues(t, 0, next)
+
+	// no switch up at a frame depending on previous frames of the layer
+	this, next = f.Select(newPacket(1, false, &fm.FrameMarking{StartOfFrame: true, TID: 1}), 0, 2)
+	require.EqualValues(t, 0, this)
+	require.EqualValues(t, 0, next)
+
+	// no switch up without signalling
+	this, next = f.Select(newPacket(1, false, nil), 0, 2)
+	require.EqualValues(t, 0, this)
+	require.EqualValues(t, 0, next)
+
+	// switch up at base layer sync
+	this, next = f.Select(newPacket(1, false, &fm.FrameMarking{StartOfFrame: true, BaseLayerSync: true, TID: 1}), 0, 2)
+	require.EqualValues(t, 1, this)
+	require.EqualValues(t, 1, next)
+
+	// no switch up beyond target
+	this, next = f.Select(newPacket(2, false, &fm.FrameMarking{StartOfFrame: true, BaseLayerSync: true, TID: 2}), 0, 1)
+	require.EqualValues(t, 0, this)
+	require.EqualValues(t, 0, next)
+
+	// switch up at switch indication of dependency descriptor
+	ddPacket := newPacket(2, false, nil)
+	ddPacket.DependencyDescriptor = &buffer.ExtDependencyDescriptor{
+		Descriptor: &dd.DependencyDescriptor{
+			FirstPacketInFrame: true,
+			FrameDependencies: &dd.FrameDependencyTemplate{
+				TemporalId:              2,
+				DecodeTargetIndications: []dd.DecodeTargetIndication{dd.DecodeTargetNotPresent, dd.DecodeTargetNotPresent, dd.DecodeTargetSwitch},
+			},
+		},
+	}
+	this, next = f.Select(ddPacket, 1, 2)
+	require.EqualValues(t, 2, this)
+	require.EqualValues(t, 2, next)
+
+	// switch down at end of frame
+	this, next = f.Select(newPacket(2, false, &fm.FrameMarking{TID: 2}), 2, 0)
+	require.EqualValues(t, 2, this)
+	require.EqualValues(t, 2, next)
+	this, next = f.Select(newPacket(2, true, &fm.FrameMarking{EndOfFrame: true, TID: 2}), 2, 0)
+	require.EqualValues(t, 2, this)
+	require.EqualValues(t, 0, next)
+}
@@ -16,6 +16,7 @@ package telemetry
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -29,6 +30,38 @@ import (
 	"github.com/livekit/protocol/webhook"
 )
 
+// webhook events of participants held in the lobby of a room, admitted participants are notified as joined
+const (
+	EventParticipantPending  = "participant_pending"
+	EventParticipantRejected = "participant_rejected"
+)
+
+// webhook events of agent jobs. The webhook event has no agent job field, job details are
+// carried as attributes of a placeholder participant without identity or kind.
+const (
+	EventAgentJobAssigned = "agent_job_assigned"
+	EventAgentJobStarted  = "agent_job_started"
+	EventAgentJobEnded    = "agent_job_ended"
+	EventAgentJobFailed   = "agent_job_failed"
+	// a job of a draining worker was handed off to another worker, or the handoff was abandoned
+	EventAgentJobHandoffCompleted = "agent_job_handoff_completed"
+	EventAgentJobHandoffFailed    = "agent_job_handoff_failed"
+
+	AgentJobIDAttribute         = "lk.agent.job_id"
+	AgentJobDispatchIDAttribute = "lk.agent.dispatch_id"
+	AgentJobAgentNameAttribute  = "lk.agent.agent_name"
+	AgentJobTypeAttribute       = "lk.agent.job_type"
+	AgentJobStatusAttribute     = "lk.agent.status"
+	AgentJobWorkerIDAttribute   = "lk.agent.worker_id"
+	AgentJobIdentityAttribute   = "lk.agent.participant_identity"
+	AgentJobErrorAttribute      = "lk.agent.error"
+	AgentJobStartedAtAttribute  = "lk.agent.started_at"
+	AgentJobEndedAtAttribute    = "lk.agent.ended_at"
+
+	AgentJobReplacementIDAttribute       = "lk.agent.replacement_job_id"
+	AgentJobReplacementWorkerIDAttribute = "lk.agent.replacement_worker_id"
+)
+
 func (t *telemetryService) NotifyEvent(ctx context.Context, event *livekit.WebhookEvent, opts ...webhook.NotifyOption) {
 	if t.notifier == nil {
 		return
@@ -222,6 +255,26 @@ func (t *telemetryService) ParticipantLeft(ctx context.Context,
 	})
 }
 
+func (t *telemetryService) ParticipantPending(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo) {
+	t.enqueue(func() {
+		t.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event:       EventParticipantPending,
+			Room:        room,
+			Participant: participant,
+		})
+	})
+}
+
+func (t *telemetryService) ParticipantRejected(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo) {
+	t.enqueue(func() {
+		t.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event:       EventParticipantRejected,
+			Room:        room,
+			Participant: participant,
+		})
+	})
+}
+
 func (t *telemetryService) TrackPublishRequested(
 	ctx context.Context,
 	participantID livekit.ParticipantID,
@@ -560,6 +613,69 @@ func (t *telemetryService) Webhook(ctx context.Context, webhookInfo *livekit.Web
 	})
 }
 
+func (t *telemetryService) AgentJobEvent(ctx context.Context, event string, job *livekit.Job) {
+	t.enqueue(func() {
+		t.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event:       event,
+			Room:        job.Room,
+			Participant: agentJobParticipant(job),
+		})
+	})
+}
+
+func (t *telemetryService) AgentJobHandoff(ctx context.Context, job *livekit.Job, replacement *livekit.Job, err error) {
+	t.enqueue(func() {
+		event := EventAgentJobHandoffCompleted
+		participant := agentJobParticipant(job)
+		if replacement != nil {
+			participant.Attributes[AgentJobReplacementIDAttribute] = replacement.Id
+			participant.Attributes[AgentJobReplacementWorkerIDAttribute] = replacement.GetState().GetWorkerId()
+		}
+		if err != nil {
+			event = EventAgentJobHandoffFailed
+			participant.Attributes[AgentJobErrorAttribute] = err.Error()
+		}
+
+		t.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event:       event,
+			Room:        job.Room,
+			Participant: participant,
+		})
+	})
+}
+
+func agentJobParticipant(job *livekit.Job) *livekit.ParticipantInfo {
+	state := job.GetState()
+	attributes := map[string]string{
+		AgentJobIDAttribute:         job.Id,
+		AgentJobDispatchIDAttribute: job.DispatchId,
+		AgentJobAgentNameAttribute:  job.AgentName,
+		AgentJobTypeAttribute:       job.Type.String(),
+		AgentJobStatusAttribute:     state.GetStatus().String(),
+	}
+	if state.GetWorkerId() != "" {
+		attributes[AgentJobWorkerIDAttribute] = state.GetWorkerId()
+	}
+	if state.GetParticipantIdentity() != "" {
+		attributes[AgentJobIdentityAttribute] = state.GetParticipantIdentity()
+	}
+	if state.GetError() != "" {
+		attributes[AgentJobErrorAttribute] = state.GetError()
+	}
+	// unix nano
+	if state.GetStartedAt() != 0 {
+		attributes[AgentJobStartedAtAttribute] = strconv.FormatInt(state.GetStartedAt(), 10)
+	}
+	if state.GetEndedAt() != 0 {
+		attributes[AgentJobEndedAtAttribute] = strconv.FormatInt(state.GetEndedAt(), 10)
+	}
+
+	// carries job attributes only, identity and kind are left unset so it is not mistaken for a participant
+	return &livekit.ParticipantInfo{
+		Attributes: attributes,
+	}
+}
+
 // returns a livekit.Room with only name and sid filled out
 // returns nil if room is not found
 func (t *telemetryService) getRoomDetails(participantID livekit.ParticipantID) *livekit.Room {
@@ -0,0 +1,91 @@
+// Copyright 2024 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	promAgentWorkers            *prometheus.GaugeVec
+	promAgentAvailableWorkers   *prometheus.GaugeVec
+	promAgentLoad               *prometheus.GaugeVec
+	promAgentRunningJobs        *prometheus.GaugeVec
+	promAgentRecommendedWorkers *prometheus.GaugeVec
+	promAgentQueuedJobs         *prometheus.GaugeVec
+	promAgentJobRequestsFailed  *prometheus.CounterVec
+)
+
+func initAgentStats(nodeID string, nodeType livekit.NodeType) {
+	newGauge := func(name string, help string) *prometheus.GaugeVec {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   livekitNamespace,
+			Subsystem:   "agent",
+			Name:        name,
+			ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+			Help:        help,
+		}, []string{"agent_name", "namespace"})
+	}
+
+	promAgentWorkers = newGauge("workers", "Registered agent workers.")
+	promAgentAvailableWorkers = newGauge("available_workers", "Registered agent workers which can take another job.")
+	promAgentLoad = newGauge("load", "Sum of the load reported by registered agent workers.")
+	promAgentRunningJobs = newGauge("running_jobs", "Jobs running on registered agent workers.")
+	promAgentRecommendedWorkers = newGauge("recommended_workers", "Agent workers needed for the current demand.")
+	promAgentQueuedJobs = newGauge("queued_jobs", "Jobs waiting on this node for an agent worker to become available.")
+	promAgentJobRequestsFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "agent",
+		Name:        "job_requests_failed",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Help:        "Job requests which could not be assigned to an agent worker.",
+	}, []string{"agent_name", "namespace", "reason"})
+
+	prometheus.MustRegister(promAgentWorkers)
+	prometheus.MustRegister(promAgentAvailableWorkers)
+	prometheus.MustRegister(promAgentLoad)
+	prometheus.MustRegister(promAgentRunningJobs)
+	prometheus.MustRegister(promAgentRecommendedWorkers)
+	prometheus.MustRegister(promAgentQueuedJobs)
+	prometheus.MustRegister(promAgentJobRequestsFailed)
+}
+
+// SetAgentFleetStats records the workers registered on this node for an agent
+func SetAgentFleetStats(agentName string, namespace string, workers int, availableWorkers int, load float64, runningJobs int, recommendedWorkers int) {
+	promAgentWorkers.WithLabelValues(agentName, namespace).Set(float64(workers))
+	promAgentAvailableWorkers.WithLabelValues(agentName, namespace).Set(float64(availableWorkers))
+	promAgentLoad.WithLabelValues(agentName, namespace).Set(load)
+	promAgentRunningJobs.WithLabelValues(agentName, namespace).Set(float64(runningJobs))
+	promAgentRecommendedWorkers.WithLabelValues(agentName, namespace).Set(float64(recommendedWorkers))
+}
+
+// DeleteAgentFleetStats removes the stats of an agent once its last worker is gone from this node
+func DeleteAgentFleetStats(agentName string, namespace string) {
+	promAgentWorkers.DeleteLabelValues(agentName, namespace)
+	promAgentAvailableWorkers.DeleteLabelValues(agentName, namespace)
+	promAgentLoad.DeleteLabelValues(agentName, namespace)
+	promAgentRunningJobs.DeleteLabelValues(agentName, namespace)
+	promAgentRecommendedWorkers.DeleteLabelValues(agentName, namespace)
+}
+
+func AddAgentQueuedJobs(agentName string, namespace string, delta int) {
+	promAgentQueuedJobs.WithLabelValues(agentName, namespace).Add(float64(delta))
+}
+
+func RecordAgentJobRequestFailed(agentName string, namespace string, reason string) {
+	promAgentJobRequestsFailed.WithLabelValues(agentName, namespace, reason).Inc()
+}
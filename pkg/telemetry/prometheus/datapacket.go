@@ -29,6 +29,9 @@ var (
 
 	promDataPacketStreamDestCount *prometheus.HistogramVec
 	promDataPacketStreamSize      *prometheus.HistogramVec
+
+	promDataPolicyActions  *prometheus.CounterVec
+	promDataPolicyFailures *prometheus.CounterVec
 )
 
 func initDataPacketStats(nodeID string, nodeType livekit.NodeType) {
@@ -46,9 +49,23 @@ func initDataPacketStats(nodeID string, nodeType livekit.NodeType) {
 		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
 		Buckets:     []float64{128, 512, 2048, 8192, 32768, 131072, 524288, 2097152, 8388608, 33554432},
 	}, promDataPacketStreamLabels)
+	promDataPolicyActions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "datapacket_policy",
+		Name:        "actions",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"action"})
+	promDataPolicyFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "datapacket_policy",
+		Name:        "failures",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"reason"})
 
 	prometheus.MustRegister(promDataPacketStreamDestCount)
 	prometheus.MustRegister(promDataPacketStreamSize)
+	prometheus.MustRegister(promDataPolicyActions)
+	prometheus.MustRegister(promDataPolicyFailures)
 }
 
 func RecordDataPacketStream(h *livekit.DataStream_Header, destCount int) {
@@ -73,3 +90,11 @@ func RecordDataPacketStream(h *livekit.DataStream_Header, destCount int) {
 		promDataPacketStreamSize.WithLabelValues(streamType, mimeType).Observe(float64(*h.TotalLength))
 	}
 }
+
+func RecordDataPolicyAction(action string) {
+	promDataPolicyActions.WithLabelValues(action).Inc()
+}
+
+func RecordDataPolicyFailure(reason string) {
+	promDataPolicyFailures.WithLabelValues(reason).Inc()
+}
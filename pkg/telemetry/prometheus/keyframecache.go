@@ -0,0 +1,70 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	promKeyFrameCachePrimeTotal *prometheus.CounterVec
+	promKeyFrameCacheDropTotal  prometheus.Counter
+	promKeyFrameCacheBytes      prometheus.Gauge
+)
+
+func initKeyFrameCacheStats(nodeID string, nodeType livekit.NodeType) {
+	promKeyFrameCachePrimeTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "keyframe_cache",
+		Name:        "prime_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"result"})
+	promKeyFrameCacheDropTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "keyframe_cache",
+		Name:        "drop_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	})
+	promKeyFrameCacheBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "keyframe_cache",
+		Name:        "bytes",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	})
+
+	prometheus.MustRegister(promKeyFrameCachePrimeTotal)
+	prometheus.MustRegister(promKeyFrameCacheDropTotal)
+	prometheus.MustRegister(promKeyFrameCacheBytes)
+}
+
+// RecordKeyFrameCachePrime records whether a subscriber could be primed from the cache or had to wait for a keyframe
+func RecordKeyFrameCachePrime(hit bool) {
+	if hit {
+		promKeyFrameCachePrimeTotal.WithLabelValues("hit").Inc()
+	} else {
+		promKeyFrameCachePrimeTotal.WithLabelValues("miss").Inc()
+	}
+}
+
+// RecordKeyFrameCacheDrop records a cached keyframe being dropped for exceeding the memory budget
+func RecordKeyFrameCacheDrop() {
+	promKeyFrameCacheDropTotal.Inc()
+}
+
+func AddKeyFrameCacheBytes(delta int64) {
+	promKeyFrameCacheBytes.Add(float64(delta))
+}
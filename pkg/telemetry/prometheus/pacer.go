@@ -0,0 +1,57 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	promPacerQueueDelay *prometheus.HistogramVec
+	promPacerDropTotal  *prometheus.CounterVec
+)
+
+func initPacerStats(nodeID string, nodeType livekit.NodeType) {
+	promPacerQueueDelay = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "pacer",
+		Name:        "queue_delay_ms",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+		Buckets:     []float64{1, 5, 10, 20, 50, 100, 200, 500, 1000},
+	}, []string{"class"})
+	promPacerDropTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "pacer",
+		Name:        "drop_total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String()},
+	}, []string{"class"})
+
+	prometheus.MustRegister(promPacerQueueDelay)
+	prometheus.MustRegister(promPacerDropTotal)
+}
+
+// RecordPacerQueueDelay records how long a packet of a scheduling class waited in the pacer
+func RecordPacerQueueDelay(class string, delay time.Duration) {
+	promPacerQueueDelay.WithLabelValues(class).Observe(float64(delay.Microseconds()) / 1000.0)
+}
+
+// RecordPacerDrop records a packet of a scheduling class dropped by the pacer for being stale
+func RecordPacerDrop(class string) {
+	promPacerDropTotal.WithLabelValues(class).Inc()
+}
@@ -18,6 +18,21 @@ type FakeTelemetryService struct {
 		arg1 context.Context
 		arg2 *livekit.APICallInfo
 	}
+	AgentJobEventStub        func(context.Context, string, *livekit.Job)
+	agentJobEventMutex       sync.RWMutex
+	agentJobEventArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 *livekit.Job
+	}
+	AgentJobHandoffStub        func(context.Context, *livekit.Job, *livekit.Job, error)
+	agentJobHandoffMutex       sync.RWMutex
+	agentJobHandoffArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.Job
+		arg3 *livekit.Job
+		arg4 error
+	}
 	EgressEndedStub        func(context.Context, *livekit.EgressInfo)
 	egressEndedMutex       sync.RWMutex
 	egressEndedArgsForCall []struct {
@@ -113,6 +128,20 @@ type FakeTelemetryService struct {
 		arg4 bool
 		arg5 *telemetry.ReferenceGuard
 	}
+	ParticipantPendingStub        func(context.Context, *livekit.Room, *livekit.ParticipantInfo)
+	participantPendingMutex       sync.RWMutex
+	participantPendingArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 *livekit.ParticipantInfo
+	}
+	ParticipantRejectedStub        func(context.Context, *livekit.Room, *livekit.ParticipantInfo)
+	participantRejectedMutex       sync.RWMutex
+	participantRejectedArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 *livekit.ParticipantInfo
+	}
 	ParticipantResumedStub        func(context.Context, *livekit.Room, *livekit.ParticipantInfo, livekit.NodeID, livekit.ReconnectReason)
 	participantResumedMutex       sync.RWMutex
 	participantResumedArgsForCall []struct {
@@ -326,6 +355,75 @@ func (fake *FakeTelemetryService) APICallArgsForCall(i int) (context.Context, *l
 	return argsForCall.arg1, argsForCall.arg2
 }
 
+func (fake *FakeTelemetryService) AgentJobEvent(arg1 context.Context, arg2 string, arg3 *livekit.Job) {
+	fake.agentJobEventMutex.Lock()
+	fake.agentJobEventArgsForCall = append(fake.agentJobEventArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 *livekit.Job
+	}{arg1, arg2, arg3})
+	stub := fake.AgentJobEventStub
+	fake.recordInvocation("AgentJobEvent", []interface{}{arg1, arg2, arg3})
+	fake.agentJobEventMutex.Unlock()
+	if stub != nil {
+		fake.AgentJobEventStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *FakeTelemetryService) AgentJobEventCallCount() int {
+	fake.agentJobEventMutex.RLock()
+	defer fake.agentJobEventMutex.RUnlock()
+	return len(fake.agentJobEventArgsForCall)
+}
+
+func (fake *FakeTelemetryService) AgentJobEventCalls(stub func(context.Context, string, *livekit.Job)) {
+	fake.agentJobEventMutex.Lock()
+	defer fake.agentJobEventMutex.Unlock()
+	fake.AgentJobEventStub = stub
+}
+
+func (fake *FakeTelemetryService) AgentJobEventArgsForCall(i int) (context.Context, string, *livekit.Job) {
+	fake.agentJobEventMutex.RLock()
+	defer fake.agentJobEventMutex.RUnlock()
+	argsForCall := fake.agentJobEventArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeTelemetryService) AgentJobHandoff(arg1 context.Context, arg2 *livekit.Job, arg3 *livekit.Job, arg4 error) {
+	fake.agentJobHandoffMutex.Lock()
+	fake.agentJobHandoffArgsForCall = append(fake.agentJobHandoffArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.Job
+		arg3 *livekit.Job
+		arg4 error
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.AgentJobHandoffStub
+	fake.recordInvocation("AgentJobHandoff", []interface{}{arg1, arg2, arg3, arg4})
+	fake.agentJobHandoffMutex.Unlock()
+	if stub != nil {
+		fake.AgentJobHandoffStub(arg1, arg2, arg3, arg4)
+	}
+}
+
+func (fake *FakeTelemetryService) AgentJobHandoffCallCount() int {
+	fake.agentJobHandoffMutex.RLock()
+	defer fake.agentJobHandoffMutex.RUnlock()
+	return len(fake.agentJobHandoffArgsForCall)
+}
+
+func (fake *FakeTelemetryService) AgentJobHandoffCalls(stub func(context.Context, *livekit.Job, *livekit.Job, error)) {
+	fake.agentJobHandoffMutex.Lock()
+	defer fake.agentJobHandoffMutex.Unlock()
+	fake.AgentJobHandoffStub = stub
+}
+
+func (fake *FakeTelemetryService) AgentJobHandoffArgsForCall(i int) (context.Context, *livekit.Job, *livekit.Job, error) {
+	fake.agentJobHandoffMutex.RLock()
+	defer fake.agentJobHandoffMutex.RUnlock()
+	argsForCall := fake.agentJobHandoffArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
 func (fake *FakeTelemetryService) EgressEnded(arg1 context.Context, arg2 *livekit.EgressInfo) {
 	fake.egressEndedMutex.Lock()
 	fake.egressEndedArgsForCall = append(fake.egressEndedArgsForCall, struct {
@@ -792,6 +890,74 @@ func (fake *FakeTelemetryService) ParticipantLeftArgsForCall(i int) (context.Con
 	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
 }
 
+func (fake *FakeTelemetryService) ParticipantPending(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.ParticipantInfo) {
+	fake.participantPendingMutex.Lock()
+	fake.participantPendingArgsForCall = append(fake.participantPendingArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 *livekit.ParticipantInfo
+	}{arg1, arg2, arg3})
+	stub := fake.ParticipantPendingStub
+	fake.recordInvocation("ParticipantPending", []interface{}{arg1, arg2, arg3})
+	fake.participantPendingMutex.Unlock()
+	if stub != nil {
+		fake.ParticipantPendingStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *FakeTelemetryService) ParticipantPendingCallCount() int {
+	fake.participantPendingMutex.RLock()
+	defer fake.participantPendingMutex.RUnlock()
+	return len(fake.participantPendingArgsForCall)
+}
+
+func (fake *FakeTelemetryService) ParticipantPendingCalls(stub func(context.Context, *livekit.Room, *livekit.ParticipantInfo)) {
+	fake.participantPendingMutex.Lock()
+	defer fake.participantPendingMutex.Unlock()
+	fake.ParticipantPendingStub = stub
+}
+
+func (fake *FakeTelemetryService) ParticipantPendingArgsForCall(i int) (context.Context, *livekit.Room, *livekit.ParticipantInfo) {
+	fake.participantPendingMutex.RLock()
+	defer fake.participantPendingMutex.RUnlock()
+	argsForCall := fake.participantPendingArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeTelemetryService) ParticipantRejected(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.ParticipantInfo) {
+	fake.participantRejectedMutex.Lock()
+	fake.participantRejectedArgsForCall = append(fake.participantRejectedArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 *livekit.ParticipantInfo
+	}{arg1, arg2, arg3})
+	stub := fake.ParticipantRejectedStub
+	fake.recordInvocation("ParticipantRejected", []interface{}{arg1, arg2, arg3})
+	fake.participantRejectedMutex.Unlock()
+	if stub != nil {
+		fake.ParticipantRejectedStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *FakeTelemetryService) ParticipantRejectedCallCount() int {
+	fake.participantRejectedMutex.RLock()
+	defer fake.participantRejectedMutex.RUnlock()
+	return len(fake.participantRejectedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) ParticipantRejectedCalls(stub func(context.Context, *livekit.Room, *livekit.ParticipantInfo)) {
+	fake.participantRejectedMutex.Lock()
+	defer fake.participantRejectedMutex.Unlock()
+	fake.ParticipantRejectedStub = stub
+}
+
+func (fake *FakeTelemetryService) ParticipantRejectedArgsForCall(i int) (context.Context, *livekit.Room, *livekit.ParticipantInfo) {
+	fake.participantRejectedMutex.RLock()
+	defer fake.participantRejectedMutex.RUnlock()
+	argsForCall := fake.participantRejectedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
 func (fake *FakeTelemetryService) ParticipantResumed(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.ParticipantInfo, arg4 livekit.NodeID, arg5 livekit.ReconnectReason) {
 	fake.participantResumedMutex.Lock()
 	fake.participantResumedArgsForCall = append(fake.participantResumedArgsForCall, struct {
@@ -44,6 +44,10 @@ type TelemetryService interface {
 	ParticipantResumed(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo, nodeID livekit.NodeID, reason livekit.ReconnectReason)
 	// ParticipantLeft - the participant leaves the room, only sent if ParticipantActive has been called before
 	ParticipantLeft(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo, shouldSendEvent bool, guard *ReferenceGuard)
+	// ParticipantPending - the participant is waiting in the lobby to be admitted
+	ParticipantPending(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo)
+	// ParticipantRejected - the participant waiting in the lobby has been rejected
+	ParticipantRejected(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo)
 	// TrackPublishRequested - a publication attempt has been received
 	TrackPublishRequested(ctx context.Context, participantID livekit.ParticipantID, identity livekit.ParticipantIdentity, track *livekit.TrackInfo)
 	// TrackPublished - a publication attempt has been successful
@@ -80,6 +84,10 @@ type TelemetryService interface {
 	Report(ctx context.Context, reportInfo *livekit.ReportInfo)
 	APICall(ctx context.Context, apiCallInfo *livekit.APICallInfo)
 	Webhook(ctx context.Context, webhookInfo *livekit.WebhookInfo)
+	// AgentJobEvent - an agent job was assigned to a worker, started, ended or failed
+	AgentJobEvent(ctx context.Context, event string, job *livekit.Job)
+	// AgentJobHandoff - a job of a draining worker was handed off to the replacement job, or failed to with err
+	AgentJobHandoff(ctx context.Context, job *livekit.Job, replacement *livekit.Job, err error)
 
 	// helpers
 	AnalyticsService
@@ -106,6 +114,10 @@ func (n NullTelemetryService) ParticipantResumed(ctx context.Context, room *live
 }
 func (n NullTelemetryService) ParticipantLeft(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo, shouldSendEvent bool, guard *ReferenceGuard) {
 }
+func (n NullTelemetryService) ParticipantPending(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo) {
+}
+func (n NullTelemetryService) ParticipantRejected(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo) {
+}
 func (n NullTelemetryService) TrackPublishRequested(ctx context.Context, participantID livekit.ParticipantID, identity livekit.ParticipantIdentity, track *livekit.TrackInfo) {
 }
 func (n NullTelemetryService) TrackPublished(ctx context.Context, participantID livekit.ParticipantID, identity livekit.ParticipantIdentity, track *livekit.TrackInfo, shouldSendEvent bool) {
@@ -144,6 +156,9 @@ func (n NullTelemetryService) LocalRoomState(ctx context.Context, info *livekit.
 func (n NullTelemetryService) Report(ctx context.Context, reportInfo *livekit.ReportInfo)           {}
 func (n NullTelemetryService) APICall(ctx context.Context, apiCallInfo *livekit.APICallInfo)        {}
 func (n NullTelemetryService) Webhook(ctx context.Context, webhookInfo *livekit.WebhookInfo)        {}
+func (n NullTelemetryService) AgentJobEvent(ctx context.Context, event string, job *livekit.Job)    {}
+func (n NullTelemetryService) AgentJobHandoff(ctx context.Context, job *livekit.Job, replacement *livekit.Job, err error) {
+}
 func (n NullTelemetryService) NotifyEgressEvent(ctx context.Context, event string, info *livekit.EgressInfo) {
 }
 func (n NullTelemetryService) FlushStats() {}